@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/snapcore/bolt"
@@ -35,19 +36,25 @@ import (
 var (
 	cmdBucketKey = []byte("Commands")
 	pkgBucketKey = []byte("Snaps")
+	// snaps without commands are kept apart so that they are only
+	// found when searching the catalog, and not offered by
+	// FindPackage
+	catalogBucketKey = []byte("CatalogOnly")
 )
 
 type writer struct {
-	fn        string
-	db        *bolt.DB
-	tx        *bolt.Tx
-	cmdBucket *bolt.Bucket
-	pkgBucket *bolt.Bucket
+	fn            string
+	db            *bolt.DB
+	tx            *bolt.Tx
+	cmdBucket     *bolt.Bucket
+	pkgBucket     *bolt.Bucket
+	catalogBucket *bolt.Bucket
 }
 
 type CommandDB interface {
 	// AddSnap adds the entries for commands pointing to the given
-	// snap name to the commands database.
+	// snap name to the commands database. Snaps without commands are
+	// only added to the searchable catalog.
 	AddSnap(snapName, version, summary string, commands []string) error
 	// Commit persist the changes, and closes the database. If the
 	// database has already been committed/rollbacked, does nothing.
@@ -79,6 +86,9 @@ func Create() (CommandDB, error) {
 		if err == nil {
 			t.pkgBucket, err = t.tx.CreateBucket(pkgBucketKey)
 		}
+		if err == nil {
+			t.catalogBucket, err = t.tx.CreateBucket(catalogBucketKey)
+		}
 
 		if err != nil {
 			t.tx.Rollback()
@@ -124,7 +134,11 @@ func (t *writer) AddSnap(snapName, version, summary string, commands []string) e
 	if err != nil {
 		return err
 	}
-	if err := t.pkgBucket.Put([]byte(snapName), bj); err != nil {
+	bucket := t.pkgBucket
+	if len(commands) == 0 {
+		bucket = t.catalogBucket
+	}
+	if err := bucket.Put([]byte(snapName), bj); err != nil {
 		return err
 	}
 
@@ -168,6 +182,7 @@ func (t *writer) done(commit bool) error {
 
 	t.cmdBucket = nil
 	t.pkgBucket = nil
+	t.catalogBucket = nil
 	if t.tx != nil {
 		if commit {
 			e1 = t.tx.Commit()
@@ -298,3 +313,58 @@ func (f *boltFinder) FindPackage(pkgName string) (*Package, error) {
 
 	return &Package{Snap: pkgName, Version: si.Version, Summary: si.Summary}, nil
 }
+
+func packageMatches(pkg *Package, words []string, prefix bool) bool {
+	if prefix {
+		return strings.HasPrefix(pkg.Snap, words[0])
+	}
+	name := strings.ToLower(pkg.Snap)
+	summary := strings.ToLower(pkg.Summary)
+	for _, w := range words {
+		if !strings.Contains(name, w) && !strings.Contains(summary, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *boltFinder) SearchPackages(query string, prefix bool) ([]Package, error) {
+	var words []string
+	if prefix {
+		words = []string{query}
+	} else {
+		words = strings.Fields(strings.ToLower(query))
+	}
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	tx, err := f.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var pkgs []Package
+	for _, key := range [][]byte{pkgBucketKey, catalogBucketKey} {
+		// the catalog-only bucket is missing from databases
+		// written by older versions
+		b := tx.Bucket(key)
+		if b == nil {
+			continue
+		}
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var pkg Package
+			if err := json.Unmarshal(v, &pkg); err != nil {
+				return nil, err
+			}
+			pkg.Snap = string(k)
+			if packageMatches(&pkg, words, prefix) {
+				pkgs = append(pkgs, pkg)
+			}
+		}
+	}
+
+	return pkgs, nil
+}
@@ -46,6 +46,7 @@ func (s *cmdfinderSuite) SetUpTest(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(db.AddSnap("foo", "1.0", "foo summary", []string{"foo", "meh"}), IsNil)
 	c.Assert(db.AddSnap("bar", "2.0", "bar summary", []string{"bar", "meh"}), IsNil)
+	c.Assert(db.AddSnap("qux", "3.0", "content only", nil), IsNil)
 	c.Assert(db.Commit(), IsNil)
 }
 
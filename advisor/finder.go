@@ -24,6 +24,7 @@ var newFinder = Open
 type Finder interface {
 	FindCommand(command string) ([]Command, error)
 	FindPackage(pkgName string) (*Package, error)
+	SearchPackages(query string, prefix bool) ([]Package, error)
 	Close() error
 }
 
@@ -41,3 +41,17 @@ func FindPackage(pkgName string) (*Package, error) {
 
 	return finder.FindPackage(pkgName)
 }
+
+// SearchPackages returns the packages in the local catalog whose name or
+// summary contain all the words of the query, or whose name starts with
+// the query if prefix is set. It returns an error satisfying
+// os.IsNotExist if there is no local catalog.
+func SearchPackages(query string, prefix bool) ([]Package, error) {
+	finder, err := newFinder()
+	if err != nil {
+		return nil, err
+	}
+	defer finder.Close()
+
+	return finder.SearchPackages(query, prefix)
+}
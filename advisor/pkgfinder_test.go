@@ -37,4 +37,52 @@ func (s *cmdfinderSuite) TestFindPackageMiss(c *C) {
 	pkg, err := advisor.FindPackage("moh")
 	c.Assert(err, IsNil)
 	c.Check(pkg, IsNil)
+
+	// snaps without commands are not offered
+	pkg, err = advisor.FindPackage("qux")
+	c.Assert(err, IsNil)
+	c.Check(pkg, IsNil)
+}
+
+func (s *cmdfinderSuite) TestSearchPackages(c *C) {
+	pkgs, err := advisor.SearchPackages("Foo", false)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, DeepEquals, []advisor.Package{
+		{Snap: "foo", Version: "1.0", Summary: "foo summary"},
+	})
+
+	// all words must match the name or the summary
+	pkgs, err = advisor.SearchPackages("summary ba", false)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, DeepEquals, []advisor.Package{
+		{Snap: "bar", Version: "2.0", Summary: "bar summary"},
+	})
+
+	pkgs, err = advisor.SearchPackages("summary", false)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, HasLen, 2)
+
+	pkgs, err = advisor.SearchPackages("moh", false)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, HasLen, 0)
+
+	// snaps without commands are found too
+	pkgs, err = advisor.SearchPackages("content", false)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, DeepEquals, []advisor.Package{
+		{Snap: "qux", Version: "3.0", Summary: "content only"},
+	})
+}
+
+func (s *cmdfinderSuite) TestSearchPackagesPrefix(c *C) {
+	pkgs, err := advisor.SearchPackages("fo", true)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, DeepEquals, []advisor.Package{
+		{Snap: "foo", Version: "1.0", Summary: "foo summary"},
+	})
+
+	// the summary is not considered
+	pkgs, err = advisor.SearchPackages("summ", true)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, HasLen, 0)
 }
@@ -449,3 +449,109 @@ func (v *ValidationSets) CheckInstalledSnaps(snaps []*InstalledSnap) error {
 	}
 	return nil
 }
+
+// Keys returns the sorted keys of the validation sets in the combination.
+func (v *ValidationSets) Keys() []string {
+	keys := make([]string, 0, len(v.sets))
+	for k := range v.sets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *ValidationSets) constraintsForSnap(snapRef naming.SnapRef) *snapContraints {
+	if cstrs := v.snaps[snapRef.ID()]; cstrs != nil {
+		return cstrs
+	}
+	// snap names are unique, fall back to them for references without
+	// or with a not yet known snap-id
+	for _, cstrs := range v.snaps {
+		if cstrs.name == snapRef.SnapName() {
+			return cstrs
+		}
+	}
+	return nil
+}
+
+func (c *snapContraints) keys(presence asserts.Presence) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, revCstr := range c.revisions {
+		for _, rc := range revCstr {
+			if seen[rc.validationSetKey] || (presence != "" && rc.Presence != presence) {
+				continue
+			}
+			seen[rc.validationSetKey] = true
+			keys = append(keys, rc.validationSetKey)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Revision returns the revision the given snap must be at when installed
+// according to the combination, or an unset revision if any revision is
+// fine, together with the sorted keys of the validation sets constraining
+// its revision. It returns an error if the validation sets are in
+// conflict about the snap.
+func (v *ValidationSets) Revision(snapRef naming.SnapRef) (snap.Revision, []string, error) {
+	cstrs := v.constraintsForSnap(snapRef)
+	if cstrs == nil || cstrs.presence == asserts.PresenceInvalid {
+		return snap.Revision{}, nil, nil
+	}
+	if err := cstrs.conflict(); err != nil {
+		return snap.Revision{}, nil, err
+	}
+	for rev, revCstr := range cstrs.revisions {
+		if rev.N < 1 {
+			continue
+		}
+		keys := make([]string, 0, len(revCstr))
+		for _, rc := range revCstr {
+			keys = append(keys, rc.validationSetKey)
+		}
+		sort.Strings(keys)
+		return rev, keys, nil
+	}
+	return snap.Revision{}, nil, nil
+}
+
+// CheckPresenceRequired returns the sorted keys of the validation sets
+// requiring the given snap to be installed, or nil if it is not required.
+// It returns an error if the validation sets are in conflict about the
+// snap.
+func (v *ValidationSets) CheckPresenceRequired(snapRef naming.SnapRef) ([]string, error) {
+	cstrs := v.constraintsForSnap(snapRef)
+	if cstrs == nil {
+		return nil, nil
+	}
+	if err := cstrs.conflict(); err != nil {
+		return nil, err
+	}
+	if cstrs.presence != asserts.PresenceRequired {
+		return nil, nil
+	}
+	return cstrs.keys(asserts.PresenceRequired), nil
+}
+
+// CheckPresenceInvalid returns the sorted keys of the validation sets
+// making the given snap invalid, or nil if the snap is not invalid. It
+// returns an error if the validation sets are in conflict about the snap.
+func (v *ValidationSets) CheckPresenceInvalid(snapRef naming.SnapRef) ([]string, error) {
+	cstrs := v.constraintsForSnap(snapRef)
+	if cstrs == nil {
+		return nil, nil
+	}
+	if err := cstrs.conflict(); err != nil {
+		return nil, err
+	}
+	if cstrs.presence != asserts.PresenceInvalid {
+		return nil, nil
+	}
+	if keys := cstrs.keys(asserts.PresenceInvalid); len(keys) != 0 {
+		return keys, nil
+	}
+	// optional at different revisions
+	return cstrs.keys(""), nil
+}
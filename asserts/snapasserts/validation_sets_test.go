@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
 )
 
 type validationSetsSuite struct{}
@@ -652,3 +653,129 @@ func (s *validationSetsSuite) TestSortByRevision(c *C) {
 	sort.Sort(snapasserts.ByRevision(revs))
 	c.Assert(revs, DeepEquals, []snap.Revision{snap.R(-1), snap.R(4), snap.R(5), snap.R(10)})
 }
+
+func (s *validationSetsSuite) TestSnapConstraints(c *C) {
+	valset1 := assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "acme",
+		"series":       "16",
+		"account-id":   "acme",
+		"name":         "one",
+		"sequence":     "1",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":     "snap-a",
+				"id":       "mysnapaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"presence": "required",
+				"revision": "7",
+			},
+			map[string]interface{}{
+				"name":     "snap-b",
+				"id":       "mysnapbbbbbbbbbbbbbbbbbbbbbbbbbb",
+				"presence": "invalid",
+			},
+			map[string]interface{}{
+				"name":     "snap-c",
+				"id":       "mysnapcccccccccccccccccccccccccc",
+				"presence": "optional",
+				"revision": "3",
+			},
+		},
+	}).(*asserts.ValidationSet)
+	valset2 := assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "acme",
+		"series":       "16",
+		"account-id":   "acme",
+		"name":         "two",
+		"sequence":     "1",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":     "snap-a",
+				"id":       "mysnapaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"presence": "required",
+			},
+			map[string]interface{}{
+				"name":     "snap-d",
+				"id":       "mysnapdddddddddddddddddddddddddd",
+				"presence": "required",
+				"revision": "1",
+			},
+		},
+	}).(*asserts.ValidationSet)
+	valset3 := assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "acme",
+		"series":       "16",
+		"account-id":   "acme",
+		"name":         "three",
+		"sequence":     "1",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":     "snap-d",
+				"id":       "mysnapdddddddddddddddddddddddddd",
+				"presence": "required",
+				"revision": "2",
+			},
+		},
+	}).(*asserts.ValidationSet)
+
+	valsets := snapasserts.NewValidationSets()
+	c.Assert(valsets.Add(valset1), IsNil)
+	c.Assert(valsets.Add(valset2), IsNil)
+	c.Assert(valsets.Add(valset3), IsNil)
+	c.Check(valsets.Keys(), DeepEquals, []string{"acme/one", "acme/three", "acme/two"})
+
+	snapA := naming.NewSnapRef("snap-a", "mysnapaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	// snap names are enough
+	snapB := naming.Snap("snap-b")
+	snapC := naming.NewSnapRef("snap-c", "mysnapcccccccccccccccccccccccccc")
+	snapD := naming.NewSnapRef("snap-d", "mysnapdddddddddddddddddddddddddd")
+	other := naming.Snap("other")
+
+	keys, err := valsets.CheckPresenceRequired(snapA)
+	c.Assert(err, IsNil)
+	c.Check(keys, DeepEquals, []string{"acme/one", "acme/two"})
+	rev, keys, err := valsets.Revision(snapA)
+	c.Assert(err, IsNil)
+	c.Check(rev, Equals, snap.R(7))
+	c.Check(keys, DeepEquals, []string{"acme/one"})
+	keys, err = valsets.CheckPresenceInvalid(snapA)
+	c.Assert(err, IsNil)
+	c.Check(keys, IsNil)
+
+	keys, err = valsets.CheckPresenceInvalid(snapB)
+	c.Assert(err, IsNil)
+	c.Check(keys, DeepEquals, []string{"acme/one"})
+	keys, err = valsets.CheckPresenceRequired(snapB)
+	c.Assert(err, IsNil)
+	c.Check(keys, IsNil)
+	rev, _, err = valsets.Revision(snapB)
+	c.Assert(err, IsNil)
+	c.Check(rev.Unset(), Equals, true)
+
+	keys, err = valsets.CheckPresenceRequired(snapC)
+	c.Assert(err, IsNil)
+	c.Check(keys, IsNil)
+	rev, keys, err = valsets.Revision(snapC)
+	c.Assert(err, IsNil)
+	c.Check(rev, Equals, snap.R(3))
+	c.Check(keys, DeepEquals, []string{"acme/one"})
+
+	// unconstrained snap
+	keys, err = valsets.CheckPresenceRequired(other)
+	c.Assert(err, IsNil)
+	c.Check(keys, IsNil)
+	keys, err = valsets.CheckPresenceInvalid(other)
+	c.Assert(err, IsNil)
+	c.Check(keys, IsNil)
+	rev, _, err = valsets.Revision(other)
+	c.Assert(err, IsNil)
+	c.Check(rev.Unset(), Equals, true)
+
+	// conflicts are reported
+	_, err = valsets.CheckPresenceRequired(snapD)
+	c.Check(err, ErrorMatches, `cannot constrain snap "snap-d" at different revisions 1 \(acme/two\), 2 \(acme/three\)`)
+	_, _, err = valsets.Revision(snapD)
+	c.Check(err, ErrorMatches, `cannot constrain snap "snap-d" at different revisions .*`)
+}
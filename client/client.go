@@ -633,6 +633,10 @@ type RefreshInfo struct {
 	Last     string `json:"last,omitempty"`
 	Hold     string `json:"hold,omitempty"`
 	Next     string `json:"next,omitempty"`
+	// Deferred is the time of the last auto-refresh attempt that was
+	// deferred, with DeferReason explaining why.
+	Deferred    string `json:"deferred,omitempty"`
+	DeferReason string `json:"defer-reason,omitempty"`
 }
 
 // SysInfo holds system information
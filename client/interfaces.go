@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Plug represents the potential of a given snap to connect to a slot.
@@ -75,6 +76,9 @@ type InterfaceAction struct {
 	Forget bool   `json:"forget,omitempty"`
 	Plugs  []Plug `json:"plugs,omitempty"`
 	Slots  []Slot `json:"slots,omitempty"`
+	// ExpiresAt is when a connection made by a connect action is
+	// disconnected automatically.
+	ExpiresAt *time.Time `json:"expires-at,omitempty"`
 }
 
 // InterfaceOptions represents opt-in elements include in responses.
@@ -86,6 +90,13 @@ type InterfaceOptions struct {
 	Connected bool
 }
 
+// ConnectOptions represents extra options for connect op
+type ConnectOptions struct {
+	// ExpiresAt is when the connection is disconnected
+	// automatically, if set.
+	ExpiresAt time.Time
+}
+
 // DisconnectOptions represents extra options for disconnect op
 type DisconnectOptions struct {
 	Forget bool
@@ -131,11 +142,21 @@ func (client *Client) performInterfaceAction(sa *InterfaceAction) (changeID stri
 // Connect establishes a connection between a plug and a slot.
 // The plug and the slot must have the same interface.
 func (client *Client) Connect(plugSnapName, plugName, slotSnapName, slotName string) (changeID string, err error) {
-	return client.performInterfaceAction(&InterfaceAction{
+	return client.ConnectWithOptions(plugSnapName, plugName, slotSnapName, slotName, nil)
+}
+
+// ConnectWithOptions establishes a connection between a plug and a slot,
+// like Connect, with the given options.
+func (client *Client) ConnectWithOptions(plugSnapName, plugName, slotSnapName, slotName string, opts *ConnectOptions) (changeID string, err error) {
+	action := &InterfaceAction{
 		Action: "connect",
 		Plugs:  []Plug{{Snap: plugSnapName, Name: plugName}},
 		Slots:  []Slot{{Snap: slotSnapName, Name: slotName}},
-	})
+	}
+	if opts != nil && !opts.ExpiresAt.IsZero() {
+		action.ExpiresAt = &opts.ExpiresAt
+	}
+	return client.performInterfaceAction(action)
 }
 
 // Disconnect breaks the connection between a plug and a slot.
@@ -80,6 +80,29 @@ type Snap struct {
 	Tracks []string `json:"tracks,omitempty"`
 
 	Health *SnapHealth `json:"health,omitempty"`
+
+	// Hold is set when the administrator holds the auto-refreshes of
+	// the snap, it is either "forever" or the RFC3339 time the hold ends.
+	Hold string `json:"hold,omitempty"`
+	// RefreshTimer is the schedule the administrator limited the
+	// auto-refreshes of the snap to.
+	RefreshTimer string `json:"refresh-timer,omitempty"`
+	// TrackingPolicy is set when the administrator made the snap follow
+	// a channel other than the one it tracks.
+	TrackingPolicy *TrackingPolicy `json:"tracking-policy,omitempty"`
+}
+
+// TrackingPolicy describes the channel the refreshes of a snap, including
+// auto-refreshes, take it from.
+type TrackingPolicy struct {
+	// Risk is the risk level followed.
+	Risk string `json:"risk"`
+	// Track pins the track followed, otherwise the track of the tracked
+	// channel is kept.
+	Track string `json:"track,omitempty"`
+	// MinAge is for how long a revision must have been released to the
+	// followed channel before it is taken, as a Go duration.
+	MinAge string `json:"min-age,omitempty"`
 }
 
 type SnapHealth struct {
@@ -125,6 +148,10 @@ const (
 
 type ResultInfo struct {
 	SuggestedCurrency string `json:"suggested-currency"`
+	// CatalogTimestamp is set when the results were served from the
+	// local catalog because the store could not be reached, and is the
+	// time that catalog was last refreshed.
+	CatalogTimestamp *time.Time `json:"catalog-timestamp,omitempty"`
 }
 
 // FindOptions supports exactly one of the following options:
@@ -268,3 +295,83 @@ func (client *Client) Snap(name string) (*Snap, *ResultInfo, error) {
 	}
 	return snap, ri, nil
 }
+
+// RefreshHistoryEntry records a refresh or a revert of a snap.
+type RefreshHistoryEntry struct {
+	FromRevision snap.Revision `json:"from-revision"`
+	ToRevision   snap.Revision `json:"to-revision"`
+	Channel      string        `json:"channel,omitempty"`
+	Time         time.Time     `json:"time"`
+	ChangeID     string        `json:"change-id,omitempty"`
+	// Trigger is what caused the refresh, one of "manual", "auto",
+	// "validation-set" or "remodel".
+	Trigger string `json:"trigger"`
+	// Revert is set if the snap was reverted to a revision it had
+	// before.
+	Revert bool `json:"revert,omitempty"`
+	// Outcome is "refreshed" or "reverted" if the refresh was undone.
+	Outcome string `json:"outcome"`
+}
+
+// SnapRefreshHistory returns the recorded refreshes and reverts of the
+// snap with the given name, oldest first.
+func (client *Client) SnapRefreshHistory(name string) ([]*RefreshHistoryEntry, error) {
+	var history []*RefreshHistoryEntry
+	path := fmt.Sprintf("/v2/snaps/%s/history", name)
+	if _, err := client.doSync("GET", path, nil, nil, nil, &history); err != nil {
+		fmt := "cannot retrieve refresh history of snap %q: %w"
+		return nil, xerrors.Errorf(fmt, name, err)
+	}
+	return history, nil
+}
+
+// Denial is an access of a snap denied by its sandbox.
+type Denial struct {
+	// Time is when the denial was last logged.
+	Time time.Time `json:"time"`
+	// Count is how many times the same access was denied.
+	Count int `json:"count"`
+	// Sandbox is "apparmor" or "seccomp".
+	Sandbox string `json:"sandbox"`
+	Snap    string `json:"snap"`
+	// Tag is the security tag of the app or hook denied the access.
+	Tag  string `json:"tag,omitempty"`
+	Comm string `json:"comm,omitempty"`
+
+	// Operation, Name, RequestedMask and DeniedMask describe accesses
+	// denied by apparmor.
+	Operation     string `json:"operation,omitempty"`
+	Name          string `json:"name,omitempty"`
+	RequestedMask string `json:"requested-mask,omitempty"`
+	DeniedMask    string `json:"denied-mask,omitempty"`
+	// Syscall and Arch describe system calls denied by seccomp.
+	Syscall string `json:"syscall,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+
+	// SuggestedInterfaces are the interfaces that would have allowed
+	// the access if a plug of the snap was connected.
+	SuggestedInterfaces []string `json:"suggested-interfaces,omitempty"`
+}
+
+// SnapDenials returns the recent accesses of the snap with the given name
+// denied by its sandbox, oldest first.
+func (client *Client) SnapDenials(name string) ([]*Denial, error) {
+	var denials []*Denial
+	path := fmt.Sprintf("/v2/snaps/%s/denials", name)
+	if _, err := client.doSync("GET", path, nil, nil, nil, &denials); err != nil {
+		fmt := "cannot retrieve sandbox denials of snap %q: %w"
+		return nil, xerrors.Errorf(fmt, name, err)
+	}
+	return denials, nil
+}
+
+// UpdateSnapEgress asks snapd to update the egress ruleset of the snap with
+// the given name with the cgroups its applications are tracked in.
+func (client *Client) UpdateSnapEgress(name string) error {
+	path := fmt.Sprintf("/v2/snaps/%s/egress", name)
+	if _, err := client.doSync("POST", path, nil, nil, nil, nil); err != nil {
+		fmt := "cannot update egress ruleset of snap %q: %w"
+		return xerrors.Errorf(fmt, name, err)
+	}
+	return nil
+}
@@ -405,3 +405,133 @@ func (cs *clientSuite) TestClientFindFromPathErrIsWrapped(c *check.C) {
 	_, err = cs.cli.List([]string{"snap"}, nil)
 	c.Assert(xerrors.As(err, &e), check.Equals, true)
 }
+
+func (cs *clientSuite) TestClientSnapRefreshHistory(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [{
+			"from-revision": "1",
+			"to-revision": "2",
+			"channel": "latest/stable",
+			"time": "2021-06-01T10:00:00Z",
+			"change-id": "42",
+			"trigger": "auto",
+			"outcome": "refreshed"
+		}, {
+			"from-revision": "2",
+			"to-revision": "1",
+			"time": "2021-06-02T10:00:00Z",
+			"change-id": "43",
+			"trigger": "manual",
+			"revert": true,
+			"outcome": "refreshed"
+		}]
+	}`
+	history, err := cs.cli.SnapRefreshHistory("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo/history")
+	c.Check(history, check.DeepEquals, []*client.RefreshHistoryEntry{
+		{
+			FromRevision: snap.R(1),
+			ToRevision:   snap.R(2),
+			Channel:      "latest/stable",
+			Time:         time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+			ChangeID:     "42",
+			Trigger:      "auto",
+			Outcome:      "refreshed",
+		}, {
+			FromRevision: snap.R(2),
+			ToRevision:   snap.R(1),
+			Time:         time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC),
+			ChangeID:     "43",
+			Trigger:      "manual",
+			Revert:       true,
+			Outcome:      "refreshed",
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientSnapRefreshHistoryError(c *check.C) {
+	cs.err = errors.New("boom")
+	_, err := cs.cli.SnapRefreshHistory("foo")
+	c.Check(err, check.ErrorMatches, `cannot retrieve refresh history of snap "foo": .*boom`)
+	var e xerrors.Wrapper
+	c.Assert(err, check.Implements, &e)
+}
+
+func (cs *clientSuite) TestClientSnapDenials(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [{
+			"time": "2021-03-01T10:00:01Z",
+			"count": 2,
+			"sandbox": "apparmor",
+			"snap": "foo",
+			"tag": "snap.foo.app",
+			"comm": "app",
+			"operation": "open",
+			"name": "/etc/foo",
+			"requested-mask": "r",
+			"denied-mask": "r",
+			"suggested-interfaces": ["system-files"]
+		}, {
+			"time": "2021-03-01T10:00:02Z",
+			"count": 1,
+			"sandbox": "seccomp",
+			"snap": "foo",
+			"tag": "snap.foo.app",
+			"syscall": "165",
+			"arch": "c000003e"
+		}]
+	}`
+	denials, err := cs.cli.SnapDenials("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo/denials")
+	c.Check(denials, check.DeepEquals, []*client.Denial{
+		{
+			Time:                time.Date(2021, 3, 1, 10, 0, 1, 0, time.UTC),
+			Count:               2,
+			Sandbox:             "apparmor",
+			Snap:                "foo",
+			Tag:                 "snap.foo.app",
+			Comm:                "app",
+			Operation:           "open",
+			Name:                "/etc/foo",
+			RequestedMask:       "r",
+			DeniedMask:          "r",
+			SuggestedInterfaces: []string{"system-files"},
+		}, {
+			Time:    time.Date(2021, 3, 1, 10, 0, 2, 0, time.UTC),
+			Count:   1,
+			Sandbox: "seccomp",
+			Snap:    "foo",
+			Tag:     "snap.foo.app",
+			Syscall: "165",
+			Arch:    "c000003e",
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientSnapDenialsError(c *check.C) {
+	cs.err = errors.New("boom")
+	_, err := cs.cli.SnapDenials("foo")
+	c.Check(err, check.ErrorMatches, `cannot retrieve sandbox denials of snap "foo": .*boom`)
+	var e xerrors.Wrapper
+	c.Assert(err, check.Implements, &e)
+}
+
+func (cs *clientSuite) TestClientUpdateSnapEgress(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": null}`
+	err := cs.cli.UpdateSnapEgress("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo/egress")
+}
+
+func (cs *clientSuite) TestClientUpdateSnapEgressError(c *check.C) {
+	cs.err = errors.New("boom")
+	err := cs.cli.UpdateSnapEgress("foo")
+	c.Check(err, check.ErrorMatches, `cannot update egress ruleset of snap "foo": .*boom`)
+}
@@ -0,0 +1,114 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Prompt is a file access of a snap waiting for the user to allow or deny
+// it.
+type Prompt struct {
+	ID          string    `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	User        int       `json:"user"`
+	Snap        string    `json:"snap"`
+	Path        string    `json:"path"`
+	Permissions []string  `json:"permissions"`
+}
+
+// PromptReply is the decision of a user about a prompt, applying to the
+// paths matching the apparmor glob of PathPattern.
+type PromptReply struct {
+	// Outcome is "allow" or "deny".
+	Outcome string `json:"outcome"`
+	// Lifespan is "once" or "always".
+	Lifespan    string   `json:"lifespan"`
+	PathPattern string   `json:"path-pattern"`
+	Permissions []string `json:"permissions"`
+}
+
+// PromptingRule is the decision of a user about the accesses of a snap.
+type PromptingRule struct {
+	ID          string     `json:"id"`
+	Timestamp   time.Time  `json:"timestamp"`
+	User        int        `json:"user"`
+	Snap        string     `json:"snap"`
+	PathPattern string     `json:"path-pattern"`
+	Permissions []string   `json:"permissions"`
+	Outcome     string     `json:"outcome"`
+	Lifespan    string     `json:"lifespan"`
+	Expiration  *time.Time `json:"expiration,omitempty"`
+}
+
+// Prompts returns the prompts waiting for a reply of the user.
+func (client *Client) Prompts() ([]*Prompt, error) {
+	var prompts []*Prompt
+	if _, err := client.doSync("GET", "/v2/prompting/prompts", nil, nil, nil, &prompts); err != nil {
+		return nil, xerrors.Errorf("cannot retrieve prompts: %w", err)
+	}
+	return prompts, nil
+}
+
+// ReplyToPrompt sends the decision of the user about the prompt with the
+// given ID, and returns the resulting prompting rule.
+func (client *Client) ReplyToPrompt(id string, reply *PromptReply) (*PromptingRule, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(reply); err != nil {
+		return nil, err
+	}
+	var rule PromptingRule
+	path := fmt.Sprintf("/v2/prompting/prompts/%s", id)
+	if _, err := client.doSync("POST", path, nil, nil, &body, &rule); err != nil {
+		return nil, xerrors.Errorf("cannot reply to prompt %s: %w", id, err)
+	}
+	return &rule, nil
+}
+
+// PromptingRules returns the prompting rules of the user.
+func (client *Client) PromptingRules() ([]*PromptingRule, error) {
+	var rules []*PromptingRule
+	if _, err := client.doSync("GET", "/v2/prompting/rules", nil, nil, nil, &rules); err != nil {
+		return nil, xerrors.Errorf("cannot retrieve prompting rules: %w", err)
+	}
+	return rules, nil
+}
+
+type promptingRuleAction struct {
+	Action string `json:"action"`
+}
+
+// RevokePromptingRule removes the prompting rule with the given ID.
+func (client *Client) RevokePromptingRule(id string) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&promptingRuleAction{Action: "revoke"}); err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/v2/prompting/rules/%s", id)
+	if _, err := client.doSync("POST", path, nil, nil, &body, nil); err != nil {
+		return xerrors.Errorf("cannot revoke prompting rule %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientPrompts(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": [{
+		"id": "1",
+		"timestamp": "2021-06-01T10:00:00Z",
+		"user": 1000,
+		"snap": "foo",
+		"path": "/home/alice/doc.txt",
+		"permissions": ["read"]
+	}]}`
+	prompts, err := cs.cli.Prompts()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/prompting/prompts")
+	c.Check(prompts, check.DeepEquals, []*client.Prompt{{
+		ID:          "1",
+		Timestamp:   time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		User:        1000,
+		Snap:        "foo",
+		Path:        "/home/alice/doc.txt",
+		Permissions: []string{"read"},
+	}})
+}
+
+func (cs *clientSuite) TestClientPromptsError(c *check.C) {
+	cs.err = errors.New("boom")
+	_, err := cs.cli.Prompts()
+	c.Check(err, check.ErrorMatches, `cannot retrieve prompts: .*boom`)
+}
+
+func (cs *clientSuite) TestClientReplyToPrompt(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {
+		"id": "2",
+		"timestamp": "2021-06-01T10:00:00Z",
+		"user": 1000,
+		"snap": "foo",
+		"path-pattern": "/home/alice/**",
+		"permissions": ["read"],
+		"outcome": "allow",
+		"lifespan": "always"
+	}}`
+	rule, err := cs.cli.ReplyToPrompt("1", &client.PromptReply{
+		Outcome:     "allow",
+		Lifespan:    "always",
+		PathPattern: "/home/alice/**",
+		Permissions: []string{"read"},
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/prompting/prompts/1")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"outcome":      "allow",
+		"lifespan":     "always",
+		"path-pattern": "/home/alice/**",
+		"permissions":  []interface{}{"read"},
+	})
+	c.Check(rule, check.DeepEquals, &client.PromptingRule{
+		ID:          "2",
+		Timestamp:   time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		User:        1000,
+		Snap:        "foo",
+		PathPattern: "/home/alice/**",
+		Permissions: []string{"read"},
+		Outcome:     "allow",
+		Lifespan:    "always",
+	})
+}
+
+func (cs *clientSuite) TestClientReplyToPromptError(c *check.C) {
+	cs.rsp = `{"type": "error", "status-code": 400, "result": {"message": "invalid outcome \"maybe\""}}`
+	_, err := cs.cli.ReplyToPrompt("1", &client.PromptReply{Outcome: "maybe"})
+	c.Check(err, check.ErrorMatches, `cannot reply to prompt 1: invalid outcome "maybe"`)
+}
+
+func (cs *clientSuite) TestClientPromptingRules(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": [{
+		"id": "2",
+		"timestamp": "2021-06-01T10:00:00Z",
+		"user": 1000,
+		"snap": "foo",
+		"path-pattern": "/home/alice/doc.txt",
+		"permissions": ["write"],
+		"outcome": "deny",
+		"lifespan": "once",
+		"expiration": "2021-06-01T10:05:00Z"
+	}]}`
+	rules, err := cs.cli.PromptingRules()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/prompting/rules")
+	expiration := time.Date(2021, 6, 1, 10, 5, 0, 0, time.UTC)
+	c.Check(rules, check.DeepEquals, []*client.PromptingRule{{
+		ID:          "2",
+		Timestamp:   time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		User:        1000,
+		Snap:        "foo",
+		PathPattern: "/home/alice/doc.txt",
+		Permissions: []string{"write"},
+		Outcome:     "deny",
+		Lifespan:    "once",
+		Expiration:  &expiration,
+	}})
+}
+
+func (cs *clientSuite) TestClientRevokePromptingRule(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": null}`
+	err := cs.cli.RevokePromptingRule("2")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/prompting/rules/2")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{"action": "revoke"})
+
+	cs.err = errors.New("boom")
+	err = cs.cli.RevokePromptingRule("2")
+	c.Check(err, check.ErrorMatches, `cannot revoke prompting rule 2: .*boom`)
+}
@@ -46,6 +46,14 @@ type SnapOptions struct {
 	Amend            bool   `json:"amend,omitempty"`
 
 	Users []string `json:"users,omitempty"`
+
+	// Time and RefreshTimer are only used when holding refreshes, see
+	// HoldRefreshes.
+	Time         string `json:"time,omitempty"`
+	RefreshTimer string `json:"refresh-timer,omitempty"`
+	// TrackingPolicy is only used when setting tracking policies, see
+	// SetTrackingPolicy.
+	TrackingPolicy *TrackingPolicy `json:"tracking-policy,omitempty"`
 }
 
 func writeFieldBool(mw *multipart.Writer, key string, val bool) error {
@@ -89,9 +97,13 @@ type actionData struct {
 }
 
 type multiActionData struct {
-	Action string   `json:"action"`
-	Snaps  []string `json:"snaps,omitempty"`
-	Users  []string `json:"users,omitempty"`
+	Action       string   `json:"action"`
+	Snaps        []string `json:"snaps,omitempty"`
+	Users        []string `json:"users,omitempty"`
+	Time         string   `json:"time,omitempty"`
+	RefreshTimer string   `json:"refresh-timer,omitempty"`
+
+	TrackingPolicy *TrackingPolicy `json:"tracking-policy,omitempty"`
 }
 
 // Install adds the snap with the given name from the given channel (or
@@ -141,6 +153,39 @@ func (client *Client) Switch(name string, options *SnapOptions) (changeID string
 	return client.doSnapAction("switch", name, options)
 }
 
+// HoldRefreshes holds the auto-refreshes of the given snaps until
+// options.Time, which is either "forever" or in RFC3339 format, and/or
+// limits them to the windows of options.RefreshTimer.
+func (client *Client) HoldRefreshes(names []string, options *SnapOptions) (changeID string, err error) {
+	if options == nil || (options.Time == "" && options.RefreshTimer == "") {
+		return "", fmt.Errorf("cannot hold refreshes without a time or a refresh timer")
+	}
+	_, changeID, err = client.doMultiSnapActionFull("hold", names, options)
+	return changeID, err
+}
+
+// UnholdRefreshes removes the holds and the refresh timers of the
+// auto-refreshes of the given snaps.
+func (client *Client) UnholdRefreshes(names []string) (changeID string, err error) {
+	return client.doMultiSnapAction("unhold", names, nil)
+}
+
+// SetTrackingPolicy makes the refreshes of the given snaps, including
+// auto-refreshes, follow the channel described by the policy.
+func (client *Client) SetTrackingPolicy(names []string, policy *TrackingPolicy) (changeID string, err error) {
+	if policy == nil || policy.Risk == "" {
+		return "", fmt.Errorf("cannot set a tracking policy without a risk level")
+	}
+	_, changeID, err = client.doMultiSnapActionFull("follow", names, &SnapOptions{TrackingPolicy: policy})
+	return changeID, err
+}
+
+// RemoveTrackingPolicy removes the tracking policies of the given snaps,
+// they keep tracking the channel they were last refreshed from.
+func (client *Client) RemoveTrackingPolicy(names []string) (changeID string, err error) {
+	return client.doMultiSnapAction("unfollow", names, nil)
+}
+
 // SnapshotMany snapshots many snaps (all, if names empty) for many users (all, if users is empty).
 func (client *Client) SnapshotMany(names []string, users []string) (setID uint64, changeID string, err error) {
 	result, changeID, err := client.doMultiSnapActionFull("snapshot", names, &SnapOptions{Users: users})
@@ -198,6 +243,9 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, o
 	}
 	if options != nil {
 		action.Users = options.Users
+		action.Time = options.Time
+		action.RefreshTimer = options.RefreshTimer
+		action.TrackingPolicy = options.TrackingPolicy
 	}
 	data, err := json.Marshal(&action)
 	if err != nil {
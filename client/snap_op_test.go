@@ -212,6 +212,106 @@ func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 	c.Check(changeID, check.Equals, "d728")
 }
 
+func (cs *clientSuite) TestClientHoldRefreshes(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.HoldRefreshes([]string{pkgName}, &client.SnapOptions{Time: "forever", RefreshTimer: "sat,10:00-12:00"})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	c.Assert(json.Unmarshal(body, &jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action":        "hold",
+		"snaps":         []interface{}{pkgName},
+		"time":          "forever",
+		"refresh-timer": "sat,10:00-12:00",
+	})
+
+	_, err = cs.cli.HoldRefreshes([]string{pkgName}, nil)
+	c.Check(err, check.ErrorMatches, "cannot hold refreshes without a time or a refresh timer")
+}
+
+func (cs *clientSuite) TestClientUnholdRefreshes(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.UnholdRefreshes([]string{pkgName})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	c.Assert(json.Unmarshal(body, &jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action": "unhold",
+		"snaps":  []interface{}{pkgName},
+	})
+}
+
+func (cs *clientSuite) TestClientSetTrackingPolicy(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.SetTrackingPolicy([]string{pkgName}, &client.TrackingPolicy{Risk: "candidate", Track: "2.0", MinAge: "72h"})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	c.Assert(json.Unmarshal(body, &jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action": "follow",
+		"snaps":  []interface{}{pkgName},
+		"tracking-policy": map[string]interface{}{
+			"risk":    "candidate",
+			"track":   "2.0",
+			"min-age": "72h",
+		},
+	})
+
+	_, err = cs.cli.SetTrackingPolicy([]string{pkgName}, &client.TrackingPolicy{Track: "2.0"})
+	c.Check(err, check.ErrorMatches, "cannot set a tracking policy without a risk level")
+}
+
+func (cs *clientSuite) TestClientRemoveTrackingPolicy(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.RemoveTrackingPolicy([]string{pkgName})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	jsonBody := make(map[string]interface{})
+	c.Assert(json.Unmarshal(body, &jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+		"action": "unfollow",
+		"snaps":  []interface{}{pkgName},
+	})
+}
+
 func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	cs.status = 202
 	cs.rsp = `{
@@ -464,6 +564,8 @@ func (cs *clientSuite) TestSnapOptionsSerialises(c *check.C) {
 		`{"unaliased":true}`:         {Unaliased: true},
 		`{"purge":true}`:             {Purge: true},
 		`{"amend":true}`:             {Amend: true},
+		`{"time":"forever"}`:         {Time: "forever"},
+		`{"refresh-timer":"10:00"}`:  {RefreshTimer: "10:00"},
 	}
 	for expected, opts := range tests {
 		buf, err := json.Marshal(&opts)
@@ -101,6 +101,47 @@ func (client *Client) ApplyValidationSet(accountID, name string, opts *ValidateA
 	return nil
 }
 
+// EnforceValidationSet applies the given validation set identified by
+// account, name and optional sequence (if non-zero) in enforcing mode. The
+// returned change id is empty unless snaps required by the validation set
+// need to be installed.
+func (client *Client) EnforceValidationSet(accountID, name string, sequence int) (changeID string, err error) {
+	if accountID == "" || name == "" {
+		return "", xerrors.Errorf("cannot enforce validation set without account ID and name")
+	}
+
+	data := &postValidationSetData{
+		Action:   "apply",
+		Mode:     "enforce",
+		Sequence: sequence,
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("/v2/validation-sets/%s/%s", accountID, name)
+	var rsp response
+	statusCode, err := client.do("POST", path, nil, nil, &body, &rsp, nil)
+	if err == nil {
+		err = rsp.err(client, statusCode)
+	}
+	if err != nil {
+		fmt := "cannot enforce validation set: %w"
+		return "", xerrors.Errorf(fmt, err)
+	}
+	switch rsp.Type {
+	case "sync":
+		return "", nil
+	case "async":
+		if rsp.Change == "" {
+			return "", fmt.Errorf("async response without change reference")
+		}
+		return rsp.Change, nil
+	}
+	return "", fmt.Errorf("unexpected response type %q", rsp.Type)
+}
+
 // ListValidationsSets queries all validation sets.
 func (client *Client) ListValidationsSets() ([]*ValidationSetResult, error) {
 	var res []*ValidationSetResult
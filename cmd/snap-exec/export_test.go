@@ -19,6 +19,10 @@
 
 package main
 
+import (
+	"github.com/snapcore/snapd/sandbox/landlock"
+)
+
 var (
 	ExpandEnvCmdArgs = expandEnvCmdArgs
 	FindCommand      = findCommand
@@ -58,3 +62,11 @@ func MockOsReadlink(f func(string) (string, error)) func() {
 		osReadlink = realOsReadlink
 	}
 }
+
+func MockLandlockRestrict(f func(rules []landlock.Rule) error) func() {
+	realLandlockRestrict := landlockRestrict
+	landlockRestrict = f
+	return func() {
+		landlockRestrict = realLandlockRestrict
+	}
+}
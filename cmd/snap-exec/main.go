@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"syscall"
 
@@ -30,6 +31,7 @@ import (
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/sandbox/landlock"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapenv"
 )
@@ -37,6 +39,7 @@ import (
 // for the tests
 var syscallExec = syscall.Exec
 var osReadlink = os.Readlink
+var landlockRestrict = landlock.Restrict
 
 // commandline args
 var opts struct {
@@ -229,6 +232,9 @@ func execApp(snapApp, revision, command string, args []string) error {
 
 	fullCmd = append(absoluteCommandChain(app.Snap, app.CommandChain), fullCmd...)
 
+	if err := restrictFilesystem(app.SecurityTag(), env); err != nil {
+		return err
+	}
 	if err := syscallExec(fullCmd[0], fullCmd, env.ForExec()); err != nil {
 		return fmt.Errorf("cannot exec %q: %s", fullCmd[0], err)
 	}
@@ -268,5 +274,59 @@ func execHook(snapName, revision, hookName string) error {
 
 	// run the hook
 	cmd := append(absoluteCommandChain(hook.Snap, hook.CommandChain), filepath.Join(hook.Snap.HooksDir(), hook.Name))
+	if err := restrictFilesystem(hook.SecurityTag(), env); err != nil {
+		return err
+	}
 	return syscallExec(cmd[0], cmd, env.ForExec())
 }
+
+// restrictFilesystem applies the landlock profile of the given security
+// tag, if any, to the thread executing the application or hook. The
+// variables in the paths of the profile are expanded from the environment
+// of the application, the rules with unset variables are ignored, and then
+// the globs of the paths are expanded.
+func restrictFilesystem(securityTag string, env osutil.Environment) error {
+	f, err := os.Open(filepath.Join(dirs.SnapLandlockDir, securityTag))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open landlock profile: %v", err)
+	}
+	defer f.Close()
+	rules, err := landlock.ParseProfile(f)
+	if err != nil {
+		return err
+	}
+
+	expanded := make([]landlock.Rule, 0, len(rules))
+	for _, rule := range rules {
+		unset := false
+		rule.Path = os.Expand(rule.Path, func(name string) string {
+			value := env[name]
+			if value == "" {
+				unset = true
+			}
+			return value
+		})
+		if unset {
+			continue
+		}
+		expanded = append(expanded, rule)
+	}
+	expanded, err = landlock.ExpandGlobs(expanded)
+	if err != nil {
+		return err
+	}
+
+	// the ruleset applies to the thread executing the command
+	runtime.LockOSThread()
+	err = landlockRestrict(expanded)
+	if err == landlock.ErrUnsupported {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot restrict filesystem access: %v", err)
+	}
+	return nil
+}
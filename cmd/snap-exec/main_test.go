@@ -20,6 +20,7 @@
 package main_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -33,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/sandbox/landlock"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
@@ -620,3 +622,127 @@ func (s *snapExecSuite) TestSnapExecCompleteClassicNoReexec(c *C) {
 	c.Check(execEnv, testutil.Contains, "SNAP_DATA=/var/snap/snapname/42")
 	c.Check(execEnv, testutil.Contains, "TMPDIR=/var/tmp99")
 }
+
+func (s *snapExecSuite) TestSnapExecAppLandlockProfile(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	snaptest.MockSnap(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapLandlockDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapLandlockDir, "snap.snapname.app"), []byte(`# This file is automatically generated.
+rx /usr
+rw $SNAP_DATA
+rw /var/snap/${SNAP_INSTANCE_NAME}/common/foo
+rw $SNAP_LANDLOCK_UNSET/foo
+rw $SNAP_REAL_HOME/[^s.]*
+r $SNAP_REAL_HOME/bin
+`), 0644), IsNil)
+
+	home := c.MkDir()
+	for _, name := range []string{"Documents", "bin", "snap", ".ssh"} {
+		c.Assert(os.Mkdir(filepath.Join(home, name), 0755), IsNil)
+	}
+
+	os.Setenv("SNAP_DATA", "/var/snap/snapname/42")
+	defer os.Unsetenv("SNAP_DATA")
+	os.Setenv("SNAP_INSTANCE_NAME", "snapname")
+	defer os.Unsetenv("SNAP_INSTANCE_NAME")
+	os.Setenv("SNAP_REAL_HOME", home)
+	defer os.Unsetenv("SNAP_REAL_HOME")
+
+	var calls []string
+	restore := snapExec.MockLandlockRestrict(func(rules []landlock.Rule) error {
+		calls = append(calls, "restrict")
+		c.Check(rules, DeepEquals, []landlock.Rule{
+			{Access: landlock.Read | landlock.Execute, Path: "/usr"},
+			{Access: landlock.Read | landlock.Write, Path: "/var/snap/snapname/42"},
+			{Access: landlock.Read | landlock.Write, Path: "/var/snap/snapname/common/foo"},
+			// the entries of the glob, but bin
+			{Access: landlock.Read | landlock.Write, Path: filepath.Join(home, "Documents")},
+			{Access: landlock.Read, Path: filepath.Join(home, "bin")},
+		})
+		return nil
+	})
+	defer restore()
+	restore = snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		calls = append(calls, "exec")
+		return nil
+	})
+	defer restore()
+
+	err := snapExec.ExecApp("snapname.app", "42", "", nil)
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{"restrict", "exec"})
+
+	// the other apps have no profile
+	calls = nil
+	err = snapExec.ExecApp("snapname.app2", "42", "", nil)
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{"exec"})
+}
+
+func (s *snapExecSuite) TestSnapExecHookLandlockProfile(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	snaptest.MockSnap(c, string(mockHookYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapLandlockDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapLandlockDir, "snap.snapname.hook.configure"), []byte("rx /usr\n"), 0644), IsNil)
+
+	var calls []string
+	restore := snapExec.MockLandlockRestrict(func(rules []landlock.Rule) error {
+		calls = append(calls, "restrict")
+		c.Check(rules, DeepEquals, []landlock.Rule{{Access: landlock.Read | landlock.Execute, Path: "/usr"}})
+		return nil
+	})
+	defer restore()
+	restore = snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		calls = append(calls, "exec")
+		return nil
+	})
+	defer restore()
+
+	err := snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{"restrict", "exec"})
+}
+
+func (s *snapExecSuite) TestSnapExecLandlockErrors(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	snaptest.MockSnap(c, string(mockHookYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+	profile := filepath.Join(dirs.SnapLandlockDir, "snap.snapname.hook.configure")
+	c.Assert(os.MkdirAll(dirs.SnapLandlockDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(profile, []byte("rx /usr\n"), 0644), IsNil)
+
+	execed := false
+	restore := snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		execed = true
+		return nil
+	})
+	defer restore()
+
+	// kernels without landlock support run the hook unrestricted
+	restore = snapExec.MockLandlockRestrict(func(rules []landlock.Rule) error {
+		return landlock.ErrUnsupported
+	})
+	err := snapExec.ExecHook("snapname", "42", "configure")
+	restore()
+	c.Assert(err, IsNil)
+	c.Check(execed, Equals, true)
+
+	execed = false
+	restore = snapExec.MockLandlockRestrict(func(rules []landlock.Rule) error {
+		return errors.New("boom")
+	})
+	err = snapExec.ExecHook("snapname", "42", "configure")
+	restore()
+	c.Assert(err, ErrorMatches, "cannot restrict filesystem access: boom")
+	c.Check(execed, Equals, false)
+
+	c.Assert(ioutil.WriteFile(profile, []byte("rx usr\n"), 0644), IsNil)
+	err = snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, ErrorMatches, `cannot parse line 1 of landlock profile: "rx usr"`)
+	c.Check(execed, Equals, false)
+}
@@ -67,6 +67,10 @@ func (sf *sillyFinder) FindPackage(pkgName string) (*advisor.Package, error) {
 	}
 }
 
+func (sf *sillyFinder) SearchPackages(query string, prefix bool) ([]advisor.Package, error) {
+	return nil, nil
+}
+
 func (*sillyFinder) Close() error { return nil }
 
 func (s *SnapSuite) TestAdviseCommandHappyText(c *C) {
@@ -0,0 +1,167 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdConnectionPolicy struct {
+	clientMixin
+	Positionals struct {
+		PlugSpec connectPlugSpec `required:"yes"`
+		SlotSpec connectSlotSpec
+	} `positional-args:"true"`
+}
+
+var shortConnectionPolicyHelp = i18n.G("Explain the connection policy of a plug")
+var longConnectionPolicyHelp = i18n.G(`
+The connection-policy command explains which rules of the base declaration
+or of the snap declarations allow or deny the installation of the plug and of
+the slot, their connection and their auto-connection.
+
+Without a slot, all the slots of the interface of the plug are considered.
+With only a snap, the slots of that snap are considered.
+`)
+
+func init() {
+	addDebugCommand("connection-policy", shortConnectionPolicyHelp, longConnectionPolicyHelp, func() flags.Commander {
+		return &cmdConnectionPolicy{}
+	}, nil, []argDesc{
+		// TRANSLATORS: This needs to begin with < and end with >
+		{name: i18n.G("<snap>:<plug>")},
+		// TRANSLATORS: This needs to begin with < and end with >
+		{name: i18n.G("<snap>:<slot>")},
+	})
+}
+
+type policyDecision struct {
+	Allowed     bool   `json:"allowed"`
+	Declaration string `json:"declaration"`
+	Snap        string `json:"snap"`
+	Side        string `json:"side"`
+	Constraint  string `json:"constraint"`
+	Alternative int    `json:"alternative"`
+	Reason      string `json:"reason"`
+}
+
+type connectionPolicy struct {
+	Plug struct {
+		Snap string `json:"snap"`
+		Name string `json:"plug"`
+	} `json:"plug"`
+	Slot struct {
+		Snap string `json:"snap"`
+		Name string `json:"slot"`
+	} `json:"slot"`
+	PlugInstallation     *policyDecision `json:"plug-installation"`
+	SlotInstallation     *policyDecision `json:"slot-installation"`
+	Connection           *policyDecision `json:"connection"`
+	AutoConnection       *policyDecision `json:"auto-connection"`
+	InterfaceAutoConnect bool            `json:"interface-auto-connect"`
+	Unasserted           []string        `json:"unasserted"`
+}
+
+func (d *policyDecision) String() string {
+	if d.Declaration == "" {
+		return i18n.G("allowed, no rule for the interface")
+	}
+	decl := d.Declaration
+	if d.Snap != "" {
+		decl = fmt.Sprintf("%s of %q", d.Declaration, d.Snap)
+	}
+	// TRANSLATORS: the first %s is a constraint (e.g. allow-connection), the second is "plug" or "slot", the last one is the declaration
+	rule := fmt.Sprintf(i18n.G("%s of the %s rule in the %s"), d.Constraint, d.Side, decl)
+	if d.Alternative > 0 {
+		// TRANSLATORS: %d is the number of the alternative of the constraint, counting from 1
+		rule += fmt.Sprintf(i18n.G(" (alternative %d)"), d.Alternative+1)
+	}
+	switch {
+	case d.Allowed:
+		return fmt.Sprintf(i18n.G("allowed by %s"), rule)
+	case d.Alternative >= 0:
+		return fmt.Sprintf(i18n.G("denied by %s"), rule)
+	case d.Reason != "":
+		return fmt.Sprintf(i18n.G("not allowed by %s: %s"), rule, d.Reason)
+	default:
+		return fmt.Sprintf(i18n.G("not allowed by %s"), rule)
+	}
+}
+
+func (x *cmdConnectionPolicy) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	plug := x.Positionals.PlugSpec.SnapAndName
+	params := map[string]string{
+		"plug": fmt.Sprintf("%s:%s", plug.Snap, plug.Name),
+	}
+	if slot := x.Positionals.SlotSpec.SnapAndName; slot.Snap != "" || slot.Name != "" {
+		params["slot"] = slot.Snap
+		if slot.Name != "" {
+			params["slot"] = fmt.Sprintf("%s:%s", slot.Snap, slot.Name)
+		}
+	}
+
+	var policies []*connectionPolicy
+	if err := x.client.DebugGet("connection-policy", &policies, params); err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("No slots to connect plug %s to.\n"), params["plug"])
+		return nil
+	}
+
+	w := tabWriter()
+	for i, p := range policies {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "plug:\t%s:%s\n", p.Plug.Snap, p.Plug.Name)
+		fmt.Fprintf(w, "slot:\t%s:%s\n", p.Slot.Snap, p.Slot.Name)
+		if p.PlugInstallation != nil {
+			fmt.Fprintf(w, "plug-installation:\t%s\n", p.PlugInstallation)
+		}
+		if p.SlotInstallation != nil {
+			fmt.Fprintf(w, "slot-installation:\t%s\n", p.SlotInstallation)
+		}
+		if p.Connection != nil {
+			fmt.Fprintf(w, "connection:\t%s\n", p.Connection)
+		}
+		if p.AutoConnection != nil {
+			autoConnection := p.AutoConnection.String()
+			if p.AutoConnection.Allowed && !p.InterfaceAutoConnect {
+				autoConnection += i18n.G(", but the interface does not auto-connect them")
+			}
+			fmt.Fprintf(w, "auto-connection:\t%s\n", autoConnection)
+		}
+		for _, snapName := range p.Unasserted {
+			fmt.Fprintf(w, "note:\t%s\n", fmt.Sprintf(i18n.G("snap %q has no snap-declaration, its installation is only checked minimally and its manual connections are not checked"), snapName))
+		}
+	}
+	w.Flush()
+
+	return nil
+}
@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const connectionPolicyResponse = `{"type": "sync", "result": [{
+  "plug": {"snap": "consumer", "plug": "plug"},
+  "slot": {"snap": "producer", "slot": "slot"},
+  "plug-installation": {"allowed": true, "alternative": -1},
+  "slot-installation": {"allowed": true, "declaration": "base-declaration", "side": "slot", "constraint": "allow-installation", "alternative": 1},
+  "connection": {"allowed": false, "declaration": "snap-declaration", "snap": "consumer", "side": "plug", "constraint": "allow-connection", "alternative": -1, "reason": "slot attribute \"s\" value \"S2\" does not match ^(S1)$"},
+  "auto-connection": {"allowed": false, "declaration": "base-declaration", "side": "slot", "constraint": "deny-auto-connection", "alternative": 0},
+  "interface-auto-connect": true
+}, {
+  "plug": {"snap": "consumer", "plug": "plug"},
+  "slot": {"snap": "other", "slot": "slot"},
+  "connection": {"allowed": true, "declaration": "base-declaration", "side": "slot", "constraint": "allow-connection", "alternative": 0},
+  "auto-connection": {"allowed": true, "declaration": "base-declaration", "side": "slot", "constraint": "allow-auto-connection", "alternative": 0},
+  "interface-auto-connect": false,
+  "unasserted": ["other"]
+}]}`
+
+func (s *SnapSuite) TestConnectionPolicy(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+				"aspect": []string{"connection-policy"},
+				"plug":   []string{"consumer:plug"},
+			})
+			fmt.Fprintln(w, connectionPolicyResponse)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "connection-policy", "consumer:plug"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `plug:               consumer:plug
+slot:               producer:slot
+plug-installation:  allowed, no rule for the interface
+slot-installation:  allowed by allow-installation of the slot rule in the base-declaration (alternative 2)
+connection:         not allowed by allow-connection of the plug rule in the snap-declaration of "consumer": slot attribute "s" value "S2" does not match ^(S1)$
+auto-connection:    denied by deny-auto-connection of the slot rule in the base-declaration
+
+plug:             consumer:plug
+slot:             other:slot
+connection:       allowed by allow-connection of the slot rule in the base-declaration
+auto-connection:  allowed by allow-auto-connection of the slot rule in the base-declaration, but the interface does not auto-connect them
+note:             snap "other" has no snap-declaration, its installation is only checked minimally and its manual connections are not checked
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestConnectionPolicySlot(c *check.C) {
+	for _, t := range []struct {
+		slot  string
+		param string
+	}{
+		{"producer:slot", "producer:slot"},
+		{"producer", "producer"},
+		{":slot", ":slot"},
+	} {
+		s.stdout.Reset()
+		s.stderr.Reset()
+
+		n := 0
+		s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+				"aspect": []string{"connection-policy"},
+				"plug":   []string{"consumer:plug"},
+				"slot":   []string{t.param},
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+			n++
+		})
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "connection-policy", "consumer:plug", t.slot})
+		c.Assert(err, check.IsNil)
+		c.Check(s.Stdout(), check.Equals, "")
+		c.Check(s.Stderr(), check.Equals, "No slots to connect plug consumer:plug to.\n")
+		c.Check(n, check.Equals, 1)
+	}
+}
+
+func (s *SnapSuite) TestConnectionPolicyError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "snap \"consumer\" has no plug named \"missing\""}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "connection-policy", "consumer:missing"})
+	c.Assert(err, check.ErrorMatches, `snap "consumer" has no plug named "missing"`)
+}
@@ -0,0 +1,107 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDenials struct {
+	clientMixin
+	timeMixin
+	Positionals struct {
+		Snap installedSnapName `required:"yes"`
+	} `positional-args:"true"`
+}
+
+var shortDenialsHelp = i18n.G("Show the recent accesses denied by the sandbox of a snap")
+var longDenialsHelp = i18n.G(`
+The denials command shows the recent accesses of the apps and hooks of the
+given snap that were denied by apparmor or seccomp, as logged to the journal,
+along with how many times each was denied.
+
+For file accesses denied by apparmor, the interfaces whose plugs would have
+allowed the access once connected are suggested.
+`)
+
+func init() {
+	addDebugCommand("denials", shortDenialsHelp, longDenialsHelp, func() flags.Commander {
+		return &cmdDenials{}
+	}, timeDescs, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<snap>"),
+	}})
+}
+
+func denialAccess(d *client.Denial) string {
+	if d.Sandbox == "seccomp" {
+		if d.Arch != "" {
+			return fmt.Sprintf("syscall %s (%s)", d.Syscall, d.Arch)
+		}
+		return fmt.Sprintf("syscall %s", d.Syscall)
+	}
+	access := d.Operation
+	if d.Name != "" {
+		access += " " + d.Name
+	}
+	if d.RequestedMask != "" {
+		access += fmt.Sprintf(" (%s)", d.RequestedMask)
+	}
+	return access
+}
+
+func (x *cmdDenials) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	name := string(x.Positionals.Snap)
+	denials, err := x.client.SnapDenials(name)
+	if err != nil {
+		return err
+	}
+	if len(denials) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("No accesses of snap %q were denied recently.\n"), name)
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Time\tApp\tSandbox\tAccess\tCount\tSuggested"))
+	for _, d := range denials {
+		app := strings.TrimPrefix(d.Tag, "snap.")
+		if app == "" {
+			app = "-"
+		}
+		suggested := "-"
+		if len(d.SuggestedInterfaces) > 0 {
+			suggested = strings.Join(d.SuggestedInterfaces, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", x.fmtTime(d.Time), app, d.Sandbox, denialAccess(d), d.Count, suggested)
+	}
+	return nil
+}
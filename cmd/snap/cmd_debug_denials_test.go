@@ -0,0 +1,95 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const denialsResponse = `{"type": "sync", "result": [{
+  "time": "2021-03-01T10:00:01Z",
+  "count": 2,
+  "sandbox": "apparmor",
+  "snap": "consumer",
+  "tag": "snap.consumer.app",
+  "comm": "app",
+  "operation": "open",
+  "name": "/etc/foo",
+  "requested-mask": "r",
+  "denied-mask": "r",
+  "suggested-interfaces": ["system-files", "system-backup"]
+}, {
+  "time": "2021-03-01T10:00:02Z",
+  "count": 1,
+  "sandbox": "seccomp",
+  "snap": "consumer",
+  "tag": "snap.consumer.hook.install",
+  "syscall": "165",
+  "arch": "c000003e"
+}]}`
+
+func (s *SnapSuite) TestDenials(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/consumer/denials")
+			fmt.Fprintln(w, denialsResponse)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "--abs-time", "consumer"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+Time                  App                    Sandbox   Access                  Count  Suggested
+2021-03-01T10:00:01Z  consumer.app           apparmor  open /etc/foo (r)       2      system-files,system-backup
+2021-03-01T10:00:02Z  consumer.hook.install  seccomp   syscall 165 (c000003e)  1      -
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDenialsNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "consumer"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No accesses of snap \"consumer\" were denied recently.\n")
+}
+
+func (s *SnapSuite) TestDenialsError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "snap \"consumer\" is not installed", "kind": "snap-not-found"}, "status-code": 404}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "consumer"})
+	c.Assert(err, check.ErrorMatches, `cannot retrieve sandbox denials of snap "consumer": snap "consumer" is not installed`)
+}
@@ -0,0 +1,134 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdSandboxPolicy struct {
+	clientMixin
+	Diff        bool `long:"diff"`
+	Positionals struct {
+		App string `required:"yes"`
+	} `positional-args:"true"`
+}
+
+var shortSandboxPolicyHelp = i18n.G("Show the sandbox policy generated for an app")
+var longSandboxPolicyHelp = i18n.G(`
+The sandbox-policy command shows the parts of the apparmor, seccomp, udev,
+mount and kernel module policies that the interfaces generate for the given
+app, or for all the apps and hooks of the given snap, along with the plug,
+slot or connection each part comes from.
+
+The base templates and the layouts of the snap are not shown.
+`)
+
+func init() {
+	addDebugCommand("sandbox-policy", shortSandboxPolicyHelp, longSandboxPolicyHelp, func() flags.Commander {
+		return &cmdSandboxPolicy{}
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"diff": i18n.G("Mark the apparmor and seccomp lines missing from the installed profiles with +"),
+	}, []argDesc{{
+		// TRANSLATORS: This needs to begin with < and end with >
+		name: i18n.G("<snap>.<app>"),
+	}})
+}
+
+type sandboxSnippet struct {
+	Backend string `json:"backend"`
+	Tag     string `json:"tag"`
+
+	Interface string `json:"interface"`
+	Plug      *struct {
+		Snap string `json:"snap"`
+		Name string `json:"plug"`
+	} `json:"plug"`
+	Slot *struct {
+		Snap string `json:"snap"`
+		Name string `json:"slot"`
+	} `json:"slot"`
+	Connection string `json:"connection"`
+
+	Snippet string   `json:"snippet"`
+	Missing []string `json:"missing"`
+}
+
+func (s *sandboxSnippet) origin() string {
+	var side string
+	switch {
+	case s.Plug != nil:
+		side = fmt.Sprintf("plug %s:%s", s.Plug.Snap, s.Plug.Name)
+	case s.Slot != nil:
+		side = fmt.Sprintf("slot %s:%s", s.Slot.Snap, s.Slot.Name)
+	}
+	if s.Connection != "" {
+		// TRANSLATORS: the first %s is the plug or slot, the second the connection, the last one the interface
+		return fmt.Sprintf(i18n.G("%s, connection %s (%s)"), side, s.Connection, s.Interface)
+	}
+	// TRANSLATORS: the first %s is the plug or slot, the second one the interface
+	return fmt.Sprintf(i18n.G("%s (%s)"), side, s.Interface)
+}
+
+func (x *cmdSandboxPolicy) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	params := map[string]string{"app": x.Positionals.App}
+	if x.Diff {
+		params["diff"] = "true"
+	}
+	var snippets []*sandboxSnippet
+	if err := x.client.DebugGet("sandbox-policy", &snippets, params); err != nil {
+		return err
+	}
+	if len(snippets) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("No sandbox policy is generated by the interfaces of %s.\n"), x.Positionals.App)
+		return nil
+	}
+
+	backend := ""
+	for _, s := range snippets {
+		if s.Backend != backend {
+			backend = s.Backend
+			fmt.Fprintf(Stdout, "%s:\n", backend)
+		}
+		fmt.Fprintf(Stdout, "  %s: %s\n", s.Tag, s.origin())
+		missing := make(map[string]bool, len(s.Missing))
+		for _, line := range s.Missing {
+			missing[line] = true
+		}
+		for _, line := range strings.Split(s.Snippet, "\n") {
+			prefix := "    "
+			if missing[strings.TrimSpace(line)] {
+				prefix = "  + "
+			}
+			fmt.Fprintf(Stdout, "%s%s\n", prefix, line)
+		}
+	}
+	return nil
+}
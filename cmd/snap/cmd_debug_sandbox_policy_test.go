@@ -0,0 +1,111 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const sandboxPolicyResponse = `{"type": "sync", "result": [{
+  "backend": "apparmor",
+  "tag": "snap.consumer.app",
+  "interface": "test",
+  "plug": {"snap": "consumer", "plug": "plug"},
+  "snippet": "/permanent r,"
+}, {
+  "backend": "apparmor",
+  "tag": "snap.consumer.app",
+  "interface": "test",
+  "plug": {"snap": "consumer", "plug": "plug"},
+  "connection": "consumer:plug producer:slot",
+  "snippet": "/connected r,\n  /other rw,",
+  "missing": ["/other rw,"]
+}, {
+  "backend": "kmod",
+  "tag": "snap.consumer.conf",
+  "interface": "test",
+  "slot": {"snap": "consumer", "slot": "slot"},
+  "snippet": "module"
+}]}`
+
+func (s *SnapSuite) TestSandboxPolicy(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+				"aspect": []string{"sandbox-policy"},
+				"app":    []string{"consumer.app"},
+				"diff":   []string{"true"},
+			})
+			fmt.Fprintln(w, sandboxPolicyResponse)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-policy", "--diff", "consumer.app"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `apparmor:
+  snap.consumer.app: plug consumer:plug (test)
+    /permanent r,
+  snap.consumer.app: plug consumer:plug, connection consumer:plug producer:slot (test)
+    /connected r,
+  +   /other rw,
+kmod:
+  snap.consumer.conf: slot consumer:slot (test)
+    module
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestSandboxPolicyEmpty(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+			"aspect": []string{"sandbox-policy"},
+			"app":    []string{"consumer"},
+		})
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-policy", "consumer"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No sandbox policy is generated by the interfaces of consumer.\n")
+}
+
+func (s *SnapSuite) TestSandboxPolicyError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "snap \"consumer\" has no app \"missing\""}}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox-policy", "consumer.missing"})
+	c.Assert(err, check.ErrorMatches, `snap "consumer" has no app "missing"`)
+}
@@ -241,6 +241,10 @@ func (x *cmdFind) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
+	if resInfo != nil && resInfo.CatalogTimestamp != nil {
+		// TRANSLATORS: the %s is a time, e.g. "yesterday at 10:21 UTC"
+		fmt.Fprintf(Stderr, i18n.G("Unable to contact snap store; results are from the local catalog as of %s and may be out of date.\n"), timeutilHuman(*resInfo.CatalogTimestamp))
+	}
 	if len(snaps) == 0 {
 		if x.Section == "" {
 			// TRANSLATORS: the %q is the (quoted) query the user entered
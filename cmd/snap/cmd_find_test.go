@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 	"gopkg.in/check.v1"
@@ -468,6 +469,45 @@ func (s *SnapSuite) TestFindNetworkTimeoutError(c *check.C) {
 	c.Check(s.Stdout(), check.Equals, "")
 }
 
+const findLocalCatalogJSON = `
+{
+  "type": "sync",
+  "status-code": 200,
+  "status": "OK",
+  "result": [
+    {
+      "name": "hello",
+      "version": "2.10",
+      "summary": "GNU Hello, the \"hello world\" snap",
+      "status": "available"
+    }
+  ],
+  "sources": [
+    "local-catalog"
+  ],
+  "catalog-timestamp": "2021-06-01T10:21:00Z"
+}
+`
+
+func (s *SnapSuite) TestFindFromLocalCatalog(c *check.C) {
+	restore := snap.MockTimeutilHuman(func(t time.Time) string {
+		c.Check(t.Equal(time.Date(2021, 6, 1, 10, 21, 0, 0, time.UTC)), check.Equals, true)
+		return "yesterday at 10:21 UTC"
+	})
+	defer restore()
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/find")
+		fmt.Fprintln(w, findLocalCatalogJSON)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"find", "hello"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `Name +Version +Publisher +Notes +Summary
+hello +2.10 +- +- +GNU Hello, the "hello world" snap
+`)
+	c.Check(s.Stderr(), check.Equals, "Unable to contact snap store; results are from the local catalog as of yesterday at 10:21 UTC and may be out of date.\n")
+}
+
 func (s *SnapSuite) TestFindSnapSectionOverview(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -50,6 +50,7 @@ type infoCmd struct {
 	timeMixin
 
 	Verbose    bool `long:"verbose"`
+	History    bool `long:"history"`
 	Positional struct {
 		Snaps []anySnapName `positional-arg-name:"<snap>" required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -74,6 +75,8 @@ func init() {
 		}, colorDescs.also(timeDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"verbose": i18n.G("Include more details on the snap (expanded notes, base, etc.)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"history": i18n.G("Include the refresh history of installed snaps"),
 		}), nil)
 }
 
@@ -279,6 +282,8 @@ type infoWriter struct {
 	remoteSnap *client.Snap
 	resInfo    *client.ResultInfo
 	path       string
+	// history is only set if requested
+	history []*client.RefreshHistoryEntry
 	// fields that don't change and so can be set once
 	writeflusher
 	esc       *escapes
@@ -290,6 +295,7 @@ type infoWriter struct {
 
 func (iw *infoWriter) setupDiskSnap(path string, diskSnap *client.Snap) {
 	iw.localSnap, iw.remoteSnap, iw.resInfo = nil, nil, nil
+	iw.history = nil
 	iw.path = path
 	iw.diskSnap = diskSnap
 	iw.theSnap = diskSnap
@@ -297,6 +303,7 @@ func (iw *infoWriter) setupDiskSnap(path string, diskSnap *client.Snap) {
 
 func (iw *infoWriter) setupSnap(localSnap, remoteSnap *client.Snap, resInfo *client.ResultInfo) {
 	iw.path, iw.diskSnap = "", nil
+	iw.history = nil
 	iw.localSnap = localSnap
 	iw.remoteSnap = remoteSnap
 	iw.resInfo = resInfo
@@ -358,6 +365,68 @@ func (iw *infoWriter) maybePrintInstallDate() {
 	fmt.Fprintf(iw, "refresh-date:\t%s\n", iw.fmtTime(iw.localSnap.InstallDate))
 }
 
+func (iw *infoWriter) maybePrintRefreshHold() {
+	if iw.localSnap == nil {
+		return
+	}
+	switch iw.localSnap.Hold {
+	case "":
+		// not held
+	case "forever":
+		fmt.Fprintf(iw, "hold:\tforever\n")
+	default:
+		fmt.Fprintf(iw, "hold:\t%s\n", iw.fmtTime(parseSysinfoTime(iw.localSnap.Hold)))
+	}
+	if iw.localSnap.RefreshTimer != "" {
+		fmt.Fprintf(iw, "refresh-timer:\t%s\n", iw.localSnap.RefreshTimer)
+	}
+}
+
+func (iw *infoWriter) maybePrintTrackingPolicy() {
+	if iw.localSnap == nil || iw.localSnap.TrackingPolicy == nil {
+		return
+	}
+	policy := iw.localSnap.TrackingPolicy
+	followed := policy.Risk
+	if policy.Track != "" {
+		followed = policy.Track + "/" + policy.Risk
+	}
+	fmt.Fprintf(iw, "follows:\t%s\n", followed)
+	if policy.MinAge != "" {
+		fmt.Fprintf(iw, "min-age:\t%s\n", policy.MinAge)
+	}
+}
+
+func (iw *infoWriter) setupHistory(history []*client.RefreshHistoryEntry) {
+	if history == nil {
+		history = []*client.RefreshHistoryEntry{}
+	}
+	iw.history = history
+}
+
+func (iw *infoWriter) maybePrintRefreshHistory() {
+	if iw.history == nil {
+		return
+	}
+	iw.Flush()
+	if len(iw.history) == 0 {
+		fmt.Fprintf(iw, "history:\t%s\n", iw.esc.dash)
+		return
+	}
+	fmt.Fprintln(iw, "history:")
+	for _, entry := range iw.history {
+		channel := entry.Channel
+		if channel == "" {
+			channel = iw.esc.dash
+		}
+		trigger := entry.Trigger
+		if entry.Revert {
+			trigger = "revert"
+		}
+		fmt.Fprintf(iw, "  %s\t%s -> %s\t%s\t%s\t%s\t%s\n", iw.fmtTime(entry.Time), entry.FromRevision, entry.ToRevision, channel, trigger, entry.Outcome, entry.ChangeID)
+	}
+}
+
 func (iw *infoWriter) maybePrintChinfo() {
 	if iw.diskSnap != nil {
 		return
@@ -711,6 +780,14 @@ func (x *infoCmd) Execute([]string) error {
 			remoteSnap, resInfo, _ := x.client.FindOne(snap.InstanceSnap(snapName))
 			localSnap, _, _ := x.client.Snap(snapName)
 			iw.setupSnap(localSnap, remoteSnap, resInfo)
+			if x.History && localSnap != nil {
+				history, err := x.client.SnapRefreshHistory(snapName)
+				if err != nil {
+					w.Flush()
+					return err
+				}
+				iw.setupHistory(history)
+			}
 		}
 		// note diskSnap == nil, or localSnap == nil and remoteSnap == nil
 
@@ -748,8 +825,11 @@ func (x *infoCmd) Execute([]string) error {
 		iw.maybePrintID()
 		iw.maybePrintCohortKey()
 		iw.maybePrintTrackingChannel()
+		iw.maybePrintTrackingPolicy()
 		iw.maybePrintInstallDate()
+		iw.maybePrintRefreshHold()
 		iw.maybePrintChinfo()
+		iw.maybePrintRefreshHistory()
 	}
 	w.Flush()
 
@@ -380,6 +380,90 @@ func (s *infoSuite) TestMaybePrintBase(c *check.C) {
 	buf.Reset()
 }
 
+func (s *infoSuite) TestMaybePrintRefreshHold(c *check.C) {
+	var buf flushBuffer
+	iw := snap.NewInfoWriter(&buf)
+
+	for _, t := range []struct {
+		hold, timer, expected string
+	}{
+		{"", "", ""},
+		{"forever", "", "hold:\tforever\n"},
+		{"2021-05-10T10:00:00Z", "", "hold:\t10:00AM\n"},
+		{"", "sat,10:00-12:00", "refresh-timer:\tsat,10:00-12:00\n"},
+		{"forever", "10:00", "hold:\tforever\nrefresh-timer:\t10:00\n"},
+	} {
+		buf.Reset()
+		snap.SetupSnap(iw, &client.Snap{Hold: t.hold, RefreshTimer: t.timer}, nil, nil)
+		snap.MaybePrintRefreshHold(iw)
+		c.Check(buf.String(), check.Equals, t.expected, check.Commentf("%q %q", t.hold, t.timer))
+	}
+
+	// nothing for remote snaps
+	buf.Reset()
+	snap.SetupSnap(iw, nil, &client.Snap{Hold: "forever"}, nil)
+	snap.MaybePrintRefreshHold(iw)
+	c.Check(buf.String(), check.Equals, "")
+}
+
+func (s *infoSuite) TestMaybePrintTrackingPolicy(c *check.C) {
+	var buf flushBuffer
+	iw := snap.NewInfoWriter(&buf)
+
+	for _, t := range []struct {
+		policy   *client.TrackingPolicy
+		expected string
+	}{
+		{nil, ""},
+		{&client.TrackingPolicy{Risk: "candidate"}, "follows:\tcandidate\n"},
+		{&client.TrackingPolicy{Risk: "stable", Track: "2.0", MinAge: "72h0m0s"}, "follows:\t2.0/stable\nmin-age:\t72h0m0s\n"},
+	} {
+		buf.Reset()
+		snap.SetupSnap(iw, &client.Snap{TrackingPolicy: t.policy}, nil, nil)
+		snap.MaybePrintTrackingPolicy(iw)
+		c.Check(buf.String(), check.Equals, t.expected, check.Commentf("%+v", t.policy))
+	}
+}
+
+func (s *infoSuite) TestMaybePrintRefreshHistory(c *check.C) {
+	var buf flushBuffer
+	iw := snap.NewInfoWriter(&buf)
+	t0 := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	// not requested
+	snap.SetupSnap(iw, &client.Snap{}, nil, nil)
+	snap.MaybePrintRefreshHistory(iw)
+	c.Check(buf.String(), check.Equals, "")
+
+	// requested but empty
+	snap.SetupHistory(iw, nil)
+	snap.MaybePrintRefreshHistory(iw)
+	c.Check(buf.String(), check.Equals, "history:\t--\n")
+
+	buf.Reset()
+	snap.SetupHistory(iw, []*client.RefreshHistoryEntry{{
+		FromRevision: snaplib.R(1),
+		ToRevision:   snaplib.R(2),
+		Channel:      "latest/stable",
+		Time:         t0,
+		ChangeID:     "42",
+		Trigger:      "validation-set",
+		Outcome:      "reverted",
+	}, {
+		FromRevision: snaplib.R(2),
+		ToRevision:   snaplib.R(1),
+		Time:         t0,
+		ChangeID:     "43",
+		Trigger:      "manual",
+		Revert:       true,
+		Outcome:      "refreshed",
+	}})
+	snap.MaybePrintRefreshHistory(iw)
+	c.Check(buf.String(), check.Equals, "history:\n"+
+		"  10:00AM\t1 -> 2\tlatest/stable\tvalidation-set\treverted\t42\n"+
+		"  10:00AM\t2 -> 1\t--\trevert\trefreshed\t43\n")
+}
+
 func (s *infoSuite) TestMaybePrintPath(c *check.C) {
 	var buf flushBuffer
 	iw := snap.NewInfoWriter(&buf)
@@ -712,6 +796,56 @@ installed:    2.10 (1) 1kB disabled,blocked
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *infoSuite) TestInfoWithHistory(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/find")
+			fmt.Fprintln(w, mockInfoJSON)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/hello")
+			fmt.Fprintln(w, mockInfoJSONNoLicense)
+		case 2:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/hello/history")
+			fmt.Fprintln(w, `{"type": "sync", "result": [{
+				"from-revision": "99",
+				"to-revision": "100",
+				"channel": "latest/beta",
+				"time": "2006-01-02T22:04:07Z",
+				"change-id": "42",
+				"trigger": "auto",
+				"outcome": "refreshed"
+			}]}`)
+		default:
+			c.Fatalf("expected to get 3 requests, now on %d (%v)", n+1, r)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"info", "--abs-time", "--history", "hello"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `name:      hello
+summary:   The GNU Hello snap
+publisher: Canonical*
+license:   unset
+description: |
+  GNU hello prints a friendly greeting. This is part of the snapcraft tour at
+  https://snapcraft.io/
+snap-id:      mVyGrEwiqSi5PugCwyH7WgpoQLemtTd6
+tracking:     beta
+refresh-date: 2006-01-02T22:04:07Z
+installed:    2.10 (100) 1kB disabled
+history:
+  2006-01-02T22:04:07Z 99 -> 100 latest/beta auto refreshed 42
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *infoSuite) TestInfoNotFound(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
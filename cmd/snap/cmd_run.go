@@ -43,6 +43,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/egress"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/strace"
@@ -1152,10 +1153,16 @@ func (x *cmdRun) runSnapConfine(info *snap.Info, securityTag, snapApp, hook stri
 	}
 	// Allow using the session bus for all apps but not for hooks.
 	allowSessionBus := hook == ""
+	// The egress ruleset of a snap restricted to some network destinations
+	// matches its applications by the slice they are started in.
+	restricted := osutil.FileExists(filepath.Join(dirs.SnapEgressDir, snap.SecurityTag(info.InstanceName())+".nft"))
 	// Track, or confirm existing tracking from systemd.
 	var trackingErr error
 	if needsTracking {
 		opts := &cgroup.TrackingOptions{AllowSessionBus: allowSessionBus}
+		if restricted {
+			opts.Slice = cgroup.SliceForTracking(securityTag)
+		}
 		trackingErr = cgroupCreateTransientScopeForTracking(securityTag, opts)
 	} else {
 		trackingErr = cgroupConfirmSystemdServiceTracking(securityTag)
@@ -1170,6 +1177,17 @@ func (x *cmdRun) runSnapConfine(info *snap.Info, securityTag, snapApp, hook stri
 		logger.Debugf("snapd cannot track the started application")
 		logger.Debugf("snap refreshes will not be postponed by this process")
 	}
+	// The egress ruleset is updated when it does not match the cgroup of
+	// the application yet, as when the slice is new. Without tracking the
+	// application cannot be restricted and does not start.
+	if restricted {
+		if trackingErr != nil {
+			return fmt.Errorf("cannot restrict network access of snap %q: %v", info.InstanceName(), trackingErr)
+		}
+		if err := x.ensureEgressRestricted(info.InstanceName()); err != nil {
+			return err
+		}
+	}
 	if x.TraceExec {
 		return x.runCmdWithTraceExec(cmd, envForExec)
 	} else if x.Gdb {
@@ -1193,5 +1211,31 @@ func (x *cmdRun) runSnapConfine(info *snap.Info, securityTag, snapApp, hook stri
 	}
 }
 
+// ensureEgressRestricted makes sure the egress ruleset of the snap matches
+// the cgroup of the current process, asking snapd to update it if needed.
+func (x *cmdRun) ensureEgressRestricted(snapName string) error {
+	pid := os.Getpid()
+	matches, err := egressRulesetMatchesProcess(snapName, pid)
+	if err != nil {
+		return fmt.Errorf("cannot restrict network access of snap %q: %v", snapName, err)
+	}
+	if matches {
+		return nil
+	}
+	if err := x.client.UpdateSnapEgress(snapName); err != nil {
+		return err
+	}
+	matches, err = egressRulesetMatchesProcess(snapName, pid)
+	if err != nil {
+		return fmt.Errorf("cannot restrict network access of snap %q: %v", snapName, err)
+	}
+	if !matches {
+		return fmt.Errorf("cannot restrict network access of snap %q: egress ruleset does not match the application", snapName)
+	}
+	return nil
+}
+
+var egressRulesetMatchesProcess = egress.RulesetMatchesProcess
+
 var cgroupCreateTransientScopeForTracking = cgroup.CreateTransientScopeForTracking
 var cgroupConfirmSystemdServiceTracking = cgroup.ConfirmSystemdServiceTracking
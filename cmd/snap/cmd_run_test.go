@@ -22,6 +22,7 @@ package main_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/user"
@@ -1583,3 +1584,132 @@ func (s *RunSuite) TestRunGdbserverNoGdbserver(c *check.C) {
 	_, err := snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--gdbserver", "snapname.app"})
 	c.Assert(err, check.ErrorMatches, "please install gdbserver on your system")
 }
+
+func (s *RunSuite) TestSnapRunUpdatesEgressRuleset(c *check.C) {
+	defer mockSnapConfine(dirs.DistroLibExecDir)()
+
+	snaptest.MockSnapCurrent(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("x2"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapEgressDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapEgressDir, "snap.snapname.nft"), nil, 0644), check.IsNil)
+
+	var calls []string
+	restore := snaprun.MockCreateTransientScopeForTracking(func(securityTag string, opts *cgroup.TrackingOptions) error {
+		c.Check(opts.Slice, check.Equals, "snap.snapname.app.slice")
+		calls = append(calls, "track")
+		return nil
+	})
+	defer restore()
+	updated := false
+	restore = snaprun.MockEgressRulesetMatchesProcess(func(snapName string, pid int) (bool, error) {
+		c.Check(snapName, check.Equals, "snapname")
+		c.Check(pid, check.Equals, os.Getpid())
+		calls = append(calls, "match")
+		return updated, nil
+	})
+	defer restore()
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/snapname/egress")
+		calls = append(calls, "egress")
+		updated = true
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+	restore = snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		calls = append(calls, "exec")
+		return nil
+	})
+	defer restore()
+
+	_, err := snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--", "snapname.app"})
+	c.Assert(err, check.IsNil)
+	// the ruleset is updated with the new cgroup before running the app
+	c.Check(calls, check.DeepEquals, []string{"track", "match", "egress", "match", "exec"})
+
+	// but not when it matches the cgroup already
+	calls = nil
+	_, err = snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--", "snapname.app"})
+	c.Assert(err, check.IsNil)
+	c.Check(calls, check.DeepEquals, []string{"track", "match", "exec"})
+}
+
+func (s *RunSuite) TestSnapRunUnrestrictedSnapIsNotInSlice(c *check.C) {
+	defer mockSnapConfine(dirs.DistroLibExecDir)()
+
+	snaptest.MockSnapCurrent(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("x2"),
+	})
+
+	restore := snaprun.MockCreateTransientScopeForTracking(func(securityTag string, opts *cgroup.TrackingOptions) error {
+		c.Check(opts.Slice, check.Equals, "")
+		return nil
+	})
+	defer restore()
+	restore = snaprun.MockEgressRulesetMatchesProcess(func(snapName string, pid int) (bool, error) {
+		c.Fatalf("unexpected egress ruleset check")
+		return false, nil
+	})
+	defer restore()
+	restore = snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		return nil
+	})
+	defer restore()
+
+	_, err := snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--", "snapname.app"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *RunSuite) TestSnapRunEgressRulesetErrors(c *check.C) {
+	defer mockSnapConfine(dirs.DistroLibExecDir)()
+
+	snaptest.MockSnapCurrent(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("x2"),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapEgressDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapEgressDir, "snap.snapname.nft"), nil, 0644), check.IsNil)
+
+	trackingErr := cgroup.ErrCannotTrackProcess
+	restore := snaprun.MockCreateTransientScopeForTracking(func(securityTag string, opts *cgroup.TrackingOptions) error {
+		return trackingErr
+	})
+	defer restore()
+	var matchErr error
+	restore = snaprun.MockEgressRulesetMatchesProcess(func(snapName string, pid int) (bool, error) {
+		return false, matchErr
+	})
+	defer restore()
+	var updateErr bool
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if updateErr {
+			w.WriteHeader(500)
+			fmt.Fprintln(w, `{"type": "error", "result": {"message": "boom"}}`)
+			return
+		}
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+	restore = snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		c.Fatalf("the app must not run without its egress ruleset")
+		return nil
+	})
+	defer restore()
+
+	// untracked applications cannot be restricted
+	_, err := snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--", "snapname.app"})
+	c.Assert(err, check.ErrorMatches, `cannot restrict network access of snap "snapname": cannot track application process`)
+
+	trackingErr = nil
+	matchErr = errors.New("cgroup v2 is not in use")
+	_, err = snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--", "snapname.app"})
+	c.Assert(err, check.ErrorMatches, `cannot restrict network access of snap "snapname": cgroup v2 is not in use`)
+
+	matchErr = nil
+	updateErr = true
+	_, err = snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--", "snapname.app"})
+	c.Assert(err, check.ErrorMatches, `cannot update egress ruleset of snap "snapname": boom`)
+
+	// the ruleset must match the application once updated
+	updateErr = false
+	_, err = snaprun.Parser(snaprun.Client()).ParseArgs([]string{"run", "--", "snapname.app"})
+	c.Assert(err, check.ErrorMatches, `cannot restrict network access of snap "snapname": egress ruleset does not match the application`)
+}
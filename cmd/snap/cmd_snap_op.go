@@ -92,6 +92,20 @@ have developer access to the snap, either directly or through the
 store's collaboration feature, and to be logged in (see 'snap help login').
 
 Note a later refresh will typically undo a revision override.
+
+The --hold option holds the auto-refreshes of the given snaps for a duration
+like 24h, or forever if no duration is given, while --timer limits their
+auto-refreshes to the windows of a schedule in the format of refresh.timer.
+Both can be removed again with --unhold. Explicit refreshes of the snaps are
+not affected.
+
+The --follow option makes all refreshes of the given snaps, including
+auto-refreshes, take them from the given risk level, switching them to it with
+the first such refresh. The track they follow is kept unless --follow-track
+pins it, and --min-age makes them wait until a revision has been released to
+the followed channel for the given duration, like 72h. The snaps keep tracking
+the channel they were last refreshed from after --unfollow. Refreshes that name
+the snaps are not affected.
 `)
 
 var longTryHelp = i18n.G(`
@@ -640,11 +654,120 @@ type cmdRefresh struct {
 	Time             bool   `long:"time"`
 	IgnoreValidation bool   `long:"ignore-validation"`
 	IgnoreRunning    bool   `long:"ignore-running" hidden:"yes"`
+	Hold             string `long:"hold" optional:"yes" optional-value:"forever"`
+	Unhold           bool   `long:"unhold"`
+	Timer            string `long:"timer"`
+	Follow           string `long:"follow"`
+	FollowTrack      string `long:"follow-track"`
+	MinAge           string `long:"min-age"`
+	Unfollow         bool   `long:"unfollow"`
 	Positional       struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
 }
 
+func (x *cmdRefresh) holdRefreshes(snaps []string) error {
+	var holdUntil string
+	switch x.Hold {
+	case "", "forever":
+		holdUntil = x.Hold
+	default:
+		dur, err := time.ParseDuration(x.Hold)
+		if err != nil || dur <= 0 {
+			return fmt.Errorf(i18n.G("cannot hold refreshes for %q: not a positive duration or \"forever\""), x.Hold)
+		}
+		holdUntil = timeNow().Add(dur).Format(time.RFC3339)
+	}
+
+	var changeID string
+	var err error
+	if x.Unhold {
+		changeID, err = x.client.UnholdRefreshes(snaps)
+	} else {
+		changeID, err = x.client.HoldRefreshes(snaps, &client.SnapOptions{
+			Time:         holdUntil,
+			RefreshTimer: x.Timer,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	quoted := strutil.Quoted(snaps)
+	switch {
+	case x.Unhold:
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		fmt.Fprintf(Stdout, i18n.G("Auto-refreshes of %s are no longer held\n"), quoted)
+	case holdUntil == "forever":
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		fmt.Fprintf(Stdout, i18n.G("Auto-refreshes of %s are held until further notice\n"), quoted)
+	case holdUntil != "":
+		// TRANSLATORS: the first %s is a comma-separated list of quoted snap names, the second one a time
+		fmt.Fprintf(Stdout, i18n.G("Auto-refreshes of %s are held until %s\n"), quoted, x.fmtTime(parseSysinfoTime(holdUntil)))
+	}
+	if !x.Unhold && x.Timer != "" {
+		// TRANSLATORS: the first %s is a comma-separated list of quoted snap names, the second one a schedule
+		fmt.Fprintf(Stdout, i18n.G("Auto-refreshes of %s are limited to %s\n"), quoted, x.Timer)
+	}
+	return nil
+}
+
+func (x *cmdRefresh) followChannels(snaps []string) error {
+	if x.MinAge != "" {
+		if dur, err := time.ParseDuration(x.MinAge); err != nil || dur <= 0 {
+			return fmt.Errorf(i18n.G("cannot use minimum revision age %q: not a positive duration"), x.MinAge)
+		}
+	}
+
+	var changeID string
+	var err error
+	if x.Unfollow {
+		changeID, err = x.client.RemoveTrackingPolicy(snaps)
+	} else {
+		changeID, err = x.client.SetTrackingPolicy(snaps, &client.TrackingPolicy{
+			Risk:   x.Follow,
+			Track:  x.FollowTrack,
+			MinAge: x.MinAge,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	quoted := strutil.Quoted(snaps)
+	if x.Unfollow {
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		fmt.Fprintf(Stdout, i18n.G("Refreshes of %s follow the tracked channels again\n"), quoted)
+		return nil
+	}
+	followed := x.Follow
+	if x.FollowTrack != "" {
+		followed = x.FollowTrack + "/" + x.Follow
+	}
+	if x.MinAge != "" {
+		// TRANSLATORS: the first %s is a comma-separated list of quoted snap names, the second one a channel, the third one a duration
+		fmt.Fprintf(Stdout, i18n.G("Refreshes of %s follow %s with revisions released at least %s ago\n"), quoted, followed, x.MinAge)
+	} else {
+		// TRANSLATORS: the first %s is a comma-separated list of quoted snap names, the second one a channel
+		fmt.Fprintf(Stdout, i18n.G("Refreshes of %s follow %s\n"), quoted, followed)
+	}
+	return nil
+}
+
 func (x *cmdRefresh) refreshMany(snaps []string, opts *client.SnapOptions) error {
 	changeID, err := x.client.RefreshMany(snaps, opts)
 	if err != nil {
@@ -720,6 +843,7 @@ func (x *cmdRefresh) showRefreshTimes() error {
 	last := parseSysinfoTime(sysinfo.Refresh.Last)
 	hold := parseSysinfoTime(sysinfo.Refresh.Hold)
 	next := parseSysinfoTime(sysinfo.Refresh.Next)
+	deferred := parseSysinfoTime(sysinfo.Refresh.Deferred)
 
 	if !last.IsZero() {
 		fmt.Fprintf(Stdout, "last: %s\n", x.fmtTime(last))
@@ -741,6 +865,9 @@ func (x *cmdRefresh) showRefreshTimes() error {
 	} else {
 		fmt.Fprintf(Stdout, "next: n/a\n")
 	}
+	if !deferred.IsZero() {
+		fmt.Fprintf(Stdout, "deferred: %s (%s)\n", x.fmtTime(deferred), sysinfo.Refresh.DeferReason)
+	}
 	return nil
 }
 
@@ -800,6 +927,34 @@ func (x *cmdRefresh) Execute([]string) error {
 	}
 
 	names := installedSnapNames(x.Positional.Snaps)
+	if x.Hold != "" || x.Unhold || x.Timer != "" {
+		if x.Unhold && (x.Hold != "" || x.Timer != "") {
+			return errors.New(i18n.G("cannot use --unhold together with --hold or --timer"))
+		}
+		if len(names) == 0 {
+			return errors.New(i18n.G("holding or unholding auto-refreshes needs snap names"))
+		}
+		if x.asksForMode() || x.asksForChannel() || x.Amend || x.Revision != "" || x.Cohort != "" || x.LeaveCohort || x.IgnoreValidation || x.IgnoreRunning || x.Follow != "" || x.Unfollow {
+			return errors.New(i18n.G("--hold, --unhold and --timer do not take other refresh options"))
+		}
+		return x.holdRefreshes(names)
+	}
+	if x.Follow != "" || x.FollowTrack != "" || x.MinAge != "" || x.Unfollow {
+		if x.Unfollow && (x.Follow != "" || x.FollowTrack != "" || x.MinAge != "") {
+			return errors.New(i18n.G("cannot use --unfollow together with --follow, --follow-track or --min-age"))
+		}
+		if !x.Unfollow && x.Follow == "" {
+			return errors.New(i18n.G("--follow-track and --min-age need --follow"))
+		}
+		if len(names) == 0 {
+			return errors.New(i18n.G("following or unfollowing channels needs snap names"))
+		}
+		if x.asksForMode() || x.asksForChannel() || x.Amend || x.Revision != "" || x.Cohort != "" || x.LeaveCohort || x.IgnoreValidation || x.IgnoreRunning {
+			return errors.New(i18n.G("--follow and --unfollow do not take other refresh options"))
+		}
+		return x.followChannels(names)
+	}
+
 	if len(names) == 1 {
 		opts := &client.SnapOptions{
 			Amend:            x.Amend,
@@ -1127,6 +1282,20 @@ func init() {
 			"cohort": i18n.G("Refresh the snap into the given cohort"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"leave-cohort": i18n.G("Refresh the snap out of its cohort"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"hold": i18n.G("Hold auto-refreshes of the snaps for the given duration, or forever"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"unhold": i18n.G("Remove the hold and the refresh timer of the snaps"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"timer": i18n.G("Auto-refresh the snaps only within the given refresh.timer like schedule"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"follow": i18n.G("Refresh the snaps from the given risk level from now on"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"follow-track": i18n.G("Pin the track followed with --follow"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"min-age": i18n.G("Only take revisions released to the followed channel for at least the given duration"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"unfollow": i18n.G("Stop following a channel other than the tracked one"),
 		}), nil)
 	addCommand("try", shortTryHelp, longTryHelp, func() flags.Commander { return &cmdTry{} }, waitDescs.also(modeDescs), nil)
 	addCommand("enable", shortEnableHelp, longEnableHelp, func() flags.Commander { return &cmdEnable{} }, waitDescs, nil)
@@ -1169,6 +1169,33 @@ next: 2017-04-26T00:58:00+02:00 (but held)
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *SnapSuite) TestRefreshDeferred(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00", "deferred": "2017-04-26T00:58:00+02:00", "defer-reason": "on battery power"}}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--time", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `timer: 0:00-24:00/4
+last: 2017-04-25T17:35:00+02:00
+next: 2017-04-26T00:58:00+02:00
+deferred: 2017-04-26T00:58:00+02:00 (on battery power)
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	// ensure that the fake server api was actually hit
+	c.Check(n, check.Equals, 1)
+}
+
 func (s *SnapSuite) TestRefreshNoTimerNoSchedule(c *check.C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		c.Check(r.Method, check.Equals, "GET")
@@ -1419,6 +1446,150 @@ func (s *SnapOpSuite) TestRefreshManyIgnoreValidation(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `a single snap name must be specified when ignoring validation`)
 }
 
+func (s *SnapOpSuite) TestRefreshHoldForever(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "hold",
+			"snaps":  []interface{}{"one", "two"},
+			"time":   "forever",
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--hold", "one", "two"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Auto-refreshes of \"one\", \"two\" are held until further notice\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapOpSuite) TestRefreshHoldDurationAndTimer(c *check.C) {
+	now := time.Date(2021, 5, 10, 10, 0, 0, 0, time.UTC)
+	restore := snap.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":        "hold",
+			"snaps":         []interface{}{"one"},
+			"time":          "2021-05-10T12:00:00Z",
+			"refresh-timer": "sat,10:00-12:00",
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--hold=2h", "--timer=sat,10:00-12:00", "--abs-time", "one"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `Auto-refreshes of "one" are held until 2021-05-10T12:00:00Z
+Auto-refreshes of "one" are limited to sat,10:00-12:00
+`)
+}
+
+func (s *SnapOpSuite) TestRefreshUnhold(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "unhold",
+			"snaps":  []interface{}{"one"},
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--unhold", "one"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Auto-refreshes of \"one\" are no longer held\n")
+}
+
+func (s *SnapOpSuite) TestRefreshHoldErrors(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--hold=1h", "--unhold", "one"}, `cannot use --unhold together with --hold or --timer`},
+		{[]string{"--timer=10:00", "--unhold", "one"}, `cannot use --unhold together with --hold or --timer`},
+		{[]string{"--hold"}, `holding or unholding auto-refreshes needs snap names`},
+		{[]string{"--unhold"}, `holding or unholding auto-refreshes needs snap names`},
+		{[]string{"--hold", "--beta", "one"}, `--hold, --unhold and --timer do not take other refresh options`},
+		{[]string{"--timer=10:00", "--amend", "one"}, `--hold, --unhold and --timer do not take other refresh options`},
+		{[]string{"--hold=-1h", "one"}, `cannot hold refreshes for "-1h": not a positive duration or "forever"`},
+		{[]string{"--hold=tomorrow", "one"}, `cannot hold refreshes for "tomorrow": not a positive duration or "forever"`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(append([]string{"refresh"}, t.args...))
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
+func (s *SnapOpSuite) TestRefreshFollow(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "follow",
+			"snaps":  []interface{}{"one", "two"},
+			"tracking-policy": map[string]interface{}{
+				"risk": "candidate",
+			},
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--follow=candidate", "one", "two"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Refreshes of \"one\", \"two\" follow candidate\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapOpSuite) TestRefreshFollowTrackMinAge(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "follow",
+			"snaps":  []interface{}{"one"},
+			"tracking-policy": map[string]interface{}{
+				"risk":    "stable",
+				"track":   "2.0",
+				"min-age": "72h",
+			},
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--follow=stable", "--follow-track=2.0", "--min-age=72h", "one"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Refreshes of \"one\" follow 2.0/stable with revisions released at least 72h ago\n")
+}
+
+func (s *SnapOpSuite) TestRefreshUnfollow(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "unfollow",
+			"snaps":  []interface{}{"one"},
+		})
+	}
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--unfollow", "one"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Refreshes of \"one\" follow the tracked channels again\n")
+}
+
+func (s *SnapOpSuite) TestRefreshFollowErrors(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--follow=beta", "--unfollow", "one"}, `cannot use --unfollow together with --follow, --follow-track or --min-age`},
+		{[]string{"--follow-track=2.0", "one"}, `--follow-track and --min-age need --follow`},
+		{[]string{"--min-age=1h", "one"}, `--follow-track and --min-age need --follow`},
+		{[]string{"--follow=beta"}, `following or unfollowing channels needs snap names`},
+		{[]string{"--unfollow"}, `following or unfollowing channels needs snap names`},
+		{[]string{"--follow=beta", "--amend", "one"}, `--follow and --unfollow do not take other refresh options`},
+		{[]string{"--follow=beta", "--hold", "one"}, `--hold, --unhold and --timer do not take other refresh options`},
+		{[]string{"--follow=beta", "--min-age=3d", "one"}, `cannot use minimum revision age "3d": not a positive duration`},
+		{[]string{"--follow=beta", "--min-age=-1h", "one"}, `cannot use minimum revision age "-1h": not a positive duration`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(append([]string{"refresh"}, t.args...))
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
 func (s *SnapOpSuite) TestRefreshAllModeFlags(c *check.C) {
 	s.RedirectClientToTestServer(nil)
 	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--devmode"})
@@ -1761,6 +1932,37 @@ func (s *SnapOpSuite) TestRefreshInsufficientDiskSpace(c *check.C) {
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *SnapOpSuite) TestRefreshInsufficientDiskSpaceReclaimable(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{
+			"type": "error",
+			"result": {
+				"message": "disk space error",
+				"kind": "insufficient-disk-space",
+				"value": {
+					"snap-names": ["foo"],
+					"change-kind": "refresh",
+					"required-space": 1000000,
+					"reclaimable": [
+						{"snap": "foo", "revision": "2", "size": 20000000},
+						{"path": "/var/lib/snapd/cache", "size": 5000}
+					]
+				},
+				"status-code": 507
+				}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "foo"})
+	c.Check(err, check.ErrorMatches, `cannot refresh "foo" due to low disk space
+
+ +The following could be removed to free up space:
+
+ +revision 2 of snap "foo" \(20.0MB\): snap remove foo --revision=2
+ +cached snap files in /var/lib/snapd/cache \(5000B\)`)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *SnapOpSuite) TestRemoveRevision(c *check.C) {
 	s.srv.total = 3
 	s.srv.checker = func(r *http.Request) {
@@ -32,10 +32,9 @@ import (
 )
 
 type cmdValidate struct {
-	clientMixin
-	Monitor bool `long:"monitor"`
-	// XXX: enforce mode is not supported yet
-	Enforce    bool `long:"enforce" hidden:"yes"`
+	waitMixin
+	Monitor    bool `long:"monitor"`
+	Enforce    bool `long:"enforce"`
 	Forget     bool `long:"forget"`
 	Positional struct {
 		ValidationSet string `positional-arg-name:"<validation-set>"`
@@ -49,7 +48,7 @@ The validate command lists or applies validations sets
 `)
 
 func init() {
-	cmd := addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, colorDescs.also(map[string]string{
+	cmd := addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, colorDescs.also(waitDescs).also(map[string]string{
 		// TRANSLATORS: This should not start with a lowercase letter.
 		"monitor": i18n.G("Monitor the given validations set"),
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -147,6 +146,17 @@ func (cmd *cmdValidate) Execute(args []string) error {
 		if cmd.Forget {
 			return cmd.client.ForgetValidationSet(accountID, name, seq)
 		}
+		if cmd.Enforce {
+			changeID, err := cmd.client.EnforceValidationSet(accountID, name, seq)
+			if err != nil || changeID == "" {
+				return err
+			}
+			// snaps required by the validation set are being installed
+			if _, err := cmd.wait(changeID); err != nil && err != noWait {
+				return err
+			}
+			return nil
+		}
 		// apply
 		opts := &client.ValidateApplyOptions{
 			Mode:     action,
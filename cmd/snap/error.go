@@ -253,6 +253,7 @@ If you understand and want to proceed repeat the command including --classic.
 				msg = fmt.Sprintf(i18n.G("cannot install %s due to low disk space"), names)
 			case "refresh":
 				msg = fmt.Sprintf(i18n.G("cannot refresh %s due to low disk space"), names)
+				msg += reclaimableSpaceHint(values)
 			default:
 				msg = err.Error()
 			}
@@ -276,6 +277,32 @@ If you understand and want to proceed repeat the command including --classic.
 	return msg, nil
 }
 
+// reclaimableSpaceHint returns a hint listing what could be removed to free
+// disk space, as reported in the values of an insufficient-disk-space error.
+func reclaimableSpaceHint(values map[string]interface{}) string {
+	reclaimable, _ := values["reclaimable"].([]interface{})
+	var lines []string
+	for _, v := range reclaimable {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		size, _ := item["size"].(float64)
+		if name, _ := item["snap"].(string); name != "" {
+			rev, _ := item["revision"].(string)
+			// TRANSLATORS: %s are a revision, a snap name, a size, and a command
+			lines = append(lines, fmt.Sprintf(i18n.G("revision %s of snap %q (%s): snap remove %s --revision=%s"), rev, name, strings.TrimSpace(fmtSize(int64(size))), name, rev))
+		} else if path, _ := item["path"].(string); path != "" {
+			// TRANSLATORS: %s are a directory and a size
+			lines = append(lines, fmt.Sprintf(i18n.G("cached snap files in %s (%s)"), path, strings.TrimSpace(fmtSize(int64(size)))))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n" + i18n.G("The following could be removed to free up space:") + "\n\n  " + strings.Join(lines, "\n  ")
+}
+
 func snapRevisionNotAvailableMessage(kind client.ErrorKind, snapName, action, arch, snapChannel string, releases []interface{}) string {
 	// releases contains all available (arch x channel)
 	// as reported by the store through the daemon
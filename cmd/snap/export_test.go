@@ -134,6 +134,10 @@ var (
 	MaybePrintSum               = (*infoWriter).maybePrintSum
 	MaybePrintCohortKey         = (*infoWriter).maybePrintCohortKey
 	MaybePrintHealth            = (*infoWriter).maybePrintHealth
+	MaybePrintRefreshHold       = (*infoWriter).maybePrintRefreshHold
+	MaybePrintTrackingPolicy    = (*infoWriter).maybePrintTrackingPolicy
+	SetupHistory                = (*infoWriter).setupHistory
+	MaybePrintRefreshHistory    = (*infoWriter).maybePrintRefreshHistory
 )
 
 func MockPollTime(d time.Duration) (restore func()) {
@@ -337,6 +341,14 @@ func MockCreateTransientScopeForTracking(fn func(securityTag string, opts *cgrou
 	}
 }
 
+func MockEgressRulesetMatchesProcess(fn func(snapName string, pid int) (bool, error)) (restore func()) {
+	old := egressRulesetMatchesProcess
+	egressRulesetMatchesProcess = fn
+	return func() {
+		egressRulesetMatchesProcess = old
+	}
+}
+
 func MockConfirmSystemdServiceTracking(fn func(securityTag string) error) (restore func()) {
 	old := cgroupConfirmSystemdServiceTracking
 	cgroupConfirmSystemdServiceTracking = fn
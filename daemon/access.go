@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/polkit"
+	"github.com/snapcore/snapd/sandbox/cgroup"
 )
 
 var polkitCheckAuthorization = polkit.CheckAuthorization
@@ -137,6 +138,29 @@ func (ac rootAccess) CheckAccess(d *Daemon, r *http.Request, ucred *ucrednet, us
 	return Forbidden("access denied")
 }
 
+var cgroupSnapNameFromPid = cgroup.SnapNameFromPid
+
+// snapProcessAccess allows requests about the snap named in the URL from
+// the root uid or from processes of the snap, provided they were not
+// received on snapd-snap.socket
+type snapProcessAccess struct{}
+
+func (ac snapProcessAccess) CheckAccess(d *Daemon, r *http.Request, ucred *ucrednet, user *auth.UserState) *apiError {
+	if rspe := requireSnapdSocket(ucred); rspe != nil {
+		return rspe
+	}
+
+	if ucred.Uid == 0 {
+		return nil
+	}
+
+	snapName, err := cgroupSnapNameFromPid(int(ucred.Pid))
+	if err == nil && snapName == muxVars(r)["name"] {
+		return nil
+	}
+	return Forbidden("access denied")
+}
+
 // snapAccess allows requests from the snapd-snap.socket
 type snapAccess struct{}
 
@@ -20,6 +20,7 @@
 package daemon_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 
@@ -211,6 +212,44 @@ func (s *accessSuite) TestRootAccess(c *C) {
 	c.Check(ac.CheckAccess(nil, nil, ucred, nil), IsNil)
 }
 
+func (s *accessSuite) TestSnapProcessAccess(c *C) {
+	var ac daemon.AccessChecker = daemon.SnapProcessAccess{}
+
+	restore := daemon.MockMuxVars(func(*http.Request) map[string]string {
+		return map[string]string{"name": "foo"}
+	})
+	defer restore()
+	snapNames := map[int]string{100: "foo", 200: "bar"}
+	restore = daemon.MockCgroupSnapNameFromPid(func(pid int) (string, error) {
+		if name, ok := snapNames[pid]; ok {
+			return name, nil
+		}
+		return "", errors.New("cannot find snap security tag")
+	})
+	defer restore()
+	req := httptest.NewRequest("POST", "/v2/snaps/foo/egress", nil)
+
+	// snapProcessAccess denies access without ucred or from snapd-snap.socket
+	c.Check(ac.CheckAccess(nil, req, nil, nil), DeepEquals, errForbidden)
+	ucred := &daemon.Ucrednet{Uid: 0, Pid: 100, Socket: dirs.SnapSocket}
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), DeepEquals, errForbidden)
+
+	// processes of the snap are granted access
+	ucred = &daemon.Ucrednet{Uid: 42, Pid: 100, Socket: dirs.SnapdSocket}
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), IsNil)
+
+	// but not the ones of other snaps or outside of snaps
+	ucred.Pid = 200
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), DeepEquals, errForbidden)
+	ucred.Pid = 300
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), DeepEquals, errForbidden)
+	c.Check(ac.CheckAccess(nil, req, ucred, &auth.UserState{}), DeepEquals, errForbidden)
+
+	// Root is granted access
+	ucred.Uid = 0
+	c.Check(ac.CheckAccess(nil, req, ucred, nil), IsNil)
+}
+
 func (s *accessSuite) TestSnapAccess(c *C) {
 	var ac daemon.AccessChecker = daemon.SnapAccess{}
 
@@ -47,6 +47,13 @@ var api = []*Command{
 	snapFileCmd,
 	snapDownloadCmd,
 	snapConfCmd,
+	snapHistoryCmd,
+	snapDenialsCmd,
+	snapEgressCmd,
+	promptsCmd,
+	promptCmd,
+	promptingRulesCmd,
+	promptingRuleCmd,
 	interfacesCmd,
 	assertsCmd,
 	assertsFindManyCmd,
@@ -307,6 +307,10 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getChangeTimings(st, chgID, ensureTag, startupTag, all == "true")
 	case "seeding":
 		return getSeedingInfo(st)
+	case "connection-policy":
+		return getConnectionPolicy(c, query.Get("plug"), query.Get("slot"))
+	case "sandbox-policy":
+		return getSandboxPolicy(c, query.Get("app"), query.Get("diff") == "true")
 	default:
 		return BadRequest("unknown debug aspect %q", aspect)
 	}
@@ -0,0 +1,143 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/snap"
+)
+
+type policyDecisionJSON struct {
+	Allowed bool `json:"allowed"`
+	// Declaration is "base-declaration" or "snap-declaration", unset
+	// if no rule applies.
+	Declaration string `json:"declaration,omitempty"`
+	// Snap is the snap of the snap-declaration.
+	Snap string `json:"snap,omitempty"`
+	// Side is "plug" or "slot" for the side of the deciding rule.
+	Side       string `json:"side,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	// Alternative is the index of the matching alternative of the
+	// constraint, -1 if none matched.
+	Alternative int    `json:"alternative"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type connectionPolicyJSON struct {
+	Plug interfaces.PlugRef `json:"plug"`
+	Slot interfaces.SlotRef `json:"slot"`
+
+	PlugInstallation *policyDecisionJSON `json:"plug-installation,omitempty"`
+	SlotInstallation *policyDecisionJSON `json:"slot-installation,omitempty"`
+	Connection       *policyDecisionJSON `json:"connection"`
+	AutoConnection   *policyDecisionJSON `json:"auto-connection"`
+
+	InterfaceAutoConnect bool     `json:"interface-auto-connect"`
+	Unasserted           []string `json:"unasserted,omitempty"`
+}
+
+func policyDecisionToJSON(d *policy.Decision) *policyDecisionJSON {
+	if d == nil {
+		return nil
+	}
+	return &policyDecisionJSON{
+		Allowed:     d.Allowed,
+		Declaration: d.Declaration,
+		Snap:        d.Snap,
+		Side:        d.Side,
+		Constraint:  d.Constraint,
+		Alternative: d.Alternative,
+		Reason:      d.Reason,
+	}
+}
+
+// splitPlugOrSlotRef splits <snap>:<plug or slot>, a reference without a
+// colon is a snap name.
+func splitPlugOrSlotRef(ref string) (snapName, name string) {
+	if i := strings.IndexRune(ref, ':'); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// getConnectionPolicy explains how the policy decides on connecting the
+// given plug to the given slot, or to each of the candidate slots of the
+// plug's interface when the slot or its name are omitted.
+func getConnectionPolicy(c *Command, plugRef, slotRef string) Response {
+	plugSnap, plugName := splitPlugOrSlotRef(plugRef)
+	if plugSnap == "" || plugName == "" {
+		return BadRequest("cannot explain connection policy: plug must be given as <snap>:<plug>")
+	}
+	plugSnap = ifacestate.RemapSnapFromRequest(plugSnap)
+
+	ifacemgr := c.d.overlord.InterfaceManager()
+	repo := ifacemgr.Repository()
+	plug := repo.Plug(plugSnap, plugName)
+	if plug == nil {
+		return NotFound("snap %q has no plug named %q", plugSnap, plugName)
+	}
+
+	var slots []*snap.SlotInfo
+	if slotRef == "" {
+		slots = repo.AllSlots(plug.Interface)
+	} else {
+		slotSnap, slotName := splitPlugOrSlotRef(slotRef)
+		if slotSnap == "" {
+			slotSnap = ifacestate.SystemSnapName()
+		}
+		slotSnap = ifacestate.RemapSnapFromRequest(slotSnap)
+		if slotName != "" {
+			slot := repo.Slot(slotSnap, slotName)
+			if slot == nil {
+				return NotFound("snap %q has no slot named %q", slotSnap, slotName)
+			}
+			slots = append(slots, slot)
+		} else {
+			for _, slot := range repo.Slots(slotSnap) {
+				if slot.Interface == plug.Interface {
+					slots = append(slots, slot)
+				}
+			}
+		}
+	}
+
+	result := make([]*connectionPolicyJSON, 0, len(slots))
+	for _, slot := range slots {
+		decisions, err := ifacemgr.ExplainConnectionPolicy(plug, slot)
+		if err != nil {
+			return InternalError("cannot explain connection policy: %v", err)
+		}
+		result = append(result, &connectionPolicyJSON{
+			Plug:                 decisions.Plug,
+			Slot:                 decisions.Slot,
+			PlugInstallation:     policyDecisionToJSON(decisions.PlugInstallation),
+			SlotInstallation:     policyDecisionToJSON(decisions.SlotInstallation),
+			Connection:           policyDecisionToJSON(decisions.Connection),
+			AutoConnection:       policyDecisionToJSON(decisions.AutoConnection),
+			InterfaceAutoConnect: decisions.InterfaceAutoConnect,
+			Unasserted:           decisions.Unasserted,
+		})
+	}
+	return SyncResponse(result)
+}
@@ -0,0 +1,146 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+)
+
+var _ = check.Suite(&connectionPolicyDebugSuite{})
+
+type connectionPolicyDebugSuite struct {
+	apiBaseSuite
+}
+
+func (s *connectionPolicyDebugSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.AddCleanup(assertstest.MockBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+slots:
+  test:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`)))
+
+	d := s.daemon(c)
+	st := d.Overlord().State()
+	st.Lock()
+	s.mockModel(c, st, nil)
+	st.Unlock()
+	mockIface(c, d, &ifacetest.TestInterface{InterfaceName: "test"})
+
+	s.mockSnap(c, `
+name: consumer
+version: 1
+plugs:
+  plug:
+    interface: test
+`)
+	s.mockSnap(c, `
+name: producer
+version: 1
+slots:
+  slot:
+    interface: test
+  other:
+    interface: test
+`)
+}
+
+func (s *connectionPolicyDebugSuite) getConnectionPolicy(c *check.C, query string) []interface{} {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=connection-policy&"+query, nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	data, err := json.Marshal(rsp.Result)
+	c.Assert(err, check.IsNil)
+	var result []interface{}
+	c.Assert(json.Unmarshal(data, &result), check.IsNil)
+	return result
+}
+
+func (s *connectionPolicyDebugSuite) TestConnectionPolicy(c *check.C) {
+	result := s.getConnectionPolicy(c, "plug=consumer:plug&slot=producer:slot")
+	c.Check(result, check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"plug": map[string]interface{}{"snap": "consumer", "plug": "plug"},
+			"slot": map[string]interface{}{"snap": "producer", "slot": "slot"},
+			"connection": map[string]interface{}{
+				"allowed":     true,
+				"declaration": "base-declaration",
+				"side":        "slot",
+				"constraint":  "allow-connection",
+				"alternative": 0.0,
+			},
+			"auto-connection": map[string]interface{}{
+				"allowed":     false,
+				"declaration": "base-declaration",
+				"side":        "slot",
+				"constraint":  "deny-auto-connection",
+				"alternative": 0.0,
+			},
+			"interface-auto-connect": true,
+			"unasserted":             []interface{}{"consumer", "producer"},
+		},
+	})
+}
+
+func (s *connectionPolicyDebugSuite) TestConnectionPolicyCandidateSlots(c *check.C) {
+	result := s.getConnectionPolicy(c, "plug=consumer:plug")
+	c.Assert(result, check.HasLen, 2)
+	c.Check(result[0].(map[string]interface{})["slot"], check.DeepEquals, map[string]interface{}{"snap": "producer", "slot": "other"})
+	c.Check(result[1].(map[string]interface{})["slot"], check.DeepEquals, map[string]interface{}{"snap": "producer", "slot": "slot"})
+
+	result = s.getConnectionPolicy(c, "plug=consumer:plug&slot=producer")
+	c.Check(result, check.HasLen, 2)
+
+	result = s.getConnectionPolicy(c, "plug=consumer:plug&slot=consumer")
+	c.Check(result, check.HasLen, 0)
+}
+
+func (s *connectionPolicyDebugSuite) TestConnectionPolicyErrors(c *check.C) {
+	for _, t := range []struct {
+		query  string
+		status int
+		err    string
+	}{
+		{"plug=consumer", 400, `cannot explain connection policy: plug must be given as <snap>:<plug>`},
+		{"slot=producer:slot", 400, `cannot explain connection policy: plug must be given as <snap>:<plug>`},
+		{"plug=consumer:missing", 404, `snap "consumer" has no plug named "missing"`},
+		{"plug=consumer:plug&slot=producer:missing", 404, `snap "producer" has no slot named "missing"`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/debug?aspect=connection-policy&"+t.query, nil)
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.query))
+		c.Check(rspe.Message, check.Equals, t.err, check.Commentf(t.query))
+	}
+}
@@ -0,0 +1,101 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"sort"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+type sandboxSnippetJSON struct {
+	Backend interfaces.SecuritySystem `json:"backend"`
+	// Tag is the security tag of the app or hook, or the name of the
+	// snap-wide profile.
+	Tag       string              `json:"tag"`
+	Interface string              `json:"interface"`
+	Plug      *interfaces.PlugRef `json:"plug,omitempty"`
+	Slot      *interfaces.SlotRef `json:"slot,omitempty"`
+	// Connection is the ID of the connection the snippet comes from,
+	// unset for the permanent snippets of a plug or slot.
+	Connection string   `json:"connection,omitempty"`
+	Snippet    string   `json:"snippet"`
+	Missing    []string `json:"missing,omitempty"`
+}
+
+// getSandboxPolicy returns the snippets of the sandbox policy generated by
+// the interfaces for the given app, or for all the apps and hooks of the
+// snap if only a snap is given.
+func getSandboxPolicy(c *Command, snapOrApp string, diff bool) Response {
+	snapName, appName := splitAppName(snapOrApp)
+	if snapName == "" {
+		return BadRequest("cannot show sandbox policy: app must be given as <snap>.<app> or <snap>")
+	}
+
+	st := c.d.overlord.State()
+	info, err := snapstate.CurrentInfo(st, snapName)
+	if err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(snapName, err)
+		}
+		return InternalError("cannot show sandbox policy: %v", err)
+	}
+
+	var securityTags []string
+	if appName != "" {
+		app, ok := info.Apps[appName]
+		if !ok {
+			return NotFound("snap %q has no app %q", snapName, appName)
+		}
+		securityTags = append(securityTags, app.SecurityTag())
+	} else {
+		for _, app := range info.Apps {
+			securityTags = append(securityTags, app.SecurityTag())
+		}
+		for _, hook := range info.Hooks {
+			securityTags = append(securityTags, hook.SecurityTag())
+		}
+		sort.Strings(securityTags)
+	}
+
+	snippets, err := c.d.overlord.InterfaceManager().SandboxPolicy(info.InstanceName(), securityTags, diff)
+	if err != nil {
+		return InternalError("cannot show sandbox policy: %v", err)
+	}
+	result := make([]*sandboxSnippetJSON, 0, len(snippets))
+	for _, snippet := range snippets {
+		s := &sandboxSnippetJSON{
+			Backend:   snippet.Backend,
+			Tag:       snippet.Tag,
+			Interface: snippet.Interface,
+			Plug:      snippet.Plug,
+			Slot:      snippet.Slot,
+			Snippet:   snippet.Snippet,
+			Missing:   snippet.Missing,
+		}
+		if snippet.Connection != nil {
+			s.Connection = snippet.Connection.ID()
+		}
+		result = append(result, s)
+	}
+	return SyncResponse(result)
+}
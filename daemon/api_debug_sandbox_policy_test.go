@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/snap"
+)
+
+var _ = check.Suite(&sandboxPolicyDebugSuite{})
+
+type sandboxPolicyDebugSuite struct {
+	apiBaseSuite
+}
+
+func (s *sandboxPolicyDebugSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	d := s.daemon(c)
+	repo := d.Overlord().InterfaceManager().Repository()
+	c.Assert(repo.AddBackend(&apparmor.Backend{}), check.IsNil)
+	mockIface(c, d, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		AppArmorPermanentPlugCallback: func(spec *apparmor.Specification, plug *snap.PlugInfo) error {
+			spec.AddSnippet("/permanent r,")
+			return nil
+		},
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("/connected r,")
+			return nil
+		},
+	})
+
+	s.mockSnap(c, `
+name: consumer
+version: 1
+apps:
+  app:
+    plugs: [plug]
+  other:
+hooks:
+  install:
+    plugs: [plug]
+plugs:
+  plug:
+    interface: test
+`)
+	s.mockSnap(c, `
+name: producer
+version: 1
+slots:
+  slot:
+    interface: test
+`)
+	_, err := repo.Connect(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}, nil, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *sandboxPolicyDebugSuite) getSandboxPolicy(c *check.C, query string) []interface{} {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=sandbox-policy&"+query, nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	data, err := json.Marshal(rsp.Result)
+	c.Assert(err, check.IsNil)
+	var result []interface{}
+	c.Assert(json.Unmarshal(data, &result), check.IsNil)
+	return result
+}
+
+func (s *sandboxPolicyDebugSuite) TestSandboxPolicy(c *check.C) {
+	result := s.getSandboxPolicy(c, "app=consumer.app")
+	c.Check(result, check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"backend":   "apparmor",
+			"tag":       "snap.consumer.app",
+			"interface": "test",
+			"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+			"snippet":   "/permanent r,",
+		},
+		map[string]interface{}{
+			"backend":    "apparmor",
+			"tag":        "snap.consumer.app",
+			"interface":  "test",
+			"plug":       map[string]interface{}{"snap": "consumer", "plug": "plug"},
+			"connection": "consumer:plug producer:slot",
+			"snippet":    "/connected r,",
+		},
+	})
+
+	result = s.getSandboxPolicy(c, "app=consumer.other")
+	c.Check(result, check.HasLen, 0)
+}
+
+func (s *sandboxPolicyDebugSuite) TestSandboxPolicySnap(c *check.C) {
+	result := s.getSandboxPolicy(c, "app=consumer")
+	c.Assert(result, check.HasLen, 4)
+	var tags []interface{}
+	for _, snippet := range result {
+		tags = append(tags, snippet.(map[string]interface{})["tag"])
+	}
+	c.Check(tags, check.DeepEquals, []interface{}{
+		"snap.consumer.app", "snap.consumer.hook.install",
+		"snap.consumer.app", "snap.consumer.hook.install",
+	})
+}
+
+func (s *sandboxPolicyDebugSuite) TestSandboxPolicyDiff(c *check.C) {
+	// no profiles are installed
+	result := s.getSandboxPolicy(c, "app=consumer.app&diff=true")
+	c.Assert(result, check.HasLen, 2)
+	c.Check(result[0].(map[string]interface{})["missing"], check.DeepEquals, []interface{}{"/permanent r,"})
+	c.Check(result[1].(map[string]interface{})["missing"], check.DeepEquals, []interface{}{"/connected r,"})
+}
+
+func (s *sandboxPolicyDebugSuite) TestSandboxPolicyErrors(c *check.C) {
+	for _, t := range []struct {
+		query  string
+		status int
+		err    string
+	}{
+		{"", 400, `cannot show sandbox policy: app must be given as <snap>.<app> or <snap>`},
+		{"app=missing.app", 404, `snap "missing" is not installed`},
+		{"app=consumer.missing", 404, `snap "consumer" has no app "missing"`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/debug?aspect=sandbox-policy&"+t.query, nil)
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.query))
+		c.Check(rspe.Message, check.Equals, t.err, check.Commentf(t.query))
+	}
+}
@@ -24,11 +24,16 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/snapcore/snapd/advisor"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/client/clientutil"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/httputil"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/auth"
@@ -115,31 +120,16 @@ func searchStore(c *Command, r *http.Request, user *auth.UserState) Response {
 	case store.ErrUnauthenticated, store.ErrInvalidCredentials:
 		return Unauthorized(err.Error())
 	default:
-		// XXX should these return 503 actually?
-		if e, ok := err.(*url.Error); ok {
-			if neterr, ok := e.Err.(*net.OpError); ok {
-				if dnserr, ok := neterr.Err.(*net.DNSError); ok {
-					return &apiError{
-						Status:  400,
-						Message: dnserr.Error(),
-						Kind:    client.ErrorKindDNSFailure,
-					}
+		if apiErr := storeNetworkError(err); apiErr != nil {
+			// an empty query lists what the store features, which
+			// the local catalog cannot answer
+			if strings.TrimSpace(q) != "" && section == "" && commonID == "" && !private {
+				// try to answer from the local catalog instead
+				if resp := searchLocalCatalog(route, q, prefix); resp != nil {
+					return resp
 				}
 			}
-		}
-		if e, ok := err.(net.Error); ok && e.Timeout() {
-			return &apiError{
-				Status:  400,
-				Message: err.Error(),
-				Kind:    client.ErrorKindNetworkTimeout,
-			}
-		}
-		if e, ok := err.(*httputil.PersistentNetworkError); ok {
-			return &apiError{
-				Status:  400,
-				Message: e.Error(),
-				Kind:    client.ErrorKindDNSFailure,
-			}
+			return apiErr
 		}
 
 		return InternalError("%v", err)
@@ -153,6 +143,79 @@ func searchStore(c *Command, r *http.Request, user *auth.UserState) Response {
 	return sendStorePackages(route, found, fresp)
 }
 
+// storeNetworkError returns the error response for failing to reach the
+// store, or nil if err is not a network error.
+func storeNetworkError(err error) *apiError {
+	// XXX should these return 503 actually?
+	if e, ok := err.(*url.Error); ok {
+		if neterr, ok := e.Err.(*net.OpError); ok {
+			if dnserr, ok := neterr.Err.(*net.DNSError); ok {
+				return &apiError{
+					Status:  400,
+					Message: dnserr.Error(),
+					Kind:    client.ErrorKindDNSFailure,
+				}
+			}
+		}
+	}
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return &apiError{
+			Status:  400,
+			Message: err.Error(),
+			Kind:    client.ErrorKindNetworkTimeout,
+		}
+	}
+	if e, ok := err.(*httputil.PersistentNetworkError); ok {
+		return &apiError{
+			Status:  400,
+			Message: e.Error(),
+			Kind:    client.ErrorKindDNSFailure,
+		}
+	}
+	return nil
+}
+
+// searchLocalCatalog searches the catalog periodically downloaded from
+// the store. It returns nil if there is no usable local catalog.
+func searchLocalCatalog(route *mux.Route, q string, prefix bool) Response {
+	st, err := os.Stat(dirs.SnapCommandsDB)
+	if err != nil {
+		return nil
+	}
+	pkgs, err := advisor.SearchPackages(q, prefix)
+	if err != nil {
+		logger.Noticef("cannot search the local catalog: %v", err)
+		return nil
+	}
+
+	results := make([]*json.RawMessage, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		url, err := route.URL("name", pkg.Snap)
+		if err != nil {
+			logger.Noticef("Cannot build URL for snap %q: %v", pkg.Snap, err)
+			continue
+		}
+		data, err := json.Marshal(webify(&client.Snap{
+			Name:    pkg.Snap,
+			Version: pkg.Version,
+			Summary: pkg.Summary,
+			Status:  client.StatusAvailable,
+		}, url.String()))
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		raw := json.RawMessage(data)
+		results = append(results, &raw)
+	}
+
+	catalogTime := st.ModTime()
+	return &findResponse{
+		Results:          results,
+		Sources:          []string{"local-catalog"},
+		CatalogTimestamp: &catalogTime,
+	}
+}
+
 func findOne(c *Command, r *http.Request, user *auth.UserState, name string) Response {
 	if err := snap.ValidateName(name); err != nil {
 		return BadRequest(err.Error())
@@ -250,6 +313,9 @@ type findResponse struct {
 	Results           interface{}
 	Sources           []string
 	SuggestedCurrency string
+	// CatalogTimestamp is set when the results come from the local
+	// catalog, which may be out of date.
+	CatalogTimestamp *time.Time
 }
 
 func (r *findResponse) JSON() *respJSON {
@@ -259,6 +325,7 @@ func (r *findResponse) JSON() *respJSON {
 		Result:            r.Results,
 		Sources:           r.Sources,
 		SuggestedCurrency: r.SuggestedCurrency,
+		CatalogTimestamp:  r.CatalogTimestamp,
 	}
 }
 
@@ -25,10 +25,14 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"time"
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/advisor"
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/httputil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -465,6 +469,79 @@ func (s *findSuite) TestFindNetworkErrorsReturnCorrectErrorKind(c *check.C) {
 	}
 }
 
+func (s *findSuite) TestFindNetworkErrorFallsBackToLocalCatalog(c *check.C) {
+	s.daemon(c)
+
+	c.Assert(os.MkdirAll(dirs.SnapCacheDir, 0755), check.IsNil)
+	db, err := advisor.Create()
+	c.Assert(err, check.IsNil)
+	c.Assert(db.AddSnap("banana", "1.0", "a yellow fruit", nil), check.IsNil)
+	c.Assert(db.AddSnap("apple", "2.0", "a green fruit", []string{"apple"}), check.IsNil)
+	c.Assert(db.Commit(), check.IsNil)
+	catalogTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	c.Assert(os.Chtimes(dirs.SnapCommandsDB, catalogTime, catalogTime), check.IsNil)
+
+	s.err = &httputil.PersistentNetworkError{Err: errors.New("problem")}
+
+	req, err := http.NewRequest("GET", "/v2/find?q=yellow", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+
+	c.Check(rsp.Sources, check.DeepEquals, []string{"local-catalog"})
+	c.Assert(rsp.CatalogTimestamp, check.NotNil)
+	c.Check(rsp.CatalogTimestamp.Equal(catalogTime), check.Equals, true)
+	snaps := snapList(rsp.Result)
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0]["name"], check.Equals, "banana")
+	c.Check(snaps[0]["version"], check.Equals, "1.0")
+	c.Check(snaps[0]["summary"], check.Equals, "a yellow fruit")
+	c.Check(snaps[0]["status"], check.Equals, "available")
+
+	// name prefixes are supported too
+	req, err = http.NewRequest("GET", "/v2/find?name=app*", nil)
+	c.Assert(err, check.IsNil)
+	rsp = s.syncReq(c, req, nil)
+	snaps = snapList(rsp.Result)
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0]["name"], check.Equals, "apple")
+
+	// but not sections
+	req, err = http.NewRequest("GET", "/v2/find?q=yellow&section=food", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindDNSFailure)
+}
+
+func (s *findSuite) TestFindNetworkErrorEmptyQueryNoLocalCatalog(c *check.C) {
+	s.daemon(c)
+
+	c.Assert(os.MkdirAll(dirs.SnapCacheDir, 0755), check.IsNil)
+	db, err := advisor.Create()
+	c.Assert(err, check.IsNil)
+	c.Assert(db.AddSnap("banana", "1.0", "a yellow fruit", nil), check.IsNil)
+	c.Assert(db.Commit(), check.IsNil)
+
+	s.err = &httputil.PersistentNetworkError{Err: errors.New("problem")}
+
+	for _, u := range []string{"/v2/find", "/v2/find?q=%20", "/v2/find?name=*"} {
+		req, err := http.NewRequest("GET", u, nil)
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Kind, check.Equals, client.ErrorKindDNSFailure, check.Commentf("%s", u))
+	}
+}
+
+func (s *findSuite) TestFindNetworkErrorNoLocalCatalog(c *check.C) {
+	s.daemon(c)
+
+	s.err = &httputil.PersistentNetworkError{Err: errors.New("problem")}
+
+	req, err := http.NewRequest("GET", "/v2/find?q=yellow", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindDNSFailure)
+}
+
 func (s *findSuite) TestFindPriced(c *check.C) {
 	s.daemon(c)
 
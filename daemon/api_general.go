@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox"
@@ -108,6 +109,7 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	nextRefresh := snapMgr.NextRefresh()
 	lastRefresh, _ := snapMgr.LastRefresh()
 	refreshHold, _ := snapMgr.EffectiveRefreshHold()
+	refreshDeferral, _ := snapMgr.LastRefreshDeferral()
 	refreshScheduleStr, legacySchedule, err := snapMgr.RefreshSchedule()
 	if err != nil {
 		return InternalError("cannot get refresh schedule: %s", err)
@@ -122,6 +124,10 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		Hold: formatRefreshTime(refreshHold),
 		Next: formatRefreshTime(nextRefresh),
 	}
+	if refreshDeferral != nil {
+		refreshInfo.Deferred = formatRefreshTime(refreshDeferral.Time)
+		refreshInfo.DeferReason = refreshDeferral.Reason
+	}
 	if !legacySchedule {
 		refreshInfo.Timer = refreshScheduleStr
 	} else {
@@ -389,6 +395,16 @@ func change2changeInfo(chg *state.Change) *changeInfo {
 	if chg.Get("api-data", &data) == nil {
 		chgInfo.Data = data
 	}
+	if done, total, ok := snapstate.DownloadProgress(chg); ok {
+		progress, err := json.Marshal(map[string]int64{"done": done, "total": total})
+		if err == nil {
+			if chgInfo.Data == nil {
+				chgInfo.Data = make(map[string]*json.RawMessage)
+			}
+			raw := json.RawMessage(progress)
+			chgInfo.Data["download-progress"] = &raw
+		}
+	}
 
 	return chgInfo
 }
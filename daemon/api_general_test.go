@@ -38,9 +38,11 @@ import (
 	"github.com/snapcore/snapd/interfaces/ifacetest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox"
+	"github.com/snapcore/snapd/snap"
 )
 
 var _ = check.Suite(&generalSuite{})
@@ -223,6 +225,32 @@ func (s *generalSuite) TestSysInfoLegacyRefresh(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, expected)
 }
 
+func (s *generalSuite) TestSysInfoRefreshDeferred(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/system-info", nil)
+	c.Assert(err, check.IsNil)
+
+	d := s.daemon(c)
+
+	deferred := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("last-refresh-deferral", &snapstate.RefreshDeferral{
+		Time:   deferred,
+		Reason: "on battery power",
+	})
+	st.Unlock()
+
+	rec := httptest.NewRecorder()
+	s.req(c, req, nil).ServeHTTP(rec, nil)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var rsp daemon.RespJSON
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), check.IsNil)
+	refresh := rsp.Result.(map[string]interface{})["refresh"].(map[string]interface{})
+	c.Check(refresh["deferred"], check.Equals, deferred.Format(time.RFC3339))
+	c.Check(refresh["defer-reason"], check.Equals, "on battery power")
+}
+
 func (s *generalSuite) testSysInfoSystemMode(c *check.C, mode string) {
 	req, err := http.NewRequest("GET", "/v2/system-info", nil)
 	c.Assert(err, check.IsNil)
@@ -594,6 +622,44 @@ func (s *generalSuite) TestStateChange(c *check.C) {
 	})
 }
 
+func (s *generalSuite) TestStateChangeDownloadProgress(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("refresh-snap", "refresh...")
+	for _, size := range []int64{100, 200} {
+		t := st.NewTask("download-snap", "download...")
+		t.Set("snap-setup", &snapstate.SnapSetup{
+			SideInfo:     &snap.SideInfo{RealName: "foo"},
+			DownloadInfo: &snap.DownloadInfo{Size: size},
+		})
+		chg.AddTask(t)
+		if size == 100 {
+			t.SetStatus(state.DoneStatus)
+		}
+	}
+	chg.Set("api-data", map[string]interface{}{"snap-names": []string{"foo"}})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/changes/"+chg.ID(), nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var body struct {
+		Result struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"result"`
+	}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &body), check.IsNil)
+	c.Check(body.Result.Data, check.DeepEquals, map[string]interface{}{
+		"snap-names":        []interface{}{"foo"},
+		"download-progress": map[string]interface{}{"done": 100., "total": 300.},
+	})
+}
+
 func (s *generalSuite) expectManageAccess() {
 	s.expectWriteAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage"})
 }
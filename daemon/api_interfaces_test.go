@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -177,6 +178,130 @@ func (s *interfacesSuite) TestConnectPlugSuccess(c *check.C) {
 	}})
 }
 
+func (s *interfacesSuite) TestConnectPlugExpiresAt(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	d := s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	action := &client.InterfaceAction{
+		Action:    "connect",
+		Plugs:     []client.Plug{{Snap: "consumer", Name: "plug"}},
+		Slots:     []client.Slot{{Snap: "producer", Name: "slot"}},
+		ExpiresAt: &expiresAt,
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	buf := bytes.NewBuffer(text)
+	req, err := http.NewRequest("POST", "/v2/interfaces", buf)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	s.req(c, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 202)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	id := body["change"].(string)
+
+	st := d.Overlord().State()
+	st.Lock()
+	chg := st.Change(id)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+
+	<-chg.Ready()
+
+	st.Lock()
+	defer st.Unlock()
+	c.Assert(chg.Err(), check.IsNil)
+
+	var conns map[string]map[string]interface{}
+	c.Assert(st.Get("conns", &conns), check.IsNil)
+	c.Check(conns["consumer:plug producer:slot"]["expires-at"], check.Equals, expiresAt.Format(time.RFC3339))
+}
+
+func (s *interfacesSuite) TestConnectPlugExpiresAtAlreadyConnected(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	d := s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+	st.Unlock()
+
+	d.Overlord().Loop()
+	defer d.Overlord().Stop()
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	action := &client.InterfaceAction{
+		Action:    "connect",
+		Plugs:     []client.Plug{{Snap: "consumer", Name: "plug"}},
+		Slots:     []client.Slot{{Snap: "producer", Name: "slot"}},
+		ExpiresAt: &expiresAt,
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus)
+
+	var conns map[string]map[string]interface{}
+	c.Assert(st.Get("conns", &conns), check.IsNil)
+	c.Check(conns["consumer:plug producer:slot"]["expires-at"], check.Equals, expiresAt.Format(time.RFC3339))
+}
+
+func (s *interfacesSuite) TestInterfaceActionExpiresAtErrors(c *check.C) {
+	s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	past := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	future := time.Now().Add(time.Hour)
+	for _, t := range []struct {
+		action    string
+		expiresAt *time.Time
+		err       string
+	}{
+		{"connect", &past, fmt.Sprintf("cannot connect until %s: time is in the past", past.Format(time.RFC3339))},
+		{"disconnect", &future, "expiry time is only supported by connect actions"},
+	} {
+		action := &client.InterfaceAction{
+			Action:    t.action,
+			Plugs:     []client.Plug{{Snap: "consumer", Name: "plug"}},
+			Slots:     []client.Slot{{Snap: "producer", Name: "slot"}},
+			ExpiresAt: t.expiresAt,
+		}
+		text, err := json.Marshal(action)
+		c.Assert(err, check.IsNil)
+		req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, 400)
+		c.Check(rspe.Message, check.Equals, t.err)
+	}
+}
+
 func (s *interfacesSuite) TestConnectPlugFailureInterfaceMismatch(c *check.C) {
 	d := s.daemon(c)
 
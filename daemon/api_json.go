@@ -20,6 +20,8 @@
 package daemon
 
 import (
+	"time"
+
 	"github.com/snapcore/snapd/interfaces"
 )
 
@@ -62,6 +64,9 @@ type interfaceAction struct {
 	Forget bool       `json:"forget,omitempty"`
 	Plugs  []plugJSON `json:"plugs,omitempty"`
 	Slots  []slotJSON `json:"slots,omitempty"`
+	// ExpiresAt is when a connection made by a connect action is
+	// disconnected automatically.
+	ExpiresAt *time.Time `json:"expires-at,omitempty"`
 }
 
 // connectionsJSON aids in marshalling information about a single connection
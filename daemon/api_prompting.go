@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+)
+
+// the prompting API is open to all users, they can only see and act on
+// their own prompts and rules, except for root
+
+var (
+	promptsCmd = &Command{
+		Path:       "/v2/prompting/prompts",
+		GET:        getPrompts,
+		ReadAccess: openAccess{},
+	}
+
+	promptCmd = &Command{
+		Path:        "/v2/prompting/prompts/{id}",
+		POST:        postPrompt,
+		WriteAccess: openAccess{},
+	}
+
+	promptingRulesCmd = &Command{
+		Path:       "/v2/prompting/rules",
+		GET:        getPromptingRules,
+		ReadAccess: openAccess{},
+	}
+
+	promptingRuleCmd = &Command{
+		Path:        "/v2/prompting/rules/{id}",
+		POST:        postPromptingRule,
+		WriteAccess: openAccess{},
+	}
+)
+
+func promptingErrorResponse(err error) Response {
+	switch err.(type) {
+	case *ifacestate.PromptReplyError:
+		return BadRequest(err.Error())
+	}
+	switch err {
+	case ifacestate.ErrPromptingDisabled:
+		return BadRequest(err.Error())
+	case ifacestate.ErrNoPrompt, ifacestate.ErrNoPromptingRule:
+		return NotFound(err.Error())
+	}
+	return InternalError(err.Error())
+}
+
+func getPrompts(c *Command, r *http.Request, user *auth.UserState) Response {
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return Forbidden("cannot get remote user: %s", err)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	prompts, err := ifacestate.Prompts(st, int(ucred.Uid))
+	if err != nil {
+		return promptingErrorResponse(err)
+	}
+	if prompts == nil {
+		prompts = []*ifacestate.Prompt{}
+	}
+	return SyncResponse(prompts)
+}
+
+type promptReplyJSON struct {
+	Outcome     string   `json:"outcome"`
+	Lifespan    string   `json:"lifespan"`
+	PathPattern string   `json:"path-pattern"`
+	Permissions []string `json:"permissions"`
+}
+
+func postPrompt(c *Command, r *http.Request, user *auth.UserState) Response {
+	id := muxVars(r)["id"]
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return Forbidden("cannot get remote user: %s", err)
+	}
+
+	var reply promptReplyJSON
+	if err := json.NewDecoder(r.Body).Decode(&reply); err != nil {
+		return BadRequest("cannot decode prompt reply from request body: %v", err)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	rule, err := ifacestate.ReplyToPrompt(st, int(ucred.Uid), id, &ifacestate.PromptReply{
+		Outcome:     reply.Outcome,
+		Lifespan:    reply.Lifespan,
+		PathPattern: reply.PathPattern,
+		Permissions: reply.Permissions,
+	})
+	if err != nil {
+		return promptingErrorResponse(err)
+	}
+	// apply the rule to the profiles of the snap
+	ensureStateSoon(st)
+	return SyncResponse(rule)
+}
+
+func getPromptingRules(c *Command, r *http.Request, user *auth.UserState) Response {
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return Forbidden("cannot get remote user: %s", err)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	rules, err := ifacestate.PromptingRules(st, int(ucred.Uid))
+	if err != nil {
+		return promptingErrorResponse(err)
+	}
+	if rules == nil {
+		rules = []*ifacestate.PromptingRule{}
+	}
+	return SyncResponse(rules)
+}
+
+type promptingRuleAction struct {
+	Action string `json:"action"`
+}
+
+func postPromptingRule(c *Command, r *http.Request, user *auth.UserState) Response {
+	id := muxVars(r)["id"]
+	ucred, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return Forbidden("cannot get remote user: %s", err)
+	}
+
+	var action promptingRuleAction
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+		return BadRequest("cannot decode prompting rule action from request body: %v", err)
+	}
+	if action.Action != "revoke" {
+		return BadRequest("unsupported prompting rule action %q", action.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := ifacestate.RevokePromptingRule(st, int(ucred.Uid), id); err != nil {
+		return promptingErrorResponse(err)
+	}
+	ensureStateSoon(st)
+	return SyncResponse(nil)
+}
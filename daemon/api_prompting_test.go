@@ -0,0 +1,231 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"net/http"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = check.Suite(&promptingSuite{})
+
+type promptingSuite struct {
+	apiBaseSuite
+
+	st         *state.State
+	uid        uint32
+	home       string
+	ensureSoon int
+}
+
+func (s *promptingSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectReadAccess(daemon.OpenAccess{})
+	s.expectWriteAccess(daemon.OpenAccess{})
+
+	// the prompts are of the user running the tests, for replies to
+	// be checked against their home directory
+	u, err := user.Current()
+	c.Assert(err, check.IsNil)
+	uid, err := strconv.Atoi(u.Uid)
+	c.Assert(err, check.IsNil)
+	s.uid = uint32(uid)
+	s.home = filepath.Clean(u.HomeDir)
+	s.mockPeerUid(s.uid)
+	s.ensureSoon = 0
+	_, restore := daemon.MockEnsureStateSoon(func(*state.State) {
+		s.ensureSoon++
+	})
+	s.AddCleanup(restore)
+
+	d := s.daemon(c)
+	s.st = d.Overlord().State()
+	s.st.Lock()
+	defer s.st.Unlock()
+	tr := config.NewTransaction(s.st)
+	tr.Set("core", "experimental.prompting", true)
+	tr.Commit()
+	s.st.Set("prompts", []*ifacestate.Prompt{{
+		ID:          "1",
+		Timestamp:   time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		User:        uid,
+		Snap:        "consumer",
+		Path:        s.home + "/doc.txt",
+		Permissions: []string{"read"},
+	}})
+	s.st.Set("prompting-rules", []*ifacestate.PromptingRule{{
+		ID:          "2",
+		Timestamp:   time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		User:        uid,
+		Snap:        "consumer",
+		PathPattern: s.home + "/Music/**",
+		Permissions: []string{"read"},
+		Outcome:     "allow",
+		Lifespan:    "always",
+	}})
+	s.st.Set("prompting-last-id", 2)
+}
+
+func (s *promptingSuite) mockPeerUid(uid uint32) {
+	s.AddCleanup(daemon.MockUcrednetGet(func(string) (*daemon.Ucrednet, error) {
+		return &daemon.Ucrednet{Uid: uid, Pid: 100, Socket: dirs.SnapdSocket}, nil
+	}))
+}
+
+func (s *promptingSuite) TestGetPrompts(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/prompting/prompts", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []*ifacestate.Prompt{{
+		ID:          "1",
+		Timestamp:   time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		User:        int(s.uid),
+		Snap:        "consumer",
+		Path:        s.home + "/doc.txt",
+		Permissions: []string{"read"},
+	}})
+
+	// other users do not see them
+	s.mockPeerUid(s.uid + 1)
+	rsp = s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []*ifacestate.Prompt{})
+}
+
+func (s *promptingSuite) TestPostPrompt(c *check.C) {
+	body := `{"outcome": "allow", "lifespan": "always", "path-pattern": "` + s.home + `/*.txt", "permissions": ["read"]}`
+	req, err := http.NewRequest("POST", "/v2/prompting/prompts/1", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	rule, ok := rsp.Result.(*ifacestate.PromptingRule)
+	c.Assert(ok, check.Equals, true)
+	c.Check(rule.ID, check.Equals, "3")
+	c.Check(rule.PathPattern, check.Equals, s.home+"/*.txt")
+	c.Check(rule.Outcome, check.Equals, "allow")
+	c.Check(s.ensureSoon, check.Equals, 1)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	prompts, err := ifacestate.Prompts(s.st, 0)
+	c.Assert(err, check.IsNil)
+	c.Check(prompts, check.HasLen, 0)
+}
+
+func (s *promptingSuite) TestPostPromptErrors(c *check.C) {
+	for _, t := range []struct {
+		id     string
+		body   string
+		status int
+		msg    string
+	}{
+		{"1", `{`, 400, `cannot decode prompt reply from request body: .*`},
+		{"1", `{"outcome": "maybe"}`, 400, `cannot reply to prompt 1: invalid outcome "maybe"`},
+		{"1", `{"outcome": "allow", "lifespan": "once", "path-pattern": "/etc/*", "permissions": ["read"]}`, 400, `cannot reply to prompt 1: path pattern "/etc/\*" must be within .*`},
+		{"42", `{"outcome": "allow"}`, 404, `cannot find prompt`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/prompting/prompts/"+t.id, bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rspe.Message, check.Matches, t.msg, check.Commentf(t.body))
+	}
+
+	// the prompts of other users cannot be replied to
+	s.mockPeerUid(s.uid + 1)
+	req, err := http.NewRequest("POST", "/v2/prompting/prompts/1", bytes.NewBufferString(`{}`))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+}
+
+func (s *promptingSuite) TestPromptingDisabled(c *check.C) {
+	s.st.Lock()
+	tr := config.NewTransaction(s.st)
+	tr.Set("core", "experimental.prompting", false)
+	tr.Commit()
+	s.st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/prompting/prompts", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, "experimental feature disabled - test it by setting 'experimental.prompting' to true")
+}
+
+func (s *promptingSuite) TestGetPromptingRules(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/prompting/rules", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	rules, ok := rsp.Result.([]*ifacestate.PromptingRule)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(rules, check.HasLen, 1)
+	c.Check(rules[0].ID, check.Equals, "2")
+
+	s.mockPeerUid(s.uid + 1)
+	rsp = s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []*ifacestate.PromptingRule{})
+}
+
+func (s *promptingSuite) TestRevokePromptingRule(c *check.C) {
+	req, err := http.NewRequest("POST", "/v2/prompting/rules/2", bytes.NewBufferString(`{"action": "revoke"}`))
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(s.ensureSoon, check.Equals, 1)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	rules, err := ifacestate.PromptingRules(s.st, 0)
+	c.Assert(err, check.IsNil)
+	c.Check(rules, check.HasLen, 0)
+}
+
+func (s *promptingSuite) TestRevokePromptingRuleErrors(c *check.C) {
+	for _, t := range []struct {
+		id     string
+		body   string
+		status int
+		msg    string
+	}{
+		{"2", `{`, 400, `cannot decode prompting rule action from request body: .*`},
+		{"2", `{"action": "forget"}`, 400, `unsupported prompting rule action "forget"`},
+		{"42", `{"action": "revoke"}`, 404, `cannot find prompting rule`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/prompting/rules/"+t.id, bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rspe.Message, check.Matches, t.msg, check.Commentf(t.body))
+	}
+}
@@ -0,0 +1,85 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/sandbox/denials"
+	"github.com/snapcore/snapd/snap"
+)
+
+var snapDenialsCmd = &Command{
+	Path:       "/v2/snaps/{name}/denials",
+	GET:        getSnapDenials,
+	ReadAccess: authenticatedAccess{Polkit: polkitActionManage},
+}
+
+type denialJSON struct {
+	*denials.Denial
+	// SuggestedInterfaces are the interfaces that would have allowed
+	// the denied access.
+	SuggestedInterfaces []string `json:"suggested-interfaces,omitempty"`
+}
+
+func getSnapDenials(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	name := vars["name"]
+
+	ifacemgr := c.d.overlord.InterfaceManager()
+	// the denials logged since the last collection are collected
+	// without holding the state lock, the ones already collected are
+	// returned even if this fails
+	if err := ifacemgr.CollectDenials(); err != nil {
+		logger.Noticef("cannot collect sandbox denials: %v", err)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if _, err := snapstate.CurrentInfo(st, name); err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(name, err)
+		}
+		return InternalError("cannot get denials of snap %q: %v", name, err)
+	}
+
+	recent, err := ifacestate.Denials(st, name)
+	if err == ifacestate.ErrSandboxDenialsDisabled {
+		return BadRequest(err.Error())
+	}
+	if err != nil {
+		return InternalError("cannot get denials of snap %q: %v", name, err)
+	}
+	result := make([]*denialJSON, 0, len(recent))
+	for _, d := range recent {
+		suggested, err := ifacemgr.SuggestInterfaces(d)
+		if err != nil {
+			return InternalError("cannot suggest interfaces for denials of snap %q: %v", name, err)
+		}
+		result = append(result, &denialJSON{Denial: d, SuggestedInterfaces: suggested})
+	}
+	return SyncResponse(result)
+}
@@ -0,0 +1,165 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/systemd"
+)
+
+var _ = check.Suite(&snapDenialsSuite{})
+
+type snapDenialsSuite struct {
+	apiBaseSuite
+
+	journal string
+}
+
+const denialsJournal = `{"__CURSOR": "c1", "__REALTIME_TIMESTAMP": "1614592801000000", "MESSAGE": "audit: type=1400 audit(1614592801.123:45): apparmor=\"DENIED\" operation=\"open\" profile=\"snap.consumer.app\" name=\"/etc/foo\" pid=1 comm=\"app\" requested_mask=\"r\" denied_mask=\"r\" fsuid=0 ouid=0"}
+{"__CURSOR": "c2", "__REALTIME_TIMESTAMP": "1614592802000000", "MESSAGE": "audit: type=1326 audit(1614592802.123:46): auid=0 uid=0 gid=0 ses=1 subj=snap.consumer.app (enforce) pid=1 comm=\"app\" exe=\"/snap/consumer/1/app\" sig=0 arch=c000003e syscall=165 compat=0 ip=0x7f code=0x50000"}
+`
+
+func (s *snapDenialsSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectReadAccess(daemon.AuthenticatedAccess{Polkit: "io.snapcraft.snapd.manage"})
+
+	s.journal = denialsJournal
+	s.AddCleanup(systemd.MockJournalctlAudit(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		if s.journal == "" {
+			return nil, errors.New("no journal")
+		}
+		return ioutil.NopCloser(strings.NewReader(s.journal)), nil
+	}))
+
+	d := s.daemon(c)
+	st := d.Overlord().State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "experimental.sandbox-denials", true)
+	tr.Commit()
+	st.Unlock()
+
+	mockIface(c, d, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("/etc/foo r,")
+			return nil
+		},
+	})
+	s.mockSnap(c, `
+name: core
+version: 1
+type: os
+slots:
+  test:
+`)
+	s.mockSnap(c, `
+name: consumer
+version: 1
+apps:
+  app:
+`)
+}
+
+func (s *snapDenialsSuite) getDenials(c *check.C, name string) []interface{} {
+	req, err := http.NewRequest("GET", "/v2/snaps/"+name+"/denials", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	data, err := json.Marshal(rsp.Result)
+	c.Assert(err, check.IsNil)
+	var result []interface{}
+	c.Assert(json.Unmarshal(data, &result), check.IsNil)
+	return result
+}
+
+func (s *snapDenialsSuite) TestGetDenials(c *check.C) {
+	c.Check(s.getDenials(c, "consumer"), check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"time":                 "2021-03-01T10:00:01Z",
+			"count":                1.0,
+			"sandbox":              "apparmor",
+			"snap":                 "consumer",
+			"tag":                  "snap.consumer.app",
+			"comm":                 "app",
+			"operation":            "open",
+			"name":                 "/etc/foo",
+			"requested-mask":       "r",
+			"denied-mask":          "r",
+			"fsuid":                "0",
+			"suggested-interfaces": []interface{}{"test"},
+		},
+		map[string]interface{}{
+			"time":    "2021-03-01T10:00:02Z",
+			"count":   1.0,
+			"sandbox": "seccomp",
+			"snap":    "consumer",
+			"tag":     "snap.consumer.app",
+			"comm":    "app",
+			"syscall": "165",
+			"arch":    "c000003e",
+		},
+	})
+
+	c.Check(s.getDenials(c, "core"), check.HasLen, 0)
+}
+
+func (s *snapDenialsSuite) TestGetDenialsCollectError(c *check.C) {
+	// the denials collected before are returned
+	c.Check(s.getDenials(c, "consumer"), check.HasLen, 2)
+	s.journal = ""
+	c.Check(s.getDenials(c, "consumer"), check.HasLen, 2)
+}
+
+func (s *snapDenialsSuite) TestGetDenialsNotInstalled(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/snaps/missing/denials", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+	c.Check(rspe.Message, check.Equals, `snap "missing" is not installed`)
+}
+
+func (s *snapDenialsSuite) TestGetDenialsDisabled(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	tr := config.NewTransaction(st)
+	tr.Set("core", "experimental.sandbox-denials", false)
+	tr.Commit()
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/consumer/denials", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `experimental feature disabled - test it by setting 'experimental.sandbox-denials' to true`)
+}
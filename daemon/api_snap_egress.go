@@ -0,0 +1,59 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+var snapEgressCmd = &Command{
+	Path: "/v2/snaps/{name}/egress",
+	POST: postSnapEgress,
+	// snap run updates the ruleset from the cgroup of the application it
+	// starts, for any user
+	WriteAccess: snapProcessAccess{},
+}
+
+// postSnapEgress updates the egress ruleset of the snap with the cgroups
+// its applications are currently tracked in.
+func postSnapEgress(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	name := vars["name"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if _, err := snapstate.CurrentInfo(st, name); err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(name, err)
+		}
+		return InternalError("cannot update egress ruleset of snap %q: %v", name, err)
+	}
+
+	if err := c.d.overlord.InterfaceManager().UpdateEgressCgroups(name); err != nil {
+		return InternalError("cannot update egress ruleset of snap %q: %v", name, err)
+	}
+	return SyncResponse(nil)
+}
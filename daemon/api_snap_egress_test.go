@@ -0,0 +1,90 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/egress"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&snapEgressSuite{})
+
+type snapEgressSuite struct {
+	apiBaseSuite
+
+	nftCmd *testutil.MockCmd
+}
+
+func (s *snapEgressSuite) SetUpTest(c *check.C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.expectWriteAccess(daemon.SnapProcessAccess{})
+
+	s.nftCmd = testutil.MockCommand(c, "nft", "")
+	s.AddCleanup(s.nftCmd.Restore)
+
+	d := s.daemon(c)
+	c.Assert(d.Overlord().InterfaceManager().Repository().AddBackend(&egress.Backend{}), check.IsNil)
+	s.mockSnap(c, `
+name: consumer
+version: 1
+apps:
+  app:
+`)
+}
+
+func (s *snapEgressSuite) TestPostEgress(c *check.C) {
+	req, err := http.NewRequest("POST", "/v2/snaps/consumer/egress", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	// the snap has no ruleset
+	c.Check(s.nftCmd.Calls(), check.HasLen, 0)
+}
+
+func (s *snapEgressSuite) TestPostEgressError(c *check.C) {
+	// the ruleset of a snap no longer restricted cannot be unloaded
+	c.Assert(os.MkdirAll(dirs.SnapEgressDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapEgressDir, "snap.consumer.nft"), nil, 0644), check.IsNil)
+	s.nftCmd = testutil.MockCommand(c, "nft", "echo 'no such table'; exit 1")
+	s.AddCleanup(s.nftCmd.Restore)
+
+	req, err := http.NewRequest("POST", "/v2/snaps/consumer/egress", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 500)
+	c.Check(rspe.Message, check.Equals, `cannot update egress ruleset of snap "consumer": cannot unload egress ruleset of snap "consumer": no such table`)
+}
+
+func (s *snapEgressSuite) TestPostEgressNotInstalled(c *check.C) {
+	req, err := http.NewRequest("POST", "/v2/snaps/missing/egress", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+	c.Check(rspe.Message, check.Equals, `snap "missing" is not installed`)
+}
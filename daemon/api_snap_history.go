@@ -0,0 +1,62 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var snapHistoryCmd = &Command{
+	Path:       "/v2/snaps/{name}/history",
+	GET:        getSnapHistory,
+	ReadAccess: openAccess{},
+}
+
+func getSnapHistory(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	name := vars["name"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var snapst snapstate.SnapState
+	switch err := snapstate.Get(st, name, &snapst); err {
+	case nil:
+		// ok
+	case state.ErrNoState:
+		return SnapNotFound(name, err)
+	default:
+		return InternalError("cannot get refresh history of snap %q: %v", name, err)
+	}
+
+	history, err := snapstate.RefreshHistory(st, name)
+	if err != nil {
+		return InternalError("cannot get refresh history of snap %q: %v", name, err)
+	}
+	if history == nil {
+		history = []*snapstate.RefreshHistoryEntry{}
+	}
+	return SyncResponse(history)
+}
@@ -0,0 +1,104 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+var _ = check.Suite(&snapHistorySuite{})
+
+type snapHistorySuite struct {
+	apiBaseSuite
+}
+
+func (s *snapHistorySuite) TestGetHistory(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.Overlord().State()
+
+	t0 := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	st.Lock()
+	snapstate.Set(st, "foo", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "foo", Revision: snap.R(2)}},
+		Current:  snap.R(2),
+	})
+	st.Set("refresh-history", map[string][]*snapstate.RefreshHistoryEntry{
+		"foo": {{
+			FromRevision: snap.R(1),
+			ToRevision:   snap.R(2),
+			Channel:      "latest/stable",
+			Time:         t0,
+			ChangeID:     "42",
+			Trigger:      snapstate.RefreshTriggerAuto,
+			Outcome:      snapstate.RefreshOutcomeRefreshed,
+		}},
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/history", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []*snapstate.RefreshHistoryEntry{{
+		FromRevision: snap.R(1),
+		ToRevision:   snap.R(2),
+		Channel:      "latest/stable",
+		Time:         t0,
+		ChangeID:     "42",
+		Trigger:      snapstate.RefreshTriggerAuto,
+		Outcome:      snapstate.RefreshOutcomeRefreshed,
+	}})
+}
+
+func (s *snapHistorySuite) TestGetHistoryEmpty(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	st := d.Overlord().State()
+
+	st.Lock()
+	snapstate.Set(st, "foo", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "foo", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/history", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, check.DeepEquals, []*snapstate.RefreshHistoryEntry{})
+}
+
+func (s *snapHistorySuite) TestGetHistoryNotInstalled(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/history", nil)
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil)
+	c.Check(rspe.Status, check.Equals, 404)
+	c.Check(rspe.Kind, check.Equals, client.ErrorKindSnapNotFound)
+}
@@ -27,6 +27,7 @@ import (
 	"mime"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
@@ -196,6 +197,15 @@ type snapInstruction struct {
 	Snaps            []string `json:"snaps"`
 	Users            []string `json:"users"`
 
+	// Time is when a hold of auto-refreshes expires, either in RFC3339
+	// format or "forever".
+	Time string `json:"time"`
+	// RefreshTimer limits the auto-refreshes of the snaps to its
+	// windows, using the format of refresh.timer.
+	RefreshTimer string `json:"refresh-timer"`
+	// TrackingPolicy describes the channel the snaps follow.
+	TrackingPolicy *client.TrackingPolicy `json:"tracking-policy"`
+
 	// The fields below should not be unmarshalled into. Do not export them.
 	userID int
 	ctx    context.Context
@@ -240,6 +250,14 @@ func (inst *snapInstruction) validate() error {
 			return fmt.Errorf("leave-cohort can only be specified for refresh or switch")
 		}
 	}
+	if inst.Time != "" || inst.RefreshTimer != "" {
+		if inst.Action != "hold" {
+			return fmt.Errorf("time and refresh-timer can only be specified for hold")
+		}
+	}
+	if inst.TrackingPolicy != nil && inst.Action != "follow" {
+		return fmt.Errorf("tracking-policy can only be specified for follow")
+	}
 	if inst.Action == "install" {
 		for _, snapName := range inst.Snaps {
 			// FIXME: alternatively we could simply mutate *inst
@@ -546,6 +564,14 @@ func (inst *snapInstruction) dispatchForMany() (op snapManyActionFunc) {
 	case "snapshot":
 		// see api_snapshots.go
 		op = snapshotMany
+	case "hold":
+		op = snapHoldMany
+	case "unhold":
+		op = snapUnholdMany
+	case "follow":
+		op = snapFollowMany
+	case "unfollow":
+		op = snapUnfollowMany
 	}
 	return op
 }
@@ -641,6 +667,72 @@ func snapRemoveMany(inst *snapInstruction, st *state.State) (*snapInstructionRes
 	}, nil
 }
 
+func snapHoldMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	if len(inst.Snaps) == 0 {
+		return nil, fmt.Errorf("cannot hold zero snaps")
+	}
+	if inst.Time == "" && inst.RefreshTimer == "" {
+		return nil, fmt.Errorf("hold needs a time or a refresh timer")
+	}
+
+	if inst.Time != "" {
+		var holdUntil time.Time
+		if inst.Time != "forever" {
+			var err error
+			holdUntil, err = time.Parse(time.RFC3339, inst.Time)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse hold time: %v", err)
+			}
+			if !holdUntil.After(time.Now()) {
+				return nil, fmt.Errorf("hold time %s is in the past", inst.Time)
+			}
+		}
+		if err := snapstate.HoldRefreshesBySystem(st, holdUntil, inst.Snaps...); err != nil {
+			return nil, err
+		}
+	}
+	if inst.RefreshTimer != "" {
+		if err := snapstate.SetRefreshTimer(st, inst.RefreshTimer, inst.Snaps...); err != nil {
+			return nil, err
+		}
+	}
+
+	var msg string
+	if len(inst.Snaps) == 1 {
+		msg = fmt.Sprintf(i18n.G("Hold auto-refreshes of snap %q"), inst.Snaps[0])
+	} else {
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Hold auto-refreshes of snaps %s"), strutil.Quoted(inst.Snaps))
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: inst.Snaps,
+	}, nil
+}
+
+func snapUnholdMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	if len(inst.Snaps) == 0 {
+		return nil, fmt.Errorf("cannot unhold zero snaps")
+	}
+	if err := snapstate.UnholdRefreshesBySystem(st, inst.Snaps...); err != nil {
+		return nil, err
+	}
+
+	var msg string
+	if len(inst.Snaps) == 1 {
+		msg = fmt.Sprintf(i18n.G("Remove hold on auto-refreshes of snap %q"), inst.Snaps[0])
+	} else {
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Remove hold on auto-refreshes of snaps %s"), strutil.Quoted(inst.Snaps))
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: inst.Snaps,
+	}, nil
+}
+
 // query many snaps
 func getSnapsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 
@@ -727,3 +819,66 @@ func shouldSearchStore(r *http.Request) bool {
 
 	return false
 }
+
+func snapFollowMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	if len(inst.Snaps) == 0 {
+		return nil, fmt.Errorf("cannot follow channels for zero snaps")
+	}
+	if inst.TrackingPolicy == nil {
+		return nil, fmt.Errorf("follow needs a tracking policy")
+	}
+
+	policy := &snapstate.TrackingPolicy{
+		Risk:  inst.TrackingPolicy.Risk,
+		Track: inst.TrackingPolicy.Track,
+	}
+	if inst.TrackingPolicy.MinAge != "" {
+		minAge, err := time.ParseDuration(inst.TrackingPolicy.MinAge)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse minimum revision age: %v", err)
+		}
+		policy.MinAge = minAge
+	}
+	if err := snapstate.SetTrackingPolicy(st, policy, inst.Snaps...); err != nil {
+		return nil, err
+	}
+
+	ch := policy.Risk
+	if policy.Track != "" {
+		ch = policy.Track + "/" + policy.Risk
+	}
+	var msg string
+	if len(inst.Snaps) == 1 {
+		msg = fmt.Sprintf(i18n.G("Make snap %q follow %q"), inst.Snaps[0], ch)
+	} else {
+		// TRANSLATORS: the first %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Make snaps %s follow %q"), strutil.Quoted(inst.Snaps), ch)
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: inst.Snaps,
+	}, nil
+}
+
+func snapUnfollowMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	if len(inst.Snaps) == 0 {
+		return nil, fmt.Errorf("cannot unfollow channels for zero snaps")
+	}
+	if err := snapstate.RemoveTrackingPolicy(st, inst.Snaps...); err != nil {
+		return nil, err
+	}
+
+	var msg string
+	if len(inst.Snaps) == 1 {
+		msg = fmt.Sprintf(i18n.G("Remove tracking policy of snap %q"), inst.Snaps[0])
+	} else {
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Remove tracking policies of snaps %s"), strutil.Quoted(inst.Snaps))
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: inst.Snaps,
+	}, nil
+}
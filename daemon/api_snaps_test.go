@@ -676,6 +676,149 @@ func (s *snapsSuite) TestRemoveMany(c *check.C) {
 	c.Check(res.Affected, check.DeepEquals, inst.Snaps)
 }
 
+func (s *snapsSuite) TestHoldAndUnholdMany(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	postSnaps := func(body string) *http.Request {
+		req, err := http.NewRequest("POST", "/v2/snaps", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+	getFoo := func() *client.Snap {
+		req, err := http.NewRequest("GET", "/v2/snaps/foo", nil)
+		c.Assert(err, check.IsNil)
+		rsp := s.syncReq(c, req, nil)
+		c.Assert(rsp.Result, check.FitsTypeOf, &client.Snap{})
+		return rsp.Result.(*client.Snap)
+	}
+
+	rsp := s.asyncReq(c, postSnaps(`{"action": "hold", "snaps": ["foo"], "time": "forever", "refresh-timer": "sat,10:00-12:00"}`), nil)
+	st := d.Overlord().State()
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	c.Check(chg.Summary(), check.Equals, `Hold auto-refreshes of snap "foo"`)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus)
+	st.Unlock()
+
+	m := getFoo()
+	c.Check(m.Hold, check.Equals, "forever")
+	c.Check(m.RefreshTimer, check.Equals, "sat,10:00-12:00")
+
+	holdUntil := time.Now().Add(time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+	s.asyncReq(c, postSnaps(fmt.Sprintf(`{"action": "hold", "snaps": ["foo"], "time": %q}`, holdUntil)), nil)
+	m = getFoo()
+	c.Check(m.Hold, check.Equals, holdUntil)
+
+	rsp = s.asyncReq(c, postSnaps(`{"action": "unhold", "snaps": ["foo"]}`), nil)
+	st.Lock()
+	c.Check(st.Change(rsp.Change).Summary(), check.Equals, `Remove hold on auto-refreshes of snap "foo"`)
+	st.Unlock()
+	m = getFoo()
+	c.Check(m.Hold, check.Equals, "")
+	c.Check(m.RefreshTimer, check.Equals, "")
+}
+
+func (s *snapsSuite) TestHoldManyErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	for _, t := range []struct {
+		body   string
+		status int
+		msg    string
+	}{
+		{`{"action": "hold", "snaps": ["foo"]}`, 400, `cannot hold "foo": hold needs a time or a refresh timer`},
+		{`{"action": "hold", "snaps": [], "time": "forever"}`, 400, `cannot hold: cannot hold zero snaps`},
+		{`{"action": "hold", "snaps": ["foo"], "time": "tomorrow"}`, 400, `cannot hold "foo": cannot parse hold time: .*`},
+		{`{"action": "hold", "snaps": ["foo"], "time": "2010-01-01T10:00:00Z"}`, 400, `cannot hold "foo": hold time 2010-01-01T10:00:00Z is in the past`},
+		{`{"action": "hold", "snaps": ["foo"], "refresh-timer": "never"}`, 400, `cannot hold "foo": cannot parse refresh timer "never": .*`},
+		{`{"action": "hold", "snaps": ["bar"], "time": "forever"}`, 400, `snap "bar" is not installed`},
+		{`{"action": "refresh", "snaps": ["foo"], "time": "forever"}`, 400, `time and refresh-timer can only be specified for hold`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps", strings.NewReader(t.body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rspe.Message, check.Matches, t.msg, check.Commentf(t.body))
+	}
+}
+
+func (s *snapsSuite) TestFollowAndUnfollowMany(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	postSnaps := func(body string) *http.Request {
+		req, err := http.NewRequest("POST", "/v2/snaps", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+	getFoo := func() *client.Snap {
+		req, err := http.NewRequest("GET", "/v2/snaps/foo", nil)
+		c.Assert(err, check.IsNil)
+		rsp := s.syncReq(c, req, nil)
+		c.Assert(rsp.Result, check.FitsTypeOf, &client.Snap{})
+		return rsp.Result.(*client.Snap)
+	}
+
+	rsp := s.asyncReq(c, postSnaps(`{"action": "follow", "snaps": ["foo"], "tracking-policy": {"risk": "candidate", "track": "2.0", "min-age": "72h"}}`), nil)
+	st := d.Overlord().State()
+	st.Lock()
+	chg := st.Change(rsp.Change)
+	c.Check(chg.Summary(), check.Equals, `Make snap "foo" follow "2.0/candidate"`)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus)
+	policies, err := snapstate.TrackingPolicies(st)
+	c.Assert(err, check.IsNil)
+	c.Check(policies, check.DeepEquals, map[string]*snapstate.TrackingPolicy{
+		"foo": {Risk: "candidate", Track: "2.0", MinAge: 72 * time.Hour},
+	})
+	st.Unlock()
+
+	m := getFoo()
+	c.Check(m.TrackingPolicy, check.DeepEquals, &client.TrackingPolicy{
+		Risk:   "candidate",
+		Track:  "2.0",
+		MinAge: "72h0m0s",
+	})
+
+	rsp = s.asyncReq(c, postSnaps(`{"action": "unfollow", "snaps": ["foo"]}`), nil)
+	st.Lock()
+	c.Check(st.Change(rsp.Change).Summary(), check.Equals, `Remove tracking policy of snap "foo"`)
+	st.Unlock()
+	m = getFoo()
+	c.Check(m.TrackingPolicy, check.IsNil)
+}
+
+func (s *snapsSuite) TestFollowManyErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	for _, t := range []struct {
+		body   string
+		status int
+		msg    string
+	}{
+		{`{"action": "follow", "snaps": ["foo"]}`, 400, `cannot follow "foo": follow needs a tracking policy`},
+		{`{"action": "follow", "snaps": [], "tracking-policy": {"risk": "beta"}}`, 400, `cannot follow: cannot follow channels for zero snaps`},
+		{`{"action": "follow", "snaps": ["foo"], "tracking-policy": {"risk": "beta", "min-age": "3 days"}}`, 400, `cannot follow "foo": cannot parse minimum revision age: .*`},
+		{`{"action": "follow", "snaps": ["foo"], "tracking-policy": {"risk": "foo"}}`, 400, `cannot follow "foo": invalid risk level "foo"`},
+		{`{"action": "follow", "snaps": ["bar"], "tracking-policy": {"risk": "beta"}}`, 400, `snap "bar" is not installed`},
+		{`{"action": "refresh", "snaps": ["foo"], "tracking-policy": {"risk": "beta"}}`, 400, `tracking-policy can only be specified for follow`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps", strings.NewReader(t.body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+
+		rspe := s.errorReq(c, req, nil)
+		c.Check(rspe.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rspe.Message, check.Matches, t.msg, check.Commentf(t.body))
+	}
+}
+
 func (s *snapsSuite) TestSnapInfoOneIntegration(c *check.C) {
 	d := s.daemon(c)
 
@@ -29,11 +29,13 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var (
@@ -280,15 +282,19 @@ func applyValidationSet(c *Command, r *http.Request, user *auth.UserState) Respo
 	}
 }
 
-var validationSetAssertionForMonitor = assertstate.ValidationSetAssertionForMonitor
+var (
+	validationSetAssertionForMonitor = assertstate.ValidationSetAssertionForMonitor
+	validationSetAssertionForEnforce = assertstate.ValidationSetAssertionForEnforce
+)
 
 // updateValidationSet handles snap validate --monitor and --enforce accountId/name[=sequence].
 func updateValidationSet(st *state.State, accountID, name string, reqMode string, sequence int, user *auth.UserState) Response {
 	var mode assertstate.ValidationSetMode
-	// TODO: only monitor mode for now, add enforce.
 	switch reqMode {
 	case "monitor":
 		mode = assertstate.Monitor
+	case "enforce":
+		return enforceValidationSet(st, accountID, name, sequence, user)
 	default:
 		return BadRequest("invalid mode %q", reqMode)
 	}
@@ -318,6 +324,75 @@ func updateValidationSet(st *state.State, accountID, name string, reqMode string
 	return SyncResponse(nil)
 }
 
+// enforceValidationSet handles snap validate --enforce accountId/name[=sequence].
+// Snaps required by the validation set that are missing are installed by
+// the returned change, other unmet constraints are reported as errors.
+func enforceValidationSet(st *state.State, accountID, name string, sequence int, user *auth.UserState) Response {
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	as, sets, err := validationSetAssertionForEnforce(st, accountID, name, sequence, userID)
+	if err != nil {
+		return BadRequest("cannot enforce validation set %v: %v", assertstate.ValidationSetKey(accountID, name), err)
+	}
+
+	snaps, err := installedSnaps(st)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+	var missing []string
+	if err := checkInstalledSnaps(sets, snaps); err != nil {
+		verr, ok := err.(*snapasserts.ValidationSetsValidationError)
+		if !ok {
+			return InternalError(err.Error())
+		}
+		if len(verr.InvalidSnaps) != 0 || len(verr.WrongRevisionSnaps) != 0 {
+			return BadRequest("cannot enforce validation set %v: %v", assertstate.ValidationSetKey(accountID, name), verr)
+		}
+		for snapName := range verr.MissingSnaps {
+			missing = append(missing, snapName)
+		}
+		sort.Strings(missing)
+	}
+
+	var prev assertstate.ValidationSetTracking
+	err = assertstate.GetValidationSet(st, accountID, name, &prev)
+	if err != nil && err != state.ErrNoState {
+		return InternalError("accessing validation sets failed: %v", err)
+	}
+	hadPrev := err == nil
+
+	tr := assertstate.ValidationSetTracking{
+		AccountID: accountID,
+		Name:      name,
+		Mode:      assertstate.Enforce,
+		// note, Sequence may be 0, meaning not pinned.
+		PinnedAt: sequence,
+		Current:  as.Sequence(),
+	}
+	assertstate.UpdateValidationSet(st, &tr)
+	if len(missing) == 0 {
+		return SyncResponse(nil)
+	}
+
+	// the missing snaps are installed at the revisions required by
+	// the validation sets now in enforcing mode
+	installed, tss, err := snapstateInstallMany(st, missing, userID)
+	if err != nil {
+		if hadPrev {
+			assertstate.UpdateValidationSet(st, &prev)
+		} else {
+			assertstate.DeleteValidationSet(st, accountID, name)
+		}
+		return errToResponse(err, missing, BadRequest, "cannot install snaps required by validation set %v: %v", assertstate.ValidationSetKey(accountID, name))
+	}
+	msg := fmt.Sprintf(i18n.G("Install snaps %s required by validation set %s"), strutil.Quoted(installed), assertstate.ValidationSetKey(accountID, name))
+	chg := newChange(st, "install-snap", msg, tss, installed)
+	ensureStateSoon(st)
+	return AsyncResponse(nil, chg.ID())
+}
+
 // forgetValidationSet forgets the validation set.
 // The state needs to be locked by the caller.
 func forgetValidationSet(st *state.State, accountID, name string, sequence int) Response {
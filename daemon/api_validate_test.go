@@ -799,6 +799,145 @@ func (s *apiValidationSetsSuite) TestApplyValidationSetMonitorModeError(c *check
 	c.Check(rspe.Message, check.Equals, fmt.Sprintf(`cannot get validation set assertion for %s/bar: boom`, s.dev1acct.AccountID()))
 }
 
+func (s *apiValidationSetsSuite) mockEnforce(c *check.C, sequence int) {
+	restore := daemon.MockValidationSetAssertionForEnforce(func(st *state.State, accountID, name string, seq int, userID int) (*asserts.ValidationSet, *snapasserts.ValidationSets, error) {
+		c.Assert(accountID, check.Equals, s.dev1acct.AccountID())
+		c.Assert(name, check.Equals, "bar")
+		c.Assert(seq, check.Equals, sequence)
+		vs := s.mockAssert(c, "bar", "3").(*asserts.ValidationSet)
+		sets := snapasserts.NewValidationSets()
+		c.Assert(sets.Add(vs), check.IsNil)
+		return vs, sets, nil
+	})
+	s.AddCleanup(restore)
+}
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceMode(c *check.C) {
+	s.mockEnforce(c, 3)
+	restore := daemon.MockCheckInstalledSnaps(func(vsets *snapasserts.ValidationSets, snaps []*snapasserts.InstalledSnap) error {
+		return nil
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce", "sequence":3}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+
+	var tr assertstate.ValidationSetTracking
+	st := s.d.Overlord().State()
+	st.Lock()
+	err = assertstate.GetValidationSet(st, s.dev1acct.AccountID(), "bar", &tr)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(tr, check.DeepEquals, assertstate.ValidationSetTracking{
+		Mode:      assertstate.Enforce,
+		AccountID: s.dev1acct.AccountID(),
+		Name:      "bar",
+		PinnedAt:  3,
+		Current:   3,
+	})
+}
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceModeInstallsMissing(c *check.C) {
+	s.mockEnforce(c, 0)
+	restore := daemon.MockSnapstateInstallMany(func(st *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+		c.Check(names, check.DeepEquals, []string{"snap-b"})
+		// the validation set is enforced when the install is planned
+		var tr assertstate.ValidationSetTracking
+		c.Assert(assertstate.GetValidationSet(st, s.dev1acct.AccountID(), "bar", &tr), check.IsNil)
+		c.Check(tr.Mode, check.Equals, assertstate.Enforce)
+		t := st.NewTask("fake-install-snap", "Doing a fake install")
+		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce"}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.asyncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 202)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "install-snap")
+	c.Check(chg.Summary(), check.Equals, fmt.Sprintf(`Install snaps "snap-b" required by validation set %s/bar`, s.dev1acct.AccountID()))
+
+	var tr assertstate.ValidationSetTracking
+	c.Assert(assertstate.GetValidationSet(st, s.dev1acct.AccountID(), "bar", &tr), check.IsNil)
+	c.Check(tr, check.DeepEquals, assertstate.ValidationSetTracking{
+		Mode:      assertstate.Enforce,
+		AccountID: s.dev1acct.AccountID(),
+		Name:      "bar",
+		Current:   3,
+	})
+}
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceModeInstallError(c *check.C) {
+	s.mockEnforce(c, 0)
+	restore := daemon.MockSnapstateInstallMany(func(st *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+		return nil, nil, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce"}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Assert(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, fmt.Sprintf("cannot install snaps required by validation set %s/bar: boom", s.dev1acct.AccountID()))
+
+	// not enforced
+	var tr assertstate.ValidationSetTracking
+	st := s.d.Overlord().State()
+	st.Lock()
+	err = assertstate.GetValidationSet(st, s.dev1acct.AccountID(), "bar", &tr)
+	st.Unlock()
+	c.Check(err, check.Equals, state.ErrNoState)
+}
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceModeInvalidSnaps(c *check.C) {
+	s.mockEnforce(c, 0)
+	restore := daemon.MockCheckInstalledSnaps(func(vsets *snapasserts.ValidationSets, snaps []*snapasserts.InstalledSnap) error {
+		return &snapasserts.ValidationSetsValidationError{
+			WrongRevisionSnaps: map[string]map[snap.Revision][]string{
+				"snap-b": {snap.R(1): []string{s.dev1acct.AccountID() + "/bar"}},
+			},
+		}
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce"}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Assert(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Matches, fmt.Sprintf(`(?s)cannot enforce validation set %s/bar: validation sets assertions are not met:.*snap-b \(required at revision 1 by sets %s/bar\)`, s.dev1acct.AccountID(), s.dev1acct.AccountID()))
+}
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceModeConflict(c *check.C) {
+	restore := daemon.MockValidationSetAssertionForEnforce(func(st *state.State, accountID, name string, seq int, userID int) (*asserts.ValidationSet, *snapasserts.ValidationSets, error) {
+		return nil, nil, fmt.Errorf("validation sets are in conflict")
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce"}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil)
+	c.Assert(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, fmt.Sprintf("cannot enforce validation set %s/bar: validation sets are in conflict", s.dev1acct.AccountID()))
+}
+
 func (s *apiValidationSetsSuite) TestForgetValidationSet(c *check.C) {
 	st := s.d.Overlord().State()
 
@@ -887,13 +1026,6 @@ func (s *apiValidationSetsSuite) TestApplyValidationSetsErrors(c *check.C) {
 			message:       `invalid mode "bad"`,
 			status:        400,
 		},
-		// XXX: enable when enforcing is implemented.
-		{
-			validationSet: "foo/bar",
-			mode:          "enforce",
-			message:       `invalid mode "enforce"`,
-			status:        400,
-		},
 		{
 			validationSet: "foo/bar",
 			sequence:      "-1",
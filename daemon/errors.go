@@ -216,6 +216,24 @@ func InsufficientSpace(dserr *snapstate.InsufficientSpaceError) *apiError {
 	if dserr.ChangeKind != "" {
 		value["change-kind"] = dserr.ChangeKind
 	}
+	if dserr.RequiredSpace > 0 {
+		value["required-space"] = dserr.RequiredSpace
+	}
+	if len(dserr.Reclaimable) > 0 {
+		reclaimable := make([]map[string]interface{}, len(dserr.Reclaimable))
+		for i, r := range dserr.Reclaimable {
+			item := map[string]interface{}{"size": r.Size}
+			if r.Snap != "" {
+				item["snap"] = r.Snap
+				item["revision"] = r.Revision.String()
+			}
+			if r.Path != "" {
+				item["path"] = r.Path
+			}
+			reclaimable[i] = item
+		}
+		value["reclaimable"] = reclaimable
+	}
 	return &apiError{
 		Status:  507,
 		Message: dserr.Error(),
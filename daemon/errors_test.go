@@ -234,6 +234,34 @@ func (s *errorsSuite) TestErrToResponseInsufficentSpace(c *C) {
 	})
 }
 
+func (s *errorsSuite) TestErrToResponseInsufficentSpaceReclaimable(c *C) {
+	err := &snapstate.InsufficientSpaceError{
+		Snaps:         []string{"foo"},
+		ChangeKind:    "refresh",
+		Path:          "/path",
+		RequiredSpace: 1000,
+		Reclaimable: []snapstate.ReclaimableSpace{
+			{Snap: "foo", Revision: snap.R(1), Size: 100},
+			{Path: "/path/cache", Size: 10},
+		},
+	}
+	rspe := daemon.ErrToResponse(err, nil, daemon.BadRequest, "%s: %v", "ERR")
+	c.Check(rspe, DeepEquals, &daemon.APIError{
+		Status:  507,
+		Message: `insufficient space in "/path" to perform "refresh" change for the following snaps: foo`,
+		Kind:    client.ErrorKindInsufficientDiskSpace,
+		Value: map[string]interface{}{
+			"snap-names":     []string{"foo"},
+			"change-kind":    "refresh",
+			"required-space": uint64(1000),
+			"reclaimable": []map[string]interface{}{
+				{"snap": "foo", "revision": "1", "size": uint64(100)},
+				{"path": "/path/cache", "size": uint64(10)},
+			},
+		},
+	})
+}
+
 func (s *errorsSuite) TestAuthCancelled(c *C) {
 	c.Check(daemon.AuthCancelled("auth cancelled"), DeepEquals, &daemon.APIError{
 		Status:  403,
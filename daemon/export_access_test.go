@@ -32,6 +32,7 @@ type (
 	AuthenticatedAccess = authenticatedAccess
 	RootAccess          = rootAccess
 	SnapAccess          = snapAccess
+	SnapProcessAccess   = snapProcessAccess
 )
 
 var CheckPolkitActionImpl = checkPolkitActionImpl
@@ -44,6 +45,14 @@ func MockCheckPolkitAction(new func(r *http.Request, ucred *Ucrednet, action str
 	}
 }
 
+func MockCgroupSnapNameFromPid(new func(pid int) (string, error)) (restore func()) {
+	old := cgroupSnapNameFromPid
+	cgroupSnapNameFromPid = new
+	return func() {
+		cgroupSnapNameFromPid = old
+	}
+}
+
 func MockPolkitCheckAuthorization(new func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error)) (restore func()) {
 	old := polkitCheckAuthorization
 	polkitCheckAuthorization = new
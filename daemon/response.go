@@ -76,6 +76,9 @@ type respJSON struct {
 	Change string `json:"change,omitempty"`
 	// Sources is used in find responses.
 	Sources []string `json:"sources,omitempty"`
+	// CatalogTimestamp is set in find responses served from the
+	// local catalog, to the time the catalog was last refreshed.
+	CatalogTimestamp *time.Time `json:"catalog-timestamp,omitempty"`
 	// XXX SuggestedCurrency is part of unsupported paid snap code.
 	SuggestedCurrency string `json:"suggested-currency,omitempty"`
 	// Maintenance...  are filled as needed by the serving pipeline.
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/client/clientutil"
@@ -41,6 +42,11 @@ type aboutSnap struct {
 	info   *snap.Info
 	snapst *snapstate.SnapState
 	health *client.SnapHealth
+
+	// hold, refreshTimer and trackingPolicy are set by the administrator
+	hold           string
+	refreshTimer   string
+	trackingPolicy *client.TrackingPolicy
 }
 
 // localSnapInfo returns the information about the current snap for the given name plus the SnapState with the active flag and other snap revisions.
@@ -72,10 +78,22 @@ func localSnapInfo(st *state.State, name string) (aboutSnap, error) {
 		return aboutSnap{}, err
 	}
 
+	holds, timers, err := refreshHoldsAndTimers(st)
+	if err != nil {
+		return aboutSnap{}, err
+	}
+	policies, err := trackingPolicies(st)
+	if err != nil {
+		return aboutSnap{}, err
+	}
+
 	return aboutSnap{
-		info:   info,
-		snapst: &snapst,
-		health: clientHealthFromHealthstate(health),
+		info:           info,
+		snapst:         &snapst,
+		health:         clientHealthFromHealthstate(health),
+		hold:           holds[name],
+		refreshTimer:   timers[name],
+		trackingPolicy: policies[name],
 	}, nil
 }
 
@@ -95,12 +113,31 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 		return nil, err
 	}
 
+	holds, timers, err := refreshHoldsAndTimers(st)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := trackingPolicies(st)
+	if err != nil {
+		return nil, err
+	}
+
 	var firstErr error
 	for name, snapst := range snapStates {
 		if len(wanted) > 0 && !wanted[name] {
 			continue
 		}
 		health := clientHealthFromHealthstate(healths[name])
+		newAbout := func(info *snap.Info) aboutSnap {
+			return aboutSnap{
+				info:           info,
+				snapst:         snapst,
+				health:         health,
+				hold:           holds[name],
+				refreshTimer:   timers[name],
+				trackingPolicy: policies[name],
+			}
+		}
 		var aboutThis []aboutSnap
 		var info *snap.Info
 		var err error
@@ -122,13 +159,13 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 				if err != nil && firstErr == nil {
 					firstErr = err
 				}
-				aboutThis = append(aboutThis, aboutSnap{info, snapst, health})
+				aboutThis = append(aboutThis, newAbout(info))
 			}
 		} else {
 			info, err = snapst.CurrentInfo()
 			if err == nil {
 				info.Publisher, err = publisherAccount(st, info.SnapID)
-				aboutThis = append(aboutThis, aboutSnap{info, snapst, health})
+				aboutThis = append(aboutThis, newAbout(info))
 			}
 		}
 
@@ -145,6 +182,49 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 	return about, firstErr
 }
 
+// refreshHoldsAndTimers returns the holds of auto-refreshes, as presented
+// to clients, and the refresh timers set by the administrator.
+func refreshHoldsAndTimers(st *state.State) (holds map[string]string, timers map[string]string, err error) {
+	holdTimes, err := snapstate.SystemRefreshHolds(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	holds = make(map[string]string, len(holdTimes))
+	for name, until := range holdTimes {
+		if until.IsZero() {
+			holds[name] = "forever"
+		} else {
+			holds[name] = until.Format(time.RFC3339)
+		}
+	}
+	timers, err = snapstate.RefreshTimers(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	return holds, timers, nil
+}
+
+// trackingPolicies returns the tracking policies set by the administrator,
+// as presented to clients.
+func trackingPolicies(st *state.State) (map[string]*client.TrackingPolicy, error) {
+	policies, err := snapstate.TrackingPolicies(st)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*client.TrackingPolicy, len(policies))
+	for name, policy := range policies {
+		p := &client.TrackingPolicy{
+			Risk:  policy.Risk,
+			Track: policy.Track,
+		}
+		if policy.MinAge != 0 {
+			p.MinAge = policy.MinAge.String()
+		}
+		result[name] = p
+	}
+	return result, nil
+}
+
 func publisherAccount(st *state.State, snapID string) (snap.StoreAccount, error) {
 	if snapID == "" {
 		return snap.StoreAccount{}, nil
@@ -207,6 +287,9 @@ func mapLocal(about aboutSnap, sd clientutil.StatusDecorator) *client.Snap {
 		result.MountedFrom, _ = os.Readlink(result.MountedFrom)
 	}
 	result.Health = about.health
+	result.Hold = about.hold
+	result.RefreshTimer = about.refreshTimer
+	result.TrackingPolicy = about.trackingPolicy
 
 	return result
 }
@@ -46,9 +46,12 @@ var (
 	SnapConfineAppArmorDir    string
 	SnapSeccompBase           string
 	SnapSeccompDir            string
+	SnapLandlockDir           string
 	SnapMountPolicyDir        string
 	SnapUdevRulesDir          string
 	SnapKModModulesDir        string
+	SnapEgressDir             string
+	SnapPolkitPolicyDir       string
 	LocaleDir                 string
 	SnapMetaDir               string
 	SnapdSocket               string
@@ -82,6 +85,8 @@ var (
 
 	SnapRollbackDir string
 
+	SnapGateAutoRefreshDir string
+
 	SnapCacheDir        string
 	SnapNamesFile       string
 	SnapSectionsFile    string
@@ -322,6 +327,8 @@ func SetRootDir(rootdir string) {
 	SnapDownloadCacheDir = filepath.Join(rootdir, snappyDir, "cache")
 	SnapSeccompBase = filepath.Join(rootdir, snappyDir, "seccomp")
 	SnapSeccompDir = filepath.Join(SnapSeccompBase, "bpf")
+	SnapLandlockDir = filepath.Join(rootdir, snappyDir, "landlock", "profiles")
+	SnapEgressDir = filepath.Join(rootdir, snappyDir, "egress")
 	SnapMountPolicyDir = filepath.Join(rootdir, snappyDir, "mount")
 	SnapMetaDir = filepath.Join(rootdir, snappyDir, "meta")
 	SnapdMaintenanceFile = filepath.Join(rootdir, snappyDir, "maintenance.json")
@@ -374,6 +381,8 @@ func SetRootDir(rootdir string) {
 
 	SnapRollbackDir = filepath.Join(rootdir, snappyDir, "rollback")
 
+	SnapGateAutoRefreshDir = filepath.Join(rootdir, "/etc/snapd/gate-auto-refresh.d")
+
 	SnapBinariesDir = filepath.Join(SnapMountDir, "bin")
 	SnapServicesDir = filepath.Join(rootdir, "/etc/systemd/system")
 	SnapUserServicesDir = filepath.Join(rootdir, "/etc/systemd/user")
@@ -391,6 +400,7 @@ func SetRootDir(rootdir string) {
 	SnapUdevRulesDir = filepath.Join(rootdir, "/etc/udev/rules.d")
 
 	SnapKModModulesDir = filepath.Join(rootdir, "/etc/modules-load.d/")
+	SnapPolkitPolicyDir = filepath.Join(rootdir, "/usr/share/polkit-1/actions")
 
 	LocaleDir = filepath.Join(rootdir, "/usr/share/locale")
 	ClassicDir = filepath.Join(rootdir, "/writable/classic")
@@ -65,6 +65,13 @@ const (
 	// QuotaGroups enable creating resource quota groups for snaps via the rest API and cli.
 	QuotaGroups
 
+	// Prompting enables prompting users for the file accesses of snaps outside of their connected interfaces.
+	// Users are prompted after the access was denied, their decision applies when the snap tries again.
+	Prompting
+
+	// SandboxDenials enables collecting the denials of the sandbox of snaps from the journal.
+	SandboxDenials
+
 	// lastFeature is the final known feature, it is only used for testing.
 	lastFeature
 )
@@ -103,6 +110,10 @@ var featureNames = map[SnapdFeature]string{
 	GateAutoRefreshHook: "gate-auto-refresh-hook",
 
 	QuotaGroups: "quota-groups",
+
+	Prompting: "prompting",
+
+	SandboxDenials: "sandbox-denials",
 }
 
 // featuresEnabledWhenUnset contains a set of features that are enabled when not explicitly configured.
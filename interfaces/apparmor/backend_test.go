@@ -236,6 +236,13 @@ func (s *backendSuite) TestInstallingSnapWithLayoutWritesAndLoadsProfiles(c *C)
 	})
 }
 
+func (s *backendSuite) TestInstallingSnapWithPromptingRules(c *C) {
+	opts := interfaces.ConfinementOptions{PromptingRules: "owner /home/alice/Documents/** rw,"}
+	s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	c.Check(profile, testutil.FileContains, "# Accesses allowed by users when prompted\nowner /home/alice/Documents/** rw,\n")
+}
+
 const gadgetYaml = `name: mydevice
 type: gadget
 version: 1
@@ -322,6 +322,26 @@ func (spec *Specification) AddLayout(si *snap.Info) {
 	}
 }
 
+// AddPromptingRules adds the given file rules, granting the accesses users
+// allowed when prompted, to all the apps and hooks of the snap.
+func (spec *Specification) AddPromptingRules(si *snap.Info, rules string) {
+	if rules == "" {
+		return
+	}
+	snippet := "# Accesses allowed by users when prompted\n" + rules
+	if spec.snippets == nil {
+		spec.snippets = make(map[string][]string)
+	}
+	for _, app := range si.Apps {
+		tag := app.SecurityTag()
+		spec.snippets[tag] = append(spec.snippets[tag], snippet)
+	}
+	for _, hook := range si.Hooks {
+		tag := hook.SecurityTag()
+		spec.snippets[tag] = append(spec.snippets[tag], snippet)
+	}
+}
+
 // AddOvername adds AppArmor snippets allowing remapping of snap
 // directories for parallel installed snaps
 //
@@ -275,6 +275,20 @@ layout:
     bind-file: $SNAP/foo.conf
 `
 
+func (s *specSuite) TestAddPromptingRules(c *C) {
+	snapInfo := snaptest.MockInfo(c, snapWithLayout, &snap.SideInfo{Revision: snap.R(42)})
+
+	s.spec.AddPromptingRules(snapInfo, "")
+	c.Check(s.spec.Snippets(), HasLen, 0)
+
+	s.spec.AddPromptingRules(snapInfo, "owner /home/alice/Documents/** rw,")
+	c.Check(s.spec.Snippets(), DeepEquals, map[string][]string{
+		"snap.vanguard.vanguard": {
+			"# Accesses allowed by users when prompted\nowner /home/alice/Documents/** rw,",
+		},
+	})
+}
+
 func (s *specSuite) TestApparmorSnippetsFromLayout(c *C) {
 	snapInfo := snaptest.MockInfo(c, snapWithLayout, &snap.SideInfo{Revision: snap.R(42)})
 	restore := apparmor.SetSpecScope(s.spec, []string{"snap.vanguard.vanguard"})
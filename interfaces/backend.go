@@ -63,6 +63,9 @@ type ConfinementOptions struct {
 	JailMode bool
 	// Classic flag switches the core snap "chroot" off.
 	Classic bool
+	// PromptingRules are the apparmor file rules, one per line, granting
+	// the accesses users allowed to the snap when prompted.
+	PromptingRules string
 }
 
 // SecurityBackendOptions carries extra flags that affect initialization of the
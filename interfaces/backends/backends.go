@@ -25,8 +25,11 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/dbus"
+	"github.com/snapcore/snapd/interfaces/egress"
 	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/landlock"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/polkit"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
@@ -45,6 +48,8 @@ func backends() []interfaces.SecurityBackend {
 		&udev.Backend{},
 		&mount.Backend{},
 		&kmod.Backend{},
+		&egress.Backend{},
+		&polkit.Backend{},
 	}
 
 	// TODO use something like:
@@ -72,6 +77,10 @@ func backends() []interfaces.SecurityBackend {
 	switch apparmor_sandbox.ProbedLevel() {
 	case apparmor_sandbox.Partial, apparmor_sandbox.Full:
 		all = append(all, &apparmor.Backend{})
+	default:
+		// Without apparmor the filesystem accesses of snaps are
+		// restricted with landlock, when the kernel supports it.
+		all = append(all, &landlock.Backend{})
 	}
 	return all
 }
@@ -48,10 +48,13 @@ func (s *backendsSuite) TestIsAppArmorEnabled(c *C) {
 		switch level {
 		case apparmor_sandbox.Unsupported, apparmor_sandbox.Unusable:
 			c.Assert(names, Not(testutil.Contains), "apparmor")
+			c.Assert(names, testutil.Contains, "landlock")
 		case apparmor_sandbox.Partial, apparmor_sandbox.Full:
 			c.Assert(names, testutil.Contains, "apparmor")
+			c.Assert(names, Not(testutil.Contains), "landlock")
 		}
-
+		c.Assert(names, testutil.Contains, "egress")
+		c.Assert(names, testutil.Contains, "polkit")
 	}
 }
 
@@ -19,6 +19,17 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 // Only allow raw disk devices; not loop, ram, CDROM, generic SCSI, network,
 // tape, raid, etc devices or disk partitions. For some devices, allow controller
 // character devices since they are used to configure the corresponding block
@@ -103,10 +114,44 @@ var blockDevicesConnectedPlugUDev = []string{
 	`KERNEL=="megaraid_sas_ioctl_node"`,
 }
 
+// Pattern to match the USB disks handed out by hotplug slots, this must be
+// a subset of the SCSI disks allowed by blockDevicesConnectedPlugAppArmor
+var blockDevicesHotplugNodePattern = regexp.MustCompile("^/dev/sd[a-z]{1,2}$")
+
 type blockDevicesInterface struct {
 	commonInterface
 }
 
+func (iface *blockDevicesInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	return verifyHotplugSlotPath(slot, blockDevicesHotplugNodePattern)
+}
+
+func (iface *blockDevicesInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	// A hotplug slot is a single disk, the implicit slot is all of
+	// them. The AppArmor rules are the same for both, udev tagging and
+	// device cgroups restrict down to the specific device.
+	if path := hotplugSlotPath(slot); path != "" {
+		spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="block", KERNEL=="%s"`, strings.TrimPrefix(path, "/dev/")))
+		return nil
+	}
+	return iface.commonInterface.UDevConnectedPlug(spec, plug, slot)
+}
+
+func (iface *blockDevicesInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	// only whole USB disks, like with the implicit slot partitions are
+	// not handed out
+	bus, _ := di.Attribute("ID_BUS")
+	if di.Subsystem() != "block" || di.DeviceType() != "disk" || bus != "usb" || !blockDevicesHotplugNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	return hotplugDeviceSlot(di), nil
+}
+
+func (iface *blockDevicesInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	// tells apart the LUNs of card readers
+	return stableHotplugKey(di, "ID_INSTANCE"), nil
+}
+
 func init() {
 	registerIface(&blockDevicesInterface{commonInterface{
 		name:                  "block-devices",
@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -97,6 +98,56 @@ KERNEL=="megaraid_sas_ioctl_node", TAG+="snap_consumer_app"`)
 	c.Assert(spec.Snippets(), testutil.Contains, fmt.Sprintf(`TAG=="snap_consumer_app", RUN+="%v/snap-device-helper $env{ACTION} snap_consumer_app $devpath $major:$minor"`, dirs.DistroLibExecDir))
 }
 
+func (s *blockDevicesInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	env := func(devname, devtype, bus string) map[string]string {
+		return map[string]string{"DEVPATH": "/devices/foo/block/" + devname, "DEVNAME": "/dev/" + devname, "DEVTYPE": devtype, "ACTION": "add", "SUBSYSTEM": "block", "ID_BUS": bus, "ID_VENDOR_ID": "0781", "ID_MODEL_ID": "5581"}
+	}
+	di, err := hotplug.NewHotplugDeviceInfo(env("sdb", "disk", "usb"))
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Check(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/sdb", "usb-vendor": "0781", "usb-product": "5581"}})
+
+	// partitions and internal disks are not handed out
+	for _, e := range []map[string]string{
+		env("sdb1", "partition", "usb"),
+		env("sda", "disk", "ata"),
+		env("loop0", "disk", ""),
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(e)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, IsNil)
+	}
+}
+
+func (s *blockDevicesInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	key := func(devname, lun string) snap.HotplugKey {
+		di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/foo/block/" + devname, "DEVNAME": "/dev/" + devname, "DEVTYPE": "disk", "ACTION": "add", "SUBSYSTEM": "block", "ID_BUS": "usb", "ID_VENDOR_ID": "0bda", "ID_MODEL_ID": "0158", "ID_SERIAL_SHORT": "20060413092100000", "ID_INSTANCE": lun})
+		c.Assert(err, IsNil)
+		key, err := keyHandler.HotplugKey(di)
+		c.Assert(err, IsNil)
+		return key
+	}
+	// the same card reader slot, whatever the name of the disk
+	c.Check(key("sdb", "0:0"), Equals, key("sdc", "0:0"))
+	// another slot of the card reader
+	c.Check(key("sdb", "0:0"), Not(Equals), key("sdc", "0:1"))
+}
+
+func (s *blockDevicesInterfaceSuite) TestUDevSpecHotplugSlot(c *C) {
+	slotInfo := MockHotplugSlot(c, blockDevicesCoreYaml, nil, "hotplugkey", "block-devices", "usb-disk", map[string]interface{}{"path": "/dev/sdb"})
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Assert(spec.Snippets(), testutil.Contains, `# block-devices
+SUBSYSTEM=="block", KERNEL=="sdb", TAG+="snap_consumer_app"`)
+}
+
 func (s *blockDevicesInterfaceSuite) TestStaticInfo(c *C) {
 	si := interfaces.StaticInfoOf(s.iface)
 	c.Assert(si.ImplicitOnCore, Equals, true)
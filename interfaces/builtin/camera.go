@@ -19,6 +19,17 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 const cameraSummary = `allows access to all cameras`
 
 const cameraBaseDeclarationSlots = `
@@ -57,8 +68,47 @@ var cameraConnectedPlugUDev = []string{
 	`KERNEL=="vchiq"`,
 }
 
+// Pattern to match the device nodes of cameras handed out by hotplug slots
+var cameraDeviceNodePattern = regexp.MustCompile("^/dev/video[0-9]{1,3}$")
+
+type cameraInterface struct {
+	commonInterface
+}
+
+func (iface *cameraInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	return verifyHotplugSlotPath(slot, cameraDeviceNodePattern)
+}
+
+func (iface *cameraInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	// A hotplug slot is a single camera, the implicit slot is all of
+	// them. The AppArmor rules are the same for both, udev tagging and
+	// device cgroups restrict down to the specific device.
+	if path := hotplugSlotPath(slot); path != "" {
+		spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="video4linux", KERNEL=="%s"`, strings.TrimPrefix(path, "/dev/")))
+		return nil
+	}
+	return iface.commonInterface.UDevConnectedPlug(spec, plug, slot)
+}
+
+func (iface *cameraInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.Subsystem() != "video4linux" || !cameraDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	// skip the metadata and output nodes of cameras
+	if caps, ok := di.Attribute("ID_V4L_CAPABILITIES"); ok && !strings.Contains(caps, ":capture:") {
+		return nil, nil
+	}
+	return hotplugDeviceSlot(di), nil
+}
+
+func (iface *cameraInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	// tells apart the capture nodes of cameras with several sensors,
+	// e.g. a color and an infrared one
+	return stableHotplugKey(di, "ID_V4L_PRODUCT", "ID_V4L_CAPABILITIES"), nil
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&cameraInterface{commonInterface{
 		name:                  "camera",
 		summary:               cameraSummary,
 		implicitOnCore:        true,
@@ -66,5 +116,5 @@ func init() {
 		baseDeclarationSlots:  cameraBaseDeclarationSlots,
 		connectedPlugAppArmor: cameraConnectedPlugAppArmor,
 		connectedPlugUDev:     cameraConnectedPlugUDev,
-	})
+	}})
 }
@@ -28,8 +28,10 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -94,6 +96,88 @@ KERNEL=="vchiq", TAG+="snap_consumer_app"`)
 	c.Assert(spec.Snippets(), testutil.Contains, fmt.Sprintf(`TAG=="snap_consumer_app", RUN+="%v/snap-device-helper $env{ACTION} snap_consumer_app $devpath $major:$minor"`, dirs.DistroLibExecDir))
 }
 
+func (s *CameraInterfaceSuite) TestUDevSpecHotplugSlot(c *C) {
+	slotInfo := MockHotplugSlot(c, cameraCoreYaml, nil, "hotplugkey", "camera", "webcam", map[string]interface{}{"path": "/dev/video2"})
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Assert(spec.Snippets(), testutil.Contains, `# camera
+SUBSYSTEM=="video4linux", KERNEL=="video2", TAG+="snap_consumer_app"`)
+
+	// the access is the same, the device cgroup restricts it
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(apparmorSpec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/dev/video[0-9]* rw")
+}
+
+func (s *CameraInterfaceSuite) TestSanitizeHotplugSlot(c *C) {
+	const coreYaml = `name: core
+version: 0
+type: os
+slots:
+  webcam:
+    interface: camera
+    path: /dev/video0
+  bad:
+    interface: camera
+    path: /dev/sda
+`
+	info := snaptest.MockInfo(c, coreYaml, nil)
+	c.Check(interfaces.BeforePrepareSlot(s.iface, info.Slots["webcam"]), IsNil)
+	c.Check(interfaces.BeforePrepareSlot(s.iface, info.Slots["bad"]), ErrorMatches, "camera slot path attribute must be a valid device node")
+}
+
+func (s *CameraInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-5/1-5:1.0/video4linux/video0", "DEVNAME": "/dev/video0", "ACTION": "add", "SUBSYSTEM": "video4linux", "ID_BUS": "usb", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825", "ID_V4L_CAPABILITIES": ":capture:"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Check(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/video0", "usb-vendor": "046d", "usb-product": "0825"}})
+
+	// the metadata node of the same camera
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-5/1-5:1.0/video4linux/video1", "DEVNAME": "/dev/video1", "ACTION": "add", "SUBSYSTEM": "video4linux", "ID_BUS": "usb", "ID_V4L_CAPABILITIES": ":"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Check(proposedSlot, IsNil)
+
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/foo", "DEVNAME": "/dev/ttyUSB0", "ACTION": "add", "SUBSYSTEM": "tty", "ID_BUS": "usb"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Check(proposedSlot, IsNil)
+}
+
+func (s *CameraInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	key := func(env map[string]string) snap.HotplugKey {
+		env["ACTION"] = "add"
+		env["SUBSYSTEM"] = "video4linux"
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		key, err := keyHandler.HotplugKey(di)
+		c.Assert(err, IsNil)
+		return key
+	}
+
+	key1 := key(map[string]string{"DEVPATH": "/devices/usb1/1-5/1-5:1.0/video4linux/video0", "DEVNAME": "/dev/video0", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825", "ID_SERIAL_SHORT": "1234", "ID_PATH": "pci-0000:00:14.0-usb-0:5:1.0", "ID_V4L_PRODUCT": "Webcam"})
+	c.Check(key1, Not(Equals), snap.HotplugKey(""))
+	// the same camera plugged elsewhere
+	c.Check(key(map[string]string{"DEVPATH": "/devices/usb1/1-2/1-2:1.0/video4linux/video2", "DEVNAME": "/dev/video2", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825", "ID_SERIAL_SHORT": "1234", "ID_PATH": "pci-0000:00:14.0-usb-0:2:1.0", "ID_V4L_PRODUCT": "Webcam"}), Equals, key1)
+	// another sensor of the camera
+	c.Check(key(map[string]string{"DEVPATH": "/devices/usb1/1-5/1-5:1.2/video4linux/video2", "DEVNAME": "/dev/video2", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825", "ID_SERIAL_SHORT": "1234", "ID_PATH": "pci-0000:00:14.0-usb-0:5:1.0", "ID_V4L_PRODUCT": "Webcam: IR"}), Not(Equals), key1)
+
+	// without a serial number the port identifies the camera
+	key2 := key(map[string]string{"DEVPATH": "/devices/usb1/1-5/1-5:1.0/video4linux/video0", "DEVNAME": "/dev/video0", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825", "ID_PATH": "pci-0000:00:14.0-usb-0:5:1.0"})
+	c.Check(key2, Not(Equals), snap.HotplugKey(""))
+	c.Check(key(map[string]string{"DEVPATH": "/devices/usb1/1-5/1-5:1.0/video4linux/video3", "DEVNAME": "/dev/video3", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825", "ID_PATH": "pci-0000:00:14.0-usb-0:5:1.0"}), Equals, key2)
+
+	// the default key is used
+	c.Check(key(map[string]string{"DEVPATH": "/devices/foo/video4linux/video0", "DEVNAME": "/dev/video0", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "0825"}), Equals, snap.HotplugKey(""))
+}
+
 func (s *CameraInterfaceSuite) TestStaticInfo(c *C) {
 	si := interfaces.StaticInfoOf(s.iface)
 	c.Assert(si.ImplicitOnCore, Equals, true)
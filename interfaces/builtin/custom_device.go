@@ -0,0 +1,475 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+const customDeviceSummary = `provides access to custom devices specified via the gadget snap`
+
+const customDeviceBaseDeclarationSlots = `
+  custom-device:
+    allow-installation:
+      slot-snap-type:
+        - gadget
+    allow-connection:
+      plug-attributes:
+        custom-device: $SLOT(custom-device)
+    deny-auto-connection: true
+`
+
+// The custom-device slot describes the device in the gadget snap:
+//
+//  slots:
+//    my-board:
+//      interface: custom-device
+//      custom-device: my-board    # defaults to the slot name
+//      devices:                   # read-write access
+//        - /dev/foo[0-9]
+//      read-devices:              # read-only access
+//        - /dev/bar
+//      files:
+//        read:
+//          - /sys/class/foo/*
+//        write:
+//          - /sys/devices/platform/foo/enable
+//      udev-tagging:              # defaults to KERNEL=="<device name>"
+//        - kernel: foo[0-9]
+//          subsystem: foo
+//          attributes:
+//            idVendor: "1234"
+//          environment:
+//            ID_MODEL: bar
+//      host-directories:          # bind mounted read-only
+//        - /usr/share/my-board
+//
+// The files are in sysfs or in the procfs entries about devices, and only the
+// ones of the devices in sysfs can be written to.
+//
+// Plugs connect to the slot with the same custom-device attribute.
+
+// Pattern to match the device nodes, AppArmor and udev globs are allowed
+// but no alternations nor recursive wildcards
+var customDeviceNodePattern = regexp.MustCompile(`^/dev/[-a-zA-Z0-9_.+:/\[\]*?]+$`)
+
+// Pattern to match the sysfs and procfs paths, a subset of AARE; like for
+// the device nodes recursive wildcards are not allowed
+var customDeviceFilePattern = regexp.MustCompile(`^/(sys|proc)/[-a-zA-Z0-9_.+:@/\[\]*?]+$`)
+
+// The top-level procfs entries about devices, the files are limited to these
+// as the rest of procfs is about processes and the kernel
+var customDeviceProcEntries = map[string]bool{
+	"bus":         true,
+	"device-tree": true,
+	"devices":     true,
+	"driver":      true,
+	"interrupts":  true,
+	"tty":         true,
+}
+
+// The top-level sysfs directories of the devices, writing to the files is
+// limited to these as the rest of sysfs configures the kernel
+var customDeviceWritableSysDirs = map[string]bool{
+	"bus":     true,
+	"class":   true,
+	"devices": true,
+}
+
+// Pattern to match bracket expressions in globs
+var customDeviceBracketPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// Pattern to match the host directories, without globs
+var customDeviceHostDirectoryPattern = regexp.MustCompile(`^/(etc|usr/share)/[-a-zA-Z0-9_.+:@/]+$`)
+
+var customDeviceNamePattern = regexp.MustCompile(`^[a-z](?:-?[a-z0-9])*$`)
+
+var (
+	customDeviceKernelPattern      = regexp.MustCompile(`^[-a-zA-Z0-9_.+:\[\]*?]+$`)
+	customDeviceSubsystemPattern   = regexp.MustCompile(`^[-a-z0-9_]+$`)
+	customDeviceUDevKeyPattern     = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	customDeviceUDevValuePattern   = regexp.MustCompile(`^[-a-zA-Z0-9_.+:/ \[\]*?]*$`)
+	customDeviceUDevTaggingAllowed = map[string]bool{"kernel": true, "subsystem": true, "attributes": true, "environment": true}
+)
+
+type customDeviceUDevRule struct {
+	kernel      string
+	subsystem   string
+	attributes  map[string]string
+	environment map[string]string
+}
+
+func (r *customDeviceUDevRule) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `KERNEL=="%s"`, r.kernel)
+	if r.subsystem != "" {
+		fmt.Fprintf(&buf, `, SUBSYSTEM=="%s"`, r.subsystem)
+	}
+	for _, key := range customDeviceSortedKeys(r.attributes) {
+		fmt.Fprintf(&buf, `, ATTRS{%s}=="%s"`, key, r.attributes[key])
+	}
+	for _, key := range customDeviceSortedKeys(r.environment) {
+		fmt.Fprintf(&buf, `, ENV{%s}=="%s"`, key, r.environment[key])
+	}
+	return buf.String()
+}
+
+func customDeviceSortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// customDevice is the device described by a custom-device slot.
+type customDevice struct {
+	name            string
+	devices         []string
+	readDevices     []string
+	readFiles       []string
+	writeFiles      []string
+	udevRules       []*customDeviceUDevRule
+	hostDirectories []string
+}
+
+func customDeviceStringList(attrs interfaces.Attrer, name string, pattern *regexp.Regexp) ([]string, error) {
+	value, ok := attrs.Lookup(name)
+	if !ok {
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q must be a list of strings", name)
+	}
+	paths := make([]string, 0, len(list))
+	for _, item := range list {
+		path, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a list of strings", name)
+		}
+		if filepath.Clean(path) != path {
+			return nil, fmt.Errorf("%q path %q is not clean", name, path)
+		}
+		if !pattern.MatchString(path) {
+			return nil, fmt.Errorf("%q path %q is not allowed", name, path)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func customDeviceStringMap(value interface{}, name string, keyPattern, valuePattern *regexp.Regexp) (map[string]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q must be a map of strings", name)
+	}
+	result := make(map[string]string, len(m))
+	for key, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a map of strings", name)
+		}
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%q key %q is not allowed", name, key)
+		}
+		if !valuePattern.MatchString(s) {
+			return nil, fmt.Errorf("%q value %q is not allowed", name, s)
+		}
+		result[key] = s
+	}
+	return result, nil
+}
+
+func customDeviceUDevRules(attrs interfaces.Attrer, deviceNames map[string]bool) ([]*customDeviceUDevRule, error) {
+	value, ok := attrs.Lookup("udev-tagging")
+	if !ok {
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`"udev-tagging" must be a list of maps`)
+	}
+	rules := make([]*customDeviceUDevRule, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`"udev-tagging" must be a list of maps`)
+		}
+		for key := range m {
+			if !customDeviceUDevTaggingAllowed[key] {
+				return nil, fmt.Errorf(`"udev-tagging" does not support %q`, key)
+			}
+		}
+		var rule customDeviceUDevRule
+		rule.kernel, ok = m["kernel"].(string)
+		if !ok || !customDeviceKernelPattern.MatchString(rule.kernel) {
+			return nil, fmt.Errorf(`"udev-tagging" rules must have a valid "kernel" value`)
+		}
+		// the rules only narrow the devices down
+		if !deviceNames[rule.kernel] {
+			return nil, fmt.Errorf(`"udev-tagging" kernel %q does not match any of the devices`, rule.kernel)
+		}
+		if subsystem, ok := m["subsystem"]; ok {
+			rule.subsystem, ok = subsystem.(string)
+			if !ok || !customDeviceSubsystemPattern.MatchString(rule.subsystem) {
+				return nil, fmt.Errorf(`"udev-tagging" subsystem %v is not allowed`, subsystem)
+			}
+		}
+		var err error
+		rule.attributes, err = customDeviceStringMap(m["attributes"], "attributes", customDeviceUDevKeyPattern, customDeviceUDevValuePattern)
+		if err != nil {
+			return nil, err
+		}
+		rule.environment, err = customDeviceStringMap(m["environment"], "environment", customDeviceUDevKeyPattern, customDeviceUDevValuePattern)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, nil
+}
+
+// parseCustomDevice returns the device described by the attributes of a
+// custom-device slot, validating them.
+func parseCustomDevice(attrs interfaces.Attrer) (*customDevice, error) {
+	var device customDevice
+	var err error
+
+	if err := attrs.Attr("custom-device", &device.name); err != nil {
+		return nil, fmt.Errorf(`"custom-device" must be a string`)
+	}
+	if device.devices, err = customDeviceStringList(attrs, "devices", customDeviceNodePattern); err != nil {
+		return nil, err
+	}
+	if device.readDevices, err = customDeviceStringList(attrs, "read-devices", customDeviceNodePattern); err != nil {
+		return nil, err
+	}
+	if value, ok := attrs.Lookup("files"); ok {
+		files, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`"files" must be a map`)
+		}
+		for key := range files {
+			if key != "read" && key != "write" {
+				return nil, fmt.Errorf(`"files" does not support %q`, key)
+			}
+		}
+	}
+	if device.readFiles, err = customDeviceStringList(attrs, "files.read", customDeviceFilePattern); err != nil {
+		return nil, err
+	}
+	if device.writeFiles, err = customDeviceStringList(attrs, "files.write", customDeviceFilePattern); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		name  string
+		paths []string
+	}{{"files.read", device.readFiles}, {"files.write", device.writeFiles}} {
+		for _, path := range f.paths {
+			if !customDeviceFileAllowed(path, f.name == "files.write") {
+				return nil, fmt.Errorf("%q path %q is not allowed", f.name, path)
+			}
+		}
+	}
+	if device.hostDirectories, err = customDeviceStringList(attrs, "host-directories", customDeviceHostDirectoryPattern); err != nil {
+		return nil, err
+	}
+
+	// the kernel names of the devices, these are tagged by default
+	deviceNames := make(map[string]bool)
+	var allDevices []string
+	allDevices = append(allDevices, device.devices...)
+	allDevices = append(allDevices, device.readDevices...)
+	for _, path := range allDevices {
+		name := filepath.Base(path)
+		// the device name must not match anything
+		if strings.Contains(path, "**") || !customDeviceKernelPattern.MatchString(name) || customDeviceGlobLiteral(name) == "" {
+			return nil, fmt.Errorf("device path %q is not allowed", path)
+		}
+		deviceNames[name] = true
+	}
+	if len(deviceNames) == 0 && len(device.readFiles) == 0 && len(device.writeFiles) == 0 {
+		return nil, fmt.Errorf(`"devices", "read-devices" or "files" must be set`)
+	}
+	if device.udevRules, err = customDeviceUDevRules(attrs, deviceNames); err != nil {
+		return nil, err
+	}
+	if device.udevRules == nil {
+		for _, path := range allDevices {
+			device.udevRules = append(device.udevRules, &customDeviceUDevRule{kernel: filepath.Base(path)})
+		}
+	}
+	return &device, nil
+}
+
+// customDeviceFileAllowed returns whether the given sysfs or procfs path,
+// already matched against customDeviceFilePattern, may be accessed for
+// reading or for writing.
+func customDeviceFileAllowed(path string, write bool) bool {
+	if strings.Contains(path, "**") {
+		return false
+	}
+	// the top-level entry decides what the path is about, so it must not be
+	// a glob
+	parts := strings.SplitN(path[1:], "/", 3)
+	if len(parts) < 2 || strings.ContainsAny(parts[1], "*?[]") {
+		return false
+	}
+	if parts[0] == "proc" {
+		return customDeviceProcEntries[parts[1]]
+	}
+	return !write || customDeviceWritableSysDirs[parts[1]]
+}
+
+// customDeviceGlobLiteral returns the literal part of the given glob.
+func customDeviceGlobLiteral(glob string) string {
+	return strings.Trim(customDeviceBracketPattern.ReplaceAllString(glob, ""), "*?")
+}
+
+type customDeviceInterface struct {
+	commonInterface
+}
+
+func (iface *customDeviceInterface) validateName(attrs map[string]interface{}, defaultName string) error {
+	name, ok := attrs["custom-device"]
+	if !ok {
+		// custom-device defaults to the plug or slot name
+		attrs["custom-device"] = defaultName
+		return nil
+	}
+	if s, ok := name.(string); !ok || !customDeviceNamePattern.MatchString(s) {
+		return fmt.Errorf(`"custom-device" must be a valid name: %v`, name)
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	if slot.Attrs == nil {
+		slot.Attrs = make(map[string]interface{})
+	}
+	if err := iface.validateName(slot.Attrs, slot.Name); err != nil {
+		return fmt.Errorf("cannot add custom-device slot %q: %v", slot.Name, err)
+	}
+	if _, err := parseCustomDevice(slot); err != nil {
+		return fmt.Errorf("cannot add custom-device slot %q: %v", slot.Name, err)
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	if plug.Attrs == nil {
+		plug.Attrs = make(map[string]interface{})
+	}
+	if err := iface.validateName(plug.Attrs, plug.Name); err != nil {
+		return fmt.Errorf("cannot add custom-device plug %q: %v", plug.Name, err)
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	device, err := parseCustomDevice(slot)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Description: access to the %s custom device\n", device.name)
+	for _, path := range device.devices {
+		fmt.Fprintf(&buf, "%s rw,\n", path)
+	}
+	for _, path := range device.readDevices {
+		fmt.Fprintf(&buf, "%s r,\n", path)
+	}
+	for _, path := range device.readFiles {
+		fmt.Fprintf(&buf, "%s r,\n", path)
+	}
+	for _, path := range device.writeFiles {
+		fmt.Fprintf(&buf, "%s rw,\n", path)
+	}
+	for _, dir := range device.hostDirectories {
+		fmt.Fprintf(&buf, "%s/{,**} r,\n", dir)
+	}
+	spec.AddSnippet(buf.String())
+
+	emit := spec.AddUpdateNSf
+	for _, dir := range device.hostDirectories {
+		emit("  # Mount host directory %s\n", dir)
+		emit("  mount options=(bind) /var/lib/snapd/hostfs%s/ -> %s/,\n", dir, dir)
+		emit("  remount options=(bind, ro) %s/,\n", dir)
+		emit("  umount %s/,\n", dir)
+		// the directory may need to be created in the base snap
+		apparmor.GenWritableProfile(emit, dir, 1)
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) MountConnectedPlug(spec *mount.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	device, err := parseCustomDevice(slot)
+	if err != nil {
+		return err
+	}
+	for _, dir := range device.hostDirectories {
+		err := spec.AddMountEntry(osutil.MountEntry{
+			Name:    filepath.Join("/var/lib/snapd/hostfs", dir),
+			Dir:     dir,
+			Options: []string{"bind", "ro"},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	device, err := parseCustomDevice(slot)
+	if err != nil {
+		return err
+	}
+	for _, rule := range device.udevRules {
+		spec.TagDevice(rule.String())
+	}
+	return nil
+}
+
+func init() {
+	registerIface(&customDeviceInterface{commonInterface{
+		name:                 "custom-device",
+		summary:              customDeviceSummary,
+		baseDeclarationSlots: customDeviceBaseDeclarationSlots,
+	}})
+}
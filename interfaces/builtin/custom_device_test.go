@@ -0,0 +1,242 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type CustomDeviceInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&CustomDeviceInterfaceSuite{
+	iface: builtin.MustInterface("custom-device"),
+})
+
+const customDeviceConsumerYaml = `name: consumer
+version: 0
+plugs:
+  my-board:
+    interface: custom-device
+apps:
+ app:
+  plugs: [my-board]
+`
+
+const customDeviceGadgetYaml = `name: gadget
+version: 0
+type: gadget
+slots:
+  my-board:
+    interface: custom-device
+    devices:
+      - /dev/foo[0-9]
+      - /dev/input/event*
+    read-devices:
+      - /dev/bar
+    files:
+      read:
+        - /sys/class/foo/*
+      write:
+        - /sys/devices/platform/foo/enable
+    host-directories:
+      - /usr/share/my-board
+`
+
+func (s *CustomDeviceInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, customDeviceConsumerYaml, nil, "my-board")
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+	s.slotInfo = snaptest.MockInfo(c, customDeviceGadgetYaml, nil).Slots["my-board"]
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "custom-device")
+}
+
+func (s *CustomDeviceInterfaceSuite) TestSanitizeDefaults(c *C) {
+	c.Check(s.slotInfo.Attrs["custom-device"], Equals, "my-board")
+	c.Check(s.plugInfo.Attrs["custom-device"], Equals, "my-board")
+}
+
+func (s *CustomDeviceInterfaceSuite) TestSanitizeSlotErrors(c *C) {
+	for _, t := range []struct {
+		attrs string
+		err   string
+	}{
+		{"custom-device: Foo\n    devices: [/dev/foo]", `cannot add custom-device slot "my-board": "custom-device" must be a valid name: Foo`},
+		{"read-devices: [/dev/foo]\n    custom-device: [foo]", `cannot add custom-device slot "my-board": "custom-device" must be a valid name: \[foo\]`},
+		{"udev-tagging: []", `cannot add custom-device slot "my-board": "devices", "read-devices" or "files" must be set`},
+		{"devices: /dev/foo", `cannot add custom-device slot "my-board": "devices" must be a list of strings`},
+		{"devices: [1]", `cannot add custom-device slot "my-board": "devices" must be a list of strings`},
+		{"devices: [/dev/../etc/shadow]", `cannot add custom-device slot "my-board": "devices" path "/dev/../etc/shadow" is not clean`},
+		{"devices: [/etc/shadow]", `cannot add custom-device slot "my-board": "devices" path "/etc/shadow" is not allowed`},
+		{`devices: ["/dev/foo{,bar}"]`, `cannot add custom-device slot "my-board": "devices" path "/dev/foo{,bar}" is not allowed`},
+		{`devices: ["/dev/foo rw,\n/etc/shadow"]`, `cannot add custom-device slot "my-board": "devices" path .* is not allowed`},
+		{"devices: [/dev/**]", `cannot add custom-device slot "my-board": device path "/dev/\*\*" is not allowed`},
+		{"read-devices: [/dev/*]", `cannot add custom-device slot "my-board": device path "/dev/\*" is not allowed`},
+		{"devices: ['/dev/[a-z]*']", `cannot add custom-device slot "my-board": device path "/dev/\[a-z\]\*" is not allowed`},
+		{"devices: ['/dev/[a-z][0-9]?']", `cannot add custom-device slot "my-board": device path "/dev/\[a-z\]\[0-9\]\?" is not allowed`},
+		{"files: [/sys/foo]", `cannot add custom-device slot "my-board": "files" must be a map`},
+		{"files: {exec: [/sys/foo]}", `cannot add custom-device slot "my-board": "files" does not support "exec"`},
+		{"files: {read: [/etc/foo]}", `cannot add custom-device slot "my-board": "files.read" path "/etc/foo" is not allowed`},
+		{"files: {read: [/sys/class/foo/**]}", `cannot add custom-device slot "my-board": "files.read" path "/sys/class/foo/\*\*" is not allowed`},
+		{"files: {write: [/sys/**/enable]}", `cannot add custom-device slot "my-board": "files.write" path "/sys/\*\*/enable" is not allowed`},
+		{`files: {write: ["/proc/@{pid}/foo"]}`, `cannot add custom-device slot "my-board": "files.write" path "/proc/@{pid}/foo" is not allowed`},
+		{"files: {write: [/proc/sys/kernel/core_pattern]}", `cannot add custom-device slot "my-board": "files.write" path "/proc/sys/kernel/core_pattern" is not allowed`},
+		{"files: {write: [/proc/sysrq-trigger]}", `cannot add custom-device slot "my-board": "files.write" path "/proc/sysrq-trigger" is not allowed`},
+		{"files: {read: [/proc/1/mem]}", `cannot add custom-device slot "my-board": "files.read" path "/proc/1/mem" is not allowed`},
+		{"files: {read: [/proc/*/mem]}", `cannot add custom-device slot "my-board": "files.read" path "/proc/\*/mem" is not allowed`},
+		{"files: {read: ['/proc/[0-9]*/environ']}", `cannot add custom-device slot "my-board": "files.read" path "/proc/\[0-9\]\*/environ" is not allowed`},
+		{"files: {write: [/sys/kernel/uevent_helper]}", `cannot add custom-device slot "my-board": "files.write" path "/sys/kernel/uevent_helper" is not allowed`},
+		{"files: {write: [/sys/*/foo/enable]}", `cannot add custom-device slot "my-board": "files.write" path "/sys/\*/foo/enable" is not allowed`},
+		{"files: {read: [/sys/k*/foo]}", `cannot add custom-device slot "my-board": "files.read" path "/sys/k\*/foo" is not allowed`},
+		{"files: {read: [/sys/foo]}\n    host-directories: [/var/lib/foo]", `cannot add custom-device slot "my-board": "host-directories" path "/var/lib/foo" is not allowed`},
+		{"files: {read: [/sys/foo]}\n    host-directories: [/etc/*]", `cannot add custom-device slot "my-board": "host-directories" path "/etc/\*" is not allowed`},
+		{"devices: [/dev/foo]\n    udev-tagging: {kernel: foo}", `cannot add custom-device slot "my-board": "udev-tagging" must be a list of maps`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{subsystem: foo}]", `cannot add custom-device slot "my-board": "udev-tagging" rules must have a valid "kernel" value`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: bar}]", `cannot add custom-device slot "my-board": "udev-tagging" kernel "bar" does not match any of the devices`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, run: /bin/sh}]", `cannot add custom-device slot "my-board": "udev-tagging" does not support "run"`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, subsystem: 'foo\", RUN+=\"/bin/sh'}]", `cannot add custom-device slot "my-board": "udev-tagging" subsystem .* is not allowed`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, attributes: {'id\"': bar}}]", `cannot add custom-device slot "my-board": "attributes" key "id\\"" is not allowed`},
+		{"devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, environment: {ID_MODEL: 'a\"'}}]", `cannot add custom-device slot "my-board": "environment" value "a\\"" is not allowed`},
+	} {
+		yaml := fmt.Sprintf("name: gadget\nversion: 0\ntype: gadget\nslots:\n  my-board:\n    interface: custom-device\n    %s\n", t.attrs)
+		slotInfo := snaptest.MockInfo(c, yaml, nil).Slots["my-board"]
+		c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, t.err, Commentf(t.attrs))
+	}
+}
+
+func (s *CustomDeviceInterfaceSuite) TestSanitizePlugErrors(c *C) {
+	plugInfo := snaptest.MockInfo(c, `name: consumer
+version: 0
+plugs:
+  my-board:
+    interface: custom-device
+    custom-device: "my board"
+`, nil).Plugs["my-board"]
+	c.Check(interfaces.BeforePreparePlug(s.iface, plugInfo), ErrorMatches, `cannot add custom-device plug "my-board": "custom-device" must be a valid name: my board`)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), Equals, `# Description: access to the my-board custom device
+/dev/foo[0-9] rw,
+/dev/input/event* rw,
+/dev/bar r,
+/sys/class/foo/* r,
+/sys/devices/platform/foo/enable rw,
+/usr/share/my-board/{,**} r,
+`)
+
+	updateNS := spec.UpdateNS()
+	c.Check(updateNS, testutil.Contains, "  # Mount host directory /usr/share/my-board\n")
+	c.Check(updateNS, testutil.Contains, "  mount options=(bind) /var/lib/snapd/hostfs/usr/share/my-board/ -> /usr/share/my-board/,\n")
+	c.Check(updateNS, testutil.Contains, "  remount options=(bind, ro) /usr/share/my-board/,\n")
+	c.Check(updateNS, testutil.Contains, "  umount /usr/share/my-board/,\n")
+	c.Check(updateNS, testutil.Contains, "  # Writable mimic /usr/share\n")
+}
+
+func (s *CustomDeviceInterfaceSuite) TestMountSpec(c *C) {
+	spec := &mount.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	entries := spec.MountEntries()
+	c.Assert(entries, HasLen, 1)
+	c.Check(entries[0].Name, Equals, "/var/lib/snapd/hostfs/usr/share/my-board")
+	c.Check(entries[0].Dir, Equals, "/usr/share/my-board")
+	c.Check(entries[0].Options, DeepEquals, []string{"bind", "ro"})
+}
+
+func (s *CustomDeviceInterfaceSuite) TestUDevSpec(c *C) {
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 4)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="foo[0-9]", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="event*", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="bar", TAG+="snap_consumer_app"`)
+	c.Check(spec.Snippets(), testutil.Contains, fmt.Sprintf(`TAG=="snap_consumer_app", RUN+="%v/snap-device-helper $env{ACTION} snap_consumer_app $devpath $major:$minor"`, dirs.DistroLibExecDir))
+}
+
+func (s *CustomDeviceInterfaceSuite) TestUDevSpecTagging(c *C) {
+	slotInfo := snaptest.MockInfo(c, `name: gadget
+version: 0
+type: gadget
+slots:
+  my-board:
+    interface: custom-device
+    devices:
+      - /dev/input/event*
+    udev-tagging:
+      - kernel: event*
+        subsystem: input
+        attributes:
+          idVendor: "1234"
+          idProduct: "5678"
+        environment:
+          ID_INPUT_TOUCHSCREEN: "1"
+`, nil).Slots["my-board"]
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Check(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="event*", SUBSYSTEM=="input", ATTRS{idProduct}=="5678", ATTRS{idVendor}=="1234", ENV{ID_INPUT_TOUCHSCREEN}=="1", TAG+="snap_consumer_app"`)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, false)
+	c.Assert(si.ImplicitOnClassic, Equals, false)
+	c.Assert(si.Summary, Equals, `provides access to custom devices specified via the gadget snap`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "custom-device: $SLOT(custom-device)")
+}
+
+func (s *CustomDeviceInterfaceSuite) TestAutoConnect(c *C) {
+	c.Assert(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+}
+
+func (s *CustomDeviceInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -23,10 +23,12 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 )
@@ -193,6 +195,64 @@ func (iface *hidrawInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
 	return true
 }
 
+func (iface *hidrawInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.Subsystem() != "hidraw" || !hidrawDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	return hotplugDeviceSlot(di), nil
+}
+
+// Pattern to match the sysfs path of a hidraw device, the HID device it
+// belongs to is named after its bus, vendor and product followed by a
+// sequence number that changes with every plug
+var hidrawHIDDevicePathPattern = regexp.MustCompile(`^(/devices/.*/[0-9A-F]{4}:([0-9A-F]{4}):([0-9A-F]{4}))\.[0-9A-F]+/hidraw/hidraw[0-9]+$`)
+
+func (iface *hidrawInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	if key := stableHotplugKey(di); key != "" {
+		return key, nil
+	}
+	// hidraw devices usually carry no identifiers, the path of the HID
+	// device without its sequence number identifies the port, the
+	// interface, the vendor and the product
+	devpath, _ := di.Attribute("DEVPATH")
+	m := hidrawHIDDevicePathPattern.FindStringSubmatch(devpath)
+	if m == nil {
+		return "", nil
+	}
+	return hotplugKeyOf("DEVPATH", m[1]), nil
+}
+
+func (iface *hidrawInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	var usbVendor, usbProduct int64
+	if err := slot.Attr("usb-vendor", &usbVendor); err == nil {
+		if err := slot.Attr("usb-product", &usbProduct); err != nil {
+			return false
+		}
+		if _, ok := di.Attribute("ID_VENDOR_ID"); ok {
+			return slotDeviceAttrEqual(di, "ID_VENDOR_ID", usbVendor) && slotDeviceAttrEqual(di, "ID_MODEL_ID", usbProduct)
+		}
+		// without the usb_id properties, use the identifiers of the
+		// HID device
+		devpath, _ := di.Attribute("DEVPATH")
+		m := hidrawHIDDevicePathPattern.FindStringSubmatch(devpath)
+		if m == nil {
+			return false
+		}
+		vendor, err := strconv.ParseInt(m[2], 16, 64)
+		if err != nil || vendor != usbVendor {
+			return false
+		}
+		product, err := strconv.ParseInt(m[3], 16, 64)
+		return err == nil && product == usbProduct
+	}
+
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
+		return false
+	}
+	return di.DeviceName() == path
+}
+
 func (iface *hidrawInterface) hasUsbAttrs(attrs interfaces.Attrer) bool {
 	var v int64
 	if err := attrs.Attr("usb-vendor", &v); err == nil {
@@ -21,6 +21,7 @@ package builtin_test
 
 import (
 	"fmt"
+	"path/filepath"
 
 	. "gopkg.in/check.v1"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -362,6 +364,68 @@ SUBSYSTEM=="hidraw", SUBSYSTEMS=="usb", ATTRS{idVendor}=="ffff", ATTRS{idProduct
 	c.Assert(extraSnippet, Equals, expectedExtraSnippet3)
 }
 
+func (s *HidrawInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/0003:1050:0407.0004/hidraw/hidraw2", "DEVNAME": "/dev/hidraw2", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Check(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/hidraw2"}})
+
+	// the hotplug slot is a single device
+	slotInfo := MockHotplugSlot(c, "name: core\nversion: 0\ntype: os", nil, "hotplugkey", "hidraw", "yubikey", proposedSlot.Attrs)
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+	udevSpec := &udev.Specification{}
+	c.Assert(udevSpec.AddConnectedPlug(s.iface, s.testPlugPort1, slot), IsNil)
+	c.Check(udevSpec.Snippets()[0], Equals, `# hidraw
+SUBSYSTEM=="hidraw", KERNEL=="hidraw2", TAG+="snap_client-snap_app-accessing-2-devices"`)
+
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/foo", "DEVNAME": "/dev/ttyUSB0", "ACTION": "add", "SUBSYSTEM": "tty"})
+	c.Assert(err, IsNil)
+	proposedSlot, err = hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Check(proposedSlot, IsNil)
+}
+
+func (s *HidrawInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	key := func(devpath string) snap.HotplugKey {
+		di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": devpath, "DEVNAME": "/dev/" + filepath.Base(devpath), "ACTION": "add", "SUBSYSTEM": "hidraw"})
+		c.Assert(err, IsNil)
+		key, err := keyHandler.HotplugKey(di)
+		c.Assert(err, IsNil)
+		return key
+	}
+
+	key1 := key("/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/0003:1050:0407.0004/hidraw/hidraw2")
+	c.Check(key1, Not(Equals), snap.HotplugKey(""))
+	// replugged into the same port
+	c.Check(key("/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/0003:1050:0407.0009/hidraw/hidraw3"), Equals, key1)
+	// another interface of the device
+	c.Check(key("/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.1/0003:1050:0407.0005/hidraw/hidraw3"), Not(Equals), key1)
+	// the default key is used
+	c.Check(key("/devices/virtual/misc/uhid/hidraw/hidraw4"), Equals, snap.HotplugKey(""))
+}
+
+func (s *HidrawInterfaceSuite) TestHotplugHandledByGadget(c *C) {
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/0003:0001:0001.0004/hidraw/hidraw0", "DEVNAME": "/dev/hidraw0", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+
+	// matching vendor and product
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev1Info), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev2Info), Equals, false)
+	// matching path
+	c.Check(byGadgetPred.HandledByGadget(di, s.testSlot1Info), Equals, true)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testSlot2Info), Equals, false)
+
+	// vendor and product from usb_id
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/foo/hidraw/hidraw1", "DEVNAME": "/dev/hidraw1", "ID_VENDOR_ID": "ffff", "ID_MODEL_ID": "ffff", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev1Info), Equals, false)
+	c.Check(byGadgetPred.HandledByGadget(di, s.testUDev2Info), Equals, true)
+}
+
 func (s *HidrawInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
@@ -0,0 +1,119 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/snap"
+)
+
+// hotplugDeviceSlot returns the slot proposed for a single hotplug device,
+// identified by its device node. The usb-vendor and usb-product attributes
+// of USB devices are informational, like with serial-port hotplug slots,
+// the connected plugs are restricted to the device node.
+func hotplugDeviceSlot(di *hotplug.HotplugDeviceInfo) *hotplug.ProposedSlot {
+	slot := hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	}
+	if bus, _ := di.Attribute("ID_BUS"); bus == "usb" {
+		if vendor, ok := di.Attribute("ID_VENDOR_ID"); ok {
+			slot.Attrs["usb-vendor"] = vendor
+		}
+		if product, ok := di.Attribute("ID_MODEL_ID"); ok {
+			slot.Attrs["usb-product"] = product
+		}
+	}
+	return &slot
+}
+
+// hotplugSlotPath returns the device node of a slot created for a single
+// hotplug device, or "" for other slots, e.g. implicit ones.
+func hotplugSlotPath(slot interfaces.Attrer) string {
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// verifyHotplugSlotPath checks that the path attribute, if the slot has
+// one, is a device node the interface hands out.
+func verifyHotplugSlotPath(slot *snap.SlotInfo, reg *regexp.Regexp) error {
+	path, ok := slot.Attrs["path"]
+	if !ok {
+		return nil
+	}
+	if s, ok := path.(string); !ok || !reg.MatchString(s) {
+		return fmt.Errorf("%s slot path attribute must be a valid device node", slot.Interface)
+	}
+	return nil
+}
+
+// hotplugKeyVersion is the version of the keys computed by
+// stableHotplugKey. Any change to the attributes going into the key requires
+// a new version, otherwise connections of known devices are lost.
+const hotplugKeyVersion = 0
+
+// stableHotplugKey computes a hotplug key identifying the given device
+// across replugs and reboots. Unlike the default key it is not shared by
+// the various device nodes of a single physical device, the extra attributes
+// tell them apart. The device is identified by its serial number or, when
+// it has none, by the port it is plugged into. An empty key is returned
+// when neither is known, in which case the default key is used.
+func stableHotplugKey(di *hotplug.HotplugDeviceInfo, extraAttrs ...string) snap.HotplugKey {
+	attrs := []string{"ID_VENDOR_ID", "ID_MODEL_ID"}
+	// ID_SERIAL falls back to the vendor and model names of devices
+	// without a serial number, only ID_SERIAL_SHORT is an actual one
+	if serial, _ := di.Attribute("ID_SERIAL_SHORT"); serial != "" {
+		attrs = append(attrs, "ID_SERIAL_SHORT")
+	} else if path, _ := di.Attribute("ID_PATH"); path != "" {
+		attrs = append(attrs, "ID_PATH")
+	} else {
+		return ""
+	}
+	attrs = append(attrs, "ID_USB_INTERFACE_NUM")
+	attrs = append(attrs, extraAttrs...)
+
+	var values []string
+	for _, attr := range attrs {
+		if val, ok := di.Attribute(attr); ok && val != "" {
+			values = append(values, attr, val)
+		}
+	}
+	return hotplugKeyOf(values...)
+}
+
+// hotplugKeyOf returns the hotplug key made of the given values, in the
+// same <version><checksum> format as the default keys.
+func hotplugKeyOf(values ...string) snap.HotplugKey {
+	key := sha256.New()
+	for _, val := range values {
+		key.Write([]byte(val))
+		key.Write([]byte{0})
+	}
+	return snap.HotplugKey(fmt.Sprintf("%x%x", hotplugKeyVersion, key.Sum(nil)))
+}
@@ -19,6 +19,14 @@
 
 package builtin
 
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/egress"
+	"github.com/snapcore/snapd/snap"
+)
+
 const networkSummary = `allows access to the network`
 
 const networkBaseDeclarationSlots = `
@@ -90,8 +98,61 @@ socket AF_NETLINK - NETLINK_ROUTE
 socket AF_CONN
 `
 
+type networkInterface struct {
+	commonInterface
+}
+
+// egressDestinations returns the destinations listed in the optional
+// "egress" attribute of the plug, and whether the attribute is set. Host
+// names are resolved when the egress ruleset of the snap is derived, not
+// when the applications connect.
+func egressDestinations(attrs interfaces.Attrer) ([]egress.Destination, bool, error) {
+	v, ok := attrs.Lookup("egress")
+	if !ok {
+		return nil, false, nil
+	}
+	dests, ok := v.([]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf(`"egress" must be a list of strings`)
+	}
+	destinations := make([]egress.Destination, 0, len(dests))
+	for _, v := range dests {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false, fmt.Errorf(`"egress" must be a list of strings`)
+		}
+		d, err := egress.ParseDestination(s)
+		if err != nil {
+			return nil, false, err
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, true, nil
+}
+
+func (iface *networkInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	if _, _, err := egressDestinations(plug); err != nil {
+		return fmt.Errorf("cannot add network plug: %v", err)
+	}
+	return nil
+}
+
+func (iface *networkInterface) EgressConnectedPlug(spec *egress.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	destinations, ok, err := egressDestinations(plug)
+	if err != nil {
+		return fmt.Errorf("cannot connect plug %s: %v", plug.Name(), err)
+	}
+	if !ok {
+		// without the attribute the plug reaches any destination
+		spec.AllowAllDestinations()
+		return nil
+	}
+	spec.AddDestinations(destinations)
+	return nil
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&networkInterface{commonInterface{
 		name:                  "network",
 		summary:               networkSummary,
 		implicitOnCore:        true,
@@ -99,5 +160,5 @@ func init() {
 		baseDeclarationSlots:  networkBaseDeclarationSlots,
 		connectedPlugAppArmor: networkConnectedPlugAppArmor,
 		connectedPlugSecComp:  networkConnectedPlugSecComp,
-	})
+	}})
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/egress"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -91,6 +92,64 @@ func (s *NetworkInterfaceSuite) TestUsedSecuritySystems(c *C) {
 	c.Check(seccompSpec.SnippetForTag("snap.other.app2"), testutil.Contains, "bind\n")
 }
 
+const netMockEgressPlugSnapInfoYaml = `name: other
+version: 1.0
+plugs:
+ network:
+  egress:
+   - 192.0.2.0/24
+   - "[2001:db8::1]:443"
+   - api.example.com:443
+apps:
+ app2:
+  command: foo
+  plugs: [network]
+`
+
+func (s *NetworkInterfaceSuite) TestSanitizePlugEgress(c *C) {
+	plugSnap := snaptest.MockInfo(c, netMockEgressPlugSnapInfoYaml, nil)
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plugSnap.Plugs["network"]), IsNil)
+
+	for _, t := range []struct {
+		egress interface{}
+		err    string
+	}{
+		{"example.com", `cannot add network plug: "egress" must be a list of strings`},
+		{[]interface{}{42}, `cannot add network plug: "egress" must be a list of strings`},
+		{[]interface{}{"example.com:http"}, `cannot add network plug: invalid port in destination "example.com:http"`},
+		{[]interface{}{"192.0.2.0/33"}, `cannot add network plug: invalid network in destination "192.0.2.0/33"`},
+		{[]interface{}{"-bad-.example.com"}, `cannot add network plug: invalid host in destination "-bad-.example.com"`},
+	} {
+		plugInfo := &snap.PlugInfo{
+			Snap:      plugSnap,
+			Name:      "network",
+			Interface: "network",
+			Attrs:     map[string]interface{}{"egress": t.egress},
+		}
+		c.Check(interfaces.BeforePreparePlug(s.iface, plugInfo), ErrorMatches, t.err)
+	}
+}
+
+func (s *NetworkInterfaceSuite) TestEgressConnectedPlug(c *C) {
+	// without the egress attribute any destination can be reached
+	spec := &egress.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.SecurityTags(), HasLen, 0)
+
+	plugSnap := snaptest.MockInfo(c, netMockEgressPlugSnapInfoYaml, nil)
+	plug := interfaces.NewConnectedPlug(plugSnap.Plugs["network"], nil, nil)
+	spec = &egress.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	c.Check(spec.SecurityTags(), DeepEquals, []string{"snap.other.app2"})
+	dests, restricted := spec.DestinationsForTag("snap.other.app2")
+	c.Check(restricted, Equals, true)
+	c.Check(dests, DeepEquals, []egress.Destination{
+		{Host: "192.0.2.0/24"},
+		{Host: "2001:db8::1", Port: 443},
+		{Host: "api.example.com", Port: 443},
+	})
+}
+
 func (s *NetworkInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
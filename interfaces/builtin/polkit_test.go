@@ -0,0 +1,207 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/polkit"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type polkitInterfaceSuite struct {
+	testutil.BaseTest
+
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&polkitInterfaceSuite{
+	iface: builtin.MustInterface("polkit"),
+})
+
+const polkitConsumerYaml = `name: other
+version: 0
+apps:
+  app:
+    command: foo
+    plugs: [polkit]
+plugs:
+  polkit:
+    action-prefix: org.example.foo
+`
+
+const polkitCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  polkit:
+`
+
+const polkitSamplePolicy = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE policyconfig PUBLIC
+ "-//freedesktop//DTD PolicyKit Policy Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/PolicyKit/1.0/policyconfig.dtd">
+<policyconfig>
+  <action id="org.example.foo.some-action">
+    <description>Some action</description>
+    <message>Authentication is required to do some action</message>
+    <defaults>
+      <allow_any>no</allow_any>
+      <allow_inactive>no</allow_inactive>
+      <allow_active>auth_admin</allow_active>
+    </defaults>
+  </action>
+</policyconfig>
+`
+
+func (s *polkitInterfaceSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.plug, s.plugInfo = MockConnectedPlug(c, polkitConsumerYaml, &snap.SideInfo{Revision: snap.R(1)}, "polkit")
+	s.slot, s.slotInfo = MockConnectedSlot(c, polkitCoreYaml, nil, "polkit")
+}
+
+func (s *polkitInterfaceSuite) writePolicy(c *C, name, content string) {
+	policyDir := filepath.Join(s.plugInfo.Snap.MountDir(), "meta", "polkit")
+	c.Assert(os.MkdirAll(policyDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(policyDir, name), []byte(content), 0644), IsNil)
+}
+
+func (s *polkitInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "polkit")
+}
+
+func (s *polkitInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Check(si.ImplicitOnCore, Equals, false)
+	c.Check(si.ImplicitOnClassic, Equals, true)
+	c.Check(si.Summary, Equals, "allows access to polkitd to check authorisation")
+	c.Check(si.BaseDeclarationPlugs, testutil.Contains, "polkit")
+	c.Check(si.BaseDeclarationSlots, testutil.Contains, "polkit")
+}
+
+func (s *polkitInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *polkitInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *polkitInterfaceSuite) TestSanitizePlugErrors(c *C) {
+	for _, t := range []struct {
+		attrs string
+		err   string
+	}{{
+		attrs: ``,
+		err:   `snap "other" does not have attribute "action-prefix" for interface "polkit"`,
+	}, {
+		attrs: `action-prefix: 42`,
+		err:   `snap "other" has interface "polkit" with invalid value type for "action-prefix" attribute`,
+	}, {
+		attrs: `action-prefix: foo`,
+		err:   `plug has invalid action-prefix: "foo"`,
+	}, {
+		attrs: `action-prefix: org.example.foo.*`,
+		err:   `plug has invalid action-prefix: "org.example.foo.\*"`,
+	}} {
+		info := snaptest.MockInfo(c, `name: other
+version: 0
+plugs:
+  polkit:
+    `+t.attrs+`
+`, nil)
+		plug := info.Plugs["polkit"]
+		c.Check(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, t.err, Commentf("attrs: %s", t.attrs))
+	}
+}
+
+func (s *polkitInterfaceSuite) TestAppArmorConnectedPlug(c *C) {
+	apparmorSpec := &apparmor.Specification{}
+	err := apparmorSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(apparmorSpec.SecurityTags(), DeepEquals, []string{"snap.other.app"})
+	c.Check(apparmorSpec.SnippetForTag("snap.other.app"), testutil.Contains, `interface="org.freedesktop.PolicyKit1.Authority"`)
+	c.Check(apparmorSpec.SnippetForTag("snap.other.app"), testutil.Contains, `member="{,Cancel}CheckAuthorization"`)
+}
+
+func (s *polkitInterfaceSuite) TestPolkitConnectedPlug(c *C) {
+	s.writePolicy(c, "polkit.foo.policy", polkitSamplePolicy)
+	s.writePolicy(c, "polkit.bar.policy", polkitSamplePolicy)
+	// policies of other plugs are not picked up
+	s.writePolicy(c, "other.baz.policy", polkitSamplePolicy)
+
+	polkitSpec := &polkit.Specification{}
+	err := polkitSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Check(polkitSpec.Policies(), DeepEquals, map[string]polkit.Policy{
+		"polkit.foo": polkit.Policy(polkitSamplePolicy),
+		"polkit.bar": polkit.Policy(polkitSamplePolicy),
+	})
+}
+
+func (s *polkitInterfaceSuite) TestPolkitConnectedPlugNoPolicies(c *C) {
+	polkitSpec := &polkit.Specification{}
+	err := polkitSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Check(err, ErrorMatches, `cannot find any policy files for plug "polkit"`)
+}
+
+func (s *polkitInterfaceSuite) TestPolkitConnectedPlugInvalidPolicy(c *C) {
+	s.writePolicy(c, "polkit.foo.policy", `<policyconfig><action id="org.example.foo.bar"><annotate key="org.freedesktop.policykit.imply">org.freedesktop.login1.reboot</annotate></action></policyconfig>`)
+
+	polkitSpec := &polkit.Specification{}
+	err := polkitSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Check(err, ErrorMatches, `cannot validate policy file ".*/polkit.foo.policy": invalid policy: action "org.example.foo.bar" uses unsupported annotation "org.freedesktop.policykit.imply"`)
+}
+
+func (s *polkitInterfaceSuite) TestPolkitConnectedPlugActionOutsidePrefix(c *C) {
+	for _, id := range []string{"org.example.bar", "org.example.foobar", "org.example"} {
+		s.writePolicy(c, "polkit.foo.policy", `<policyconfig><action id="`+id+`"/></policyconfig>`)
+
+		polkitSpec := &polkit.Specification{}
+		err := polkitSpec.AddConnectedPlug(s.iface, s.plug, s.slot)
+		c.Check(err, ErrorMatches, `policy file ".*/polkit.foo.policy" contains unexpected action ID "`+id+`"`)
+	}
+
+	// the prefix itself is a valid action ID
+	s.writePolicy(c, "polkit.foo.policy", `<policyconfig><action id="org.example.foo"/></policyconfig>`)
+	polkitSpec := &polkit.Specification{}
+	c.Check(polkitSpec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+}
+
+func (s *polkitInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -19,6 +19,17 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 const rawusbSummary = `allows raw access to all USB devices`
 
 const rawusbBaseDeclarationSlots = `
@@ -66,8 +77,47 @@ var rawusbConnectedPlugUDev = []string{
 	`SUBSYSTEM=="tty", ENV{ID_BUS}=="usb"`,
 }
 
+// Pattern to match the USB device nodes handed out by hotplug slots
+var rawusbDeviceNodePattern = regexp.MustCompile("^/dev/bus/usb/[0-9]{3}/[0-9]{3}$")
+
+type rawusbInterface struct {
+	commonInterface
+}
+
+func (iface *rawusbInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	return verifyHotplugSlotPath(slot, rawusbDeviceNodePattern)
+}
+
+func (iface *rawusbInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	// A hotplug slot is a single USB device, the implicit slot is all of
+	// them. The AppArmor rules are the same for both, udev tagging and
+	// device cgroups restrict down to the specific device. The kernel name
+	// of USB devices is their port, match the device node instead.
+	if path := hotplugSlotPath(slot); path != "" {
+		spec.TagDevice(fmt.Sprintf(`SUBSYSTEM=="usb", ENV{DEVNAME}=="%s"`, path))
+		return nil
+	}
+	return iface.commonInterface.UDevConnectedPlug(spec, plug, slot)
+}
+
+func (iface *rawusbInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.Subsystem() != "usb" || di.DeviceType() != "usb_device" || !rawusbDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	// hubs, including the root ones, are not handed out; TYPE is the
+	// class/subclass/protocol of the device
+	if typ, _ := di.Attribute("TYPE"); strings.HasPrefix(typ, "9/") {
+		return nil, nil
+	}
+	return hotplugDeviceSlot(di), nil
+}
+
+func (iface *rawusbInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	return stableHotplugKey(di), nil
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&rawusbInterface{commonInterface{
 		name:                  "raw-usb",
 		summary:               rawusbSummary,
 		implicitOnCore:        true,
@@ -76,5 +126,5 @@ func init() {
 		connectedPlugAppArmor: rawusbConnectedPlugAppArmor,
 		connectedPlugSecComp:  rawusbConnectedPlugSecComp,
 		connectedPlugUDev:     rawusbConnectedPlugUDev,
-	})
+	}})
 }
@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
@@ -104,6 +105,46 @@ SUBSYSTEM=="tty", ENV{ID_BUS}=="usb", TAG+="snap_consumer_app"`)
 	c.Assert(spec.Snippets(), testutil.Contains, fmt.Sprintf(`TAG=="snap_consumer_app", RUN+="%v/snap-device-helper $env{ACTION} snap_consumer_app $devpath $major:$minor"`, dirs.DistroLibExecDir))
 }
 
+func (s *RawUsbInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-4", "DEVNAME": "/dev/bus/usb/001/007", "DEVTYPE": "usb_device", "TYPE": "255/255/255", "ACTION": "add", "SUBSYSTEM": "usb", "ID_BUS": "usb", "ID_VENDOR_ID": "0403", "ID_MODEL_ID": "6001", "ID_SERIAL_SHORT": "A50285BI"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Check(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/bus/usb/001/007", "usb-vendor": "0403", "usb-product": "6001"}})
+
+	// replugged, the device number changes
+	key1, err := s.iface.(hotplug.HotplugKeyHandler).HotplugKey(di)
+	c.Assert(err, IsNil)
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-4", "DEVNAME": "/dev/bus/usb/001/008", "DEVTYPE": "usb_device", "TYPE": "255/255/255", "ACTION": "add", "SUBSYSTEM": "usb", "ID_BUS": "usb", "ID_VENDOR_ID": "0403", "ID_MODEL_ID": "6001", "ID_SERIAL_SHORT": "A50285BI"})
+	c.Assert(err, IsNil)
+	key2, err := s.iface.(hotplug.HotplugKeyHandler).HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key2, Equals, key1)
+
+	// interfaces and hubs are not handed out
+	for _, env := range []map[string]string{
+		{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-4/1-4:1.0", "DEVTYPE": "usb_interface", "ACTION": "add", "SUBSYSTEM": "usb"},
+		{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1", "DEVNAME": "/dev/bus/usb/001/001", "DEVTYPE": "usb_device", "TYPE": "9/0/1", "ACTION": "add", "SUBSYSTEM": "usb"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, IsNil)
+	}
+}
+
+func (s *RawUsbInterfaceSuite) TestUDevSpecHotplugSlot(c *C) {
+	slotInfo := MockHotplugSlot(c, rawusbCoreYaml, nil, "hotplugkey", "raw-usb", "ftdi", map[string]interface{}{"path": "/dev/bus/usb/001/007"})
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Assert(spec.Snippets(), testutil.Contains, `# raw-usb
+SUBSYSTEM=="usb", ENV{DEVNAME}=="/dev/bus/usb/001/007", TAG+="snap_consumer_app"`)
+}
+
 func (s *RawUsbInterfaceSuite) TestStaticInfo(c *C) {
 	si := interfaces.StaticInfoOf(s.iface)
 	c.Assert(si.ImplicitOnCore, Equals, true)
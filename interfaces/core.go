@@ -266,6 +266,12 @@ const (
 	SecurityKMod SecuritySystem = "kmod"
 	// SecuritySystemd identifies the systemd services security system.
 	SecuritySystemd SecuritySystem = "systemd"
+	// SecurityLandlock identifies the landlock security system.
+	SecurityLandlock SecuritySystem = "landlock"
+	// SecurityEgress identifies the network egress security system.
+	SecurityEgress SecuritySystem = "egress"
+	// SecurityPolkit identifies the polkit security system.
+	SecurityPolkit SecuritySystem = "polkit"
 )
 
 var isValidBusName = regexp.MustCompile(`^[a-zA-Z_-][a-zA-Z0-9_-]*(\.[a-zA-Z_-][a-zA-Z0-9_-]*)+$`).MatchString
@@ -0,0 +1,359 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package egress implements a backend restricting the network destinations
+// snaps can reach.
+//
+// Interfaces restrict the applications and hooks of a snap to some network
+// destinations via their respective "Egress*" methods. The backend stores an
+// nftables ruleset for each restricted snap in
+// /var/lib/snapd/egress/snap.<snapname>.nft and loads it with nft. The
+// ruleset has a table named after the snap, with one chain of allowed
+// destinations per restricted security tag. Packets sent by the processes
+// of the snap are sent to the chain of their security tag based on the
+// cgroup of the process, that is the slice snap run starts the
+// applications of the tag in, or the tracking cgroup of the process when it
+// is not in a slice, as with services. Only the unified hierarchy is
+// supported, snaps cannot be restricted on systems using cgroup v1.
+//
+// As nftables resolves cgroups when loading the rules, the ruleset must be
+// updated when a cgroup it does not match is created, see UpdateCgroups
+// and RulesetMatchesProcess. Host names of destinations are resolved at
+// the same time, their addresses are not followed in between. The name
+// servers of the system are always allowed, for applications to resolve
+// host names.
+package egress
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timings"
+)
+
+var (
+	cgroupsOfSnap    = cgroup.CgroupsOfSnap
+	cgroupID         = cgroup.ID
+	processCgroupIDs = cgroup.ProcessCgroupIDs
+	lookupIP         = net.LookupIP
+)
+
+// Backend is responsible for maintaining the nftables rulesets of snaps.
+type Backend struct {
+	preseed bool
+}
+
+// Initialize does nothing.
+func (b *Backend) Initialize(opts *interfaces.SecurityBackendOptions) error {
+	if opts != nil && opts.Preseed {
+		b.preseed = true
+	}
+	return nil
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() interfaces.SecuritySystem {
+	return interfaces.SecurityEgress
+}
+
+// Setup creates and loads the nftables ruleset of the given snap, if some
+// of its applications or hooks are restricted to some destinations. Snaps
+// in devmode and classic snaps not in jailmode are not restricted.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	restrict := !opts.DevMode && (!opts.Classic || opts.JailMode)
+	return b.ensureRuleset(snapInfo.InstanceName(), restrict, repo)
+}
+
+// Remove removes and unloads the nftables ruleset of the given snap.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Remove(snapName string) error {
+	return b.ensureRuleset(snapName, false, nil)
+}
+
+// UpdateCgroups updates the nftables ruleset of the given snap with its
+// current cgroups and the current addresses of its destinations, and
+// reloads it. Snaps without a ruleset are left alone.
+func (b *Backend) UpdateCgroups(snapName string, repo *interfaces.Repository) error {
+	if !osutil.FileExists(rulesetPath(snapName)) {
+		return nil
+	}
+	return b.ensureRuleset(snapName, true, repo)
+}
+
+// RulesetMatchesProcess returns whether the nftables ruleset of the given
+// snap matches the cgroup of the given process, or one of its parents.
+// Processes of snaps without a ruleset are not restricted and always
+// match.
+func RulesetMatchesProcess(snapName string, pid int) (bool, error) {
+	content, err := ioutil.ReadFile(rulesetPath(snapName))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ids, err := processCgroupIDs(pid)
+	if err != nil {
+		return false, err
+	}
+	matched := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		matched[strconv.FormatUint(id, 10)] = true
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "#" && fields[1] == "cgroup-id" && matched[fields[2]] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func rulesetPath(snapName string) string {
+	return filepath.Join(dirs.SnapEgressDir, rulesetName(snapName))
+}
+
+func rulesetName(snapName string) string {
+	return fmt.Sprintf("%s.nft", snap.SecurityTag(snapName))
+}
+
+func (b *Backend) ensureRuleset(snapName string, restrict bool, repo *interfaces.Repository) error {
+	var spec *Specification
+	if restrict {
+		s, err := repo.SnapSpecification(b.Name(), snapName)
+		if err != nil {
+			return fmt.Errorf("cannot obtain egress specification for snap %q: %s", snapName, err)
+		}
+		spec = s.(*Specification)
+		if len(spec.SecurityTags()) == 0 {
+			spec = nil
+		}
+	}
+	if spec != nil && !b.preseed && !cgroup.IsUnified() {
+		return fmt.Errorf("cannot restrict network destinations of snap %q: unsupported without cgroup v2", snapName)
+	}
+
+	dir := dirs.SnapEgressDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for egress rulesets %q: %s", dir, err)
+	}
+
+	// The cgroups of the snap can go away before the ruleset is loaded, in
+	// which case nft fails and the ruleset is derived again.
+	var err error
+	for tries := 0; tries < 3; tries++ {
+		var content map[string]osutil.FileState
+		if spec != nil {
+			content, err = b.deriveContent(spec, snapName)
+			if err != nil {
+				return err
+			}
+		}
+		var removed []string
+		_, removed, err = osutil.EnsureDirState(dir, rulesetName(snapName), content)
+		if err != nil {
+			return fmt.Errorf("cannot synchronize egress ruleset for snap %q: %s", snapName, err)
+		}
+		if b.preseed {
+			return nil
+		}
+		if spec == nil {
+			if len(removed) > 0 {
+				return unloadRuleset(snapName)
+			}
+			return nil
+		}
+		if err = loadRuleset(snapName); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func loadRuleset(snapName string) error {
+	output, err := exec.Command("nft", "-f", rulesetPath(snapName)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot load egress ruleset of snap %q: %v", snapName, osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+func unloadRuleset(snapName string) error {
+	output, err := exec.Command("nft", "delete", "table", "inet", snap.SecurityTag(snapName)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot unload egress ruleset of snap %q: %v", snapName, osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+func (b *Backend) deriveContent(spec *Specification, snapName string) (map[string]osutil.FileState, error) {
+	// no application runs when preseeding
+	var cgroupsByTag map[string][]string
+	if !b.preseed {
+		var err error
+		cgroupsByTag, err = cgroupsOfSnap(snapName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	nameServers := nameServerRules()
+	table := snap.SecurityTag(snapName)
+	tags := spec.SecurityTags()
+
+	var buffer bytes.Buffer
+	buffer.WriteString("# This file is automatically generated.\n")
+	// declaring the table before deleting it replaces any previous version
+	fmt.Fprintf(&buffer, "table inet %s\ndelete table inet %s\n", table, table)
+	fmt.Fprintf(&buffer, "table inet %s {\n", table)
+	buffer.WriteString("\tchain output {\n")
+	buffer.WriteString("\t\ttype filter hook output priority 0; policy accept;\n")
+	for _, tag := range tags {
+		cgroups := append([]string(nil), cgroupsByTag[tag]...)
+		sort.Strings(cgroups)
+		for _, cg := range cgroups {
+			id, err := cgroupID(cg)
+			if err != nil {
+				// the cgroup went away
+				continue
+			}
+			// the identifier is what nftables matches, it tells
+			// RulesetMatchesProcess which processes are restricted
+			fmt.Fprintf(&buffer, "\t\t# cgroup-id %d\n", id)
+			fmt.Fprintf(&buffer, "\t\tsocket cgroupv2 level %d %q jump %s\n", strings.Count(cg, "/")+1, cg, tag)
+		}
+	}
+	buffer.WriteString("\t}\n")
+	for _, tag := range tags {
+		destinations, _ := spec.DestinationsForTag(tag)
+		fmt.Fprintf(&buffer, "\tchain %s {\n", tag)
+		buffer.WriteString("\t\toif \"lo\" accept\n")
+		buffer.WriteString("\t\tct state established,related accept\n")
+		for _, rule := range nameServers {
+			fmt.Fprintf(&buffer, "\t\t%s accept\n", rule)
+		}
+		for _, rule := range destinationRules(destinations) {
+			fmt.Fprintf(&buffer, "\t\t%s accept\n", rule)
+		}
+		buffer.WriteString("\t\treject\n")
+		buffer.WriteString("\t}\n")
+	}
+	buffer.WriteString("}\n")
+
+	return map[string]osutil.FileState{
+		rulesetName(snapName): &osutil.MemoryFileState{
+			Content: buffer.Bytes(),
+			Mode:    0644,
+		},
+	}, nil
+}
+
+// nameServerRules returns the nftables matches of the DNS service of the
+// name servers of the system. Loopback name servers, like the stub
+// resolver of systemd-resolved, are always reachable.
+func nameServerRules() []string {
+	content, err := ioutil.ReadFile(filepath.Join(dirs.GlobalRootDir, "/etc/resolv.conf"))
+	if err != nil {
+		logger.Noticef("cannot read name servers: %v", err)
+		return nil
+	}
+	var destinations []Destination
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		ip := net.ParseIP(fields[1])
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		destinations = append(destinations, Destination{Host: ip.String(), Port: 53})
+	}
+	return destinationRules(destinations)
+}
+
+// destinationRules returns the nftables matches of the given destinations,
+// host names are resolved to their current addresses.
+func destinationRules(destinations []Destination) []string {
+	var rules []string
+	seen := make(map[string]bool)
+	for _, d := range destinations {
+		var addrs []string
+		switch {
+		case strings.Contains(d.Host, "/"), net.ParseIP(d.Host) != nil:
+			addrs = []string{d.Host}
+		default:
+			ips, err := lookupIP(d.Host)
+			if err != nil {
+				// the destination stays unreachable
+				logger.Noticef("cannot resolve egress destination %q: %v", d.Host, err)
+				continue
+			}
+			for _, ip := range ips {
+				addrs = append(addrs, ip.String())
+			}
+		}
+		for _, addr := range addrs {
+			family := "ip"
+			if strings.Contains(addr, ":") {
+				family = "ip6"
+			}
+			rule := fmt.Sprintf("%s daddr %s", family, addr)
+			if d.Port != 0 {
+				rule += fmt.Sprintf(" meta l4proto { tcp, udp } th dport %d", d.Port)
+			}
+			if !seen[rule] {
+				seen[rule] = true
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// NewSpecification returns a new egress specification.
+func (b *Backend) NewSpecification() interfaces.Specification {
+	return &Specification{}
+}
+
+// SandboxFeatures returns the list of features supported by snapd for
+// restricting network destinations, the nftables rules match the processes
+// of snaps by their cgroup in the unified hierarchy.
+func (b *Backend) SandboxFeatures() []string {
+	if !cgroup.IsUnified() {
+		return nil
+	}
+	return []string{"nftables-cgroupv2"}
+}
@@ -0,0 +1,288 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package egress_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/egress"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+
+	nftCmd  *testutil.MockCmd
+	cgroups map[string][]string
+	meas    *timings.Span
+}
+
+var _ = Suite(&backendSuite{})
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &egress.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+
+	s.nftCmd = testutil.MockCommand(c, "nft", "")
+	s.AddCleanup(s.nftCmd.Restore)
+
+	s.AddCleanup(cgroup.MockVersion(cgroup.V2, nil))
+	c.Assert(os.MkdirAll(filepath.Join(s.RootDir, "/etc"), 0755), IsNil)
+	resolvConf := "# generated\nnameserver 127.0.0.53\nnameserver 192.0.2.53\nnameserver fe80::1%eth0\noptions edns0\n"
+	c.Assert(ioutil.WriteFile(filepath.Join(s.RootDir, "/etc/resolv.conf"), []byte(resolvConf), 0644), IsNil)
+
+	s.cgroups = map[string][]string{
+		"snap.samba.smbd": {
+			"user.slice/user-1000.slice/user@1000.service/app.slice/snap.samba.smbd.1234.scope",
+			"system.slice/snap.samba.smbd.service",
+		},
+	}
+	s.AddCleanup(egress.MockCgroupsOfSnap(func(snapName string) (map[string][]string, error) {
+		c.Check(snapName, Equals, "samba")
+		return s.cgroups, nil
+	}))
+	s.AddCleanup(egress.MockCgroupID(func(path string) (uint64, error) {
+		return uint64(len(path)), nil
+	}))
+	s.AddCleanup(egress.MockLookupIP(func(host string) ([]net.IP, error) {
+		switch host {
+		case "telemetry.example.com":
+			return []net.IP{net.ParseIP("198.51.100.7"), net.ParseIP("2001:db8::7")}, nil
+		}
+		return nil, errors.New("no such host")
+	}))
+
+	perf := timings.New(nil)
+	s.meas = perf.StartSpan("", "")
+
+	s.Iface.EgressPermanentSlotCallback = func(spec *egress.Specification, slot *snap.SlotInfo) error {
+		spec.AddDestinations([]egress.Destination{
+			{Host: "192.0.2.0/24"},
+			{Host: "telemetry.example.com", Port: 443},
+			{Host: "unknown.example.com"},
+		})
+		return nil
+	}
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	s.BackendSuite.TearDownTest(c)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecurityEgress)
+}
+
+const sambaRuleset = `# This file is automatically generated.
+table inet snap.samba
+delete table inet snap.samba
+table inet snap.samba {
+	chain output {
+		type filter hook output priority 0; policy accept;
+		# cgroup-id 36
+		socket cgroupv2 level 2 "system.slice/snap.samba.smbd.service" jump snap.samba.smbd
+		# cgroup-id 81
+		socket cgroupv2 level 5 "user.slice/user-1000.slice/user@1000.service/app.slice/snap.samba.smbd.1234.scope" jump snap.samba.smbd
+	}
+	chain snap.samba.smbd {
+		oif "lo" accept
+		ct state established,related accept
+		ip daddr 192.0.2.53 meta l4proto { tcp, udp } th dport 53 accept
+		ip daddr 192.0.2.0/24 accept
+		ip daddr 198.51.100.7 meta l4proto { tcp, udp } th dport 443 accept
+		ip6 daddr 2001:db8::7 meta l4proto { tcp, udp } th dport 443 accept
+		reject
+	}
+}
+`
+
+func (s *backendSuite) TestInstallingSnapWritesAndLoadsRuleset(c *C) {
+	path := filepath.Join(dirs.SnapEgressDir, "snap.samba.nft")
+	for _, opts := range []interfaces.ConfinementOptions{{}, {JailMode: true}, {Classic: true, JailMode: true}} {
+		s.nftCmd.ForgetCalls()
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Check(path, testutil.FileEquals, sambaRuleset)
+		c.Check(s.nftCmd.Calls(), DeepEquals, [][]string{{"nft", "-f", path}})
+
+		s.nftCmd.ForgetCalls()
+		s.RemoveSnap(c, snapInfo)
+		c.Check(path, testutil.FileAbsent)
+		c.Check(s.nftCmd.Calls(), DeepEquals, [][]string{{"nft", "delete", "table", "inet", "snap.samba"}})
+	}
+}
+
+func (s *backendSuite) TestUnrestrictedSnapsHaveNoRuleset(c *C) {
+	for _, opts := range []interfaces.ConfinementOptions{{DevMode: true}, {Classic: true}} {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Check(filepath.Join(dirs.SnapEgressDir, "snap.samba.nft"), testutil.FileAbsent)
+		s.RemoveSnap(c, snapInfo)
+	}
+	c.Check(s.nftCmd.Calls(), HasLen, 0)
+
+	s.Iface.EgressPermanentSlotCallback = func(spec *egress.Specification, slot *snap.SlotInfo) error {
+		spec.AllowAllDestinations()
+		return nil
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(filepath.Join(dirs.SnapEgressDir, "snap.samba.nft"), testutil.FileAbsent)
+	s.RemoveSnap(c, snapInfo)
+	c.Check(s.nftCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestUpdateCgroups(c *C) {
+	b := s.Backend.(*egress.Backend)
+	path := filepath.Join(dirs.SnapEgressDir, "snap.samba.nft")
+
+	// snaps without rulesets are left alone
+	c.Assert(b.UpdateCgroups("samba", s.Repo), IsNil)
+	c.Check(path, testutil.FileAbsent)
+
+	s.cgroups = nil
+	s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(path, Not(testutil.FileContains), "socket cgroupv2")
+
+	s.cgroups = map[string][]string{
+		"snap.samba.smbd": {"system.slice/snap.samba.smbd.service"},
+	}
+	s.nftCmd.ForgetCalls()
+	c.Assert(b.UpdateCgroups("samba", s.Repo), IsNil)
+	c.Check(path, testutil.FileContains, `socket cgroupv2 level 2 "system.slice/snap.samba.smbd.service" jump snap.samba.smbd`)
+	c.Check(s.nftCmd.Calls(), DeepEquals, [][]string{{"nft", "-f", path}})
+
+	// the ruleset is loaded again even if it did not change
+	s.nftCmd.ForgetCalls()
+	c.Assert(b.UpdateCgroups("samba", s.Repo), IsNil)
+	c.Check(s.nftCmd.Calls(), DeepEquals, [][]string{{"nft", "-f", path}})
+}
+
+func (s *backendSuite) TestUpdateCgroupsSkipsRemovedCgroups(c *C) {
+	b := s.Backend.(*egress.Backend)
+	path := filepath.Join(dirs.SnapEgressDir, "snap.samba.nft")
+	s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+
+	restore := egress.MockCgroupID(func(path string) (uint64, error) {
+		if path == "system.slice/snap.samba.smbd.service" {
+			return 0, os.ErrNotExist
+		}
+		return 42, nil
+	})
+	defer restore()
+	c.Assert(b.UpdateCgroups("samba", s.Repo), IsNil)
+	c.Check(path, Not(testutil.FileContains), "system.slice")
+	c.Check(path, testutil.FileContains, "# cgroup-id 42\n\t\tsocket cgroupv2 level 5")
+}
+
+func (s *backendSuite) TestRulesetMatchesProcess(c *C) {
+	restore := egress.MockProcessCgroupIDs(func(pid int) ([]uint64, error) {
+		c.Check(pid, Equals, 1234)
+		return []uint64{1000, 81, 1}, nil
+	})
+	defer restore()
+
+	// processes of snaps without ruleset are not restricted
+	matches, err := egress.RulesetMatchesProcess("samba", 1234)
+	c.Assert(err, IsNil)
+	c.Check(matches, Equals, true)
+
+	s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	matches, err = egress.RulesetMatchesProcess("samba", 1234)
+	c.Assert(err, IsNil)
+	c.Check(matches, Equals, true)
+
+	restore = egress.MockProcessCgroupIDs(func(pid int) ([]uint64, error) {
+		return []uint64{1000, 1}, nil
+	})
+	defer restore()
+	matches, err = egress.RulesetMatchesProcess("samba", 1234)
+	c.Assert(err, IsNil)
+	c.Check(matches, Equals, false)
+
+	restore = egress.MockProcessCgroupIDs(func(pid int) ([]uint64, error) {
+		return nil, errors.New("cgroup v2 is not in use")
+	})
+	defer restore()
+	_, err = egress.RulesetMatchesProcess("samba", 1234)
+	c.Assert(err, ErrorMatches, "cgroup v2 is not in use")
+}
+
+func (s *backendSuite) TestSetupUnsupportedWithoutCgroupV2(c *C) {
+	restore := cgroup.MockVersion(cgroup.V1, nil)
+	defer restore()
+
+	// unrestricted snaps are fine
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{DevMode: true}, "", ifacetest.SambaYamlV1, 0)
+
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, ErrorMatches, `cannot restrict network destinations of snap "samba": unsupported without cgroup v2`)
+	c.Check(filepath.Join(dirs.SnapEgressDir, "snap.samba.nft"), testutil.FileAbsent)
+	c.Check(s.nftCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestSetupLoadErrorIsRetried(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+
+	s.nftCmd = testutil.MockCommand(c, "nft", "echo 'no such cgroup'; exit 1")
+	s.AddCleanup(s.nftCmd.Restore)
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, ErrorMatches, `cannot load egress ruleset of snap "samba": no such cgroup`)
+	c.Check(s.nftCmd.Calls(), HasLen, 3)
+}
+
+func (s *backendSuite) TestSetupCgroupError(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	s.nftCmd.ForgetCalls()
+
+	restore := egress.MockCgroupsOfSnap(func(snapName string) (map[string][]string, error) {
+		return nil, errors.New(`cannot find cgroups of snap "samba": cgroup v2 is not in use`)
+	})
+	defer restore()
+
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, ErrorMatches, `cannot find cgroups of snap "samba": cgroup v2 is not in use`)
+	c.Check(s.nftCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	restore := cgroup.MockVersion(cgroup.V2, nil)
+	defer restore()
+	c.Check(s.Backend.SandboxFeatures(), DeepEquals, []string{"nftables-cgroupv2"})
+
+	restore = cgroup.MockVersion(cgroup.V1, nil)
+	defer restore()
+	c.Check(s.Backend.SandboxFeatures(), IsNil)
+}
@@ -0,0 +1,56 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package egress
+
+import (
+	"net"
+)
+
+func MockCgroupsOfSnap(f func(snapName string) (map[string][]string, error)) (restore func()) {
+	old := cgroupsOfSnap
+	cgroupsOfSnap = f
+	return func() {
+		cgroupsOfSnap = old
+	}
+}
+
+func MockLookupIP(f func(host string) ([]net.IP, error)) (restore func()) {
+	old := lookupIP
+	lookupIP = f
+	return func() {
+		lookupIP = old
+	}
+}
+
+func MockCgroupID(f func(path string) (uint64, error)) (restore func()) {
+	old := cgroupID
+	cgroupID = f
+	return func() {
+		cgroupID = old
+	}
+}
+
+func MockProcessCgroupIDs(f func(pid int) ([]uint64, error)) (restore func()) {
+	old := processCgroupIDs
+	processCgroupIDs = f
+	return func() {
+		processCgroupIDs = old
+	}
+}
@@ -0,0 +1,209 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package egress
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Destination is a network destination snaps can be allowed to reach.
+type Destination struct {
+	// Host is an IP address, a network in CIDR notation or a host name.
+	// Host names are resolved when the ruleset is derived.
+	Host string
+	// Port is the TCP or UDP port, zero allows all ports.
+	Port int
+}
+
+// String returns the destination in the format parsed by ParseDestination.
+func (d Destination) String() string {
+	if d.Port == 0 {
+		return d.Host
+	}
+	return net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+}
+
+var validHostName = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`).MatchString
+
+// ParseDestination parses a destination in the form "host" or "host:port",
+// where host is an IP address, a network in CIDR notation or a host name.
+// IPv6 addresses with a port are enclosed in brackets, as in
+// "[2001:db8::1]:443".
+func ParseDestination(s string) (Destination, error) {
+	host, port := s, ""
+	if strings.HasPrefix(s, "[") || strings.Count(s, ":") == 1 {
+		var err error
+		host, port, err = net.SplitHostPort(s)
+		if err != nil {
+			return Destination{}, fmt.Errorf("invalid destination %q", s)
+		}
+		if port == "" {
+			return Destination{}, fmt.Errorf("invalid port in destination %q", s)
+		}
+	}
+
+	d := Destination{Host: host}
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return Destination{}, fmt.Errorf("invalid port in destination %q", s)
+		}
+		d.Port = p
+	}
+
+	switch {
+	case strings.Contains(host, "/"):
+		if _, _, err := net.ParseCIDR(host); err != nil {
+			return Destination{}, fmt.Errorf("invalid network in destination %q", s)
+		}
+	case net.ParseIP(host) != nil:
+	case validHostName(host) && len(host) <= 253:
+	default:
+		return Destination{}, fmt.Errorf("invalid host in destination %q", s)
+	}
+	return d, nil
+}
+
+// Specification assists in collecting the network destinations snaps can
+// reach.
+//
+// Unlike the Backend itself (which is stateless and non-persistent) this type
+// holds internal state that is used by the egress backend during the interface
+// setup process.
+type Specification struct {
+	// scope for AddDestinations and AllowAllDestinations
+	securityTags []string
+
+	destinations map[string][]Destination
+	unrestricted map[string]bool
+}
+
+func (spec *Specification) setScope(securityTags []string) (restore func()) {
+	spec.securityTags = securityTags
+	return func() {
+		spec.securityTags = nil
+	}
+}
+
+// AddDestinations restricts the applications and hooks using the interface
+// to the given destinations, and to the ones added by other interfaces.
+func (spec *Specification) AddDestinations(destinations []Destination) {
+	if spec.destinations == nil {
+		spec.destinations = make(map[string][]Destination)
+	}
+	for _, tag := range spec.securityTags {
+		spec.destinations[tag] = append(spec.destinations[tag], destinations...)
+	}
+}
+
+// AllowAllDestinations lifts the restrictions of the applications and hooks
+// using the interface.
+func (spec *Specification) AllowAllDestinations() {
+	if spec.unrestricted == nil {
+		spec.unrestricted = make(map[string]bool)
+	}
+	for _, tag := range spec.securityTags {
+		spec.unrestricted[tag] = true
+	}
+}
+
+// SecurityTags returns the security tags restricted to some destinations.
+func (spec *Specification) SecurityTags() []string {
+	var tags []string
+	for tag := range spec.destinations {
+		if !spec.unrestricted[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// DestinationsForTag returns the destinations the given security tag is
+// restricted to, if it is restricted.
+func (spec *Specification) DestinationsForTag(tag string) (destinations []Destination, restricted bool) {
+	dests, ok := spec.destinations[tag]
+	if !ok || spec.unrestricted[tag] {
+		return nil, false
+	}
+	return append([]Destination(nil), dests...), true
+}
+
+// Implementation of methods required by interfaces.Specification
+
+// AddConnectedPlug records egress-specific side-effects of having a connected plug.
+func (spec *Specification) AddConnectedPlug(iface interfaces.Interface, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	type definer interface {
+		EgressConnectedPlug(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	}
+	if iface, ok := iface.(definer); ok {
+		restore := spec.setScope(plug.SecurityTags())
+		defer restore()
+		return iface.EgressConnectedPlug(spec, plug, slot)
+	}
+	return nil
+}
+
+// AddConnectedSlot records egress-specific side-effects of having a connected slot.
+func (spec *Specification) AddConnectedSlot(iface interfaces.Interface, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	type definer interface {
+		EgressConnectedSlot(spec *Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	}
+	if iface, ok := iface.(definer); ok {
+		restore := spec.setScope(slot.SecurityTags())
+		defer restore()
+		return iface.EgressConnectedSlot(spec, plug, slot)
+	}
+	return nil
+}
+
+// AddPermanentPlug records egress-specific side-effects of having a plug.
+func (spec *Specification) AddPermanentPlug(iface interfaces.Interface, plug *snap.PlugInfo) error {
+	type definer interface {
+		EgressPermanentPlug(spec *Specification, plug *snap.PlugInfo) error
+	}
+	if iface, ok := iface.(definer); ok {
+		restore := spec.setScope(plug.SecurityTags())
+		defer restore()
+		return iface.EgressPermanentPlug(spec, plug)
+	}
+	return nil
+}
+
+// AddPermanentSlot records egress-specific side-effects of having a slot.
+func (spec *Specification) AddPermanentSlot(iface interfaces.Interface, slot *snap.SlotInfo) error {
+	type definer interface {
+		EgressPermanentSlot(spec *Specification, slot *snap.SlotInfo) error
+	}
+	if iface, ok := iface.(definer); ok {
+		restore := spec.setScope(slot.SecurityTags())
+		defer restore()
+		return iface.EgressPermanentSlot(spec, slot)
+	}
+	return nil
+}
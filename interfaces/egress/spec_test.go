@@ -0,0 +1,147 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package egress_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/egress"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type specSuite struct {
+	iface    *ifacetest.TestInterface
+	spec     *egress.Specification
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+}
+
+var _ = Suite(&specSuite{
+	iface: &ifacetest.TestInterface{
+		InterfaceName: "test",
+		EgressConnectedPlugCallback: func(spec *egress.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddDestinations([]egress.Destination{{Host: "192.0.2.1", Port: 443}})
+			return nil
+		},
+		EgressPermanentPlugCallback: func(spec *egress.Specification, plug *snap.PlugInfo) error {
+			spec.AddDestinations([]egress.Destination{{Host: "example.com"}})
+			return nil
+		},
+		EgressConnectedSlotCallback: func(spec *egress.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AllowAllDestinations()
+			return nil
+		},
+		EgressPermanentSlotCallback: func(spec *egress.Specification, slot *snap.SlotInfo) error {
+			spec.AddDestinations(nil)
+			return nil
+		},
+	},
+})
+
+func (s *specSuite) SetUpTest(c *C) {
+	s.spec = &egress.Specification{}
+	const plugYaml = `name: snap1
+version: 1
+plugs:
+  name:
+    interface: test
+apps:
+  app1:
+`
+	s.plugInfo = snaptest.MockInfo(c, plugYaml, nil).Plugs["name"]
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+	const slotYaml = `name: snap2
+version: 1
+slots:
+  name:
+    interface: test
+apps:
+  app1:
+`
+	s.slotInfo = snaptest.MockInfo(c, slotYaml, nil).Slots["name"]
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+}
+
+func (s *specSuite) TestParseDestination(c *C) {
+	for _, t := range []struct {
+		destination string
+		expected    egress.Destination
+	}{
+		{"192.0.2.1", egress.Destination{Host: "192.0.2.1"}},
+		{"192.0.2.1:443", egress.Destination{Host: "192.0.2.1", Port: 443}},
+		{"192.0.2.0/24", egress.Destination{Host: "192.0.2.0/24"}},
+		{"192.0.2.0/24:53", egress.Destination{Host: "192.0.2.0/24", Port: 53}},
+		{"2001:db8::1", egress.Destination{Host: "2001:db8::1"}},
+		{"2001:db8::/32", egress.Destination{Host: "2001:db8::/32"}},
+		{"[2001:db8::1]:443", egress.Destination{Host: "2001:db8::1", Port: 443}},
+		{"telemetry.example.com", egress.Destination{Host: "telemetry.example.com"}},
+		{"telemetry.example.com:8443", egress.Destination{Host: "telemetry.example.com", Port: 8443}},
+	} {
+		d, err := egress.ParseDestination(t.destination)
+		c.Assert(err, IsNil, Commentf(t.destination))
+		c.Check(d, Equals, t.expected)
+		c.Check(d.String(), Equals, t.destination)
+	}
+
+	for _, t := range []struct {
+		destination, err string
+	}{
+		{"", `invalid host in destination ""`},
+		{"example.com:", `invalid port in destination "example.com:"`},
+		{"example.com:http", `invalid port in destination "example.com:http"`},
+		{"example.com:70000", `invalid port in destination "example.com:70000"`},
+		{"192.0.2.0/33", `invalid network in destination "192.0.2.0/33"`},
+		{"-example.com", `invalid host in destination "-example.com"`},
+		{"example..com", `invalid host in destination "example..com"`},
+		{"[2001:db8::1]", `invalid destination "\[2001:db8::1\]"`},
+	} {
+		_, err := egress.ParseDestination(t.destination)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *specSuite) TestSpecification(c *C) {
+	c.Assert(s.spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(s.spec.AddPermanentPlug(s.iface, s.plugInfo), IsNil)
+	c.Assert(s.spec.AddPermanentSlot(s.iface, s.slotInfo), IsNil)
+
+	c.Check(s.spec.SecurityTags(), DeepEquals, []string{"snap.snap1.app1", "snap.snap2.app1"})
+	dests, restricted := s.spec.DestinationsForTag("snap.snap1.app1")
+	c.Check(restricted, Equals, true)
+	c.Check(dests, DeepEquals, []egress.Destination{{Host: "192.0.2.1", Port: 443}, {Host: "example.com"}})
+	// no destination is allowed
+	dests, restricted = s.spec.DestinationsForTag("snap.snap2.app1")
+	c.Check(restricted, Equals, true)
+	c.Check(dests, HasLen, 0)
+	dests, restricted = s.spec.DestinationsForTag("snap.snap1.other")
+	c.Check(restricted, Equals, false)
+	c.Check(dests, IsNil)
+
+	// other interfaces can lift the restrictions
+	c.Assert(s.spec.AddConnectedSlot(s.iface, s.plug, s.slot), IsNil)
+	c.Check(s.spec.SecurityTags(), DeepEquals, []string{"snap.snap1.app1"})
+	_, restricted = s.spec.DestinationsForTag("snap.snap2.app1")
+	c.Check(restricted, Equals, false)
+}
@@ -23,9 +23,11 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/dbus"
+	"github.com/snapcore/snapd/interfaces/egress"
 	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/kmod"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/polkit"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
@@ -83,6 +85,20 @@ type TestInterface struct {
 	KModPermanentPlugCallback func(spec *kmod.Specification, plug *snap.PlugInfo) error
 	KModPermanentSlotCallback func(spec *kmod.Specification, slot *snap.SlotInfo) error
 
+	// Support for interacting with the egress backend.
+
+	EgressConnectedPlugCallback func(spec *egress.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	EgressConnectedSlotCallback func(spec *egress.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	EgressPermanentPlugCallback func(spec *egress.Specification, plug *snap.PlugInfo) error
+	EgressPermanentSlotCallback func(spec *egress.Specification, slot *snap.SlotInfo) error
+
+	// Support for interacting with the polkit backend.
+
+	PolkitConnectedPlugCallback func(spec *polkit.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	PolkitConnectedSlotCallback func(spec *polkit.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	PolkitPermanentPlugCallback func(spec *polkit.Specification, plug *snap.PlugInfo) error
+	PolkitPermanentSlotCallback func(spec *polkit.Specification, slot *snap.SlotInfo) error
+
 	// Support for interacting with the seccomp backend.
 
 	SecCompConnectedPlugCallback func(spec *seccomp.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
@@ -352,6 +368,66 @@ func (t *TestInterface) KModPermanentSlot(spec *kmod.Specification, slot *snap.S
 	return nil
 }
 
+// Support for interacting with the egress backend.
+
+func (t *TestInterface) EgressConnectedPlug(spec *egress.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.EgressConnectedPlugCallback != nil {
+		return t.EgressConnectedPlugCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) EgressConnectedSlot(spec *egress.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.EgressConnectedSlotCallback != nil {
+		return t.EgressConnectedSlotCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) EgressPermanentPlug(spec *egress.Specification, plug *snap.PlugInfo) error {
+	if t.EgressPermanentPlugCallback != nil {
+		return t.EgressPermanentPlugCallback(spec, plug)
+	}
+	return nil
+}
+
+func (t *TestInterface) EgressPermanentSlot(spec *egress.Specification, slot *snap.SlotInfo) error {
+	if t.EgressPermanentSlotCallback != nil {
+		return t.EgressPermanentSlotCallback(spec, slot)
+	}
+	return nil
+}
+
+// Support for interacting with the polkit backend.
+
+func (t *TestInterface) PolkitConnectedPlug(spec *polkit.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.PolkitConnectedPlugCallback != nil {
+		return t.PolkitConnectedPlugCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) PolkitConnectedSlot(spec *polkit.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.PolkitConnectedSlotCallback != nil {
+		return t.PolkitConnectedSlotCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) PolkitPermanentPlug(spec *polkit.Specification, plug *snap.PlugInfo) error {
+	if t.PolkitPermanentPlugCallback != nil {
+		return t.PolkitPermanentPlugCallback(spec, plug)
+	}
+	return nil
+}
+
+func (t *TestInterface) PolkitPermanentSlot(spec *polkit.Specification, slot *snap.SlotInfo) error {
+	if t.PolkitPermanentSlotCallback != nil {
+		return t.PolkitPermanentSlotCallback(spec, slot)
+	}
+	return nil
+}
+
 // Support for interacting with the dbus backend.
 
 func (t *TestInterface) DBusConnectedPlug(spec *dbus.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package landlock implements integration between snappy and the Landlock
+// security module of the kernel, restricting the filesystem accesses of
+// snaps on systems where apparmor is not available.
+//
+// The backend writes a profile for each application and hook of a strictly
+// confined snap in /var/lib/snapd/landlock/profiles. Each profile lists the
+// paths the snap can access beneath, starting with the ones of the default
+// template followed by the ones derived from the file rules of the apparmor
+// snippets of the interfaces. Paths starting with an environment variable
+// are expanded by snap-exec, as are the paths ending with a glob matching
+// entries of a directory, when it applies the profile before executing the
+// application or hook.
+package landlock
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timings"
+)
+
+// Backend is responsible for maintaining landlock profiles for snaps.
+type Backend struct{}
+
+// Initialize does nothing.
+func (b *Backend) Initialize(*interfaces.SecurityBackendOptions) error {
+	return nil
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() interfaces.SecuritySystem {
+	return interfaces.SecurityLandlock
+}
+
+// Setup creates the landlock profiles of the applications and hooks of the
+// given snap. Snaps in devmode and classic snaps not in jailmode are not
+// restricted, neither are snaps on kernels without landlock support.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	snapName := snapInfo.InstanceName()
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return fmt.Errorf("cannot obtain landlock specification for snap %q: %s", snapName, err)
+	}
+
+	var content map[string]osutil.FileState
+	if _, err := landlock_sandbox.Version(); err == nil && !opts.DevMode && (!opts.Classic || opts.JailMode) {
+		content = deriveContent(spec.(*Specification), snapInfo)
+	}
+
+	dir := dirs.SnapLandlockDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for landlock profiles %q: %s", dir, err)
+	}
+	glob := interfaces.SecurityTagGlob(snapName)
+	_, _, err = osutil.EnsureDirState(dir, glob, content)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize landlock profiles for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+// Remove removes the landlock profiles of the given snap.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Remove(snapName string) error {
+	glob := interfaces.SecurityTagGlob(snapName)
+	_, _, err := osutil.EnsureDirState(dirs.SnapLandlockDir, glob, nil)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize landlock profiles for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+func deriveContent(spec *Specification, snapInfo *snap.Info) map[string]osutil.FileState {
+	var tags []string
+	for _, appInfo := range snapInfo.Apps {
+		tags = append(tags, appInfo.SecurityTag())
+	}
+	for _, hookInfo := range snapInfo.Hooks {
+		tags = append(tags, hookInfo.SecurityTag())
+	}
+	content := make(map[string]osutil.FileState, len(tags))
+	for _, tag := range tags {
+		var buffer bytes.Buffer
+		buffer.WriteString("# This file is automatically generated.\n")
+		for _, rule := range defaultTemplate {
+			fmt.Fprintln(&buffer, rule)
+		}
+		for _, rule := range spec.RulesForTag(tag) {
+			fmt.Fprintln(&buffer, rule)
+		}
+		content[tag] = &osutil.MemoryFileState{
+			Content: buffer.Bytes(),
+			Mode:    0644,
+		}
+	}
+	return content
+}
+
+// NewSpecification returns a new landlock specification.
+func (b *Backend) NewSpecification() interfaces.Specification {
+	return &Specification{}
+}
+
+// SandboxFeatures returns the ABI version of landlock supported by the
+// kernel, if any.
+func (b *Backend) SandboxFeatures() []string {
+	abi, err := landlock_sandbox.Version()
+	if err != nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("abi:%d", abi)}
+}
@@ -0,0 +1,139 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+
+	meas *timings.Span
+}
+
+var _ = Suite(&backendSuite{})
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &landlock.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+	s.AddCleanup(landlock_sandbox.MockVersion(1, nil))
+
+	perf := timings.New(nil)
+	s.meas = perf.StartSpan("", "")
+
+	s.Iface.AppArmorPermanentSlotCallback = func(spec *apparmor.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("/etc/samba/** r,\n@{PROC}/@{pid}/stat r,\n@{PROC}/cpuinfo r,\ndeny /etc/shadow r,")
+		return nil
+	}
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	s.BackendSuite.TearDownTest(c)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecurityLandlock)
+}
+
+const sambaProfile = `# This file is automatically generated.
+rx /bin
+rx /etc
+rx /lib
+rx /lib32
+rx /lib64
+rx /libx32
+rx /sbin
+rx /usr
+rx $SNAP
+r /var/lib/snapd/lib
+r /var/lib/snapd/void
+r /proc
+r /sys
+r /run
+rw /dev
+rw /tmp
+rw /var/tmp
+rwx $SNAP_DATA
+rwx $SNAP_COMMON
+rwx $SNAP_USER_DATA
+rwx $SNAP_USER_COMMON
+rw $XDG_RUNTIME_DIR
+r /etc/samba
+r /proc/cpuinfo
+`
+
+func (s *backendSuite) TestInstallingSnapWritesProfiles(c *C) {
+	for _, opts := range []interfaces.ConfinementOptions{{}, {JailMode: true}, {Classic: true, JailMode: true}} {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1WithNmbd, 0)
+		for _, tag := range []string{"snap.samba.smbd", "snap.samba.nmbd"} {
+			c.Check(filepath.Join(dirs.SnapLandlockDir, tag), testutil.FileEquals, sambaProfile)
+		}
+		s.RemoveSnap(c, snapInfo)
+		c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.smbd"), testutil.FileAbsent)
+		c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.nmbd"), testutil.FileAbsent)
+	}
+}
+
+func (s *backendSuite) TestUnrestrictedSnapsHaveNoProfiles(c *C) {
+	for _, opts := range []interfaces.ConfinementOptions{{DevMode: true}, {Classic: true}} {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.smbd"), testutil.FileAbsent)
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestSetupRemovesProfilesWithoutLandlock(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.smbd"), testutil.FilePresent)
+
+	restore := landlock_sandbox.MockVersion(0, landlock_sandbox.ErrUnsupported)
+	defer restore()
+	c.Assert(s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas), IsNil)
+	c.Check(filepath.Join(dirs.SnapLandlockDir, "snap.samba.smbd"), testutil.FileAbsent)
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	restore := landlock_sandbox.MockVersion(3, nil)
+	defer restore()
+	c.Check(s.Backend.SandboxFeatures(), DeepEquals, []string{"abi:3"})
+
+	restore = landlock_sandbox.MockVersion(0, landlock_sandbox.ErrUnsupported)
+	defer restore()
+	c.Check(s.Backend.SandboxFeatures(), IsNil)
+}
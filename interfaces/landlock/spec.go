@@ -0,0 +1,405 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+)
+
+// Specification assists in collecting the filesystem accesses granted by
+// interfaces.
+//
+// Interfaces do not describe their policy for landlock directly, the
+// specification is filled by the apparmor definers of the interfaces and
+// the file rules of the resulting apparmor snippets are translated into
+// landlock rules.
+type Specification struct {
+	apparmor.Specification
+}
+
+// variables of apparmor profiles expanded when deriving rules, the
+// variables of the snap are left for snap-exec to expand from the
+// environment
+var profileVariables = map[string]string{
+	"@{PROC}":               "/proc",
+	"@{HOME}":               "${SNAP_REAL_HOME}",
+	"@{INSTALL_DIR}":        "{/snap,/var/lib/snapd/snap}",
+	"@{SNAP_NAME}":          "${SNAP_NAME}",
+	"@{SNAP_INSTANCE_NAME}": "${SNAP_INSTANCE_NAME}",
+	"@{SNAP_REVISION}":      "${SNAP_REVISION}",
+}
+
+// RulesForTag returns the landlock rules derived from the file rules of
+// the apparmor snippets of the given security tag.
+//
+// Landlock grants access to files or to everything beneath directories, so
+// only the rules of literal paths and of whole directories, like /dir/**,
+// are translated, as well as the rules of everything beneath the entries of
+// a directory matching a glob, like @{HOME}/[^s.]**, which are expanded when
+// the profile is applied. The rules with other globs or with unknown
+// variables are skipped rather than widened to a parent directory. Deny
+// rules cannot be expressed either, the accesses they deny are removed from
+// the rules of the paths they overlap, and the entries matching a glob that
+// overlap a denied path get a rule of their own.
+func (spec *Specification) RulesForTag(tag string) []landlock_sandbox.Rule {
+	access := make(map[string]landlock_sandbox.Access)
+	globs := make(map[string]landlock_sandbox.Access)
+	denied := make(map[string]landlock_sandbox.Access)
+	// like in apparmor profiles, the home interface allows executing
+	// unless an interface asked otherwise
+	homeIx := "ix"
+	if spec.SuppressHomeIx() {
+		homeIx = ""
+	}
+	snippet := strings.Replace(spec.SnippetForTag(tag), "###HOME_IX###", homeIx, -1)
+	for _, line := range strings.Split(snippet, "\n") {
+		pattern, acc, deny, ok := parseFileRule(line)
+		if !ok {
+			continue
+		}
+		for _, path := range expandAlternations(expandVariables(pattern)) {
+			if deny {
+				// denying beneath the directory before the globs
+				// removes too much rather than too little
+				denied[literalPrefix(path)] |= acc
+				continue
+			}
+			if exact, ok := exactPath(path); ok {
+				access[exact] |= acc
+			} else if glob, ok := entryGlob(path); ok {
+				globs[glob] |= acc
+			}
+		}
+	}
+
+	// the entries matching a glob with a rule of their own are left to
+	// that rule when the profile is applied, so it grants what the glob
+	// does too
+	entries := make(map[string]bool)
+	for glob, acc := range globs {
+		dir, pattern := splitGlob(glob)
+		for deniedPath, deniedAcc := range denied {
+			if isBeneath(dir, deniedPath) {
+				acc &^= deniedAcc
+			} else if entry, ok := matchingEntry(deniedPath, dir, pattern); ok {
+				entries[entry] = true
+			}
+		}
+		globs[glob] = acc
+		for path := range access {
+			if entry, ok := matchingEntry(path, dir, pattern); ok && entry == path {
+				entries[entry] = true
+			}
+		}
+	}
+	for glob, acc := range globs {
+		dir, pattern := splitGlob(glob)
+		for entry := range entries {
+			if _, ok := matchingEntry(entry, dir, pattern); ok {
+				access[entry] |= acc
+			}
+		}
+	}
+
+	rules := make([]landlock_sandbox.Rule, 0, len(access)+len(globs))
+	for path, acc := range access {
+		for deniedPath, deniedAcc := range denied {
+			if isBeneath(path, deniedPath) || isBeneath(deniedPath, path) {
+				acc &^= deniedAcc
+			}
+		}
+		// the rules of the entries of globs are kept even when they
+		// grant nothing
+		if acc != 0 || entries[path] {
+			rules = append(rules, landlock_sandbox.Rule{Access: acc, Path: path})
+		}
+	}
+	for glob, acc := range globs {
+		if acc != 0 {
+			rules = append(rules, landlock_sandbox.Rule{Access: acc, Path: glob})
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Path < rules[j].Path })
+	return rules
+}
+
+// parseFileRule parses an apparmor file rule like "owner /path/** rw," and
+// returns its path pattern and the landlock access it grants, or denies.
+func parseFileRule(line string) (pattern string, access landlock_sandbox.Access, deny, ok bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") || !strings.HasSuffix(line, ",") {
+		return "", 0, false, false
+	}
+	fields := strings.Fields(strings.TrimSuffix(line, ","))
+	for len(fields) > 0 {
+		switch fields[0] {
+		case "audit", "allow", "owner", "file":
+			fields = fields[1:]
+			continue
+		case "deny":
+			deny = true
+			fields = fields[1:]
+			continue
+		}
+		break
+	}
+	if len(fields) < 2 {
+		return "", 0, false, false
+	}
+	isPath := func(s string) bool {
+		return strings.HasPrefix(s, "/") || strings.HasPrefix(s, "@{")
+	}
+	var perms string
+	switch {
+	case isPath(fields[0]):
+		pattern, perms = fields[0], fields[1]
+	case isPath(fields[1]):
+		perms, pattern = fields[0], fields[1]
+	default:
+		return "", 0, false, false
+	}
+	for _, perm := range perms {
+		switch perm {
+		case 'r':
+			access |= landlock_sandbox.Read
+		case 'w', 'a':
+			access |= landlock_sandbox.Write
+		case 'x', 'm':
+			access |= landlock_sandbox.Execute
+		case 'k', 'l', 'i', 'u', 'U', 'p', 'P', 'c', 'C':
+			// locking, linking and execution modes
+		default:
+			return "", 0, false, false
+		}
+	}
+	return pattern, access, deny, access != 0
+}
+
+func expandVariables(pattern string) string {
+	for variable, value := range profileVariables {
+		pattern = strings.Replace(pattern, variable, value, -1)
+	}
+	return pattern
+}
+
+// expandAlternations expands the {a,b} alternations of a pattern, leaving
+// the ${VAR} references of the snap variables alone.
+func expandAlternations(pattern string) []string {
+	start := -1
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '{' && (i == 0 || (pattern[i-1] != '$' && pattern[i-1] != '@')) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return []string{pattern}
+	}
+	// find the matching brace and the top level alternatives
+	depth := 0
+	var alternatives []string
+	last := start + 1
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			depth--
+			if depth == 0 {
+				alternatives = append(alternatives, pattern[last:i])
+				var expanded []string
+				for _, alt := range alternatives {
+					expanded = append(expanded, expandAlternations(pattern[:start]+alt+pattern[i+1:])...)
+				}
+				return expanded
+			}
+		}
+	}
+	// unbalanced braces, the pattern is cut at the brace
+	return []string{pattern}
+}
+
+// globIndex returns the index of the first glob or unknown variable of the
+// pattern, or -1 if it has none.
+func globIndex(pattern string) int {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '$' && strings.HasPrefix(pattern[i:], "${") {
+			// skip over the snap variables
+			if j := strings.IndexRune(pattern[i:], '}'); j > 0 {
+				i += j
+				continue
+			}
+		}
+		if strings.ContainsRune("*?[{@", rune(pattern[i])) {
+			return i
+		}
+	}
+	return -1
+}
+
+// exactPath returns the path of a landlock rule granting access to exactly
+// the files matched by the pattern, when there is one: the pattern itself
+// when it is a literal path, or the directory of a pattern matching
+// everything beneath it. A directory alone, like /dir/, cannot be granted
+// without what is beneath it.
+func exactPath(pattern string) (path string, ok bool) {
+	path = pattern
+	if strings.HasSuffix(pattern, "/**") {
+		path = strings.TrimSuffix(pattern, "**")
+	} else if strings.HasSuffix(pattern, "/") {
+		return "", false
+	}
+	if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "${") {
+		return "", false
+	}
+	if globIndex(path) >= 0 {
+		return "", false
+	}
+	if path != "/" {
+		path = strings.TrimRight(path, "/")
+	}
+	return path, true
+}
+
+// entryGlob returns the path of a landlock rule granting access beneath the
+// entries of a directory matched by a glob, for a pattern matching exactly
+// everything beneath those, like /dir/[^.]**. The path is the directory
+// followed by a glob as understood by filepath.Match.
+func entryGlob(pattern string) (glob string, ok bool) {
+	if !strings.HasSuffix(pattern, "**") || strings.HasSuffix(pattern, "/**") {
+		return "", false
+	}
+	i := globIndex(pattern)
+	dirEnd := strings.LastIndex(pattern[:i], "/")
+	if dirEnd <= 0 {
+		return "", false
+	}
+	dir := pattern[:dirEnd]
+	if !strings.HasPrefix(dir, "/") && !strings.HasPrefix(dir, "${") {
+		return "", false
+	}
+	// the first * of ** matches the rest of the entry name, the other one
+	// what is beneath the entry
+	entryPattern, ok := namePattern(strings.TrimSuffix(pattern[dirEnd+1:], "*"))
+	if !ok {
+		return "", false
+	}
+	if _, err := filepath.Match(entryPattern, ""); err != nil {
+		return "", false
+	}
+	return dir + "/" + entryPattern, true
+}
+
+// namePattern returns the glob matching the names of entries that the
+// given apparmor glob matches, for a glob without alternations, variables
+// nor path separators outside of character classes. The path separators
+// of character classes, like in [^/], are dropped as names have none.
+func namePattern(glob string) (pattern string, ok bool) {
+	var buf []byte
+	class := -1
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.IndexByte("{}@$\\", c) >= 0:
+			return "", false
+		case class < 0 && c == '/':
+			return "", false
+		case class < 0 && c == '[':
+			class = len(buf)
+		case class >= 0 && c == '/':
+			continue
+		case class >= 0 && c == ']':
+			switch string(buf[class:]) {
+			case "[":
+				// only path separators
+				return "", false
+			case "[^":
+				// anything but path separators
+				buf = append(buf[:class], '?')
+				class = -1
+				continue
+			}
+			class = -1
+		case c == '*' && i > 0 && glob[i-1] == '*':
+			return "", false
+		}
+		buf = append(buf, c)
+	}
+	return string(buf), class < 0
+}
+
+// splitGlob splits the path of a rule with a glob into the directory and
+// the glob matching its entries.
+func splitGlob(glob string) (dir, pattern string) {
+	i := strings.LastIndex(glob, "/")
+	if i < 0 {
+		return "", glob
+	}
+	return glob[:i], glob[i+1:]
+}
+
+// matchingEntry returns the entry of the directory the path is beneath,
+// if the entry matches the glob pattern.
+func matchingEntry(path, dir, pattern string) (entry string, ok bool) {
+	if !strings.HasPrefix(path, dir+"/") {
+		return "", false
+	}
+	name := strings.SplitN(path[len(dir)+1:], "/", 2)[0]
+	if matched, _ := filepath.Match(pattern, name); !matched || name == "" {
+		return "", false
+	}
+	return dir + "/" + name, true
+}
+
+// literalPrefix returns the pattern when it has no globs or unknown
+// variables, or the directory before the first of them.
+func literalPrefix(pattern string) string {
+	end := len(pattern)
+	if i := globIndex(pattern); i >= 0 {
+		end = strings.LastIndex(pattern[:i], "/")
+	}
+	if end < 0 {
+		return ""
+	}
+	path := strings.TrimRight(pattern[:end], "/")
+	if path == "" && strings.HasPrefix(pattern, "/") {
+		return "/"
+	}
+	return path
+}
+
+// isBeneath returns whether the path is the given directory or beneath it.
+// Everything is beneath the empty directory.
+func isBeneath(path, dir string) bool {
+	if dir == "" || dir == "/" || path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, dir+"/")
+}
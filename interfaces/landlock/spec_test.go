@@ -0,0 +1,201 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type specSuite struct {
+	iface *ifacetest.TestInterface
+	spec  *landlock.Specification
+	plug  *interfaces.ConnectedPlug
+	slot  *interfaces.ConnectedSlot
+}
+
+var _ = Suite(&specSuite{
+	iface: &ifacetest.TestInterface{InterfaceName: "test"},
+})
+
+func (s *specSuite) SetUpTest(c *C) {
+	s.spec = &landlock.Specification{}
+	const plugYaml = `name: snap1
+version: 1
+plugs:
+  name:
+    interface: test
+apps:
+  app1:
+`
+	plugInfo := snaptest.MockInfo(c, plugYaml, nil).Plugs["name"]
+	s.plug = interfaces.NewConnectedPlug(plugInfo, nil, nil)
+	const slotYaml = `name: snap2
+version: 1
+slots:
+  name:
+    interface: test
+apps:
+  app2:
+`
+	slotInfo := snaptest.MockInfo(c, slotYaml, nil).Slots["name"]
+	s.slot = interfaces.NewConnectedSlot(slotInfo, nil, nil)
+}
+
+func (s *specSuite) TestRulesForTag(c *C) {
+	s.iface.AppArmorConnectedPlugCallback = func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+		spec.AddSnippet(`
+# Description: a comment /etc/comment r,
+/dev/ttyUSB[0-9]* rw,
+/dev/shm/foo.* rw,
+owner @{HOME}/.config/foo/** rwk,
+audit deny /etc/shadow r,
+/{,usr/}bin/foo ixr,
+@{PROC}/@{pid}/stat r,
+@{PROC}/cpuinfo r,
+/var/snap/@{SNAP_INSTANCE_NAME}/common/ r,
+/var/snap/@{SNAP_INSTANCE_NAME}/common/** r,
+/run/foo.sock l,
+rw /srv/data,
+/etc/bar r,
+/etc/bar w,
+/etc/baz/** rw,
+deny /etc/baz/*.conf w,
+capability net_admin,
+dbus (send)
+    path=/org/freedesktop/foo
+    bus=system,
+/usr/share/** mr,
+@{INSTALL_DIR}/@{SNAP_NAME}/** r,
+/ r,
+/**/ r,`)
+		return nil
+	}
+	c.Assert(s.spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+
+	c.Check(s.spec.RulesForTag("snap.snap1.app1"), DeepEquals, []landlock_sandbox.Rule{
+		{Access: landlock_sandbox.Read | landlock_sandbox.Write, Path: "${SNAP_REAL_HOME}/.config/foo"},
+		{Access: landlock_sandbox.Read | landlock_sandbox.Execute, Path: "/bin/foo"},
+		{Access: landlock_sandbox.Read | landlock_sandbox.Write, Path: "/etc/bar"},
+		// the denied writing is removed
+		{Access: landlock_sandbox.Read, Path: "/etc/baz"},
+		{Access: landlock_sandbox.Read, Path: "/proc/cpuinfo"},
+		{Access: landlock_sandbox.Read, Path: "/snap/${SNAP_NAME}"},
+		{Access: landlock_sandbox.Read | landlock_sandbox.Write, Path: "/srv/data"},
+		{Access: landlock_sandbox.Read | landlock_sandbox.Execute, Path: "/usr/bin/foo"},
+		{Access: landlock_sandbox.Read | landlock_sandbox.Execute, Path: "/usr/share"},
+		{Access: landlock_sandbox.Read, Path: "/var/lib/snapd/snap/${SNAP_NAME}"},
+		{Access: landlock_sandbox.Read, Path: "/var/snap/${SNAP_INSTANCE_NAME}/common"},
+	})
+	c.Check(s.spec.RulesForTag("snap.snap2.app2"), HasLen, 0)
+}
+
+func (s *specSuite) TestRulesForTagDenyOverlapsParent(c *C) {
+	s.iface.AppArmorConnectedPlugCallback = func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+		spec.AddSnippet(`
+/** r,
+/etc/foo rw,
+deny /etc/shadow r,`)
+		return nil
+	}
+	c.Assert(s.spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+
+	// reading everything would allow reading the denied path
+	c.Check(s.spec.RulesForTag("snap.snap1.app1"), DeepEquals, []landlock_sandbox.Rule{
+		{Access: landlock_sandbox.Read | landlock_sandbox.Write, Path: "/etc/foo"},
+	})
+}
+
+func (s *specSuite) TestRulesForTagEntryGlobs(c *C) {
+	s.iface.AppArmorConnectedPlugCallback = func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+		spec.AddSnippet(`
+/srv/[^.]** rw,
+/srv/data r,
+deny /srv/secret/** r,
+deny /srv/private/{,**} rw,
+/opt/[a-z]** rw,
+deny /opt/** w,
+/mnt/[/]** r,
+/media/*/[^.]** r,
+/var/**/foo** r,`)
+		return nil
+	}
+	c.Assert(s.spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+
+	rw := landlock_sandbox.Read | landlock_sandbox.Write
+	c.Check(s.spec.RulesForTag("snap.snap1.app1"), DeepEquals, []landlock_sandbox.Rule{
+		// writing to the directory is denied
+		{Access: landlock_sandbox.Read, Path: "/opt/[a-z]*"},
+		{Access: rw, Path: "/srv/[^.]*"},
+		// the entries left to their own rules
+		{Access: rw, Path: "/srv/data"},
+		{Access: 0, Path: "/srv/private"},
+		{Access: landlock_sandbox.Write, Path: "/srv/secret"},
+	})
+}
+
+func (s *specSuite) TestRulesForTagHome(c *C) {
+	const plugYaml = `name: snap1
+version: 1
+apps:
+  app1:
+    plugs: [home]
+`
+	const slotYaml = `name: core
+version: 1
+type: os
+slots:
+  home:
+`
+	var home interfaces.Interface
+	for _, iface := range builtin.Interfaces() {
+		if iface.Name() == "home" {
+			home = iface
+		}
+	}
+	c.Assert(home, NotNil)
+	plug := interfaces.NewConnectedPlug(snaptest.MockInfo(c, plugYaml, nil).Plugs["home"], nil, nil)
+	slot := interfaces.NewConnectedSlot(snaptest.MockInfo(c, slotYaml, nil).Slots["home"], nil, nil)
+	c.Assert(s.spec.AddConnectedPlug(home, plug, slot), IsNil)
+
+	rwx := landlock_sandbox.Read | landlock_sandbox.Write | landlock_sandbox.Execute
+	c.Check(s.spec.RulesForTag("snap.snap1.app1"), DeepEquals, []landlock_sandbox.Rule{
+		// the entries of the home directory but the hidden ones and the
+		// data of the snaps
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/[^s.]*"},
+		// writing to bin is denied
+		{Access: landlock_sandbox.Read | landlock_sandbox.Execute, Path: "${SNAP_REAL_HOME}/bin"},
+		// the files not caught by the globs
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/s"},
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/s[^n]*"},
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/sn"},
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/sn[^a]*"},
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/sna"},
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/sna[^p]*"},
+		{Access: rwx, Path: "${SNAP_REAL_HOME}/snap?*"},
+	})
+}
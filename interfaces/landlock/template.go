@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock
+
+import (
+	landlock_sandbox "github.com/snapcore/snapd/sandbox/landlock"
+)
+
+const (
+	rx  = landlock_sandbox.Read | landlock_sandbox.Execute
+	rw  = landlock_sandbox.Read | landlock_sandbox.Write
+	r   = landlock_sandbox.Read
+	rwx = rw | landlock_sandbox.Execute
+)
+
+// defaultTemplate are the filesystem accesses granted to all strictly
+// confined snaps, the counterpart of the file rules of the default apparmor
+// template.
+//
+// Landlock cannot grant less beneath a directory than on the directory
+// itself so the rules are broader than the apparmor ones, reading most of
+// the system is allowed while writing is limited to the data directories
+// of the snap and to scratch locations.
+var defaultTemplate = []landlock_sandbox.Rule{
+	// the base snap and the snap itself
+	{Access: rx, Path: "/bin"},
+	{Access: rx, Path: "/etc"},
+	{Access: rx, Path: "/lib"},
+	{Access: rx, Path: "/lib32"},
+	{Access: rx, Path: "/lib64"},
+	{Access: rx, Path: "/libx32"},
+	{Access: rx, Path: "/sbin"},
+	{Access: rx, Path: "/usr"},
+	{Access: rx, Path: "$SNAP"},
+	// the graphics libraries of the host, mapped rather than executed
+	{Access: r, Path: "/var/lib/snapd/lib"},
+	// the working directory of snap-confine when the current one is gone
+	{Access: r, Path: "/var/lib/snapd/void"},
+
+	// kernel interfaces and runtime state
+	{Access: r, Path: "/proc"},
+	{Access: r, Path: "/sys"},
+	{Access: r, Path: "/run"},
+
+	// devices and scratch locations
+	{Access: rw, Path: "/dev"},
+	{Access: rw, Path: "/tmp"},
+	{Access: rw, Path: "/var/tmp"},
+
+	// the data of the snap
+	{Access: rwx, Path: "$SNAP_DATA"},
+	{Access: rwx, Path: "$SNAP_COMMON"},
+	{Access: rwx, Path: "$SNAP_USER_DATA"},
+	{Access: rwx, Path: "$SNAP_USER_COMMON"},
+	{Access: rw, Path: "$XDG_RUNTIME_DIR"},
+}
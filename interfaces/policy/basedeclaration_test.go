@@ -597,6 +597,7 @@ var (
 		"core-support":            {"core"},
 		"cups":                    {"app"},
 		"cups-control":            {"app", "core"},
+		"custom-device":           {"gadget"},
 		"dbus":                    {"app"},
 		"docker-support":          {"core"},
 		"desktop-launch":          {"core"},
@@ -717,6 +718,7 @@ func (s *baseDeclSuite) TestPlugInstallation(c *C) {
 		"multipass-support":     true,
 		"packagekit-control":    true,
 		"personal-files":        true,
+		"polkit":                true,
 		"snapd-control":         true,
 		"system-files":          true,
 		"tee":                   true,
@@ -762,6 +764,7 @@ func (s *baseDeclSuite) TestConnection(c *C) {
 	noconnect := map[string]bool{
 		"content":          true,
 		"cups":             true,
+		"custom-device":    true,
 		"docker":           true,
 		"fwupd":            true,
 		"location-control": true,
@@ -952,6 +955,7 @@ func (s *baseDeclSuite) TestSanity(c *C) {
 		"multipass-support":     true,
 		"packagekit-control":    true,
 		"personal-files":        true,
+		"polkit":                true,
 		"snapd-control":         true,
 		"system-files":          true,
 		"tee":                   true,
@@ -1037,6 +1041,37 @@ plugs:
 	c.Check(err, NotNil)
 }
 
+func (s *baseDeclSuite) TestConnectionCustomDevice(c *C) {
+	// we let connect explicitly as long as the custom device matches
+	const gadgetYaml = `name: gadget
+version: 0
+type: gadget
+slots:
+  my-board:
+    interface: custom-device
+    custom-device: my-board
+`
+	cand := s.connectCand(c, "my-board", gadgetYaml, `
+name: plug-snap
+version: 0
+plugs:
+  my-board:
+    interface: custom-device
+    custom-device: my-board
+`)
+	c.Check(cand.Check(), IsNil)
+
+	cand = s.connectCand(c, "my-board", gadgetYaml, `
+name: plug-snap
+version: 0
+plugs:
+  my-board:
+    interface: custom-device
+    custom-device: other-board
+`)
+	c.Check(cand.Check(), NotNil)
+}
+
 func (s *baseDeclSuite) TestComposeBaseDeclaration(c *C) {
 	decl, err := policy.ComposeBaseDeclaration(nil)
 	c.Assert(err, IsNil)
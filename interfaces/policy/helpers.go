@@ -124,6 +124,16 @@ func checkDeviceScope(c *asserts.DeviceScopeConstraint, model *asserts.Model, st
 	return nil
 }
 
+// checkAttributes checks the plug or slot attributes against the
+// constraints, telling which side mismatched in the error.
+func checkAttributes(which string, constraints *asserts.AttributeConstraints, attrs asserts.Attrer, helper asserts.AttrMatchContext) error {
+	err := constraints.Check(attrs, helper)
+	if err != nil && constraints != asserts.NeverMatchAttributes {
+		return fmt.Errorf("%s %v", which, err)
+	}
+	return err
+}
+
 func checkNameConstraints(c *asserts.NameConstraints, iface, which, name string) error {
 	if c == nil {
 		return nil
@@ -142,19 +152,19 @@ func checkPlugConnectionConstraints1(connc *ConnectCandidate, constraints *asser
 		return err
 	}
 
-	if err := constraints.PlugAttributes.Check(connc.Plug, connc); err != nil {
+	if err := checkAttributes("plug", constraints.PlugAttributes, connc.Plug, connc); err != nil {
 		return err
 	}
-	if err := constraints.SlotAttributes.Check(connc.Slot, connc); err != nil {
+	if err := checkAttributes("slot", constraints.SlotAttributes, connc.Slot, connc); err != nil {
 		return err
 	}
 	if err := checkSnapType(connc.Slot.Snap(), constraints.SlotSnapTypes); err != nil {
-		return err
+		return fmt.Errorf("slot %v", err)
 	}
-	if err := checkID("snap id", connc.slotSnapID(), constraints.SlotSnapIDs, nil); err != nil {
+	if err := checkID("slot snap id", connc.slotSnapID(), constraints.SlotSnapIDs, nil); err != nil {
 		return err
 	}
-	err := checkID("publisher id", connc.slotPublisherID(), constraints.SlotPublisherIDs, map[string]string{
+	err := checkID("slot publisher id", connc.slotPublisherID(), constraints.SlotPublisherIDs, map[string]string{
 		"$PLUG_PUBLISHER_ID": connc.plugPublisherID(),
 	})
 	if err != nil {
@@ -169,19 +179,22 @@ func checkPlugConnectionConstraints1(connc *ConnectCandidate, constraints *asser
 	return nil
 }
 
-func checkPlugConnectionAltConstraints(connc *ConnectCandidate, altConstraints []*asserts.PlugConnectionConstraints) (*asserts.PlugConnectionConstraints, error) {
+// checkPlugConnectionAltConstraints returns the index of the first
+// alternative matching the connection, or -1 and the mismatch of the first
+// alternative.
+func checkPlugConnectionAltConstraints(connc *ConnectCandidate, altConstraints []*asserts.PlugConnectionConstraints) (int, error) {
 	var firstErr error
 	// OR of constraints
-	for _, constraints := range altConstraints {
+	for i, constraints := range altConstraints {
 		err := checkPlugConnectionConstraints1(connc, constraints)
 		if err == nil {
-			return constraints, nil
+			return i, nil
 		}
 		if firstErr == nil {
 			firstErr = err
 		}
 	}
-	return nil, firstErr
+	return -1, firstErr
 }
 
 func checkSlotConnectionConstraints1(connc *ConnectCandidate, constraints *asserts.SlotConnectionConstraints) error {
@@ -192,19 +205,19 @@ func checkSlotConnectionConstraints1(connc *ConnectCandidate, constraints *asser
 		return err
 	}
 
-	if err := constraints.PlugAttributes.Check(connc.Plug, connc); err != nil {
+	if err := checkAttributes("plug", constraints.PlugAttributes, connc.Plug, connc); err != nil {
 		return err
 	}
-	if err := constraints.SlotAttributes.Check(connc.Slot, connc); err != nil {
+	if err := checkAttributes("slot", constraints.SlotAttributes, connc.Slot, connc); err != nil {
 		return err
 	}
 	if err := checkSnapType(connc.Plug.Snap(), constraints.PlugSnapTypes); err != nil {
-		return err
+		return fmt.Errorf("plug %v", err)
 	}
-	if err := checkID("snap id", connc.plugSnapID(), constraints.PlugSnapIDs, nil); err != nil {
+	if err := checkID("plug snap id", connc.plugSnapID(), constraints.PlugSnapIDs, nil); err != nil {
 		return err
 	}
-	err := checkID("publisher id", connc.plugPublisherID(), constraints.PlugPublisherIDs, map[string]string{
+	err := checkID("plug publisher id", connc.plugPublisherID(), constraints.PlugPublisherIDs, map[string]string{
 		"$SLOT_PUBLISHER_ID": connc.slotPublisherID(),
 	})
 	if err != nil {
@@ -219,24 +232,27 @@ func checkSlotConnectionConstraints1(connc *ConnectCandidate, constraints *asser
 	return nil
 }
 
-func checkSlotConnectionAltConstraints(connc *ConnectCandidate, altConstraints []*asserts.SlotConnectionConstraints) (*asserts.SlotConnectionConstraints, error) {
+// checkSlotConnectionAltConstraints returns the index of the first
+// alternative matching the connection, or -1 and the mismatch of the first
+// alternative.
+func checkSlotConnectionAltConstraints(connc *ConnectCandidate, altConstraints []*asserts.SlotConnectionConstraints) (int, error) {
 	var firstErr error
 	// OR of constraints
-	for _, constraints := range altConstraints {
+	for i, constraints := range altConstraints {
 		err := checkSlotConnectionConstraints1(connc, constraints)
 		if err == nil {
-			return constraints, nil
+			return i, nil
 		}
 		if firstErr == nil {
 			firstErr = err
 		}
 	}
-	return nil, firstErr
+	return -1, firstErr
 }
 
 func checkSnapTypeSlotInstallationConstraints1(ic *InstallCandidateMinimalCheck, slot *snap.SlotInfo, constraints *asserts.SlotInstallationConstraints) error {
 	if err := checkSnapType(slot.Snap, constraints.SlotSnapTypes); err != nil {
-		return err
+		return fmt.Errorf("slot %v", err)
 	}
 	if err := checkOnClassic(constraints.OnClassic); err != nil {
 		return err
@@ -270,11 +286,11 @@ func checkSlotInstallationConstraints1(ic *InstallCandidate, slot *snap.SlotInfo
 	}
 
 	// TODO: allow evaluated attr constraints here too?
-	if err := constraints.SlotAttributes.Check(slot, nil); err != nil {
+	if err := checkAttributes("slot", constraints.SlotAttributes, slot, nil); err != nil {
 		return err
 	}
 	if err := checkSnapType(slot.Snap, constraints.SlotSnapTypes); err != nil {
-		return err
+		return fmt.Errorf("slot %v", err)
 	}
 	if err := checkOnClassic(constraints.OnClassic); err != nil {
 		return err
@@ -285,19 +301,22 @@ func checkSlotInstallationConstraints1(ic *InstallCandidate, slot *snap.SlotInfo
 	return nil
 }
 
-func checkSlotInstallationAltConstraints(ic *InstallCandidate, slot *snap.SlotInfo, altConstraints []*asserts.SlotInstallationConstraints) error {
+// checkSlotInstallationAltConstraints returns the index of the first
+// alternative matching the slot, or -1 and the mismatch of the first
+// alternative.
+func checkSlotInstallationAltConstraints(ic *InstallCandidate, slot *snap.SlotInfo, altConstraints []*asserts.SlotInstallationConstraints) (int, error) {
 	var firstErr error
 	// OR of constraints
-	for _, constraints := range altConstraints {
+	for i, constraints := range altConstraints {
 		err := checkSlotInstallationConstraints1(ic, slot, constraints)
 		if err == nil {
-			return nil
+			return i, nil
 		}
 		if firstErr == nil {
 			firstErr = err
 		}
 	}
-	return firstErr
+	return -1, firstErr
 }
 
 func checkPlugInstallationConstraints1(ic *InstallCandidate, plug *snap.PlugInfo, constraints *asserts.PlugInstallationConstraints) error {
@@ -306,11 +325,11 @@ func checkPlugInstallationConstraints1(ic *InstallCandidate, plug *snap.PlugInfo
 	}
 
 	// TODO: allow evaluated attr constraints here too?
-	if err := constraints.PlugAttributes.Check(plug, nil); err != nil {
+	if err := checkAttributes("plug", constraints.PlugAttributes, plug, nil); err != nil {
 		return err
 	}
 	if err := checkSnapType(plug.Snap, constraints.PlugSnapTypes); err != nil {
-		return err
+		return fmt.Errorf("plug %v", err)
 	}
 	if err := checkOnClassic(constraints.OnClassic); err != nil {
 		return err
@@ -321,19 +340,22 @@ func checkPlugInstallationConstraints1(ic *InstallCandidate, plug *snap.PlugInfo
 	return nil
 }
 
-func checkPlugInstallationAltConstraints(ic *InstallCandidate, plug *snap.PlugInfo, altConstraints []*asserts.PlugInstallationConstraints) error {
+// checkPlugInstallationAltConstraints returns the index of the first
+// alternative matching the plug, or -1 and the mismatch of the first
+// alternative.
+func checkPlugInstallationAltConstraints(ic *InstallCandidate, plug *snap.PlugInfo, altConstraints []*asserts.PlugInstallationConstraints) (int, error) {
 	var firstErr error
 	// OR of constraints
-	for _, constraints := range altConstraints {
+	for i, constraints := range altConstraints {
 		err := checkPlugInstallationConstraints1(ic, plug, constraints)
 		if err == nil {
-			return nil
+			return i, nil
 		}
 		if firstErr == nil {
 			firstErr = err
 		}
 	}
-	return firstErr
+	return -1, firstErr
 }
 
 // sideArity carries relevant arity constraints for successful
@@ -41,58 +41,144 @@ type InstallCandidate struct {
 	Store *asserts.Store
 }
 
-func (ic *InstallCandidate) checkSlotRule(slot *snap.SlotInfo, rule *asserts.SlotRule, snapRule bool) error {
-	context := ""
-	if snapRule {
-		context = fmt.Sprintf(" for %q snap", ic.SnapDeclaration.SnapName())
-	}
-	if checkSlotInstallationAltConstraints(ic, slot, rule.DenyInstallation) == nil {
-		return fmt.Errorf("installation denied by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
+// Decision describes the rule of the declarations deciding whether an
+// installation, connection or auto-connection is allowed, to explain the
+// outcome of the policy checks.
+type Decision struct {
+	// Allowed is whether the installation or connection is allowed.
+	Allowed bool
+	// Declaration is "base-declaration" or "snap-declaration" for the
+	// declaration the deciding rule comes from, it is empty if neither
+	// has a rule for the interface and everything is allowed.
+	Declaration string
+	// Snap is the name of the snap of the snap-declaration.
+	Snap string
+	// Side is whether the deciding rule is a "plug" or a "slot" rule.
+	Side string
+	// Constraint is the deciding constraint of the rule, either the
+	// deny-* one if it matched or else the allow-* one.
+	Constraint string
+	// Alternative is the index of the alternative of the constraint
+	// that matched, or -1 if none did.
+	Alternative int
+	// Reason is why the allow-* constraint did not match, as reported
+	// for its first alternative.
+	Reason string
+}
+
+func (d *Decision) denied() bool {
+	return !d.Allowed && d.Alternative >= 0
+}
+
+func newDecision(side, kind string, snapDecl *asserts.SnapDeclaration) *Decision {
+	d := &Decision{
+		Declaration: "base-declaration",
+		Side:        side,
+		Constraint:  "deny-" + kind,
 	}
-	if checkSlotInstallationAltConstraints(ic, slot, rule.AllowInstallation) != nil {
-		return fmt.Errorf("installation not allowed by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
+	if snapDecl != nil {
+		d.Declaration = "snap-declaration"
+		d.Snap = snapDecl.SnapName()
 	}
-	return nil
+	return d
 }
 
-func (ic *InstallCandidate) checkPlugRule(plug *snap.PlugInfo, rule *asserts.PlugRule, snapRule bool) error {
-	context := ""
-	if snapRule {
-		context = fmt.Sprintf(" for %q snap", ic.SnapDeclaration.SnapName())
+// decide completes the decision given the outcome of checking the deny
+// and the allow constraints of the rule.
+func (d *Decision) decide(kind string, denyIdx int, allowIdx int, allowErr error) {
+	if denyIdx >= 0 {
+		d.Alternative = denyIdx
+		return
 	}
-	if checkPlugInstallationAltConstraints(ic, plug, rule.DenyInstallation) == nil {
-		return fmt.Errorf("installation denied by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
+	d.Constraint = "allow-" + kind
+	d.Alternative = allowIdx
+	if allowIdx >= 0 {
+		d.Allowed = true
+	} else if allowErr != nil {
+		d.Reason = allowErr.Error()
 	}
-	if checkPlugInstallationAltConstraints(ic, plug, rule.AllowInstallation) != nil {
-		return fmt.Errorf("installation not allowed by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
+}
+
+func (ic *InstallCandidate) slotRuleDecision(slot *snap.SlotInfo, rule *asserts.SlotRule, snapDecl *asserts.SnapDeclaration) *Decision {
+	d := newDecision("slot", "installation", snapDecl)
+	denyIdx, _ := checkSlotInstallationAltConstraints(ic, slot, rule.DenyInstallation)
+	allowIdx, allowErr := -1, error(nil)
+	if denyIdx < 0 {
+		allowIdx, allowErr = checkSlotInstallationAltConstraints(ic, slot, rule.AllowInstallation)
 	}
-	return nil
+	d.decide("installation", denyIdx, allowIdx, allowErr)
+	return d
 }
 
-func (ic *InstallCandidate) checkSlot(slot *snap.SlotInfo) error {
+func (ic *InstallCandidate) plugRuleDecision(plug *snap.PlugInfo, rule *asserts.PlugRule, snapDecl *asserts.SnapDeclaration) *Decision {
+	d := newDecision("plug", "installation", snapDecl)
+	denyIdx, _ := checkPlugInstallationAltConstraints(ic, plug, rule.DenyInstallation)
+	allowIdx, allowErr := -1, error(nil)
+	if denyIdx < 0 {
+		allowIdx, allowErr = checkPlugInstallationAltConstraints(ic, plug, rule.AllowInstallation)
+	}
+	d.decide("installation", denyIdx, allowIdx, allowErr)
+	return d
+}
+
+// ExplainSlot returns the decision about the installation of the given
+// slot of the snap.
+func (ic *InstallCandidate) ExplainSlot(slot *snap.SlotInfo) *Decision {
 	iface := slot.Interface
 	if snapDecl := ic.SnapDeclaration; snapDecl != nil {
 		if rule := snapDecl.SlotRule(iface); rule != nil {
-			return ic.checkSlotRule(slot, rule, true)
+			return ic.slotRuleDecision(slot, rule, snapDecl)
 		}
 	}
 	if rule := ic.BaseDeclaration.SlotRule(iface); rule != nil {
-		return ic.checkSlotRule(slot, rule, false)
+		return ic.slotRuleDecision(slot, rule, nil)
 	}
-	return nil
+	return &Decision{Allowed: true, Alternative: -1}
 }
 
-func (ic *InstallCandidate) checkPlug(plug *snap.PlugInfo) error {
+// ExplainPlug returns the decision about the installation of the given
+// plug of the snap.
+func (ic *InstallCandidate) ExplainPlug(plug *snap.PlugInfo) *Decision {
 	iface := plug.Interface
 	if snapDecl := ic.SnapDeclaration; snapDecl != nil {
 		if rule := snapDecl.PlugRule(iface); rule != nil {
-			return ic.checkPlugRule(plug, rule, true)
+			return ic.plugRuleDecision(plug, rule, snapDecl)
 		}
 	}
 	if rule := ic.BaseDeclaration.PlugRule(iface); rule != nil {
-		return ic.checkPlugRule(plug, rule, false)
+		return ic.plugRuleDecision(plug, rule, nil)
 	}
-	return nil
+	return &Decision{Allowed: true, Alternative: -1}
+}
+
+func (ic *InstallCandidate) checkSlot(slot *snap.SlotInfo) error {
+	d := ic.ExplainSlot(slot)
+	if d.Allowed {
+		return nil
+	}
+	context := ""
+	if d.Snap != "" {
+		context = fmt.Sprintf(" for %q snap", d.Snap)
+	}
+	if d.denied() {
+		return fmt.Errorf("installation denied by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
+	}
+	return fmt.Errorf("installation not allowed by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
+}
+
+func (ic *InstallCandidate) checkPlug(plug *snap.PlugInfo) error {
+	d := ic.ExplainPlug(plug)
+	if d.Allowed {
+		return nil
+	}
+	context := ""
+	if d.Snap != "" {
+		context = fmt.Sprintf(" for %q snap", d.Snap)
+	}
+	if d.denied() {
+		return fmt.Errorf("installation denied by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
+	}
+	return fmt.Errorf("installation not allowed by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
 }
 
 // Check checks whether the installation is allowed.
@@ -176,79 +262,97 @@ func (connc *ConnectCandidate) slotPublisherID() string {
 	return "" // never a valid publisher-id
 }
 
-func (connc *ConnectCandidate) checkPlugRule(kind string, rule *asserts.PlugRule, snapRule bool) (interfaces.SideArity, error) {
-	context := ""
-	if snapRule {
-		context = fmt.Sprintf(" for %q snap", connc.PlugSnapDeclaration.SnapName())
-	}
+func (connc *ConnectCandidate) plugRuleDecision(kind string, rule *asserts.PlugRule, snapDecl *asserts.SnapDeclaration) (*Decision, interfaces.SideArity) {
 	denyConst := rule.DenyConnection
 	allowConst := rule.AllowConnection
 	if kind == "auto-connection" {
 		denyConst = rule.DenyAutoConnection
 		allowConst = rule.AllowAutoConnection
 	}
-	if _, err := checkPlugConnectionAltConstraints(connc, denyConst); err == nil {
-		return nil, fmt.Errorf("%s denied by plug rule of interface %q%s", kind, connc.Plug.Interface(), context)
+	d := newDecision("plug", kind, snapDecl)
+	denyIdx, _ := checkPlugConnectionAltConstraints(connc, denyConst)
+	allowIdx, allowErr := -1, error(nil)
+	if denyIdx < 0 {
+		allowIdx, allowErr = checkPlugConnectionAltConstraints(connc, allowConst)
 	}
-
-	allowedConstraints, err := checkPlugConnectionAltConstraints(connc, allowConst)
-	if err != nil {
-		return nil, fmt.Errorf("%s not allowed by plug rule of interface %q%s", kind, connc.Plug.Interface(), context)
+	d.decide(kind, denyIdx, allowIdx, allowErr)
+	if !d.Allowed {
+		return d, nil
 	}
-	return sideArity{allowedConstraints.SlotsPerPlug}, nil
+	return d, sideArity{allowConst[allowIdx].SlotsPerPlug}
 }
 
-func (connc *ConnectCandidate) checkSlotRule(kind string, rule *asserts.SlotRule, snapRule bool) (interfaces.SideArity, error) {
-	context := ""
-	if snapRule {
-		context = fmt.Sprintf(" for %q snap", connc.SlotSnapDeclaration.SnapName())
-	}
+func (connc *ConnectCandidate) slotRuleDecision(kind string, rule *asserts.SlotRule, snapDecl *asserts.SnapDeclaration) (*Decision, interfaces.SideArity) {
 	denyConst := rule.DenyConnection
 	allowConst := rule.AllowConnection
 	if kind == "auto-connection" {
 		denyConst = rule.DenyAutoConnection
 		allowConst = rule.AllowAutoConnection
 	}
-	if _, err := checkSlotConnectionAltConstraints(connc, denyConst); err == nil {
-		return nil, fmt.Errorf("%s denied by slot rule of interface %q%s", kind, connc.Plug.Interface(), context)
+	d := newDecision("slot", kind, snapDecl)
+	denyIdx, _ := checkSlotConnectionAltConstraints(connc, denyConst)
+	allowIdx, allowErr := -1, error(nil)
+	if denyIdx < 0 {
+		allowIdx, allowErr = checkSlotConnectionAltConstraints(connc, allowConst)
 	}
-
-	allowedConstraints, err := checkSlotConnectionAltConstraints(connc, allowConst)
-	if err != nil {
-		return nil, fmt.Errorf("%s not allowed by slot rule of interface %q%s", kind, connc.Plug.Interface(), context)
+	d.decide(kind, denyIdx, allowIdx, allowErr)
+	if !d.Allowed {
+		return d, nil
 	}
-	return sideArity{allowedConstraints.SlotsPerPlug}, nil
+	return d, sideArity{allowConst[allowIdx].SlotsPerPlug}
 }
 
-func (connc *ConnectCandidate) check(kind string) (interfaces.SideArity, error) {
+func (connc *ConnectCandidate) decide(kind string) (*Decision, interfaces.SideArity, error) {
 	baseDecl := connc.BaseDeclaration
 	if baseDecl == nil {
-		return nil, fmt.Errorf("internal error: improperly initialized ConnectCandidate")
+		return nil, nil, fmt.Errorf("internal error: improperly initialized ConnectCandidate")
 	}
 
 	iface := connc.Plug.Interface()
 
 	if connc.Slot.Interface() != iface {
-		return nil, fmt.Errorf("cannot connect mismatched plug interface %q to slot interface %q", iface, connc.Slot.Interface())
+		return nil, nil, fmt.Errorf("cannot connect mismatched plug interface %q to slot interface %q", iface, connc.Slot.Interface())
 	}
 
 	if plugDecl := connc.PlugSnapDeclaration; plugDecl != nil {
 		if rule := plugDecl.PlugRule(iface); rule != nil {
-			return connc.checkPlugRule(kind, rule, true)
+			d, arity := connc.plugRuleDecision(kind, rule, plugDecl)
+			return d, arity, nil
 		}
 	}
 	if slotDecl := connc.SlotSnapDeclaration; slotDecl != nil {
 		if rule := slotDecl.SlotRule(iface); rule != nil {
-			return connc.checkSlotRule(kind, rule, true)
+			d, arity := connc.slotRuleDecision(kind, rule, slotDecl)
+			return d, arity, nil
 		}
 	}
 	if rule := baseDecl.PlugRule(iface); rule != nil {
-		return connc.checkPlugRule(kind, rule, false)
+		d, arity := connc.plugRuleDecision(kind, rule, nil)
+		return d, arity, nil
 	}
 	if rule := baseDecl.SlotRule(iface); rule != nil {
-		return connc.checkSlotRule(kind, rule, false)
+		d, arity := connc.slotRuleDecision(kind, rule, nil)
+		return d, arity, nil
 	}
-	return nil, nil
+	return &Decision{Allowed: true, Alternative: -1}, nil, nil
+}
+
+func (connc *ConnectCandidate) check(kind string) (interfaces.SideArity, error) {
+	d, arity, err := connc.decide(kind)
+	if err != nil {
+		return nil, err
+	}
+	if d.Allowed {
+		return arity, nil
+	}
+	context := ""
+	if d.Snap != "" {
+		context = fmt.Sprintf(" for %q snap", d.Snap)
+	}
+	if d.denied() {
+		return nil, fmt.Errorf("%s denied by %s rule of interface %q%s", kind, d.Side, connc.Plug.Interface(), context)
+	}
+	return nil, fmt.Errorf("%s not allowed by %s rule of interface %q%s", kind, d.Side, connc.Plug.Interface(), context)
 }
 
 // Check checks whether the connection is allowed.
@@ -271,6 +375,20 @@ func (connc *ConnectCandidate) CheckAutoConnect() (interfaces.SideArity, error)
 	return arity, nil
 }
 
+// ExplainConnection returns the decision about whether the connection is
+// allowed.
+func (connc *ConnectCandidate) ExplainConnection() (*Decision, error) {
+	d, _, err := connc.decide("connection")
+	return d, err
+}
+
+// ExplainAutoConnection returns the decision about whether the connection
+// is allowed to auto-connect.
+func (connc *ConnectCandidate) ExplainAutoConnection() (*Decision, error) {
+	d, _, err := connc.decide("auto-connection")
+	return d, err
+}
+
 // InstallCandidateMinimalCheck represents a candidate snap installed with --dangerous flag that should pass minimum checks
 // against snap type (if present). It doesn't check interface attributes.
 type InstallCandidateMinimalCheck struct {
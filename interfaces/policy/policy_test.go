@@ -1048,6 +1048,68 @@ func (s *policySuite) TestSnapDeclAllowDenyConnection(c *C) {
 	}
 }
 
+func (s *policySuite) TestExplainConnection(c *C) {
+	tests := []struct {
+		iface    string
+		expected *policy.Decision
+	}{
+		{"random", &policy.Decision{Allowed: true, Alternative: -1}},
+		{"base-plug-allow", &policy.Decision{Allowed: true, Declaration: "base-declaration", Side: "plug", Constraint: "allow-connection", Alternative: 0}},
+		{"base-plug-deny", &policy.Decision{Declaration: "base-declaration", Side: "plug", Constraint: "deny-connection", Alternative: 0}},
+		{"base-plug-not-allow", &policy.Decision{Declaration: "base-declaration", Side: "plug", Constraint: "allow-connection", Alternative: -1, Reason: "not allowed"}},
+		{"base-plug-not-allow-plugs", &policy.Decision{Declaration: "base-declaration", Side: "plug", Constraint: "allow-connection", Alternative: -1, Reason: `plug attribute "p" has constraints but is unset`}},
+		{"plug-or-p2-s2", &policy.Decision{Allowed: true, Declaration: "base-declaration", Side: "plug", Constraint: "allow-connection", Alternative: 1}},
+		{"plug-or-p1-s2", &policy.Decision{Declaration: "base-declaration", Side: "plug", Constraint: "allow-connection", Alternative: -1, Reason: `slot attribute "s" value "S2" does not match ^(S1)$`}},
+		{"snap-plug-deny", &policy.Decision{Declaration: "snap-declaration", Snap: "plug-snap", Side: "plug", Constraint: "deny-connection", Alternative: 0}},
+		{"snap-slot-not-allow", &policy.Decision{Declaration: "snap-declaration", Snap: "slot-snap", Side: "slot", Constraint: "allow-connection", Alternative: -1, Reason: "not allowed"}},
+	}
+
+	for _, t := range tests {
+		cand := policy.ConnectCandidate{
+			Plug:                interfaces.NewConnectedPlug(s.plugSnap.Plugs[t.iface], nil, nil),
+			Slot:                interfaces.NewConnectedSlot(s.slotSnap.Slots[t.iface], nil, nil),
+			PlugSnapDeclaration: s.plugDecl,
+			SlotSnapDeclaration: s.slotDecl,
+			BaseDeclaration:     s.baseDecl,
+		}
+
+		d, err := cand.ExplainConnection()
+		c.Assert(err, IsNil, Commentf(t.iface))
+		c.Check(d, DeepEquals, t.expected, Commentf(t.iface))
+		c.Check(d.Allowed, Equals, cand.Check() == nil, Commentf(t.iface))
+	}
+}
+
+func (s *policySuite) TestExplainAutoConnection(c *C) {
+	cand := policy.ConnectCandidate{
+		Plug:                interfaces.NewConnectedPlug(s.plugSnap.Plugs["auto-base-plug-deny"], nil, nil),
+		Slot:                interfaces.NewConnectedSlot(s.slotSnap.Slots["auto-base-plug-deny"], nil, nil),
+		PlugSnapDeclaration: s.plugDecl,
+		SlotSnapDeclaration: s.slotDecl,
+		BaseDeclaration:     s.baseDecl,
+	}
+
+	d, err := cand.ExplainAutoConnection()
+	c.Assert(err, IsNil)
+	c.Check(d, DeepEquals, &policy.Decision{Declaration: "base-declaration", Side: "plug", Constraint: "deny-auto-connection", Alternative: 0})
+
+	// connecting manually is fine though
+	d, err = cand.ExplainConnection()
+	c.Assert(err, IsNil)
+	c.Check(d, DeepEquals, &policy.Decision{Allowed: true, Declaration: "base-declaration", Side: "plug", Constraint: "allow-connection", Alternative: 0})
+}
+
+func (s *policySuite) TestExplainConnectionInterfaceMismatch(c *C) {
+	cand := policy.ConnectCandidate{
+		Plug:            interfaces.NewConnectedPlug(s.plugSnap.Plugs["mismatchy"], nil, nil),
+		Slot:            interfaces.NewConnectedSlot(s.slotSnap.Slots["mismatchy"], nil, nil),
+		BaseDeclaration: s.baseDecl,
+	}
+
+	_, err := cand.ExplainConnection()
+	c.Check(err, ErrorMatches, `cannot connect mismatched plug interface "bar" to slot interface "baz"`)
+}
+
 func (s *policySuite) TestSnapDeclAllowDenyAutoConnection(c *C) {
 	tests := []struct {
 		iface    string
@@ -1496,6 +1558,51 @@ slots:
 	}
 }
 
+func (s *policySuite) TestExplainInstallation(c *C) {
+	installSnap := snaptest.MockInfo(c, `
+name: install-snap
+version: 0
+slots:
+  install-slot-coreonly:
+plugs:
+  install-plug-attr-ok:
+    attr: not-ok
+  install-plug-or:
+    p: P1
+  random:
+`, nil)
+
+	cand := policy.InstallCandidate{
+		Snap:            installSnap,
+		BaseDeclaration: s.baseDecl,
+	}
+
+	c.Check(cand.ExplainSlot(installSnap.Slots["install-slot-coreonly"]), DeepEquals, &policy.Decision{
+		Declaration: "base-declaration",
+		Side:        "slot",
+		Constraint:  "allow-installation",
+		Alternative: -1,
+		Reason:      "slot snap type does not match",
+	})
+	c.Check(cand.ExplainPlug(installSnap.Plugs["install-plug-attr-ok"]), DeepEquals, &policy.Decision{
+		Declaration: "base-declaration",
+		Side:        "plug",
+		Constraint:  "allow-installation",
+		Alternative: -1,
+		Reason:      `plug attribute "attr" value "not-ok" does not match ^(ok)$`,
+	})
+	c.Check(cand.ExplainPlug(installSnap.Plugs["install-plug-or"]), DeepEquals, &policy.Decision{
+		Declaration: "base-declaration",
+		Side:        "plug",
+		Constraint:  "deny-installation",
+		Alternative: 0,
+	})
+	c.Check(cand.ExplainPlug(installSnap.Plugs["random"]), DeepEquals, &policy.Decision{
+		Allowed:     true,
+		Alternative: -1,
+	})
+}
+
 func (s *policySuite) TestSnapDeclAllowDenyInstallation(c *C) {
 
 	tests := []struct {
@@ -0,0 +1,128 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package polkit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/polkit"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+}
+
+var _ = Suite(&backendSuite{})
+
+var testedConfinementOpts = []interfaces.ConfinementOptions{
+	{},
+	{DevMode: true},
+	{JailMode: true},
+	{Classic: true},
+}
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &polkit.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecurityPolkit)
+}
+
+func (s *backendSuite) TestInstallingSnapWritesPolicyFiles(c *C) {
+	// NOTE: Hand out a permanent policy so that the .policy file is generated.
+	s.Iface.PolkitPermanentSlotCallback = func(spec *polkit.Specification, slot *snap.SlotInfo) error {
+		return spec.AddPolicy("foo", polkit.Policy("<policyconfig/>"))
+	}
+
+	path := filepath.Join(dirs.SnapPolkitPolicyDir, "snap.samba.foo.policy")
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Check(path, testutil.FileEquals, "<policyconfig/>")
+		st, err := os.Stat(path)
+		c.Assert(err, IsNil)
+		c.Check(st.Mode().Perm(), Equals, os.FileMode(0644))
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestInstallingSnapWithoutPoliciesDoesNotCreateDir(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(osutil.IsDirectory(dirs.SnapPolkitPolicyDir), Equals, false)
+	s.RemoveSnap(c, snapInfo)
+}
+
+func (s *backendSuite) TestRemovingSnapRemovesPolicyFiles(c *C) {
+	s.Iface.PolkitPermanentSlotCallback = func(spec *polkit.Specification, slot *snap.SlotInfo) error {
+		return spec.AddPolicy("foo", polkit.Policy("<policyconfig/>"))
+	}
+
+	path := filepath.Join(dirs.SnapPolkitPolicyDir, "snap.samba.foo.policy")
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Assert(osutil.FileExists(path), Equals, true)
+		s.RemoveSnap(c, snapInfo)
+		c.Check(osutil.FileExists(path), Equals, false)
+	}
+}
+
+func (s *backendSuite) TestUpdatingSnapDropsStalePolicyFiles(c *C) {
+	s.Iface.PolkitPermanentSlotCallback = func(spec *polkit.Specification, slot *snap.SlotInfo) error {
+		return spec.AddPolicy("foo", polkit.Policy("<policyconfig/>"))
+	}
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+
+	// a policy from an older revision and one of another snap
+	stale := filepath.Join(dirs.SnapPolkitPolicyDir, "snap.samba.bar.policy")
+	other := filepath.Join(dirs.SnapPolkitPolicyDir, "snap.other.bar.policy")
+	c.Assert(ioutil.WriteFile(stale, nil, 0644), IsNil)
+	c.Assert(ioutil.WriteFile(other, nil, 0644), IsNil)
+
+	s.Iface.PolkitPermanentSlotCallback = func(spec *polkit.Specification, slot *snap.SlotInfo) error {
+		return spec.AddPolicy("foo", polkit.Policy("<policyconfig></policyconfig>"))
+	}
+	snapInfo = s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{}, ifacetest.SambaYamlV1, 1)
+	c.Check(filepath.Join(dirs.SnapPolkitPolicyDir, "snap.samba.foo.policy"), testutil.FileEquals, "<policyconfig></policyconfig>")
+	c.Check(osutil.FileExists(stale), Equals, false)
+	c.Check(osutil.FileExists(other), Equals, true)
+
+	s.RemoveSnap(c, snapInfo)
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	c.Check(s.Backend.SandboxFeatures(), DeepEquals, []string{"policy-files"})
+}
@@ -956,6 +956,103 @@ func (r *Repository) SnapSpecification(securitySystem SecuritySystem, snapName s
 	return spec, nil
 }
 
+// SpecificationPart is the part of the specification of a snap coming from
+// one of its plugs or slots, either permanently or through a connection.
+type SpecificationPart struct {
+	Interface string
+	// Plug or Slot is the plug or slot of the snap the part comes from.
+	Plug *PlugRef
+	Slot *SlotRef
+	// Connection is the connection the part comes from, nil for the
+	// permanent side-effects of the plug or slot.
+	Connection *ConnRef
+	Spec       Specification
+}
+
+// SnapSpecificationParts returns the specification of the given snap for
+// the given security system like SnapSpecification but split into the parts
+// coming from each plug, slot and connection, in a separate specification
+// each.
+func (r *Repository) SnapSpecificationParts(securitySystem SecuritySystem, snapName string) ([]*SpecificationPart, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var backend SecurityBackend
+	for _, b := range r.backends {
+		if b.Name() == securitySystem {
+			backend = b
+			break
+		}
+	}
+	if backend == nil {
+		return nil, fmt.Errorf("cannot handle interfaces of snap %q, security system %q is not known", snapName, securitySystem)
+	}
+
+	var parts []*SpecificationPart
+
+	// slot side
+	slots := make([]*snap.SlotInfo, 0, len(r.slots[snapName]))
+	for _, slotInfo := range r.slots[snapName] {
+		slots = append(slots, slotInfo)
+	}
+	sort.Sort(bySlotSnapAndName(slots))
+	for _, slotInfo := range slots {
+		iface := r.ifaces[slotInfo.Interface]
+		slotRef := &SlotRef{Snap: snapName, Name: slotInfo.Name}
+		spec := backend.NewSpecification()
+		if err := spec.AddPermanentSlot(iface, slotInfo); err != nil {
+			return nil, err
+		}
+		parts = append(parts, &SpecificationPart{Interface: slotInfo.Interface, Slot: slotRef, Spec: spec})
+		conns := make([]*Connection, 0, len(r.slotPlugs[slotInfo]))
+		for _, conn := range r.slotPlugs[slotInfo] {
+			conns = append(conns, conn)
+		}
+		sort.Slice(conns, func(i, j int) bool {
+			return conns[i].Plug.Ref().String() < conns[j].Plug.Ref().String()
+		})
+		for _, conn := range conns {
+			spec := backend.NewSpecification()
+			if err := spec.AddConnectedSlot(iface, conn.Plug, conn.Slot); err != nil {
+				return nil, err
+			}
+			connRef := &ConnRef{PlugRef: *conn.Plug.Ref(), SlotRef: *conn.Slot.Ref()}
+			parts = append(parts, &SpecificationPart{Interface: slotInfo.Interface, Slot: slotRef, Connection: connRef, Spec: spec})
+		}
+	}
+	// plug side
+	plugs := make([]*snap.PlugInfo, 0, len(r.plugs[snapName]))
+	for _, plugInfo := range r.plugs[snapName] {
+		plugs = append(plugs, plugInfo)
+	}
+	sort.Sort(byPlugSnapAndName(plugs))
+	for _, plugInfo := range plugs {
+		iface := r.ifaces[plugInfo.Interface]
+		plugRef := &PlugRef{Snap: snapName, Name: plugInfo.Name}
+		spec := backend.NewSpecification()
+		if err := spec.AddPermanentPlug(iface, plugInfo); err != nil {
+			return nil, err
+		}
+		parts = append(parts, &SpecificationPart{Interface: plugInfo.Interface, Plug: plugRef, Spec: spec})
+		conns := make([]*Connection, 0, len(r.plugSlots[plugInfo]))
+		for _, conn := range r.plugSlots[plugInfo] {
+			conns = append(conns, conn)
+		}
+		sort.Slice(conns, func(i, j int) bool {
+			return conns[i].Slot.Ref().String() < conns[j].Slot.Ref().String()
+		})
+		for _, conn := range conns {
+			spec := backend.NewSpecification()
+			if err := spec.AddConnectedPlug(iface, conn.Plug, conn.Slot); err != nil {
+				return nil, err
+			}
+			connRef := &ConnRef{PlugRef: *conn.Plug.Ref(), SlotRef: *conn.Slot.Ref()}
+			parts = append(parts, &SpecificationPart{Interface: plugInfo.Interface, Plug: plugRef, Connection: connRef, Spec: spec})
+		}
+	}
+	return parts, nil
+}
+
 // AddSnap adds plugs and slots declared by the given snap to the repository.
 //
 // This function can be used to implement snap install or, when used along with
@@ -1260,6 +1260,45 @@ func (s *RepositorySuite) TestSnapSpecification(c *C) {
 	})
 }
 
+func (s *RepositorySuite) TestSnapSpecificationParts(c *C) {
+	repo := s.emptyRepo
+	backend := &ifacetest.TestSecurityBackend{BackendName: testSecurity}
+	c.Assert(repo.AddBackend(backend), IsNil)
+	c.Assert(repo.AddInterface(testInterface), IsNil)
+	c.Assert(repo.AddPlug(s.plug), IsNil)
+	c.Assert(repo.AddSlot(s.slot), IsNil)
+	connRef := NewConnRef(s.plug, s.slot)
+	_, err := repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	parts, err := repo.SnapSpecificationParts(testSecurity, s.plug.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(parts, HasLen, 2)
+	plugRef := &PlugRef{Snap: s.plug.Snap.InstanceName(), Name: s.plug.Name}
+	c.Check(parts[0].Interface, Equals, "interface")
+	c.Check(parts[0].Plug, DeepEquals, plugRef)
+	c.Check(parts[0].Slot, IsNil)
+	c.Check(parts[0].Connection, IsNil)
+	c.Check(parts[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static plug snippet"})
+	c.Check(parts[1].Plug, DeepEquals, plugRef)
+	c.Check(parts[1].Connection, DeepEquals, connRef)
+	c.Check(parts[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific plug snippet"})
+
+	parts, err = repo.SnapSpecificationParts(testSecurity, s.slot.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(parts, HasLen, 2)
+	slotRef := &SlotRef{Snap: s.slot.Snap.InstanceName(), Name: s.slot.Name}
+	c.Check(parts[0].Slot, DeepEquals, slotRef)
+	c.Check(parts[0].Connection, IsNil)
+	c.Check(parts[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static slot snippet"})
+	c.Check(parts[1].Slot, DeepEquals, slotRef)
+	c.Check(parts[1].Connection, DeepEquals, connRef)
+	c.Check(parts[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific slot snippet"})
+
+	_, err = repo.SnapSpecificationParts("unknown", s.slot.Snap.InstanceName())
+	c.Check(err, ErrorMatches, `cannot handle interfaces of snap "producer", security system "unknown" is not known`)
+}
+
 func (s *RepositorySuite) TestSnapSpecificationFailureWithConnectionSnippets(c *C) {
 	var testSecurity SecuritySystem = "security"
 	backend := &ifacetest.TestSecurityBackend{BackendName: testSecurity}
@@ -129,6 +129,26 @@ func (spec *Specification) Snippets() (result []string) {
 	return result
 }
 
+// SnippetsForTag returns the snippets tagging devices for the given
+// security tag along with the snippets not specific to any security tag.
+func (spec *Specification) SnippetsForTag(securityTag string) (result []string) {
+	if spec.ControlsDeviceCgroup() {
+		return nil
+	}
+	tag := udevTag(securityTag)
+	entries := make([]entry, 0, len(spec.entries))
+	for _, e := range spec.entries {
+		if e.tag == "" || e.tag == tag {
+			entries = append(entries, e)
+		}
+	}
+	sort.Sort(byTagAndSnippet(entries))
+	for _, e := range entries {
+		result = append(result, e.snippet)
+	}
+	return result
+}
+
 // Implementation of methods required by interfaces.Specification
 
 // AddConnectedPlug records udev-specific side-effects of having a connected plug.
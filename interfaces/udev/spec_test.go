@@ -151,6 +151,34 @@ func (s *specSuite) TestTagDeviceAltLibexecdir(c *C) {
 	s.testTagDevice(c, "/usr/libexec/snapd")
 }
 
+func (s *specSuite) TestSnippetsForTag(c *C) {
+	defer func() { dirs.SetRootDir("") }()
+	restore := release.MockReleaseInfo(&release.OS{ID: "ubuntu"})
+	defer restore()
+	dirs.SetRootDir("")
+
+	iface := &ifacetest.TestInterface{
+		InterfaceName: "iface-1",
+		UDevConnectedPlugCallback: func(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("untagged")
+			spec.TagDevice(`kernel="voodoo"`)
+			return nil
+		},
+	}
+	c.Assert(s.spec.AddConnectedPlug(iface, s.plug, s.slot), IsNil)
+
+	c.Check(s.spec.SnippetsForTag("snap.snap1.foo"), DeepEquals, []string{
+		"untagged",
+		`# iface-1
+kernel="voodoo", TAG+="snap_snap1_foo"`,
+		`TAG=="snap_snap1_foo", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_snap1_foo $devpath $major:$minor"`,
+	})
+	c.Check(s.spec.SnippetsForTag("snap.snap1.bar"), DeepEquals, []string{"untagged"})
+
+	s.spec.SetControlsDeviceCgroup()
+	c.Check(s.spec.SnippetsForTag("snap.snap1.foo"), IsNil)
+}
+
 // The spec.Specification can be used through the interfaces.Specification interface
 func (s *specSuite) TestSpecificationIface(c *C) {
 	var r interfaces.Specification = s.spec
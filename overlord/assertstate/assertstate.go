@@ -330,6 +330,8 @@ func delayedCrossMgrInit() {
 	snapstate.AutoRefreshAssertions = AutoRefreshAssertions
 	// hook retrieving auto-aliases into snapstate logic
 	snapstate.AutoAliases = AutoAliases
+	// hook the validation sets in enforcing mode into snapstate logic
+	snapstate.EnforcedValidationSets = EnforcedValidationSets
 }
 
 // AutoRefreshAssertions tries to refresh all assertions
@@ -361,25 +363,40 @@ func RefreshValidationSetAssertions(s *state.State, userID int) error {
 	}
 
 	// update validation set tracking state
-	for _, vs := range vsets {
-		// TODO: for enforce mode check that the validation-sets don't conflict
-		// and are usable before moving Current.
-		if vs.Mode == Monitor && vs.PinnedAt == 0 {
-			headers := map[string]string{
-				"series":     release.Series,
-				"account-id": vs.AccountID,
-				"name":       vs.Name,
-			}
-			db := DB(s)
-			as, err := db.FindSequence(asserts.ValidationSetType, headers, -1, asserts.ValidationSetType.MaxSupportedFormat())
+	for key, vs := range vsets {
+		if vs.PinnedAt != 0 {
+			continue
+		}
+		headers := map[string]string{
+			"series":     release.Series,
+			"account-id": vs.AccountID,
+			"name":       vs.Name,
+		}
+		db := DB(s)
+		as, err := db.FindSequence(asserts.ValidationSetType, headers, -1, asserts.ValidationSetType.MaxSupportedFormat())
+		if err != nil {
+			return fmt.Errorf("internal error: cannot find assertion %v when refreshing validation-set assertions", headers)
+		}
+		if vs.Current == as.Sequence() {
+			continue
+		}
+		if vs.Mode == Enforce {
+			// the new sequence point must not conflict with the
+			// other enforced validation sets
+			sets, err := enforcedValidationSets(s, key)
 			if err != nil {
-				return fmt.Errorf("internal error: cannot find assertion %v when refreshing validation-set assertions", headers)
+				return err
 			}
-			if vs.Current != as.Sequence() {
-				vs.Current = as.Sequence()
-				UpdateValidationSet(s, vs)
+			if err := sets.Add(as.(*asserts.ValidationSet)); err != nil {
+				return err
+			}
+			if err := sets.Conflict(); err != nil {
+				logger.Noticef("cannot move enforced validation set %s to sequence %d: %v", key, as.Sequence(), err)
+				continue
 			}
 		}
+		vs.Current = as.Sequence()
+		UpdateValidationSet(s, vs)
 	}
 
 	return nil
@@ -478,3 +495,29 @@ func ValidationSetAssertionForMonitor(st *state.State, accountID, name string, s
 	}
 	return as, false, err
 }
+
+// ValidationSetAssertionForEnforce tries to fetch or refresh the validation
+// set assertion with accountID/name/sequence (sequence is optional) like
+// ValidationSetAssertionForMonitor and checks that it does not conflict
+// with the other validation sets in enforcing mode. It returns the
+// assertion together with the combination of all the validation sets that
+// would be enforced.
+func ValidationSetAssertionForEnforce(st *state.State, accountID, name string, sequence int, userID int) (as *asserts.ValidationSet, sets *snapasserts.ValidationSets, err error) {
+	pinned := sequence > 0
+	as, _, err = ValidationSetAssertionForMonitor(st, accountID, name, sequence, pinned, userID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sets, err = enforcedValidationSets(st, ValidationSetKey(accountID, name))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := sets.Add(as); err != nil {
+		return nil, nil, err
+	}
+	if err := sets.Conflict(); err != nil {
+		return nil, nil, err
+	}
+	return as, sets, nil
+}
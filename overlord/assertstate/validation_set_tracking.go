@@ -23,7 +23,10 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
 )
 
 // ValidationSetMode reflects the mode of respective validation set, which is
@@ -54,6 +57,15 @@ type ValidationSetTracking struct {
 	LocalOnly bool `json:"local-only,omitempty"`
 }
 
+// Sequence returns the sequence point the validation set is tracked at,
+// the pinned one if pinned, the current one otherwise.
+func (tr *ValidationSetTracking) Sequence() int {
+	if tr.PinnedAt > 0 {
+		return tr.PinnedAt
+	}
+	return tr.Current
+}
+
 // ValidationSetKey formats the given account id and name into a validation set key.
 func ValidationSetKey(accountID, name string) string {
 	return fmt.Sprintf("%s/%s", accountID, name)
@@ -130,3 +142,40 @@ func ValidationSets(st *state.State) (map[string]*ValidationSetTracking, error)
 	}
 	return vsmap, nil
 }
+
+// EnforcedValidationSets returns the combination of the validation sets
+// tracked in enforcing mode.
+func EnforcedValidationSets(st *state.State) (*snapasserts.ValidationSets, error) {
+	return enforcedValidationSets(st, "")
+}
+
+// enforcedValidationSets returns the combination of the validation sets
+// tracked in enforcing mode, except for the one with the given key.
+func enforcedValidationSets(st *state.State, exceptKey string) (*snapasserts.ValidationSets, error) {
+	valsets, err := ValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := snapasserts.NewValidationSets()
+	db := DB(st)
+	for key, tr := range valsets {
+		if tr.Mode != Enforce || key == exceptKey {
+			continue
+		}
+		headers := map[string]string{
+			"series":     release.Series,
+			"account-id": tr.AccountID,
+			"name":       tr.Name,
+			"sequence":   fmt.Sprintf("%d", tr.Sequence()),
+		}
+		as, err := db.Find(asserts.ValidationSetType, headers)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find validation set %s at sequence %d: %v", key, tr.Sequence(), err)
+		}
+		if err := sets.Add(as.(*asserts.ValidationSet)); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
@@ -0,0 +1,47 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	supportedConfigurations["core.download.max-parallel"] = true
+}
+
+func validateDownloadSettings(tr config.Conf) error {
+	maxParallelStr, err := coreCfg(tr, "download.max-parallel")
+	if err != nil {
+		return err
+	}
+	// reset is fine
+	if maxParallelStr == "" {
+		return nil
+	}
+	if n, err := strconv.ParseUint(maxParallelStr, 10, 8); err != nil || (n < 1 || n > 16) {
+		return fmt.Errorf("download.max-parallel must be a number between 1 and 16, not %q", maxParallelStr)
+	}
+	return nil
+}
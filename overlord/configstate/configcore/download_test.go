@@ -0,0 +1,56 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type downloadSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&downloadSuite{})
+
+func (s *downloadSuite) TestConfigureMaxParallelHappy(c *C) {
+	for _, v := range []interface{}{"1", "4", 16, ""} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"download.max-parallel": v,
+			},
+		})
+		c.Check(err, IsNil, Commentf("%v", v))
+	}
+}
+
+func (s *downloadSuite) TestConfigureMaxParallelInvalid(c *C) {
+	for _, v := range []string{"0", "17", "-1", "many"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"download.max-parallel": v,
+			},
+		})
+		c.Check(err, ErrorMatches, `download.max-parallel must be a number between 1 and 16, not "`+v+`"`)
+	}
+}
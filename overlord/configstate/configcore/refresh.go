@@ -23,10 +23,13 @@ package configcore
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timeutil"
 )
@@ -38,6 +41,13 @@ func init() {
 	supportedConfigurations["core.refresh.metered"] = true
 	supportedConfigurations["core.refresh.retain"] = true
 	supportedConfigurations["core.refresh.rate-limit"] = true
+	supportedConfigurations["core.refresh.rollback-unhealthy"] = true
+	supportedConfigurations["core.refresh.rollback-grace-period"] = true
+	supportedConfigurations["core.refresh.waves"] = true
+	supportedConfigurations["core.refresh.wave-delay"] = true
+	supportedConfigurations["core.refresh.battery"] = true
+	supportedConfigurations["core.refresh.battery-threshold"] = true
+	supportedConfigurations["core.refresh.do-not-disturb"] = true
 }
 
 func reportOrIgnoreInvalidManageRefreshes(tr config.Conf, optName string) error {
@@ -146,3 +156,94 @@ func validateRefreshRateLimit(tr config.Conf) error {
 	}
 	return nil
 }
+
+func validateRefreshRollback(tr config.Conf) error {
+	rollbackUnhealthy, err := coreCfg(tr, "refresh.rollback-unhealthy")
+	if err != nil {
+		return err
+	}
+	if rollbackUnhealthy != "" && rollbackUnhealthy != "all" {
+		for _, instanceName := range strings.Split(rollbackUnhealthy, ",") {
+			if err := naming.ValidateInstance(instanceName); err != nil {
+				return fmt.Errorf("cannot set \"refresh.rollback-unhealthy\": %v", err)
+			}
+		}
+	}
+
+	gracePeriod, err := coreCfg(tr, "refresh.rollback-grace-period")
+	if err != nil {
+		return err
+	}
+	// reset is fine
+	if gracePeriod == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(gracePeriod); err != nil || d < 0 || d > time.Hour {
+		return fmt.Errorf("refresh.rollback-grace-period must be a duration of at most 1h, not %q", gracePeriod)
+	}
+	return nil
+}
+
+func validateRefreshWaves(tr config.Conf) error {
+	waves, err := coreCfg(tr, "refresh.waves")
+	if err != nil {
+		return err
+	}
+	if waves != "" {
+		for _, sel := range strings.Split(waves, ",") {
+			if strutil.ListContains(snapstate.RefreshWaveCategories, sel) {
+				continue
+			}
+			if err := naming.ValidateInstance(sel); err != nil {
+				return fmt.Errorf("cannot set \"refresh.waves\": %q is neither one of %s nor a snap name", sel, strutil.Quoted(snapstate.RefreshWaveCategories))
+			}
+		}
+	}
+
+	delay, err := coreCfg(tr, "refresh.wave-delay")
+	if err != nil {
+		return err
+	}
+	// reset is fine
+	if delay == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(delay); err != nil || d < 0 || d > 24*time.Hour {
+		return fmt.Errorf("refresh.wave-delay must be a duration of at most 24h, not %q", delay)
+	}
+	return nil
+}
+
+func validateRefreshDeferrals(tr config.Conf) error {
+	onBattery, err := coreCfg(tr, "refresh.battery")
+	if err != nil {
+		return err
+	}
+	switch onBattery {
+	case "", "hold":
+		// noop
+	default:
+		return fmt.Errorf("refresh.battery value %q is invalid", onBattery)
+	}
+
+	threshold, err := coreCfg(tr, "refresh.battery-threshold")
+	if err != nil {
+		return err
+	}
+	if threshold != "" {
+		if n, err := strconv.ParseUint(threshold, 10, 8); err != nil || n > 100 {
+			return fmt.Errorf("refresh.battery-threshold must be a percentage between 0 and 100, not %q", threshold)
+		}
+	}
+
+	doNotDisturb, err := coreCfg(tr, "refresh.do-not-disturb")
+	if err != nil {
+		return err
+	}
+	if doNotDisturb != "" {
+		if _, err := timeutil.ParseSchedule(doNotDisturb); err != nil {
+			return fmt.Errorf("cannot set \"refresh.do-not-disturb\": %v", err)
+		}
+	}
+	return nil
+}
@@ -209,3 +209,123 @@ func (s *refreshSuite) TestConfigureRefreshRetainInvalid(c *C) {
 	})
 	c.Assert(err, ErrorMatches, `retain must be a number between 2 and 20, not "invalid"`)
 }
+
+func (s *refreshSuite) TestConfigureRefreshRollbackHappy(c *C) {
+	for _, conf := range []map[string]interface{}{
+		{"refresh.rollback-unhealthy": "all"},
+		{"refresh.rollback-unhealthy": "foo,bar_instance"},
+		{"refresh.rollback-unhealthy": ""},
+		{"refresh.rollback-grace-period": "30s"},
+		{"refresh.rollback-grace-period": "1h"},
+		{"refresh.rollback-grace-period": ""},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf:  conf,
+		})
+		c.Check(err, IsNil, Commentf("%v", conf))
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshRollbackInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.rollback-unhealthy": "foo,Bar",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set "refresh.rollback-unhealthy": invalid snap name: "Bar"`)
+
+	for _, v := range []string{"2h", "-1s", "soon"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.rollback-grace-period": v,
+			},
+		})
+		c.Check(err, ErrorMatches, `refresh.rollback-grace-period must be a duration of at most 1h, not "`+v+`"`)
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshWavesHappy(c *C) {
+	for _, conf := range []map[string]interface{}{
+		{"refresh.waves": "canary-snap,apps,content-providers,bases"},
+		{"refresh.waves": "kernel,gadget"},
+		{"refresh.waves": ""},
+		{"refresh.wave-delay": "30m"},
+		{"refresh.wave-delay": ""},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf:  conf,
+		})
+		c.Check(err, IsNil, Commentf("%v", conf))
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshWavesInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.waves": "apps,Bases",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set "refresh.waves": "Bases" is neither one of "apps", "content-providers", "bases", "kernel", "gadget" nor a snap name`)
+
+	for _, v := range []string{"25h", "-1s", "later"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.wave-delay": v,
+			},
+		})
+		c.Check(err, ErrorMatches, `refresh.wave-delay must be a duration of at most 24h, not "`+v+`"`)
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshDeferralsHappy(c *C) {
+	for _, conf := range []map[string]interface{}{
+		{"refresh.battery": "hold"},
+		{"refresh.battery": ""},
+		{"refresh.battery-threshold": "0"},
+		{"refresh.battery-threshold": "25"},
+		{"refresh.battery-threshold": "100"},
+		{"refresh.battery-threshold": ""},
+		{"refresh.do-not-disturb": "mon-fri,09:00-17:00"},
+		{"refresh.do-not-disturb": ""},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf:  conf,
+		})
+		c.Check(err, IsNil, Commentf("%v", conf))
+	}
+}
+
+func (s *refreshSuite) TestConfigureRefreshDeferralsInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.battery": "invalid",
+		},
+	})
+	c.Check(err, ErrorMatches, `refresh\.battery value "invalid" is invalid`)
+
+	for _, v := range []string{"101", "-1", "10.5", "low"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"refresh.battery-threshold": v,
+			},
+		})
+		c.Check(err, ErrorMatches, `refresh.battery-threshold must be a percentage between 0 and 100, not "`+v+`"`)
+	}
+
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.do-not-disturb": "someday",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set "refresh.do-not-disturb": .*`)
+}
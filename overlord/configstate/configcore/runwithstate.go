@@ -55,7 +55,12 @@ func init() {
 	validateOnly := &flags{validatedOnlyStateConfig: true}
 	addWithStateHandler(validateRefreshSchedule, nil, validateOnly)
 	addWithStateHandler(validateRefreshRateLimit, nil, validateOnly)
+	addWithStateHandler(validateRefreshRollback, nil, validateOnly)
+	addWithStateHandler(validateRefreshWaves, nil, validateOnly)
+	addWithStateHandler(validateRefreshDeferrals, nil, validateOnly)
+	addWithStateHandler(validateDownloadSettings, nil, validateOnly)
 	addWithStateHandler(validateAutomaticSnapshotsExpiration, nil, validateOnly)
+	addWithStateHandler(validateStoreBackends, nil, validateOnly)
 }
 
 type withStateHandler struct {
@@ -116,6 +121,10 @@ func applyHandlers(cfg config.Conf, handlers []configHandler) error {
 			if !validCertOption(k) {
 				return fmt.Errorf("cannot set store ssl certificate under name %q: name must only contain word characters or a dash", k)
 			}
+		case k == "core.store-backends" || strings.HasPrefix(k, "core.store-backends."):
+			if !validStoreBackendOption(k) {
+				return fmt.Errorf("cannot set %q: unsupported store backend option", k)
+			}
 		case !supportedConfigurations[k]:
 			return fmt.Errorf("cannot set %q: unsupported system option", k)
 		}
@@ -0,0 +1,46 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !nomanagers
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+var validStoreBackendOption = regexp.MustCompile(`^core\.store-backends(?:\.[^.]+(?:\.(?:url|snaps|publishers))?)?$`).MatchString
+
+func validateStoreBackends(tr config.Conf) error {
+	changed := false
+	for _, name := range tr.Changes() {
+		if strings.HasPrefix(name, "core.store-backends") {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+	_, err := snapstate.StoreBackendsFromConfig(tr)
+	return err
+}
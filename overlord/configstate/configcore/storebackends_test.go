@@ -0,0 +1,82 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type storeBackendsSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&storeBackendsSuite{})
+
+func (s *storeBackendsSuite) run(c *C, opts map[string]string) error {
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	s.state.Unlock()
+	for k, v := range opts {
+		c.Assert(tr.Set("core", k, v), IsNil)
+	}
+	return configcore.Run(tr)
+}
+
+func (s *storeBackendsSuite) TestConfigureStoreBackendHappy(c *C) {
+	err := s.run(c, map[string]string{
+		"store-backends.acme.url":        "https://store.acme.example.com",
+		"store-backends.acme.snaps":      "acme-*, other",
+		"store-backends.acme.publishers": "acme-publisher-id",
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *storeBackendsSuite) TestConfigureStoreBackendInvalidURL(c *C) {
+	err := s.run(c, map[string]string{
+		"store-backends.acme.url": "store.acme.example.com",
+	})
+	c.Assert(err, ErrorMatches, `invalid url for store backend "acme": "store.acme.example.com" is not an absolute http\(s\) url`)
+}
+
+func (s *storeBackendsSuite) TestConfigureStoreBackendMissingURL(c *C) {
+	err := s.run(c, map[string]string{
+		"store-backends.acme.snaps": "acme-*",
+	})
+	c.Assert(err, ErrorMatches, `store backend "acme" has no url`)
+}
+
+func (s *storeBackendsSuite) TestConfigureStoreBackendInvalidPattern(c *C) {
+	err := s.run(c, map[string]string{
+		"store-backends.acme.url":   "https://store.acme.example.com",
+		"store-backends.acme.snaps": "acme-[",
+	})
+	c.Assert(err, ErrorMatches, `invalid snap pattern "acme-\[" for store backend "acme"`)
+}
+
+func (s *storeBackendsSuite) TestConfigureStoreBackendUnsupportedOption(c *C) {
+	err := s.run(c, map[string]string{
+		"store-backends.acme.url":  "https://store.acme.example.com",
+		"store-backends.acme.auth": "secret",
+	})
+	c.Assert(err, ErrorMatches, `cannot set "core.store-backends.acme.auth": unsupported store backend option`)
+}
@@ -52,6 +52,9 @@ func ContextTransaction(context *hookstate.Context) *config.Transaction {
 	context.OnDone(func() error {
 		tr.Commit()
 		if context.InstanceName() == "core" {
+			// the configuration of the store backends may have
+			// changed
+			snapstate.InvalidateStoreRoutes(context.State())
 			// make sure the Ensure logic can process
 			// system configuration changes as soon as possible
 			context.State().EnsureBefore(0)
@@ -46,6 +46,7 @@ func init() {
 	}
 
 	snapstate.CheckHealthHook = Hook
+	snapstate.CheckSnapHealth = checkSnapHealth
 }
 
 func Hook(st *state.State, snapName string, snapRev snap.Revision) *state.Task {
@@ -224,3 +225,19 @@ func Get(st *state.State, snap string) (*HealthState, error) {
 
 	return &health, nil
 }
+
+// checkSnapHealth returns an error if the given snap revision reported an
+// error status.
+func checkSnapHealth(st *state.State, instanceName string, rev snap.Revision) error {
+	health, err := Get(st, instanceName)
+	if err != nil {
+		return err
+	}
+	if health == nil || health.Revision != rev || health.Status != ErrorStatus {
+		return nil
+	}
+	if health.Message == "" {
+		return fmt.Errorf("health status is %q", health.Status)
+	}
+	return fmt.Errorf("health status is %q: %s", health.Status, health.Message)
+}
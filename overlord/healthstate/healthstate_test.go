@@ -252,3 +252,22 @@ func (s *healthSuite) TestSetFromHookContextEmpty(c *check.C) {
 	// no health in the context -> no health in state
 	c.Check(s.state.Get("health", &hs), check.Equals, state.ErrNoState)
 }
+
+func (s *healthSuite) TestCheckSnapHealth(c *check.C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// no health recorded
+	c.Check(snapstate.CheckSnapHealth(s.state, "foo", snap.R(2)), check.IsNil)
+
+	s.state.Set("health", map[string]*healthstate.HealthState{
+		"foo": {Revision: snap.R(2), Status: healthstate.ErrorStatus, Message: "no network"},
+		"bar": {Revision: snap.R(2), Status: healthstate.ErrorStatus},
+		"baz": {Revision: snap.R(2), Status: healthstate.WaitingStatus},
+	})
+	c.Check(snapstate.CheckSnapHealth(s.state, "foo", snap.R(2)), check.ErrorMatches, `health status is "error": no network`)
+	c.Check(snapstate.CheckSnapHealth(s.state, "bar", snap.R(2)), check.ErrorMatches, `health status is "error"`)
+	c.Check(snapstate.CheckSnapHealth(s.state, "baz", snap.R(2)), check.IsNil)
+	// the health of other revisions is ignored
+	c.Check(snapstate.CheckSnapHealth(s.state, "foo", snap.R(3)), check.IsNil)
+}
@@ -0,0 +1,463 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/denials"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+)
+
+var (
+	// denialsCollectInterval is how often the denials are collected
+	// from the journal.
+	denialsCollectInterval = 10 * time.Minute
+	// maxDenials is the number of recent denials kept for each snap,
+	// the least recent ones are dropped.
+	maxDenials = 50
+	// maxDenialsLogEntries is the number of journal entries read at most
+	// at each collection, the least recent ones logged since the previous
+	// collection are skipped.
+	maxDenialsLogEntries = 1000
+
+	auditLogReader = systemd.AuditLogReader
+)
+
+// ErrSandboxDenialsDisabled is returned when collecting the denials of the
+// sandbox of snaps is not enabled.
+var ErrSandboxDenialsDisabled = errors.New("experimental feature disabled - test it by setting 'experimental.sandbox-denials' to true")
+
+func sandboxDenialsEnabled(st *state.State) (bool, error) {
+	tr := config.NewTransaction(st)
+	enabled, err := features.Flag(tr, features.SandboxDenials)
+	if err != nil && !config.IsNoOption(err) {
+		return false, err
+	}
+	return enabled, nil
+}
+
+func sandboxDenials(st *state.State) (map[string][]*denials.Denial, error) {
+	// snap -> recent denials
+	var all map[string][]*denials.Denial
+	err := st.Get("sandbox-denials", &all)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("internal error: cannot get sandbox-denials: %v", err)
+	}
+	if err == state.ErrNoState {
+		return make(map[string][]*denials.Denial), nil
+	}
+	return all, nil
+}
+
+// Denials returns the recent denials of the sandbox of the given snap, the
+// least recent first. It returns ErrSandboxDenialsDisabled unless collecting
+// the denials is enabled.
+func Denials(st *state.State, instanceName string) ([]*denials.Denial, error) {
+	enabled, err := sandboxDenialsEnabled(st)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrSandboxDenialsDisabled
+	}
+	all, err := sandboxDenials(st)
+	if err != nil {
+		return nil, err
+	}
+	return all[instanceName], nil
+}
+
+// addDenial records the given denial, counting it along with the same
+// denial if it was already recorded.
+func addDenial(all map[string][]*denials.Denial, d *denials.Denial) {
+	recent := all[d.Snap]
+	for _, other := range recent {
+		if other.SameAs(d) {
+			other.Count += d.Count
+			if d.Time.After(other.Time) {
+				other.Time = d.Time
+				other.Comm = d.Comm
+			}
+			sort.SliceStable(recent, func(i, j int) bool {
+				return recent[i].Time.Before(recent[j].Time)
+			})
+			return
+		}
+	}
+	recent = append(recent, d)
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].Time.Before(recent[j].Time)
+	})
+	if len(recent) > maxDenials {
+		recent = recent[len(recent)-maxDenials:]
+	}
+	all[d.Snap] = recent
+}
+
+// collectingDenials returns whether the denials of the sandbox of snaps are
+// collected, that is when they are recorded or prompted for.
+func collectingDenials(st *state.State) (bool, error) {
+	enabled, err := sandboxDenialsEnabled(st)
+	if err != nil || enabled {
+		return enabled, err
+	}
+	return promptingEnabled(st)
+}
+
+// denialFromLog returns the denial logged in the given journal entry, if
+// any.
+func denialFromLog(log systemd.Log) *denials.Denial {
+	t, err := log.Time()
+	if err != nil {
+		return nil
+	}
+	return denials.Parse(log.Message(), t)
+}
+
+// recordDenials records the given denials of installed snaps, when
+// collecting them is enabled, and prompts for them when prompting is
+// enabled. The state is only changed if there is something to record.
+func recordDenials(st *state.State, collected []*denials.Denial) error {
+	// the denials of snaps removed since are dropped
+	installed := collected[:0]
+	for _, d := range collected {
+		var snapst snapstate.SnapState
+		err := snapstate.Get(st, d.Snap, &snapst)
+		if err != nil && err != state.ErrNoState {
+			return err
+		}
+		if snapst.IsInstalled() {
+			installed = append(installed, d)
+		}
+	}
+	collected = installed
+	if len(collected) == 0 {
+		return nil
+	}
+
+	enabled, err := sandboxDenialsEnabled(st)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		all, err := sandboxDenials(st)
+		if err != nil {
+			return err
+		}
+		for _, d := range collected {
+			addDenial(all, d)
+		}
+		st.Set("sandbox-denials", all)
+	}
+
+	enabled, err = promptingEnabled(st)
+	if err != nil || !enabled {
+		return err
+	}
+	prompts, err := addPrompts(st, collected)
+	if err != nil {
+		return err
+	}
+	notifyPrompts(prompts)
+	return nil
+}
+
+// followingDenials returns whether the journal is being followed for
+// prompting. The denials mutex must be held by the caller.
+func (m *InterfaceManager) followingDenials() bool {
+	if m.denialsFollowed == nil {
+		return false
+	}
+	select {
+	case <-m.denialsFollowed:
+		return false
+	default:
+		return true
+	}
+}
+
+// CollectDenials records the denials of the sandbox of installed snaps
+// logged to the journal since the last collection, when collecting them is
+// enabled. Nothing is done while the journal is followed for prompting, as
+// the denials are recorded as they are logged then. The state must not be
+// locked by the caller.
+func (m *InterfaceManager) CollectDenials() error {
+	m.denialsMu.Lock()
+	defer m.denialsMu.Unlock()
+
+	if m.followingDenials() {
+		return nil
+	}
+
+	st := m.state
+	st.Lock()
+	collecting, err := collectingDenials(st)
+	if err != nil || !collecting {
+		st.Unlock()
+		return err
+	}
+	var cursor string
+	err = st.Get("sandbox-denials-cursor", &cursor)
+	st.Unlock()
+	if err != nil && err != state.ErrNoState {
+		return fmt.Errorf("internal error: cannot get sandbox-denials-cursor: %v", err)
+	}
+
+	reader, err := auditLogReader(cursor, maxDenialsLogEntries, false)
+	if err != nil {
+		return fmt.Errorf("cannot read the journal: %v", err)
+	}
+	defer reader.Close()
+
+	lastCursor := cursor
+	var collected []*denials.Denial
+	decoder := json.NewDecoder(reader)
+	for {
+		var log systemd.Log
+		if err := decoder.Decode(&log); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("cannot decode journal entry: %v", err)
+		}
+		if c := log.Cursor(); c != "" {
+			cursor = c
+		}
+		if d := denialFromLog(log); d != nil {
+			collected = append(collected, d)
+		}
+	}
+
+	st.Lock()
+	defer st.Unlock()
+	if cursor != lastCursor {
+		st.Set("sandbox-denials-cursor", cursor)
+	}
+	return recordDenials(st, collected)
+}
+
+// ensureDenialsCollected collects the denials logged to the journal every
+// denialsCollectInterval. The journal is read in a goroutine not to hold up
+// the other managers, and only one collection runs at a time.
+func (m *InterfaceManager) ensureDenialsCollected() {
+	if m.denialsCollected != nil {
+		select {
+		case <-m.denialsCollected:
+			m.denialsCollected = nil
+		default:
+			return
+		}
+	}
+
+	st := m.state
+	st.Lock()
+	collecting, err := collectingDenials(st)
+	st.Unlock()
+	if err != nil {
+		logger.Noticef("cannot check whether sandbox denials are collected: %v", err)
+	}
+	if !collecting {
+		return
+	}
+
+	now := time.Now()
+	if now.Before(m.denialsNextCollect) {
+		return
+	}
+	m.denialsNextCollect = now.Add(denialsCollectInterval)
+	done := make(chan struct{})
+	m.denialsCollected = done
+	go func() {
+		defer close(done)
+		if err := m.CollectDenials(); err != nil {
+			logger.Noticef("cannot collect sandbox denials: %v", err)
+		}
+	}()
+}
+
+// waitDenialsCollected waits for the collection of the denials started by
+// Ensure, if any.
+func (m *InterfaceManager) waitDenialsCollected() {
+	if m.denialsCollected != nil {
+		<-m.denialsCollected
+		m.denialsCollected = nil
+	}
+}
+
+// ensureDenialsFollowed follows the journal in a goroutine while prompting
+// is enabled, for users to be prompted as soon as the accesses are denied.
+// Following is started again by the next Ensure if journalctl exits.
+func (m *InterfaceManager) ensureDenialsFollowed() {
+	st := m.state
+	st.Lock()
+	prompting, err := promptingEnabled(st)
+	st.Unlock()
+	if err != nil {
+		logger.Noticef("cannot check whether prompting is enabled: %v", err)
+		return
+	}
+	if !prompting {
+		m.stopFollowingDenials()
+		return
+	}
+
+	m.denialsMu.Lock()
+	defer m.denialsMu.Unlock()
+	if m.followingDenials() {
+		return
+	}
+	st.Lock()
+	var cursor string
+	err = st.Get("sandbox-denials-cursor", &cursor)
+	st.Unlock()
+	if err != nil && err != state.ErrNoState {
+		logger.Noticef("internal error: cannot get sandbox-denials-cursor: %v", err)
+		return
+	}
+	journal, err := auditLogReader(cursor, maxDenialsLogEntries, true)
+	if err != nil {
+		logger.Noticef("cannot follow the journal: %v", err)
+		return
+	}
+	done := make(chan struct{})
+	m.denialsJournal = journal
+	m.denialsFollowed = done
+	go m.followDenials(journal, done)
+}
+
+// followDenials records the denials logged to the given journal until it
+// is closed.
+func (m *InterfaceManager) followDenials(journal io.ReadCloser, done chan struct{}) {
+	defer close(done)
+	defer journal.Close()
+
+	st := m.state
+	decoder := json.NewDecoder(journal)
+	for {
+		var log systemd.Log
+		if err := decoder.Decode(&log); err != nil {
+			if err != io.EOF {
+				logger.Debugf("stopped following the journal: %v", err)
+			}
+			return
+		}
+		d := denialFromLog(log)
+		if d == nil {
+			continue
+		}
+		m.denialsMu.Lock()
+		st.Lock()
+		// the cursor is only saved along with denials, not to write
+		// the state for every message of the kernel
+		if c := log.Cursor(); c != "" {
+			st.Set("sandbox-denials-cursor", c)
+		}
+		err := recordDenials(st, []*denials.Denial{d})
+		st.Unlock()
+		m.denialsMu.Unlock()
+		if err != nil {
+			logger.Noticef("cannot record sandbox denial: %v", err)
+		}
+	}
+}
+
+// stopFollowingDenials stops following the journal, if followed, and waits
+// for the goroutine to be done.
+func (m *InterfaceManager) stopFollowingDenials() {
+	m.denialsMu.Lock()
+	journal, done := m.denialsJournal, m.denialsFollowed
+	m.denialsJournal, m.denialsFollowed = nil, nil
+	m.denialsMu.Unlock()
+	if journal == nil {
+		return
+	}
+	journal.Close()
+	<-done
+}
+
+// discardDenials forgets the denials of the given snap.
+func discardDenials(st *state.State, instanceName string) error {
+	all, err := sandboxDenials(st)
+	if err != nil {
+		return err
+	}
+	if _, ok := all[instanceName]; ok {
+		delete(all, instanceName)
+		st.Set("sandbox-denials", all)
+	}
+	return nil
+}
+
+// SuggestInterfaces returns the interfaces whose apparmor rules would have
+// allowed the access of the given denial, if a plug of the snap was
+// connected to the matching implicit slot of the system snap. Only file
+// accesses denied by apparmor can be matched. The state must be locked by
+// the caller.
+func (m *InterfaceManager) SuggestInterfaces(d *denials.Denial) ([]string, error) {
+	if d.Sandbox != "apparmor" || d.Tag == "" {
+		return nil, nil
+	}
+	info, err := snapstate.CurrentInfo(m.state, d.Snap)
+	if err != nil {
+		return nil, err
+	}
+	var suggested []string
+	for _, slotInfo := range m.repo.Slots(SystemSnapName()) {
+		iface := m.repo.Interface(slotInfo.Interface)
+		if iface == nil {
+			continue
+		}
+		plugInfo := &snap.PlugInfo{
+			Snap:      info,
+			Name:      slotInfo.Interface,
+			Interface: slotInfo.Interface,
+			Apps:      info.Apps,
+			Hooks:     info.Hooks,
+		}
+		plug := interfaces.NewConnectedPlug(plugInfo, nil, nil)
+		slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+		spec := &apparmor.Specification{}
+		if err := spec.AddPermanentPlug(iface, plugInfo); err != nil {
+			continue
+		}
+		if err := spec.AddConnectedPlug(iface, plug, slot); err != nil {
+			continue
+		}
+		if denials.AppArmorAllows(spec.SnippetForTag(d.Tag), d) {
+			suggested = append(suggested, slotInfo.Interface)
+		}
+	}
+	sort.Strings(suggested)
+	return suggested, nil
+}
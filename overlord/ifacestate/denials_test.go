@@ -0,0 +1,312 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
+// journalEntries returns the journal entries with the given messages, in
+// JSON, logged a second apart.
+func journalEntries(c *C, firstCursor int, messages ...string) string {
+	var entries []string
+	for i, message := range messages {
+		entry, err := json.Marshal(map[string]string{
+			"__CURSOR":             fmt.Sprintf("c%d", firstCursor+i),
+			"__REALTIME_TIMESTAMP": fmt.Sprintf("%d", (1614592800+int64(firstCursor+i))*1000000),
+			"MESSAGE":              message,
+		})
+		c.Assert(err, IsNil)
+		entries = append(entries, string(entry))
+	}
+	return strings.Join(entries, "\n")
+}
+
+const (
+	openDenial    = `audit: type=1400 audit(1614592800.123:45): apparmor="DENIED" operation="open" profile="snap.consumer.app" name="/etc/foo" pid=1 comm="app" requested_mask="r" denied_mask="r" fsuid=0 ouid=0`
+	seccompDenial = `audit: type=1326 audit(1614592800.123:46): auid=0 uid=0 gid=0 ses=1 subj=snap.consumer.app (enforce) pid=1 comm="app" exe="/snap/consumer/1/app" sig=0 arch=c000003e syscall=165 compat=0 ip=0x7f code=0x50000`
+)
+
+func (s *interfaceManagerSuite) mockSandboxDenials(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.sandbox-denials", true)
+	tr.Commit()
+}
+
+func (s *interfaceManagerSuite) TestCollectDenials(c *C) {
+	s.mockSandboxDenials(c)
+	s.mockSnap(c, consumerYaml)
+	var cursors []string
+	logs := []string{
+		journalEntries(c, 1, openDenial, "usb 1-1: new device", seccompDenial),
+		journalEntries(c, 4, openDenial),
+		"",
+	}
+	restore := ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		cursors = append(cursors, cursor)
+		c.Check(n, Equals, 1000)
+		log := logs[0]
+		logs = logs[1:]
+		return ioutil.NopCloser(strings.NewReader(log)), nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+
+	c.Assert(mgr.CollectDenials(), IsNil)
+	s.state.Lock()
+	recent, err := ifacestate.Denials(s.state, "consumer")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Assert(recent, HasLen, 2)
+	c.Check(recent[0], DeepEquals, &denials.Denial{
+		Time:          time.Unix(1614592801, 0).UTC(),
+		Count:         1,
+		Sandbox:       "apparmor",
+		Snap:          "consumer",
+		Tag:           "snap.consumer.app",
+		Comm:          "app",
+		Operation:     "open",
+		Name:          "/etc/foo",
+		RequestedMask: "r",
+		DeniedMask:    "r",
+		Fsuid:         "0",
+	})
+	c.Check(recent[1].Sandbox, Equals, "seccomp")
+	c.Check(recent[1].Syscall, Equals, "165")
+
+	// the same denial is counted and becomes the most recent one
+	c.Assert(mgr.CollectDenials(), IsNil)
+	s.state.Lock()
+	recent, err = ifacestate.Denials(s.state, "consumer")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Assert(recent, HasLen, 2)
+	c.Check(recent[0].Sandbox, Equals, "seccomp")
+	c.Check(recent[1].Sandbox, Equals, "apparmor")
+	c.Check(recent[1].Count, Equals, 2)
+	c.Check(recent[1].Time, Equals, time.Unix(1614592804, 0).UTC())
+
+	// nothing new, the cursor is kept
+	c.Assert(mgr.CollectDenials(), IsNil)
+	c.Check(cursors, DeepEquals, []string{"", "c3", "c4"})
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsKeepsRecent(c *C) {
+	s.mockSandboxDenials(c)
+	s.mockSnap(c, consumerYaml)
+	restore := ifacestate.MockMaxDenials(2)
+	defer restore()
+	restore = ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		var messages []string
+		for i := 0; i < 3; i++ {
+			messages = append(messages, strings.Replace(openDenial, "/etc/foo", fmt.Sprintf("/etc/foo%d", i), 1))
+		}
+		return ioutil.NopCloser(strings.NewReader(journalEntries(c, 1, messages...))), nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.CollectDenials(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	recent, err := ifacestate.Denials(s.state, "consumer")
+	c.Assert(err, IsNil)
+	c.Assert(recent, HasLen, 2)
+	c.Check(recent[0].Name, Equals, "/etc/foo1")
+	c.Check(recent[1].Name, Equals, "/etc/foo2")
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsNotInstalled(c *C) {
+	s.mockSandboxDenials(c)
+	restore := ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(journalEntries(c, 1, openDenial))), nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.CollectDenials(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var all map[string]interface{}
+	c.Check(s.state.Get("sandbox-denials", &all), Equals, state.ErrNoState)
+	var cursor string
+	c.Assert(s.state.Get("sandbox-denials-cursor", &cursor), IsNil)
+	c.Check(cursor, Equals, "c1")
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsNothingCollected(c *C) {
+	s.mockSandboxDenials(c)
+	restore := ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.CollectDenials(), IsNil)
+
+	// the state is left alone
+	s.state.Lock()
+	defer s.state.Unlock()
+	var cursor string
+	c.Check(s.state.Get("sandbox-denials-cursor", &cursor), Equals, state.ErrNoState)
+	var all map[string]interface{}
+	c.Check(s.state.Get("sandbox-denials", &all), Equals, state.ErrNoState)
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsDisabled(c *C) {
+	restore := ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		c.Fatalf("unexpected journal read")
+		return nil, nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.CollectDenials(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	_, err := ifacestate.Denials(s.state, "consumer")
+	c.Check(err, Equals, ifacestate.ErrSandboxDenialsDisabled)
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsErrors(c *C) {
+	s.mockSandboxDenials(c)
+	restore := ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+	mgr := s.manager(c)
+	c.Check(mgr.CollectDenials(), ErrorMatches, "cannot read the journal: boom")
+
+	restore = ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("{")), nil
+	})
+	defer restore()
+	c.Check(mgr.CollectDenials(), ErrorMatches, "cannot decode journal entry: .*")
+}
+
+func (s *interfaceManagerSuite) TestEnsureCollectsDenials(c *C) {
+	s.mockSandboxDenials(c)
+	s.mockSnap(c, consumerYaml)
+	n := 0
+	restore := ifacestate.MockAuditLogReader(func(cursor string, _ int, follow bool) (io.ReadCloser, error) {
+		n++
+		return ioutil.NopCloser(strings.NewReader(journalEntries(c, 1, openDenial))), nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	mgr.DisableUDevMonitor()
+
+	// not yet
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(n, Equals, 0)
+
+	mgr.SetDenialsNextCollect(time.Now().Add(-time.Second))
+	c.Assert(mgr.Ensure(), IsNil)
+	mgr.WaitDenialsCollected()
+	c.Check(n, Equals, 1)
+	// and not again until the interval passed
+	c.Assert(mgr.Ensure(), IsNil)
+	mgr.WaitDenialsCollected()
+	c.Check(n, Equals, 1)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	recent, err := ifacestate.Denials(s.state, "consumer")
+	c.Assert(err, IsNil)
+	c.Check(recent, HasLen, 1)
+}
+
+func (s *interfaceManagerSuite) TestSuggestInterfaces(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("/etc/foo rw,")
+			return nil
+		},
+	}, &ifacetest.TestInterface{
+		InterfaceName: "test2",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("/etc/bar rw,")
+			return nil
+		},
+	})
+	s.mockSnap(c, `
+name: core
+version: 1
+type: os
+slots:
+ test:
+ test2:
+`)
+	s.mockSnap(c, `
+name: consumer
+version: 1
+apps:
+ app:
+`)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// reading /etc is granted by more privileged interfaces of the
+	// system snap, like system-backup
+	d := denials.Parse(strings.Replace(openDenial, `requested_mask="r"`, `requested_mask="w"`, 1), time.Now())
+	suggested, err := mgr.SuggestInterfaces(d)
+	c.Assert(err, IsNil)
+	c.Check(suggested, DeepEquals, []string{"test"})
+
+	d.Name = "/etc/baz"
+	suggested, err = mgr.SuggestInterfaces(d)
+	c.Assert(err, IsNil)
+	c.Check(suggested, HasLen, 0)
+
+	// only apparmor denials are matched
+	suggested, err = mgr.SuggestInterfaces(denials.Parse(seccompDenial, time.Now()))
+	c.Assert(err, IsNil)
+	c.Check(suggested, HasLen, 0)
+
+	d.Snap = "missing"
+	_, err = mgr.SuggestInterfaces(d)
+	c.Check(err, ErrorMatches, `snap "missing" is not installed`)
+}
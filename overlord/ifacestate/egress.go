@@ -0,0 +1,39 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"github.com/snapcore/snapd/interfaces/egress"
+)
+
+// UpdateEgressCgroups updates the egress ruleset of the given snap, if it
+// has one, with the cgroups its applications are currently tracked in.
+// This needs to happen when an application of the snap starts, as the
+// ruleset matches the processes of the snap by cgroup.
+//
+// The state must be locked by the caller.
+func (m *InterfaceManager) UpdateEgressCgroups(snapName string) error {
+	for _, backend := range m.repo.Backends() {
+		if backend, ok := backend.(*egress.Backend); ok {
+			return backend.UpdateCgroups(snapName, m.repo)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/egress"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *interfaceManagerSuite) TestUpdateEgressCgroups(c *C) {
+	nftCmd := testutil.MockCommand(c, "nft", "")
+	defer nftCmd.Restore()
+
+	s.extraBackends = []interfaces.SecurityBackend{&egress.Backend{}}
+	s.mockSnap(c, sandboxConsumerYaml)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// snaps without a ruleset are left alone
+	c.Assert(mgr.UpdateEgressCgroups("consumer"), IsNil)
+	c.Check(nftCmd.Calls(), HasLen, 0)
+
+	// the stale ruleset of a snap no longer restricted is unloaded
+	path := filepath.Join(dirs.SnapEgressDir, "snap.consumer.nft")
+	c.Assert(os.MkdirAll(dirs.SnapEgressDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte("table inet snap.consumer {}\n"), 0644), IsNil)
+	c.Assert(mgr.UpdateEgressCgroups("consumer"), IsNil)
+	c.Check(path, testutil.FileAbsent)
+	c.Check(nftCmd.Calls(), DeepEquals, [][]string{{"nft", "delete", "table", "inet", "snap.consumer"}})
+}
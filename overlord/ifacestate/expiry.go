@@ -0,0 +1,148 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var timeNow = time.Now
+
+// setConnExpiry sets when the given existing connection is disconnected
+// automatically, or that it is not if expiresAt is zero.
+func setConnExpiry(st *state.State, connID string, expiresAt time.Time) error {
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	cstate, ok := conns[connID]
+	if !ok {
+		return fmt.Errorf("internal error: cannot find connection %s", connID)
+	}
+	if expiresAt.IsZero() && cstate.ExpiresAt == nil {
+		return nil
+	}
+	cstate.ExpiryChange = ""
+	if expiresAt.IsZero() {
+		cstate.ExpiresAt = nil
+	} else {
+		cstate.ExpiresAt = &expiresAt
+		st.EnsureBefore(expiresAt.Sub(timeNow()))
+	}
+	setConns(st, conns)
+	return nil
+}
+
+// ensureExpiredConnectionsDisconnected disconnects the time-limited
+// connections that expired, connections of snaps with changes in progress
+// are disconnected once those are done.
+func (m *InterfaceManager) ensureExpiredConnectionsDisconnected() error {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(conns))
+	for id := range conns {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	now := timeNow()
+	var nextExpiry time.Time
+	var expired []string
+	var expiredConns []*connState
+	var tss []*state.TaskSet
+	for _, id := range ids {
+		cstate := conns[id]
+		if cstate.ExpiresAt == nil || cstate.Undesired {
+			continue
+		}
+		if cstate.ExpiresAt.After(now) {
+			if nextExpiry.IsZero() || cstate.ExpiresAt.Before(nextExpiry) {
+				nextExpiry = *cstate.ExpiresAt
+			}
+			continue
+		}
+
+		// a connection is not disconnected again while its change is in
+		// progress, nor after that failed until the change is pruned
+		if chg := st.Change(cstate.ExpiryChange); chg != nil && (!chg.IsReady() || chg.Status() == state.ErrorStatus) {
+			continue
+		}
+
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return err
+		}
+		if err := snapstate.CheckChangeConflictMany(st, []string{connRef.PlugRef.Snap, connRef.SlotRef.Snap}, ""); err != nil {
+			if _, ok := err.(*snapstate.ChangeConflictError); ok {
+				// retry on the next ensure
+				continue
+			}
+			return err
+		}
+
+		var ts *state.TaskSet
+		conn, err := m.repo.Connection(connRef)
+		if err != nil || cstate.HotplugGone {
+			// the connection is not active, just forget it
+			ts = forgetTasks(st, connRef)
+		} else {
+			ts, err = disconnectTasks(st, conn, disconnectOpts{})
+			if err != nil {
+				return err
+			}
+		}
+		ts.JoinLane(st.NewLane())
+		tss = append(tss, ts)
+		expired = append(expired, id)
+		expiredConns = append(expiredConns, cstate)
+		st.Warnf("connection %s expired at %s and is being disconnected", id, cstate.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if len(tss) > 0 {
+		summary := fmt.Sprintf(i18n.G("Disconnect expired connections: %s"), strings.Join(expired, ", "))
+		chg := st.NewChange("disconnect-snap", summary)
+		for _, ts := range tss {
+			chg.AddAll(ts)
+		}
+		for _, cstate := range expiredConns {
+			cstate.ExpiryChange = chg.ID()
+		}
+		setConns(st, conns)
+		st.EnsureBefore(0)
+	}
+	if !nextExpiry.IsZero() {
+		st.EnsureBefore(nextExpiry.Sub(now))
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *interfaceManagerSuite) TestConnectUntil(c *C) {
+	s.MockModel(c, nil)
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	expiresAt := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+
+	s.state.Lock()
+	ts, err := ifacestate.ConnectUntil(s.state, "consumer", "plug", "producer", "slot", expiresAt)
+	c.Assert(err, IsNil)
+	ts.Tasks()[0].Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "consumer",
+		},
+	})
+	var connectTask *state.Task
+	for _, t := range ts.Tasks() {
+		if t.Kind() == "connect" {
+			connectTask = t
+		}
+	}
+	c.Assert(connectTask, NotNil)
+	var taskExpiresAt time.Time
+	c.Assert(connectTask.Get("expires-at", &taskExpiresAt), IsNil)
+	c.Check(taskExpiresAt.Equal(expiresAt), Equals, true)
+
+	change := s.state.NewChange("connect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	c.Check(change.Status(), Equals, state.DoneStatus)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":   "test",
+			"plug-static": map[string]interface{}{"attr1": "value1"},
+			"slot-static": map[string]interface{}{"attr2": "value2"},
+			"expires-at":  expiresAt.Format(time.RFC3339),
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestConnectUntilAlreadyConnected(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
+	})
+	s.state.Unlock()
+	_ = s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the expiry of the existing connection is set, and then refreshed
+	for _, d := range []time.Duration{time.Hour, 2 * time.Hour} {
+		expiresAt := time.Now().Add(d).UTC().Truncate(time.Second)
+		_, err := ifacestate.ConnectUntil(s.state, "consumer", "plug", "producer", "slot", expiresAt)
+		c.Assert(err, FitsTypeOf, &ifacestate.ErrAlreadyConnected{})
+
+		var conns map[string]interface{}
+		c.Assert(s.state.Get("conns", &conns), IsNil)
+		c.Check(conns, DeepEquals, map[string]interface{}{
+			"consumer:plug producer:slot": map[string]interface{}{
+				"interface":  "test",
+				"expires-at": expiresAt.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
+func (s *interfaceManagerSuite) TestConnectClearsExpiry(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":     "test",
+			"expires-at":    "2021-06-01T12:00:00Z",
+			"expiry-change": "1",
+		},
+	})
+	s.state.Unlock()
+	_ = s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// connecting again makes the connection permanent
+	_, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, FitsTypeOf, &ifacestate.ErrAlreadyConnected{})
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestEnsureDisconnectsExpiredConnections(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	restore := ifacestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":  "test",
+			"expires-at": "2021-06-01T12:00:00Z",
+		},
+		"consumer:otherplug producer:otherslot": map[string]interface{}{
+			"interface": "test2",
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+
+	// not expired yet
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	s.state.Unlock()
+
+	now = now.Add(3 * time.Hour)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	chg := s.state.Changes()[0]
+	c.Check(chg.Kind(), Equals, "disconnect-snap")
+	c.Check(chg.Summary(), Equals, "Disconnect expired connections: consumer:plug producer:slot")
+	var disconnect *state.Task
+	for _, t := range chg.Tasks() {
+		if t.Kind() == "disconnect" {
+			disconnect = t
+		}
+	}
+	c.Assert(disconnect, NotNil)
+	c.Check(disconnect.Summary(), Equals, "Disconnect consumer:plug from producer:slot")
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, "connection consumer:plug producer:slot expired at 2021-06-01T12:00:00Z and is being disconnected")
+	s.state.Unlock()
+
+	// the change in progress is not duplicated
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:otherplug producer:otherslot": map[string]interface{}{
+			"interface": "test2",
+		},
+	})
+	c.Check(mgr.Repository().Interfaces().Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestEnsureDisconnectsExpiredConnectionsFailed(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":  "test",
+			"expires-at": "2021-06-01T12:00:00Z",
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	chg := s.state.Changes()[0]
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug producer:slot"], DeepEquals, map[string]interface{}{
+		"interface":     "test",
+		"expires-at":    "2021-06-01T12:00:00Z",
+		"expiry-change": chg.ID(),
+	})
+
+	// the disconnect failed
+	for _, t := range chg.Tasks() {
+		t.SetStatus(state.ErrorStatus)
+	}
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	// no other change is created nor warning issued until the failed change
+	// is pruned
+	s.state.Unlock()
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	c.Check(s.state.AllWarnings(), HasLen, 1)
+
+	s.state.Prune(time.Now(), 0, 0, 0)
+	c.Assert(s.state.Changes(), HasLen, 0)
+	s.state.Unlock()
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	c.Check(s.state.Changes()[0].ID(), Not(Equals), chg.ID())
+}
+
+func (s *interfaceManagerSuite) TestEnsureDisconnectsExpiredConnectionsConflict(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":  "test",
+			"expires-at": "2021-06-01T12:00:00Z",
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	chg := s.state.NewChange("other", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "producer"}})
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	// the connection is disconnected once the other change is done
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+
+	t.SetStatus(state.DoneStatus)
+	s.state.Unlock()
+	c.Assert(mgr.Ensure(), IsNil)
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 2)
+}
+
+func (s *interfaceManagerSuite) TestEnsureForgetsExpiredHotplugGoneConnections(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"hotplug-gone": true,
+			"hotplug-key":  "1234",
+			"expires-at":   "2021-06-01T12:00:00Z",
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	tasks := s.state.Changes()[0].Tasks()
+	c.Assert(tasks, HasLen, 1)
+	c.Check(tasks[0].Summary(), Equals, "Forget connection consumer:plug from producer:slot")
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, HasLen, 0)
+}
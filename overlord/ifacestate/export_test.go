@@ -18,6 +18,9 @@
 package ifacestate
 
 import (
+	"context"
+	"io"
+	"os/user"
 	"time"
 
 	"github.com/snapcore/snapd/interfaces"
@@ -25,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/timings"
+	userclient "github.com/snapcore/snapd/usersession/client"
 )
 
 var (
@@ -110,6 +114,14 @@ func MockUDevInitRetryTimeout(t time.Duration) (restore func()) {
 	}
 }
 
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
+
 // UpperCaseConnState returns a canned connection state map.
 // This allows us to keep connState private and still write some tests for it.
 func UpperCaseConnState() map[string]*connState {
@@ -171,3 +183,46 @@ func (m *InterfaceManager) TransitionConnectionsCoreMigration(st *state.State, o
 func (m *InterfaceManager) SetupSecurityByBackend(task *state.Task, snaps []*snap.Info, opts []interfaces.ConfinementOptions, tm timings.Measurer) error {
 	return m.setupSecurityByBackend(task, snaps, opts, tm)
 }
+
+func MockAuditLogReader(f func(cursor string, n int, follow bool) (io.ReadCloser, error)) (restore func()) {
+	old := auditLogReader
+	auditLogReader = f
+	return func() { auditLogReader = old }
+}
+
+// WaitDenialsFollowed waits for the goroutine following the journal to be
+// done, once the journal is closed.
+func (m *InterfaceManager) WaitDenialsFollowed() {
+	m.denialsMu.Lock()
+	done := m.denialsFollowed
+	m.denialsMu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func MockMaxDenials(n int) (restore func()) {
+	old := maxDenials
+	maxDenials = n
+	return func() { maxDenials = old }
+}
+
+func (m *InterfaceManager) SetDenialsNextCollect(t time.Time) {
+	m.denialsNextCollect = t
+}
+
+func (m *InterfaceManager) WaitDenialsCollected() {
+	m.waitDenialsCollected()
+}
+
+func MockUserLookupId(f func(uid string) (*user.User, error)) (restore func()) {
+	old := userLookupId
+	userLookupId = f
+	return func() { userLookupId = old }
+}
+
+func MockAsyncPromptNotification(f func(ctx context.Context, client *userclient.Client, uid int, info *userclient.PromptInfo)) (restore func()) {
+	old := asyncPromptNotification
+	asyncPromptNotification = f
+	return func() { asyncPromptNotification = old }
+}
@@ -337,7 +337,10 @@ func (m *InterfaceManager) doDiscardConns(task *state.Task, _ *tomb.Tomb) error
 	}
 	task.Set("removed", removed)
 	setConns(st, conns)
-	return nil
+	if err := discardPromptingRules(st, instanceName); err != nil {
+		return err
+	}
+	return discardDenials(st, instanceName)
 }
 
 func (m *InterfaceManager) undoDiscardConns(task *state.Task, _ *tomb.Tomb) error {
@@ -411,6 +414,10 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err := task.Get("delayed-setup-profiles", &delayedSetupProfiles); err != nil && err != state.ErrNoState {
 		return err
 	}
+	var expiresAt *time.Time
+	if err := task.Get("expires-at", &expiresAt); err != nil && err != state.ErrNoState {
+		return err
+	}
 
 	deviceCtx, err := snapstate.DeviceCtx(st, task, nil)
 	if err != nil {
@@ -510,6 +517,10 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 	if old, ok := conns[connRef.ID()]; ok && old.Undesired {
 		task.Set("old-conn", old)
 	}
+	// a time-limited connection restored by hotplug keeps its expiry
+	if old, ok := conns[connRef.ID()]; ok && old.HotplugGone && expiresAt == nil {
+		expiresAt = old.ExpiresAt
+	}
 
 	conns[connRef.ID()] = &connState{
 		Interface:        conn.Interface(),
@@ -520,6 +531,7 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		Auto:             autoConnect,
 		ByGadget:         byGadget,
 		HotplugKey:       slot.HotplugKey,
+		ExpiresAt:        expiresAt,
 	}
 	setConns(st, conns)
 
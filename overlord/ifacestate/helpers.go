@@ -26,6 +26,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/dirs"
@@ -144,7 +145,13 @@ func (m *InterfaceManager) regenerateAllSecurityProfiles(tm timings.Measurer) er
 		if err := snapstate.Get(m.state, snapName, &snapst); err != nil {
 			logger.Noticef("cannot get state of snap %q: %s", snapName, err)
 		}
-		return confinementOptions(snapst.Flags)
+		opts := confinementOptions(snapst.Flags)
+		rules, err := promptingRulesSnippet(m.state, snapName)
+		if err != nil {
+			logger.Noticef("cannot get prompting rules of snap %q: %s", snapName, err)
+		}
+		opts.PromptingRules = rules
+		return opts
 	}
 
 	// For each backend:
@@ -368,12 +375,19 @@ func (m *InterfaceManager) setupSecurityByBackend(task *state.Task, snaps []*sna
 	if len(snaps) != len(opts) {
 		return fmt.Errorf("internal error: setupSecurityByBackend received an unexpected number of snaps (expected: %d, got %d)", len(opts), len(snaps))
 	}
+	st := task.State()
 	confOpts := make(map[string]interfaces.ConfinementOptions, len(snaps))
 	for i, snapInfo := range snaps {
-		confOpts[snapInfo.InstanceName()] = opts[i]
+		instanceName := snapInfo.InstanceName()
+		rules, err := promptingRulesSnippet(st, instanceName)
+		if err != nil {
+			return err
+		}
+		snapOpts := opts[i]
+		snapOpts.PromptingRules = rules
+		confOpts[instanceName] = snapOpts
 	}
 
-	st := task.State()
 	st.Unlock()
 	defer st.Lock()
 
@@ -454,6 +468,12 @@ type connState struct {
 	// slots.
 	HotplugGone bool            `json:"hotplug-gone,omitempty"`
 	HotplugKey  snap.HotplugKey `json:"hotplug-key,omitempty"`
+	// ExpiresAt is when a time-limited connection is disconnected
+	// automatically.
+	ExpiresAt *time.Time `json:"expires-at,omitempty"`
+	// ExpiryChange is the change disconnecting the connection once it
+	// expired.
+	ExpiryChange string `json:"expiry-change,omitempty"`
 }
 
 type gadgetConnect struct {
@@ -21,6 +21,7 @@ package ifacestate
 
 import (
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -59,6 +60,15 @@ type InterfaceManager struct {
 	// maps sysfs path -> [(interface name, device key)...]
 	hotplugDevicePaths map[string][]deviceData
 
+	denialsMu          sync.Mutex
+	denialsNextCollect time.Time
+	// closed when the collection of denials started by Ensure is done
+	denialsCollected chan struct{}
+	// the journal followed for prompting, and closed when the goroutine
+	// following it is done
+	denialsJournal  io.ReadCloser
+	denialsFollowed chan struct{}
+
 	// extras
 	extraInterfaces []interfaces.Interface
 	extraBackends   []interfaces.SecurityBackend
@@ -83,6 +93,9 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 		// note: enumeratedDeviceKeys is reset to nil when enumeration is done
 		enumeratedDeviceKeys: make(map[string]map[snap.HotplugKey]bool),
 		hotplugDevicePaths:   make(map[string][]deviceData),
+		// denials are first collected by Ensure after the interval,
+		// not to slow down the startup
+		denialsNextCollect: time.Now().Add(denialsCollectInterval),
 		// extras
 		extraInterfaces: extraInterfaces,
 		extraBackends:   extraBackends,
@@ -107,6 +120,7 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	addHandler("hotplug-update-slot", m.doHotplugUpdateSlot, nil)
 	addHandler("hotplug-remove-slot", m.doHotplugRemoveSlot, nil)
 	addHandler("hotplug-disconnect", m.doHotplugDisconnect, nil)
+	addHandler("setup-prompting-profiles", m.doSetupPromptingProfiles, nil)
 
 	// don't block on hotplug-seq-wait task
 	runner.AddHandler("hotplug-seq-wait", m.doHotplugSeqWait, nil)
@@ -187,11 +201,23 @@ func (m *InterfaceManager) StartUp() error {
 
 // Ensure implements StateManager.Ensure.
 func (m *InterfaceManager) Ensure() error {
-	// do not worry about udev monitor in preseeding mode
+	// do not worry about udev monitor, denials, expired connections and
+	// prompting rules in preseeding mode
 	if m.preseed {
 		return nil
 	}
 
+	m.ensureDenialsCollected()
+	m.ensureDenialsFollowed()
+
+	if err := m.ensureExpiredConnectionsDisconnected(); err != nil {
+		logger.Noticef("cannot disconnect expired connections: %v", err)
+	}
+
+	if err := m.ensurePromptingRules(); err != nil {
+		logger.Noticef("cannot apply prompting rules: %v", err)
+	}
+
 	if m.udevMonitorDisabled {
 		return nil
 	}
@@ -220,9 +246,12 @@ func (m *InterfaceManager) Ensure() error {
 	return nil
 }
 
-// Stop implements StateStopper. It stops the udev monitor,
-// if running.
+// Stop implements StateStopper. It waits for the denials being collected,
+// stops following the journal and the udev monitor, if running.
 func (m *InterfaceManager) Stop() {
+	m.waitDenialsCollected()
+	m.stopFollowingDenials()
+
 	m.udevMonMu.Lock()
 	udevMon := m.udevMon
 	m.udevMonMu.Unlock()
@@ -84,16 +84,45 @@ type connectOpts struct {
 	AutoConnect bool
 
 	DelayedSetupProfiles bool
+
+	// ExpiresAt is when the connection is disconnected
+	// automatically, if set.
+	ExpiresAt time.Time
 }
 
 // Connect returns a set of tasks for connecting an interface.
-//
+// If the interface is already connected, the connection is not disconnected
+// automatically anymore and ErrAlreadyConnected is returned.
 func Connect(st *state.State, plugSnap, plugName, slotSnap, slotName string) (*state.TaskSet, error) {
 	if err := snapstate.CheckChangeConflictMany(st, []string{plugSnap, slotSnap}, ""); err != nil {
 		return nil, err
 	}
 
-	return connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{})
+	ts, err := connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{})
+	if alreadyConnected, ok := err.(*ErrAlreadyConnected); ok {
+		if err := setConnExpiry(st, alreadyConnected.Connection.ID(), time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+	return ts, err
+}
+
+// ConnectUntil returns a set of tasks for connecting an interface until the
+// given time, once expired the connection is disconnected automatically.
+// If the interface is already connected, the expiry of the connection is set
+// to the given time and ErrAlreadyConnected is returned.
+func ConnectUntil(st *state.State, plugSnap, plugName, slotSnap, slotName string, expiresAt time.Time) (*state.TaskSet, error) {
+	if err := snapstate.CheckChangeConflictMany(st, []string{plugSnap, slotSnap}, ""); err != nil {
+		return nil, err
+	}
+
+	ts, err := connect(st, plugSnap, plugName, slotSnap, slotName, connectOpts{ExpiresAt: expiresAt})
+	if alreadyConnected, ok := err.(*ErrAlreadyConnected); ok {
+		if err := setConnExpiry(st, alreadyConnected.Connection.ID(), expiresAt); err != nil {
+			return nil, err
+		}
+	}
+	return ts, err
 }
 
 func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, flags connectOpts) (*state.TaskSet, error) {
@@ -208,6 +237,9 @@ func connect(st *state.State, plugSnap, plugName, slotSnap, slotName string, fla
 	if flags.DelayedSetupProfiles {
 		connectInterface.Set("delayed-setup-profiles", true)
 	}
+	if !flags.ExpiresAt.IsZero() {
+		connectInterface.Set("expires-at", flags.ExpiresAt)
+	}
 
 	// Expose a copy of all plug and slot attributes coming from yaml to interface hooks. The hooks will be able
 	// to modify them but all attributes will be checked against assertions after the hooks are run.
@@ -531,6 +563,7 @@ func delayedCrossMgrInit() {
 		// hook into conflict checks mechanisms
 		snapstate.AddAffectedSnapsByKind("connect", connectDisconnectAffectedSnaps)
 		snapstate.AddAffectedSnapsByKind("disconnect", connectDisconnectAffectedSnaps)
+		snapstate.AddAffectedSnapsByKind("setup-prompting-profiles", promptingProfilesAffectedSnaps)
 	})
 }
 
@@ -3766,6 +3766,10 @@ func (s *interfaceManagerSuite) testDoDiscardConns(c *C, snapName string) {
 			"interface": "test",
 		},
 	})
+	s.state.Set("sandbox-denials", map[string]interface{}{
+		"consumer": []interface{}{map[string]interface{}{"sandbox": "apparmor", "snap": "consumer"}},
+		"producer": []interface{}{map[string]interface{}{"sandbox": "apparmor", "snap": "producer"}},
+	})
 
 	// Store empty snap state. This snap has an empty sequence now.
 	s.state.Unlock()
@@ -3805,6 +3809,13 @@ func (s *interfaceManagerSuite) testDoDiscardConns(c *C, snapName string) {
 	c.Check(removed, DeepEquals, map[string]interface{}{
 		"consumer:plug producer:slot": map[string]interface{}{"interface": "test"},
 	})
+
+	// The denials of the snap were forgotten
+	var denials map[string]interface{}
+	err = s.state.Get("sandbox-denials", &denials)
+	c.Assert(err, IsNil)
+	c.Check(denials, HasLen, 1)
+	c.Check(denials[snapName], IsNil)
 }
 
 func (s *interfaceManagerSuite) testUndoDiscardConns(c *C, snapName string) {
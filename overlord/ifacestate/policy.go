@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+// PolicyDecisions holds how the policy of the declarations decides on a
+// plug and a slot, for debugging purposes.
+type PolicyDecisions struct {
+	Plug interfaces.PlugRef
+	Slot interfaces.SlotRef
+
+	// PlugInstallation and SlotInstallation are the decisions about
+	// installing the snaps with the plug and the slot, they are nil
+	// for snaps without a snap-declaration.
+	PlugInstallation *policy.Decision
+	SlotInstallation *policy.Decision
+
+	// Connection is the decision about connecting the plug to the
+	// slot. It is only enforced if both snaps have a snap-declaration.
+	Connection *policy.Decision
+	// AutoConnection is the decision about auto-connecting the plug
+	// to the slot.
+	AutoConnection *policy.Decision
+	// InterfaceAutoConnect is whether the interface itself lets the
+	// plug auto-connect to the slot, which is needed on top of the
+	// auto-connection being allowed.
+	InterfaceAutoConnect bool
+
+	// Unasserted lists the snaps without a snap-declaration, which
+	// were installed with --dangerous.
+	Unasserted []string
+}
+
+// ExplainConnectionPolicy evaluates the policy of the declarations for the
+// given plug and slot and returns the deciding rules.
+//
+// The state must be locked by the caller.
+func (m *InterfaceManager) ExplainConnectionPolicy(plug *snap.PlugInfo, slot *snap.SlotInfo) (*PolicyDecisions, error) {
+	st := m.state
+
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	modelAs := deviceCtx.Model()
+
+	var storeAs *asserts.Store
+	if modelAs.Store() != "" {
+		storeAs, err = assertstate.Store(st, modelAs.Store())
+		if err != nil && !asserts.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	baseDecl, err := assertstate.BaseDeclaration(st)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot find base declaration: %v", err)
+	}
+
+	decisions := &PolicyDecisions{
+		Plug: interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name},
+		Slot: interfaces.SlotRef{Snap: slot.Snap.InstanceName(), Name: slot.Name},
+	}
+
+	var plugDecl *asserts.SnapDeclaration
+	if plug.Snap.SnapID != "" {
+		plugDecl, err = assertstate.SnapDeclaration(st, plug.Snap.SnapID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find snap declaration for %q: %v", plug.Snap.InstanceName(), err)
+		}
+		ic := policy.InstallCandidate{
+			Snap:            plug.Snap,
+			SnapDeclaration: plugDecl,
+			BaseDeclaration: baseDecl,
+			Model:           modelAs,
+			Store:           storeAs,
+		}
+		decisions.PlugInstallation = ic.ExplainPlug(plug)
+	} else {
+		decisions.Unasserted = append(decisions.Unasserted, decisions.Plug.Snap)
+	}
+
+	var slotDecl *asserts.SnapDeclaration
+	if slot.Snap.SnapID != "" {
+		slotDecl, err = assertstate.SnapDeclaration(st, slot.Snap.SnapID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find snap declaration for %q: %v", slot.Snap.InstanceName(), err)
+		}
+		ic := policy.InstallCandidate{
+			Snap:            slot.Snap,
+			SnapDeclaration: slotDecl,
+			BaseDeclaration: baseDecl,
+			Model:           modelAs,
+			Store:           storeAs,
+		}
+		decisions.SlotInstallation = ic.ExplainSlot(slot)
+	} else if decisions.Slot.Snap != decisions.Plug.Snap {
+		decisions.Unasserted = append(decisions.Unasserted, decisions.Slot.Snap)
+	}
+
+	// like in auto-connection, only the static attributes are known
+	cc := policy.ConnectCandidate{
+		Plug:                interfaces.NewConnectedPlug(plug, nil, nil),
+		PlugSnapDeclaration: plugDecl,
+		Slot:                interfaces.NewConnectedSlot(slot, nil, nil),
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     baseDecl,
+		Model:               modelAs,
+		Store:               storeAs,
+	}
+	if decisions.Connection, err = cc.ExplainConnection(); err != nil {
+		return nil, err
+	}
+	if decisions.AutoConnection, err = cc.ExplainAutoConnection(); err != nil {
+		return nil, err
+	}
+	if iface := m.repo.Interface(plug.Interface); iface != nil {
+		decisions.InterfaceAutoConnect = iface.AutoConnect(plug, slot)
+	}
+
+	return decisions, nil
+}
@@ -0,0 +1,119 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+)
+
+func (s *interfaceManagerSuite) mockExplainPolicyBaseDecl(c *C) {
+	restore := assertstest.MockBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+slots:
+  test:
+    allow-connection:
+      plug-publisher-id:
+        - $SLOT_PUBLISHER_ID
+    deny-auto-connection:
+      plug-attributes:
+        attr1: value1
+`))
+	s.AddCleanup(restore)
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+}
+
+func (s *interfaceManagerSuite) TestExplainConnectionPolicy(c *C) {
+	s.MockModel(c, nil)
+	s.mockExplainPolicyBaseDecl(c)
+	s.MockSnapDecl(c, "consumer", "consumer-publisher", nil)
+	consumer := s.mockSnap(c, consumerYaml)
+	s.MockSnapDecl(c, "producer", "producer-publisher", nil)
+	producer := s.mockSnap(c, producerYaml)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	decisions, err := mgr.ExplainConnectionPolicy(consumer.Plugs["plug"], producer.Slots["slot"])
+	c.Assert(err, IsNil)
+	c.Check(decisions, DeepEquals, &ifacestate.PolicyDecisions{
+		Plug: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		Slot: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+		// there is no plug rule for the interface
+		PlugInstallation: &policy.Decision{
+			Allowed:     true,
+			Alternative: -1,
+		},
+		SlotInstallation: &policy.Decision{
+			Allowed:     true,
+			Declaration: "base-declaration",
+			Side:        "slot",
+			Constraint:  "allow-installation",
+			Alternative: 0,
+		},
+		Connection: &policy.Decision{
+			Declaration: "base-declaration",
+			Side:        "slot",
+			Constraint:  "allow-connection",
+			Alternative: -1,
+			Reason:      `plug publisher id does not match`,
+		},
+		AutoConnection: &policy.Decision{
+			Declaration: "base-declaration",
+			Side:        "slot",
+			Constraint:  "deny-auto-connection",
+			Alternative: 0,
+		},
+		InterfaceAutoConnect: true,
+	})
+}
+
+func (s *interfaceManagerSuite) TestExplainConnectionPolicyUnasserted(c *C) {
+	s.MockModel(c, nil)
+	s.mockExplainPolicyBaseDecl(c)
+	consumer := s.mockSnap(c, consumer2Yaml)
+	s.MockSnapDecl(c, "producer", "producer-publisher", nil)
+	producer := s.mockSnap(c, producerYaml)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	decisions, err := mgr.ExplainConnectionPolicy(consumer.Plugs["plug"], producer.Slots["slot"])
+	c.Assert(err, IsNil)
+	c.Check(decisions.Unasserted, DeepEquals, []string{"consumer2"})
+	c.Check(decisions.PlugInstallation, IsNil)
+	c.Check(decisions.SlotInstallation, NotNil)
+	c.Check(decisions.Connection, DeepEquals, &policy.Decision{
+		Declaration: "base-declaration",
+		Side:        "slot",
+		Constraint:  "allow-connection",
+		Alternative: -1,
+		Reason:      `plug publisher id does not match`,
+	})
+}
@@ -0,0 +1,739 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/denials"
+	"github.com/snapcore/snapd/strutil"
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
+var (
+	// promptingOnceDuration is how long the decisions of users about a
+	// single access are remembered.
+	promptingOnceDuration = 5 * time.Minute
+	// maxPrompts is the number of prompts waiting for a reply, the least
+	// recent ones are dropped.
+	maxPrompts = 100
+
+	userLookupId = user.LookupId
+)
+
+// asyncPromptNotification notifies the user about a prompt in a goroutine.
+//
+// This allows the communication with the session agent of the user to be
+// performed without holding the state lock.
+var asyncPromptNotification = func(ctx context.Context, client *userclient.Client, uid int, info *userclient.PromptInfo) {
+	go func() {
+		if err := client.PromptNotification(ctx, uid, info); err != nil {
+			logger.Noticef("Cannot send notification about prompt: %v", err)
+		}
+	}()
+}
+
+var (
+	// ErrNoPrompt is returned when there is no prompt with the given ID
+	// for the user.
+	ErrNoPrompt = errors.New("cannot find prompt")
+	// ErrNoPromptingRule is returned when there is no prompting rule with
+	// the given ID for the user.
+	ErrNoPromptingRule = errors.New("cannot find prompting rule")
+	// ErrPromptingDisabled is returned when prompting is not enabled.
+	ErrPromptingDisabled = errors.New("experimental feature disabled - test it by setting 'experimental.prompting' to true")
+)
+
+// PromptReplyError is returned when the reply of a user to a prompt is not
+// valid.
+type PromptReplyError struct {
+	ID  string
+	Err error
+}
+
+func (e *PromptReplyError) Error() string {
+	return fmt.Sprintf("cannot reply to prompt %s: %v", e.ID, e.Err)
+}
+
+// Prompt is a file access of a snap denied by apparmor, waiting for the
+// user the process runs as to allow or deny it. The prompt is built from
+// the denial logged to the journal, the access failed already and the
+// decision of the user applies when the snap tries again.
+type Prompt struct {
+	ID          string    `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	User        int       `json:"user"`
+	Snap        string    `json:"snap"`
+	Path        string    `json:"path"`
+	Permissions []string  `json:"permissions"`
+}
+
+// PromptingRule is the decision of a user about the accesses of a snap to
+// the paths matching an apparmor glob.
+type PromptingRule struct {
+	ID          string     `json:"id"`
+	Timestamp   time.Time  `json:"timestamp"`
+	User        int        `json:"user"`
+	Snap        string     `json:"snap"`
+	PathPattern string     `json:"path-pattern"`
+	Permissions []string   `json:"permissions"`
+	Outcome     string     `json:"outcome"`
+	Lifespan    string     `json:"lifespan"`
+	Expiration  *time.Time `json:"expiration,omitempty"`
+}
+
+func (r *PromptingRule) expired(now time.Time) bool {
+	return r.Expiration != nil && !r.Expiration.After(now)
+}
+
+// covers returns whether the rule decides the given accesses of the snap,
+// by the given user, to the given path.
+func (r *PromptingRule) covers(uid int, snapName, path string, permissions []string) bool {
+	if r.User != uid || r.Snap != snapName {
+		return false
+	}
+	for _, perm := range permissions {
+		if !strutil.ListContains(r.Permissions, perm) {
+			return false
+		}
+	}
+	matches, err := denials.AppArmorGlobMatches(r.PathPattern, path)
+	return err == nil && matches
+}
+
+// PromptReply is the decision of a user about a prompt.
+type PromptReply struct {
+	Outcome     string
+	Lifespan    string
+	PathPattern string
+	Permissions []string
+}
+
+func promptingEnabled(st *state.State) (bool, error) {
+	tr := config.NewTransaction(st)
+	enabled, err := features.Flag(tr, features.Prompting)
+	if err != nil && !config.IsNoOption(err) {
+		return false, err
+	}
+	return enabled, nil
+}
+
+func checkPromptingEnabled(st *state.State) error {
+	enabled, err := promptingEnabled(st)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrPromptingDisabled
+	}
+	return nil
+}
+
+func getPrompts(st *state.State) ([]*Prompt, error) {
+	var prompts []*Prompt
+	err := st.Get("prompts", &prompts)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("internal error: cannot get prompts: %v", err)
+	}
+	return prompts, nil
+}
+
+func getPromptingRules(st *state.State) ([]*PromptingRule, error) {
+	var rules []*PromptingRule
+	err := st.Get("prompting-rules", &rules)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("internal error: cannot get prompting-rules: %v", err)
+	}
+	return rules, nil
+}
+
+func nextPromptingID(st *state.State) (string, error) {
+	var lastID int
+	err := st.Get("prompting-last-id", &lastID)
+	if err != nil && err != state.ErrNoState {
+		return "", fmt.Errorf("internal error: cannot get prompting-last-id: %v", err)
+	}
+	lastID++
+	st.Set("prompting-last-id", lastID)
+	return strconv.Itoa(lastID), nil
+}
+
+// setupPromptingProfilesPending records that the security profiles of the
+// given snap need to be set up again for the prompting rules to apply, by
+// the next ensure.
+func setupPromptingProfilesPending(st *state.State, instanceName string) error {
+	var pending []string
+	err := st.Get("prompting-profiles-pending", &pending)
+	if err != nil && err != state.ErrNoState {
+		return fmt.Errorf("internal error: cannot get prompting-profiles-pending: %v", err)
+	}
+	if !strutil.ListContains(pending, instanceName) {
+		pending = append(pending, instanceName)
+	}
+	st.Set("prompting-profiles-pending", pending)
+	return nil
+}
+
+// promptPermissions returns the permissions users are prompted for, given
+// the mask of accesses requested by the process. Executing and mapping
+// files, and links, are not prompted for.
+func promptPermissions(requestedMask string) []string {
+	var permissions []string
+	for _, r := range requestedMask {
+		var perm string
+		switch r {
+		case 'r':
+			perm = "read"
+		case 'w', 'a', 'c', 'd', 'k':
+			perm = "write"
+		default:
+			return nil
+		}
+		if !strutil.ListContains(permissions, perm) {
+			permissions = append(permissions, perm)
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// userDirs returns the directories of the given user for which prompting
+// applies, with trailing slashes.
+func userDirs(u *user.User) []string {
+	return []string{
+		filepath.Clean(u.HomeDir) + "/",
+		"/media/" + u.Username + "/",
+		"/run/media/" + u.Username + "/",
+	}
+}
+
+func inUserDirs(u *user.User, path string) bool {
+	for _, dir := range userDirs(u) {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// addPrompts adds the prompts for the file accesses of the given denials,
+// unless a prompting rule already decided them, and returns the new ones.
+// Accesses to the same path waiting for a reply are merged in the same
+// prompt.
+func addPrompts(st *state.State, collected []*denials.Denial) ([]*Prompt, error) {
+	prompts, err := getPrompts(st)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return nil, err
+	}
+
+	now := timeNow()
+	var added []*Prompt
+	var changed bool
+denialsLoop:
+	for _, d := range collected {
+		if d.Sandbox != "apparmor" || d.Tag == "" || !filepath.IsAbs(d.Name) {
+			continue
+		}
+		permissions := promptPermissions(d.RequestedMask)
+		if len(permissions) == 0 {
+			continue
+		}
+		uid, err := strconv.Atoi(d.Fsuid)
+		if err != nil {
+			continue
+		}
+		u, err := userLookupId(d.Fsuid)
+		if err != nil {
+			logger.Debugf("cannot find user %s of denial: %v", d.Fsuid, err)
+			continue
+		}
+		if !inUserDirs(u, d.Name) {
+			continue
+		}
+		for _, rule := range rules {
+			if !rule.expired(now) && rule.covers(uid, d.Snap, d.Name, permissions) {
+				continue denialsLoop
+			}
+		}
+		for _, p := range prompts {
+			if p.User == uid && p.Snap == d.Snap && p.Path == d.Name {
+				for _, perm := range permissions {
+					if !strutil.ListContains(p.Permissions, perm) {
+						p.Permissions = append(p.Permissions, perm)
+						sort.Strings(p.Permissions)
+						changed = true
+					}
+				}
+				continue denialsLoop
+			}
+		}
+		id, err := nextPromptingID(st)
+		if err != nil {
+			return nil, err
+		}
+		p := &Prompt{
+			ID:          id,
+			Timestamp:   now,
+			User:        uid,
+			Snap:        d.Snap,
+			Path:        d.Name,
+			Permissions: permissions,
+		}
+		prompts = append(prompts, p)
+		added = append(added, p)
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	if len(prompts) > maxPrompts {
+		prompts = prompts[len(prompts)-maxPrompts:]
+	}
+	st.Set("prompts", prompts)
+	return added, nil
+}
+
+// notifyPrompts notifies the users about the given prompts.
+func notifyPrompts(prompts []*Prompt) {
+	for _, p := range prompts {
+		asyncPromptNotification(context.TODO(), userclient.New(), p.User, &userclient.PromptInfo{
+			ID:          p.ID,
+			Snap:        p.Snap,
+			Path:        p.Path,
+			Permissions: p.Permissions,
+		})
+	}
+}
+
+// Prompts returns the prompts waiting for a reply of the given user, or of
+// all users for root. It returns ErrPromptingDisabled unless prompting is
+// enabled. The state must be locked by the caller.
+func Prompts(st *state.State, uid int) ([]*Prompt, error) {
+	if err := checkPromptingEnabled(st); err != nil {
+		return nil, err
+	}
+	prompts, err := getPrompts(st)
+	if err != nil {
+		return nil, err
+	}
+	var res []*Prompt
+	for _, p := range prompts {
+		if uid == 0 || p.User == uid {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+// PromptingRules returns the prompting rules of the given user, or of all
+// users for root. The state must be locked by the caller.
+func PromptingRules(st *state.State, uid int) ([]*PromptingRule, error) {
+	if err := checkPromptingEnabled(st); err != nil {
+		return nil, err
+	}
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return nil, err
+	}
+	now := timeNow()
+	var res []*PromptingRule
+	for _, r := range rules {
+		if (uid == 0 || r.User == uid) && !r.expired(now) {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+// validatePathPattern checks that the path pattern of a reply is an apparmor
+// glob safe to use in a file rule, matching only paths in the directories of
+// the given user.
+func validatePathPattern(pattern string, u *user.User) error {
+	if !filepath.IsAbs(pattern) {
+		return fmt.Errorf("path pattern %q must be absolute", pattern)
+	}
+	depth := 0
+	for _, c := range pattern {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			return fmt.Errorf("path pattern %q cannot contain ',' outside of alternations", pattern)
+		case strings.ContainsRune("\"#@\\\x00", c) || c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			return fmt.Errorf("path pattern %q cannot contain %q", pattern, c)
+		}
+	}
+	for _, component := range strings.Split(pattern, "/") {
+		if component == ".." {
+			return fmt.Errorf("path pattern %q cannot contain \"..\"", pattern)
+		}
+	}
+	if _, err := denials.AppArmorGlobMatches(pattern, "/"); err != nil {
+		return fmt.Errorf("invalid path pattern: %v", err)
+	}
+	prefix := pattern
+	if i := strings.IndexAny(pattern, "*?[{"); i >= 0 {
+		prefix = pattern[:i]
+	}
+	if !inUserDirs(u, prefix) {
+		return fmt.Errorf("path pattern %q must be within %s", pattern, strings.Join(userDirs(u), ", "))
+	}
+	return nil
+}
+
+func validatePromptReply(reply *PromptReply, p *Prompt, u *user.User) error {
+	switch reply.Outcome {
+	case "allow", "deny":
+	default:
+		return fmt.Errorf("invalid outcome %q", reply.Outcome)
+	}
+	switch reply.Lifespan {
+	case "once", "always":
+	default:
+		return fmt.Errorf("invalid lifespan %q", reply.Lifespan)
+	}
+	for _, perm := range reply.Permissions {
+		if perm != "read" && perm != "write" {
+			return fmt.Errorf("invalid permission %q", perm)
+		}
+	}
+	for _, perm := range p.Permissions {
+		if !strutil.ListContains(reply.Permissions, perm) {
+			return fmt.Errorf("permissions must include %q", perm)
+		}
+	}
+	if err := validatePathPattern(reply.PathPattern, u); err != nil {
+		return err
+	}
+	if matches, _ := denials.AppArmorGlobMatches(reply.PathPattern, p.Path); !matches {
+		return fmt.Errorf("path pattern %q does not match %q", reply.PathPattern, p.Path)
+	}
+	return nil
+}
+
+// ReplyToPrompt records the decision of the given user about one of their
+// prompts as a prompting rule, and returns it. Decisions with a lifespan of
+// once are remembered for a short time only. Allowing an access reloads the
+// apparmor profiles of the snap, and so does the expiry of the rule when
+// allowing once. The state must be locked by the caller.
+func ReplyToPrompt(st *state.State, uid int, id string, reply *PromptReply) (*PromptingRule, error) {
+	if err := checkPromptingEnabled(st); err != nil {
+		return nil, err
+	}
+	prompts, err := getPrompts(st)
+	if err != nil {
+		return nil, err
+	}
+	var p *Prompt
+	for _, other := range prompts {
+		if other.ID == id && (uid == 0 || other.User == uid) {
+			p = other
+			break
+		}
+	}
+	if p == nil {
+		return nil, ErrNoPrompt
+	}
+	u, err := userLookupId(strconv.Itoa(p.User))
+	if err != nil {
+		return nil, fmt.Errorf("cannot find user %d of prompt: %v", p.User, err)
+	}
+	if err := validatePromptReply(reply, p, u); err != nil {
+		return nil, &PromptReplyError{ID: id, Err: err}
+	}
+
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return nil, err
+	}
+	ruleID, err := nextPromptingID(st)
+	if err != nil {
+		return nil, err
+	}
+	now := timeNow()
+	var permissions []string
+	for _, perm := range reply.Permissions {
+		if !strutil.ListContains(permissions, perm) {
+			permissions = append(permissions, perm)
+		}
+	}
+	sort.Strings(permissions)
+	rule := &PromptingRule{
+		ID:          ruleID,
+		Timestamp:   now,
+		User:        p.User,
+		Snap:        p.Snap,
+		PathPattern: reply.PathPattern,
+		Permissions: permissions,
+		Outcome:     reply.Outcome,
+		Lifespan:    reply.Lifespan,
+	}
+	if reply.Lifespan == "once" {
+		expiration := now.Add(promptingOnceDuration)
+		rule.Expiration = &expiration
+	}
+	rules = append(rules, rule)
+	st.Set("prompting-rules", rules)
+
+	// the rule decides the other accesses of the snap it covers too
+	remaining := make([]*Prompt, 0, len(prompts))
+	for _, other := range prompts {
+		if other != p && !rule.covers(other.User, other.Snap, other.Path, other.Permissions) {
+			remaining = append(remaining, other)
+		}
+	}
+	st.Set("prompts", remaining)
+
+	if rule.Outcome == "allow" {
+		if err := setupPromptingProfilesPending(st, rule.Snap); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// RevokePromptingRule removes the given prompting rule of the given user.
+// The state must be locked by the caller.
+func RevokePromptingRule(st *state.State, uid int, id string) error {
+	if err := checkPromptingEnabled(st); err != nil {
+		return err
+	}
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return err
+	}
+	for i, rule := range rules {
+		if rule.ID != id || (uid != 0 && rule.User != uid) {
+			continue
+		}
+		rules = append(rules[:i], rules[i+1:]...)
+		st.Set("prompting-rules", rules)
+		if rule.Outcome == "allow" {
+			return setupPromptingProfilesPending(st, rule.Snap)
+		}
+		return nil
+	}
+	return ErrNoPromptingRule
+}
+
+// discardPromptingRules forgets the prompts and prompting rules of the
+// given snap.
+func discardPromptingRules(st *state.State, instanceName string) error {
+	prompts, err := getPrompts(st)
+	if err != nil {
+		return err
+	}
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return err
+	}
+	remainingPrompts := make([]*Prompt, 0, len(prompts))
+	for _, p := range prompts {
+		if p.Snap != instanceName {
+			remainingPrompts = append(remainingPrompts, p)
+		}
+	}
+	remainingRules := make([]*PromptingRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Snap != instanceName {
+			remainingRules = append(remainingRules, rule)
+		}
+	}
+	if len(remainingPrompts) != len(prompts) {
+		st.Set("prompts", remainingPrompts)
+	}
+	if len(remainingRules) != len(rules) {
+		st.Set("prompting-rules", remainingRules)
+	}
+	return nil
+}
+
+// promptingRulesSnippet returns the apparmor file rules granting the
+// accesses allowed by users to the given snap, when prompting is enabled.
+func promptingRulesSnippet(st *state.State, instanceName string) (string, error) {
+	enabled, err := promptingEnabled(st)
+	if err != nil || !enabled {
+		return "", err
+	}
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return "", err
+	}
+	now := timeNow()
+	var b strings.Builder
+	for _, rule := range rules {
+		if rule.Snap != instanceName || rule.Outcome != "allow" || rule.expired(now) {
+			continue
+		}
+		var perms string
+		if strutil.ListContains(rule.Permissions, "read") {
+			perms += "r"
+		}
+		if strutil.ListContains(rule.Permissions, "write") {
+			perms += "wk"
+		}
+		// owner restricts the rule to the files of the user who allowed
+		// the access
+		fmt.Fprintf(&b, "owner %s %s,\n", rule.PathPattern, perms)
+	}
+	return b.String(), nil
+}
+
+// ensurePromptingRules removes the expired prompting rules and sets up the
+// security profiles of the snaps whose allowed accesses changed, once the
+// changes in progress for those are done.
+func (m *InterfaceManager) ensurePromptingRules() error {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return err
+	}
+	now := timeNow()
+	var nextExpiry time.Time
+	remaining := make([]*PromptingRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.expired(now) {
+			remaining = append(remaining, rule)
+			if rule.Expiration != nil && (nextExpiry.IsZero() || rule.Expiration.Before(nextExpiry)) {
+				nextExpiry = *rule.Expiration
+			}
+			continue
+		}
+		if rule.Outcome == "allow" {
+			if err := setupPromptingProfilesPending(st, rule.Snap); err != nil {
+				return err
+			}
+		}
+	}
+	if len(remaining) != len(rules) {
+		st.Set("prompting-rules", remaining)
+	}
+	if !nextExpiry.IsZero() {
+		st.EnsureBefore(nextExpiry.Sub(now))
+	}
+
+	var pending []string
+	err = st.Get("prompting-profiles-pending", &pending)
+	if err != nil && err != state.ErrNoState {
+		return fmt.Errorf("internal error: cannot get prompting-profiles-pending: %v", err)
+	}
+	var retry []string
+	for _, instanceName := range pending {
+		if err := snapstate.CheckChangeConflict(st, instanceName, nil); err != nil {
+			if _, ok := err.(*snapstate.ChangeConflictError); ok {
+				// retry on the next ensure
+				retry = append(retry, instanceName)
+				continue
+			}
+			return err
+		}
+		summary := fmt.Sprintf(i18n.G("Setup security profiles of snap %q for prompting rules"), instanceName)
+		task := st.NewTask("setup-prompting-profiles", summary)
+		task.Set("snap-name", instanceName)
+		chg := st.NewChange("setup-prompting-profiles", summary)
+		chg.AddTask(task)
+		st.EnsureBefore(0)
+	}
+	if len(pending) > 0 {
+		st.Set("prompting-profiles-pending", retry)
+	}
+	return nil
+}
+
+func promptingProfilesAffectedSnaps(t *state.Task) ([]string, error) {
+	var instanceName string
+	if err := t.Get("snap-name", &instanceName); err != nil {
+		return nil, fmt.Errorf("internal error: cannot obtain snap name from task: %s", t.Summary())
+	}
+	return []string{instanceName}, nil
+}
+
+func (m *InterfaceManager) doSetupPromptingProfiles(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	perfTimings := state.TimingsForTask(task)
+	defer perfTimings.Save(st)
+
+	var instanceName string
+	if err := task.Get("snap-name", &instanceName); err != nil {
+		return err
+	}
+	var snapst snapstate.SnapState
+	err := snapstate.Get(st, instanceName, &snapst)
+	if err == state.ErrNoState {
+		// the snap was removed in the meantime
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	snapInfo, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	opts := confinementOptions(snapst.Flags)
+	opts.PromptingRules, err = promptingRulesSnippet(st, instanceName)
+	if err != nil {
+		return err
+	}
+
+	// the prompting rules only change the apparmor profiles, the other
+	// security backends are left alone
+	for _, backend := range m.repo.Backends() {
+		if backend.Name() != interfaces.SecurityAppArmor {
+			continue
+		}
+		st.Unlock()
+		err := backend.Setup(snapInfo, opts, m.repo, perfTimings)
+		st.Lock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
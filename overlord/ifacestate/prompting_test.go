@@ -0,0 +1,581 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/user"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
+func homeDenial(name, requestedMask string, fsuid int) string {
+	return fmt.Sprintf(`audit: type=1400 audit(1614592800.123:45): apparmor="DENIED" operation="open" profile="snap.consumer.app" name="%s" pid=1 comm="app" requested_mask="%s" denied_mask="%s" fsuid=%d ouid=%d`, name, requestedMask, requestedMask, fsuid, fsuid)
+}
+
+func (s *interfaceManagerSuite) mockPrompting(c *C) (notified *[]*userclient.PromptInfo) {
+	s.state.Lock()
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.prompting", true)
+	tr.Commit()
+	s.state.Unlock()
+
+	s.AddCleanup(ifacestate.MockUserLookupId(func(uid string) (*user.User, error) {
+		switch uid {
+		case "1000":
+			return &user.User{Uid: "1000", Username: "alice", HomeDir: "/home/alice"}, nil
+		case "1001":
+			return &user.User{Uid: "1001", Username: "bob", HomeDir: "/home/bob"}, nil
+		}
+		return nil, user.UnknownUserIdError(0)
+	}))
+	// the journal is followed by Ensure
+	s.AddCleanup(ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}))
+	notified = &[]*userclient.PromptInfo{}
+	s.AddCleanup(ifacestate.MockAsyncPromptNotification(func(ctx context.Context, client *userclient.Client, uid int, info *userclient.PromptInfo) {
+		c.Check(uid, Equals, 1000)
+		*notified = append(*notified, info)
+	}))
+	return notified
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsAddsPrompts(c *C) {
+	notified := s.mockPrompting(c)
+	s.mockSnap(c, consumerYaml)
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	restore := ifacestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	logs := []string{
+		journalEntries(c, 1,
+			homeDenial("/home/alice/doc.txt", "r", 1000),
+			// outside of the directories of the user
+			homeDenial("/etc/foo", "r", 1000),
+			homeDenial("/home/bob/doc.txt", "r", 1000),
+			// executing is not prompted for
+			homeDenial("/home/alice/bin/tool", "x", 1000),
+			// unknown user
+			homeDenial("/home/eve/doc.txt", "r", 1002),
+			homeDenial("/media/alice/usb/photo.jpg", "rw", 1000),
+		),
+		// merged with the prompt waiting for a reply
+		journalEntries(c, 7, homeDenial("/home/alice/doc.txt", "a", 1000)),
+	}
+	restore = ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		log := logs[0]
+		logs = logs[1:]
+		return ioutil.NopCloser(strings.NewReader(log)), nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.CollectDenials(), IsNil)
+	c.Assert(mgr.CollectDenials(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	prompts, err := ifacestate.Prompts(s.state, 1000)
+	c.Assert(err, IsNil)
+	c.Check(prompts, DeepEquals, []*ifacestate.Prompt{{
+		ID:          "1",
+		Timestamp:   now,
+		User:        1000,
+		Snap:        "consumer",
+		Path:        "/home/alice/doc.txt",
+		Permissions: []string{"read", "write"},
+	}, {
+		ID:          "2",
+		Timestamp:   now,
+		User:        1000,
+		Snap:        "consumer",
+		Path:        "/media/alice/usb/photo.jpg",
+		Permissions: []string{"read", "write"},
+	}})
+	c.Check(*notified, DeepEquals, []*userclient.PromptInfo{
+		{ID: "1", Snap: "consumer", Path: "/home/alice/doc.txt", Permissions: []string{"read"}},
+		{ID: "2", Snap: "consumer", Path: "/media/alice/usb/photo.jpg", Permissions: []string{"read", "write"}},
+	})
+
+	// other users do not see the prompts
+	prompts, err = ifacestate.Prompts(s.state, 1001)
+	c.Assert(err, IsNil)
+	c.Check(prompts, HasLen, 0)
+	prompts, err = ifacestate.Prompts(s.state, 0)
+	c.Assert(err, IsNil)
+	c.Check(prompts, HasLen, 2)
+}
+
+func (s *interfaceManagerSuite) TestEnsureFollowsDenials(c *C) {
+	notified := s.mockPrompting(c)
+	s.mockSnap(c, consumerYaml)
+
+	var journals []*io.PipeWriter
+	var cursors []string
+	restore := ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		c.Check(follow, Equals, true)
+		cursors = append(cursors, cursor)
+		r, w := io.Pipe()
+		journals = append(journals, w)
+		return r, nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+	// and only once
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Assert(journals, HasLen, 1)
+
+	_, err := io.WriteString(journals[0], journalEntries(c, 1, "usb 1-1: new device"))
+	c.Assert(err, IsNil)
+	_, err = io.WriteString(journals[0], journalEntries(c, 2, homeDenial("/home/alice/doc.txt", "r", 1000)))
+	c.Assert(err, IsNil)
+	// the goroutine stops when journalctl exits, and is started again
+	// from the last denial by Ensure
+	c.Assert(journals[0].Close(), IsNil)
+	mgr.WaitDenialsFollowed()
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Assert(journals, HasLen, 2)
+	c.Check(cursors, DeepEquals, []string{"", "c2"})
+
+	s.state.Lock()
+	prompts, err := ifacestate.Prompts(s.state, 1000)
+	c.Assert(err, IsNil)
+	c.Check(prompts, HasLen, 1)
+	c.Check(*notified, HasLen, 1)
+
+	// following stops once prompting is disabled
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.prompting", false)
+	tr.Commit()
+	s.state.Unlock()
+	c.Assert(mgr.Ensure(), IsNil)
+	_, err = io.WriteString(journals[1], journalEntries(c, 3, homeDenial("/home/alice/other.txt", "r", 1000)))
+	c.Check(err, Equals, io.ErrClosedPipe)
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsWhileFollowing(c *C) {
+	s.mockPrompting(c)
+	s.mockSnap(c, consumerYaml)
+
+	var journal *io.PipeWriter
+	n := 0
+	restore := ifacestate.MockAuditLogReader(func(cursor string, _ int, follow bool) (io.ReadCloser, error) {
+		n++
+		r, w := io.Pipe()
+		journal = w
+		return r, nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(n, Equals, 1)
+	// the denials are recorded as they are logged
+	c.Assert(mgr.CollectDenials(), IsNil)
+	c.Check(n, Equals, 1)
+
+	mgr.Stop()
+	_, err := io.WriteString(journal, "{}")
+	c.Check(err, Equals, io.ErrClosedPipe)
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsNoPromptsWhenDisabled(c *C) {
+	s.mockSandboxDenials(c)
+	s.mockSnap(c, consumerYaml)
+	restore := ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(journalEntries(c, 1, homeDenial("/home/alice/doc.txt", "r", 1000)))), nil
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.CollectDenials(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var prompts []*ifacestate.Prompt
+	c.Check(s.state.Get("prompts", &prompts), Equals, state.ErrNoState)
+
+	_, err := ifacestate.Prompts(s.state, 0)
+	c.Check(err, Equals, ifacestate.ErrPromptingDisabled)
+	_, err = ifacestate.PromptingRules(s.state, 0)
+	c.Check(err, Equals, ifacestate.ErrPromptingDisabled)
+	_, err = ifacestate.ReplyToPrompt(s.state, 0, "1", &ifacestate.PromptReply{})
+	c.Check(err, ErrorMatches, `experimental feature disabled - test it by setting 'experimental.prompting' to true`)
+	c.Check(ifacestate.RevokePromptingRule(s.state, 0, "1"), Equals, ifacestate.ErrPromptingDisabled)
+}
+
+func (s *interfaceManagerSuite) TestCollectDenialsNoPromptsWithRules(c *C) {
+	notified := s.mockPrompting(c)
+	s.mockSnap(c, consumerYaml)
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	restore := ifacestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	restore = ifacestate.MockAuditLogReader(func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(journalEntries(c, 1,
+			homeDenial("/home/alice/Documents/a.txt", "r", 1000),
+			homeDenial("/home/alice/Documents/b.txt", "w", 1000),
+		))), nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	expiration := now.Add(time.Minute)
+	s.state.Set("prompting-rules", []*ifacestate.PromptingRule{{
+		ID:          "1",
+		User:        1000,
+		Snap:        "consumer",
+		PathPattern: "/home/alice/Documents/**",
+		Permissions: []string{"read"},
+		Outcome:     "deny",
+		Lifespan:    "once",
+		Expiration:  &expiration,
+	}})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.CollectDenials(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	// the rule does not decide writing
+	prompts, err := ifacestate.Prompts(s.state, 1000)
+	c.Assert(err, IsNil)
+	c.Assert(prompts, HasLen, 1)
+	c.Check(prompts[0].Path, Equals, "/home/alice/Documents/b.txt")
+	c.Check(*notified, HasLen, 1)
+}
+
+func (s *interfaceManagerSuite) mockPrompts(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("prompts", []*ifacestate.Prompt{{
+		ID:          "1",
+		User:        1000,
+		Snap:        "consumer",
+		Path:        "/home/alice/Documents/a.txt",
+		Permissions: []string{"read"},
+	}, {
+		ID:          "2",
+		User:        1000,
+		Snap:        "consumer",
+		Path:        "/home/alice/Documents/b.txt",
+		Permissions: []string{"read"},
+	}, {
+		ID:          "3",
+		User:        1001,
+		Snap:        "consumer",
+		Path:        "/home/bob/c.txt",
+		Permissions: []string{"read"},
+	}})
+	s.state.Set("prompting-last-id", 3)
+}
+
+func (s *interfaceManagerSuite) TestReplyToPrompt(c *C) {
+	s.mockPrompting(c)
+	s.mockPrompts(c)
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	restore := ifacestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	rule, err := ifacestate.ReplyToPrompt(s.state, 1000, "1", &ifacestate.PromptReply{
+		Outcome:     "allow",
+		Lifespan:    "always",
+		PathPattern: "/home/alice/Documents/**",
+		Permissions: []string{"read", "read"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(rule, DeepEquals, &ifacestate.PromptingRule{
+		ID:          "4",
+		Timestamp:   now,
+		User:        1000,
+		Snap:        "consumer",
+		PathPattern: "/home/alice/Documents/**",
+		Permissions: []string{"read"},
+		Outcome:     "allow",
+		Lifespan:    "always",
+	})
+
+	// the other prompt decided by the rule is gone too
+	prompts, err := ifacestate.Prompts(s.state, 0)
+	c.Assert(err, IsNil)
+	c.Assert(prompts, HasLen, 1)
+	c.Check(prompts[0].ID, Equals, "3")
+
+	rules, err := ifacestate.PromptingRules(s.state, 1000)
+	c.Assert(err, IsNil)
+	c.Check(rules, DeepEquals, []*ifacestate.PromptingRule{rule})
+	rules, err = ifacestate.PromptingRules(s.state, 1001)
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+
+	var pending []string
+	c.Assert(s.state.Get("prompting-profiles-pending", &pending), IsNil)
+	c.Check(pending, DeepEquals, []string{"consumer"})
+}
+
+func (s *interfaceManagerSuite) TestReplyToPromptDenyOnce(c *C) {
+	s.mockPrompting(c)
+	s.mockPrompts(c)
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	restore := ifacestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	rule, err := ifacestate.ReplyToPrompt(s.state, 1000, "1", &ifacestate.PromptReply{
+		Outcome:     "deny",
+		Lifespan:    "once",
+		PathPattern: "/home/alice/Documents/a.txt",
+		Permissions: []string{"read"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(rule.Expiration, NotNil)
+	c.Check(rule.Expiration.Equal(now.Add(5*time.Minute)), Equals, true)
+
+	prompts, err := ifacestate.Prompts(s.state, 1000)
+	c.Assert(err, IsNil)
+	c.Assert(prompts, HasLen, 1)
+	c.Check(prompts[0].ID, Equals, "2")
+
+	// denying does not change the profiles
+	var pending []string
+	c.Check(s.state.Get("prompting-profiles-pending", &pending), Equals, state.ErrNoState)
+
+	// the rule is forgotten once expired
+	now = now.Add(5 * time.Minute)
+	rules, err := ifacestate.PromptingRules(s.state, 1000)
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestReplyToPromptErrors(c *C) {
+	s.mockPrompting(c)
+	s.mockPrompts(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// prompts of other users cannot be replied to
+	_, err := ifacestate.ReplyToPrompt(s.state, 1001, "1", &ifacestate.PromptReply{})
+	c.Check(err, Equals, ifacestate.ErrNoPrompt)
+	_, err = ifacestate.ReplyToPrompt(s.state, 1000, "42", &ifacestate.PromptReply{})
+	c.Check(err, Equals, ifacestate.ErrNoPrompt)
+
+	for _, t := range []struct {
+		reply *ifacestate.PromptReply
+		err   string
+	}{
+		{&ifacestate.PromptReply{Outcome: "maybe", Lifespan: "always", PathPattern: "/home/alice/**", Permissions: []string{"read"}}, `invalid outcome "maybe"`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "forever", PathPattern: "/home/alice/**", Permissions: []string{"read"}}, `invalid lifespan "forever"`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/**", Permissions: []string{"read", "execute"}}, `invalid permission "execute"`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/**", Permissions: []string{"write"}}, `permissions must include "read"`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "home/alice/**", Permissions: []string{"read"}}, `path pattern "home/alice/\*\*" must be absolute`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/** rwx, /**", Permissions: []string{"read"}}, `path pattern ".*" cannot contain ' '`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/**,\n/etc/**", Permissions: []string{"read"}}, `path pattern ".*" cannot contain ',' outside of alternations`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/@{HOME}/**", Permissions: []string{"read"}}, `path pattern ".*" cannot contain '@'`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/../**", Permissions: []string{"read"}}, `path pattern ".*" cannot contain ".."`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/{Documents", Permissions: []string{"read"}}, `invalid path pattern: unbalanced alternation in ".*"`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/**", Permissions: []string{"read"}}, `path pattern "/home/\*\*" must be within /home/alice/, /media/alice/, /run/media/alice/`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice*/**", Permissions: []string{"read"}}, `path pattern ".*" must be within .*`},
+		{&ifacestate.PromptReply{Outcome: "allow", Lifespan: "always", PathPattern: "/home/alice/Music/**", Permissions: []string{"read"}}, `path pattern "/home/alice/Music/\*\*" does not match "/home/alice/Documents/a.txt"`},
+	} {
+		_, err := ifacestate.ReplyToPrompt(s.state, 1000, "1", t.reply)
+		c.Check(err, ErrorMatches, "cannot reply to prompt 1: "+t.err, Commentf("%q", t.reply.PathPattern))
+		c.Check(err, FitsTypeOf, &ifacestate.PromptReplyError{})
+	}
+
+	// nothing changed
+	prompts, err := ifacestate.Prompts(s.state, 0)
+	c.Assert(err, IsNil)
+	c.Check(prompts, HasLen, 3)
+	rules, err := ifacestate.PromptingRules(s.state, 0)
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestRevokePromptingRule(c *C) {
+	s.mockPrompting(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("prompting-rules", []*ifacestate.PromptingRule{{
+		ID:          "1",
+		User:        1000,
+		Snap:        "consumer",
+		PathPattern: "/home/alice/**",
+		Permissions: []string{"read"},
+		Outcome:     "allow",
+		Lifespan:    "always",
+	}, {
+		ID:          "2",
+		User:        1000,
+		Snap:        "consumer",
+		PathPattern: "/home/alice/.ssh/**",
+		Permissions: []string{"read"},
+		Outcome:     "deny",
+		Lifespan:    "always",
+	}})
+
+	c.Check(ifacestate.RevokePromptingRule(s.state, 1001, "1"), Equals, ifacestate.ErrNoPromptingRule)
+	c.Check(ifacestate.RevokePromptingRule(s.state, 1000, "3"), Equals, ifacestate.ErrNoPromptingRule)
+
+	c.Assert(ifacestate.RevokePromptingRule(s.state, 1000, "2"), IsNil)
+	var pending []string
+	c.Check(s.state.Get("prompting-profiles-pending", &pending), Equals, state.ErrNoState)
+
+	// root can revoke the rules of any user
+	c.Assert(ifacestate.RevokePromptingRule(s.state, 0, "1"), IsNil)
+	c.Assert(s.state.Get("prompting-profiles-pending", &pending), IsNil)
+	c.Check(pending, DeepEquals, []string{"consumer"})
+
+	rules, err := ifacestate.PromptingRules(s.state, 0)
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+}
+
+// runTasks runs the tasks ready to run, as settling does not converge while
+// the denials are collected often for prompting.
+func (s *interfaceManagerSuite) runTasks(c *C) {
+	runner := s.o.TaskRunner()
+	c.Assert(runner.Ensure(), IsNil)
+	runner.Wait()
+}
+
+func (s *interfaceManagerSuite) TestEnsureSetsUpPromptingProfiles(c *C) {
+	s.mockPrompting(c)
+	s.mockSnap(c, consumerYaml)
+	// only the apparmor profiles are set up
+	s.secBackend.BackendName = interfaces.SecurityAppArmor
+	otherBackend := &ifacetest.TestSecurityBackend{BackendName: "other"}
+	s.extraBackends = []interfaces.SecurityBackend{otherBackend}
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	restore := ifacestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	expiration := now.Add(time.Minute)
+	s.state.Set("prompting-rules", []*ifacestate.PromptingRule{{
+		ID:          "1",
+		User:        1000,
+		Snap:        "consumer",
+		PathPattern: "/home/alice/Documents/**",
+		Permissions: []string{"read", "write"},
+		Outcome:     "allow",
+		Lifespan:    "always",
+	}, {
+		ID:          "2",
+		User:        1000,
+		Snap:        "consumer",
+		PathPattern: "/home/alice/Music/*.mp3",
+		Permissions: []string{"read"},
+		Outcome:     "allow",
+		Lifespan:    "once",
+		Expiration:  &expiration,
+	}, {
+		ID:          "3",
+		User:        1000,
+		Snap:        "consumer",
+		PathPattern: "/home/alice/.ssh/**",
+		Permissions: []string{"read"},
+		Outcome:     "deny",
+		Lifespan:    "always",
+	}})
+	s.state.Set("prompting-profiles-pending", []string{"consumer"})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	otherBackend.SetupCalls = nil
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	chg := s.state.Changes()[0]
+	c.Check(chg.Kind(), Equals, "setup-prompting-profiles")
+	c.Check(chg.Summary(), Equals, `Setup security profiles of snap "consumer" for prompting rules`)
+	var pending []string
+	c.Assert(s.state.Get("prompting-profiles-pending", &pending), IsNil)
+	c.Check(pending, HasLen, 0)
+	s.state.Unlock()
+
+	s.runTasks(c)
+
+	s.state.Lock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	s.state.Unlock()
+	c.Assert(s.secBackend.SetupCalls, HasLen, 1)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.InstanceName(), Equals, "consumer")
+	c.Check(s.secBackend.SetupCalls[0].Options.PromptingRules, Equals, "owner /home/alice/Documents/** rwk,\nowner /home/alice/Music/*.mp3 r,\n")
+
+	// the rule allowing once expires
+	now = now.Add(time.Minute)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	rules, err := ifacestate.PromptingRules(s.state, 0)
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 2)
+	c.Assert(s.state.Changes(), HasLen, 2)
+	s.state.Unlock()
+
+	s.runTasks(c)
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[1].Options.PromptingRules, Equals, "owner /home/alice/Documents/** rwk,\n")
+	c.Check(otherBackend.SetupCalls, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestEnsureSetsUpPromptingProfilesConflict(c *C) {
+	s.mockPrompting(c)
+	s.mockSnap(c, consumerYaml)
+
+	s.state.Lock()
+	s.state.Set("prompting-profiles-pending", []string{"consumer"})
+	chg := s.state.NewChange("other", "")
+	t := s.state.NewTask("link-snap", "")
+	t.Set("snap-setup", map[string]interface{}{"side-info": map[string]interface{}{"name": "consumer"}})
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 1)
+	var pending []string
+	c.Assert(s.state.Get("prompting-profiles-pending", &pending), IsNil)
+	c.Check(pending, DeepEquals, []string{"consumer"})
+}
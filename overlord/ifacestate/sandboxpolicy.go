@@ -0,0 +1,222 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/egress"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/polkit"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// SandboxSnippet is a part of the sandbox policy of a snap along with the
+// plug or slot, and possibly the connection, it comes from.
+type SandboxSnippet struct {
+	Backend interfaces.SecuritySystem
+	// Tag is the security tag of the app or hook the snippet applies
+	// to, or the name of the snap-wide profile for the snippets of the
+	// mount namespace and of the kernel modules, or of the polkit
+	// policy file.
+	Tag string
+
+	Interface  string
+	Plug       *interfaces.PlugRef
+	Slot       *interfaces.SlotRef
+	Connection *interfaces.ConnRef
+
+	Snippet string
+	// Missing lists the lines of the snippet that cannot be found in
+	// the installed profile, when comparing against it.
+	Missing []string
+}
+
+// SandboxPolicy returns the snippets making up the sandbox policy of the
+// given security tags of the given snap, as generated by the interfaces,
+// tagged with their origin.
+//
+// The snippets of the base templates and of the layouts are not included.
+//
+// With diff, the apparmor, seccomp and landlock snippets are compared line
+// by line against the installed profiles. As the backends merge some
+// snippets and expand parametric ones, lines can be reported as missing
+// while the profile grants them in another form.
+func (m *InterfaceManager) SandboxPolicy(snapName string, securityTags []string, diff bool) ([]*SandboxSnippet, error) {
+	var result []*SandboxSnippet
+	for _, backend := range m.repo.Backends() {
+		parts, err := m.repo.SnapSpecificationParts(backend.Name(), snapName)
+		if err != nil {
+			return nil, err
+		}
+		var snippets []*SandboxSnippet
+		controlsDeviceCgroup := false
+		for _, part := range parts {
+			add := func(tag, snippet string) {
+				if snippet == "" {
+					return
+				}
+				snippets = append(snippets, &SandboxSnippet{
+					Backend:    backend.Name(),
+					Tag:        tag,
+					Interface:  part.Interface,
+					Plug:       part.Plug,
+					Slot:       part.Slot,
+					Connection: part.Connection,
+					Snippet:    snippet,
+				})
+			}
+			switch spec := part.Spec.(type) {
+			case *apparmor.Specification:
+				for _, tag := range securityTags {
+					add(tag, spec.SnippetForTag(tag))
+				}
+				add(fmt.Sprintf("snap-update-ns.%s", snapName), strings.Join(spec.UpdateNS(), "\n"))
+			case *seccomp.Specification:
+				for _, tag := range securityTags {
+					add(tag, strings.TrimSuffix(spec.SnippetForTag(tag), "\n"))
+				}
+			case *udev.Specification:
+				if spec.ControlsDeviceCgroup() {
+					controlsDeviceCgroup = true
+				}
+				for _, tag := range securityTags {
+					add(tag, strings.Join(spec.SnippetsForTag(tag), "\n"))
+				}
+			case *mount.Specification:
+				add(fmt.Sprintf("snap.%s.fstab", snapName), mountEntriesSnippet(spec.MountEntries()))
+				add(fmt.Sprintf("snap.%s.user-fstab", snapName), mountEntriesSnippet(spec.UserMountEntries()))
+			case *landlock.Specification:
+				for _, tag := range securityTags {
+					rules := spec.RulesForTag(tag)
+					lines := make([]string, 0, len(rules))
+					for _, rule := range rules {
+						lines = append(lines, rule.String())
+					}
+					add(tag, strings.Join(lines, "\n"))
+				}
+			case *egress.Specification:
+				for _, tag := range securityTags {
+					destinations, _ := spec.DestinationsForTag(tag)
+					lines := make([]string, 0, len(destinations))
+					for _, d := range destinations {
+						lines = append(lines, d.String())
+					}
+					add(tag, strings.Join(lines, "\n"))
+				}
+			case *polkit.Specification:
+				policies := spec.Policies()
+				suffixes := make([]string, 0, len(policies))
+				for suffix := range policies {
+					suffixes = append(suffixes, suffix)
+				}
+				sort.Strings(suffixes)
+				for _, suffix := range suffixes {
+					add(fmt.Sprintf("snap.%s.%s.policy", snapName, suffix), string(policies[suffix]))
+				}
+			case *kmod.Specification:
+				modules := make([]string, 0, len(spec.Modules()))
+				for module := range spec.Modules() {
+					modules = append(modules, module)
+				}
+				sort.Strings(modules)
+				add(fmt.Sprintf("snap.%s.conf", snapName), strings.Join(modules, "\n"))
+			}
+		}
+		// when the device cgroup is controlled by an interface no
+		// udev rules are generated for the snap
+		if controlsDeviceCgroup {
+			snippets = nil
+		}
+		result = append(result, snippets...)
+	}
+
+	if diff {
+		if err := findMissingSandboxLines(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func mountEntriesSnippet(entries []osutil.MountEntry) string {
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, entry.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// installedProfilePath returns the path of the installed profile of the
+// given backend and security tag, if it can be compared against.
+func installedProfilePath(backend interfaces.SecuritySystem, tag string) (string, bool) {
+	switch backend {
+	case interfaces.SecurityAppArmor:
+		return filepath.Join(dirs.SnapAppArmorDir, tag), true
+	case interfaces.SecuritySecComp:
+		return filepath.Join(dirs.SnapSeccompDir, tag+".src"), true
+	case interfaces.SecurityLandlock:
+		return filepath.Join(dirs.SnapLandlockDir, tag), true
+	}
+	return "", false
+}
+
+// findMissingSandboxLines sets the lines of the snippets not found in the
+// installed profiles.
+func findMissingSandboxLines(snippets []*SandboxSnippet) error {
+	installed := make(map[string]map[string]bool)
+	for _, snippet := range snippets {
+		path, ok := installedProfilePath(snippet.Backend, snippet.Tag)
+		if !ok {
+			continue
+		}
+		lines, ok := installed[path]
+		if !ok {
+			content, err := ioutil.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("cannot read installed profile: %v", err)
+			}
+			lines = make(map[string]bool)
+			for _, line := range strings.Split(string(content), "\n") {
+				lines[strings.TrimSpace(line)] = true
+			}
+			installed[path] = lines
+		}
+		for _, line := range strings.Split(snippet.Snippet, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !lines[line] {
+				snippet.Missing = append(snippet.Missing, line)
+			}
+		}
+	}
+	return nil
+}
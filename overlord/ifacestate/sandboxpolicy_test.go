@@ -0,0 +1,325 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/egress"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/landlock"
+	"github.com/snapcore/snapd/interfaces/polkit"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/snap"
+)
+
+// specBackend is a test backend using the specification of a real backend.
+type specBackend struct {
+	ifacetest.TestSecurityBackend
+	newSpec func() interfaces.Specification
+}
+
+func (b *specBackend) NewSpecification() interfaces.Specification {
+	return b.newSpec()
+}
+
+var sandboxConsumerYaml = `
+name: consumer
+version: 1
+apps:
+ app:
+  plugs: [plug]
+ other:
+plugs:
+ plug:
+  interface: test
+`
+
+var sandboxProducerYaml = `
+name: producer
+version: 1
+slots:
+ slot:
+  interface: test
+`
+
+func (s *interfaceManagerSuite) mockSandboxPolicy(c *C) *ifacestate.InterfaceManager {
+	s.extraBackends = []interfaces.SecurityBackend{
+		&specBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecurityAppArmor},
+			newSpec:             func() interfaces.Specification { return &apparmor.Specification{} },
+		},
+		&specBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecuritySecComp},
+			newSpec:             func() interfaces.Specification { return &seccomp.Specification{} },
+		},
+		&specBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecurityKMod},
+			newSpec:             func() interfaces.Specification { return &kmod.Specification{} },
+		},
+	}
+	s.mockIfaces(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		AppArmorPermanentPlugCallback: func(spec *apparmor.Specification, plug *snap.PlugInfo) error {
+			spec.AddSnippet("/permanent r,")
+			spec.AddUpdateNS("/update-ns rw,")
+			return nil
+		},
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("/connected r,\n/other rw,")
+			return nil
+		},
+		SecCompConnectedPlugCallback: func(spec *seccomp.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("bind")
+			return nil
+		},
+		KModPermanentSlotCallback: func(spec *kmod.Specification, slot *snap.SlotInfo) error {
+			return spec.AddModule("module")
+		},
+	})
+	s.mockSnap(c, sandboxConsumerYaml)
+	s.mockSnap(c, sandboxProducerYaml)
+	mgr := s.manager(c)
+
+	_, err := mgr.Repository().Connect(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+	return mgr
+}
+
+func (s *interfaceManagerSuite) TestSandboxPolicy(c *C) {
+	mgr := s.mockSandboxPolicy(c)
+
+	plugRef := &interfaces.PlugRef{Snap: "consumer", Name: "plug"}
+	connRef := &interfaces.ConnRef{
+		PlugRef: *plugRef,
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	snippets, err := mgr.SandboxPolicy("consumer", []string{"snap.consumer.app", "snap.consumer.other"}, false)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, []*ifacestate.SandboxSnippet{{
+		Backend:   interfaces.SecurityAppArmor,
+		Tag:       "snap.consumer.app",
+		Interface: "test",
+		Plug:      plugRef,
+		Snippet:   "/permanent r,",
+	}, {
+		Backend:   interfaces.SecurityAppArmor,
+		Tag:       "snap-update-ns.consumer",
+		Interface: "test",
+		Plug:      plugRef,
+		Snippet:   "/update-ns rw,",
+	}, {
+		Backend:    interfaces.SecurityAppArmor,
+		Tag:        "snap.consumer.app",
+		Interface:  "test",
+		Plug:       plugRef,
+		Connection: connRef,
+		Snippet:    "/connected r,\n/other rw,",
+	}, {
+		Backend:    interfaces.SecuritySecComp,
+		Tag:        "snap.consumer.app",
+		Interface:  "test",
+		Plug:       plugRef,
+		Connection: connRef,
+		Snippet:    "bind",
+	}})
+
+	snippets, err = mgr.SandboxPolicy("producer", nil, false)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, []*ifacestate.SandboxSnippet{{
+		Backend:   interfaces.SecurityKMod,
+		Tag:       "snap.producer.conf",
+		Interface: "test",
+		Slot:      &interfaces.SlotRef{Snap: "producer", Name: "slot"},
+		Snippet:   "module",
+	}})
+}
+
+func (s *interfaceManagerSuite) TestSandboxPolicyDiff(c *C) {
+	mgr := s.mockSandboxPolicy(c)
+
+	c.Assert(os.MkdirAll(dirs.SnapAppArmorDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapAppArmorDir, "snap.consumer.app"), []byte(`
+profile "snap.consumer.app" {
+  /permanent r,
+  /connected r,
+}
+`), 0644), IsNil)
+
+	snippets, err := mgr.SandboxPolicy("consumer", []string{"snap.consumer.app"}, true)
+	c.Assert(err, IsNil)
+	c.Assert(snippets, HasLen, 4)
+	c.Check(snippets[0].Missing, IsNil)
+	// there is no installed snap-update-ns profile
+	c.Check(snippets[1].Missing, DeepEquals, []string{"/update-ns rw,"})
+	c.Check(snippets[2].Missing, DeepEquals, []string{"/other rw,"})
+	c.Check(snippets[3].Missing, DeepEquals, []string{"bind"})
+}
+
+func (s *interfaceManagerSuite) TestSandboxPolicyControlsDeviceCgroup(c *C) {
+	s.extraBackends = []interfaces.SecurityBackend{
+		&specBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecurityUDev},
+			newSpec:             func() interfaces.Specification { return &udev.Specification{} },
+		},
+	}
+	s.mockIfaces(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		UDevPermanentPlugCallback: func(spec *udev.Specification, plug *snap.PlugInfo) error {
+			spec.TagDevice(`KERNEL=="foo"`)
+			return nil
+		},
+		UDevConnectedPlugCallback: func(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.SetControlsDeviceCgroup()
+			return nil
+		},
+	})
+	s.mockSnap(c, sandboxConsumerYaml)
+	s.mockSnap(c, sandboxProducerYaml)
+	mgr := s.manager(c)
+
+	snippets, err := mgr.SandboxPolicy("consumer", []string{"snap.consumer.app"}, false)
+	c.Assert(err, IsNil)
+	c.Assert(snippets, HasLen, 1)
+	c.Check(snippets[0].Backend, Equals, interfaces.SecurityUDev)
+	c.Check(snippets[0].Snippet, Matches, `(?s)# test\nKERNEL=="foo", TAG\+="snap_consumer_app".*`)
+
+	_, err = mgr.Repository().Connect(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	snippets, err = mgr.SandboxPolicy("consumer", []string{"snap.consumer.app"}, false)
+	c.Assert(err, IsNil)
+	c.Check(snippets, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestSandboxPolicyLandlock(c *C) {
+	s.extraBackends = []interfaces.SecurityBackend{
+		&specBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecurityLandlock},
+			newSpec:             func() interfaces.Specification { return &landlock.Specification{} },
+		},
+	}
+	s.mockIfaces(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		AppArmorPermanentPlugCallback: func(spec *apparmor.Specification, plug *snap.PlugInfo) error {
+			spec.AddSnippet("/etc/foo/** r,\n@{HOME}/.foo rw,")
+			return nil
+		},
+	})
+	s.mockSnap(c, sandboxConsumerYaml)
+	s.mockSnap(c, sandboxProducerYaml)
+	mgr := s.manager(c)
+
+	c.Assert(os.MkdirAll(dirs.SnapLandlockDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapLandlockDir, "snap.consumer.app"), []byte("r /etc/foo\n"), 0644), IsNil)
+
+	snippets, err := mgr.SandboxPolicy("consumer", []string{"snap.consumer.app"}, true)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, []*ifacestate.SandboxSnippet{{
+		Backend:   interfaces.SecurityLandlock,
+		Tag:       "snap.consumer.app",
+		Interface: "test",
+		Plug:      &interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		Snippet:   "rw ${SNAP_REAL_HOME}/.foo\nr /etc/foo",
+		Missing:   []string{"rw ${SNAP_REAL_HOME}/.foo"},
+	}})
+}
+
+func (s *interfaceManagerSuite) TestSandboxPolicyEgress(c *C) {
+	s.extraBackends = []interfaces.SecurityBackend{
+		&specBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecurityEgress},
+			newSpec:             func() interfaces.Specification { return &egress.Specification{} },
+		},
+	}
+	s.mockIfaces(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		EgressPermanentPlugCallback: func(spec *egress.Specification, plug *snap.PlugInfo) error {
+			spec.AddDestinations([]egress.Destination{{Host: "192.0.2.0/24"}, {Host: "example.com", Port: 443}})
+			return nil
+		},
+	})
+	s.mockSnap(c, sandboxConsumerYaml)
+	s.mockSnap(c, sandboxProducerYaml)
+	mgr := s.manager(c)
+
+	snippets, err := mgr.SandboxPolicy("consumer", []string{"snap.consumer.app", "snap.consumer.other"}, false)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, []*ifacestate.SandboxSnippet{{
+		Backend:   interfaces.SecurityEgress,
+		Tag:       "snap.consumer.app",
+		Interface: "test",
+		Plug:      &interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		Snippet:   "192.0.2.0/24\nexample.com:443",
+	}})
+}
+
+func (s *interfaceManagerSuite) TestSandboxPolicyPolkit(c *C) {
+	s.extraBackends = []interfaces.SecurityBackend{
+		&specBackend{
+			TestSecurityBackend: ifacetest.TestSecurityBackend{BackendName: interfaces.SecurityPolkit},
+			newSpec:             func() interfaces.Specification { return &polkit.Specification{} },
+		},
+	}
+	s.mockIfaces(c, &ifacetest.TestInterface{
+		InterfaceName: "test",
+		PolkitPermanentPlugCallback: func(spec *polkit.Specification, plug *snap.PlugInfo) error {
+			if err := spec.AddPolicy("plug.foo", polkit.Policy("<policyconfig/>")); err != nil {
+				return err
+			}
+			return spec.AddPolicy("plug.bar", polkit.Policy("<policyconfig></policyconfig>"))
+		},
+	})
+	s.mockSnap(c, sandboxConsumerYaml)
+	s.mockSnap(c, sandboxProducerYaml)
+	mgr := s.manager(c)
+
+	snippets, err := mgr.SandboxPolicy("consumer", []string{"snap.consumer.app"}, false)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, []*ifacestate.SandboxSnippet{{
+		Backend:   interfaces.SecurityPolkit,
+		Tag:       "snap.consumer.plug.bar.policy",
+		Interface: "test",
+		Plug:      &interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		Snippet:   "<policyconfig></policyconfig>",
+	}, {
+		Backend:   interfaces.SecurityPolkit,
+		Tag:       "snap.consumer.plug.foo.policy",
+		Interface: "test",
+		Plug:      &interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		Snippet:   "<policyconfig/>",
+	}})
+}
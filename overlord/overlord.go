@@ -198,6 +198,9 @@ func New(restartBehavior RestartBehavior) (*Overlord, error) {
 	sto := o.newStoreWithContext(storeCtx)
 
 	snapstate.ReplaceStore(s, sto)
+	snapstate.ReplaceStoreBackendFactory(s, func(storeURL *url.URL) snapstate.StoreService {
+		return o.newStoreBackend(storeURL, storeCtx)
+	})
 
 	return o, nil
 }
@@ -305,6 +308,27 @@ func (o *Overlord) newStoreWithContext(storeCtx store.DeviceAndAuthContext) snap
 	return sto
 }
 
+// backendStoreContext ties a store backend to the device and auth context
+// of the default store without letting a proxy store override the
+// backend URL.
+type backendStoreContext struct {
+	store.DeviceAndAuthContext
+}
+
+func (backendStoreContext) ProxyStoreParams(defaultURL *url.URL) (proxyStoreID string, proxyStoreURL *url.URL, err error) {
+	return "", defaultURL, nil
+}
+
+// newStoreBackend makes the stores for the configured store backends.
+func (o *Overlord) newStoreBackend(storeURL *url.URL, storeCtx store.DeviceAndAuthContext) snapstate.StoreService {
+	cfg := store.DefaultConfig()
+	cfg.Proxy = o.proxyConf
+	cfg.StoreBaseURL = storeURL
+	sto := storeNew(cfg, backendStoreContext{storeCtx})
+	sto.SetCacheDownloads(defaultCachedDownloads)
+	return sto
+}
+
 // newStore can make new stores for use during remodeling.
 // The device backend will tie them to the remodeling device state.
 func (o *Overlord) newStore(devBE storecontext.DeviceBackend) snapstate.StoreService {
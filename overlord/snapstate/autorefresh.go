@@ -23,6 +23,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/httputil"
@@ -56,8 +58,15 @@ var (
 	CanAutoRefresh        func(st *state.State) (bool, error)
 	CanManageRefreshes    func(st *state.State) bool
 	IsOnMeteredConnection func() (bool, error)
+	BatteryStatus         func() (onBattery bool, percentage float64, err error)
 )
 
+// defaultBatteryThreshold is the battery charge in percent below which
+// auto-refreshes are deferred while on battery power, unless configured
+// otherwise via refresh.battery-threshold. Deferring on low battery is off
+// by default.
+const defaultBatteryThreshold = 0
+
 // refreshRetryDelay specified the minimum time to retry failed refreshes
 var refreshRetryDelay = 20 * time.Minute
 
@@ -108,6 +117,11 @@ type autoRefresh struct {
 	nextRefresh         time.Time
 	lastRefreshAttempt  time.Time
 	managedDeniedLogged bool
+
+	// auto-refreshes of the snaps with their own refresh timer
+	lastRefreshTimers   string
+	nextOwnTimerRefresh time.Time
+	lastOwnTimerRefresh time.Time
 }
 
 func newAutoRefresh(st *state.State) *autoRefresh {
@@ -235,29 +249,116 @@ func canRefreshOnMeteredConnection(st *state.State) (bool, error) {
 	return onMetered != "hold", nil
 }
 
-func (m *autoRefresh) canRefreshRespectingMetered(now, lastRefresh time.Time) (can bool, err error) {
-	can, err = canRefreshOnMeteredConnection(m.state)
+// RefreshDeferral records why an auto-refresh was last deferred.
+type RefreshDeferral struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+}
+
+// LastRefreshDeferral returns why and when the auto-refresh was last
+// deferred, if it has not happened since.
+func (m *autoRefresh) LastRefreshDeferral() (*RefreshDeferral, error) {
+	var deferral RefreshDeferral
+	err := m.state.Get("last-refresh-deferral", &deferral)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	if can {
-		return true, nil
+	return &deferral, nil
+}
+
+func (m *autoRefresh) clearRefreshDeferral() {
+	var deferral RefreshDeferral
+	if err := m.state.Get("last-refresh-deferral", &deferral); err == nil {
+		m.state.Set("last-refresh-deferral", nil)
+	}
+}
+
+// refreshDeferralReason returns why an auto-refresh should be deferred
+// at the given time according to the refresh.metered, refresh.battery,
+// refresh.battery-threshold and refresh.do-not-disturb options, or an
+// empty string if it can happen.
+func refreshDeferralReason(st *state.State, now time.Time) (string, error) {
+	tr := config.NewTransaction(st)
+
+	var doNotDisturb string
+	if err := tr.GetMaybe("core", "refresh.do-not-disturb", &doNotDisturb); err != nil {
+		return "", err
+	}
+	if doNotDisturb != "" {
+		sched, err := timeutil.ParseSchedule(doNotDisturb)
+		if err != nil {
+			logger.Noticef("cannot use refresh.do-not-disturb %q: %v", doNotDisturb, err)
+		} else if timeutil.Includes(sched, now) {
+			return "within do-not-disturb window", nil
+		}
 	}
 
-	// ignore any errors that occurred while checking if we are on a metered
-	// connection
-	metered, _ := IsOnMeteredConnection()
-	if !metered {
+	can, err := canRefreshOnMeteredConnection(st)
+	if err != nil {
+		return "", err
+	}
+	if !can {
+		// ignore any errors that occurred while checking if we are
+		// on a metered connection
+		if metered, _ := IsOnMeteredConnection(); metered {
+			return "on a metered connection", nil
+		}
+	}
+
+	var onBatteryOpt string
+	if err := tr.GetMaybe("core", "refresh.battery", &onBatteryOpt); err != nil {
+		return "", err
+	}
+	threshold := defaultBatteryThreshold
+	if err := tr.GetMaybe("core", "refresh.battery-threshold", &threshold); err != nil {
+		return "", err
+	}
+	if BatteryStatus == nil || (onBatteryOpt != "hold" && threshold <= 0) {
+		return "", nil
+	}
+	// like for metered connections, errors mean the power supply
+	// cannot be determined, which does not prevent refreshes
+	onBattery, percentage, _ := BatteryStatus()
+	if !onBattery {
+		return "", nil
+	}
+	if onBatteryOpt == "hold" {
+		return "on battery power", nil
+	}
+	if percentage < float64(threshold) {
+		return fmt.Sprintf("battery charge is low (%.0f%%)", percentage), nil
+	}
+	return "", nil
+}
+
+// canRefreshRespectingDeferrals returns whether an auto-refresh can happen
+// now, recording the reason if it is deferred. Auto-refreshes are not
+// deferred if they have been pending for too long.
+func (m *autoRefresh) canRefreshRespectingDeferrals(now, lastRefresh time.Time) (can bool, err error) {
+	reason, err := refreshDeferralReason(m.state, now)
+	if err != nil {
+		return false, err
+	}
+	if reason == "" {
+		m.clearRefreshDeferral()
 		return true, nil
 	}
 
 	if now.Sub(lastRefresh) >= maxPostponement {
 		// TODO use warnings when the infra becomes available
-		logger.Noticef("Auto refresh disabled while on metered connections, but pending for too long (%d days). Trying to refresh now.", int(maxPostponement.Hours()/24))
+		logger.Noticef("Auto refresh deferred (%s), but pending for too long (%d days). Trying to refresh now.", reason, int(maxPostponement.Hours()/24))
+		m.clearRefreshDeferral()
 		return true, nil
 	}
 
-	logger.Debugf("Auto refresh disabled on metered connections")
+	logger.Debugf("Auto refresh deferred: %s", reason)
+	m.state.Set("last-refresh-deferral", &RefreshDeferral{
+		Time:   now,
+		Reason: reason,
+	})
 
 	return false, nil
 }
@@ -347,7 +448,7 @@ func (m *autoRefresh) Ensure() error {
 		// or operation
 		if !m.nextRefresh.After(now) {
 			var can bool
-			can, err = m.canRefreshRespectingMetered(now, lastRefresh)
+			can, err = m.canRefreshRespectingDeferrals(now, lastRefresh)
 			if err != nil {
 				return err
 			}
@@ -364,16 +465,84 @@ func (m *autoRefresh) Ensure() error {
 				return nil
 			}
 
-			err = m.launchAutoRefresh(refreshSchedule)
+			err = m.launchAutoRefresh(refreshSchedule, false)
 			if _, ok := err.(*httputil.PersistentNetworkError); !ok {
 				m.nextRefresh = time.Time{}
 			} // else - refresh will be retried after refreshRetryDelay
 		}
+
+		if err == nil {
+			err = m.ensureOwnTimerRefresh(refreshSchedule, lastRefresh)
+		}
 	}
 
 	return err
 }
 
+// ensureOwnTimerRefresh auto-refreshes the snaps that have their own
+// refresh timer set by the administrator when one of their windows is due,
+// independently of the system wide refresh schedule.
+func (m *autoRefresh) ensureOwnTimerRefresh(refreshSchedule []*timeutil.Schedule, lastRefresh time.Time) error {
+	timers, err := refreshTimers(m.state)
+	if err != nil {
+		return err
+	}
+	var schedules []*timeutil.Schedule
+	var timersStr []string
+	for snapName, timer := range timers {
+		sched, err := timeutil.ParseSchedule(timer)
+		if err != nil {
+			logger.Noticef("cannot use refresh timer %q of snap %q: %v", timer, snapName, err)
+			continue
+		}
+		schedules = append(schedules, sched...)
+		timersStr = append(timersStr, snapName+"="+timer)
+	}
+	sort.Strings(timersStr)
+	if refreshTimersStr := strings.Join(timersStr, ","); m.lastRefreshTimers != refreshTimersStr {
+		// the refresh timers have changed
+		m.nextOwnTimerRefresh = time.Time{}
+		m.lastRefreshTimers = refreshTimersStr
+	}
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if m.nextOwnTimerRefresh.IsZero() {
+		last := m.lastOwnTimerRefresh
+		if last.IsZero() {
+			last = lastRefresh
+		}
+		delta := timeutil.Next(schedules, last, maxPostponement)
+		now = time.Now()
+		m.nextOwnTimerRefresh = now.Add(delta)
+		logger.Debugf("Next refresh of snaps with their own refresh timer scheduled for %s.", m.nextOwnTimerRefresh.Format(time.RFC3339))
+	}
+	if m.nextOwnTimerRefresh.After(now) {
+		return nil
+	}
+
+	can, err := m.canRefreshRespectingDeferrals(now, lastRefresh)
+	if err != nil {
+		return err
+	}
+	if !can {
+		m.nextOwnTimerRefresh = time.Time{}
+		return nil
+	}
+	if !m.lastRefreshAttempt.IsZero() && m.lastRefreshAttempt.Add(refreshRetryDelay).After(time.Now()) {
+		return nil
+	}
+
+	m.lastOwnTimerRefresh = now
+	err = m.launchAutoRefresh(refreshSchedule, true)
+	if _, ok := err.(*httputil.PersistentNetworkError); !ok {
+		m.nextOwnTimerRefresh = time.Time{}
+	}
+	return err
+}
+
 // isRefreshHeld returns whether an auto-refresh is currently held back or not,
 // as indicated by m.EffectiveRefreshHold().
 func (m *autoRefresh) isRefreshHeld(refreshSchedule []*timeutil.Schedule) (bool, time.Time, error) {
@@ -472,7 +641,9 @@ func (m *autoRefresh) refreshScheduleWithDefaultsFallback() (ts []*timeutil.Sche
 }
 
 // launchAutoRefresh creates the auto-refresh taskset and a change for it.
-func (m *autoRefresh) launchAutoRefresh(refreshSchedule []*timeutil.Schedule) error {
+// With ownTimerOnly only the snaps with their own refresh timer are
+// considered and the last refresh time is left alone.
+func (m *autoRefresh) launchAutoRefresh(refreshSchedule []*timeutil.Schedule, ownTimerOnly bool) error {
 	perfTimings := timings.New(map[string]string{"ensure": "auto-refresh"})
 	tm := perfTimings.StartSpan("auto-refresh", "query store and setup auto-refresh change")
 	defer func() {
@@ -483,7 +654,7 @@ func (m *autoRefresh) launchAutoRefresh(refreshSchedule []*timeutil.Schedule) er
 	m.lastRefreshAttempt = time.Now()
 
 	// NOTE: this will unlock and re-lock state for network ops
-	updated, tasksets, err := AutoRefresh(auth.EnsureContextTODO(), m.state)
+	updated, tasksets, err := autoRefreshSnaps(auth.EnsureContextTODO(), m.state, ownTimerOnly)
 
 	// TODO: we should have some way to lock just creating and starting changes,
 	//       as that would alleviate this race condition we are guarding against
@@ -508,7 +679,9 @@ func (m *autoRefresh) launchAutoRefresh(refreshSchedule []*timeutil.Schedule) er
 		logger.Noticef("Cannot prepare auto-refresh change due to a permanent network error: %s", err)
 		return err
 	}
-	m.state.Set("last-refresh", time.Now())
+	if !ownTimerOnly {
+		m.state.Set("last-refresh", time.Now())
+	}
 	if err != nil {
 		logger.Noticef("Cannot prepare auto-refresh change: %s", err)
 		return err
@@ -616,7 +789,11 @@ var asyncPendingRefreshNotification = func(context context.Context, client *user
 // Internally the snap state is updated to remember when the inhibition first
 // took place. Apps can inhibit refreshes for up to "maxInhibition", beyond
 // that period the refresh will go ahead despite application activity.
-func inhibitRefresh(st *state.State, snapst *SnapState, info *snap.Info, checker func(*snap.Info) error) error {
+//
+// The users are notified about the pending refresh only if notifyPending is
+// set; inhibited auto-refreshes are pre-downloaded and the users are
+// notified once the download is done instead.
+func inhibitRefresh(st *state.State, snapst *SnapState, info *snap.Info, checker func(*snap.Info) error, notifyPending bool) error {
 	checkerErr := checker(info)
 	if checkerErr == nil {
 		return nil
@@ -658,6 +835,9 @@ func inhibitRefresh(st *state.State, snapst *SnapState, info *snap.Info, checker
 		checkerErr = nil
 	}
 
+	if checkerErr != nil && !notifyPending {
+		return checkerErr
+	}
 	// Send the notification asynchronously to avoid holding the state lock.
 	asyncPendingRefreshNotification(context.TODO(), userclient.New(), refreshInfo)
 	return checkerErr
@@ -33,6 +33,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timeutil"
 )
 
 var gateAutoRefreshHookName = "gate-auto-refresh"
@@ -50,6 +51,11 @@ const (
 // cumulative hold time for snaps other than self
 const maxOtherHoldDuration = time.Hour * 48
 
+// holdingSystem is used in place of the gating snap for holds requested by
+// the administrator. Like the keys of the holds of refresh policies, it
+// cannot be the name of a snap.
+const holdingSystem = ":system"
+
 var timeNow = func() time.Time {
 	return time.Now()
 }
@@ -121,6 +127,15 @@ func (h *HoldError) Error() string {
 	return fmt.Sprintf("cannot hold some snaps:%s", strings.Join(l, "\n - "))
 }
 
+// holderDescription returns how the given holder of refreshes, a gating snap
+// or a gate-auto-refresh policy, is referred to in messages.
+func holderDescription(holder string) string {
+	if strings.HasPrefix(holder, gatePolicyHolderPrefix) {
+		return fmt.Sprintf("gate-auto-refresh policy %q", strings.TrimPrefix(holder, gatePolicyHolderPrefix))
+	}
+	return fmt.Sprintf("snap %q", holder)
+}
+
 func maxAllowedPostponement(gatingSnap, affectedSnap string, maxPostponement time.Duration) time.Duration {
 	if affectedSnap == gatingSnap {
 		return maxPostponement
@@ -175,7 +190,7 @@ func HoldRefresh(st *state.State, gatingSnap string, holdDuration time.Duration,
 		left := holdDurationLeft(now, lastRefreshTime, hold.FirstHeld, maxDur, mp)
 		if left <= 0 {
 			herr.SnapsInError[heldSnap] = HoldDurationError{
-				Err: fmt.Errorf("snap %q cannot hold snap %q anymore, maximum refresh postponement exceeded", gatingSnap, heldSnap),
+				Err: fmt.Errorf("%s cannot hold snap %q anymore, maximum refresh postponement exceeded", holderDescription(gatingSnap), heldSnap),
 			}
 			continue
 		}
@@ -189,7 +204,7 @@ func HoldRefresh(st *state.State, gatingSnap string, holdDuration time.Duration,
 			// explicit hold duration requested
 			if dur > maxDur {
 				herr.SnapsInError[heldSnap] = HoldDurationError{
-					Err:          fmt.Errorf("requested holding duration for snap %q of %s by snap %q exceeds maximum holding time", heldSnap, holdDuration, gatingSnap),
+					Err:          fmt.Errorf("requested holding duration for snap %q of %s by %s exceeds maximum holding time", heldSnap, holdDuration, holderDescription(gatingSnap)),
 					DurationLeft: left,
 				}
 				continue
@@ -251,6 +266,177 @@ func ProceedWithRefresh(st *state.State, gatingSnap string) error {
 	return nil
 }
 
+// systemHoldActive returns whether the given hold by the administrator is
+// in effect at the given time, a zero HoldUntil means forever.
+func systemHoldActive(hold *holdState, now time.Time) bool {
+	return hold.HoldUntil.IsZero() || hold.HoldUntil.After(now)
+}
+
+func checkInstalled(st *state.State, snaps []string) error {
+	for _, snapName := range snaps {
+		var snapst SnapState
+		if err := Get(st, snapName, &snapst); err != nil && err != state.ErrNoState {
+			return err
+		}
+		if !snapst.IsInstalled() {
+			return &snap.NotInstalledError{Snap: snapName}
+		}
+	}
+	return nil
+}
+
+// HoldRefreshesBySystem holds the auto-refreshes of the given snaps on
+// behalf of the administrator until holdUntil, or forever if holdUntil is
+// zero. Unlike holds by gating snaps these are not limited by the maximum
+// refresh postponement and are kept across refreshes.
+func HoldRefreshesBySystem(st *state.State, holdUntil time.Time, snaps ...string) error {
+	if err := checkInstalled(st, snaps); err != nil {
+		return err
+	}
+	gating, err := refreshGating(st)
+	if err != nil {
+		return err
+	}
+	now := timeNow()
+	for _, heldSnap := range snaps {
+		hold, ok := gating[heldSnap][holdingSystem]
+		if !ok {
+			hold = &holdState{
+				FirstHeld: now,
+			}
+		}
+		hold.HoldUntil = holdUntil
+		if _, ok := gating[heldSnap]; !ok {
+			gating[heldSnap] = make(map[string]*holdState)
+		}
+		gating[heldSnap][holdingSystem] = hold
+	}
+	st.Set("snaps-hold", gating)
+	return nil
+}
+
+// UnholdRefreshesBySystem removes the holds and the refresh timers set by
+// the administrator for the given snaps.
+func UnholdRefreshesBySystem(st *state.State, snaps ...string) error {
+	gating, err := refreshGating(st)
+	if err != nil {
+		return err
+	}
+	timers, err := refreshTimers(st)
+	if err != nil {
+		return err
+	}
+	var gatingChanged, timersChanged bool
+	for _, snapName := range snaps {
+		if _, ok := gating[snapName][holdingSystem]; ok {
+			delete(gating[snapName], holdingSystem)
+			if len(gating[snapName]) == 0 {
+				delete(gating, snapName)
+			}
+			gatingChanged = true
+		}
+		if _, ok := timers[snapName]; ok {
+			delete(timers, snapName)
+			timersChanged = true
+		}
+	}
+	if gatingChanged {
+		st.Set("snaps-hold", gating)
+	}
+	if timersChanged {
+		st.Set("snaps-refresh-timer", timers)
+	}
+	return nil
+}
+
+// SystemRefreshHolds returns the snaps whose auto-refreshes are currently
+// held by the administrator along with the time the holds expire, which
+// is zero for holds without expiry.
+func SystemRefreshHolds(st *state.State) (map[string]time.Time, error) {
+	gating, err := refreshGating(st)
+	if err != nil {
+		return nil, err
+	}
+	now := timeNow()
+	holds := make(map[string]time.Time)
+	for heldSnap, holdingSnaps := range gating {
+		if hold, ok := holdingSnaps[holdingSystem]; ok && systemHoldActive(hold, now) {
+			holds[heldSnap] = hold.HoldUntil
+		}
+	}
+	return holds, nil
+}
+
+func refreshTimers(st *state.State) (map[string]string, error) {
+	// snap -> refresh.timer like schedule
+	var timers map[string]string
+	err := st.Get("snaps-refresh-timer", &timers)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("internal error: cannot get snaps-refresh-timer: %v", err)
+	}
+	if err == state.ErrNoState {
+		return make(map[string]string), nil
+	}
+	return timers, nil
+}
+
+// RefreshTimers returns the refresh timers set by the administrator for
+// individual snaps.
+func RefreshTimers(st *state.State) (map[string]string, error) {
+	return refreshTimers(st)
+}
+
+// SetRefreshTimer makes the given snaps be auto-refreshed only within the
+// windows of the given schedule, using the format of refresh.timer.
+func SetRefreshTimer(st *state.State, timer string, snaps ...string) error {
+	if _, err := timeutil.ParseSchedule(timer); err != nil {
+		return fmt.Errorf("cannot parse refresh timer %q: %v", timer, err)
+	}
+	if err := checkInstalled(st, snaps); err != nil {
+		return err
+	}
+	timers, err := refreshTimers(st)
+	if err != nil {
+		return err
+	}
+	for _, snapName := range snaps {
+		timers[snapName] = timer
+	}
+	st.Set("snaps-refresh-timer", timers)
+	return nil
+}
+
+// heldBySystem returns a function reporting whether the auto-refresh of a
+// snap at the given time is prevented by the administrator, either because
+// the snap is held or because the time is outside of the snap's own refresh
+// timer. With ownTimerOnly snaps without an own refresh timer are reported
+// as held as well.
+func heldBySystem(st *state.State, now time.Time, ownTimerOnly bool) (func(instanceName string) bool, error) {
+	holds, err := SystemRefreshHolds(st)
+	if err != nil {
+		return nil, err
+	}
+	timers, err := refreshTimers(st)
+	if err != nil {
+		return nil, err
+	}
+	return func(instanceName string) bool {
+		if _, ok := holds[instanceName]; ok {
+			return true
+		}
+		timer, ok := timers[instanceName]
+		if !ok {
+			return ownTimerOnly
+		}
+		sched, err := timeutil.ParseSchedule(timer)
+		if err != nil {
+			logger.Noticef("cannot use refresh timer %q of snap %q: %v", timer, instanceName, err)
+			return ownTimerOnly
+		}
+		return !timeutil.Includes(sched, now)
+	}, nil
+}
+
 // resetGatingForRefreshed resets gating information by removing refreshedSnaps
 // (they are not held anymore). This should be called for all successfully
 // refreshed snaps.
@@ -265,10 +451,20 @@ func resetGatingForRefreshed(st *state.State, refreshedSnaps ...string) error {
 
 	var changed bool
 	for _, snapName := range refreshedSnaps {
-		if _, ok := gating[snapName]; ok {
+		holdingSnaps, ok := gating[snapName]
+		if !ok {
+			continue
+		}
+		// holds by the administrator are kept across refreshes
+		if systemHold, ok := holdingSnaps[holdingSystem]; ok {
+			if len(holdingSnaps) == 1 {
+				continue
+			}
+			gating[snapName] = map[string]*holdState{holdingSystem: systemHold}
+		} else {
 			delete(gating, snapName)
-			changed = true
 		}
+		changed = true
 	}
 
 	if changed {
@@ -292,6 +488,11 @@ func heldSnaps(st *state.State) (map[string]bool, error) {
 	held := make(map[string]bool)
 Loop:
 	for heldSnap, holdingSnaps := range gating {
+		// holds by the administrator are not limited by maxPostponement
+		if hold, ok := holdingSnaps[holdingSystem]; ok && systemHoldActive(hold, now) {
+			held[heldSnap] = true
+			continue
+		}
 		refreshed, err := lastRefreshed(st, heldSnap)
 		if err != nil {
 			return nil, err
@@ -300,8 +501,8 @@ Loop:
 		if refreshed.Add(maxPostponement).Before(now) {
 			continue
 		}
-		for _, hold := range holdingSnaps {
-			if hold.HoldUntil.Before(now) {
+		for holdingSnap, hold := range holdingSnaps {
+			if holdingSnap == holdingSystem || hold.HoldUntil.Before(now) {
 				continue
 			}
 			held[heldSnap] = true
@@ -318,6 +519,12 @@ type affectedSnapInfo struct {
 }
 
 func affectedByRefresh(st *state.State, updates []*snap.Info) (map[string]*affectedSnapInfo, error) {
+	return affectedSnapsByRefresh(st, updates, true)
+}
+
+// affectedSnapsByRefresh returns the snaps affected by the given updates,
+// with withGateHookOnly only the ones that have a gate-auto-refresh hook.
+func affectedSnapsByRefresh(st *state.State, updates []*snap.Info, withGateHookOnly bool) (map[string]*affectedSnapInfo, error) {
 	all, err := All(st)
 	if err != nil {
 		return nil, err
@@ -347,7 +554,7 @@ func affectedByRefresh(st *state.State, updates []*snap.Info) (map[string]*affec
 			return nil, err
 		}
 		// optimization: do not consider snaps that don't have gate-auto-refresh hook.
-		if inf.Hooks[gateAutoRefreshHookName] == nil {
+		if withGateHookOnly && inf.Hooks[gateAutoRefreshHookName] == nil {
 			delete(all, name)
 			continue
 		}
@@ -530,6 +530,30 @@ func (s *autorefreshGatingSuite) TestHoldRefreshHelperErrors(c *C) {
 	c.Assert(snapstate.HoldRefresh(st, "snap-b", 0, "snap-b"), ErrorMatches, `cannot hold some snaps:\n - snap "snap-b" cannot hold snap "snap-b" anymore, maximum refresh postponement exceeded`)
 }
 
+func (s *autorefreshGatingSuite) TestHoldRefreshHelperErrorsPolicy(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	now := "2021-05-10T10:00:00Z"
+	restore := snapstate.MockTimeNow(func() time.Time {
+		t, err := time.Parse(time.RFC3339, now)
+		c.Assert(err, IsNil)
+		return t
+	})
+	defer restore()
+
+	mockInstalledSnap(c, st, snapByaml, false)
+	mockLastRefreshed(c, st, "2021-05-01T10:00:00Z", "snap-b")
+
+	hold := time.Hour * 49
+	c.Check(snapstate.HoldRefresh(st, "system-policy:hold-all", hold, "snap-b"), ErrorMatches, `cannot hold some snaps:\n - requested holding duration for snap "snap-b" of 49h0m0s by gate-auto-refresh policy "hold-all" exceeds maximum holding time`)
+
+	// refreshed long time ago (> maxPostponement)
+	mockLastRefreshed(c, st, "2021-01-01T10:00:00Z", "snap-b")
+	c.Check(snapstate.HoldRefresh(st, "system-policy:hold-all", 0, "snap-b"), ErrorMatches, `cannot hold some snaps:\n - gate-auto-refresh policy "hold-all" cannot hold snap "snap-b" anymore, maximum refresh postponement exceeded`)
+}
+
 func (s *autorefreshGatingSuite) TestHoldAndProceedWithRefreshHelper(c *C) {
 	st := s.state
 	st.Lock()
@@ -610,6 +634,154 @@ func (s *autorefreshGatingSuite) TestResetGatingForRefreshedHelper(c *C) {
 	c.Check(held, DeepEquals, map[string]bool{"snap-d": true})
 }
 
+func (s *autorefreshGatingSuite) TestHoldRefreshesBySystem(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	now, err := time.Parse(time.RFC3339, "2021-05-10T10:00:00Z")
+	c.Assert(err, IsNil)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	mockInstalledSnap(c, st, snapAyaml, false)
+	mockInstalledSnap(c, st, snapByaml, false)
+	mockInstalledSnap(c, st, snapCyaml, false)
+	// way past the maximum postponement
+	mockLastRefreshed(c, st, "2020-01-01T10:00:00Z", "snap-a", "snap-b", "snap-c")
+
+	until := now.Add(24 * time.Hour)
+	c.Assert(snapstate.HoldRefreshesBySystem(st, time.Time{}, "snap-a"), IsNil)
+	c.Assert(snapstate.HoldRefreshesBySystem(st, until, "snap-b"), IsNil)
+	c.Assert(snapstate.HoldRefresh(st, "snap-b", 0, "snap-b"), NotNil)
+
+	var gating map[string]map[string]*snapstate.HoldState
+	c.Assert(st.Get("snaps-hold", &gating), IsNil)
+	c.Check(gating, DeepEquals, map[string]map[string]*snapstate.HoldState{
+		"snap-a": {
+			":system": {FirstHeld: now},
+		},
+		"snap-b": {
+			":system": snapstate.MockHoldState("2021-05-10T10:00:00Z", "2021-05-11T10:00:00Z"),
+		},
+	})
+
+	holds, err := snapstate.SystemRefreshHolds(st)
+	c.Assert(err, IsNil)
+	c.Check(holds, DeepEquals, map[string]time.Time{
+		"snap-a": {},
+		"snap-b": until,
+	})
+	held, err := snapstate.HeldSnaps(st)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string]bool{"snap-a": true, "snap-b": true})
+
+	// holds by the system are kept when refreshed
+	c.Assert(snapstate.ResetGatingForRefreshed(st, "snap-a", "snap-b"), IsNil)
+	held, err = snapstate.HeldSnaps(st)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string]bool{"snap-a": true, "snap-b": true})
+
+	// the hold of snap-b expires
+	now = now.Add(25 * time.Hour)
+	holds, err = snapstate.SystemRefreshHolds(st)
+	c.Assert(err, IsNil)
+	c.Check(holds, DeepEquals, map[string]time.Time{"snap-a": {}})
+	held, err = snapstate.HeldSnaps(st)
+	c.Assert(err, IsNil)
+	c.Check(held, DeepEquals, map[string]bool{"snap-a": true})
+
+	c.Assert(snapstate.UnholdRefreshesBySystem(st, "snap-a", "snap-b", "snap-c"), IsNil)
+	holds, err = snapstate.SystemRefreshHolds(st)
+	c.Assert(err, IsNil)
+	c.Check(holds, HasLen, 0)
+
+	err = snapstate.HoldRefreshesBySystem(st, time.Time{}, "snap-c", "snap-x")
+	c.Check(err, ErrorMatches, `snap "snap-x" is not installed`)
+}
+
+func (s *autorefreshGatingSuite) TestSetRefreshTimer(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	mockInstalledSnap(c, st, snapAyaml, false)
+	mockInstalledSnap(c, st, snapByaml, false)
+
+	c.Assert(snapstate.SetRefreshTimer(st, "sat,10:00-12:00", "snap-a", "snap-b"), IsNil)
+	timers, err := snapstate.RefreshTimers(st)
+	c.Assert(err, IsNil)
+	c.Check(timers, DeepEquals, map[string]string{
+		"snap-a": "sat,10:00-12:00",
+		"snap-b": "sat,10:00-12:00",
+	})
+
+	err = snapstate.SetRefreshTimer(st, "foo", "snap-a")
+	c.Check(err, ErrorMatches, `cannot parse refresh timer "foo": .*`)
+	err = snapstate.SetRefreshTimer(st, "10:00", "snap-x")
+	c.Check(err, ErrorMatches, `snap "snap-x" is not installed`)
+
+	c.Assert(snapstate.UnholdRefreshesBySystem(st, "snap-a"), IsNil)
+	timers, err = snapstate.RefreshTimers(st)
+	c.Assert(err, IsNil)
+	c.Check(timers, DeepEquals, map[string]string{"snap-b": "sat,10:00-12:00"})
+}
+
+func (s *autorefreshGatingSuite) TestAutoRefreshPhase1HeldBySystem(c *C) {
+	s.store.refreshedSnaps = []*snap.Info{{
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "snap-a",
+			Revision: snap.R(8),
+		},
+	}, {
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "snap-b",
+			Revision: snap.R(3),
+		},
+	}, {
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "snap-c",
+			Revision: snap.R(5),
+		},
+	}}
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	now, err := time.Parse(time.RFC3339, "2021-05-10T10:00:00Z")
+	c.Assert(err, IsNil)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	mockInstalledSnap(c, s.state, snapAyaml, noHook)
+	mockInstalledSnap(c, s.state, snapByaml, noHook)
+	mockInstalledSnap(c, s.state, snapCyaml, noHook)
+
+	restore = snapstatetest.MockDeviceModel(DefaultModel())
+	defer restore()
+
+	c.Assert(snapstate.HoldRefreshesBySystem(st, time.Time{}, "snap-a"), IsNil)
+	// it is not the time for snap-b
+	c.Assert(snapstate.SetRefreshTimer(st, "12:00-14:00", "snap-b"), IsNil)
+
+	names, _, err := snapstate.AutoRefreshPhase1(context.TODO(), st)
+	c.Assert(err, IsNil)
+	c.Check(names, DeepEquals, []string{"snap-c"})
+
+	// the window of snap-b is open
+	now = now.Add(3 * time.Hour)
+	names, _, err = snapstate.AutoRefreshPhase1(context.TODO(), st)
+	c.Assert(err, IsNil)
+	c.Check(names, DeepEquals, []string{"snap-b", "snap-c"})
+}
+
 const useHook = true
 const noHook = false
 
@@ -1541,7 +1713,9 @@ func (s *snapmgrTestSuite) testAutoRefreshPhase2DiskSpaceCheck(c *C, fail bool)
 	defer st.Unlock()
 
 	restore := snapstate.MockOsutilCheckFreeSpace(func(path string, sz uint64) error {
-		c.Check(sz, Equals, snapstate.SafetyMarginDiskSpace(123))
+		// 123 from installSize plus 1 from the mocked data size estimate
+		// of the refreshed app snap
+		c.Check(sz, Equals, snapstate.SafetyMarginDiskSpace(123+1))
 		if fail {
 			return &osutil.NotEnoughDiskSpaceError{}
 		}
@@ -1832,3 +2006,23 @@ func verifyPhasedAutorefreshTasks(c *C, tasks []*state.Task, expected []string)
 		c.Assert(got, Equals, expected[i])
 	}
 }
+
+func (s *snapmgrTestSuite) TestAutoRefreshHeldBySystem(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshWaves(c, "", map[string]snap.Type{
+		"some-snap":       snap.TypeApp,
+		"some-other-snap": snap.TypeApp,
+	})
+	c.Assert(snapstate.HoldRefreshesBySystem(s.state, time.Time{}, "some-other-snap"), IsNil)
+
+	updated, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"some-snap"})
+
+	// an explicit refresh is still possible
+	updated, _, err = snapstate.UpdateMany(context.Background(), s.state, []string{"some-other-snap"}, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"some-other-snap"})
+}
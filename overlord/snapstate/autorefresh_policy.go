@@ -0,0 +1,276 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Gate-auto-refresh policies are executables placed by the administrator in
+// dirs.SnapGateAutoRefreshDir. They are consulted after the gate-auto-refresh
+// hooks of the snaps, with the same information as the hooks, passed as JSON
+// on stdin:
+//
+//	{
+//	  "affected-snaps": {"<snap>": {"restart": true, "base": false, "affecting-snaps": ["<snap>", ...]}, ...},
+//	  "pending": {"<snap>": {"revision": "<rev>", "version": "<version>", "channel": "<channel>", "type": "<type>"}, ...}
+//	}
+//
+// and print their decision as JSON on stdout:
+//
+//	{"action": "hold", "snaps": ["<snap>", ...], "duration": "<duration>"}
+//	{"action": "proceed"}
+//
+// A hold without snaps holds all pending snaps, one without a duration
+// holds them for as long as allowed. No output means proceed. The holds
+// have the same limits as the ones requested by gating snaps and are
+// recorded on behalf of the policy.
+
+// gateAutoRefreshPolicyTimeout is how long a gate-auto-refresh policy may
+// run before it is killed.
+var gateAutoRefreshPolicyTimeout = 30 * time.Second
+
+type gatePolicyAffected struct {
+	Restart        bool     `json:"restart"`
+	Base           bool     `json:"base"`
+	AffectingSnaps []string `json:"affecting-snaps"`
+}
+
+type gatePolicyPending struct {
+	Revision snap.Revision `json:"revision"`
+	Version  string        `json:"version,omitempty"`
+	Channel  string        `json:"channel,omitempty"`
+	Type     snap.Type     `json:"type"`
+}
+
+type gatePolicyInput struct {
+	AffectedSnaps map[string]*gatePolicyAffected `json:"affected-snaps"`
+	Pending       map[string]*gatePolicyPending  `json:"pending"`
+}
+
+type gatePolicyDecision struct {
+	Action   string   `json:"action"`
+	Snaps    []string `json:"snaps,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+}
+
+// gateAutoRefreshPolicies returns the names of the executable
+// gate-auto-refresh policies of the administrator, sorted.
+func gateAutoRefreshPolicies() ([]string, error) {
+	fis, err := ioutil.ReadDir(dirs.SnapGateAutoRefreshDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// gatePolicyHolderPrefix prefixes the names of the policies in place of a
+// gating snap in the holds they requested.
+const gatePolicyHolderPrefix = "system-policy:"
+
+// gatePolicyHolder returns what the holds requested by the given policy are
+// recorded under in place of a gating snap.
+func gatePolicyHolder(policy string) string {
+	return gatePolicyHolderPrefix + policy
+}
+
+// createGateAutoRefreshPolicyTask creates the task consulting the
+// gate-auto-refresh policies about the refresh of the given snaps.
+func createGateAutoRefreshPolicyTask(st *state.State, affectedSnaps map[string]*affectedSnapInfo, toUpdate map[string]*refreshCandidate) *state.Task {
+	t := st.NewTask("run-gate-auto-refresh-policies", i18n.G("Consult gate-auto-refresh policies of the system"))
+	t.Set("affected-snaps", affectedSnaps)
+	t.Set("snaps", toUpdate)
+	return t
+}
+
+var runGateAutoRefreshPolicy = func(path string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gateAutoRefreshPolicyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("exceeded maximum runtime of %s", gateAutoRefreshPolicyTimeout)
+		}
+		return nil, osutil.OutputErr(stderr.Bytes(), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func parseGatePolicyDecision(out []byte) (*gatePolicyDecision, error) {
+	var decision gatePolicyDecision
+	if len(bytes.TrimSpace(out)) == 0 {
+		decision.Action = "proceed"
+		return &decision, nil
+	}
+	if err := json.Unmarshal(out, &decision); err != nil {
+		return nil, fmt.Errorf("cannot decode decision: %v", err)
+	}
+	switch decision.Action {
+	case "hold":
+		if decision.Duration != "" {
+			dur, err := time.ParseDuration(decision.Duration)
+			if err != nil || dur <= 0 {
+				return nil, fmt.Errorf("invalid hold duration %q", decision.Duration)
+			}
+		}
+	case "proceed":
+		if len(decision.Snaps) != 0 || decision.Duration != "" {
+			return nil, fmt.Errorf("proceed does not take snaps or a duration")
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", decision.Action)
+	}
+	return &decision, nil
+}
+
+func (m *SnapManager) doRunGateAutoRefreshPolicies(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var affectedSnaps map[string]*affectedSnapInfo
+	if err := t.Get("affected-snaps", &affectedSnaps); err != nil {
+		return err
+	}
+	var snaps map[string]*refreshCandidate
+	if err := t.Get("snaps", &snaps); err != nil {
+		return err
+	}
+
+	input := gatePolicyInput{
+		AffectedSnaps: make(map[string]*gatePolicyAffected, len(affectedSnaps)),
+		Pending:       make(map[string]*gatePolicyPending, len(snaps)),
+	}
+	for name, affected := range affectedSnaps {
+		affecting := make([]string, 0, len(affected.AffectingSnaps))
+		for affectingSnap := range affected.AffectingSnaps {
+			affecting = append(affecting, affectingSnap)
+		}
+		sort.Strings(affecting)
+		input.AffectedSnaps[name] = &gatePolicyAffected{
+			Restart:        affected.Restart,
+			Base:           affected.Base,
+			AffectingSnaps: affecting,
+		}
+	}
+	pending := make([]string, 0, len(snaps))
+	for name, cand := range snaps {
+		pending = append(pending, name)
+		input.Pending[name] = &gatePolicyPending{
+			Revision: cand.Revision(),
+			Version:  cand.Version,
+			Channel:  cand.Channel,
+			Type:     cand.Type(),
+		}
+	}
+	sort.Strings(pending)
+	data, err := json.Marshal(&input)
+	if err != nil {
+		return err
+	}
+
+	policies, err := gateAutoRefreshPolicies()
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		st.Unlock()
+		out, err := runGateAutoRefreshPolicy(filepath.Join(dirs.SnapGateAutoRefreshDir, policy), data)
+		st.Lock()
+
+		var decision *gatePolicyDecision
+		if err == nil {
+			decision, err = parseGatePolicyDecision(out)
+		}
+		if err != nil {
+			// a broken policy holds the refresh like a failing
+			// gate-auto-refresh hook would, within the usual limits
+			logger.Noticef("gate-auto-refresh policy %q failed, holding refreshes: %v", policy, err)
+			decision = &gatePolicyDecision{Action: "hold"}
+		}
+
+		holder := gatePolicyHolder(policy)
+		if decision.Action == "proceed" {
+			if err := ProceedWithRefresh(st, holder); err != nil {
+				return err
+			}
+			continue
+		}
+
+		held := pending
+		if len(decision.Snaps) > 0 {
+			held = make([]string, 0, len(decision.Snaps))
+			for _, name := range decision.Snaps {
+				if _, ok := snaps[name]; !ok {
+					logger.Noticef("gate-auto-refresh policy %q cannot hold snap %q: not refreshed", policy, name)
+					continue
+				}
+				held = append(held, name)
+			}
+		}
+		var holdDuration time.Duration
+		if decision.Duration != "" {
+			// already validated
+			holdDuration, _ = time.ParseDuration(decision.Duration)
+		}
+		if err := HoldRefresh(st, holder, holdDuration, held...); err != nil {
+			if _, ok := err.(*HoldError); !ok {
+				return err
+			}
+			logger.Noticef("gate-auto-refresh policy %q: %v", policy, err)
+		}
+	}
+
+	return nil
+}
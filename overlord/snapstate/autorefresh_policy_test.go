@@ -0,0 +1,280 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func mockGateAutoRefreshPolicy(c *C, name, script string) {
+	c.Assert(os.MkdirAll(dirs.SnapGateAutoRefreshDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapGateAutoRefreshDir, name), []byte("#!/bin/sh\n"+script), 0755), IsNil)
+}
+
+func (s *autorefreshGatingSuite) TestAutoRefreshPhase1GatePolicy(c *C) {
+	s.store.refreshedSnaps = []*snap.Info{{
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeApp,
+		SideInfo: snap.SideInfo{
+			RealName: "snap-a",
+			Revision: snap.R(8),
+		},
+	}, {
+		Architectures: []string{"all"},
+		SnapType:      snap.TypeBase,
+		SideInfo: snap.SideInfo{
+			RealName: "base-snap-b",
+			Revision: snap.R(3),
+		},
+	}}
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	mockInstalledSnap(c, s.state, snapAyaml, useHook)
+	// snap-b does not have a hook but the policies learn that it is
+	// affected by the refresh of its base
+	mockInstalledSnap(c, s.state, snapByaml, noHook)
+	mockInstalledSnap(c, s.state, baseSnapByaml, noHook)
+
+	restore := snapstatetest.MockDeviceModel(DefaultModel())
+	defer restore()
+
+	// not executable, ignored
+	c.Assert(os.MkdirAll(dirs.SnapGateAutoRefreshDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapGateAutoRefreshDir, "README"), nil, 0644), IsNil)
+
+	_, tss, err := snapstate.AutoRefreshPhase1(context.TODO(), st)
+	c.Assert(err, IsNil)
+	// the gating task and the hook of snap-a only
+	c.Assert(tss, HasLen, 2)
+
+	mockGateAutoRefreshPolicy(c, "policy", "true\n")
+	_, tss, err = snapstate.AutoRefreshPhase1(context.TODO(), st)
+	c.Assert(err, IsNil)
+	c.Assert(tss, HasLen, 3)
+
+	ar := tss[0].Tasks()[0]
+	c.Assert(ar.Kind(), Equals, "conditional-auto-refresh")
+	c.Assert(tss[1].Tasks(), HasLen, 1)
+	hook := tss[1].Tasks()[0]
+	c.Assert(tss[2].Tasks(), HasLen, 1)
+	policyTask := tss[2].Tasks()[0]
+	c.Check(policyTask.Kind(), Equals, "run-gate-auto-refresh-policies")
+	c.Check(policyTask.WaitTasks(), DeepEquals, []*state.Task{hook})
+	c.Check(ar.WaitTasks(), testutil.Contains, policyTask)
+
+	var affected map[string]*snapstate.AffectedSnapInfo
+	c.Assert(policyTask.Get("affected-snaps", &affected), IsNil)
+	c.Check(affected, DeepEquals, map[string]*snapstate.AffectedSnapInfo{
+		"snap-a":      {},
+		"base-snap-b": {},
+		"snap-b": {
+			Base:           true,
+			AffectingSnaps: map[string]bool{"base-snap-b": true},
+		},
+	})
+	var snaps map[string]*snapstate.RefreshCandidate
+	c.Assert(policyTask.Get("snaps", &snaps), IsNil)
+	c.Check(snaps, HasLen, 2)
+}
+
+func (s *autorefreshGatingSuite) TestParseGatePolicyDecision(c *C) {
+	for _, t := range []struct {
+		out    string
+		action string
+		err    string
+	}{
+		{"", "proceed", ""},
+		{"\n", "proceed", ""},
+		{`{"action": "proceed"}`, "proceed", ""},
+		{`{"action": "hold"}`, "hold", ""},
+		{`{"action": "hold", "snaps": ["foo"], "duration": "24h"}`, "hold", ""},
+		{`{"action": "hold", "duration": "-1h"}`, "", `invalid hold duration "-1h"`},
+		{`{"action": "hold", "duration": "tomorrow"}`, "", `invalid hold duration "tomorrow"`},
+		{`{"action": "proceed", "snaps": ["foo"]}`, "", `proceed does not take snaps or a duration`},
+		{`{"action": "wait"}`, "", `unknown action "wait"`},
+		{`hold`, "", `cannot decode decision: .*`},
+	} {
+		decision, err := snapstate.ParseGatePolicyDecision([]byte(t.out))
+		if t.err != "" {
+			c.Check(err, ErrorMatches, t.err, Commentf(t.out))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf(t.out))
+		c.Check(decision.Action, Equals, t.action, Commentf(t.out))
+	}
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshPhase2HeldByPolicy(c *C) {
+	logbuf, restoreLogger := logger.MockLogger()
+	defer restoreLogger()
+
+	expected := []string{
+		"conditional-auto-refresh",
+		"run-hook [snap-a;gate-auto-refresh]",
+		// snap-b hook is triggered because of base-snap-b refresh
+		"run-hook [snap-b;gate-auto-refresh]",
+		"run-gate-auto-refresh-policies",
+		"prerequisites",
+		"download-snap",
+		"validate-snap",
+		"mount-snap",
+		"run-hook [snap-a;pre-refresh]",
+		"stop-snap-services",
+		"remove-aliases",
+		"unlink-current-snap",
+		"copy-snap-data",
+		"setup-profiles",
+		"link-snap",
+		"auto-connect",
+		"set-auto-aliases",
+		"setup-aliases",
+		"run-hook [snap-a;post-refresh]",
+		"start-snap-services",
+		"cleanup",
+		"run-hook [snap-a;configure]",
+		"run-hook [snap-a;check-health]",
+		"check-rerefresh",
+	}
+
+	inputFile := filepath.Join(c.MkDir(), "input")
+	s.testAutoRefreshPhase2(c, func() {
+		mockGateAutoRefreshPolicy(c, "hold-bases", fmt.Sprintf(`cat > %s
+echo '{"action": "hold", "snaps": ["base-snap-b"], "duration": "2h"}'
+`, inputFile))
+		// proceeding is the default
+		mockGateAutoRefreshPolicy(c, "other", "true\n")
+	}, nil, expected)
+
+	c.Assert(logbuf.String(), testutil.Contains, `skipping refresh of held snaps: base-snap-b`)
+
+	data, err := ioutil.ReadFile(inputFile)
+	c.Assert(err, IsNil)
+	var input map[string]interface{}
+	c.Assert(json.Unmarshal(data, &input), IsNil)
+	c.Check(input["affected-snaps"], DeepEquals, map[string]interface{}{
+		"snap-a":      map[string]interface{}{"restart": false, "base": false, "affecting-snaps": []interface{}{}},
+		"base-snap-b": map[string]interface{}{"restart": false, "base": false, "affecting-snaps": []interface{}{}},
+		"snap-b":      map[string]interface{}{"restart": false, "base": true, "affecting-snaps": []interface{}{"base-snap-b"}},
+	})
+	c.Check(input["pending"], DeepEquals, map[string]interface{}{
+		"snap-a":      map[string]interface{}{"revision": "8", "type": "app"},
+		"base-snap-b": map[string]interface{}{"revision": "3", "type": "base"},
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var gating map[string]map[string]*snapstate.HoldState
+	c.Assert(s.state.Get("snaps-hold", &gating), IsNil)
+	c.Assert(gating["base-snap-b"], HasLen, 1)
+	hold := gating["base-snap-b"]["system-policy:hold-bases"]
+	c.Assert(hold, NotNil)
+	c.Check(hold.HoldUntil.Sub(hold.FirstHeld), Equals, 2*time.Hour)
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshPhase2PolicyProceeds(c *C) {
+	expected := []string{
+		"conditional-auto-refresh",
+		"run-hook [snap-a;gate-auto-refresh]",
+		"run-hook [snap-b;gate-auto-refresh]",
+		"run-gate-auto-refresh-policies",
+		"prerequisites",
+		"download-snap",
+		"validate-snap",
+		"mount-snap",
+		"run-hook [base-snap-b;pre-refresh]",
+		"stop-snap-services",
+		"remove-aliases",
+		"unlink-current-snap",
+		"copy-snap-data",
+		"setup-profiles",
+		"link-snap",
+		"auto-connect",
+		"set-auto-aliases",
+		"setup-aliases",
+		"run-hook [base-snap-b;post-refresh]",
+		"start-snap-services",
+		"cleanup",
+		"run-hook [base-snap-b;check-health]",
+		"prerequisites",
+		"download-snap",
+		"validate-snap",
+		"mount-snap",
+		"run-hook [snap-a;pre-refresh]",
+		"stop-snap-services",
+		"remove-aliases",
+		"unlink-current-snap",
+		"copy-snap-data",
+		"setup-profiles",
+		"link-snap",
+		"auto-connect",
+		"set-auto-aliases",
+		"setup-aliases",
+		"run-hook [snap-a;post-refresh]",
+		"start-snap-services",
+		"cleanup",
+		"run-hook [snap-a;configure]",
+		"run-hook [snap-a;check-health]",
+		"check-rerefresh",
+	}
+
+	s.testAutoRefreshPhase2(c, func() {
+		// held by the policy previously
+		c.Assert(snapstate.HoldRefresh(s.state, "system-policy:policy", 0, "base-snap-b"), IsNil)
+		mockGateAutoRefreshPolicy(c, "policy", `echo '{"action": "proceed"}'`)
+	}, nil, expected)
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshPhase2BrokenPolicyHolds(c *C) {
+	logbuf, restoreLogger := logger.MockLogger()
+	defer restoreLogger()
+
+	expected := []string{
+		"conditional-auto-refresh",
+		"run-hook [snap-a;gate-auto-refresh]",
+		"run-hook [snap-b;gate-auto-refresh]",
+		"run-gate-auto-refresh-policies",
+	}
+
+	s.testAutoRefreshPhase2(c, func() {
+		mockGateAutoRefreshPolicy(c, "broken", "echo oops >&2\nexit 1\n")
+	}, nil, expected)
+
+	c.Check(logbuf.String(), testutil.Contains, `gate-auto-refresh policy "broken" failed, holding refreshes: oops`)
+	c.Check(logbuf.String(), testutil.Contains, `skipping refresh of held snaps: base-snap-b,snap-a`)
+}
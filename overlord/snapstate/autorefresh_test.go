@@ -285,6 +285,32 @@ func (s *autoRefreshTestSuite) TestLastRefreshNoRefreshNeeded(c *C) {
 	c.Check(s.store.ops, HasLen, 0)
 }
 
+func (s *autoRefreshTestSuite) TestRefreshOwnTimer(c *C) {
+	s.state.Lock()
+	lastRefresh := time.Now().Add(-48 * time.Hour)
+	s.state.Set("last-refresh", lastRefresh)
+	c.Assert(snapstate.SetRefreshTimer(s.state, "00:00-24:00", "some-snap"), IsNil)
+	s.state.Unlock()
+
+	af := snapstate.NewAutoRefresh(s.state)
+	// the system wide refresh is not due yet
+	nextRefresh := time.Now().Add(time.Hour)
+	snapstate.MockLastRefreshSchedule(af, "00:00~24:00/4")
+	snapstate.MockNextRefresh(af, nextRefresh)
+
+	err := af.Ensure()
+	c.Check(err, IsNil)
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+	c.Check(af.NextRefresh(), Equals, nextRefresh)
+
+	// the last refresh of all snaps is unchanged
+	var t1 time.Time
+	s.state.Lock()
+	c.Assert(s.state.Get("last-refresh", &t1), IsNil)
+	s.state.Unlock()
+	c.Check(t1.Equal(lastRefresh), Equals, true)
+}
+
 func (s *autoRefreshTestSuite) TestRefreshBackoff(c *C) {
 	s.store.err = fmt.Errorf("random store error")
 	af := snapstate.NewAutoRefresh(s.state)
@@ -891,6 +917,181 @@ func (s *autoRefreshTestSuite) TestRefreshOnMeteredConnNotMetered(c *C) {
 	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
 }
 
+func (s *autoRefreshTestSuite) TestRefreshOnMeteredConnRecordsDeferral(c *C) {
+	revert := snapstate.MockIsOnMeteredConnection(func() (bool, error) {
+		return true, nil
+	})
+	defer revert()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.metered", "hold")
+	tr.Commit()
+
+	af := snapstate.NewAutoRefresh(s.state)
+
+	s.state.Set("last-refresh", time.Now().Add(-5*24*time.Hour))
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(s.store.ops, HasLen, 0)
+
+	deferral, err := af.LastRefreshDeferral()
+	c.Assert(err, IsNil)
+	c.Assert(deferral, NotNil)
+	c.Check(deferral.Reason, Equals, "on a metered connection")
+	c.Check(deferral.Time.IsZero(), Equals, false)
+}
+
+func (s *autoRefreshTestSuite) testRefreshDeferredOnBattery(c *C, conf map[string]interface{}, percentage float64, reason string) {
+	revert := snapstate.MockBatteryStatus(func() (bool, float64, error) {
+		return true, percentage, nil
+	})
+	defer revert()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	for k, v := range conf {
+		tr.Set("core", k, v)
+	}
+	tr.Commit()
+
+	af := snapstate.NewAutoRefresh(s.state)
+
+	s.state.Set("last-refresh", time.Now().Add(-5*24*time.Hour))
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+
+	deferral, err := af.LastRefreshDeferral()
+	c.Assert(err, IsNil)
+	if reason == "" {
+		c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+		c.Check(deferral, IsNil)
+		return
+	}
+	c.Check(s.store.ops, HasLen, 0)
+	c.Check(af.NextRefresh(), DeepEquals, time.Time{})
+	c.Assert(deferral, NotNil)
+	c.Check(deferral.Reason, Equals, reason)
+}
+
+func (s *autoRefreshTestSuite) TestRefreshDeferredOnBatteryHold(c *C) {
+	s.testRefreshDeferredOnBattery(c, map[string]interface{}{"refresh.battery": "hold"}, 80, "on battery power")
+}
+
+func (s *autoRefreshTestSuite) TestRefreshOnLowBatteryByDefault(c *C) {
+	s.testRefreshDeferredOnBattery(c, nil, 5, "")
+}
+
+func (s *autoRefreshTestSuite) TestRefreshOnBatteryAboveThreshold(c *C) {
+	s.testRefreshDeferredOnBattery(c, map[string]interface{}{"refresh.battery-threshold": 10}, 50, "")
+}
+
+func (s *autoRefreshTestSuite) TestRefreshDeferredOnBatteryBelowConfiguredThreshold(c *C) {
+	s.testRefreshDeferredOnBattery(c, map[string]interface{}{"refresh.battery-threshold": 60}, 50, "battery charge is low (50%)")
+}
+
+func (s *autoRefreshTestSuite) TestRefreshOnLowBatteryThresholdDisabled(c *C) {
+	s.testRefreshDeferredOnBattery(c, map[string]interface{}{"refresh.battery-threshold": 0}, 5, "")
+}
+
+func (s *autoRefreshTestSuite) TestRefreshDeferralsInvalidBatteryThreshold(c *C) {
+	restore := snapstate.MockBatteryStatus(func() (bool, float64, error) {
+		return true, 5, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.battery-threshold", "low")
+	tr.Commit()
+
+	af := snapstate.NewAutoRefresh(s.state)
+
+	s.state.Set("last-refresh", time.Now().Add(-5*24*time.Hour))
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, ErrorMatches, `.*cannot unmarshal snap "core" option "refresh.battery-threshold" into \*int: .*`)
+	c.Check(s.store.ops, HasLen, 0)
+}
+
+func (s *autoRefreshTestSuite) TestRefreshOnACPower(c *C) {
+	revert := snapstate.MockBatteryStatus(func() (bool, float64, error) {
+		return false, 0, nil
+	})
+	defer revert()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.battery", "hold")
+	tr.Commit()
+
+	// a previous deferral is forgotten once the refresh happens
+	s.state.Set("last-refresh-deferral", &snapstate.RefreshDeferral{
+		Time:   time.Now().Add(-time.Hour),
+		Reason: "on battery power",
+	})
+
+	af := snapstate.NewAutoRefresh(s.state)
+
+	s.state.Set("last-refresh", time.Now().Add(-5*24*time.Hour))
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+
+	deferral, err := af.LastRefreshDeferral()
+	c.Assert(err, IsNil)
+	c.Check(deferral, IsNil)
+}
+
+func (s *autoRefreshTestSuite) TestRefreshDeferredWithinDoNotDisturb(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.do-not-disturb", "00:00-24:00")
+	tr.Commit()
+
+	af := snapstate.NewAutoRefresh(s.state)
+
+	s.state.Set("last-refresh", time.Now().Add(-5*24*time.Hour))
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(s.store.ops, HasLen, 0)
+
+	deferral, err := af.LastRefreshDeferral()
+	c.Assert(err, IsNil)
+	c.Assert(deferral, NotNil)
+	c.Check(deferral.Reason, Equals, "within do-not-disturb window")
+
+	// the deferral is bounded by the max postponement
+	s.state.Set("last-refresh", time.Now().Add(-96*24*time.Hour))
+	s.state.Unlock()
+	err = af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+	deferral, err = af.LastRefreshDeferral()
+	c.Assert(err, IsNil)
+	c.Check(deferral, IsNil)
+}
+
 func (s *autoRefreshTestSuite) TestInitialInhibitRefreshWithinInhibitWindow(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -911,7 +1112,7 @@ func (s *autoRefreshTestSuite) TestInitialInhibitRefreshWithinInhibitWindow(c *C
 	}
 	err := snapstate.InhibitRefresh(s.state, snapst, info, func(si *snap.Info) error {
 		return &snapstate.BusySnapError{SnapInfo: si}
-	})
+	}, true)
 	c.Assert(err, ErrorMatches, `snap "pkg" has running apps or hooks`)
 	c.Check(notificationCount, Equals, 1)
 }
@@ -943,7 +1144,7 @@ func (s *autoRefreshTestSuite) TestSubsequentInhibitRefreshWithinInhibitWindow(c
 
 	err := snapstate.InhibitRefresh(s.state, snapst, info, func(si *snap.Info) error {
 		return &snapstate.BusySnapError{SnapInfo: si}
-	})
+	}, true)
 	c.Assert(err, ErrorMatches, `snap "pkg" has running apps or hooks`)
 	c.Check(notificationCount, Equals, 1)
 }
@@ -972,7 +1173,40 @@ func (s *autoRefreshTestSuite) TestInhibitRefreshRefreshesWhenOverdue(c *C) {
 	}
 	err := snapstate.InhibitRefresh(s.state, snapst, info, func(si *snap.Info) error {
 		return &snapstate.BusySnapError{SnapInfo: si}
+	}, true)
+	c.Assert(err, IsNil)
+	c.Check(notificationCount, Equals, 1)
+}
+
+func (s *autoRefreshTestSuite) TestInhibitRefreshWithoutPendingNotification(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	notificationCount := 0
+	restore := snapstate.MockAsyncPendingRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.PendingSnapRefreshInfo) {
+		notificationCount++
+		c.Check(refreshInfo.TimeRemaining, Equals, time.Duration(0))
 	})
+	defer restore()
+
+	si := &snap.SideInfo{RealName: "pkg", Revision: snap.R(1)}
+	info := &snap.Info{SideInfo: *si}
+	snapst := &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	}
+	busy := func(si *snap.Info) error {
+		return &snapstate.BusySnapError{SnapInfo: si}
+	}
+	err := snapstate.InhibitRefresh(s.state, snapst, info, busy, false)
+	c.Assert(err, ErrorMatches, `snap "pkg" has running apps or hooks`)
+	c.Check(notificationCount, Equals, 0)
+	c.Check(snapst.RefreshInhibitedTime, NotNil)
+
+	// the users are still told when the refresh goes ahead anyway
+	pastInstant := time.Now().Add(-snapstate.MaxInhibition * 2)
+	snapst.RefreshInhibitedTime = &pastInstant
+	err = snapstate.InhibitRefresh(s.state, snapst, info, busy, false)
 	c.Assert(err, IsNil)
 	c.Check(notificationCount, Equals, 1)
 }
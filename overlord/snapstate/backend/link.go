@@ -312,6 +312,12 @@ func (b Backend) QueryDisabledServices(info *snap.Info, pb progress.Meter) ([]st
 	return wrappers.QueryDisabledServices(info, pb)
 }
 
+// InactiveServices returns the enabled services of a snap that are
+// expected to be running but are not.
+func (b Backend) InactiveServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	return wrappers.InactiveServices(info, meter)
+}
+
 func removeCurrentSymlinks(info snap.PlaceInfo) error {
 	var err1, err2 error
 
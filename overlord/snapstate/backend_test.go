@@ -29,6 +29,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	. "gopkg.in/check.v1"
 
@@ -157,6 +158,7 @@ type fakeStore struct {
 
 	downloads           []fakeDownload
 	refreshRevnos       map[string]snap.Revision
+	releasedAt          map[string]time.Time
 	fakeBackend         *fakeSnappyBackend
 	fakeCurrentProgress int
 	fakeTotalProgress   int
@@ -425,6 +427,15 @@ func (f *fakeStore) lookupRefresh(cand refreshCand) (*snap.Info, error) {
 	case "channel-for-base/stable":
 		info.Base = "some-base"
 	}
+	if releasedAt, ok := f.releasedAt[cand.channel]; ok {
+		info.Channels = map[string]*snap.ChannelSnapInfo{
+			cand.channel: {
+				Revision:   revno,
+				Channel:    cand.channel,
+				ReleasedAt: releasedAt,
+			},
+		}
+	}
 
 	var hit snap.Revision
 	if cand.revision != revno {
@@ -680,6 +691,7 @@ type fakeSnappyBackend struct {
 	emptyContainer          snap.Container
 
 	servicesCurrentlyDisabled []string
+	servicesCurrentlyInactive []string
 
 	lockDir string
 
@@ -999,6 +1011,14 @@ func (f *fakeSnappyBackend) QueryDisabledServices(info *snap.Info, meter progres
 	return l, nil
 }
 
+func (f *fakeSnappyBackend) InactiveServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	f.appendOp(&fakeOp{
+		op:   "inactive-snap-services",
+		name: info.InstanceName(),
+	})
+	return f.servicesCurrentlyInactive, f.maybeErrForLastOp()
+}
+
 func (f *fakeSnappyBackend) UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, installRecord *backend.InstallRecord, dev boot.Device, p progress.Meter) error {
 	p.Notify("setup-snap")
 	f.appendOp(&fakeOp{
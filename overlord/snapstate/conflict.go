@@ -177,6 +177,12 @@ func CheckChangeConflictMany(st *state.State, instanceNames []string, ignoreChan
 			// conflicts
 			continue
 		}
+		if chg.Kind() == "pre-download" {
+			// pre-download only downloads a snap and waits for its
+			// apps to be closed, the refresh itself happens in a
+			// change of its own
+			continue
+		}
 
 		snaps, err := affectedSnaps(task)
 		if err != nil {
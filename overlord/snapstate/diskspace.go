@@ -0,0 +1,197 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"syscall"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// ReclaimableSpace describes disk space that could be freed to make room
+// for an operation.
+type ReclaimableSpace struct {
+	// Snap and Revision are set for a retained revision of a snap that
+	// could be removed.
+	Snap     string
+	Revision snap.Revision
+	// Path is set for the cached snap files that could be removed.
+	Path string
+	// Size is the amount of disk space that would be freed.
+	Size uint64
+}
+
+// refreshSize returns the disk space needed to refresh the given snaps: the
+// download size of the new revisions and of their missing prerequisites,
+// plus an estimate of the snap data copied for each new revision. Retained
+// revisions are only discarded once a refresh is done, so they do not make
+// room for it.
+// The state must be locked by the caller.
+func refreshSize(st *state.State, snaps []minimalInstallInfo, userID int) (uint64, error) {
+	total, err := installSize(st, snaps, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, inst := range snaps {
+		var snapst SnapState
+		err := Get(st, inst.InstanceName(), &snapst)
+		if err == state.ErrNoState {
+			// accounted for by installSize already
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		// installSize only accounts for snaps that are not installed
+		total += uint64(inst.DownloadSize())
+
+		if inst.Type() == snap.TypeApp && EstimateSnapshotSize != nil {
+			// the data of the current revision is copied over for the
+			// new one, the size of an automatic snapshot is a good
+			// estimate of it
+			dataSize, err := EstimateSnapshotSize(st, inst.InstanceName(), nil)
+			if err != nil {
+				return 0, err
+			}
+			total += dataSize
+		}
+	}
+
+	return total, nil
+}
+
+// checkRefreshDiskSpace checks that there is enough disk space to refresh
+// the given snaps if the check-disk-space-refresh feature is enabled.
+// The returned InsufficientSpaceError suggests what could be removed to
+// make room for the refresh.
+// The state must be locked by the caller.
+func checkRefreshDiskSpace(st *state.State, snaps []minimalInstallInfo, userID int) error {
+	tr := config.NewTransaction(st)
+	checkDiskSpaceRefresh, err := features.Flag(tr, features.CheckDiskSpaceRefresh)
+	if err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	if !checkDiskSpaceRefresh {
+		return nil
+	}
+
+	// check if there is enough disk space for requested snaps and their
+	// prerequisites.
+	totalSize, err := refreshSize(st, snaps, userID)
+	if err != nil {
+		return err
+	}
+	requiredSpace := safetyMarginDiskSpace(totalSize)
+	path := dirs.SnapdStateDir(dirs.GlobalRootDir)
+	if err := osutilCheckFreeSpace(path, requiredSpace); err != nil {
+		if _, ok := err.(*osutil.NotEnoughDiskSpaceError); !ok {
+			return err
+		}
+		names := make([]string, len(snaps))
+		for i, inst := range snaps {
+			names[i] = inst.InstanceName()
+		}
+		reclaimable, err := reclaimableSpace(st)
+		if err != nil {
+			logger.Noticef("cannot determine reclaimable disk space: %v", err)
+		}
+		return &InsufficientSpaceError{
+			Path:          path,
+			Snaps:         names,
+			ChangeKind:    "refresh",
+			RequiredSpace: requiredSpace,
+			Reclaimable:   reclaimable,
+		}
+	}
+	return nil
+}
+
+// reclaimableSpace returns the retained revisions of installed snaps and
+// the cached snap files that could be removed to free disk space, largest
+// first.
+// The state must be locked by the caller.
+func reclaimableSpace(st *state.State) ([]ReclaimableSpace, error) {
+	snapStates, err := All(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var reclaimable []ReclaimableSpace
+	for instanceName, snapst := range snapStates {
+		for _, si := range snapst.Sequence {
+			if si.Revision == snapst.Current {
+				continue
+			}
+			fi, err := os.Stat(snap.MountFile(instanceName, si.Revision))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			reclaimable = append(reclaimable, ReclaimableSpace{
+				Snap:     instanceName,
+				Revision: si.Revision,
+				Size:     uint64(fi.Size()),
+			})
+		}
+	}
+
+	cached, err := ioutil.ReadDir(dirs.SnapDownloadCacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var cacheSize uint64
+	for _, fi := range cached {
+		// cached files are hard links, removing the ones still
+		// linked to a snap file does not free anything
+		if stat, ok := fi.Sys().(*syscall.Stat_t); ok && stat.Nlink > 1 {
+			continue
+		}
+		cacheSize += uint64(fi.Size())
+	}
+	if cacheSize > 0 {
+		reclaimable = append(reclaimable, ReclaimableSpace{
+			Path: dirs.SnapDownloadCacheDir,
+			Size: cacheSize,
+		})
+	}
+
+	sort.SliceStable(reclaimable, func(i, j int) bool {
+		if reclaimable[i].Size != reclaimable[j].Size {
+			return reclaimable[i].Size > reclaimable[j].Size
+		}
+		if reclaimable[i].Snap != reclaimable[j].Snap {
+			return reclaimable[i].Snap < reclaimable[j].Snap
+		}
+		return reclaimable[i].Revision.N < reclaimable[j].Revision.N
+	})
+	return reclaimable, nil
+}
@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) TestRefreshSize(c *C) {
+	restore := snapstate.MockInstallSize(func(st *state.State, snaps []snapstate.MinimalInstallInfo, userID int) (uint64, error) {
+		c.Check(snaps, HasLen, 2)
+		// size of some-base, a missing prerequisite
+		return 1000, nil
+	})
+	defer restore()
+
+	var estimated []string
+	oldEstimateSnapshotSize := snapstate.EstimateSnapshotSize
+	snapstate.EstimateSnapshotSize = func(st *state.State, instanceName string, users []string) (uint64, error) {
+		estimated = append(estimated, instanceName)
+		return 30, nil
+	}
+	defer func() { snapstate.EstimateSnapshotSize = oldEstimateSnapshotSize }()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+	snapstate.Set(s.state, "some-kernel", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-kernel", SnapID: "some-kernel-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "kernel",
+	})
+
+	someSnap := &snap.Info{
+		SideInfo:     snap.SideInfo{RealName: "some-snap", Revision: snap.R(2)},
+		SnapType:     snap.TypeApp,
+		DownloadInfo: snap.DownloadInfo{Size: 100},
+	}
+	someKernel := &snap.Info{
+		SideInfo:     snap.SideInfo{RealName: "some-kernel", Revision: snap.R(2)},
+		SnapType:     snap.TypeKernel,
+		DownloadInfo: snap.DownloadInfo{Size: 200},
+	}
+
+	sz, err := snapstate.RefreshSize(s.state, []snapstate.MinimalInstallInfo{
+		snapstate.InstallSnapInfo{Info: someSnap},
+		snapstate.InstallSnapInfo{Info: someKernel},
+	}, 0)
+	c.Assert(err, IsNil)
+	// prerequisites, the new revisions and the data copy of the app
+	c.Check(sz, Equals, uint64(1000+100+200+30))
+	c.Check(estimated, DeepEquals, []string{"some-snap"})
+}
+
+func (s *snapmgrTestSuite) TestUpdateDiskSpaceErrorReclaimable(c *C) {
+	restore := snapstate.MockOsutilCheckFreeSpace(func(path string, sz uint64) error {
+		return &osutil.NotEnoughDiskSpaceError{}
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.check-disk-space-refresh", true)
+	tr.Commit()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(2)},
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(3)},
+		},
+		Current:  snap.R(3),
+		SnapType: "app",
+	})
+	snapstate.Set(s.state, "other-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "other-snap", SnapID: "other-snap-id", Revision: snap.R(5)},
+			{RealName: "other-snap", SnapID: "other-snap-id", Revision: snap.R(6)},
+		},
+		Current:  snap.R(6),
+		SnapType: "app",
+	})
+
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	for _, blob := range []struct {
+		name string
+		rev  snap.Revision
+		size int
+	}{
+		{"some-snap", snap.R(1), 10},
+		{"some-snap", snap.R(2), 20},
+		{"some-snap", snap.R(3), 30},
+		{"other-snap", snap.R(5), 20},
+		// revision 6 of other-snap is missing
+	} {
+		c.Assert(ioutil.WriteFile(snap.MountFile(blob.name, blob.rev), bytes.Repeat([]byte{'x'}, blob.size), 0644), IsNil)
+	}
+
+	c.Assert(os.MkdirAll(dirs.SnapDownloadCacheDir, 0700), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapDownloadCacheDir, "unused"), bytes.Repeat([]byte{'x'}, 5), 0644), IsNil)
+	// still linked to an installed revision, removing it frees nothing
+	c.Assert(os.Link(snap.MountFile("some-snap", snap.R(3)), filepath.Join(dirs.SnapDownloadCacheDir, "in-use")), IsNil)
+
+	_, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, FitsTypeOf, &snapstate.InsufficientSpaceError{})
+	diskSpaceErr := err.(*snapstate.InsufficientSpaceError)
+	c.Check(diskSpaceErr.Snaps, DeepEquals, []string{"some-snap"})
+	c.Check(diskSpaceErr.RequiredSpace, Not(Equals), uint64(0))
+	c.Check(diskSpaceErr.Reclaimable, DeepEquals, []snapstate.ReclaimableSpace{
+		{Snap: "other-snap", Revision: snap.R(5), Size: 20},
+		{Snap: "some-snap", Revision: snap.R(2), Size: 20},
+		{Snap: "some-snap", Revision: snap.R(1), Size: 10},
+		{Path: dirs.SnapDownloadCacheDir, Size: 5},
+	})
+}
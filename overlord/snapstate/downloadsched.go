@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"time"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// defaultMaxParallelDownloads is the number of snaps downloaded at the
+// same time unless configured otherwise via download.max-parallel.
+const defaultMaxParallelDownloads = 3
+
+// maxParallelDownloads returns how many "download-snap" tasks can run at
+// the same time.
+func maxParallelDownloads(st *state.State) int {
+	tr := config.NewTransaction(st)
+	var n int
+	if err := tr.Get("core", "download.max-parallel", &n); err != nil || n < 1 {
+		return defaultMaxParallelDownloads
+	}
+	return n
+}
+
+// downloadPriority returns the priority of the download of the given
+// snap, downloads of essential snaps come first, then the ones of user
+// initiated changes and lastly the ones of auto-refreshes.
+func downloadPriority(snapsup *SnapSetup) int {
+	prio := 0
+	if !snapsup.IsAutoRefresh {
+		prio++
+	}
+	switch snapsup.Type {
+	case snap.TypeSnapd, snap.TypeOS, snap.TypeBase, snap.TypeKernel, snap.TypeGadget:
+		prio += 2
+	}
+	return prio
+}
+
+func isPendingDownload(t *state.Task, now time.Time) bool {
+	if t.Kind() != "download-snap" {
+		return false
+	}
+	switch t.Status() {
+	case state.DoStatus, state.DoingStatus:
+	default:
+		return false
+	}
+	for _, wt := range t.WaitTasks() {
+		if wt.Status() != state.DoneStatus {
+			return false
+		}
+	}
+	if when := t.AtTime(); !when.IsZero() && now.Before(when) {
+		return false
+	}
+	return true
+}
+
+// blockedDownload returns whether the download task cand must wait,
+// either because the maximum number of parallel downloads is reached or
+// because the remaining download slots are taken by downloads with a
+// higher priority that are ready to run.
+func blockedDownload(cand *state.Task, running []*state.Task) bool {
+	if cand.Kind() != "download-snap" {
+		return false
+	}
+	switch cand.Status() {
+	case state.DoStatus, state.DoingStatus:
+	default:
+		// undoing a download does not download anything
+		return false
+	}
+
+	st := cand.State()
+	busy := 0
+	for _, t := range running {
+		if t.Kind() == "download-snap" && t.Status() == state.DoingStatus {
+			busy++
+		}
+	}
+	max := maxParallelDownloads(st)
+	if busy >= max {
+		return true
+	}
+
+	snapsup, err := TaskSnapSetup(cand)
+	if err != nil {
+		// let the task handler report the error
+		return false
+	}
+	prio := downloadPriority(snapsup)
+
+	isRunning := make(map[string]bool, len(running))
+	for _, t := range running {
+		isRunning[t.ID()] = true
+	}
+	now := timeNow()
+	for _, t := range st.Tasks() {
+		if t == cand || isRunning[t.ID()] || !isPendingDownload(t, now) {
+			continue
+		}
+		other, err := TaskSnapSetup(t)
+		if err != nil {
+			continue
+		}
+		if downloadPriority(other) > prio {
+			busy++
+			if busy >= max {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DownloadProgress returns the aggregate progress in bytes of the snap
+// downloads of the given change. ok is false if the change downloads
+// nothing.
+func DownloadProgress(chg *state.Change) (done, total int64, ok bool) {
+	for _, t := range chg.Tasks() {
+		if t.Kind() != "download-snap" {
+			continue
+		}
+		status := t.Status()
+		switch status {
+		case state.DoStatus, state.DoingStatus, state.DoneStatus:
+		default:
+			continue
+		}
+		snapsup, err := TaskSnapSetup(t)
+		if err != nil {
+			continue
+		}
+		var size int64
+		if snapsup.DownloadInfo != nil {
+			size = snapsup.DownloadInfo.Size
+		}
+		ok = true
+		switch status {
+		case state.DoneStatus:
+			done += size
+			total += size
+		case state.DoingStatus:
+			// the task progress is reported in bytes once the
+			// download started
+			if _, d, tot := t.Progress(); tot > 1 {
+				done += int64(d)
+				total += int64(tot)
+				continue
+			}
+			total += size
+		default:
+			total += size
+		}
+	}
+	return done, total, ok
+}
@@ -0,0 +1,142 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type downloadSchedSuite struct {
+	state *state.State
+	chg   *state.Change
+}
+
+var _ = Suite(&downloadSchedSuite{})
+
+func (s *downloadSchedSuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+	s.state.Lock()
+	s.chg = s.state.NewChange("refresh", "...")
+	s.state.Unlock()
+}
+
+func (s *downloadSchedSuite) setMaxParallel(c *C, n int) {
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "download.max-parallel", n), IsNil)
+	tr.Commit()
+}
+
+func (s *downloadSchedSuite) download(name string, typ snap.Type, autoRefresh bool, size int64) *state.Task {
+	t := s.state.NewTask("download-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo:      &snap.SideInfo{RealName: name},
+		Type:          typ,
+		IsAutoRefresh: autoRefresh,
+		DownloadInfo:  &snap.DownloadInfo{Size: size},
+	})
+	s.chg.AddTask(t)
+	return t
+}
+
+func (s *downloadSchedSuite) TestMaxParallelDownloads(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(snapstate.MaxParallelDownloads(s.state), Equals, 3)
+	s.setMaxParallel(c, 5)
+	c.Check(snapstate.MaxParallelDownloads(s.state), Equals, 5)
+}
+
+func (s *downloadSchedSuite) TestBlockedDownloadLimit(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setMaxParallel(c, 2)
+
+	t1 := s.download("foo", snap.TypeApp, false, 10)
+	t2 := s.download("bar", snap.TypeApp, false, 10)
+	t3 := s.download("baz", snap.TypeApp, false, 10)
+	other := s.state.NewTask("link-snap", "...")
+	other.SetStatus(state.DoingStatus)
+
+	c.Check(snapstate.BlockedDownload(t1, []*state.Task{other}), Equals, false)
+	t1.SetStatus(state.DoingStatus)
+	c.Check(snapstate.BlockedDownload(t2, []*state.Task{other, t1}), Equals, false)
+	t2.SetStatus(state.DoingStatus)
+	c.Check(snapstate.BlockedDownload(t3, []*state.Task{other, t1, t2}), Equals, true)
+
+	// a slot is free again
+	t1.SetStatus(state.DoneStatus)
+	c.Check(snapstate.BlockedDownload(t3, []*state.Task{other, t2}), Equals, false)
+
+	// other tasks are never blocked
+	c.Check(snapstate.BlockedDownload(other, []*state.Task{t2, t3}), Equals, false)
+}
+
+func (s *downloadSchedSuite) TestBlockedDownloadPriority(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setMaxParallel(c, 2)
+
+	autoApp := s.download("foo", snap.TypeApp, true, 10)
+	userApp := s.download("bar", snap.TypeApp, false, 10)
+	autoKernel := s.download("pc-kernel", snap.TypeKernel, true, 10)
+
+	// the kernel download and the user initiated one take the two slots
+	c.Check(snapstate.BlockedDownload(autoApp, nil), Equals, true)
+	c.Check(snapstate.BlockedDownload(userApp, nil), Equals, false)
+	c.Check(snapstate.BlockedDownload(autoKernel, nil), Equals, false)
+
+	// downloads that are not ready to run do not take slots
+	prereq := s.state.NewTask("prerequisites", "...")
+	userApp.WaitFor(prereq)
+	c.Check(snapstate.BlockedDownload(autoApp, nil), Equals, false)
+
+	autoKernel.SetStatus(state.DoingStatus)
+	c.Check(snapstate.BlockedDownload(autoApp, []*state.Task{autoKernel}), Equals, false)
+	prereq.SetStatus(state.DoneStatus)
+	c.Check(snapstate.BlockedDownload(autoApp, []*state.Task{autoKernel}), Equals, true)
+	c.Check(snapstate.BlockedDownload(userApp, []*state.Task{autoKernel}), Equals, false)
+}
+
+func (s *downloadSchedSuite) TestDownloadProgress(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, _, ok := snapstate.DownloadProgress(s.chg)
+	c.Check(ok, Equals, false)
+
+	t1 := s.download("foo", snap.TypeApp, false, 100)
+	t2 := s.download("bar", snap.TypeApp, false, 200)
+	s.download("baz", snap.TypeApp, false, 300)
+
+	t1.SetStatus(state.DoneStatus)
+	t2.SetStatus(state.DoingStatus)
+	t2.SetProgress("bar", 50, 200)
+
+	done, total, ok := snapstate.DownloadProgress(s.chg)
+	c.Check(ok, Equals, true)
+	c.Check(done, Equals, int64(150))
+	c.Check(total, Equals, int64(600))
+}
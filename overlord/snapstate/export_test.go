@@ -113,6 +113,7 @@ func PreviousSideInfo(snapst *SnapState) *snap.SideInfo {
 
 // helpers
 var InstallSize = installSize
+var RefreshSize = refreshSize
 
 // aliases v2
 var (
@@ -181,6 +182,14 @@ func MockIsOnMeteredConnection(mock func() (bool, error)) func() {
 	}
 }
 
+func MockBatteryStatus(mock func() (bool, float64, error)) func() {
+	old := BatteryStatus
+	BatteryStatus = mock
+	return func() {
+		BatteryStatus = old
+	}
+}
+
 func MockLocalInstallCleanupWait(d time.Duration) (restore func()) {
 	old := localInstallCleanupWait
 	localInstallCleanupWait = d
@@ -248,6 +257,22 @@ func (m *SnapManager) MaybeUndoRemodelBootChanges(t *state.Task) error {
 	return m.maybeUndoRemodelBootChanges(t)
 }
 
+func MockAsyncFinishRefreshNotification(fn func(context.Context, *userclient.Client, *userclient.FinishedSnapRefreshInfo)) (restore func()) {
+	old := asyncFinishRefreshNotification
+	asyncFinishRefreshNotification = fn
+	return func() {
+		asyncFinishRefreshNotification = old
+	}
+}
+
+func MockInhibitedRefreshPollInterval(d time.Duration) (restore func()) {
+	old := inhibitedRefreshPollInterval
+	inhibitedRefreshPollInterval = d
+	return func() {
+		inhibitedRefreshPollInterval = old
+	}
+}
+
 func MockPidsOfSnap(f func(instanceName string) (map[string][]int, error)) func() {
 	old := pidsOfSnap
 	pidsOfSnap = f
@@ -330,9 +355,13 @@ var (
 	HeldSnaps                  = heldSnaps
 	ResetGatingForRefreshed    = resetGatingForRefreshed
 	CreateGateAutoRefreshHooks = createGateAutoRefreshHooks
-	AutoRefreshPhase1          = autoRefreshPhase1
+	ParseGatePolicyDecision    = parseGatePolicyDecision
 )
 
+func AutoRefreshPhase1(ctx context.Context, st *state.State) ([]string, []*state.TaskSet, error) {
+	return autoRefreshPhase1(ctx, st, false)
+}
+
 func MockTimeNow(f func() time.Time) (restore func()) {
 	old := timeNow
 	timeNow = f
@@ -363,3 +392,16 @@ func MockSnapsToRefresh(f func(gatingTask *state.Task) ([]*refreshCandidate, err
 		snapsToRefresh = old
 	}
 }
+
+var (
+	BlockedDownload      = blockedDownload
+	MaxParallelDownloads = maxParallelDownloads
+)
+
+func MockMaxRefreshHistory(n int) (restore func()) {
+	old := maxRefreshHistory
+	maxRefreshHistory = n
+	return func() {
+		maxRefreshHistory = old
+	}
+}
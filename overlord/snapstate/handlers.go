@@ -553,7 +553,10 @@ func downloadSnapParams(st *state.State, t *state.Task) (*SnapSetup, StoreServic
 		return nil, nil, nil, err
 	}
 
-	sto := Store(st, deviceCtx)
+	sto, err := storeForSnapSetup(st, snapsup, deviceCtx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	user, err := userFromUserID(st, snapsup.UserID)
 	if err != nil {
@@ -1195,6 +1198,11 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 	snapst.Classic = snapsup.Classic
 	oldCohortKey := snapst.CohortKey
 	snapst.CohortKey = snapsup.CohortKey
+	oldStoreBackend := snapst.StoreBackend
+	if snapsup.DownloadInfo != nil {
+		// only revisions coming from a store change the store backend
+		snapst.StoreBackend = snapsup.StoreBackend
+	}
 	if snapsup.Required { // set only on install and left alone on refresh
 		snapst.Required = true
 	}
@@ -1311,6 +1319,7 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 	t.Set("old-candidate-index", oldCandidateIndex)
 	t.Set("old-refresh-inhibited-time", oldRefreshInhibitedTime)
 	t.Set("old-cohort-key", oldCohortKey)
+	t.Set("old-store-backend", oldStoreBackend)
 	t.Set("old-last-refresh-time", oldLastRefreshTime)
 
 	// Record the fact that the snap was refreshed successfully.
@@ -1370,6 +1379,12 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 		}
 	}
 
+	if isInstalled && oldCurrent != cand.Revision {
+		if err := recordRefresh(t, snapsup, oldCurrent); err != nil {
+			return err
+		}
+	}
+
 	// Do at the end so we only preserve the new state if it worked.
 	Set(st, snapsup.InstanceName(), snapst)
 
@@ -1577,6 +1592,10 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err := t.Get("old-cohort-key", &oldCohortKey); err != nil && err != state.ErrNoState {
 		return err
 	}
+	var oldStoreBackend string
+	if err := t.Get("old-store-backend", &oldStoreBackend); err != nil && err != state.ErrNoState {
+		return err
+	}
 
 	if len(snapst.Sequence) == 1 {
 		// XXX: shouldn't these two just log and carry on? this is an undo handler...
@@ -1622,6 +1641,7 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.RefreshInhibitedTime = oldRefreshInhibitedTime
 	snapst.LastRefreshTime = oldLastRefreshTime
 	snapst.CohortKey = oldCohortKey
+	snapst.StoreBackend = oldStoreBackend
 
 	newInfo, err := readInfo(snapsup.InstanceName(), snapsup.SideInfo, 0)
 	if err != nil {
@@ -1684,6 +1704,12 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 		m.maybeRestart(t, newInfo, rebootRequired, deviceCtx)
 	}
 
+	if !firstInstall && oldCurrent != snapsup.Revision() {
+		if err := recordRefreshUndone(t, snapsup); err != nil {
+			return err
+		}
+	}
+
 	// write sequence file for failover helpers
 	if err := writeSeqFile(snapsup.InstanceName(), snapst); err != nil {
 		return err
@@ -2291,6 +2317,19 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		if err := EnsureSnapAbsentFromQuotaGroup(st, snapsup.InstanceName()); err != nil {
 			return err
 		}
+
+		// forget about any hold, refresh timer or tracking policy set
+		// by the administrator
+		if err := UnholdRefreshesBySystem(st, snapsup.InstanceName()); err != nil {
+			return err
+		}
+		if err := RemoveTrackingPolicy(st, snapsup.InstanceName()); err != nil {
+			return err
+		}
+
+		if err := discardRefreshHistory(st, snapsup.InstanceName()); err != nil {
+			return err
+		}
 	}
 	if err = config.DiscardRevisionConfig(st, snapsup.InstanceName(), snapsup.Revision()); err != nil {
 		return err
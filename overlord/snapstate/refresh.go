@@ -203,7 +203,7 @@ func hardEnsureNothingRunningDuringRefresh(backend managerBackend, st *state.Sta
 		// to indicate when the refresh was first inhibited. If the first
 		// refresh inhibition is outside of a grace period then refresh
 		// proceeds regardless of the existing processes.
-		return inhibitRefresh(st, snapst, info, HardNothingRunningRefreshCheck)
+		return inhibitRefresh(st, snapst, info, HardNothingRunningRefreshCheck, true)
 	})
 }
 
@@ -218,7 +218,9 @@ func hardEnsureNothingRunningDuringRefresh(backend managerBackend, st *state.Sta
 // refresh was first postponed. Eventually the check does not fail, even if
 // non-service apps are running, because this mechanism only allows postponing
 // refreshes for a bounded amount of time.
-func softCheckNothingRunningForRefresh(st *state.State, snapst *SnapState, info *snap.Info) error {
+//
+// The users are notified about the pending refresh if notifyPending is set.
+func softCheckNothingRunningForRefresh(st *state.State, snapst *SnapState, info *snap.Info, notifyPending bool) error {
 	// Grab per-snap lock to prevent new processes from starting. This is
 	// sufficient to perform the check, even though individual processes may
 	// fork or exit, we will have per-security-tag information about what is
@@ -226,6 +228,6 @@ func softCheckNothingRunningForRefresh(st *state.State, snapst *SnapState, info
 	return backend.WithSnapLock(info, func() error {
 		// Perform the soft refresh viability check, possibly writing to the state
 		// on failure.
-		return inhibitRefresh(st, snapst, info, SoftNothingRunningRefreshCheck)
+		return inhibitRefresh(st, snapst, info, SoftNothingRunningRefreshCheck, notifyPending)
 	})
 }
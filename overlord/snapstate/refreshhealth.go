@@ -0,0 +1,198 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// defaultRollbackGracePeriod is how long snaps are given to become
+// healthy after a refresh unless configured otherwise via
+// refresh.rollback-grace-period.
+const defaultRollbackGracePeriod = 1 * time.Minute
+
+// CheckSnapHealth allows to hook checking the health reported by the
+// given snap revision, it returns an error describing the problem if the
+// snap is unhealthy.
+var CheckSnapHealth func(st *state.State, instanceName string, rev snap.Revision) error
+
+// rollbackOnUnhealthy returns whether a refresh of the given snap must be
+// reverted if the snap is unhealthy afterwards, as configured via
+// refresh.rollback-unhealthy which is either "all" or a comma separated
+// list of snaps.
+func rollbackOnUnhealthy(st *state.State, instanceName string) (bool, error) {
+	tr := config.NewTransaction(st)
+	var opt string
+	if err := tr.Get("core", "refresh.rollback-unhealthy", &opt); err != nil && !config.IsNoOption(err) {
+		return false, err
+	}
+	switch opt {
+	case "":
+		return false, nil
+	case "all":
+		return true, nil
+	}
+	return strutil.ListContains(strings.Split(opt, ","), instanceName), nil
+}
+
+func rollbackGracePeriod(st *state.State) time.Duration {
+	tr := config.NewTransaction(st)
+	var opt string
+	if err := tr.Get("core", "refresh.rollback-grace-period", &opt); err != nil || opt == "" {
+		return defaultRollbackGracePeriod
+	}
+	grace, err := time.ParseDuration(opt)
+	if err != nil || grace < 0 {
+		logger.Noticef("cannot use refresh.rollback-grace-period %q, using default", opt)
+		return defaultRollbackGracePeriod
+	}
+	return grace
+}
+
+type refreshHealth struct {
+	Revision snap.Revision `json:"revision"`
+	// Status is either "healthy" or "reverted".
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// recordRefreshHealth records the outcome of the health check of a
+// refreshed snap in the "refresh-health" entry of the change api-data.
+func recordRefreshHealth(chg *state.Change, instanceName string, health *refreshHealth) error {
+	var data map[string]interface{}
+	err := chg.Get("api-data", &data)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if len(data) == 0 {
+		data = make(map[string]interface{})
+	}
+	healths, _ := data["refresh-health"].(map[string]interface{})
+	if healths == nil {
+		healths = make(map[string]interface{})
+	}
+	healths[instanceName] = health
+	data["refresh-health"] = healths
+	chg.Set("api-data", data)
+	return nil
+}
+
+// waitGracePeriod returns a state.Retry error until the given period has
+// passed since it was first called for the task.
+func waitGracePeriod(t *state.Task, period time.Duration) error {
+	var waitUntil time.Time
+	err := t.Get("wait-until", &waitUntil)
+	if err == state.ErrNoState {
+		waitUntil = timeNow().Add(period)
+		t.Set("wait-until", waitUntil)
+	} else if err != nil {
+		return err
+	}
+	if wait := waitUntil.Sub(timeNow()); wait > 0 {
+		return &state.Retry{After: wait, Reason: "waiting for the grace period to pass"}
+	}
+	return nil
+}
+
+// snapHealthProblems returns the problems of the current revision of the
+// given snap, both its reported health and its services are checked.
+// The state must be locked by the caller, it is unlocked while querying
+// the services.
+func (m *SnapManager) snapHealthProblems(st *state.State, info *snap.Info) ([]string, error) {
+	var problems []string
+	if CheckSnapHealth != nil {
+		if err := CheckSnapHealth(st, info.InstanceName(), info.Revision); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	st.Unlock()
+	inactive, err := m.backend.InactiveServices(info, progress.Null)
+	st.Lock()
+	if err != nil {
+		return nil, err
+	}
+	if len(inactive) != 0 {
+		problems = append(problems, fmt.Sprintf("services %s are not running", strutil.Quoted(inactive)))
+	}
+	return problems, nil
+}
+
+// doCheckRefreshHealth waits for the grace period after a refresh and
+// then checks the health and the services of the refreshed snap. If the
+// snap is unhealthy the task errors so that the refresh is undone,
+// reverting the snap to its previous revision.
+func (m *SnapManager) doCheckRefreshHealth(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	instanceName := snapsup.InstanceName()
+	rev := snapsup.Revision()
+	if snapst.Current != rev {
+		// the snap was reverted or refreshed again meanwhile
+		return nil
+	}
+
+	if err := waitGracePeriod(t, rollbackGracePeriod(st)); err != nil {
+		return err
+	}
+
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	problems, err := m.snapHealthProblems(st, info)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		return recordRefreshHealth(t.Change(), instanceName, &refreshHealth{
+			Revision: rev,
+			Status:   "healthy",
+		})
+	}
+
+	msg := strings.Join(problems, "; ")
+	if err := recordRefreshHealth(t.Change(), instanceName, &refreshHealth{
+		Revision: rev,
+		Status:   "reverted",
+		Message:  msg,
+	}); err != nil {
+		return err
+	}
+	st.Warnf("refresh of snap %q to revision %s was reverted, the snap was unhealthy: %s", instanceName, rev, msg)
+	return fmt.Errorf("snap %q is unhealthy after refresh to revision %s: %s", instanceName, rev, msg)
+}
@@ -0,0 +1,243 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setupRefreshHealth(c *C, rollbackUnhealthy, gracePeriod string) *state.Change {
+	si := &snap.SideInfo{
+		RealName: "some-snap",
+		SnapID:   "some-snap-id",
+		Revision: snap.R(7),
+	}
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:          true,
+		Sequence:        []*snap.SideInfo{si},
+		Current:         si.Revision,
+		TrackingChannel: "latest/stable",
+		SnapType:        "app",
+	})
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.rollback-unhealthy", rollbackUnhealthy)
+	tr.Set("core", "refresh.rollback-grace-period", gracePeriod)
+	tr.Commit()
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "some-channel"}, s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	return chg
+}
+
+func (s *snapmgrTestSuite) mockCheckSnapHealth(f func(st *state.State, instanceName string, rev snap.Revision) error) {
+	old := snapstate.CheckSnapHealth
+	snapstate.CheckSnapHealth = f
+	s.AddCleanup(func() { snapstate.CheckSnapHealth = old })
+}
+
+func findTaskOfKind(chg *state.Change, kind string) *state.Task {
+	for _, t := range chg.Tasks() {
+		if t.Kind() == kind {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *snapmgrTestSuite) TestUpdateNoRefreshHealthCheckByDefault(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.setupRefreshHealth(c, "", "")
+	c.Check(findTaskOfKind(chg, "check-refresh-health"), IsNil)
+	chg.Abort()
+
+	chg = s.setupRefreshHealth(c, "other-snap", "")
+	c.Check(findTaskOfKind(chg, "check-refresh-health"), IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateRefreshHealthCheckTask(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, opt := range []string{"all", "other-snap,some-snap"} {
+		chg := s.setupRefreshHealth(c, opt, "")
+		t := findTaskOfKind(chg, "check-refresh-health")
+		c.Assert(t, NotNil, Commentf(opt))
+		c.Check(t.Summary(), Equals, `Check health of snap "some-snap" (11) after refresh`)
+		// it runs after the check-health hook
+		c.Assert(t.WaitTasks(), HasLen, 1)
+		c.Check(t.WaitTasks()[0].Kind(), Equals, "run-hook")
+		snapsup, err := snapstate.TaskSnapSetup(t)
+		c.Assert(err, IsNil)
+		c.Check(snapsup.InstanceName(), Equals, "some-snap")
+		chg.Abort()
+	}
+}
+
+func (s *snapmgrTestSuite) TestUpdateHealthyAfterRefresh(c *C) {
+	s.mockCheckSnapHealth(func(st *state.State, instanceName string, rev snap.Revision) error {
+		c.Check(instanceName, Equals, "some-snap")
+		c.Check(rev, Equals, snap.R(11))
+		return nil
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.setupRefreshHealth(c, "all", "0s")
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops.First("inactive-snap-services"), NotNil)
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(11))
+
+	var apiData map[string]interface{}
+	c.Assert(chg.Get("api-data", &apiData), IsNil)
+	c.Check(apiData["refresh-health"], DeepEquals, map[string]interface{}{
+		"some-snap": map[string]interface{}{
+			"revision": "11",
+			"status":   "healthy",
+		},
+	})
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) testUpdateRevertedWhenUnhealthy(c *C, problem string) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.setupRefreshHealth(c, "some-snap", "0s")
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, fmt.Sprintf(`(?s).*snap "some-snap" is unhealthy after refresh to revision 11: %s.*`, problem))
+	c.Check(findTaskOfKind(chg, "link-snap").Status(), Equals, state.UndoneStatus)
+
+	// back to the previous revision
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(7))
+	c.Check(snapst.Active, Equals, true)
+
+	var apiData map[string]interface{}
+	c.Assert(chg.Get("api-data", &apiData), IsNil)
+	c.Check(apiData["refresh-health"], DeepEquals, map[string]interface{}{
+		"some-snap": map[string]interface{}{
+			"revision": "11",
+			"status":   "reverted",
+			"message":  problem,
+		},
+	})
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, fmt.Sprintf(`refresh of snap "some-snap" to revision 11 was reverted, the snap was unhealthy: %s`, problem))
+}
+
+func (s *snapmgrTestSuite) TestUpdateRevertedWhenHealthError(c *C) {
+	s.mockCheckSnapHealth(func(st *state.State, instanceName string, rev snap.Revision) error {
+		return fmt.Errorf(`health status is "error": no network`)
+	})
+
+	s.testUpdateRevertedWhenUnhealthy(c, `health status is "error": no network`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateRevertedWhenServicesInactive(c *C) {
+	s.mockCheckSnapHealth(func(st *state.State, instanceName string, rev snap.Revision) error {
+		return nil
+	})
+	s.fakeBackend.servicesCurrentlyInactive = []string{"svc1", "svc2"}
+
+	s.testUpdateRevertedWhenUnhealthy(c, `services "svc1", "svc2" are not running`)
+}
+
+func (s *snapmgrTestSuite) TestRefreshHealthWaitsForGracePeriod(c *C) {
+	var checked int
+	s.mockCheckSnapHealth(func(st *state.State, instanceName string, rev snap.Revision) error {
+		checked++
+		return nil
+	})
+	now := time.Now()
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.setupRefreshHealth(c, "all", "10m")
+	t := findTaskOfKind(chg, "check-refresh-health")
+	c.Assert(t, NotNil)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	for i := 0; i < 50; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+
+		s.state.Lock()
+		waiting := t.Has("wait-until")
+		s.state.Unlock()
+		if waiting {
+			break
+		}
+	}
+	s.state.Lock()
+
+	c.Check(t.Status(), Equals, state.DoingStatus)
+	c.Check(checked, Equals, 0)
+	var waitUntil time.Time
+	c.Assert(t.Get("wait-until", &waitUntil), IsNil)
+	c.Check(waitUntil.Equal(now.Add(10*time.Minute)), Equals, true)
+
+	// once the grace period is over the snap is checked
+	now = now.Add(11 * time.Minute)
+	t.At(time.Time{})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(checked, Equals, 1)
+}
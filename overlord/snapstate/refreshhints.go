@@ -150,12 +150,24 @@ func refreshHintsFromCandidates(st *state.State, updates []*snap.Info, ignoreVal
 		}
 	}
 
+	policies, err := trackingPolicies(st)
+	if err != nil {
+		return nil, err
+	}
+
 	hints := make(map[string]*refreshCandidate, len(updates))
 	for _, update := range updates {
 		var snapst SnapState
 		if err := Get(st, update.InstanceName(), &snapst); err != nil {
 			return nil, err
 		}
+		trackingChannel := snapst.TrackingChannel
+		if policy := policies[update.InstanceName()]; policy != nil {
+			// the candidate comes from the channel of the policy
+			if ch, err := policy.ChannelFor(snapst.TrackingChannel); err == nil {
+				trackingChannel = ch
+			}
+		}
 
 		flags := snapst.Flags
 		flags.IsAutoRefresh = true
@@ -165,12 +177,18 @@ func refreshHintsFromCandidates(st *state.State, updates []*snap.Info, ignoreVal
 			continue
 		}
 
+		storeBackend, err := storeBackendFor(st, &snapst, update)
+		if err != nil {
+			return nil, err
+		}
+
 		snapsup := &refreshCandidate{
 			SnapSetup: SnapSetup{
-				Base:      update.Base,
-				Prereq:    defaultContentPlugProviders(st, update),
-				Channel:   snapst.TrackingChannel,
-				CohortKey: snapst.CohortKey,
+				Base:         update.Base,
+				Prereq:       defaultContentPlugProviders(st, update),
+				Channel:      trackingChannel,
+				CohortKey:    snapst.CohortKey,
+				StoreBackend: storeBackend,
 				// UserID not set
 				Flags:        flags.ForSnapSetup(),
 				DownloadInfo: &update.DownloadInfo,
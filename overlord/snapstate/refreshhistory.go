@@ -0,0 +1,199 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// maxRefreshHistory is the number of refresh history entries kept for
+// each snap, older entries are dropped.
+var maxRefreshHistory = 20
+
+// RefreshTrigger describes what caused a refresh of a snap.
+type RefreshTrigger string
+
+const (
+	// RefreshTriggerManual is used for refreshes and reverts requested
+	// explicitly.
+	RefreshTriggerManual RefreshTrigger = "manual"
+	// RefreshTriggerAuto is used for auto-refreshes.
+	RefreshTriggerAuto RefreshTrigger = "auto"
+	// RefreshTriggerValidationSet is used for refreshes to the revision
+	// required by the validation sets in enforcing mode.
+	RefreshTriggerValidationSet RefreshTrigger = "validation-set"
+	// RefreshTriggerRemodel is used for refreshes done as part of a
+	// remodel.
+	RefreshTriggerRemodel RefreshTrigger = "remodel"
+)
+
+// RefreshOutcome describes how a refresh of a snap ended.
+type RefreshOutcome string
+
+const (
+	// RefreshOutcomeRefreshed is used for refreshes that took effect.
+	RefreshOutcomeRefreshed RefreshOutcome = "refreshed"
+	// RefreshOutcomeReverted is used for refreshes that were undone,
+	// either because the change failed or because the snap was
+	// unhealthy afterwards.
+	RefreshOutcomeReverted RefreshOutcome = "reverted"
+)
+
+// RefreshHistoryEntry records a refresh or a revert of a snap.
+type RefreshHistoryEntry struct {
+	FromRevision snap.Revision  `json:"from-revision"`
+	ToRevision   snap.Revision  `json:"to-revision"`
+	Channel      string         `json:"channel,omitempty"`
+	Time         time.Time      `json:"time"`
+	ChangeID     string         `json:"change-id,omitempty"`
+	Trigger      RefreshTrigger `json:"trigger"`
+	// Revert is set if the snap was reverted to a revision it had
+	// before.
+	Revert  bool           `json:"revert,omitempty"`
+	Outcome RefreshOutcome `json:"outcome"`
+}
+
+func refreshHistories(st *state.State) (map[string][]*RefreshHistoryEntry, error) {
+	// snap -> refresh history, oldest first
+	var histories map[string][]*RefreshHistoryEntry
+	err := st.Get("refresh-history", &histories)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("internal error: cannot get refresh-history: %v", err)
+	}
+	if err == state.ErrNoState {
+		return make(map[string][]*RefreshHistoryEntry), nil
+	}
+	return histories, nil
+}
+
+// RefreshHistory returns the recorded refreshes and reverts of the given
+// snap, oldest first.
+func RefreshHistory(st *state.State, instanceName string) ([]*RefreshHistoryEntry, error) {
+	histories, err := refreshHistories(st)
+	if err != nil {
+		return nil, err
+	}
+	return histories[instanceName], nil
+}
+
+// refreshTriggerFor determines what causes the refresh set up by snapsup,
+// when its tasks are created.
+func refreshTriggerFor(snapsup *SnapSetup, vsets *snapasserts.ValidationSets, deviceCtx DeviceContext) RefreshTrigger {
+	if deviceCtx != nil && deviceCtx.ForRemodeling() {
+		return RefreshTriggerRemodel
+	}
+	if vsets != nil {
+		// refreshes go to the revision required by the validation
+		// sets, if any
+		rev, _, err := vsets.Revision(naming.NewSnapRef(snapsup.SnapName(), snapsup.SideInfo.SnapID))
+		if err == nil && !rev.Unset() && rev == snapsup.Revision() {
+			return RefreshTriggerValidationSet
+		}
+	}
+	if snapsup.IsAutoRefresh {
+		return RefreshTriggerAuto
+	}
+	return RefreshTriggerManual
+}
+
+// refreshTrigger returns what caused the refresh done by the given
+// link-snap task.
+func refreshTrigger(snapsup *SnapSetup) RefreshTrigger {
+	if snapsup.RefreshTrigger != "" {
+		return snapsup.RefreshTrigger
+	}
+	// reverts and tasks created by older versions of snapd
+	if snapsup.IsAutoRefresh {
+		return RefreshTriggerAuto
+	}
+	return RefreshTriggerManual
+}
+
+// recordRefresh adds an entry for the refresh of the snap from the given
+// revision done by the given link-snap task to the refresh history.
+func recordRefresh(t *state.Task, snapsup *SnapSetup, from snap.Revision) error {
+	st := t.State()
+	histories, err := refreshHistories(st)
+	if err != nil {
+		return err
+	}
+	entry := &RefreshHistoryEntry{
+		FromRevision: from,
+		ToRevision:   snapsup.Revision(),
+		Channel:      snapsup.Channel,
+		Time:         timeNow(),
+		Trigger:      refreshTrigger(snapsup),
+		Revert:       snapsup.Revert,
+		Outcome:      RefreshOutcomeRefreshed,
+	}
+	if chg := t.Change(); chg != nil {
+		entry.ChangeID = chg.ID()
+	}
+	history := append(histories[snapsup.InstanceName()], entry)
+	if len(history) > maxRefreshHistory {
+		history = history[len(history)-maxRefreshHistory:]
+	}
+	histories[snapsup.InstanceName()] = history
+	st.Set("refresh-history", histories)
+	return nil
+}
+
+// recordRefreshUndone marks the refresh done by the given link-snap task
+// as reverted in the refresh history.
+func recordRefreshUndone(t *state.Task, snapsup *SnapSetup) error {
+	chg := t.Change()
+	if chg == nil {
+		return nil
+	}
+	st := t.State()
+	histories, err := refreshHistories(st)
+	if err != nil {
+		return err
+	}
+	history := histories[snapsup.InstanceName()]
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if entry.ChangeID == chg.ID() && entry.ToRevision == snapsup.Revision() {
+			entry.Outcome = RefreshOutcomeReverted
+			st.Set("refresh-history", histories)
+			break
+		}
+	}
+	return nil
+}
+
+// discardRefreshHistory forgets the refresh history of the given snap.
+func discardRefreshHistory(st *state.State, instanceName string) error {
+	histories, err := refreshHistories(st)
+	if err != nil {
+		return err
+	}
+	if _, ok := histories[instanceName]; ok {
+		delete(histories, instanceName)
+		st.Set("refresh-history", histories)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setupRefreshHistory(c *C) time.Time {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	s.AddCleanup(snapstate.MockTimeNow(func() time.Time { return now }))
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:         snap.R(1),
+		SnapType:        "app",
+		TrackingChannel: "latest/stable",
+	})
+	return now
+}
+
+// settleLocked is like settle but for callers holding the state lock.
+func (s *snapmgrTestSuite) settleLocked(c *C) {
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+}
+
+func (s *snapmgrTestSuite) TestRefreshHistoryRefreshAndRevert(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	defer s.se.Stop()
+
+	now := s.setupRefreshHistory(c)
+
+	ts, err := snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Channel: "latest/stable"}, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	refreshChg := s.state.NewChange("refresh", "...")
+	refreshChg.AddAll(ts)
+	s.settleLocked(c)
+	c.Assert(refreshChg.Err(), IsNil)
+
+	ts, err = snapstate.Revert(s.state, "some-snap", snapstate.Flags{})
+	c.Assert(err, IsNil)
+	revertChg := s.state.NewChange("revert", "...")
+	revertChg.AddAll(ts)
+	s.settleLocked(c)
+	c.Assert(revertChg.Err(), IsNil)
+
+	history, err := snapstate.RefreshHistory(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, DeepEquals, []*snapstate.RefreshHistoryEntry{
+		{
+			FromRevision: snap.R(1),
+			ToRevision:   snap.R(11),
+			Channel:      "latest/stable",
+			Time:         now,
+			ChangeID:     refreshChg.ID(),
+			Trigger:      snapstate.RefreshTriggerManual,
+			Outcome:      snapstate.RefreshOutcomeRefreshed,
+		}, {
+			FromRevision: snap.R(11),
+			ToRevision:   snap.R(1),
+			Time:         now,
+			ChangeID:     revertChg.ID(),
+			Trigger:      snapstate.RefreshTriggerManual,
+			Revert:       true,
+			Outcome:      snapstate.RefreshOutcomeRefreshed,
+		},
+	})
+
+	// installs are not recorded
+	history, err = snapstate.RefreshHistory(s.state, "some-other-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestRefreshHistoryUndone(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	defer s.se.Stop()
+
+	s.setupRefreshHistory(c)
+
+	ts, err := snapstate.Update(s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("refresh", "...")
+	chg.AddAll(ts)
+	last := lastWithLane(ts.Tasks())
+	c.Assert(last, NotNil)
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(last)
+	terr.JoinLane(last.Lanes()[0])
+	chg.AddTask(terr)
+	s.settleLocked(c)
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	history, err := snapstate.RefreshHistory(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].ToRevision, Equals, snap.R(11))
+	c.Check(history[0].ChangeID, Equals, chg.ID())
+	c.Check(history[0].Outcome, Equals, snapstate.RefreshOutcomeReverted)
+}
+
+func (s *snapmgrTestSuite) TestRefreshHistoryAutoRefresh(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	defer s.se.Stop()
+
+	s.setupRefreshHistory(c)
+
+	_, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("auto-refresh", "...")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+	s.settleLocked(c)
+	c.Assert(chg.Err(), IsNil)
+
+	history, err := snapstate.RefreshHistory(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Trigger, Equals, snapstate.RefreshTriggerAuto)
+}
+
+func (s *snapmgrTestSuite) TestRefreshHistoryValidationSets(c *C) {
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "somesnapidididididididididididid",
+		"presence": "required",
+		"revision": "11",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	defer s.se.Stop()
+
+	s.setupRefreshHistory(c)
+
+	ts, err := snapstate.Update(s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.RefreshTrigger, Equals, snapstate.RefreshTriggerValidationSet)
+
+	// the trigger is the one at the time the refresh was requested
+	snapstate.EnforcedValidationSets = func(st *state.State) (*snapasserts.ValidationSets, error) {
+		return nil, nil
+	}
+
+	chg := s.state.NewChange("refresh", "...")
+	chg.AddAll(ts)
+	s.settleLocked(c)
+	c.Assert(chg.Err(), IsNil)
+
+	history, err := snapstate.RefreshHistory(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].ToRevision, Equals, snap.R(11))
+	c.Check(history[0].Trigger, Equals, snapstate.RefreshTriggerValidationSet)
+}
+
+func (s *snapmgrTestSuite) TestRefreshHistoryCapped(c *C) {
+	restore := snapstate.MockMaxRefreshHistory(2)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	defer s.se.Stop()
+
+	s.setupRefreshHistory(c)
+
+	for i := 0; i < 3; i++ {
+		ts, err := snapstate.Update(s.state, "some-snap", nil, 0, snapstate.Flags{})
+		c.Assert(err, IsNil)
+		chg := s.state.NewChange("refresh", "...")
+		chg.AddAll(ts)
+		s.settleLocked(c)
+		c.Assert(chg.Err(), IsNil)
+
+		ts, err = snapstate.Revert(s.state, "some-snap", snapstate.Flags{})
+		c.Assert(err, IsNil)
+		chg = s.state.NewChange("revert", "...")
+		chg.AddAll(ts)
+		s.settleLocked(c)
+		c.Assert(chg.Err(), IsNil)
+	}
+
+	history, err := snapstate.RefreshHistory(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Revert, Equals, false)
+	c.Check(history[1].Revert, Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestRefreshHistoryDiscardedOnRemove(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	defer s.se.Stop()
+
+	s.setupRefreshHistory(c)
+
+	ts, err := snapstate.Update(s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("refresh", "...")
+	chg.AddAll(ts)
+	s.settleLocked(c)
+	c.Assert(chg.Err(), IsNil)
+
+	ts, err = snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
+	c.Assert(err, IsNil)
+	chg = s.state.NewChange("remove", "...")
+	chg.AddAll(ts)
+	s.settleLocked(c)
+	c.Assert(chg.Err(), IsNil)
+
+	history, err := snapstate.RefreshHistory(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
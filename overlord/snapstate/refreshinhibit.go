@@ -0,0 +1,272 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
+// inhibitedRefreshPollInterval is how often the apps of a snap whose
+// auto-refresh was inhibited are checked for having been closed.
+var inhibitedRefreshPollInterval = 30 * time.Second
+
+// asyncFinishRefreshNotification broadcasts desktop notification in a goroutine.
+//
+// Like asyncPendingRefreshNotification this avoids holding the state lock
+// while talking to the snapd session agents.
+var asyncFinishRefreshNotification = func(context context.Context, client *userclient.Client, refreshInfo *userclient.FinishedSnapRefreshInfo) {
+	go func() {
+		if err := client.FinishRefreshNotification(context, refreshInfo); err != nil {
+			logger.Noticef("Cannot send notification about finished refresh: %v", err)
+		}
+	}()
+}
+
+// preDownloadChangeInFlight returns whether a pre-download change for the
+// given snap is in progress.
+func preDownloadChangeInFlight(st *state.State, instanceName string) (bool, error) {
+	for _, chg := range st.Changes() {
+		if chg.Kind() != "pre-download" || chg.Status().Ready() {
+			continue
+		}
+		for _, t := range chg.Tasks() {
+			if !t.Has("snap-setup") {
+				continue
+			}
+			snapsup, err := TaskSnapSetup(t)
+			if err != nil {
+				return false, err
+			}
+			if snapsup.InstanceName() == instanceName {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// preDownloadInhibitedRefresh is used when the auto-refresh of a snap is
+// inhibited by its running apps. It creates a pre-download change that
+// downloads the new revision right away and refreshes the snap as soon as
+// its apps are closed.
+func preDownloadInhibitedRefresh(st *state.State, snapsup *SnapSetup) error {
+	inFlight, err := preDownloadChangeInFlight(st, snapsup.InstanceName())
+	if err != nil || inFlight {
+		return err
+	}
+	var snapst SnapState
+	if err := Get(st, snapsup.InstanceName(), &snapst); err != nil {
+		return err
+	}
+
+	download := st.NewTask("pre-download-snap", fmt.Sprintf(i18n.G("Pre-download snap %q (%s) from channel %q"), snapsup.InstanceName(), snapsup.Revision(), snapsup.Channel))
+	download.Set("snap-setup", snapsup)
+	cont := st.NewTask("continue-inhibited-refresh", fmt.Sprintf(i18n.G("Refresh snap %q once its apps are closed"), snapsup.InstanceName()))
+	cont.Set("snap-setup-task", download.ID())
+	// the refresh is not continued if the snap was refreshed meanwhile
+	cont.Set("refresh-from", snapst.Current)
+	cont.WaitFor(download)
+
+	chg := st.NewChange("pre-download", fmt.Sprintf(i18n.G("Pre-download %q for auto-refresh"), snapsup.InstanceName()))
+	chg.AddAll(state.NewTaskSet(download, cont))
+	return nil
+}
+
+// doContinueInhibitedRefresh waits until the apps of a snap whose
+// auto-refresh was inhibited are closed, notifying the desktop users once
+// that the update is ready. It then auto-refreshes the snap to the
+// pre-downloaded revision and waits for that change to notify the users
+// that the snap was refreshed.
+func (m *SnapManager) doContinueInhibitedRefresh(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	instanceName := snapsup.InstanceName()
+
+	var refreshChgID string
+	if err := t.Get("refresh-change", &refreshChgID); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if refreshChgID != "" {
+		return waitInhibitedRefresh(t, instanceName, refreshChgID)
+	}
+
+	var refreshFrom snap.Revision
+	if err := t.Get("refresh-from", &refreshFrom); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !snapst.IsInstalled() || snapst.Current == snapsup.Revision() {
+		// removed or refreshed meanwhile
+		return removePreDownloadedBlob(snapsup, snapst)
+	}
+	// auto-refreshes can go back to older revisions of the channel, only
+	// stop if the target is no longer newer
+	wasNewer := refreshFrom.Unset() || newerRevision(snapsup.Revision(), refreshFrom)
+	if (!refreshFrom.Unset() && snapst.Current != refreshFrom) || (wasNewer && !newerRevision(snapsup.Revision(), snapst.Current)) {
+		// refreshed to another revision meanwhile, continuing would
+		// go back to the pre-downloaded one
+		logger.Noticef("snap %q was refreshed to revision %s meanwhile, not continuing its auto-refresh to revision %s", instanceName, snapst.Current, snapsup.Revision())
+		return removePreDownloadedBlob(snapsup, snapst)
+	}
+	held, err := heldBySystem(st, timeNow(), false)
+	if err != nil {
+		return err
+	}
+	if held(instanceName) {
+		logger.Noticef("auto-refresh of snap %q is held, not continuing it", instanceName)
+		return removePreDownloadedBlob(snapsup, snapst)
+	}
+
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	// like softCheckNothingRunningForRefresh, hold the snap lock for no
+	// app to be started while checking
+	checkErr := backend.WithSnapLock(info, func() error {
+		return SoftNothingRunningRefreshCheck(info)
+	})
+	if checkErr != nil {
+		if !t.Has("notified") {
+			var refreshInfo *userclient.PendingSnapRefreshInfo
+			if err, ok := checkErr.(*BusySnapError); ok {
+				refreshInfo = err.PendingSnapRefreshInfo()
+			} else {
+				refreshInfo = &userclient.PendingSnapRefreshInfo{
+					InstanceName: instanceName,
+				}
+			}
+			if snapst.RefreshInhibitedTime != nil {
+				refreshInfo.TimeRemaining = (maxInhibition - timeNow().Sub(*snapst.RefreshInhibitedTime)).Truncate(time.Second)
+			}
+			refreshInfo.Downloaded = true
+			asyncPendingRefreshNotification(context.TODO(), userclient.New(), refreshInfo)
+			t.Set("notified", true)
+		}
+		return &state.Retry{After: inhibitedRefreshPollInterval, Reason: "waiting for the apps of the snap to be closed"}
+	}
+
+	// refresh to the pre-downloaded revision, the download is served
+	// from the download cache and validated as for any other refresh
+	candidate := &refreshCandidate{SnapSetup: *snapsup}
+	candidate.SnapPath = ""
+	tss, err := autoRefreshPhase2(auth.EnsureContextTODO(), st, []*refreshCandidate{candidate})
+	if err != nil {
+		return err
+	}
+	if !hasSnapSetupTask(tss) {
+		// the apps were started again meanwhile
+		return &state.Retry{After: inhibitedRefreshPollInterval, Reason: "waiting for the apps of the snap to be closed"}
+	}
+	chg := st.NewChange("auto-refresh", fmt.Sprintf(i18n.G("Auto-refresh snap %q"), instanceName))
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+	chg.Set("snap-names", []string{instanceName})
+	chg.Set("api-data", map[string]interface{}{"snap-names": []string{instanceName}})
+	t.Set("refresh-change", chg.ID())
+	t.Logf("Continuing refresh of snap %q in change %s", instanceName, chg.ID())
+
+	return &state.Retry{After: inhibitedRefreshPollInterval, Reason: "waiting for the refresh to finish"}
+}
+
+// newerRevision returns whether the target revision is newer than the
+// current one, revisions not from the store are never older.
+func newerRevision(target, current snap.Revision) bool {
+	if !target.Store() || !current.Store() {
+		return true
+	}
+	return target.N > current.N
+}
+
+func hasSnapSetupTask(tss []*state.TaskSet) bool {
+	for _, ts := range tss {
+		for _, t := range ts.Tasks() {
+			if t.Has("snap-setup") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// waitInhibitedRefresh waits for the given auto-refresh change to be
+// ready and notifies the desktop users if it succeeded.
+func waitInhibitedRefresh(t *state.Task, instanceName, chgID string) error {
+	chg := t.State().Change(chgID)
+	if chg == nil {
+		// pruned meanwhile
+		return nil
+	}
+	if !chg.Status().Ready() {
+		return &state.Retry{After: inhibitedRefreshPollInterval, Reason: "waiting for the refresh to finish"}
+	}
+	if chg.Status() != state.DoneStatus {
+		return fmt.Errorf("cannot refresh snap %q: change %s ended with status %s", instanceName, chgID, chg.Status())
+	}
+	asyncFinishRefreshNotification(context.TODO(), userclient.New(), &userclient.FinishedSnapRefreshInfo{
+		InstanceName: instanceName,
+	})
+	return nil
+}
+
+// undoPreDownloadSnap removes the pre-downloaded snap file when the
+// inhibited refresh could not be finished.
+func (m *SnapManager) undoPreDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	return removePreDownloadedBlob(snapsup, snapst)
+}
+
+// removePreDownloadedBlob removes the pre-downloaded snap file unless the
+// revision is in use by the snap.
+func removePreDownloadedBlob(snapsup *SnapSetup, snapst *SnapState) error {
+	if snapst.IsInstalled() && snapst.LastIndex(snapsup.Revision()) >= 0 {
+		return nil
+	}
+	if err := os.Remove(snapsup.MountFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
+// setupBusySnap sets up "some-snap" with an app that is running while
+// busy is set.
+func (s *snapmgrTestSuite) setupBusySnap(c *C, busy *bool) {
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.refresh-app-awareness", true)
+	tr.Commit()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:         snap.R(1),
+		SnapType:        "app",
+		TrackingChannel: "latest/stable",
+	})
+
+	s.AddCleanup(snapstate.MockSnapReadInfo(func(name string, si *snap.SideInfo) (*snap.Info, error) {
+		if name != "some-snap" {
+			return s.fakeBackend.ReadInfo(name, si)
+		}
+		info := &snap.Info{SuggestedName: name, SideInfo: *si, SnapType: snap.TypeApp}
+		info.Apps = map[string]*snap.AppInfo{
+			"app": {Snap: info, Name: "app"},
+		}
+		return info, nil
+	}))
+	s.AddCleanup(snapstate.MockPidsOfSnap(func(instanceName string) (map[string][]int, error) {
+		if !*busy {
+			return nil, nil
+		}
+		return map[string][]int{
+			"snap.some-snap.app": {1234},
+		}, nil
+	}))
+}
+
+func changesOfKind(st *state.State, kind string) []*state.Change {
+	var chgs []*state.Change
+	for _, chg := range st.Changes() {
+		if chg.Kind() == kind {
+			chgs = append(chgs, chg)
+		}
+	}
+	return chgs
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshBusySnapPreDownloads(c *C) {
+	var notified []*userclient.PendingSnapRefreshInfo
+	restore := snapstate.MockAsyncPendingRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.PendingSnapRefreshInfo) {
+		notified = append(notified, refreshInfo)
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	busy := true
+	s.setupBusySnap(c, &busy)
+
+	updated, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, HasLen, 0)
+	// the users are notified once the update is downloaded
+	c.Check(notified, HasLen, 0)
+
+	chgs := changesOfKind(s.state, "pre-download")
+	c.Assert(chgs, HasLen, 1)
+	c.Check(chgs[0].Summary(), Equals, `Pre-download "some-snap" for auto-refresh`)
+	tasks := chgs[0].Tasks()
+	c.Assert(tasks, HasLen, 2)
+	c.Check(tasks[0].Kind(), Equals, "pre-download-snap")
+	c.Check(tasks[0].Summary(), Equals, `Pre-download snap "some-snap" (11) from channel "latest/stable"`)
+	c.Check(tasks[1].Kind(), Equals, "continue-inhibited-refresh")
+	c.Check(tasks[1].Summary(), Equals, `Refresh snap "some-snap" once its apps are closed`)
+	c.Check(tasks[1].WaitTasks(), DeepEquals, []*state.Task{tasks[0]})
+	snapsup, err := snapstate.TaskSnapSetup(tasks[1])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.InstanceName(), Equals, "some-snap")
+	c.Check(snapsup.Revision(), Equals, snap.R(11))
+
+	// the pre-download does not conflict with other changes of the snap
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), IsNil)
+
+	// and is not repeated by the next auto-refresh
+	_, _, err = snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(changesOfKind(s.state, "pre-download"), HasLen, 1)
+}
+
+func (s *snapmgrTestSuite) TestManualRefreshBusySnapNoPreDownload(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	busy := true
+	s.setupBusySnap(c, &busy)
+
+	_, err := snapstate.Update(s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, ErrorMatches, `snap "some-snap" has running apps \(app\)`)
+	c.Check(changesOfKind(s.state, "pre-download"), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestContinueInhibitedRefresh(c *C) {
+	var notified []*userclient.PendingSnapRefreshInfo
+	restore := snapstate.MockAsyncPendingRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.PendingSnapRefreshInfo) {
+		notified = append(notified, refreshInfo)
+	})
+	defer restore()
+	var finished []*userclient.FinishedSnapRefreshInfo
+	restore = snapstate.MockAsyncFinishRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.FinishedSnapRefreshInfo) {
+		finished = append(finished, refreshInfo)
+	})
+	defer restore()
+	restore = snapstate.MockInhibitedRefreshPollInterval(time.Millisecond)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	busy := true
+	s.setupBusySnap(c, &busy)
+
+	_, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	chgs := changesOfKind(s.state, "pre-download")
+	c.Assert(chgs, HasLen, 1)
+	preDownload := chgs[0]
+	cont := preDownload.Tasks()[1]
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	for i := 0; i < 50; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+
+		s.state.Lock()
+		waiting := cont.Has("notified")
+		s.state.Unlock()
+		if waiting {
+			break
+		}
+	}
+	s.state.Lock()
+
+	// the update was downloaded while the app is running
+	c.Check(s.fakeBackend.ops.First("storesvc-download"), NotNil)
+	c.Check(preDownload.Tasks()[0].Status(), Equals, state.DoneStatus)
+	c.Check(cont.Status(), Equals, state.DoingStatus)
+	// the users are notified only once
+	c.Assert(notified, HasLen, 1)
+	c.Check(notified[0].InstanceName, Equals, "some-snap")
+	c.Check(notified[0].Downloaded, Equals, true)
+	c.Check(notified[0].TimeRemaining > 0, Equals, true)
+	c.Check(changesOfKind(s.state, "auto-refresh"), HasLen, 0)
+
+	// once the app is closed the snap is refreshed
+	busy = false
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(preDownload.Status(), Equals, state.DoneStatus)
+	chgs = changesOfKind(s.state, "auto-refresh")
+	c.Assert(chgs, HasLen, 1)
+	c.Check(chgs[0].Summary(), Equals, `Auto-refresh snap "some-snap"`)
+	c.Check(chgs[0].Status(), Equals, state.DoneStatus)
+	var refreshChgID string
+	c.Assert(cont.Get("refresh-change", &refreshChgID), IsNil)
+	c.Check(refreshChgID, Equals, chgs[0].ID())
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(11))
+
+	c.Check(finished, DeepEquals, []*userclient.FinishedSnapRefreshInfo{{InstanceName: "some-snap"}})
+}
+
+func (s *snapmgrTestSuite) TestContinueInhibitedRefreshRefreshedMeanwhile(c *C) {
+	restore := snapstate.MockAsyncPendingRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.PendingSnapRefreshInfo) {})
+	defer restore()
+	restore = snapstate.MockAsyncFinishRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.FinishedSnapRefreshInfo) {
+		c.Errorf("unexpected finish notification")
+	})
+	defer restore()
+	restore = snapstate.MockInhibitedRefreshPollInterval(time.Millisecond)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	busy := true
+	s.setupBusySnap(c, &busy)
+
+	_, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	chgs := changesOfKind(s.state, "pre-download")
+	c.Assert(chgs, HasLen, 1)
+	preDownload := chgs[0]
+	cont := preDownload.Tasks()[1]
+	var refreshFrom snap.Revision
+	c.Assert(cont.Get("refresh-from", &refreshFrom), IsNil)
+	c.Check(refreshFrom, Equals, snap.R(1))
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	for i := 0; i < 50; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+
+		s.state.Lock()
+		waiting := cont.Has("notified")
+		s.state.Unlock()
+		if waiting {
+			break
+		}
+	}
+	s.state.Lock()
+	c.Check(cont.Status(), Equals, state.DoingStatus)
+
+	// a newer revision is installed while the task is waiting
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	snapst.Sequence = append(snapst.Sequence, &snap.SideInfo{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(12)})
+	snapst.Current = snap.R(12)
+	snapstate.Set(s.state, "some-snap", &snapst)
+	busy = false
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	// the snap is not taken back to the pre-downloaded revision
+	c.Check(preDownload.Status(), Equals, state.DoneStatus)
+	c.Check(changesOfKind(s.state, "auto-refresh"), HasLen, 0)
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(12))
+}
+
+func (s *snapmgrTestSuite) TestContinueInhibitedRefreshSnapRemoved(c *C) {
+	restore := snapstate.MockAsyncPendingRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.PendingSnapRefreshInfo) {})
+	defer restore()
+	restore = snapstate.MockAsyncFinishRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.FinishedSnapRefreshInfo) {
+		c.Errorf("unexpected finish notification")
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	busy := true
+	s.setupBusySnap(c, &busy)
+
+	_, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	chgs := changesOfKind(s.state, "pre-download")
+	c.Assert(chgs, HasLen, 1)
+
+	snapstate.Set(s.state, "some-snap", nil)
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chgs[0].Status(), Equals, state.DoneStatus)
+	c.Check(changesOfKind(s.state, "auto-refresh"), HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestContinueInhibitedRefreshAbortRemovesBlob(c *C) {
+	restore := snapstate.MockAsyncPendingRefreshNotification(func(ctx context.Context, client *userclient.Client, refreshInfo *userclient.PendingSnapRefreshInfo) {})
+	defer restore()
+	restore = snapstate.MockInhibitedRefreshPollInterval(time.Millisecond)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	busy := true
+	s.setupBusySnap(c, &busy)
+
+	_, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	chgs := changesOfKind(s.state, "pre-download")
+	c.Assert(chgs, HasLen, 1)
+	preDownload := chgs[0]
+	cont := preDownload.Tasks()[1]
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	for i := 0; i < 50; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+
+		s.state.Lock()
+		waiting := cont.Has("notified")
+		s.state.Unlock()
+		if waiting {
+			break
+		}
+	}
+	s.state.Lock()
+
+	snapsup, err := snapstate.TaskSnapSetup(preDownload.Tasks()[0])
+	c.Assert(err, IsNil)
+	blob := snapsup.MountFile()
+	c.Assert(os.MkdirAll(filepath.Dir(blob), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(blob, nil, 0644), IsNil)
+
+	preDownload.Abort()
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(preDownload.Tasks()[0].Status(), Equals, state.UndoneStatus)
+	c.Check(blob, testutil.FileAbsent)
+	c.Check(changesOfKind(s.state, "auto-refresh"), HasLen, 0)
+}
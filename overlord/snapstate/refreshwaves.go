@@ -0,0 +1,273 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// defaultRefreshWaveDelay is how long the snaps refreshed in a wave are
+// given to settle before their health is checked, unless configured
+// otherwise via refresh.wave-delay.
+const defaultRefreshWaveDelay = 5 * time.Minute
+
+// RefreshWaveCategories are the categories of snaps that can be used in
+// refresh.waves, any other entry is the name of a snap.
+var RefreshWaveCategories = []string{"apps", "content-providers", "bases", "kernel", "gadget"}
+
+func refreshWaveSelectors(st *state.State) ([]string, error) {
+	tr := config.NewTransaction(st)
+	var opt string
+	if err := tr.Get("core", "refresh.waves", &opt); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	if opt == "" {
+		return nil, nil
+	}
+	return strings.Split(opt, ","), nil
+}
+
+func refreshWaveDelay(st *state.State) time.Duration {
+	tr := config.NewTransaction(st)
+	var opt string
+	if err := tr.Get("core", "refresh.wave-delay", &opt); err != nil || opt == "" {
+		return defaultRefreshWaveDelay
+	}
+	delay, err := time.ParseDuration(opt)
+	if err != nil || delay < 0 {
+		logger.Noticef("cannot use refresh.wave-delay %q, using default", opt)
+		return defaultRefreshWaveDelay
+	}
+	return delay
+}
+
+// refreshWaveCategory returns the category of the given snap as used in
+// refresh.waves.
+func refreshWaveCategory(st *state.State, snapsup *SnapSetup) string {
+	switch snapsup.Type {
+	case snap.TypeBase, snap.TypeOS, snap.TypeSnapd:
+		return "bases"
+	case snap.TypeKernel:
+		return "kernel"
+	case snap.TypeGadget:
+		return "gadget"
+	}
+	var snapst SnapState
+	if err := Get(st, snapsup.InstanceName(), &snapst); err == nil {
+		if info, err := snapst.CurrentInfo(); err == nil {
+			for _, slot := range info.Slots {
+				if slot.Interface == "content" {
+					return "content-providers"
+				}
+			}
+		}
+	}
+	return "apps"
+}
+
+// refreshWave returns the index of the wave the given snap is refreshed
+// in, naming a snap takes precedence over its category. Snaps not
+// matching any selector are refreshed in a last wave.
+func refreshWave(selectors []string, instanceName, category string) int {
+	for i, sel := range selectors {
+		if sel == instanceName {
+			return i
+		}
+	}
+	for i, sel := range selectors {
+		if sel == category {
+			return i
+		}
+	}
+	return len(selectors)
+}
+
+func taskSetSnapSetup(ts *state.TaskSet) *SnapSetup {
+	for _, t := range ts.Tasks() {
+		if !t.Has("snap-setup") {
+			continue
+		}
+		snapsup, err := TaskSnapSetup(t)
+		if err != nil {
+			return nil
+		}
+		return snapsup
+	}
+	return nil
+}
+
+// arrangeRefreshWaves makes the refreshes of the given tasksets happen in
+// the waves configured via refresh.waves. Between waves a
+// check-refresh-wave task checks the health of the snaps refreshed so far
+// and stops the refresh if any of them regressed. A snap is never
+// refreshed in an earlier wave than the snaps it waits for, e.g. an app
+// waits for the refresh of its base.
+func arrangeRefreshWaves(st *state.State, tasksets []*state.TaskSet) ([]*state.TaskSet, error) {
+	selectors, err := refreshWaveSelectors(st)
+	if err != nil || len(selectors) == 0 {
+		return tasksets, err
+	}
+
+	type snapRefresh struct {
+		name string
+		ts   *state.TaskSet
+		wave int
+	}
+	var refreshes []*snapRefresh
+	refreshByTask := make(map[string]*snapRefresh)
+	for _, ts := range tasksets {
+		snapsup := taskSetSnapSetup(ts)
+		if snapsup == nil {
+			// not the refresh of a snap, e.g. aliases
+			continue
+		}
+		r := &snapRefresh{
+			name: snapsup.InstanceName(),
+			ts:   ts,
+			wave: refreshWave(selectors, snapsup.InstanceName(), refreshWaveCategory(st, snapsup)),
+		}
+		refreshes = append(refreshes, r)
+		for _, t := range ts.Tasks() {
+			refreshByTask[t.ID()] = r
+		}
+	}
+
+	// move snaps to the wave of the snaps they wait for if that is later
+	for moved := true; moved; {
+		moved = false
+		for _, r := range refreshes {
+			for _, t := range r.ts.Tasks() {
+				for _, wt := range t.WaitTasks() {
+					dep := refreshByTask[wt.ID()]
+					if dep != nil && dep.wave > r.wave {
+						r.wave = dep.wave
+						moved = true
+					}
+				}
+			}
+		}
+	}
+
+	waves := make(map[int][]*snapRefresh)
+	var order []int
+	for _, r := range refreshes {
+		if _, ok := waves[r.wave]; !ok {
+			order = append(order, r.wave)
+		}
+		waves[r.wave] = append(waves[r.wave], r)
+	}
+	if len(order) < 2 {
+		return tasksets, nil
+	}
+	sort.Ints(order)
+
+	// the checks share a lane so that a regression stops all the
+	// remaining waves without undoing the refreshes done so far
+	lane := st.NewLane()
+	var checks []*state.TaskSet
+	for i := 1; i < len(order); i++ {
+		prev := waves[order[i-1]]
+		names := make([]string, len(prev))
+		for j, r := range prev {
+			names[j] = r.name
+		}
+		sort.Strings(names)
+
+		check := st.NewTask("check-refresh-wave", fmt.Sprintf(i18n.G("Check health of snaps %s before refreshing more snaps"), strutil.Quoted(names)))
+		check.Set("snap-names", names)
+		check.JoinLane(lane)
+		for _, r := range prev {
+			check.WaitAll(r.ts)
+		}
+		for _, r := range waves[order[i]] {
+			r.ts.WaitFor(check)
+		}
+		checks = append(checks, state.NewTaskSet(check))
+	}
+
+	// keep the re-refresh check last
+	n := len(tasksets)
+	if n > 0 {
+		if last := tasksets[n-1].Tasks(); len(last) == 1 && last[0].Kind() == "check-rerefresh" {
+			return append(append(tasksets[:n-1:n-1], checks...), tasksets[n-1]), nil
+		}
+	}
+	return append(tasksets, checks...), nil
+}
+
+// doCheckRefreshWave waits for the snaps refreshed in a wave to settle
+// and then checks their health. If any of them is unhealthy the task
+// errors, which holds the refresh of the remaining waves.
+func (m *SnapManager) doCheckRefreshWave(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var names []string
+	if err := t.Get("snap-names", &names); err != nil {
+		return err
+	}
+
+	if err := waitGracePeriod(t, refreshWaveDelay(st)); err != nil {
+		return err
+	}
+
+	var regressed []string
+	for _, name := range names {
+		var snapst SnapState
+		if err := Get(st, name, &snapst); err != nil {
+			if err == state.ErrNoState {
+				// removed meanwhile
+				continue
+			}
+			return err
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return err
+		}
+		problems, err := m.snapHealthProblems(st, info)
+		if err != nil {
+			return err
+		}
+		if len(problems) != 0 {
+			regressed = append(regressed, fmt.Sprintf("%q (%s)", name, strings.Join(problems, "; ")))
+		}
+	}
+	if len(regressed) == 0 {
+		return nil
+	}
+
+	msg := strings.Join(regressed, ", ")
+	st.Warnf("refresh of the remaining snaps was stopped, refreshed snaps are unhealthy: %s", msg)
+	return fmt.Errorf("cannot refresh more snaps, refreshed snaps are unhealthy: %s", msg)
+}
@@ -0,0 +1,227 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *snapmgrTestSuite) setupRefreshWaves(c *C, waves string, snaps map[string]snap.Type) {
+	for name, typ := range snaps {
+		snapID := name + "-id"
+		if name == "core" {
+			snapID = "core-snap-id"
+		}
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, SnapID: snapID, Revision: snap.R(1)},
+			},
+			Current:         snap.R(1),
+			SnapType:        string(typ),
+			TrackingChannel: "latest/stable",
+		})
+	}
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.waves", waves)
+	tr.Set("core", "refresh.wave-delay", "0s")
+	tr.Commit()
+}
+
+func taskSetSnapName(c *C, ts *state.TaskSet) string {
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	return snapsup.InstanceName()
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshNoWavesByDefault(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshWaves(c, "", map[string]snap.Type{
+		"some-snap":       snap.TypeApp,
+		"some-other-snap": snap.TypeApp,
+	})
+
+	_, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	for _, ts := range tss {
+		for _, t := range ts.Tasks() {
+			c.Check(t.Kind(), Not(Equals), "check-refresh-wave")
+		}
+	}
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshWaves(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRefreshWaves(c, "bases,some-other-snap,apps", map[string]snap.Type{
+		"core":            snap.TypeOS,
+		"some-snap":       snap.TypeApp,
+		"some-other-snap": snap.TypeApp,
+	})
+
+	updated, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	sort.Strings(updated)
+	c.Check(updated, DeepEquals, []string{"core", "some-other-snap", "some-snap"})
+	verifyLastTasksetIsReRefresh(c, tss)
+
+	chg := s.state.NewChange("auto-refresh", "...")
+	refreshTs := make(map[string]*state.TaskSet)
+	var checks []*state.Task
+	for _, ts := range tss {
+		chg.AddAll(ts)
+		if ts.Tasks()[0].Kind() == "check-refresh-wave" {
+			c.Assert(ts.Tasks(), HasLen, 1)
+			checks = append(checks, ts.Tasks()[0])
+		} else if ts.Tasks()[0].Has("snap-setup") {
+			refreshTs[taskSetSnapName(c, ts)] = ts
+		}
+	}
+	c.Assert(refreshTs, HasLen, 3)
+	c.Assert(checks, HasLen, 2)
+
+	var names []string
+	c.Assert(checks[0].Get("snap-names", &names), IsNil)
+	c.Check(names, DeepEquals, []string{"core"})
+	c.Check(checks[0].Summary(), Equals, `Check health of snaps "core" before refreshing more snaps`)
+	c.Assert(checks[1].Get("snap-names", &names), IsNil)
+	c.Check(names, DeepEquals, []string{"some-other-snap"})
+
+	// the checks wait for the previous wave and the next wave waits for
+	// the checks
+	c.Check(checks[0].WaitTasks(), HasLen, len(refreshTs["core"].Tasks()))
+	c.Check(checks[1].WaitTasks(), HasLen, len(refreshTs["some-other-snap"].Tasks()))
+	for _, t := range refreshTs["some-other-snap"].Tasks() {
+		c.Check(t.WaitTasks(), testutil.Contains, checks[0])
+	}
+	for _, t := range refreshTs["some-snap"].Tasks() {
+		c.Check(t.WaitTasks(), testutil.Contains, checks[1])
+	}
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshWavesRespectPrerequisites(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// apps wait for the refresh of core, so they cannot be refreshed
+	// before it
+	s.setupRefreshWaves(c, "apps,bases", map[string]snap.Type{
+		"core":            snap.TypeOS,
+		"some-snap":       snap.TypeApp,
+		"some-other-snap": snap.TypeApp,
+	})
+
+	_, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	for _, ts := range tss {
+		for _, t := range ts.Tasks() {
+			c.Check(t.Kind(), Not(Equals), "check-refresh-wave")
+		}
+	}
+}
+
+func (s *snapmgrTestSuite) testAutoRefreshWavesRun(c *C, unhealthy string) *state.Change {
+	s.mockCheckSnapHealth(func(st *state.State, instanceName string, rev snap.Revision) error {
+		if instanceName == unhealthy {
+			return fmt.Errorf(`health status is "error"`)
+		}
+		return nil
+	})
+
+	s.setupRefreshWaves(c, "some-other-snap", map[string]snap.Type{
+		"some-snap":       snap.TypeApp,
+		"some-other-snap": snap.TypeApp,
+	})
+
+	_, tss, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("auto-refresh", "...")
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	return chg
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshWavesHealthy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.testAutoRefreshWavesRun(c, "")
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+
+	for _, name := range []string{"some-snap", "some-other-snap"} {
+		var snapst snapstate.SnapState
+		c.Assert(snapstate.Get(s.state, name, &snapst), IsNil)
+		c.Check(snapst.Current, Equals, snap.R(11), Commentf(name))
+	}
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshWavesStopOnRegression(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.testAutoRefreshWavesRun(c, "some-other-snap")
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot refresh more snaps, refreshed snaps are unhealthy: "some-other-snap" \(health status is "error"\).*`)
+
+	// the first wave is kept, the second one did not happen
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-other-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(11))
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(1))
+	for _, t := range chg.Tasks() {
+		if t.Kind() != "link-snap" {
+			continue
+		}
+		snapsup, err := snapstate.TaskSnapSetup(t)
+		c.Assert(err, IsNil)
+		if snapsup.InstanceName() == "some-snap" {
+			c.Check(t.Status(), Equals, state.HoldStatus)
+		} else {
+			c.Check(t.Status(), Equals, state.DoneStatus)
+		}
+	}
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, `refresh of the remaining snaps was stopped, refreshed snaps are unhealthy: "some-other-snap" (health status is "error")`)
+}
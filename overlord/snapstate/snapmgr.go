@@ -79,6 +79,14 @@ type SnapSetup struct {
 
 	CohortKey string `json:"cohort-key,omitempty"`
 
+	// StoreBackend is the name of the store backend the snap is
+	// installed from, empty for the default store.
+	StoreBackend string `json:"store-backend,omitempty"`
+
+	// RefreshTrigger records what caused a refresh when its tasks were
+	// created, for the refresh history.
+	RefreshTrigger RefreshTrigger `json:"refresh-trigger,omitempty"`
+
 	// FIXME: implement rename of this as suggested in
 	//  https://github.com/snapcore/snapd/pull/4103#discussion_r169569717
 	//
@@ -168,6 +176,11 @@ type SnapState struct {
 	InstanceKey string `json:"instance-key,omitempty"`
 	CohortKey   string `json:"cohort-key,omitempty"`
 
+	// StoreBackend is the name of the store backend the snap was
+	// installed from and keeps getting refreshed from, empty for the
+	// default store.
+	StoreBackend string `json:"store-backend,omitempty"`
+
 	// RefreshInhibitedime records the time when the refresh was first
 	// attempted but inhibited because the snap was busy. This value is
 	// reset on each successful refresh.
@@ -378,7 +391,8 @@ var _ StoreService = (*store.Store)(nil)
 
 // Store returns the store service provided by the optional device context or
 // the one used by the snapstate package if the former has no
-// override.
+// override. In the latter case requests are routed to the configured
+// store backends, if any.
 func Store(st *state.State, deviceCtx DeviceContext) StoreService {
 	if deviceCtx != nil {
 		sto := deviceCtx.Store()
@@ -387,7 +401,7 @@ func Store(st *state.State, deviceCtx DeviceContext) StoreService {
 		}
 	}
 	if cachedStore := cachedStore(st); cachedStore != nil {
-		return cachedStore
+		return routedStore(st, cachedStore)
 	}
 	panic("internal error: needing the store before managers have initialized it")
 }
@@ -437,7 +451,12 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 	runner.AddHandler("switch-snap-channel", m.doSwitchSnapChannel, nil)
 	runner.AddHandler("toggle-snap-flags", m.doToggleSnapFlags, nil)
 	runner.AddHandler("check-rerefresh", m.doCheckReRefresh, nil)
+	runner.AddHandler("check-refresh-health", m.doCheckRefreshHealth, nil)
+	runner.AddHandler("check-refresh-wave", m.doCheckRefreshWave, nil)
 	runner.AddHandler("conditional-auto-refresh", m.doConditionalAutoRefresh, nil)
+	runner.AddHandler("run-gate-auto-refresh-policies", m.doRunGateAutoRefreshPolicies, nil)
+	runner.AddHandler("pre-download-snap", m.doDownloadSnap, m.undoPreDownloadSnap)
+	runner.AddHandler("continue-inhibited-refresh", m.doContinueInhibitedRefresh, nil)
 
 	// FIXME: drop the task entirely after a while
 	// (having this wart here avoids yet-another-patch)
@@ -522,7 +541,9 @@ func (m *SnapManager) blockedTask(cand *state.Task, running []*state.Task) bool
 		}
 	}
 
-	return false
+	// Limit the number of parallel downloads, giving precedence
+	// to the most important ones.
+	return blockedDownload(cand, running)
 }
 
 // NextRefresh returns the time the next update of the system's snaps
@@ -546,6 +567,13 @@ func (m *SnapManager) LastRefresh() (time.Time, error) {
 	return m.autoRefresh.LastRefresh()
 }
 
+// LastRefreshDeferral returns why and when the auto-refresh was last
+// deferred, or nil if it was not deferred since it last happened.
+// The caller should be holding the state lock.
+func (m *SnapManager) LastRefreshDeferral() (*RefreshDeferral, error) {
+	return m.autoRefresh.LastRefreshDeferral()
+}
+
 // RefreshSchedule returns the current refresh schedule as a string suitable for
 // display to a user and a flag indicating whether the schedule is a legacy one.
 // The caller should be holding the state lock.
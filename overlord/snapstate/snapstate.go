@@ -47,6 +47,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/channel"
+	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/strutil"
 )
@@ -132,11 +133,17 @@ func (ins installSnapInfo) SnapSetupForUpdate(st *state.State, params updatePara
 		return nil, nil, err
 	}
 
+	storeBackend, err := storeBackendFor(st, snapst, update)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	snapsup := SnapSetup{
 		Base:         update.Base,
 		Prereq:       defaultContentPlugProviders(st, update),
 		Channel:      revnoOpts.Channel,
 		CohortKey:    revnoOpts.CohortKey,
+		StoreBackend: storeBackend,
 		UserID:       snapUserID,
 		Flags:        flags.ForSnapSetup(),
 		DownloadInfo: &update.DownloadInfo,
@@ -166,6 +173,10 @@ type InsufficientSpaceError struct {
 	ChangeKind string
 	// Message is optional, otherwise one is composed from the other information
 	Message string
+	// RequiredSpace is the estimated disk space needed, if known
+	RequiredSpace uint64
+	// Reclaimable lists what could be removed to free disk space, if known
+	Reclaimable []ReclaimableSpace
 }
 
 func (e *InsufficientSpaceError) Error() string {
@@ -258,8 +269,10 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 		if experimentalRefreshAppAwareness && !snapsup.Flags.IgnoreRunning {
 			// Note that because we are modifying the snap state inside
 			// softCheckNothingRunningForRefresh, this block must be located
-			// after the conflict check done above.
-			if err := softCheckNothingRunningForRefresh(st, snapst, info); err != nil {
+			// after the conflict check done above. Inhibited
+			// auto-refreshes are pre-downloaded and the users are
+			// notified once that is done.
+			if err := softCheckNothingRunningForRefresh(st, snapst, info, !snapsup.IsAutoRefresh); err != nil {
 				return nil, err
 			}
 		}
@@ -513,6 +526,20 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 	healthCheck.WaitAll(ts)
 	ts.AddTask(healthCheck)
 
+	if snapst.IsInstalled() && !snapsup.Flags.Revert {
+		rollback, err := rollbackOnUnhealthy(st, snapsup.InstanceName())
+		if err != nil {
+			return nil, err
+		}
+		if rollback {
+			// revert the refresh if the snap is unhealthy afterwards
+			checkRefreshHealth := st.NewTask("check-refresh-health", fmt.Sprintf(i18n.G("Check health of snap %q%s after refresh"), snapsup.InstanceName(), revisionStr))
+			checkRefreshHealth.Set("snap-setup-task", prepare.ID())
+			checkRefreshHealth.WaitFor(healthCheck)
+			ts.AddTask(checkRefreshHealth)
+		}
+	}
+
 	return ts, nil
 }
 
@@ -925,12 +952,32 @@ func InstallWithDeviceContext(ctx context.Context, st *state.State, name string,
 		return nil, fmt.Errorf("invalid instance name: %v", err)
 	}
 
+	vsets, err := enforcedValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+	requiredRev, err := validationSetsRevision(vsets, "install", naming.Snap(snap.InstanceSnap(name)), opts.Revision)
+	if err != nil {
+		return nil, err
+	}
+	if !requiredRev.Unset() && opts.Revision.Unset() {
+		// install the revision required by the validation sets
+		revOpts := *opts
+		revOpts.Revision = requiredRev
+		revOpts.CohortKey = ""
+		opts = &revOpts
+	}
+
 	sar, err := installInfo(ctx, st, name, opts, userID, deviceCtx)
 	if err != nil {
 		return nil, err
 	}
 	info := sar.Info
 
+	if err := checkValidationSetsInfo(vsets, "install", info); err != nil {
+		return nil, err
+	}
+
 	if flags.RequireTypeBase && info.Type() != snap.TypeBase && info.Type() != snap.TypeOS {
 		return nil, fmt.Errorf("unexpected snap type %q, instead of 'base'", info.Type())
 	}
@@ -966,6 +1013,11 @@ func InstallWithDeviceContext(ctx context.Context, st *state.State, name string,
 		}
 	}
 
+	storeBackend, err := storeBackendFor(st, &snapst, info)
+	if err != nil {
+		return nil, err
+	}
+
 	snapsup := &SnapSetup{
 		Channel:      opts.Channel,
 		Base:         info.Base,
@@ -981,7 +1033,8 @@ func InstallWithDeviceContext(ctx context.Context, st *state.State, name string,
 			Media:   info.Media,
 			Website: info.Website,
 		},
-		CohortKey: opts.CohortKey,
+		CohortKey:    opts.CohortKey,
+		StoreBackend: storeBackend,
 	}
 
 	if sar.RedirectChannel != "" {
@@ -1059,12 +1112,21 @@ func InstallMany(st *state.State, names []string, userID int) ([]string, []*stat
 		}
 	}
 
+	vsets, err := enforcedValidationSets(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	tasksets := make([]*state.TaskSet, 0, len(installs))
 	for _, sar := range installs {
 		info := sar.Info
 		var snapst SnapState
 		var flags Flags
 
+		if err := checkValidationSetsInfo(vsets, "install", info); err != nil {
+			return nil, nil, err
+		}
+
 		flags, err := ensureInstallPreconditions(st, info, flags, &snapst)
 		if err != nil {
 			return nil, nil, err
@@ -1075,6 +1137,11 @@ func InstallMany(st *state.State, names []string, userID int) ([]string, []*stat
 			channel = sar.RedirectChannel
 		}
 
+		storeBackend, err := storeBackendFor(st, &snapst, info)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		snapsup := &SnapSetup{
 			Channel:      channel,
 			Base:         info.Base,
@@ -1086,6 +1153,7 @@ func InstallMany(st *state.State, names []string, userID int) ([]string, []*stat
 			Type:         info.Type(),
 			PlugsOnly:    len(info.Slots) == 0,
 			InstanceKey:  info.InstanceKey,
+			StoreBackend: storeBackend,
 		}
 
 		ts, err := doInstall(st, &snapst, snapsup, 0, "", inUseFor(deviceCtx))
@@ -1167,6 +1235,16 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, us
 		}
 	}
 
+	// tracking policies apply when refreshing all snaps
+	var policyChannels map[string]string
+	if len(names) == 0 {
+		policies, err := trackingPolicies(st)
+		if err != nil {
+			return nil, nil, err
+		}
+		policyChannels = trackingPolicyChannels(policies, stateByInstanceName)
+	}
+
 	params := func(update *snap.Info) (*RevisionOptions, Flags, *SnapState) {
 		snapst := stateByInstanceName[update.InstanceName()]
 		// setting options to what's in state as multi-refresh doesn't let you change these
@@ -1174,6 +1252,9 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, us
 			Channel:   snapst.TrackingChannel,
 			CohortKey: snapst.CohortKey,
 		}
+		if ch, ok := policyChannels[update.InstanceName()]; ok {
+			opts.Channel = ch
+		}
 		return opts, snapst.Flags, snapst
 
 	}
@@ -1183,35 +1264,9 @@ func updateManyFiltered(ctx context.Context, st *state.State, names []string, us
 		toUpdate[i] = installSnapInfo{up}
 	}
 
-	tr := config.NewTransaction(st)
-	checkDiskSpaceRefresh, err := features.Flag(tr, features.CheckDiskSpaceRefresh)
-	if err != nil && !config.IsNoOption(err) {
+	if err := checkRefreshDiskSpace(st, toUpdate, userID); err != nil {
 		return nil, nil, err
 	}
-	if checkDiskSpaceRefresh {
-		// check if there is enough disk space for requested snap and its
-		// prerequisites.
-		totalSize, err := installSize(st, toUpdate, userID)
-		if err != nil {
-			return nil, nil, err
-		}
-		requiredSpace := safetyMarginDiskSpace(totalSize)
-		path := dirs.SnapdStateDir(dirs.GlobalRootDir)
-		if err := osutilCheckFreeSpace(path, requiredSpace); err != nil {
-			snaps := make([]string, len(updates))
-			for i, up := range updates {
-				snaps[i] = up.InstanceName()
-			}
-			if _, ok := err.(*osutil.NotEnoughDiskSpaceError); ok {
-				return nil, nil, &InsufficientSpaceError{
-					Path:       path,
-					Snaps:      snaps,
-					ChangeKind: "refresh",
-				}
-			}
-			return nil, nil, err
-		}
-	}
 
 	updated, tasksets, err := doUpdate(ctx, st, names, toUpdate, params, userID, flags, deviceCtx, fromChange)
 	if err != nil {
@@ -1242,6 +1297,11 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []mi
 		return nil, nil, err
 	}
 
+	vsets, err := enforcedValidationSets(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	reportUpdated := make(map[string]bool, len(updates))
 	var pruningAutoAliasesTs *state.TaskSet
 
@@ -1288,12 +1348,20 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []mi
 			}
 			return nil, nil, err
 		}
+		snapsup.RefreshTrigger = refreshTriggerFor(snapsup, vsets, deviceCtx)
 
 		ts, err := doInstall(st, snapst, snapsup, 0, fromChange, inUseFor(deviceCtx))
 		if err != nil {
 			if refreshAll {
 				// doing "refresh all", just skip this snap
 				logger.Noticef("cannot refresh snap %q: %v", update.InstanceName(), err)
+				if _, ok := err.(*BusySnapError); ok && globalFlags.IsAutoRefresh {
+					// download the update meanwhile and finish
+					// the refresh once the apps are closed
+					if err := preDownloadInhibitedRefresh(st, snapsup); err != nil {
+						logger.Noticef("cannot pre-download snap %q: %v", update.InstanceName(), err)
+					}
+				}
 				continue
 			}
 			return nil, nil, err
@@ -1714,8 +1782,32 @@ func UpdateWithDeviceContext(st *state.State, name string, opts *RevisionOptions
 		flags.Classic = flags.Classic || snapst.Flags.Classic
 	}
 
+	vsets, err := enforcedValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+	si := snapst.CurrentSideInfo()
+	requiredRev, err := validationSetsRevision(vsets, "refresh", naming.NewSnapRef(si.RealName, si.SnapID), opts.Revision)
+	if err != nil {
+		return nil, err
+	}
+
 	var updates []*snap.Info
-	info, infoErr := infoForUpdate(st, &snapst, name, opts, userID, flags, deviceCtx)
+	var info *snap.Info
+	var infoErr error
+	if !requiredRev.Unset() && opts.Revision.Unset() && requiredRev == snapst.Current {
+		// already at the revision required by the validation sets
+		infoErr = store.ErrNoUpdateAvailable
+	} else {
+		if !requiredRev.Unset() && opts.Revision.Unset() {
+			// refresh to the revision required by the validation
+			// sets, without touching the options of the caller
+			revOpts := *opts
+			revOpts.Revision = requiredRev
+			opts = &revOpts
+		}
+		info, infoErr = infoForUpdate(st, &snapst, name, opts, userID, flags, deviceCtx)
+	}
 	switch infoErr {
 	case nil:
 		updates = append(updates, info)
@@ -1730,35 +1822,9 @@ func UpdateWithDeviceContext(st *state.State, name string, opts *RevisionOptions
 		toUpdate[i] = installSnapInfo{up}
 	}
 
-	tr := config.NewTransaction(st)
-	checkDiskSpaceRefresh, err := features.Flag(tr, features.CheckDiskSpaceRefresh)
-	if err != nil && !config.IsNoOption(err) {
+	if err := checkRefreshDiskSpace(st, toUpdate, userID); err != nil {
 		return nil, err
 	}
-	if checkDiskSpaceRefresh {
-		// check if there is enough disk space for requested snap and its
-		// prerequisites.
-		totalSize, err := installSize(st, toUpdate, userID)
-		if err != nil {
-			return nil, err
-		}
-		requiredSpace := safetyMarginDiskSpace(totalSize)
-		path := dirs.SnapdStateDir(dirs.GlobalRootDir)
-		if err := osutilCheckFreeSpace(path, requiredSpace); err != nil {
-			snaps := make([]string, len(updates))
-			for i, up := range updates {
-				snaps[i] = up.InstanceName()
-			}
-			if _, ok := err.(*osutil.NotEnoughDiskSpaceError); ok {
-				return nil, &InsufficientSpaceError{
-					Path:       path,
-					Snaps:      snaps,
-					ChangeKind: "refresh",
-				}
-			}
-			return nil, err
-		}
-	}
 
 	params := func(update *snap.Info) (*RevisionOptions, Flags, *SnapState) {
 		return opts, flags, &snapst
@@ -1875,6 +1941,13 @@ var AutoRefreshAssertions func(st *state.State, userID int) error
 // snaps on the system. In addition to that it will also refresh important
 // assertions.
 func AutoRefresh(ctx context.Context, st *state.State) ([]string, []*state.TaskSet, error) {
+	return autoRefreshSnaps(ctx, st, false)
+}
+
+// autoRefreshSnaps does the work of AutoRefresh leaving out the snaps held
+// by the administrator or outside of their own refresh timer. With
+// ownTimerOnly only snaps with their own refresh timer are refreshed.
+func autoRefreshSnaps(ctx context.Context, st *state.State, ownTimerOnly bool) ([]string, []*state.TaskSet, error) {
 	userID := 0
 
 	if AutoRefreshAssertions != nil {
@@ -1890,21 +1963,40 @@ func AutoRefresh(ctx context.Context, st *state.State) ([]string, []*state.TaskS
 	}
 	if !gateAutoRefreshHook {
 		// old-style refresh (gate-auto-refresh-hook feature disabled)
-		return UpdateMany(ctx, st, nil, userID, &Flags{IsAutoRefresh: true})
+		held, err := heldBySystem(st, timeNow(), ownTimerOnly)
+		if err != nil {
+			return nil, nil, err
+		}
+		notHeld := func(update *snap.Info, _ *SnapState) bool {
+			return !held(update.InstanceName())
+		}
+		updated, tasksets, err := updateManyFiltered(ctx, st, nil, userID, notHeld, &Flags{IsAutoRefresh: true}, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		tasksets, err = arrangeRefreshWaves(st, tasksets)
+		if err != nil {
+			return nil, nil, err
+		}
+		return updated, tasksets, nil
 	}
 
 	// TODO: rename to autoRefreshTasks when old auto refresh logic gets removed.
-	return autoRefreshPhase1(ctx, st)
+	return autoRefreshPhase1(ctx, st, ownTimerOnly)
 }
 
 // autoRefreshPhase1 creates gate-auto-refresh hooks and conditional-auto-refresh
 // task that initiates actual refresh.
 // The state needs to be locked by the caller.
-func autoRefreshPhase1(ctx context.Context, st *state.State) ([]string, []*state.TaskSet, error) {
+func autoRefreshPhase1(ctx context.Context, st *state.State, ownTimerOnly bool) ([]string, []*state.TaskSet, error) {
 	user, err := userFromUserID(st, 0)
 	if err != nil {
 		return nil, nil, err
 	}
+	held, err := heldBySystem(st, timeNow(), ownTimerOnly)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	refreshOpts := &store.RefreshOptions{IsAutoRefresh: true}
 	candidates, snapstateByInstance, ignoreValidationByInstanceName, err := refreshCandidates(ctx, st, nil, user, refreshOpts)
@@ -1932,6 +2024,10 @@ func autoRefreshPhase1(ctx context.Context, st *state.State) ([]string, []*state
 			// filtered out by refreshHintsFromCandidates
 			continue
 		}
+		if held(up.InstanceName()) {
+			logger.Debugf("auto-refresh of snap %q is held by the system", up.InstanceName())
+			continue
+		}
 		snapst := snapstateByInstance[up.InstanceName()]
 		if err := checkChangeConflictIgnoringOneChange(st, up.InstanceName(), snapst, fromChange); err != nil {
 			logger.Noticef("cannot refresh snap %q: %v", up.InstanceName(), err)
@@ -1972,15 +2068,6 @@ func autoRefreshPhase1(ctx context.Context, st *state.State) ([]string, []*state
 		hooks = createGateAutoRefreshHooks(st, affectedSnaps)
 	}
 
-	// gate-auto-refresh hooks, followed by conditional-auto-refresh task waiting
-	// for all hooks.
-	ar := st.NewTask("conditional-auto-refresh", "Run auto-refresh for ready snaps")
-	tss := []*state.TaskSet{state.NewTaskSet(ar)}
-	if hooks != nil {
-		ar.WaitAll(hooks)
-		tss = append(tss, hooks)
-	}
-
 	// return all names as potentially getting updated even though some may be
 	// held.
 	names := make([]string, len(updates))
@@ -1991,6 +2078,42 @@ func autoRefreshPhase1(ctx context.Context, st *state.State) ([]string, []*state
 	}
 	sort.Strings(names)
 
+	// the gate-auto-refresh policies of the administrator are consulted
+	// about all affected snaps, not only the ones with hooks
+	var policyTask *state.Task
+	policies, err := gateAutoRefreshPolicies()
+	if err != nil {
+		logger.Noticef("cannot list gate-auto-refresh policies: %v", err)
+	}
+	if len(policies) > 0 {
+		policyAffected, err := affectedSnapsByRefresh(st, updates, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, up := range updates {
+			if policyAffected[up.InstanceName()] == nil {
+				policyAffected[up.InstanceName()] = &affectedSnapInfo{}
+			}
+		}
+		policyTask = createGateAutoRefreshPolicyTask(st, policyAffected, toUpdate)
+		if hooks != nil {
+			policyTask.WaitAll(hooks)
+		}
+	}
+
+	// gate-auto-refresh hooks and policies, followed by
+	// conditional-auto-refresh task waiting for all of them.
+	ar := st.NewTask("conditional-auto-refresh", "Run auto-refresh for ready snaps")
+	tss := []*state.TaskSet{state.NewTaskSet(ar)}
+	if hooks != nil {
+		ar.WaitAll(hooks)
+		tss = append(tss, hooks)
+	}
+	if policyTask != nil {
+		ar.WaitFor(policyTask)
+		tss = append(tss, state.NewTaskSet(policyTask))
+	}
+
 	// store the list of snaps to update on the conditional-auto-refresh task
 	// (this may be a subset of refresh-candidates due to conflicts).
 	ar.Set("snaps", toUpdate)
@@ -2014,40 +2137,18 @@ func autoRefreshPhase2(ctx context.Context, st *state.State, updates []*refreshC
 		toUpdate[i] = up
 	}
 
-	tr := config.NewTransaction(st)
-	checkDiskSpaceRefresh, err := features.Flag(tr, features.CheckDiskSpaceRefresh)
-	if err != nil && !config.IsNoOption(err) {
+	if err := checkRefreshDiskSpace(st, toUpdate, 0); err != nil {
 		return nil, err
 	}
-	if checkDiskSpaceRefresh {
-		// check if there is enough disk space for requested snaps and their
-		// prerequisites.
-		totalSize, err := installSize(st, toUpdate, 0)
-		if err != nil {
-			return nil, err
-		}
-		requiredSpace := safetyMarginDiskSpace(totalSize)
-		path := dirs.SnapdStateDir(dirs.GlobalRootDir)
-		if err := osutilCheckFreeSpace(path, requiredSpace); err != nil {
-			snaps := make([]string, len(updates))
-			for i, up := range updates {
-				snaps[i] = up.InstanceName()
-			}
-			if _, ok := err.(*osutil.NotEnoughDiskSpaceError); ok {
-				return nil, &InsufficientSpaceError{
-					Path:       path,
-					Snaps:      snaps,
-					ChangeKind: "refresh",
-				}
-			}
-			return nil, err
-		}
-	}
 
 	_, tasksets, err := doUpdate(ctx, st, nil, toUpdate, nil, userID, flags, deviceCtx, fromChange)
 	if err != nil {
 		return nil, err
 	}
+	tasksets, err = arrangeRefreshWaves(st, tasksets)
+	if err != nil {
+		return nil, err
+	}
 
 	tasksets = finalizeUpdate(st, tasksets, len(updates) > 0, nil, userID, flags)
 	return tasksets, nil
@@ -2308,6 +2409,12 @@ func removeTasks(st *state.State, name string, revision snap.Revision, flags *Re
 		removeAll = len(snapst.Sequence) == 1
 	}
 
+	if removeAll {
+		if err := checkRemoveValidationSets(st, &snapst); err != nil {
+			return nil, 0, err
+		}
+	}
+
 	info, err := Info(st, name, revision)
 	if err != nil {
 		return nil, 0, err
@@ -2767,6 +2874,7 @@ func Set(st *state.State, name string, snapst *SnapState) {
 		snaps[name] = &raw
 	}
 	st.Set("snaps", snaps)
+	invalidateStoreRoutesFor(st, name, snapst)
 }
 
 // ActiveInfos returns information about all active snaps.
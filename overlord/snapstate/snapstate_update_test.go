@@ -785,10 +785,11 @@ func (s *snapmgrTestSuite) TestUpdateAmendRunThrough(c *C) {
 			DownloadURL: "https://some-server.com/some/path.snap",
 			Size:        5,
 		},
-		SideInfo:  snapsup.SideInfo,
-		Type:      snap.TypeApp,
-		PlugsOnly: true,
-		Flags:     snapstate.Flags{Amend: true},
+		SideInfo:       snapsup.SideInfo,
+		Type:           snap.TypeApp,
+		PlugsOnly:      true,
+		RefreshTrigger: snapstate.RefreshTriggerManual,
+		Flags:          snapstate.Flags{Amend: true},
 	})
 	c.Assert(snapsup.SideInfo, DeepEquals, &snap.SideInfo{
 		RealName: "some-snap",
@@ -1019,9 +1020,10 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 		DownloadInfo: &snap.DownloadInfo{
 			DownloadURL: "https://some-server.com/some/path.snap",
 		},
-		SideInfo:  snapsup.SideInfo,
-		Type:      snap.TypeApp,
-		PlugsOnly: true,
+		SideInfo:       snapsup.SideInfo,
+		Type:           snap.TypeApp,
+		PlugsOnly:      true,
+		RefreshTrigger: snapstate.RefreshTriggerManual,
 	})
 	c.Assert(snapsup.SideInfo, DeepEquals, &snap.SideInfo{
 		RealName: "services-snap",
@@ -1246,10 +1248,11 @@ func (s *snapmgrTestSuite) TestParallelInstanceUpdateRunThrough(c *C) {
 		DownloadInfo: &snap.DownloadInfo{
 			DownloadURL: "https://some-server.com/some/path.snap",
 		},
-		SideInfo:    snapsup.SideInfo,
-		Type:        snap.TypeApp,
-		PlugsOnly:   true,
-		InstanceKey: "instance",
+		SideInfo:       snapsup.SideInfo,
+		Type:           snap.TypeApp,
+		PlugsOnly:      true,
+		RefreshTrigger: snapstate.RefreshTriggerManual,
+		InstanceKey:    "instance",
 	})
 	c.Assert(snapsup.SideInfo, DeepEquals, &snap.SideInfo{
 		RealName: "services-snap",
@@ -1580,9 +1583,10 @@ func (s *snapmgrTestSuite) TestUpdateModelKernelSwitchTrackRunThrough(c *C) {
 		DownloadInfo: &snap.DownloadInfo{
 			DownloadURL: "https://some-server.com/some/path.snap",
 		},
-		SideInfo:  snapsup.SideInfo,
-		Type:      snap.TypeKernel,
-		PlugsOnly: true,
+		SideInfo:       snapsup.SideInfo,
+		Type:           snap.TypeKernel,
+		PlugsOnly:      true,
+		RefreshTrigger: snapstate.RefreshTriggerManual,
 	})
 	c.Assert(snapsup.SideInfo, DeepEquals, &snap.SideInfo{
 		RealName: "kernel",
@@ -5007,7 +5011,9 @@ func (s *snapmgrTestSuite) testUpdateManyDiskSpaceCheck(c *C, featureFlag, failD
 	restore := snapstate.MockOsutilCheckFreeSpace(func(path string, sz uint64) error {
 		diskCheckCalled = true
 		c.Check(path, Equals, filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd"))
-		c.Check(sz, Equals, snapstate.SafetyMarginDiskSpace(123))
+		// 123 from installSize plus 1 from the mocked data size estimate
+		// of the refreshed app snap
+		c.Check(sz, Equals, snapstate.SafetyMarginDiskSpace(123+1))
 		if failDiskCheck {
 			return &osutil.NotEnoughDiskSpaceError{}
 		}
@@ -5631,7 +5637,9 @@ func (s *snapmgrTestSuite) TestEmptyUpdateWithChannelChangeAndAutoAlias(c *C) {
 
 func (s *snapmgrTestSuite) testUpdateDiskSpaceCheck(c *C, featureFlag, failInstallSize, failDiskCheck bool) error {
 	restore := snapstate.MockOsutilCheckFreeSpace(func(path string, sz uint64) error {
-		c.Check(sz, Equals, snapstate.SafetyMarginDiskSpace(123))
+		// 123 from installSize plus 1 from the mocked data size estimate
+		// of the refreshed app snap
+		c.Check(sz, Equals, snapstate.SafetyMarginDiskSpace(123+1))
 		if failDiskCheck {
 			return &osutil.NotEnoughDiskSpaceError{}
 		}
@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/strutil"
 )
@@ -461,6 +462,27 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 		fallbackID = user.ID
 	}
 
+	vsets, err := enforcedValidationSets(st)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// tracking policies apply when refreshing all snaps
+	var policies map[string]*TrackingPolicy
+	var policyChannels map[string]string
+	var seen map[string]*revisionSeen
+	if len(names) == 0 {
+		policies, err = trackingPolicies(st)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		policyChannels = trackingPolicyChannels(policies, snapStates)
+		seen, err = revisionsSeen(st)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
 	actionsByUserID := make(map[int][]*store.SnapAction)
 	stateByInstanceName := make(map[string]*SnapState, len(snapStates))
 	ignoreValidationByInstanceName := make(map[string]bool)
@@ -482,6 +504,17 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 			return
 		}
 
+		// refreshes are constrained to the revisions required by the
+		// validation sets, conflicts among them were checked when
+		// they were enforced
+		var requiredRev snap.Revision
+		if vsets != nil {
+			requiredRev, _, _ = vsets.Revision(naming.NewSnapRef(snap.InstanceSnap(installed.InstanceName), installed.SnapID))
+		}
+		if !requiredRev.Unset() && requiredRev == installed.Revision {
+			return
+		}
+
 		stateByInstanceName[installed.InstanceName] = snapst
 
 		if len(names) == 0 {
@@ -492,11 +525,16 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 		if userID == 0 {
 			userID = fallbackID
 		}
-		actionsByUserID[userID] = append(actionsByUserID[userID], &store.SnapAction{
+		action := &store.SnapAction{
 			Action:       "refresh",
 			SnapID:       installed.SnapID,
 			InstanceName: installed.InstanceName,
-		})
+			Revision:     requiredRev,
+		}
+		if requiredRev.Unset() {
+			action.Channel = policyChannels[installed.InstanceName]
+		}
+		actionsByUserID[userID] = append(actionsByUserID[userID], action)
 		if snapst.IgnoreValidation {
 			ignoreValidationByInstanceName[installed.InstanceName] = true
 		}
@@ -552,9 +590,15 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 		}
 
 		for _, sar := range sarsForUser {
+			if policy := policies[sar.InstanceName()]; policy != nil && policy.tooRecent(sar.Info, timeNow(), seen) {
+				continue
+			}
 			updates = append(updates, sar.Info)
 		}
 	}
+	if len(policies) != 0 {
+		setRevisionsSeen(st, seen, policies)
+	}
 
 	return updates, stateByInstanceName, ignoreValidationByInstanceName, nil
 }
@@ -570,6 +614,11 @@ func installCandidates(st *state.State, names []string, channel string, user *au
 		return nil, err
 	}
 
+	vsets, err := enforcedValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+
 	actions := make([]*store.SnapAction, len(names))
 	for i, name := range names {
 		actions[i] = &store.SnapAction{
@@ -578,6 +627,16 @@ func installCandidates(st *state.State, names []string, channel string, user *au
 			// the desired channel
 			Channel: channel,
 		}
+		rev, err := validationSetsRevision(vsets, "install", naming.Snap(snap.InstanceSnap(name)), snap.Revision{})
+		if err != nil {
+			return nil, err
+		}
+		if !rev.Unset() {
+			// the revision required by the validation sets,
+			// cannot specify both with the API
+			actions[i].Channel = ""
+			actions[i].Revision = rev
+		}
 	}
 
 	// TODO: possibly support a deviceCtx
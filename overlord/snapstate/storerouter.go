@@ -0,0 +1,601 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/channel"
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// StoreBackend is an additional store, speaking the same API as the
+// default one, to which snaps are routed by name or by publisher. Store
+// backends are configured with the store-backends.<name>.{url,snaps,publishers}
+// system options.
+type StoreBackend struct {
+	Name string
+	URL  *url.URL
+	// Snaps is a list of glob patterns matching the names of the snaps
+	// routed to this backend.
+	Snaps []string
+	// Publishers is a list of publisher account ids whose snaps are
+	// routed to this backend.
+	Publishers []string
+}
+
+type storeBackendConfig struct {
+	URL        string `json:"url"`
+	Snaps      string `json:"snaps"`
+	Publishers string `json:"publishers"`
+}
+
+func splitStoreBackendList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+// ParseStoreBackend parses and validates the configuration of a store
+// backend as found in the store-backends.<name> system option.
+func ParseStoreBackend(name string, rawURL, snaps, publishers string) (*StoreBackend, error) {
+	if err := naming.ValidateSnap(name); err != nil {
+		return nil, fmt.Errorf("invalid store backend name %q", name)
+	}
+	if rawURL == "" {
+		return nil, fmt.Errorf("store backend %q has no url", name)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url for store backend %q: %v", name, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url for store backend %q: %q is not an absolute http(s) url", name, rawURL)
+	}
+	if u.RawQuery != "" {
+		return nil, fmt.Errorf("invalid url for store backend %q: url may not contain a query string", name)
+	}
+	b := &StoreBackend{
+		Name:       name,
+		URL:        u,
+		Snaps:      splitStoreBackendList(snaps),
+		Publishers: splitStoreBackendList(publishers),
+	}
+	for _, pat := range b.Snaps {
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("invalid snap pattern %q for store backend %q", pat, name)
+		}
+	}
+	return b, nil
+}
+
+func (b *StoreBackend) matchesName(snapName string) bool {
+	for _, pat := range b.Snaps {
+		if ok, _ := filepath.Match(pat, snapName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *StoreBackend) matchesPublisher(publisherID string) bool {
+	return publisherID != "" && strutil.ListContains(b.Publishers, publisherID)
+}
+
+// StoreBackends returns the configured additional store backends,
+// sorted by name.
+func StoreBackends(st *state.State) ([]*StoreBackend, error) {
+	return StoreBackendsFromConfig(config.NewTransaction(st))
+}
+
+// StoreBackendsFromConfig returns the additional store backends
+// configured in the given configuration, sorted by name.
+func StoreBackendsFromConfig(tr config.ConfGetter) ([]*StoreBackend, error) {
+	var cfg map[string]storeBackendConfig
+	if err := tr.Get("core", "store-backends", &cfg); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	backends := make([]*StoreBackend, 0, len(cfg))
+	for name, bcfg := range cfg {
+		b, err := ParseStoreBackend(name, bcfg.URL, bcfg.Snaps, bcfg.Publishers)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, b)
+	}
+	sort.Slice(backends, func(i, j int) bool {
+		return backends[i].Name < backends[j].Name
+	})
+	return backends, nil
+}
+
+// storeBackendFor returns the name of the store backend the given snap is
+// routed to, or "" for the default store. A snap that is already
+// installed sticks to the backend it was installed from, otherwise snaps
+// are routed first by name and then by publisher.
+func storeBackendFor(st *state.State, snapst *SnapState, info *snap.Info) (string, error) {
+	backends, err := StoreBackends(st)
+	if err != nil {
+		return "", err
+	}
+	var recorded string
+	installed := snapst != nil && snapst.IsInstalled()
+	if installed {
+		recorded = snapst.StoreBackend
+	}
+	b := routeSnap(backends, info.SnapName(), info.Publisher.ID, installed, recorded)
+	if b == nil {
+		return "", nil
+	}
+	return b.Name, nil
+}
+
+// routeSnap returns the backend a snap is routed to, nil meaning the
+// default store. The publisher is only considered for snaps that are not
+// installed yet.
+func routeSnap(backends []*StoreBackend, snapName, publisherID string, installed bool, recorded string) *StoreBackend {
+	if recorded != "" {
+		for _, b := range backends {
+			if b.Name == recorded {
+				return b
+			}
+		}
+	}
+	for _, b := range backends {
+		if b.matchesName(snapName) {
+			return b
+		}
+	}
+	if installed {
+		return nil
+	}
+	for _, b := range backends {
+		if b.matchesPublisher(publisherID) {
+			return b
+		}
+	}
+	return nil
+}
+
+type storeBackendFactoryKey struct{}
+
+type storeBackendKey struct {
+	name string
+	url  string
+}
+
+// ReplaceStoreBackendFactory sets the function used to create the stores
+// for the additional store backends.
+func ReplaceStoreBackendFactory(st *state.State, newStore func(storeURL *url.URL) StoreService) {
+	st.Cache(storeBackendFactoryKey{}, newStore)
+}
+
+// storeForBackend returns the store for the named backend, the default
+// store if name is empty.
+func storeForBackend(st *state.State, defaultStore StoreService, backends []*StoreBackend, name string) (StoreService, error) {
+	if name == "" {
+		return defaultStore, nil
+	}
+	for _, b := range backends {
+		if b.Name != name {
+			continue
+		}
+		key := storeBackendKey{name: b.Name, url: b.URL.String()}
+		if sto, ok := st.Cached(key).(StoreService); ok {
+			return sto, nil
+		}
+		newStore, ok := st.Cached(storeBackendFactoryKey{}).(func(*url.URL) StoreService)
+		if !ok {
+			return nil, fmt.Errorf("internal error: cannot create stores for store backends")
+		}
+		sto := newStore(b.URL)
+		st.Cache(key, sto)
+		return sto, nil
+	}
+	return nil, fmt.Errorf("store backend %q is not configured", name)
+}
+
+// storeForSnapSetup returns the store the snap of the given SnapSetup was
+// installed from.
+func storeForSnapSetup(st *state.State, snapsup *SnapSetup, deviceCtx DeviceContext) (StoreService, error) {
+	sto := Store(st, deviceCtx)
+	if snapsup.StoreBackend == "" {
+		return sto, nil
+	}
+	router, ok := sto.(*storeRouter)
+	if !ok {
+		// not routing, e.g. while remodeling to a new store
+		return sto, nil
+	}
+	for _, b := range router.backends {
+		if b.Name == snapsup.StoreBackend {
+			return b.sto, nil
+		}
+	}
+	return nil, fmt.Errorf("store backend %q is not configured", snapsup.StoreBackend)
+}
+
+type routedBackend struct {
+	*StoreBackend
+	sto StoreService
+}
+
+// storeRouter multiplexes the default store and the additional store
+// backends behind the StoreService interface. Operations not about
+// specific snaps are served by the default store.
+type storeRouter struct {
+	StoreService
+
+	backends []*routedBackend
+	// installed maps the instance names of installed snaps to the
+	// store backends recorded for them
+	installed map[string]string
+}
+
+type storeRoutesKey struct{}
+
+// storeRoutes caches the store returned by routedStore for a default store.
+type storeRoutes struct {
+	defaultStore StoreService
+	sto          StoreService
+}
+
+// InvalidateStoreRoutes drops the cached routing of store requests, it
+// must be called when the configuration of the store backends changes.
+func InvalidateStoreRoutes(st *state.State) {
+	st.Cache(storeRoutesKey{}, nil)
+}
+
+// invalidateStoreRoutesFor drops the cached routing of store requests if
+// the given state of a snap changes its route.
+func invalidateStoreRoutesFor(st *state.State, instanceName string, snapst *SnapState) {
+	routes, ok := st.Cached(storeRoutesKey{}).(*storeRoutes)
+	if !ok {
+		return
+	}
+	r, ok := routes.sto.(*storeRouter)
+	if !ok {
+		// no store backends, nothing to route
+		return
+	}
+	recorded, wasInstalled := r.installed[instanceName]
+	installed := snapst != nil && len(snapst.Sequence) > 0
+	if installed != wasInstalled || (installed && snapst.StoreBackend != recorded) {
+		InvalidateStoreRoutes(st)
+	}
+}
+
+// routedStore wraps the default store into a storeRouter if store
+// backends are configured. The result is cached until the configuration
+// of the store backends or the routes of installed snaps change.
+func routedStore(st *state.State, defaultStore StoreService) StoreService {
+	if routes, ok := st.Cached(storeRoutesKey{}).(*storeRoutes); ok && routes.defaultStore == defaultStore {
+		return routes.sto
+	}
+	sto := newRoutedStore(st, defaultStore)
+	st.Cache(storeRoutesKey{}, &storeRoutes{defaultStore: defaultStore, sto: sto})
+	return sto
+}
+
+func newRoutedStore(st *state.State, defaultStore StoreService) StoreService {
+	backends, err := StoreBackends(st)
+	if err != nil {
+		logger.Noticef("cannot get store backends: %v", err)
+		return defaultStore
+	}
+	if len(backends) == 0 {
+		return defaultStore
+	}
+	r := &storeRouter{
+		StoreService: defaultStore,
+		installed:    make(map[string]string),
+	}
+	for _, b := range backends {
+		sto, err := storeForBackend(st, defaultStore, backends, b.Name)
+		if err != nil {
+			logger.Noticef("cannot use store backend %q: %v", b.Name, err)
+			continue
+		}
+		r.backends = append(r.backends, &routedBackend{StoreBackend: b, sto: sto})
+	}
+	snapStates, err := All(st)
+	if err != nil {
+		logger.Noticef("cannot get installed snaps for routing store requests: %v", err)
+	}
+	for instanceName, snapst := range snapStates {
+		r.installed[instanceName] = snapst.StoreBackend
+	}
+	return r
+}
+
+// fixedRoute returns the backend a snap is routed to without having to
+// know its publisher, nil meaning the default store, and whether the
+// route is known.
+func (r *storeRouter) fixedRoute(instanceName string) (*routedBackend, bool) {
+	snapName := snap.InstanceSnap(instanceName)
+	recorded, installed := r.installed[instanceName]
+	if recorded != "" {
+		for _, b := range r.backends {
+			if b.Name == recorded {
+				return b, true
+			}
+		}
+	}
+	for _, b := range r.backends {
+		if b.matchesName(snapName) {
+			return b, true
+		}
+	}
+	if installed || !r.hasPublisherRoutes() {
+		return nil, true
+	}
+	return nil, false
+}
+
+func (r *storeRouter) hasPublisherRoutes() bool {
+	for _, b := range r.backends {
+		if len(b.Publishers) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *storeRouter) publisherBackend(publisherID string) *routedBackend {
+	for _, b := range r.backends {
+		if b.matchesPublisher(publisherID) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (r *storeRouter) storeOf(b *routedBackend) StoreService {
+	if b == nil {
+		return r.StoreService
+	}
+	return b.sto
+}
+
+// servedBy returns the backend the given store result must come from.
+func (r *storeRouter) servedBy(info *snap.Info) *routedBackend {
+	if b, ok := r.fixedRoute(info.InstanceName()); ok {
+		return b
+	}
+	return r.publisherBackend(info.Publisher.ID)
+}
+
+func (r *storeRouter) SnapInfo(ctx context.Context, spec store.SnapSpec, user *auth.UserState) (*snap.Info, error) {
+	if b, ok := r.fixedRoute(spec.Name); ok {
+		return r.storeOf(b).SnapInfo(ctx, spec, user)
+	}
+	for _, b := range r.backends {
+		if len(b.Publishers) == 0 {
+			continue
+		}
+		info, err := b.sto.SnapInfo(ctx, spec, user)
+		if err == nil && b.matchesPublisher(info.Publisher.ID) {
+			return info, nil
+		}
+	}
+	info, err := r.StoreService.SnapInfo(ctx, spec, user)
+	if err != nil {
+		return nil, err
+	}
+	if r.publisherBackend(info.Publisher.ID) != nil {
+		// the snap must come from the backend of its publisher
+		return nil, store.ErrSnapNotFound
+	}
+	return info, nil
+}
+
+func (r *storeRouter) SnapExists(ctx context.Context, spec store.SnapSpec, user *auth.UserState) (naming.SnapRef, *channel.Channel, error) {
+	b, _ := r.fixedRoute(spec.Name)
+	return r.storeOf(b).SnapExists(ctx, spec, user)
+}
+
+func (r *storeRouter) Find(ctx context.Context, search *store.Search, user *auth.UserState) ([]*snap.Info, error) {
+	found, err := r.StoreService.Find(ctx, search, user)
+	if err != nil {
+		return nil, err
+	}
+	var res []*snap.Info
+	for _, info := range found {
+		if r.servedBy(info) == nil {
+			res = append(res, info)
+		}
+	}
+	for _, b := range r.backends {
+		found, err := b.sto.Find(ctx, search, user)
+		if err != nil {
+			logger.Noticef("cannot search store backend %q: %v", b.Name, err)
+			continue
+		}
+		for _, info := range found {
+			if r.servedBy(info) == b {
+				res = append(res, info)
+			}
+		}
+	}
+	return res, nil
+}
+
+func actionErrors(saErr *store.SnapActionError, action string) map[string]error {
+	var errs *map[string]error
+	switch action {
+	case "refresh":
+		errs = &saErr.Refresh
+	case "install":
+		errs = &saErr.Install
+	default:
+		errs = &saErr.Download
+	}
+	if *errs == nil {
+		*errs = make(map[string]error)
+	}
+	return *errs
+}
+
+func mergeSnapActionError(merged *store.SnapActionError, err error, actions []*store.SnapAction) {
+	saErr, ok := err.(*store.SnapActionError)
+	if !ok {
+		// attribute the error to every action sent to the store
+		for _, a := range actions {
+			actionErrors(merged, a.Action)[a.InstanceName] = err
+		}
+		return
+	}
+	for name, e := range saErr.Refresh {
+		actionErrors(merged, "refresh")[name] = e
+	}
+	for name, e := range saErr.Install {
+		actionErrors(merged, "install")[name] = e
+	}
+	for name, e := range saErr.Download {
+		actionErrors(merged, "download")[name] = e
+	}
+	merged.Other = append(merged.Other, saErr.Other...)
+}
+
+func (r *storeRouter) SnapAction(ctx context.Context, currentSnaps []*store.CurrentSnap, actions []*store.SnapAction, assertQuery store.AssertionQuery, user *auth.UserState, opts *store.RefreshOptions) ([]store.SnapActionResult, []store.AssertionResult, error) {
+	perBackend := make(map[*routedBackend][]*store.SnapAction)
+	var undecided []*store.SnapAction
+	for _, a := range actions {
+		if b, ok := r.fixedRoute(a.InstanceName); ok {
+			perBackend[b] = append(perBackend[b], a)
+		} else {
+			undecided = append(undecided, a)
+		}
+	}
+
+	if len(undecided) == 0 && len(perBackend) == 1 {
+		for b, bActions := range perBackend {
+			if b == nil || assertQuery == nil {
+				// a single store is involved
+				return r.storeOf(b).SnapAction(ctx, r.currentSnapsFor(b, currentSnaps), bActions, assertQuery, user, opts)
+			}
+		}
+	}
+
+	var sars []store.SnapActionResult
+	merged := &store.SnapActionError{}
+
+	// snaps without a fixed route are looked up first in the backends
+	// of their possible publishers
+	for _, b := range r.backends {
+		if len(undecided) == 0 {
+			break
+		}
+		if len(b.Publishers) == 0 {
+			continue
+		}
+		bSars, _, err := b.sto.SnapAction(ctx, r.currentSnapsFor(b, currentSnaps), undecided, nil, user, opts)
+		if err != nil {
+			if _, ok := err.(*store.SnapActionError); !ok {
+				logger.Noticef("cannot query store backend %q: %v", b.Name, err)
+			}
+		}
+		found := make(map[string]bool, len(bSars))
+		for _, sar := range bSars {
+			if b.matchesPublisher(sar.Publisher.ID) {
+				sars = append(sars, sar)
+				found[sar.InstanceName()] = true
+			}
+		}
+		remaining := undecided[:0:0]
+		for _, a := range undecided {
+			if !found[a.InstanceName] {
+				remaining = append(remaining, a)
+			}
+		}
+		undecided = remaining
+	}
+	undecidedNames := make(map[string]string, len(undecided))
+	for _, a := range undecided {
+		undecidedNames[a.InstanceName] = a.Action
+	}
+	perBackend[nil] = append(perBackend[nil], undecided...)
+
+	var ars []store.AssertionResult
+	stores := append([]*routedBackend{nil}, r.backends...)
+	for _, b := range stores {
+		bActions := perBackend[b]
+		var bAssertQuery store.AssertionQuery
+		if b == nil {
+			// assertions are always fetched from the default store
+			bAssertQuery = assertQuery
+		}
+		if len(bActions) == 0 && bAssertQuery == nil {
+			continue
+		}
+		bSars, bArs, err := r.storeOf(b).SnapAction(ctx, r.currentSnapsFor(b, currentSnaps), bActions, bAssertQuery, user, opts)
+		if err != nil {
+			if saErr, ok := err.(*store.SnapActionError); ok && saErr.NoResults {
+				// nothing found in this store
+			} else {
+				mergeSnapActionError(merged, err, bActions)
+			}
+		}
+		for _, sar := range bSars {
+			if action, ok := undecidedNames[sar.InstanceName()]; ok && r.publisherBackend(sar.Publisher.ID) != nil {
+				// the snap must come from the backend of its publisher
+				actionErrors(merged, action)[sar.InstanceName()] = store.ErrSnapNotFound
+				continue
+			}
+			sars = append(sars, sar)
+		}
+		ars = append(ars, bArs...)
+	}
+
+	if len(merged.Refresh)+len(merged.Install)+len(merged.Download)+len(merged.Other) == 0 {
+		if len(sars) == 0 && len(ars) == 0 {
+			return nil, nil, &store.SnapActionError{NoResults: true}
+		}
+		return sars, ars, nil
+	}
+	return sars, ars, merged
+}
+
+// currentSnapsFor returns the current snaps routed to the given backend.
+func (r *storeRouter) currentSnapsFor(b *routedBackend, currentSnaps []*store.CurrentSnap) []*store.CurrentSnap {
+	var res []*store.CurrentSnap
+	for _, cur := range currentSnaps {
+		if cb, _ := r.fixedRoute(cur.InstanceName); cb == b {
+			res = append(res, cur)
+		}
+	}
+	return res
+}
@@ -0,0 +1,416 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"net/url"
+	"sort"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/storetest"
+)
+
+// routedCatalogStore serves the snaps of a fixed catalog.
+type routedCatalogStore struct {
+	storetest.Store
+
+	name    string
+	catalog map[string]string // snap name -> publisher id
+
+	actions      []string
+	currentSnaps []string
+	assertQuery  bool
+}
+
+func (s *routedCatalogStore) info(name string) *snap.Info {
+	info := &snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: name,
+			SnapID:   name + "-id",
+			Revision: snap.R(1),
+		},
+		Publisher: snap.StoreAccount{ID: s.catalog[name]},
+	}
+	// remember where the result came from
+	info.Website = s.name
+	return info
+}
+
+func (s *routedCatalogStore) SnapInfo(ctx context.Context, spec store.SnapSpec, user *auth.UserState) (*snap.Info, error) {
+	s.actions = append(s.actions, "info:"+spec.Name)
+	if _, ok := s.catalog[spec.Name]; !ok {
+		return nil, store.ErrSnapNotFound
+	}
+	return s.info(spec.Name), nil
+}
+
+func (s *routedCatalogStore) Find(ctx context.Context, search *store.Search, user *auth.UserState) ([]*snap.Info, error) {
+	var res []*snap.Info
+	for name := range s.catalog {
+		res = append(res, s.info(name))
+	}
+	return res, nil
+}
+
+func (s *routedCatalogStore) SnapAction(ctx context.Context, currentSnaps []*store.CurrentSnap, actions []*store.SnapAction, assertQuery store.AssertionQuery, user *auth.UserState, opts *store.RefreshOptions) ([]store.SnapActionResult, []store.AssertionResult, error) {
+	for _, cur := range currentSnaps {
+		s.currentSnaps = append(s.currentSnaps, cur.InstanceName)
+	}
+	if assertQuery != nil {
+		s.assertQuery = true
+	}
+	var sars []store.SnapActionResult
+	saErr := &store.SnapActionError{}
+	for _, a := range actions {
+		s.actions = append(s.actions, a.Action+":"+a.InstanceName)
+		if _, ok := s.catalog[a.InstanceName]; !ok {
+			if saErr.Install == nil {
+				saErr.Install = make(map[string]error)
+			}
+			saErr.Install[a.InstanceName] = store.ErrSnapNotFound
+			continue
+		}
+		sars = append(sars, store.SnapActionResult{Info: s.info(a.InstanceName)})
+	}
+	if len(saErr.Install) != 0 {
+		if len(sars) == 0 {
+			saErr.NoResults = true
+		}
+		return sars, nil, saErr
+	}
+	return sars, nil, nil
+}
+
+type storeRouterSuite struct {
+	state *state.State
+
+	defaultStore *routedCatalogStore
+	backends     map[string]*routedCatalogStore
+}
+
+var _ = Suite(&storeRouterSuite{})
+
+func (s *storeRouterSuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+	s.defaultStore = &routedCatalogStore{
+		name: "default",
+		catalog: map[string]string{
+			"core":        "canonical",
+			"foo":         "publisher",
+			"acme-client": "acme",
+		},
+	}
+	s.backends = map[string]*routedCatalogStore{
+		"http://acme.example.com/": {
+			name: "acme",
+			catalog: map[string]string{
+				"acme-client": "acme",
+				"acme-tool":   "acme",
+				"foo":         "publisher",
+			},
+		},
+		"http://internal.example.com/": {
+			name: "internal",
+			catalog: map[string]string{
+				"internal-app": "someone",
+			},
+		},
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.ReplaceStore(s.state, s.defaultStore)
+	snapstate.ReplaceStoreBackendFactory(s.state, func(storeURL *url.URL) snapstate.StoreService {
+		sto := s.backends[storeURL.String()]
+		c.Assert(sto, NotNil)
+		return sto
+	})
+}
+
+func (s *storeRouterSuite) configure(c *C, backends map[string]interface{}) {
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "store-backends", backends), IsNil)
+	tr.Commit()
+}
+
+func (s *storeRouterSuite) configureDefault(c *C) {
+	s.configure(c, map[string]interface{}{
+		"acme": map[string]interface{}{
+			"url":        "http://acme.example.com/",
+			"publishers": "acme",
+		},
+		"internal": map[string]interface{}{
+			"url":   "http://internal.example.com/",
+			"snaps": "internal-*",
+		},
+	})
+}
+
+func (s *storeRouterSuite) TestNoBackendsUsesDefaultStore(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(snapstate.Store(s.state, nil), Equals, s.defaultStore)
+}
+
+func (s *storeRouterSuite) TestStoreBackends(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.configureDefault(c)
+
+	backends, err := snapstate.StoreBackends(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(backends, HasLen, 2)
+	c.Check(backends[0].Name, Equals, "acme")
+	c.Check(backends[0].URL.String(), Equals, "http://acme.example.com/")
+	c.Check(backends[0].Snaps, HasLen, 0)
+	c.Check(backends[0].Publishers, DeepEquals, []string{"acme"})
+	c.Check(backends[1].Name, Equals, "internal")
+	c.Check(backends[1].Snaps, DeepEquals, []string{"internal-*"})
+	c.Check(backends[1].Publishers, HasLen, 0)
+}
+
+func (s *storeRouterSuite) TestParseStoreBackendErrors(c *C) {
+	for _, t := range []struct {
+		name, url, snaps string
+		err              string
+	}{
+		{"Bad_Name", "http://example.com", "", `invalid store backend name "Bad_Name"`},
+		{"foo", "", "", `store backend "foo" has no url`},
+		{"foo", "example.com", "", `invalid url for store backend "foo": "example.com" is not an absolute http\(s\) url`},
+		{"foo", "ftp://example.com", "", `invalid url for store backend "foo": "ftp://example.com" is not an absolute http\(s\) url`},
+		{"foo", "http://example.com/?q=1", "", `invalid url for store backend "foo": url may not contain a query string`},
+		{"foo", "http://example.com", "foo-[", `invalid snap pattern "foo-\[" for store backend "foo"`},
+	} {
+		_, err := snapstate.ParseStoreBackend(t.name, t.url, t.snaps, "")
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *storeRouterSuite) TestSnapInfoRoutesByName(c *C) {
+	s.state.Lock()
+	s.configureDefault(c)
+	sto := snapstate.Store(s.state, nil)
+	s.state.Unlock()
+
+	info, err := sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "internal-app"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Website, Equals, "internal")
+	c.Check(s.defaultStore.actions, HasLen, 0)
+	c.Check(s.backends["http://acme.example.com/"].actions, HasLen, 0)
+}
+
+func (s *storeRouterSuite) TestSnapInfoRoutesByPublisher(c *C) {
+	s.state.Lock()
+	s.configureDefault(c)
+	sto := snapstate.Store(s.state, nil)
+	s.state.Unlock()
+
+	// the snap of a routed publisher comes from its backend
+	info, err := sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "acme-client"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Website, Equals, "acme")
+
+	// a snap of another publisher found in the backend comes from the
+	// default store
+	info, err = sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "foo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Website, Equals, "default")
+}
+
+func (s *storeRouterSuite) TestSnapInfoRejectsPublisherSnapFromDefaultStore(c *C) {
+	s.defaultStore.catalog["acme-only"] = "acme"
+
+	s.state.Lock()
+	s.configureDefault(c)
+	sto := snapstate.Store(s.state, nil)
+	s.state.Unlock()
+
+	_, err := sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "acme-only"}, nil)
+	c.Check(err, Equals, store.ErrSnapNotFound)
+}
+
+func (s *storeRouterSuite) TestInstalledSnapSticksToRecordedBackend(c *C) {
+	s.state.Lock()
+	s.configureDefault(c)
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active:       true,
+		Sequence:     []*snap.SideInfo{{RealName: "foo", SnapID: "foo-id", Revision: snap.R(1)}},
+		Current:      snap.R(1),
+		StoreBackend: "acme",
+	})
+	snapstate.Set(s.state, "acme-client", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "acme-client", SnapID: "acme-client-id", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	})
+	sto := snapstate.Store(s.state, nil)
+	s.state.Unlock()
+
+	info, err := sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "foo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Website, Equals, "acme")
+
+	// installed from the default store before the backend was configured
+	info, err = sto.SnapInfo(context.TODO(), store.SnapSpec{Name: "acme-client"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Website, Equals, "default")
+}
+
+func (s *storeRouterSuite) TestStoreRoutesCached(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.configureDefault(c)
+	sto := snapstate.Store(s.state, nil)
+	c.Check(sto, Not(Equals), s.defaultStore)
+	c.Check(snapstate.Store(s.state, nil), Equals, sto)
+
+	// the routes of installed snaps are part of the cached routing
+	foo := &snapstate.SnapState{
+		Active:       true,
+		Sequence:     []*snap.SideInfo{{RealName: "foo", SnapID: "foo-id", Revision: snap.R(1)}},
+		Current:      snap.R(1),
+		StoreBackend: "acme",
+	}
+	snapstate.Set(s.state, "foo", foo)
+	sto2 := snapstate.Store(s.state, nil)
+	c.Check(sto2, Not(Equals), sto)
+
+	// changes not affecting the route keep the cached routing
+	foo.Sequence = append(foo.Sequence, &snap.SideInfo{RealName: "foo", SnapID: "foo-id", Revision: snap.R(2)})
+	foo.Current = snap.R(2)
+	snapstate.Set(s.state, "foo", foo)
+	c.Check(snapstate.Store(s.state, nil), Equals, sto2)
+
+	// removing the snap changes its route
+	snapstate.Set(s.state, "foo", nil)
+	sto3 := snapstate.Store(s.state, nil)
+	c.Check(sto3, Not(Equals), sto2)
+
+	// configuration changes are picked up once the routing is invalidated
+	s.configure(c, map[string]interface{}{})
+	c.Check(snapstate.Store(s.state, nil), Equals, sto3)
+	snapstate.InvalidateStoreRoutes(s.state)
+	c.Check(snapstate.Store(s.state, nil), Equals, s.defaultStore)
+}
+
+func (s *storeRouterSuite) TestSnapActionSplitsPerStore(c *C) {
+	s.state.Lock()
+	s.configureDefault(c)
+	snapstate.Set(s.state, "core", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "core", SnapID: "core-id", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	})
+	snapstate.Set(s.state, "internal-app", &snapstate.SnapState{
+		Active:       true,
+		Sequence:     []*snap.SideInfo{{RealName: "internal-app", SnapID: "internal-app-id", Revision: snap.R(1)}},
+		Current:      snap.R(1),
+		StoreBackend: "internal",
+	})
+	sto := snapstate.Store(s.state, nil)
+	s.state.Unlock()
+
+	current := []*store.CurrentSnap{
+		{InstanceName: "core", SnapID: "core-id", Revision: snap.R(1)},
+		{InstanceName: "internal-app", SnapID: "internal-app-id", Revision: snap.R(1)},
+	}
+	actions := []*store.SnapAction{
+		{Action: "refresh", InstanceName: "core", SnapID: "core-id"},
+		{Action: "refresh", InstanceName: "internal-app", SnapID: "internal-app-id"},
+		{Action: "install", InstanceName: "acme-tool"},
+		{Action: "install", InstanceName: "foo"},
+		{Action: "install", InstanceName: "missing"},
+	}
+	sars, _, err := sto.SnapAction(context.TODO(), current, actions, nil, nil, nil)
+	c.Assert(err, FitsTypeOf, &store.SnapActionError{})
+	c.Check(err.(*store.SnapActionError).Install, DeepEquals, map[string]error{
+		"missing": store.ErrSnapNotFound,
+	})
+
+	got := make([]string, 0, len(sars))
+	for _, sar := range sars {
+		got = append(got, sar.InstanceName()+"@"+sar.Website)
+	}
+	sort.Strings(got)
+	c.Check(got, DeepEquals, []string{
+		"acme-tool@acme",
+		"core@default",
+		"foo@default",
+		"internal-app@internal",
+	})
+
+	c.Check(s.defaultStore.currentSnaps, DeepEquals, []string{"core"})
+	c.Check(s.backends["http://internal.example.com/"].currentSnaps, DeepEquals, []string{"internal-app"})
+	c.Check(s.backends["http://internal.example.com/"].actions, DeepEquals, []string{"refresh:internal-app"})
+}
+
+func (s *storeRouterSuite) TestSnapActionAssertionsFromDefaultStore(c *C) {
+	s.state.Lock()
+	s.configureDefault(c)
+	sto := snapstate.Store(s.state, nil)
+	s.state.Unlock()
+
+	actions := []*store.SnapAction{
+		{Action: "install", InstanceName: "internal-app"},
+	}
+	sars, _, err := sto.SnapAction(context.TODO(), nil, actions, &fakeAssertQuery{}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(sars, HasLen, 1)
+	c.Check(sars[0].Website, Equals, "internal")
+	c.Check(s.defaultStore.assertQuery, Equals, true)
+	c.Check(s.backends["http://internal.example.com/"].assertQuery, Equals, false)
+}
+
+func (s *storeRouterSuite) TestFindMergesStores(c *C) {
+	s.state.Lock()
+	s.configureDefault(c)
+	sto := snapstate.Store(s.state, nil)
+	s.state.Unlock()
+
+	found, err := sto.Find(context.TODO(), &store.Search{Query: "x"}, nil)
+	c.Assert(err, IsNil)
+	got := make([]string, 0, len(found))
+	for _, info := range found {
+		got = append(got, info.InstanceName()+"@"+info.Website)
+	}
+	sort.Strings(got)
+	c.Check(got, DeepEquals, []string{
+		"acme-client@acme",
+		"acme-tool@acme",
+		"core@default",
+		"foo@default",
+		"internal-app@internal",
+	})
+}
+
+type fakeAssertQuery struct {
+	store.AssertionQuery
+}
@@ -0,0 +1,219 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/channel"
+)
+
+// TrackingPolicy controls the channel the refreshes of all snaps, including
+// auto-refreshes, take a snap from. The snap is switched to the channel
+// with the first refresh from it. Refreshes of explicitly named snaps are
+// not affected.
+type TrackingPolicy struct {
+	// Risk is the risk level followed.
+	Risk string `json:"risk"`
+	// Track pins the track followed, otherwise the track of the channel
+	// tracked by the snap is kept.
+	Track string `json:"track,omitempty"`
+	// MinAge is for how long a revision must have been released to the
+	// followed channel before it is taken.
+	MinAge time.Duration `json:"min-age,omitempty"`
+}
+
+// Validate checks that the tracking policy is well formed.
+func (p *TrackingPolicy) Validate() error {
+	ch, err := channel.ParseVerbatim(p.Risk, "-")
+	if err != nil || !ch.VerbatimRiskOnly() {
+		return fmt.Errorf("invalid risk level %q", p.Risk)
+	}
+	if p.Track != "" {
+		ch, err := channel.ParseVerbatim(p.Track, "-")
+		if err != nil || !ch.VerbatimTrackOnly() {
+			return fmt.Errorf("invalid track %q", p.Track)
+		}
+	}
+	if p.MinAge < 0 {
+		return fmt.Errorf("invalid minimum revision age %v", p.MinAge)
+	}
+	return nil
+}
+
+// ChannelFor returns the channel followed by a snap tracking the given
+// channel.
+func (p *TrackingPolicy) ChannelFor(tracking string) (string, error) {
+	if p.Track != "" {
+		return channel.ResolvePinned(p.Track, p.Risk)
+	}
+	// keeps the track but not the branch
+	return channel.Resolve(tracking, p.Risk)
+}
+
+// revisionSeen records when a revision was first offered from a channel.
+type revisionSeen struct {
+	Revision snap.Revision `json:"revision"`
+	Channel  string        `json:"channel"`
+	Time     time.Time     `json:"time"`
+}
+
+// releaseTime returns when the given refresh candidate was released to its
+// channel. When the store does not tell, the time the revision was first
+// offered from the channel, as recorded in seen, is used instead.
+func releaseTime(update *snap.Info, now time.Time, seen map[string]*revisionSeen) time.Time {
+	if release := update.Channels[update.Channel]; release != nil && !release.ReleasedAt.IsZero() {
+		return release.ReleasedAt
+	}
+	name := update.InstanceName()
+	if s := seen[name]; s != nil && s.Revision == update.Revision && s.Channel == update.Channel {
+		return s.Time
+	}
+	logger.Noticef("cannot tell when revision %s of snap %q was released to %q, using the time it was first offered instead", update.Revision, name, update.Channel)
+	seen[name] = &revisionSeen{
+		Revision: update.Revision,
+		Channel:  update.Channel,
+		Time:     now,
+	}
+	return now
+}
+
+// tooRecent returns whether the given refresh candidate was released to
+// its channel too recently to be taken.
+func (p *TrackingPolicy) tooRecent(update *snap.Info, now time.Time, seen map[string]*revisionSeen) bool {
+	if p.MinAge == 0 {
+		return false
+	}
+	if releaseTime(update, now, seen).Add(p.MinAge).After(now) {
+		logger.Debugf("revision %s of snap %q was released to %q too recently", update.Revision, update.InstanceName(), update.Channel)
+		return true
+	}
+	return false
+}
+
+func revisionsSeen(st *state.State) (map[string]*revisionSeen, error) {
+	// snap -> last revision offered without a release time
+	var seen map[string]*revisionSeen
+	err := st.Get("snaps-tracking-seen", &seen)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("internal error: cannot get snaps-tracking-seen: %v", err)
+	}
+	if err == state.ErrNoState {
+		return make(map[string]*revisionSeen), nil
+	}
+	return seen, nil
+}
+
+// setRevisionsSeen stores the revisions seen for the snaps that still have
+// a tracking policy.
+func setRevisionsSeen(st *state.State, seen map[string]*revisionSeen, policies map[string]*TrackingPolicy) {
+	for name := range seen {
+		if policies[name] == nil {
+			delete(seen, name)
+		}
+	}
+	if len(seen) == 0 {
+		st.Set("snaps-tracking-seen", nil)
+		return
+	}
+	st.Set("snaps-tracking-seen", seen)
+}
+
+func trackingPolicies(st *state.State) (map[string]*TrackingPolicy, error) {
+	// snap -> tracking policy
+	var policies map[string]*TrackingPolicy
+	err := st.Get("snaps-tracking-policy", &policies)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("internal error: cannot get snaps-tracking-policy: %v", err)
+	}
+	if err == state.ErrNoState {
+		return make(map[string]*TrackingPolicy), nil
+	}
+	return policies, nil
+}
+
+// TrackingPolicies returns the tracking policies set by the administrator
+// for individual snaps.
+func TrackingPolicies(st *state.State) (map[string]*TrackingPolicy, error) {
+	return trackingPolicies(st)
+}
+
+// SetTrackingPolicy sets the tracking policy of the given snaps.
+func SetTrackingPolicy(st *state.State, policy *TrackingPolicy, snaps ...string) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if err := checkInstalled(st, snaps); err != nil {
+		return err
+	}
+	policies, err := trackingPolicies(st)
+	if err != nil {
+		return err
+	}
+	for _, snapName := range snaps {
+		p := *policy
+		policies[snapName] = &p
+	}
+	st.Set("snaps-tracking-policy", policies)
+	return nil
+}
+
+// RemoveTrackingPolicy removes the tracking policy of the given snaps, they
+// keep tracking the channel they were last refreshed from.
+func RemoveTrackingPolicy(st *state.State, snaps ...string) error {
+	policies, err := trackingPolicies(st)
+	if err != nil {
+		return err
+	}
+	var changed bool
+	for _, snapName := range snaps {
+		if _, ok := policies[snapName]; ok {
+			delete(policies, snapName)
+			changed = true
+		}
+	}
+	if changed {
+		st.Set("snaps-tracking-policy", policies)
+	}
+	return nil
+}
+
+// trackingPolicyChannels returns the channels followed by the given snaps
+// as determined by their tracking policies, snaps without one are left out.
+func trackingPolicyChannels(policies map[string]*TrackingPolicy, snapStates map[string]*SnapState) map[string]string {
+	channels := make(map[string]string, len(policies))
+	for instanceName, policy := range policies {
+		snapst := snapStates[instanceName]
+		if snapst == nil {
+			continue
+		}
+		ch, err := policy.ChannelFor(snapst.TrackingChannel)
+		if err != nil {
+			logger.Noticef("cannot use tracking policy of snap %q: %v", instanceName, err)
+			continue
+		}
+		channels[instanceName] = ch
+	}
+	return channels
+}
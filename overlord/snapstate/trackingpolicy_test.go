@@ -0,0 +1,265 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) TestTrackingPolicyValidate(c *C) {
+	for _, t := range []struct {
+		policy snapstate.TrackingPolicy
+		err    string
+	}{
+		{snapstate.TrackingPolicy{Risk: "candidate"}, ""},
+		{snapstate.TrackingPolicy{Risk: "stable", Track: "2.0"}, ""},
+		{snapstate.TrackingPolicy{Risk: "beta", MinAge: 24 * time.Hour}, ""},
+		{snapstate.TrackingPolicy{}, `invalid risk level ""`},
+		{snapstate.TrackingPolicy{Risk: "foo"}, `invalid risk level "foo"`},
+		{snapstate.TrackingPolicy{Risk: "2.0/stable"}, `invalid risk level "2.0/stable"`},
+		{snapstate.TrackingPolicy{Risk: "stable", Track: "2.0/stable"}, `invalid track "2.0/stable"`},
+		{snapstate.TrackingPolicy{Risk: "stable", Track: "edge"}, `invalid track "edge"`},
+		{snapstate.TrackingPolicy{Risk: "stable", MinAge: -time.Hour}, `invalid minimum revision age -1h0m0s`},
+	} {
+		err := t.policy.Validate()
+		if t.err == "" {
+			c.Check(err, IsNil, Commentf("%+v", t.policy))
+		} else {
+			c.Check(err, ErrorMatches, t.err, Commentf("%+v", t.policy))
+		}
+	}
+}
+
+func (s *snapmgrTestSuite) TestTrackingPolicyChannelFor(c *C) {
+	for _, t := range []struct {
+		policy   snapstate.TrackingPolicy
+		tracking string
+		channel  string
+	}{
+		{snapstate.TrackingPolicy{Risk: "candidate"}, "stable", "candidate"},
+		{snapstate.TrackingPolicy{Risk: "candidate"}, "latest/stable", "latest/candidate"},
+		{snapstate.TrackingPolicy{Risk: "candidate"}, "2.0/stable/branch", "2.0/candidate"},
+		{snapstate.TrackingPolicy{Risk: "stable", Track: "3.0"}, "2.0/edge", "3.0/stable"},
+		{snapstate.TrackingPolicy{Risk: "beta", Track: "3.0"}, "", "3.0/beta"},
+	} {
+		ch, err := t.policy.ChannelFor(t.tracking)
+		c.Assert(err, IsNil)
+		c.Check(ch, Equals, t.channel, Commentf("%+v tracking %q", t.policy, t.tracking))
+	}
+}
+
+func (s *snapmgrTestSuite) TestSetRemoveTrackingPolicy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, name := range []string{"some-snap", "other-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, SnapID: name + "-id", Revision: snap.R(1)},
+			},
+			Current:  snap.R(1),
+			SnapType: "app",
+		})
+	}
+
+	policy := &snapstate.TrackingPolicy{Risk: "candidate", MinAge: time.Hour}
+	c.Assert(snapstate.SetTrackingPolicy(s.state, policy, "some-snap", "other-snap"), IsNil)
+	policies, err := snapstate.TrackingPolicies(s.state)
+	c.Assert(err, IsNil)
+	c.Check(policies, DeepEquals, map[string]*snapstate.TrackingPolicy{
+		"some-snap":  {Risk: "candidate", MinAge: time.Hour},
+		"other-snap": {Risk: "candidate", MinAge: time.Hour},
+	})
+
+	err = snapstate.SetTrackingPolicy(s.state, &snapstate.TrackingPolicy{Risk: "foo"}, "some-snap")
+	c.Check(err, ErrorMatches, `invalid risk level "foo"`)
+	err = snapstate.SetTrackingPolicy(s.state, policy, "missing-snap")
+	c.Check(err, ErrorMatches, `snap "missing-snap" is not installed`)
+
+	c.Assert(snapstate.RemoveTrackingPolicy(s.state, "some-snap", "missing-snap"), IsNil)
+	policies, err = snapstate.TrackingPolicies(s.state)
+	c.Assert(err, IsNil)
+	c.Check(policies, DeepEquals, map[string]*snapstate.TrackingPolicy{
+		"other-snap": {Risk: "candidate", MinAge: time.Hour},
+	})
+}
+
+func (s *snapmgrTestSuite) storeActionChannels() map[string]string {
+	channels := make(map[string]string)
+	for _, op := range s.fakeBackend.ops {
+		if op.op == "storesvc-snap-action:action" {
+			channels[op.action.InstanceName] = op.action.Channel
+		}
+	}
+	return channels
+}
+
+func (s *snapmgrTestSuite) mockSnapTrackingStable(c *C) {
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:         snap.R(1),
+		SnapType:        "app",
+		TrackingChannel: "latest/stable",
+	})
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyFollowsTrackingPolicy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockSnapTrackingStable(c)
+	c.Assert(snapstate.SetTrackingPolicy(s.state, &snapstate.TrackingPolicy{Risk: "candidate"}, "some-snap"), IsNil)
+
+	updates, tts, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+	c.Check(s.storeActionChannels(), DeepEquals, map[string]string{
+		"some-snap": "latest/candidate",
+	})
+
+	chg := s.state.NewChange("refresh", "refresh snaps")
+	for _, ts := range tts {
+		chg.AddAll(ts)
+	}
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+
+	// the snap was switched to the followed channel
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.TrackingChannel, Equals, "latest/candidate")
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyNamedIgnoresTrackingPolicy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockSnapTrackingStable(c)
+	c.Assert(snapstate.SetTrackingPolicy(s.state, &snapstate.TrackingPolicy{Risk: "candidate"}, "some-snap"), IsNil)
+
+	updates, _, err := snapstate.UpdateMany(context.Background(), s.state, []string{"some-snap"}, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+	c.Check(s.storeActionChannels(), DeepEquals, map[string]string{
+		"some-snap": "",
+	})
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyTrackingPolicyMinAge(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	now := time.Date(2021, 5, 10, 10, 0, 0, 0, time.UTC)
+	restore := snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.mockSnapTrackingStable(c)
+	c.Assert(snapstate.SetTrackingPolicy(s.state, &snapstate.TrackingPolicy{Risk: "candidate", MinAge: 72 * time.Hour}, "some-snap"), IsNil)
+
+	// released a day ago
+	s.fakeStore.releasedAt = map[string]time.Time{
+		"latest/candidate": now.Add(-24 * time.Hour),
+	}
+	updates, _, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+
+	// a named refresh does not wait
+	updates, _, err = snapstate.UpdateMany(context.Background(), s.state, []string{"some-snap"}, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+
+	// released long enough ago
+	now = now.Add(48 * time.Hour)
+	updates, _, err = snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyTrackingPolicyMinAgeUnknownReleaseTime(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	now := time.Date(2021, 5, 10, 10, 0, 0, 0, time.UTC)
+	restore = snapstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.mockSnapTrackingStable(c)
+	c.Assert(snapstate.SetTrackingPolicy(s.state, &snapstate.TrackingPolicy{Risk: "candidate", MinAge: 72 * time.Hour}, "some-snap"), IsNil)
+
+	// the release time is not known, the revision is considered
+	// released when it was first offered
+	updates, _, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+
+	now = now.Add(48 * time.Hour)
+	updates, _, err = snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+
+	now = now.Add(48 * time.Hour)
+	updates, _, err = snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+
+	// and this is logged only once
+	c.Check(strings.Count(logbuf.String(), `cannot tell when revision 11 of snap "some-snap" was released to "latest/candidate"`), Equals, 1)
+}
+
+func (s *snapmgrTestSuite) TestRemoveDropsTrackingPolicy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockSnapTrackingStable(c)
+	c.Assert(snapstate.SetTrackingPolicy(s.state, &snapstate.TrackingPolicy{Risk: "candidate"}, "some-snap"), IsNil)
+
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("remove", "remove a snap")
+	chg.AddAll(ts)
+	s.state.Unlock()
+	defer s.se.Stop()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+
+	policies, err := snapstate.TrackingPolicies(s.state)
+	c.Assert(err, IsNil)
+	c.Check(policies, HasLen, 0)
+}
@@ -0,0 +1,134 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// EnforcedValidationSets allows to hook getting the combination of the
+// validation sets in enforcing mode into the installation, refresh and
+// removal of snaps.
+var EnforcedValidationSets func(st *state.State) (*snapasserts.ValidationSets, error)
+
+func enforcedValidationSets(st *state.State) (*snapasserts.ValidationSets, error) {
+	if EnforcedValidationSets == nil {
+		return nil, nil
+	}
+	return EnforcedValidationSets(st)
+}
+
+// ValidationSetsConstraintError is returned when an operation on a snap
+// is not allowed by the validation sets in enforcing mode.
+type ValidationSetsConstraintError struct {
+	Snap string
+	// Op is the refused operation, one of "install", "refresh" or
+	// "remove".
+	Op string
+	// Sets are the keys of the validation sets refusing the operation.
+	Sets []string
+	// Revision is the revision the operation would have moved the
+	// snap to, if any.
+	Revision snap.Revision
+	// Required is the revision required by Sets, if any.
+	Required snap.Revision
+}
+
+func (e *ValidationSetsConstraintError) Error() string {
+	sets := strings.Join(e.Sets, ",")
+	switch {
+	case !e.Required.Unset():
+		return fmt.Sprintf("cannot %s snap %q at revision %s: validation sets %s require revision %s", e.Op, e.Snap, e.Revision, sets, e.Required)
+	case e.Op == "remove":
+		return fmt.Sprintf("cannot remove snap %q: required by validation sets %s", e.Snap, sets)
+	default:
+		return fmt.Sprintf("cannot %s snap %q: invalid according to validation sets %s", e.Op, e.Snap, sets)
+	}
+}
+
+// validationSetsRevision returns the revision the given snap must be
+// installed or refreshed at according to the enforced validation sets,
+// or an unset revision if any is fine. It errors if the snap must not be
+// installed.
+func validationSetsRevision(vsets *snapasserts.ValidationSets, op string, snapRef naming.SnapRef, requested snap.Revision) (snap.Revision, error) {
+	if vsets == nil {
+		return snap.Revision{}, nil
+	}
+	keys, err := vsets.CheckPresenceInvalid(snapRef)
+	if err != nil {
+		return snap.Revision{}, err
+	}
+	if len(keys) != 0 {
+		return snap.Revision{}, &ValidationSetsConstraintError{
+			Snap: snapRef.SnapName(),
+			Op:   op,
+			Sets: keys,
+		}
+	}
+	rev, keys, err := vsets.Revision(snapRef)
+	if err != nil {
+		return snap.Revision{}, err
+	}
+	if !rev.Unset() && !requested.Unset() && rev != requested {
+		return snap.Revision{}, &ValidationSetsConstraintError{
+			Snap:     snapRef.SnapName(),
+			Op:       op,
+			Sets:     keys,
+			Revision: requested,
+			Required: rev,
+		}
+	}
+	return rev, nil
+}
+
+// checkValidationSetsInfo checks that the given snap revision is allowed
+// by the enforced validation sets.
+func checkValidationSetsInfo(vsets *snapasserts.ValidationSets, op string, info *snap.Info) error {
+	_, err := validationSetsRevision(vsets, op, naming.NewSnapRef(info.SnapName(), info.SnapID), info.Revision)
+	return err
+}
+
+// checkRemoveValidationSets checks that the given snap is not required
+// by the enforced validation sets.
+func checkRemoveValidationSets(st *state.State, snapst *SnapState) error {
+	vsets, err := enforcedValidationSets(st)
+	if err != nil || vsets == nil {
+		return err
+	}
+	si := snapst.CurrentSideInfo()
+	keys, err := vsets.CheckPresenceRequired(naming.NewSnapRef(si.RealName, si.SnapID))
+	if err != nil {
+		return err
+	}
+	if len(keys) != 0 {
+		return &ValidationSetsConstraintError{
+			Snap: snapst.InstanceName(),
+			Op:   "remove",
+			Sets: keys,
+		}
+	}
+	return nil
+}
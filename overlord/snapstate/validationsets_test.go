@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+func (s *snapmgrTestSuite) mockEnforcedValidationSet(c *C, snaps ...interface{}) {
+	vs := assertstest.FakeAssertion(map[string]interface{}{
+		"type":         "validation-set",
+		"authority-id": "acme",
+		"series":       "16",
+		"account-id":   "acme",
+		"name":         "one",
+		"sequence":     "1",
+		"snaps":        snaps,
+	}).(*asserts.ValidationSet)
+	old := snapstate.EnforcedValidationSets
+	snapstate.EnforcedValidationSets = func(st *state.State) (*snapasserts.ValidationSets, error) {
+		sets := snapasserts.NewValidationSets()
+		c.Assert(sets.Add(vs), IsNil)
+		return sets, nil
+	}
+	s.AddCleanup(func() { snapstate.EnforcedValidationSets = old })
+}
+
+func (s *snapmgrTestSuite) TestInstallInvalidAccordingToValidationSets(c *C) {
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "somesnapidididididididididididid",
+		"presence": "invalid",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.Install(context.Background(), s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, FitsTypeOf, &snapstate.ValidationSetsConstraintError{})
+	c.Check(err, ErrorMatches, `cannot install snap "some-snap": invalid according to validation sets acme/one`)
+
+	_, _, err = snapstate.InstallMany(s.state, []string{"some-snap"}, 0)
+	c.Check(err, ErrorMatches, `cannot install snap "some-snap": invalid according to validation sets acme/one`)
+}
+
+func (s *snapmgrTestSuite) TestInstallRevisionRequiredByValidationSets(c *C) {
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "somesnapidididididididididididid",
+		"presence": "required",
+		"revision": "7",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	ts, err := snapstate.Install(context.Background(), s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Revision(), Equals, snap.R(7))
+
+	_, tss, err := snapstate.InstallMany(s.state, []string{"some-snap"}, 0)
+	c.Assert(err, IsNil)
+	c.Assert(tss, HasLen, 1)
+	snapsup, err = snapstate.TaskSnapSetup(tss[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Revision(), Equals, snap.R(7))
+
+	// other revisions are refused
+	opts := &snapstate.RevisionOptions{Revision: snap.R(8)}
+	_, err = snapstate.Install(context.Background(), s.state, "some-snap", opts, 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap "some-snap" at revision 8: validation sets acme/one require revision 7`)
+}
+
+func (s *snapmgrTestSuite) TestRemoveRequiredByValidationSets(c *C) {
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "somesnapidididididididididididid",
+		"presence": "required",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(2)},
+		},
+		Current:  snap.R(2),
+		SnapType: "app",
+	})
+
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
+	c.Check(err, ErrorMatches, `cannot remove snap "some-snap": required by validation sets acme/one`)
+	_, _, err = snapstate.RemoveMany(s.state, []string{"some-snap"})
+	c.Check(err, ErrorMatches, `cannot remove snap "some-snap": required by validation sets acme/one`)
+
+	// removing an old revision is fine
+	_, err = snapstate.Remove(s.state, "some-snap", snap.R(1), nil)
+	c.Check(err, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyConstrainedByValidationSets(c *C) {
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "somesnapidididididididididididid",
+		"presence": "required",
+		"revision": "5",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	updates, tss, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+	c.Assert(tss, HasLen, 2)
+	snapsup, err := snapstate.TaskSnapSetup(tss[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Revision(), Equals, snap.R(5))
+
+	op := s.fakeBackend.ops.First("storesvc-snap-action:action")
+	c.Assert(op, NotNil)
+	c.Check(op.action.Revision, Equals, snap.R(5))
+}
+
+func (s *snapmgrTestSuite) TestUpdateConstrainedByValidationSetsKeepsOptions(c *C) {
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "somesnapidididididididididididid",
+		"presence": "required",
+		"revision": "5",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:         snap.R(1),
+		TrackingChannel: "latest/stable",
+		SnapType:        "app",
+	})
+
+	opts := &snapstate.RevisionOptions{Channel: "latest/stable"}
+	ts, err := snapstate.Update(s.state, "some-snap", opts, 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Revision(), Equals, snap.R(5))
+	// the options of the caller are left alone
+	c.Check(opts.Revision.Unset(), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestUpdateAtRevisionRequiredByValidationSets(c *C) {
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "somesnapidididididididididididid",
+		"presence": "required",
+		"revision": "1",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:         snap.R(1),
+		TrackingChannel: "latest/stable",
+		SnapType:        "app",
+	})
+
+	// nothing to refresh
+	updates, _, err := snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+
+	_, err = snapstate.Update(s.state, "some-snap", nil, 0, snapstate.Flags{})
+	c.Check(err, Equals, store.ErrNoUpdateAvailable)
+
+	// other revisions are refused
+	_, err = snapstate.Update(s.state, "some-snap", &snapstate.RevisionOptions{Revision: snap.R(11)}, 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot refresh snap "some-snap" at revision 11: validation sets acme/one require revision 1`)
+}
@@ -0,0 +1,123 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package polkit
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// policyConfig is the root element of a polkit action policy file, as
+// described in polkit(8).
+type policyConfig struct {
+	XMLName xml.Name `xml:"policyconfig"`
+	Actions []action `xml:"action"`
+}
+
+type action struct {
+	ID       string     `xml:"id,attr"`
+	Defaults defaults   `xml:"defaults"`
+	Annotate []annotate `xml:"annotate"`
+}
+
+type defaults struct {
+	AllowAny      string `xml:"allow_any"`
+	AllowInactive string `xml:"allow_inactive"`
+	AllowActive   string `xml:"allow_active"`
+}
+
+type annotate struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ValidatePolicy checks that the polkit action policy read from r is
+// well formed and returns the IDs of the actions it declares.
+//
+// Policies using the annotations interpreted by polkit itself, in the
+// "org.freedesktop.policykit." namespace, are rejected: these let a policy
+// grant the authorizations of actions declared elsewhere, or the running of
+// programs as another user through pkexec.
+func ValidatePolicy(r io.Reader) (actionIDs []string, err error) {
+	decoder := xml.NewDecoder(r)
+	var config policyConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("cannot decode policy: %v", err)
+	}
+	// there must be nothing but white space after the root element
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode policy: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.Comment, xml.ProcInst:
+			continue
+		case xml.CharData:
+			if isSpace(tok) {
+				continue
+			}
+		}
+		return nil, errors.New("invalid policy: unexpected data after root element")
+	}
+
+	for _, a := range config.Actions {
+		if a.ID == "" {
+			return nil, errors.New("invalid policy: action element without id")
+		}
+		for _, an := range a.Annotate {
+			if strings.HasPrefix(an.Key, "org.freedesktop.policykit.") {
+				return nil, fmt.Errorf("invalid policy: action %q uses unsupported annotation %q", a.ID, an.Key)
+			}
+		}
+		for _, value := range []string{a.Defaults.AllowAny, a.Defaults.AllowInactive, a.Defaults.AllowActive} {
+			if err := validateDefaultAuth(value); err != nil {
+				return nil, fmt.Errorf("invalid policy: action %q: %v", a.ID, err)
+			}
+		}
+		actionIDs = append(actionIDs, a.ID)
+	}
+	return actionIDs, nil
+}
+
+func validateDefaultAuth(value string) error {
+	switch value {
+	case "", "no", "yes", "auth_self", "auth_self_keep", "auth_admin", "auth_admin_keep":
+		return nil
+	}
+	return fmt.Errorf("invalid default authorization %q", value)
+}
+
+func isSpace(data []byte) bool {
+	for _, b := range data {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package polkit
+
+import (
+	"strings"
+
+	"gopkg.in/check.v1"
+)
+
+type validateSuite struct{}
+
+var _ = check.Suite(&validateSuite{})
+
+func (s *validateSuite) TestValidatePolicy(c *check.C) {
+	ids, err := ValidatePolicy(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE policyconfig PUBLIC
+ "-//freedesktop//DTD PolicyKit Policy Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/PolicyKit/1.0/policyconfig.dtd">
+<policyconfig>
+  <vendor>Example</vendor>
+  <action id="com.example.foo.action-one">
+    <description>First action</description>
+    <message>Authentication is required</message>
+    <defaults>
+      <allow_any>no</allow_any>
+      <allow_inactive>no</allow_inactive>
+      <allow_active>auth_admin_keep</allow_active>
+    </defaults>
+    <annotate key="com.example.foo.note">not interpreted by polkit</annotate>
+  </action>
+  <action id="com.example.foo.action-two">
+    <defaults>
+      <allow_active>yes</allow_active>
+    </defaults>
+  </action>
+</policyconfig>
+<!-- trailing comment -->
+`))
+	c.Assert(err, check.IsNil)
+	c.Check(ids, check.DeepEquals, []string{"com.example.foo.action-one", "com.example.foo.action-two"})
+}
+
+func (s *validateSuite) TestValidatePolicyNoActions(c *check.C) {
+	ids, err := ValidatePolicy(strings.NewReader(`<policyconfig></policyconfig>`))
+	c.Assert(err, check.IsNil)
+	c.Check(ids, check.HasLen, 0)
+}
+
+func (s *validateSuite) TestValidatePolicyErrors(c *check.C) {
+	for _, t := range []struct {
+		policy string
+		err    string
+	}{{
+		policy: ``,
+		err:    `cannot decode policy: EOF`,
+	}, {
+		policy: `<policyconfig>`,
+		err:    `cannot decode policy: XML syntax error .*`,
+	}, {
+		policy: `<something-else/>`,
+		err:    `cannot decode policy: expected element type <policyconfig> but have <something-else>`,
+	}, {
+		policy: `<policyconfig></policyconfig><policyconfig></policyconfig>`,
+		err:    `invalid policy: unexpected data after root element`,
+	}, {
+		policy: `<policyconfig></policyconfig>trailing`,
+		err:    `invalid policy: unexpected data after root element`,
+	}, {
+		policy: `<policyconfig><action></action></policyconfig>`,
+		err:    `invalid policy: action element without id`,
+	}, {
+		policy: `<policyconfig><action id="foo"><annotate key="org.freedesktop.policykit.imply">org.freedesktop.login1.reboot</annotate></action></policyconfig>`,
+		err:    `invalid policy: action "foo" uses unsupported annotation "org.freedesktop.policykit.imply"`,
+	}, {
+		policy: `<policyconfig><action id="foo"><annotate key="org.freedesktop.policykit.exec.path">/bin/sh</annotate></action></policyconfig>`,
+		err:    `invalid policy: action "foo" uses unsupported annotation "org.freedesktop.policykit.exec.path"`,
+	}, {
+		policy: `<policyconfig><action id="foo"><annotate key="org.freedesktop.policykit.exec.allow_gui">true</annotate></action></policyconfig>`,
+		err:    `invalid policy: action "foo" uses unsupported annotation "org.freedesktop.policykit.exec.allow_gui"`,
+	}, {
+		policy: `<policyconfig><action id="foo"><annotate key="org.freedesktop.policykit.owner">unix-user:1000</annotate></action></policyconfig>`,
+		err:    `invalid policy: action "foo" uses unsupported annotation "org.freedesktop.policykit.owner"`,
+	}, {
+		policy: `<policyconfig><action id="foo"><defaults><allow_active>maybe</allow_active></defaults></action></policyconfig>`,
+		err:    `invalid policy: action "foo": invalid default authorization "maybe"`,
+	}} {
+		_, err := ValidatePolicy(strings.NewReader(t.policy))
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("policy: %s", t.policy))
+	}
+}
@@ -0,0 +1,78 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package powerutil
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus"
+
+	"github.com/snapcore/snapd/dbusutil"
+	"github.com/snapcore/snapd/logger"
+)
+
+const (
+	upowerBusName = "org.freedesktop.UPower"
+	// https://upower.freedesktop.org/docs/UPower.html
+	upowerObjectPath = "/org/freedesktop/UPower"
+	// https://upower.freedesktop.org/docs/Device.html, the display
+	// device is the composite battery of the system
+	upowerDisplayDevicePath = "/org/freedesktop/UPower/devices/DisplayDevice"
+)
+
+// BatteryStatus returns whether the system is running on battery power
+// as reported by UPower, along with the charge level of the battery in
+// percent if so. If the state can not be determined, returns false and an
+// error.
+func BatteryStatus() (onBattery bool, percentage float64, err error) {
+	conn, err := dbusutil.SystemBus()
+	if err != nil {
+		return false, 0, fmt.Errorf("cannot connect to system bus: %v", err)
+	}
+
+	return upowerBatteryStatus(conn)
+}
+
+func upowerBatteryStatus(conn *dbus.Conn) (onBattery bool, percentage float64, err error) {
+	upowerObj := conn.Object(upowerBusName, upowerObjectPath)
+	dbusV, err := upowerObj.GetProperty("org.freedesktop.UPower.OnBattery")
+	if err != nil {
+		return false, 0, err
+	}
+	onBattery, ok := dbusV.Value().(bool)
+	if !ok {
+		return false, 0, fmt.Errorf("upower returned invalid value for on battery verification: %s", dbusV)
+	}
+	logger.Debugf("on battery state reported by UPower: %s", dbusV)
+	if !onBattery {
+		return false, 0, nil
+	}
+
+	deviceObj := conn.Object(upowerBusName, upowerDisplayDevicePath)
+	dbusV, err = deviceObj.GetProperty("org.freedesktop.UPower.Device.Percentage")
+	if err != nil {
+		return false, 0, err
+	}
+	percentage, ok = dbusV.Value().(float64)
+	if !ok {
+		return false, 0, fmt.Errorf("upower returned invalid value for battery percentage: %s", dbusV)
+	}
+	return true, percentage, nil
+}
@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package powerutil_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/godbus/dbus"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dbusutil"
+	"github.com/snapcore/snapd/dbusutil/dbustest"
+	"github.com/snapcore/snapd/powerutil"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type batterySuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&batterySuite{})
+
+func checkAndRespondToGetProperty(c *C, msg *dbus.Message, path dbus.ObjectPath, iface, prop string, value interface{}) *dbus.Message {
+	c.Assert(msg.Type, Equals, dbus.TypeMethodCall)
+	c.Check(msg.Headers[dbus.FieldDestination], DeepEquals, dbus.MakeVariant("org.freedesktop.UPower"))
+	c.Check(msg.Headers[dbus.FieldPath], DeepEquals, dbus.MakeVariant(path))
+	c.Check(msg.Headers[dbus.FieldInterface], DeepEquals, dbus.MakeVariant("org.freedesktop.DBus.Properties"))
+	c.Check(msg.Headers[dbus.FieldMember], DeepEquals, dbus.MakeVariant("Get"))
+	c.Check(msg.Body, DeepEquals, []interface{}{iface, prop})
+
+	return &dbus.Message{
+		Type: dbus.TypeMethodReply,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldReplySerial: dbus.MakeVariant(msg.Serial()),
+			dbus.FieldSender:      dbus.MakeVariant(":1"),
+			dbus.FieldSignature:   dbus.MakeVariant(dbus.SignatureOf(dbus.Variant{})),
+		},
+		Body: []interface{}{dbus.MakeVariant(value)},
+	}
+}
+
+func (s *batterySuite) mockUPower(c *C, onBattery interface{}, percentage interface{}) {
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0:
+			return []*dbus.Message{checkAndRespondToGetProperty(c, msg, "/org/freedesktop/UPower", "org.freedesktop.UPower", "OnBattery", onBattery)}, nil
+		case 1:
+			return []*dbus.Message{checkAndRespondToGetProperty(c, msg, "/org/freedesktop/UPower/devices/DisplayDevice", "org.freedesktop.UPower.Device", "Percentage", percentage)}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+	c.Assert(err, IsNil)
+	s.AddCleanup(dbusutil.MockOnlySystemBusAvailable(conn))
+}
+
+func (s *batterySuite) TestOnBattery(c *C) {
+	s.mockUPower(c, true, 42.5)
+
+	onBattery, percentage, err := powerutil.BatteryStatus()
+	c.Assert(err, IsNil)
+	c.Check(onBattery, Equals, true)
+	c.Check(percentage, Equals, 42.5)
+}
+
+func (s *batterySuite) TestOnACPower(c *C) {
+	s.mockUPower(c, false, nil)
+
+	onBattery, percentage, err := powerutil.BatteryStatus()
+	c.Assert(err, IsNil)
+	c.Check(onBattery, Equals, false)
+	c.Check(percentage, Equals, 0.0)
+}
+
+func (s *batterySuite) TestInvalidValues(c *C) {
+	s.mockUPower(c, "yes", nil)
+
+	_, _, err := powerutil.BatteryStatus()
+	c.Check(err, ErrorMatches, `upower returned invalid value for on battery verification: "yes"`)
+}
+
+func (s *batterySuite) TestInvalidPercentage(c *C) {
+	s.mockUPower(c, true, "full")
+
+	_, _, err := powerutil.BatteryStatus()
+	c.Check(err, ErrorMatches, `upower returned invalid value for battery percentage: "full"`)
+}
+
+func (s *batterySuite) TestNoUPower(c *C) {
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		return []*dbus.Message{{
+			Type: dbus.TypeError,
+			Headers: map[dbus.HeaderField]dbus.Variant{
+				dbus.FieldReplySerial: dbus.MakeVariant(msg.Serial()),
+				dbus.FieldSender:      dbus.MakeVariant(":1"),
+				dbus.FieldErrorName:   dbus.MakeVariant("org.freedesktop.DBus.Error.ServiceUnknown"),
+			},
+		}}, nil
+	})
+	c.Assert(err, IsNil)
+	s.AddCleanup(dbusutil.MockOnlySystemBusAvailable(conn))
+
+	_, _, err = powerutil.BatteryStatus()
+	c.Check(err, ErrorMatches, "org.freedesktop.DBus.Error.ServiceUnknown")
+}
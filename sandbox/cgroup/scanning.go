@@ -20,10 +20,12 @@
 package cgroup
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"syscall"
 
 	"github.com/snapcore/snapd/snap/naming"
 )
@@ -160,3 +162,108 @@ func PidsOfSnap(snapInstanceName string) (map[string][]int, error) {
 
 	return pidsByTag, nil
 }
+
+// securityTagFromSlicePath returns the security tag of a slice named by
+// SliceForTracking.
+func securityTagFromSlicePath(path string) naming.SecurityTag {
+	leaf := filepath.Base(filepath.Clean(path))
+	if !strings.HasPrefix(leaf, "snap.") || !strings.HasSuffix(leaf, ".slice") {
+		return nil
+	}
+	name := strings.Replace(strings.TrimSuffix(leaf, ".slice"), `\x2d`, "-", -1)
+	tag, err := naming.ParseSecurityTag(name)
+	if err != nil {
+		return nil
+	}
+	return tag
+}
+
+// CgroupsOfSnap returns the association of security tags to the cgroups of
+// the given snap, as paths relative to the root of the unified hierarchy.
+// The cgroups are the slices of the tags, see SliceForTracking, and the
+// tracking cgroups outside of them.
+//
+// Only the unified hierarchy is scanned, an error is returned when it is
+// not used. Like with PidsOfSnap the result is a snapshot that may be
+// immediately stale as applications are started and stopped.
+func CgroupsOfSnap(snapInstanceName string) (map[string][]string, error) {
+	ver, err := Version()
+	if err != nil {
+		return nil, err
+	}
+	if ver != V2 {
+		return nil, fmt.Errorf("cannot find cgroups of snap %q: cgroup v2 is not in use", snapInstanceName)
+	}
+
+	root := filepath.Join(rootPath, cgroupMountPoint)
+	cgroupsByTag := make(map[string][]string)
+	walkFunc := func(path string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fileInfo.IsDir() {
+			return nil
+		}
+		parsedTag := securityTagFromSlicePath(path)
+		if parsedTag == nil {
+			parsedTag = securityTagFromCgroupPath(path)
+		}
+		if parsedTag == nil {
+			return nil
+		}
+		if parsedTag.InstanceName() == snapInstanceName {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			tag := parsedTag.String()
+			cgroupsByTag[tag] = append(cgroupsByTag[tag], rel)
+		}
+		// the cgroups nested in a slice or a tracking cgroup belong
+		// to it
+		return filepath.SkipDir
+	}
+	if err := filepath.Walk(root, walkFunc); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cgroupsByTag, nil
+}
+
+// ID returns the identifier of the cgroup with the given path, relative to
+// the root of the unified hierarchy, that is the inode number of its
+// directory.
+func ID(path string) (uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(filepath.Join(rootPath, cgroupMountPoint, path), &st); err != nil {
+		return 0, err
+	}
+	return st.Ino, nil
+}
+
+// ProcessCgroupIDs returns the identifiers of the cgroup of the given
+// process in the unified hierarchy, and of its parents.
+func ProcessCgroupIDs(pid int) ([]uint64, error) {
+	ver, err := Version()
+	if err != nil {
+		return nil, err
+	}
+	if ver != V2 {
+		return nil, fmt.Errorf("cannot find cgroups of process %d: cgroup v2 is not in use", pid)
+	}
+	path, err := ProcessPathInTrackingCgroup(pid)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint64
+	for ; path != "/" && path != "." && path != ""; path = filepath.Dir(path) {
+		id, err := ID(path)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
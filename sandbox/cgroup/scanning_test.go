@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"syscall"
 
 	. "gopkg.in/check.v1"
 
@@ -222,3 +223,74 @@ func (s *scanningSuite) TestPidsOfSnapUnrelated(c *C) {
 		}, comment)
 	}
 }
+
+func (s *scanningSuite) TestCgroupsOfSnap(c *C) {
+	restore := cgroup.MockVersion(cgroup.V2, nil)
+	defer restore()
+
+	// Not having any cgroup directories is not an error.
+	cgroups, err := cgroup.CgroupsOfSnap("pkg")
+	c.Assert(err, IsNil)
+	c.Check(cgroups, HasLen, 0)
+
+	s.writePids(c, "system.slice/snap.pkg.daemon.service", []int{1})
+	s.writePids(c, "system.slice/snap.other.daemon.service", []int{2})
+	s.writePids(c, "user.slice/user-1000.slice/user@1000.service/app.slice/snap.pkg.app.1234-1234-1234.scope", []int{3})
+	s.writePids(c, "user.slice/user-1000.slice/user@1000.service/app.slice/snap.pkg.app.1234-1234-1234.scope/nested", []int{4})
+	s.writePids(c, "user.slice/user-1000.slice/user@1000.service/app.slice/snap.pkg.hook.configure.1234-1234-1234.scope", []int{5})
+	s.writePids(c, "system.slice/foo.service", []int{6})
+	// the scopes in the slices of the snap belong to them
+	s.writePids(c, "user.slice/user-1000.slice/user@1000.service/snap.pkg.app.slice/snap.pkg.app.4321-4321-4321.scope", []int{7})
+	s.writePids(c, `snap.pkg.an\x2dapp.slice/snap.pkg.an-app.4321-4321-4321.scope`, []int{8})
+	s.writePids(c, "snap.other.app.slice/snap.other.app.4321-4321-4321.scope", []int{9})
+
+	cgroups, err = cgroup.CgroupsOfSnap("pkg")
+	c.Assert(err, IsNil)
+	c.Check(cgroups, DeepEquals, map[string][]string{
+		"snap.pkg.daemon": {"system.slice/snap.pkg.daemon.service"},
+		"snap.pkg.app": {
+			"user.slice/user-1000.slice/user@1000.service/app.slice/snap.pkg.app.1234-1234-1234.scope",
+			"user.slice/user-1000.slice/user@1000.service/snap.pkg.app.slice",
+		},
+		"snap.pkg.an-app": {`snap.pkg.an\x2dapp.slice`},
+		"snap.pkg.hook.configure": {
+			"user.slice/user-1000.slice/user@1000.service/app.slice/snap.pkg.hook.configure.1234-1234-1234.scope",
+		},
+	})
+}
+
+func (s *scanningSuite) TestProcessCgroupIDs(c *C) {
+	restore := cgroup.MockVersion(cgroup.V2, nil)
+	defer restore()
+
+	scope := "user.slice/user-1000.slice/snap.pkg.app.slice/snap.pkg.app.1234-1234-1234.scope"
+	s.writePids(c, scope, []int{42})
+	procCgroup := filepath.Join(s.rootDir, "proc/42/cgroup")
+	c.Assert(os.MkdirAll(filepath.Dir(procCgroup), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(procCgroup, []byte("0::/"+scope+"\n"), 0644), IsNil)
+
+	var expected []uint64
+	for path := scope; path != "."; path = filepath.Dir(path) {
+		fi, err := os.Stat(filepath.Join(s.rootDir, "/sys/fs/cgroup", path))
+		c.Assert(err, IsNil)
+		expected = append(expected, fi.Sys().(*syscall.Stat_t).Ino)
+	}
+
+	ids, err := cgroup.ProcessCgroupIDs(42)
+	c.Assert(err, IsNil)
+	c.Check(ids, DeepEquals, expected)
+	c.Check(ids, HasLen, 4)
+
+	restore = cgroup.MockVersion(cgroup.V1, nil)
+	defer restore()
+	_, err = cgroup.ProcessCgroupIDs(42)
+	c.Check(err, ErrorMatches, "cannot find cgroups of process 42: cgroup v2 is not in use")
+}
+
+func (s *scanningSuite) TestCgroupsOfSnapV1(c *C) {
+	restore := cgroup.MockVersion(cgroup.V1, nil)
+	defer restore()
+
+	_, err := cgroup.CgroupsOfSnap("pkg")
+	c.Assert(err, ErrorMatches, `cannot find cgroups of snap "pkg": cgroup v2 is not in use`)
+}
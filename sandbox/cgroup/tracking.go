@@ -25,6 +25,17 @@ type TrackingOptions struct {
 	// AllowSessionBus controls if CreateTransientScopeForTracking will
 	// consider using the session bus for making the request.
 	AllowSessionBus bool
+	// Slice is the slice the transient scope is created in, see
+	// SliceForTracking, instead of the default one of systemd.
+	Slice string
+}
+
+// SliceForTracking returns the name of the slice grouping the transient
+// scopes of the given security tag, for the processes of the tag to have a
+// common parent cgroup. Dashes delimit the parents of slices, the ones of
+// the snap and app names are escaped.
+func SliceForTracking(securityTag string) string {
+	return strings.Replace(securityTag, "-", `\x2d`, -1) + ".slice"
 }
 
 // CreateTransientScopeForTracking puts the current process in a transient scope.
@@ -90,7 +101,7 @@ func CreateTransientScopeForTracking(securityTag string, opts *TrackingOptions)
 	pid := osGetpid()
 tryAgain:
 	// Create a transient scope by talking to systemd over DBus.
-	if err := doCreateTransientScope(conn, unitName, pid); err != nil {
+	if err := doCreateTransientScope(conn, unitName, opts.Slice, pid); err != nil {
 		switch err {
 		case errDBusUnknownMethod:
 			return ErrCannotTrackProcess
@@ -218,7 +229,7 @@ var (
 // The scope is created by asking systemd via the specified DBus connection.
 // The unit name and the PID to attach are provided as well. The DBus method
 // call is performed outside confinement established by snap-confine.
-var doCreateTransientScope = func(conn *dbus.Conn, unitName string, pid int) error {
+var doCreateTransientScope = func(conn *dbus.Conn, unitName, slice string, pid int) error {
 	// Documentation of StartTransientUnit is available at
 	// https://www.freedesktop.org/wiki/Software/systemd/dbus/
 	//
@@ -274,6 +285,9 @@ var doCreateTransientScope = func(conn *dbus.Conn, unitName string, pid int) err
 	// Here we choose "fail" to match systemd-run.
 	mode := "fail"
 	properties := []property{{"PIDs", []uint{uint(pid)}}}
+	if slice != "" {
+		properties = append(properties, property{"Slice", slice})
+	}
 	aux := []auxUnit(nil)
 	systemd := conn.Object("org.freedesktop.systemd1", "/org/freedesktop/systemd1")
 	call := systemd.Call(
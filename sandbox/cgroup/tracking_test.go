@@ -108,6 +108,34 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingFeatureEnabled(c *C)
 	c.Check(err, IsNil)
 }
 
+func (s *trackingSuite) TestCreateTransientScopeForTrackingSlice(c *C) {
+	restore := dbusutil.MockConnections(dbustest.StubConnection, dbustest.StubConnection)
+	defer restore()
+	restore = cgroup.MockOsGetuid(12345)
+	defer restore()
+	restore = cgroup.MockOsGetpid(312123)
+	defer restore()
+	uuid := "cc98cd01-6a25-46bd-b71b-82069b71b770"
+	restore = cgroup.MockRandomUUID(uuid)
+	defer restore()
+
+	var slices []string
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
+		slices = append(slices, slice)
+		return nil
+	})
+	defer restore()
+	restore = cgroup.MockCgroupProcessPathInTrackingCgroup(func(pid int) (string, error) {
+		return "/user.slice/user-12345.slice/user@12345.service/snap.pkg.app.slice/snap.pkg.app." + uuid + ".scope", nil
+	})
+	defer restore()
+
+	opts := &cgroup.TrackingOptions{AllowSessionBus: true, Slice: "snap.pkg.app.slice"}
+	c.Assert(cgroup.CreateTransientScopeForTracking("snap.pkg.app", opts), IsNil)
+	c.Assert(cgroup.CreateTransientScopeForTracking("snap.pkg.app", nil), IsNil)
+	c.Check(slices, DeepEquals, []string{"snap.pkg.app.slice", ""})
+}
+
 func (s *trackingSuite) TestCreateTransientScopeForTrackingUnhappyNotRootGeneric(c *C) {
 	// Pretend that refresh app awareness is enabled
 	enableFeatures(c, features.RefreshAppAwareness)
@@ -131,7 +159,7 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingUnhappyNotRootGeneric
 	defer restore()
 
 	// Pretend that attempting to create a transient scope fails with a canned error.
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		return fmt.Errorf("cannot create transient scope for testing")
 	})
 	defer restore()
@@ -142,7 +170,7 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingUnhappyNotRootGeneric
 
 	// Calling StartTransientUnit fails with org.freedesktop.DBus.UnknownMethod error.
 	// This is possible on old systemd or on deputy systemd.
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		return cgroup.ErrDBusUnknownMethod
 	})
 	defer restore()
@@ -155,7 +183,7 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingUnhappyNotRootGeneric
 	// Calling StartTransientUnit fails with org.freedesktop.DBus.Spawn.ChildExited error.
 	// This is possible where we try to activate socket activate session bus
 	// but it's not available OR when we try to socket activate systemd --user.
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		return cgroup.ErrDBusSpawnChildExited
 	})
 	defer restore()
@@ -191,7 +219,7 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingUnhappyRootFallback(c
 	// Calling StartTransientUnit fails on the session and then works on the system bus.
 	// This test emulates a root user falling back from the session bus to the system bus.
 	n := 0
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		n++
 		switch n {
 		case 1:
@@ -243,7 +271,7 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingUnhappyRootFailedFall
 	defer restore()
 
 	// Calling StartTransientUnit fails so that we try to use the system bus as fallback.
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		return cgroup.ErrDBusSpawnChildExited
 	})
 	defer restore()
@@ -284,7 +312,7 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingUnhappyNoDBus(c *C) {
 	defer restore()
 
 	// Calling StartTransientUnit is not attempted without a DBus connection.
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		c.Error("test sequence violated")
 		return fmt.Errorf("test was not expected to create a transient scope")
 	})
@@ -329,7 +357,7 @@ func (s *trackingSuite) TestCreateTransientScopeForTrackingSilentlyFails(c *C) {
 	// version is < 238 and when the calling user is in a hierarchy that is
 	// owned by another user. One example is a user logging in remotely over
 	// ssh.
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		return nil
 	})
 	defer restore()
@@ -376,7 +404,7 @@ func (s *trackingSuite) TestCreateTransientScopeForRootOnSystemBus(c *C) {
 	// Pretend that attempting to create a transient scope succeeds.  Measure
 	// the bus used and the unit name provided by the caller.  Note that the
 	// call was made on the system bus, as requested by TrackingOptions below.
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		c.Assert(conn, Equals, systemBus)
 		c.Assert(unitName, Equals, "snap.pkg.app."+uuid+".scope")
 		return nil
@@ -419,7 +447,7 @@ func (s *trackingSuite) TestCreateTransientScopeHappyWithRetriedCheck(c *C) {
 	c.Assert(err, IsNil)
 	restore = dbusutil.MockOnlySessionBusAvailable(sessionBus)
 	defer restore()
-	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName string, pid int) error {
+	restore = cgroup.MockDoCreateTransientScope(func(conn *dbus.Conn, unitName, slice string, pid int) error {
 		c.Assert(conn, Equals, sessionBus)
 		c.Assert(unitName, Equals, "snap.pkg.app."+uuid+".scope")
 		return nil
@@ -524,10 +552,48 @@ func (s *trackingSuite) TestDoCreateTransientScopeHappy(c *C) {
 
 	c.Assert(err, IsNil)
 	defer conn.Close()
-	err = cgroup.DoCreateTransientScope(conn, "foo.scope", 312123)
+	err = cgroup.DoCreateTransientScope(conn, "foo.scope", "", 312123)
+	c.Assert(err, IsNil)
+}
+
+func (s *trackingSuite) TestDoCreateTransientScopeInSlice(c *C) {
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0:
+			c.Check(msg.Body, DeepEquals, []interface{}{
+				"foo.scope",
+				"fail",
+				[][]interface{}{
+					{"PIDs", dbus.MakeVariant([]uint32{312123})},
+					{"Slice", dbus.MakeVariant("snap.pkg.app.slice")},
+				},
+				[][]interface{}{},
+			})
+			return []*dbus.Message{{
+				Type: dbus.TypeMethodReply,
+				Headers: map[dbus.HeaderField]dbus.Variant{
+					dbus.FieldReplySerial: dbus.MakeVariant(msg.Serial()),
+					dbus.FieldSender:      dbus.MakeVariant(":1"),
+					dbus.FieldSignature:   dbus.MakeVariant(dbus.SignatureOf(dbus.ObjectPath(""))),
+				},
+				Body: []interface{}{dbus.ObjectPath("/org/freedesktop/systemd1/job/1462")},
+			}}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	err = cgroup.DoCreateTransientScope(conn, "foo.scope", "snap.pkg.app.slice", 312123)
 	c.Assert(err, IsNil)
 }
 
+func (s *trackingSuite) TestSliceForTracking(c *C) {
+	c.Check(cgroup.SliceForTracking("snap.pkg.app"), Equals, "snap.pkg.app.slice")
+	c.Check(cgroup.SliceForTracking("snap.pkg-foo_bar.an-app"), Equals, `snap.pkg\x2dfoo_bar.an\x2dapp.slice`)
+	c.Check(cgroup.SliceForTracking("snap.pkg.hook.configure"), Equals, "snap.pkg.hook.configure.slice")
+}
+
 func (s *trackingSuite) TestDoCreateTransientScopeForwardedErrors(c *C) {
 	// Certain errors are forwarded and handled in the logic calling into
 	// DoCreateTransientScope. Those are tested here.
@@ -547,7 +613,7 @@ func (s *trackingSuite) TestDoCreateTransientScopeForwardedErrors(c *C) {
 		})
 		c.Assert(err, IsNil)
 		defer conn.Close()
-		err = cgroup.DoCreateTransientScope(conn, "foo.scope", 312123)
+		err = cgroup.DoCreateTransientScope(conn, "foo.scope", "", 312123)
 		c.Assert(strings.HasSuffix(err.Error(), fmt.Sprintf(" [%s]", t.dbusError)), Equals, true, Commentf("%q ~ %s", err, t.dbusError))
 		c.Check(err, ErrorMatches, t.msg+" .*")
 	}
@@ -566,7 +632,7 @@ func (s *trackingSuite) TestDoCreateTransientScopeClashingScopeName(c *C) {
 	})
 	c.Assert(err, IsNil)
 	defer conn.Close()
-	err = cgroup.DoCreateTransientScope(conn, "foo.scope", 312123)
+	err = cgroup.DoCreateTransientScope(conn, "foo.scope", "", 312123)
 	c.Assert(err, ErrorMatches, "cannot create transient scope: scope .* clashed: .*")
 }
 
@@ -582,7 +648,7 @@ func (s *trackingSuite) TestDoCreateTransientScopeOtherDBusErrors(c *C) {
 	})
 	c.Assert(err, IsNil)
 	defer conn.Close()
-	err = cgroup.DoCreateTransientScope(conn, "foo.scope", 312123)
+	err = cgroup.DoCreateTransientScope(conn, "foo.scope", "", 312123)
 	c.Assert(err, ErrorMatches, `cannot create transient scope: DBus error "org.example.BadHairDay": \[\]`)
 }
 
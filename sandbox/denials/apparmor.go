@@ -0,0 +1,185 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// appArmorVariables are regular expressions for the apparmor variables
+// used by the snippets of the interfaces, other variables match any path
+// component.
+var appArmorVariables = map[string]string{
+	"HOME":        `(?:/home/[^/]+|/root)`,
+	"HOMEDIRS":    `/home`,
+	"PROC":        `/proc`,
+	"pid":         `[0-9]+`,
+	"pids":        `[0-9]+`,
+	"tid":         `[0-9]+`,
+	"run":         `(?:/run|/var/run)`,
+	"INSTALL_DIR": `(?:/snap|/var/lib/snapd/snap)`,
+}
+
+// appArmorGlobRegexp returns a regular expression matching the paths
+// matched by an apparmor glob, with the given values for the variables.
+func appArmorGlobRegexp(glob string, variables map[string]string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteRune('^')
+	depth := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '@' && strings.HasPrefix(glob[i:], "@{"):
+			end := strings.IndexRune(glob[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated variable in %q", glob)
+			}
+			name := glob[i+2 : i+end]
+			value, ok := variables[name]
+			if !ok {
+				value, ok = appArmorVariables[name]
+			}
+			if !ok {
+				value = `[^/]*`
+			}
+			b.WriteString(value)
+			i += end
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(`.*`)
+			i++
+		case c == '*':
+			b.WriteString(`[^/]*`)
+		case c == '?':
+			b.WriteString(`[^/]`)
+		case c == '{':
+			depth++
+			b.WriteString(`(?:`)
+		case c == ',' && depth > 0:
+			b.WriteRune('|')
+		case c == '}' && depth > 0:
+			depth--
+			b.WriteRune(')')
+		case c == '[':
+			end := strings.IndexRune(glob[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in %q", glob)
+			}
+			b.WriteString(glob[i : i+end+1])
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced alternation in %q", glob)
+	}
+	b.WriteRune('$')
+	return regexp.Compile(b.String())
+}
+
+// AppArmorGlobMatches returns whether the given apparmor glob matches the
+// given path.
+func AppArmorGlobMatches(glob, path string) (bool, error) {
+	re, err := appArmorGlobRegexp(glob, nil)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(path), nil
+}
+
+// appArmorPermsAllow returns whether the permissions of a file rule grant
+// all of the requested mask of a denial.
+func appArmorPermsAllow(perms, requested string) bool {
+	if requested == "" {
+		return false
+	}
+	for _, r := range requested {
+		var granted bool
+		switch r {
+		case 'r', 'm', 'k', 'l', 'x':
+			granted = strings.ContainsRune(perms, r)
+		case 'w', 'c', 'd':
+			// creating and deleting files need write
+			granted = strings.ContainsRune(perms, 'w')
+		case 'a':
+			granted = strings.ContainsAny(perms, "aw")
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
+
+func isAppArmorPath(s string) bool {
+	return strings.HasPrefix(s, "/") || strings.HasPrefix(s, "@{")
+}
+
+// AppArmorAllows returns whether the file rules of the given apparmor
+// snippet allow the file access of the given denial. Other rules, and
+// paths with spaces, are not considered.
+func AppArmorAllows(snippet string, d *Denial) bool {
+	if d.Sandbox != "apparmor" || !strings.HasPrefix(d.Name, "/") {
+		return false
+	}
+	variables := map[string]string{
+		"SNAP_INSTANCE_NAME": regexp.QuoteMeta(d.Snap),
+		"SNAP_NAME":          regexp.QuoteMeta(strings.SplitN(d.Snap, "_", 2)[0]),
+	}
+	for _, line := range strings.Split(snippet, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(line), ","))
+		for len(fields) > 0 && (fields[0] == "audit" || fields[0] == "allow" || fields[0] == "owner" || fields[0] == "file") {
+			fields = fields[1:]
+		}
+		if len(fields) < 2 || fields[0] == "deny" {
+			continue
+		}
+		path, perms := fields[0], fields[1]
+		if !isAppArmorPath(path) {
+			// permissions may come first
+			path, perms = perms, path
+			if !isAppArmorPath(path) {
+				continue
+			}
+		}
+		if !appArmorPermsAllow(perms, d.RequestedMask) {
+			continue
+		}
+		re, err := appArmorGlobRegexp(path, variables)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(d.Name) {
+			return true
+		}
+	}
+	return false
+}
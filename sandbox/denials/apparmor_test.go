@@ -0,0 +1,117 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
+type appArmorSuite struct{}
+
+var _ = Suite(&appArmorSuite{})
+
+func (s *appArmorSuite) TestGlobRegexp(c *C) {
+	for _, t := range []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{"/etc/shadow", "/etc/shadow", true},
+		{"/etc/shadow", "/etc/shadowx", false},
+		{"/dev/tty*", "/dev/ttyUSB0", true},
+		{"/dev/tty*", "/dev/tty/x", false},
+		{"/sys/**", "/sys/class/x/y", true},
+		{"/dev/video[0-9]", "/dev/video3", true},
+		{"/dev/video[0-9]", "/dev/videoX", false},
+		{"/media/{,**}", "/media/", true},
+		{"/media/{,**}", "/media/usb/file", true},
+		{"/{,usr/}bin/ls", "/usr/bin/ls", true},
+		{"/{,usr/}bin/ls", "/bin/ls", true},
+		{"@{HOME}/.config/foo", "/home/user/.config/foo", true},
+		{"@{HOME}/.config/foo", "/root/.config/foo", true},
+		{"@{PROC}/@{pid}/status", "/proc/12/status", true},
+		{"@{PROC}/@{pid}/status", "/proc/self/status", false},
+		{"/var/snap/@{SNAP_INSTANCE_NAME}/**", "/var/snap/foo/1/x", true},
+		{"/var/snap/@{SNAP_INSTANCE_NAME}/**", "/var/snap/bar/1/x", false},
+		{"/run/@{UNKNOWN}/x", "/run/any/x", true},
+		{`/a\*b`, "/a*b", true},
+		{`/a\*b`, "/axb", false},
+	} {
+		re, err := denials.AppArmorGlobRegexp(t.glob, map[string]string{"SNAP_INSTANCE_NAME": "foo"})
+		c.Assert(err, IsNil, Commentf(t.glob))
+		c.Check(re.MatchString(t.path), Equals, t.matches, Commentf("%s %s", t.glob, t.path))
+	}
+
+	for _, glob := range []string{"/a{b", "/a[b", "/@{HOME"} {
+		_, err := denials.AppArmorGlobRegexp(glob, nil)
+		c.Check(err, NotNil, Commentf(glob))
+	}
+}
+
+func (s *appArmorSuite) TestGlobMatches(c *C) {
+	matches, err := denials.AppArmorGlobMatches("/home/alice/Documents/**", "/home/alice/Documents/a/b.txt")
+	c.Assert(err, IsNil)
+	c.Check(matches, Equals, true)
+
+	matches, err = denials.AppArmorGlobMatches("/home/alice/*.txt", "/home/alice/Documents/b.txt")
+	c.Assert(err, IsNil)
+	c.Check(matches, Equals, false)
+
+	_, err = denials.AppArmorGlobMatches("/home/alice/{a,b", "/home/alice/a")
+	c.Assert(err, ErrorMatches, `unbalanced alternation in "/home/alice/{a,b"`)
+}
+
+func (s *appArmorSuite) TestAppArmorAllows(c *C) {
+	snippet := `
+# Description: a test snippet
+/etc/foo r,
+owner @{HOME}/.config/bar rw, # trailing comment
+/usr/bin/baz ix,
+rw /srv/qux,
+deny /etc/shadow r,
+audit /dev/tty* rwk,
+network inet,
+dbus (send) bus=system,
+`
+	for _, t := range []struct {
+		name      string
+		requested string
+		allowed   bool
+	}{
+		{"/etc/foo", "r", true},
+		{"/etc/foo", "w", false},
+		{"/home/user/.config/bar", "wc", true},
+		{"/home/user/.config/bar", "a", true},
+		{"/usr/bin/baz", "x", true},
+		{"/usr/bin/baz", "r", false},
+		{"/srv/qux", "r", true},
+		{"/etc/shadow", "r", false},
+		{"/dev/ttyS0", "k", true},
+		{"/dev/ttyS0", "", false},
+		{"/other", "r", false},
+	} {
+		d := &denials.Denial{Sandbox: "apparmor", Snap: "foo", Name: t.name, RequestedMask: t.requested}
+		c.Check(denials.AppArmorAllows(snippet, d), Equals, t.allowed, Commentf("%s %s", t.name, t.requested))
+	}
+
+	c.Check(denials.AppArmorAllows(snippet, &denials.Denial{Sandbox: "seccomp", Snap: "foo", Syscall: "1"}), Equals, false)
+}
@@ -0,0 +1,200 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package denials parses the denials of the sandbox of snaps logged by the
+// kernel.
+package denials
+
+import (
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// Denial is a denial of the sandbox of a snap, as logged by the kernel.
+type Denial struct {
+	// Time is when the denial was last logged.
+	Time time.Time `json:"time"`
+	// Count is how many times the denial was logged.
+	Count int `json:"count"`
+
+	// Sandbox is "apparmor" or "seccomp".
+	Sandbox string `json:"sandbox"`
+	Snap    string `json:"snap"`
+	// Tag is the security tag of the denied app or hook, it is unset for
+	// seccomp denials of processes without an apparmor label.
+	Tag  string `json:"tag,omitempty"`
+	Comm string `json:"comm,omitempty"`
+
+	// Operation, Name and the masks describe apparmor denials.
+	Operation     string `json:"operation,omitempty"`
+	Name          string `json:"name,omitempty"`
+	RequestedMask string `json:"requested-mask,omitempty"`
+	DeniedMask    string `json:"denied-mask,omitempty"`
+	// Fsuid is the filesystem user id of the process denied by apparmor.
+	Fsuid string `json:"fsuid,omitempty"`
+
+	// Syscall is the number of the syscall denied by seccomp on the
+	// given architecture, as an audit architecture.
+	Syscall string `json:"syscall,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+}
+
+// SameAs returns whether the two denials are about the same access.
+func (d *Denial) SameAs(other *Denial) bool {
+	return d.Sandbox == other.Sandbox &&
+		d.Snap == other.Snap &&
+		d.Tag == other.Tag &&
+		d.Operation == other.Operation &&
+		d.Name == other.Name &&
+		d.RequestedMask == other.RequestedMask &&
+		d.DeniedMask == other.DeniedMask &&
+		d.Fsuid == other.Fsuid &&
+		d.Syscall == other.Syscall &&
+		d.Arch == other.Arch
+}
+
+// auditFields returns the key=value fields of an audit message.
+func auditFields(message string) map[string]string {
+	fields := make(map[string]string)
+	for len(message) > 0 {
+		message = strings.TrimLeft(message, " ")
+		eq := strings.IndexAny(message, "= ")
+		if eq < 0 {
+			break
+		}
+		if message[eq] == ' ' {
+			// not a key=value field
+			message = message[eq:]
+			continue
+		}
+		key := message[:eq]
+		message = message[eq+1:]
+		var value string
+		if strings.HasPrefix(message, `"`) {
+			end := strings.IndexRune(message[1:], '"')
+			if end < 0 {
+				break
+			}
+			value = message[1 : end+1]
+			message = message[end+2:]
+		} else {
+			end := strings.IndexRune(message, ' ')
+			if end < 0 {
+				end = len(message)
+			}
+			value = message[:end]
+			message = message[end:]
+			// untrusted strings with special characters are
+			// hex encoded
+			if key == "name" || key == "comm" || key == "profile" {
+				if decoded, err := hex.DecodeString(value); err == nil {
+					value = string(decoded)
+				}
+			}
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// snapOfLabel returns the snap and the security tag of an apparmor label.
+func snapOfLabel(label string) (snapName, tag string, ok bool) {
+	// remove any child profile
+	if i := strings.Index(label, "//"); i >= 0 {
+		label = label[:i]
+	}
+	if strings.HasPrefix(label, "snap-update-ns.") {
+		snapName = strings.TrimPrefix(label, "snap-update-ns.")
+		if naming.ValidateInstance(snapName) != nil {
+			return "", "", false
+		}
+		return snapName, label, true
+	}
+	securityTag, err := naming.ParseSecurityTag(label)
+	if err != nil {
+		return "", "", false
+	}
+	return securityTag.InstanceName(), label, true
+}
+
+// snapOfExe returns the snap of an executable from its path in the
+// mounted snap.
+func snapOfExe(exe string) (snapName string, ok bool) {
+	parts := strings.Split(exe, "/")
+	// /snap/<name>/<revision>/...
+	if len(parts) < 4 || parts[0] != "" || parts[1] != "snap" {
+		return "", false
+	}
+	if naming.ValidateInstance(parts[2]) != nil {
+		return "", false
+	}
+	return parts[2], true
+}
+
+// Parse parses a kernel or audit message logged at the given time and
+// returns the denial of the sandbox of a snap it describes, or nil if it
+// does not describe one.
+func Parse(message string, t time.Time) *Denial {
+	var d *Denial
+	switch {
+	case strings.Contains(message, `apparmor="DENIED"`):
+		fields := auditFields(message)
+		snapName, tag, ok := snapOfLabel(fields["profile"])
+		if !ok {
+			return nil
+		}
+		d = &Denial{
+			Sandbox:       "apparmor",
+			Snap:          snapName,
+			Tag:           tag,
+			Comm:          fields["comm"],
+			Operation:     fields["operation"],
+			Name:          fields["name"],
+			RequestedMask: fields["requested_mask"],
+			DeniedMask:    fields["denied_mask"],
+			Fsuid:         fields["fsuid"],
+		}
+	case strings.Contains(message, "type=1326") || strings.HasPrefix(message, "SECCOMP "):
+		fields := auditFields(message)
+		snapName, tag, ok := snapOfLabel(fields["subj"])
+		if !ok {
+			snapName, ok = snapOfExe(fields["exe"])
+			if !ok {
+				return nil
+			}
+			tag = ""
+		}
+		d = &Denial{
+			Sandbox: "seccomp",
+			Snap:    snapName,
+			Tag:     tag,
+			Comm:    fields["comm"],
+			Syscall: fields["syscall"],
+			Arch:    fields["arch"],
+		}
+	default:
+		return nil
+	}
+	d.Time = t
+	d.Count = 1
+	return d
+}
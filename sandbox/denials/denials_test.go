@@ -0,0 +1,112 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials_test
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/denials"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type denialsSuite struct{}
+
+var _ = Suite(&denialsSuite{})
+
+var now = time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func (s *denialsSuite) TestAuditFields(c *C) {
+	c.Check(denials.AuditFields(`audit: type=1400 audit(1614592800.123:45): apparmor="DENIED" name="/with space" subj=snap.foo.app (enforce) comm=2F746D70`), DeepEquals, map[string]string{
+		"type":     "1400",
+		"apparmor": "DENIED",
+		"name":     "/with space",
+		"subj":     "snap.foo.app",
+		"comm":     "/tmp",
+	})
+	c.Check(denials.AuditFields(`unterminated="value`), DeepEquals, map[string]string{})
+}
+
+func (s *denialsSuite) TestParseAppArmor(c *C) {
+	d := denials.Parse(`audit: type=1400 audit(1614592800.123:45): apparmor="DENIED" operation="open" profile="snap.foo_bar.app" name="/etc/shadow" pid=1234 comm="app" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0`, now)
+	c.Check(d, DeepEquals, &denials.Denial{
+		Time:          now,
+		Count:         1,
+		Sandbox:       "apparmor",
+		Snap:          "foo_bar",
+		Tag:           "snap.foo_bar.app",
+		Comm:          "app",
+		Operation:     "open",
+		Name:          "/etc/shadow",
+		RequestedMask: "r",
+		DeniedMask:    "r",
+		Fsuid:         "1000",
+	})
+
+	d = denials.Parse(`apparmor="DENIED" operation="mount" profile="snap-update-ns.foo" name="/usr/share/" pid=1 comm="5" flags="rw, bind"`, now)
+	c.Assert(d, NotNil)
+	c.Check(d.Snap, Equals, "foo")
+	c.Check(d.Tag, Equals, "snap-update-ns.foo")
+
+	d = denials.Parse(`apparmor="DENIED" operation="open" profile="snap.foo.hook.configure//null-/usr/bin/x" name="/x"`, now)
+	c.Assert(d, NotNil)
+	c.Check(d.Tag, Equals, "snap.foo.hook.configure")
+
+	// not snaps
+	c.Check(denials.Parse(`apparmor="DENIED" operation="open" profile="/usr/sbin/cupsd" name="/x"`, now), IsNil)
+	c.Check(denials.Parse(`apparmor="ALLOWED" operation="open" profile="snap.foo.app" name="/x"`, now), IsNil)
+	c.Check(denials.Parse(`usb 1-1: new high-speed USB device number 2`, now), IsNil)
+}
+
+func (s *denialsSuite) TestParseSeccomp(c *C) {
+	d := denials.Parse(`audit: type=1326 audit(1614592800.123:46): auid=1000 uid=1000 gid=1000 ses=2 subj=snap.foo.app (enforce) pid=1234 comm="app" exe="/snap/foo/1/bin/app" sig=0 arch=c000003e syscall=165 compat=0 ip=0x7f code=0x50000`, now)
+	c.Check(d, DeepEquals, &denials.Denial{
+		Time:    now,
+		Count:   1,
+		Sandbox: "seccomp",
+		Snap:    "foo",
+		Tag:     "snap.foo.app",
+		Comm:    "app",
+		Syscall: "165",
+		Arch:    "c000003e",
+	})
+
+	// from the audit transport, without apparmor
+	d = denials.Parse(`SECCOMP auid=1000 uid=1000 ses=2 subj=? pid=1234 comm="app" exe="/snap/foo/1/bin/app" sig=0 arch=c000003e syscall=165`, now)
+	c.Assert(d, NotNil)
+	c.Check(d.Snap, Equals, "foo")
+	c.Check(d.Tag, Equals, "")
+
+	c.Check(denials.Parse(`audit: type=1326 subj=unconfined exe="/usr/bin/foo" syscall=165`, now), IsNil)
+}
+
+func (s *denialsSuite) TestSameAs(c *C) {
+	d1 := denials.Parse(`apparmor="DENIED" operation="open" profile="snap.foo.app" name="/x" comm="a" requested_mask="r" denied_mask="r"`, now)
+	d2 := denials.Parse(`apparmor="DENIED" operation="open" profile="snap.foo.app" name="/x" comm="b" requested_mask="r" denied_mask="r"`, now.Add(time.Hour))
+	d3 := denials.Parse(`apparmor="DENIED" operation="open" profile="snap.foo.app" name="/y" comm="a" requested_mask="r" denied_mask="r"`, now)
+	d4 := denials.Parse(`apparmor="DENIED" operation="open" profile="snap.foo.app" name="/x" comm="a" requested_mask="r" denied_mask="r" fsuid=1000`, now)
+	c.Check(d1.SameAs(d2), Equals, true)
+	c.Check(d1.SameAs(d3), Equals, false)
+	// the denials of different users are told apart
+	c.Check(d1.SameAs(d4), Equals, false)
+}
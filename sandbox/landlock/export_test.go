@@ -0,0 +1,50 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock
+
+var HandledAccess = handledAccess
+
+func MockLandlockSyscall(f func(trap, a1, a2, a3 uintptr) (uintptr, error)) (restore func()) {
+	old := landlockSyscall
+	landlockSyscall = f
+	return func() {
+		landlockSyscall = old
+	}
+}
+
+func FreshABIProbe() {
+	abiProber = &abiProbe{}
+}
+
+func MockRuleset(create func(handled Access) (int, error), add func(rulesetFd, pathFd int, access Access) error, restrict func(rulesetFd int) error) (restore func()) {
+	oldCreate, oldAdd, oldRestrict := createRuleset, addPathBeneathRule, restrictSelf
+	createRuleset, addPathBeneathRule, restrictSelf = create, add, restrict
+	return func() {
+		createRuleset, addPathBeneathRule, restrictSelf = oldCreate, oldAdd, oldRestrict
+	}
+}
+
+func MockOpenPath(f func(path string) (int, error)) (restore func()) {
+	old := openPath
+	openPath = f
+	return func() {
+		openPath = old
+	}
+}
@@ -0,0 +1,355 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package landlock supports restricting the filesystem accesses of snap
+// processes with the Landlock security module of the kernel.
+package landlock
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Access is a set of filesystem access rights handled by Landlock.
+type Access uint64
+
+const (
+	AccessExecute Access = 1 << iota
+	AccessWriteFile
+	AccessReadFile
+	AccessReadDir
+	AccessRemoveDir
+	AccessRemoveFile
+	AccessMakeChar
+	AccessMakeDir
+	AccessMakeReg
+	AccessMakeSock
+	AccessMakeFifo
+	AccessMakeBlock
+	AccessMakeSym
+	// AccessRefer is handled since ABI 2.
+	AccessRefer
+	// AccessTruncate is handled since ABI 3.
+	AccessTruncate
+)
+
+const (
+	// Read allows reading files and listing directories.
+	Read = AccessReadFile | AccessReadDir
+	// Write allows writing, truncating, creating, moving and removing
+	// files and directories.
+	Write = AccessWriteFile | AccessRemoveDir | AccessRemoveFile | AccessMakeChar |
+		AccessMakeDir | AccessMakeReg | AccessMakeSock | AccessMakeFifo |
+		AccessMakeBlock | AccessMakeSym | AccessRefer | AccessTruncate
+	// Execute allows executing files.
+	Execute = AccessExecute
+
+	// fileAccess are the access rights that apply to files, the other
+	// ones only apply beneath directories.
+	fileAccess = AccessExecute | AccessWriteFile | AccessReadFile | AccessTruncate
+)
+
+// handledAccess returns the access rights handled by the given ABI
+// version of Landlock.
+func handledAccess(abi int) Access {
+	switch {
+	case abi >= 3:
+		return AccessTruncate<<1 - 1
+	case abi == 2:
+		return AccessRefer<<1 - 1
+	case abi == 1:
+		return AccessMakeSym<<1 - 1
+	}
+	return 0
+}
+
+// Rule grants access to a file, or to everything beneath a directory.
+type Rule struct {
+	Access Access
+	// Path is absolute, or starts with an environment variable that
+	// is expanded when the rule is applied. Its last component can be a
+	// glob matching entries of the directory, see ExpandGlobs.
+	Path string
+}
+
+// String returns the rule as a line of a profile, the letters r, w and x
+// for Read, Write and Execute, or - for no access, followed by the path.
+func (r Rule) String() string {
+	var perms strings.Builder
+	if r.Access&Read != 0 {
+		perms.WriteRune('r')
+	}
+	if r.Access&Write != 0 {
+		perms.WriteRune('w')
+	}
+	if r.Access&Execute != 0 {
+		perms.WriteRune('x')
+	}
+	if perms.Len() == 0 {
+		perms.WriteRune('-')
+	}
+	return fmt.Sprintf("%s %s", perms.String(), r.Path)
+}
+
+// isGlob returns whether the last component of the path is a glob.
+func isGlob(path string) bool {
+	return strings.ContainsAny(filepath.Base(path), "*?[")
+}
+
+// ExpandGlobs replaces the rules whose last path component is a glob by
+// rules granting the same access to the matching entries of the directory.
+// Entries with a rule of their own are left to that rule, which can grant
+// less than the glob.
+func ExpandGlobs(rules []Rule) ([]Rule, error) {
+	own := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if !isGlob(rule.Path) {
+			own[filepath.Clean(rule.Path)] = true
+		}
+	}
+	expanded := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if !isGlob(rule.Path) {
+			expanded = append(expanded, rule)
+			continue
+		}
+		dir, pattern := filepath.Split(rule.Path)
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("cannot expand %q: %v", rule.Path, err)
+		}
+		names, err := readDirNames(dir)
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot expand %q: %v", rule.Path, err)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if matched, _ := filepath.Match(pattern, name); !matched || own[path] {
+				continue
+			}
+			expanded = append(expanded, Rule{Access: rule.Access, Path: path})
+		}
+	}
+	return expanded, nil
+}
+
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+// ParseProfile parses the rules of a profile, one rule per line as
+// formatted by Rule.String. Empty lines and comments are ignored.
+func ParseProfile(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 || !(strings.HasPrefix(fields[1], "/") || strings.HasPrefix(fields[1], "$")) {
+			return nil, fmt.Errorf("cannot parse line %d of landlock profile: %q", lineno, line)
+		}
+		var access Access
+		if fields[0] == "-" {
+			rules = append(rules, Rule{Path: fields[1]})
+			continue
+		}
+		for _, perm := range fields[0] {
+			switch perm {
+			case 'r':
+				access |= Read
+			case 'w':
+				access |= Write
+			case 'x':
+				access |= Execute
+			default:
+				return nil, fmt.Errorf("cannot parse line %d of landlock profile: invalid access %q", lineno, fields[0])
+			}
+		}
+		rules = append(rules, Rule{Access: access, Path: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ErrUnsupported is returned when the kernel does not support Landlock or
+// when it is disabled.
+var ErrUnsupported = errors.New("landlock is not supported by the kernel")
+
+// system calls of Landlock, with the same numbers on all architectures
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+)
+
+type rulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// pathBeneathAttr matches the packed struct landlock_path_beneath_attr,
+// the trailing padding is not read by the kernel.
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+var landlockSyscall = func(trap, a1, a2, a3 uintptr) (uintptr, error) {
+	r, _, errno := syscall.Syscall(trap, a1, a2, a3)
+	if errno != 0 {
+		return 0, errno
+	}
+	return r, nil
+}
+
+func probeVersion() (int, error) {
+	abi, err := landlockSyscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if err == syscall.ENOSYS || err == syscall.EOPNOTSUPP {
+		return 0, ErrUnsupported
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot probe landlock: %v", err)
+	}
+	return int(abi), nil
+}
+
+type abiProbe struct {
+	abi int
+	err error
+
+	once sync.Once
+}
+
+var abiProber = &abiProbe{}
+
+// Version returns the ABI version of Landlock supported by the kernel.
+func Version() (int, error) {
+	abiProber.once.Do(func() {
+		abiProber.abi, abiProber.err = probeVersion()
+	})
+	return abiProber.abi, abiProber.err
+}
+
+// MockVersion makes Version return the given ABI version and error.
+func MockVersion(abi int, err error) (restore func()) {
+	old := abiProber
+	abiProber = &abiProbe{abi: abi, err: err}
+	abiProber.once.Do(func() {})
+	return func() {
+		abiProber = old
+	}
+}
+
+var (
+	createRuleset = func(handled Access) (int, error) {
+		attr := rulesetAttr{handledAccessFS: uint64(handled)}
+		fd, err := landlockSyscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+		return int(fd), err
+	}
+	addPathBeneathRule = func(rulesetFd, pathFd int, access Access) error {
+		attr := pathBeneathAttr{allowedAccess: uint64(access), parentFd: int32(pathFd)}
+		_, err := landlockSyscall(sysLandlockAddRule, uintptr(rulesetFd), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)))
+		return err
+	}
+	restrictSelf = func(rulesetFd int) error {
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return err
+		}
+		_, err := landlockSyscall(sysLandlockRestrictSelf, uintptr(rulesetFd), 0, 0)
+		return err
+	}
+	openPath = func(path string) (int, error) {
+		return unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	}
+)
+
+// Restrict restricts the filesystem accesses of the calling thread, and of
+// the processes it executes, to the ones granted by the given rules. The
+// rules of paths that do not exist or cannot be reached are ignored. The
+// calling goroutine must be locked to its thread.
+func Restrict(rules []Rule) error {
+	abi, err := Version()
+	if err != nil {
+		return err
+	}
+	handled := handledAccess(abi)
+
+	rulesetFd, err := createRuleset(handled)
+	if err != nil {
+		return fmt.Errorf("cannot create landlock ruleset: %v", err)
+	}
+	defer unix.Close(rulesetFd)
+
+	for _, rule := range rules {
+		pathFd, err := openPath(rule.Path)
+		if err == unix.ENOENT || err == unix.EACCES {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot open %q: %v", rule.Path, err)
+		}
+		access := rule.Access & handled
+		var st unix.Stat_t
+		if err := unix.Fstat(pathFd, &st); err != nil {
+			unix.Close(pathFd)
+			return fmt.Errorf("cannot stat %q: %v", rule.Path, err)
+		}
+		if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+			access &= fileAccess
+		}
+		if access != 0 {
+			err = addPathBeneathRule(rulesetFd, pathFd, access)
+		}
+		unix.Close(pathFd)
+		if err != nil {
+			return fmt.Errorf("cannot add landlock rule for %q: %v", rule.Path, err)
+		}
+	}
+
+	if err := restrictSelf(rulesetFd); err != nil {
+		return fmt.Errorf("cannot enforce landlock ruleset: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package landlock_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/sandbox/landlock"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type landlockSuite struct{}
+
+var _ = Suite(&landlockSuite{})
+
+func (s *landlockSuite) TestRuleString(c *C) {
+	c.Check(landlock.Rule{Access: landlock.Read | landlock.Execute, Path: "/usr"}.String(), Equals, "rx /usr")
+	c.Check(landlock.Rule{Access: landlock.Read | landlock.Write, Path: "$SNAP_DATA"}.String(), Equals, "rw $SNAP_DATA")
+	c.Check(landlock.Rule{Access: landlock.AccessReadFile, Path: "/etc/foo bar"}.String(), Equals, "r /etc/foo bar")
+	c.Check(landlock.Rule{Path: "/home/user/bin"}.String(), Equals, "- /home/user/bin")
+}
+
+func (s *landlockSuite) TestParseProfile(c *C) {
+	rules, err := landlock.ParseProfile(strings.NewReader(`
+# This file is automatically generated.
+rx /usr
+rw $SNAP_DATA
+
+w /etc/foo bar
+rw $SNAP_REAL_HOME/[^s.]*
+- $SNAP_REAL_HOME/bin
+`))
+	c.Assert(err, IsNil)
+	c.Check(rules, DeepEquals, []landlock.Rule{
+		{Access: landlock.Read | landlock.Execute, Path: "/usr"},
+		{Access: landlock.Read | landlock.Write, Path: "$SNAP_DATA"},
+		{Access: landlock.Write, Path: "/etc/foo bar"},
+		{Access: landlock.Read | landlock.Write, Path: "$SNAP_REAL_HOME/[^s.]*"},
+		{Path: "$SNAP_REAL_HOME/bin"},
+	})
+
+	for _, t := range []struct {
+		profile, err string
+	}{
+		{"rx", `cannot parse line 1 of landlock profile: "rx"`},
+		{"rx usr", `cannot parse line 1 of landlock profile: "rx usr"`},
+		{"# comment\nrk /usr", `cannot parse line 2 of landlock profile: invalid access "rk"`},
+	} {
+		_, err := landlock.ParseProfile(strings.NewReader(t.profile))
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *landlockSuite) TestExpandGlobs(c *C) {
+	d := c.MkDir()
+	for _, name := range []string{"Documents", "bin", "snap", "stuff", ".ssh"} {
+		c.Assert(os.Mkdir(filepath.Join(d, name), 0755), IsNil)
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(d, "notes"), nil, 0644), IsNil)
+
+	rules, err := landlock.ExpandGlobs([]landlock.Rule{
+		{Access: landlock.Read | landlock.Execute, Path: "/usr"},
+		{Access: landlock.Read | landlock.Write, Path: filepath.Join(d, "[^s.]*")},
+		{Access: landlock.Read | landlock.Write, Path: filepath.Join(d, "s[^n]*")},
+		{Access: landlock.Read, Path: filepath.Join(d, "bin")},
+		{Access: landlock.Read, Path: filepath.Join(d, "missing", "*")},
+	})
+	c.Assert(err, IsNil)
+	c.Check(rules, DeepEquals, []landlock.Rule{
+		{Access: landlock.Read | landlock.Execute, Path: "/usr"},
+		// bin is left to its own rule
+		{Access: landlock.Read | landlock.Write, Path: filepath.Join(d, "Documents")},
+		{Access: landlock.Read | landlock.Write, Path: filepath.Join(d, "notes")},
+		{Access: landlock.Read | landlock.Write, Path: filepath.Join(d, "stuff")},
+		{Access: landlock.Read, Path: filepath.Join(d, "bin")},
+	})
+
+	_, err = landlock.ExpandGlobs([]landlock.Rule{{Access: landlock.Read, Path: filepath.Join(d, "[a-")}})
+	c.Check(err, ErrorMatches, `cannot expand ".*/\[a-": syntax error in pattern`)
+}
+
+func (s *landlockSuite) TestHandledAccess(c *C) {
+	c.Check(landlock.HandledAccess(0), Equals, landlock.Access(0))
+	c.Check(landlock.HandledAccess(1), Equals, landlock.Access(0x1fff))
+	c.Check(landlock.HandledAccess(2), Equals, landlock.Access(0x3fff))
+	c.Check(landlock.HandledAccess(3), Equals, landlock.Access(0x7fff))
+	c.Check(landlock.HandledAccess(4), Equals, landlock.Access(0x7fff))
+}
+
+func (s *landlockSuite) TestVersion(c *C) {
+	for _, t := range []struct {
+		abi uintptr
+		err error
+
+		expectedABI int
+		expectedErr string
+	}{
+		{abi: 3, expectedABI: 3},
+		{err: syscall.ENOSYS, expectedErr: "landlock is not supported by the kernel"},
+		{err: syscall.EOPNOTSUPP, expectedErr: "landlock is not supported by the kernel"},
+		{err: syscall.EINVAL, expectedErr: "cannot probe landlock: invalid argument"},
+	} {
+		landlock.FreshABIProbe()
+		calls := 0
+		restore := landlock.MockLandlockSyscall(func(trap, a1, a2, a3 uintptr) (uintptr, error) {
+			calls++
+			c.Check(trap, Equals, uintptr(444))
+			c.Check([]uintptr{a1, a2, a3}, DeepEquals, []uintptr{0, 0, 1})
+			return t.abi, t.err
+		})
+		abi, err := landlock.Version()
+		// the result is cached
+		landlock.Version()
+		restore()
+		c.Check(calls, Equals, 1)
+		c.Check(abi, Equals, t.expectedABI)
+		if t.expectedErr == "" {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, ErrorMatches, t.expectedErr)
+		}
+	}
+	landlock.FreshABIProbe()
+
+	restore := landlock.MockVersion(2, nil)
+	defer restore()
+	abi, err := landlock.Version()
+	c.Check(abi, Equals, 2)
+	c.Check(err, IsNil)
+}
+
+func (s *landlockSuite) TestRestrict(c *C) {
+	restore := landlock.MockVersion(1, nil)
+	defer restore()
+
+	d := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(d, "file"), nil, 0644), IsNil)
+
+	var calls []string
+	var rulesetFd int
+	restore = landlock.MockRuleset(func(handled landlock.Access) (int, error) {
+		calls = append(calls, fmt.Sprintf("create %#x", handled))
+		f, err := os.Open("/dev/null")
+		c.Assert(err, IsNil)
+		// the ruleset is closed by Restrict
+		rulesetFd, err = syscall.Dup(int(f.Fd()))
+		c.Assert(err, IsNil)
+		f.Close()
+		return rulesetFd, nil
+	}, func(fd, pathFd int, access landlock.Access) error {
+		c.Check(fd, Equals, rulesetFd)
+		path, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", pathFd))
+		c.Assert(err, IsNil)
+		calls = append(calls, fmt.Sprintf("add %s %#x", path, access))
+		return nil
+	}, func(fd int) error {
+		c.Check(fd, Equals, rulesetFd)
+		calls = append(calls, "restrict")
+		return nil
+	})
+	defer restore()
+
+	err := landlock.Restrict([]landlock.Rule{
+		{Access: landlock.Read | landlock.Write | landlock.Execute, Path: d},
+		{Access: landlock.Read | landlock.Write, Path: filepath.Join(d, "file")},
+		{Access: landlock.Read, Path: filepath.Join(d, "missing")},
+	})
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, []string{
+		"create 0x1fff",
+		// refer and truncate are not handled by ABI 1
+		fmt.Sprintf("add %s 0x1fff", d),
+		// only file accesses
+		fmt.Sprintf("add %s 0x6", filepath.Join(d, "file")),
+		"restrict",
+	})
+}
+
+func (s *landlockSuite) TestRestrictSkipsUnreachable(c *C) {
+	restore := landlock.MockVersion(1, nil)
+	defer restore()
+	restore = landlock.MockOpenPath(func(path string) (int, error) {
+		switch path {
+		case "/missing":
+			return -1, syscall.ENOENT
+		case "/forbidden/dir":
+			return -1, syscall.EACCES
+		}
+		return -1, syscall.EIO
+	})
+	defer restore()
+
+	var added int
+	restore = landlock.MockRuleset(func(landlock.Access) (int, error) {
+		return syscall.Dup(0)
+	}, func(int, int, landlock.Access) error {
+		added++
+		return nil
+	}, func(int) error {
+		return nil
+	})
+	defer restore()
+
+	err := landlock.Restrict([]landlock.Rule{
+		{Access: landlock.Read, Path: "/missing"},
+		{Access: landlock.Read, Path: "/forbidden/dir"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(added, Equals, 0)
+
+	err = landlock.Restrict([]landlock.Rule{{Access: landlock.Read, Path: "/broken"}})
+	c.Check(err, ErrorMatches, `cannot open "/broken": input/output error`)
+}
+
+func (s *landlockSuite) TestRestrictErrors(c *C) {
+	restore := landlock.MockVersion(0, landlock.ErrUnsupported)
+	err := landlock.Restrict(nil)
+	restore()
+	c.Check(err, Equals, landlock.ErrUnsupported)
+
+	restore = landlock.MockVersion(3, nil)
+	defer restore()
+	newRuleset := func(landlock.Access) (int, error) {
+		return syscall.Dup(0)
+	}
+	restore = landlock.MockRuleset(func(landlock.Access) (int, error) {
+		return 0, errors.New("boom")
+	}, nil, nil)
+	c.Check(landlock.Restrict(nil), ErrorMatches, "cannot create landlock ruleset: boom")
+	restore()
+
+	restore = landlock.MockRuleset(newRuleset, func(int, int, landlock.Access) error {
+		return errors.New("boom")
+	}, nil)
+	c.Check(landlock.Restrict([]landlock.Rule{{Access: landlock.Read, Path: "/"}}), ErrorMatches, `cannot add landlock rule for "/": boom`)
+	restore()
+
+	restore = landlock.MockRuleset(newRuleset, nil, func(int) error {
+		return errors.New("boom")
+	})
+	c.Check(landlock.Restrict(nil), ErrorMatches, "cannot enforce landlock ruleset: boom")
+	restore()
+}
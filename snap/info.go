@@ -620,6 +620,12 @@ type DownloadInfo struct {
 	Size     int64  `json:"size,omitempty"`
 	Sha3_384 string `json:"sha3-384,omitempty"`
 
+	// The server can optionally include the sha3-384 of each chunk of
+	// ChunkSize bytes of the snap, so that corrupted parts of a download
+	// can be detected and re-fetched selectively.
+	ChunkSize     int64    `json:"chunk-size,omitempty"`
+	ChunkSha3_384 []string `json:"chunk-sha3-384,omitempty"`
+
 	// The server can include information about available deltas for a given
 	// snap at a specific revision during refresh. Currently during refresh the
 	// server will provide single matching deltas only, from the clients
@@ -65,10 +65,12 @@ type storeSnap struct {
 }
 
 type storeSnapDownload struct {
-	Sha3_384 string           `json:"sha3-384"`
-	Size     int64            `json:"size"`
-	URL      string           `json:"url"`
-	Deltas   []storeSnapDelta `json:"deltas"`
+	Sha3_384      string           `json:"sha3-384"`
+	Size          int64            `json:"size"`
+	URL           string           `json:"url"`
+	Deltas        []storeSnapDelta `json:"deltas"`
+	ChunkSize     int64            `json:"chunk-size"`
+	ChunkSha3_384 []string         `json:"chunk-sha3-384"`
 }
 
 type storeSnapDelta struct {
@@ -277,6 +279,8 @@ func infoFromStoreSnap(d *storeSnap) (*snap.Info, error) {
 	info.DownloadURL = d.Download.URL
 	info.Size = d.Download.Size
 	info.Sha3_384 = d.Download.Sha3_384
+	info.ChunkSize = d.Download.ChunkSize
+	info.ChunkSha3_384 = d.Download.ChunkSha3_384
 	if len(d.Download.Deltas) > 0 {
 		deltas := make([]snap.DeltaInfo, len(d.Download.Deltas))
 		for i, d := range d.Download.Deltas {
@@ -93,6 +93,11 @@ const (
      "sha3-384": "a29f8d894c92ad19bb943764eb845c6bd7300f555ee9b9dbb460599fecf712775c0f3e2117b5c56b08fcb9d78fc8ae4d",
      "size": 10000021,
      "url": "https://api.snapcraft.io/api/v1/snaps/download/XYZEfjn4WJYnm0FzDKwqqRZZI77awQEV_21.snap",
+     "chunk-size": 8388608,
+     "chunk-sha3-384": [
+       "b691f6dde3d8022e4db563840f0ef82320cb824b6292ffd027dbc838535214dac31c3512c619beaf73f1aeaf35ac62d5",
+       "9f8d894c92ad19bb943764eb845c6bd7300f555ee9b9dbb460599fecf712775c0f3e2117b5c56b08fcb9d78fc8ae4dfa"
+     ],
      "deltas": [
        {
          "format": "xdelta3",
@@ -234,6 +239,11 @@ func (s *detailsV2Suite) TestInfoFromStoreSnap(c *C) {
 			DownloadURL: "https://api.snapcraft.io/api/v1/snaps/download/XYZEfjn4WJYnm0FzDKwqqRZZI77awQEV_21.snap",
 			Sha3_384:    "a29f8d894c92ad19bb943764eb845c6bd7300f555ee9b9dbb460599fecf712775c0f3e2117b5c56b08fcb9d78fc8ae4d",
 			Size:        10000021,
+			ChunkSize:   8388608,
+			ChunkSha3_384: []string{
+				"b691f6dde3d8022e4db563840f0ef82320cb824b6292ffd027dbc838535214dac31c3512c619beaf73f1aeaf35ac62d5",
+				"9f8d894c92ad19bb943764eb845c6bd7300f555ee9b9dbb460599fecf712775c0f3e2117b5c56b08fcb9d78fc8ae4dfa",
+			},
 			Deltas: []snap.DeltaInfo{
 				{
 					Format:       "xdelta3",
@@ -87,6 +87,14 @@ func MockConnCheckStrategy(t *testutil.BaseTest, strategy retry.Strategy) {
 	})
 }
 
+func MockMaxRecordedBodySize(size int) (restore func()) {
+	old := maxRecordedBodySize
+	maxRecordedBodySize = size
+	return func() {
+		maxRecordedBodySize = old
+	}
+}
+
 func MockDownloadSpeedParams(measureWindow time.Duration, minSpeed float64) (restore func()) {
 	oldSpeedMeasureWindow := downloadSpeedMeasureWindow
 	oldSpeedMin := downloadSpeedMin
@@ -98,6 +106,21 @@ func MockDownloadSpeedParams(measureWindow time.Duration, minSpeed float64) (res
 	}
 }
 
+func MockDownloadChunkSize(size int64) (restore func()) {
+	oldChunkSize := downloadChunkSize
+	downloadChunkSize = size
+	return func() {
+		downloadChunkSize = oldChunkSize
+	}
+}
+
+// FeedDownloadChunks feeds data to the chunk hashing set up by Download,
+// for mocked downloads.
+func FeedDownloadChunks(dlOpts *DownloadOptions, data []byte) {
+	dlOpts.chunks.restart(0)
+	dlOpts.chunks.Write(data)
+}
+
 func IsTransferSpeedError(err error) (ok bool, speed float64) {
 	de, ok := err.(*transferSpeedError)
 	if !ok {
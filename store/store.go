@@ -430,7 +430,7 @@ func (s *Store) newHTTPClient(opts *httputil.ClientOptions) *http.Client {
 	opts.ExtraSSLCerts = &httputil.ExtraSSLCertsFromDir{
 		Dir: dirs.SnapdStoreSSLCertsDir,
 	}
-	return httputil.NewHTTPClient(opts)
+	return recordOrReplay(httputil.NewHTTPClient(opts))
 }
 
 func (s *Store) defaultSnapQuery() url.Values {
@@ -780,17 +780,18 @@ func decodeCatalog(resp *http.Response, names io.Writer, db SnapAdder) error {
 			continue
 		}
 		fmt.Fprintln(names, v.Name)
-		if len(v.Apps) == 0 {
-			continue
-		}
-
-		commands := make([]string, 0, len(v.Aliases)+len(v.Apps))
 
-		for _, alias := range v.Aliases {
-			commands = append(commands, alias.Name)
-		}
-		for _, app := range v.Apps {
-			commands = append(commands, snap.JoinSnapApp(v.Name, app))
+		// snaps without apps are still added, without commands, so
+		// that the catalog can be searched offline
+		var commands []string
+		if len(v.Apps) > 0 {
+			commands = make([]string, 0, len(v.Aliases)+len(v.Apps))
+			for _, alias := range v.Aliases {
+				commands = append(commands, alias.Name)
+			}
+			for _, app := range v.Apps {
+				commands = append(commands, snap.JoinSnapApp(v.Name, app))
+			}
 		}
 
 		if err := db.AddSnap(v.Name, v.Version, v.Summary, commands); err != nil {
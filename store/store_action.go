@@ -175,6 +175,7 @@ type snapActionResult struct {
 	Snap             storeSnap `json:"snap"`
 	EffectiveChannel string    `json:"effective-channel,omitempty"`
 	RedirectChannel  string    `json:"redirect-channel,omitempty"`
+	ReleasedAt       time.Time `json:"released-at"`
 	Error            struct {
 		Code    string `json:"code"`
 		Message string `json:"message"`
@@ -601,6 +602,20 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 		}
 
 		snapInfo.Channel = res.EffectiveChannel
+		if !res.ReleasedAt.IsZero() && res.EffectiveChannel != "" {
+			// the part of the channel map relevant to the result
+			snapInfo.Channels = map[string]*snap.ChannelSnapInfo{
+				res.EffectiveChannel: {
+					Revision:    snapInfo.Revision,
+					Confinement: snapInfo.Confinement,
+					Version:     snapInfo.Version,
+					Channel:     res.EffectiveChannel,
+					Epoch:       snapInfo.Epoch,
+					Size:        snapInfo.Size,
+					ReleasedAt:  res.ReleasedAt.UTC(),
+				},
+			}
+		}
 
 		var instanceName string
 		if res.Result == "refresh" {
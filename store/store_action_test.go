@@ -413,6 +413,71 @@ func (s *storeActionSuite) TestSnapActionRefreshedDateIsOptional(c *C) {
 	c.Check(err, DeepEquals, &store.SnapActionError{NoResults: true})
 }
 
+func (s *storeActionSuite) TestSnapActionRefreshReleasedAt(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "POST", snapActionPath)
+		io.WriteString(w, `{
+  "results": [{
+     "result": "refresh",
+     "instance-key": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+     "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+     "name": "hello-world",
+     "effective-channel": "latest/candidate",
+     "released-at": "2021-06-01T10:00:00.123456+02:00",
+     "snap": {
+       "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+       "name": "hello-world",
+       "revision": 26,
+       "version": "6.1",
+       "publisher": {
+          "id": "canonical",
+          "username": "canonical",
+          "display-name": "Canonical"
+       }
+     }
+  }]
+}`)
+	}))
+
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := store.Config{
+		StoreBaseURL: mockServerURL,
+	}
+	dauthCtx := &testDauthContext{c: c, device: s.device}
+	sto := store.New(&cfg, dauthCtx)
+
+	results, _, err := sto.SnapAction(s.ctx, []*store.CurrentSnap{
+		{
+			InstanceName:    "hello-world",
+			SnapID:          helloWorldSnapID,
+			TrackingChannel: "stable",
+			Revision:        snap.R(1),
+		},
+	}, []*store.SnapAction{
+		{
+			Action:       "refresh",
+			SnapID:       helloWorldSnapID,
+			InstanceName: "hello-world",
+			Channel:      "candidate",
+		},
+	}, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(results, HasLen, 1)
+	c.Check(results[0].Channel, Equals, "latest/candidate")
+	c.Assert(results[0].Channels, HasLen, 1)
+	ch := results[0].Channels["latest/candidate"]
+	c.Assert(ch, NotNil)
+	c.Check(ch.Revision, Equals, snap.R(26))
+	c.Check(ch.Version, Equals, "6.1")
+	c.Check(ch.ReleasedAt.Equal(time.Date(2021, 6, 1, 8, 0, 0, 123456000, time.UTC)), Equals, true)
+}
+
 func (s *storeActionSuite) TestSnapActionSkipBlocked(c *C) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assertRequest(c, r, "POST", snapActionPath)
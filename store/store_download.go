@@ -141,6 +141,10 @@ type DownloadOptions struct {
 	RateLimit           int64
 	IsAutoRefresh       bool
 	LeavePartialOnError bool
+
+	// chunks, if set, is fed with the downloaded data to record the
+	// chunk hashes of the partial file
+	chunks *downloadChunks
 }
 
 // Download downloads the snap addressed by download info and returns its
@@ -175,8 +179,12 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	if err != nil {
 		return err
 	}
-	resume, err := w.Seek(0, io.SeekEnd)
+	// verify what was downloaded so far against the chunk hashes, so
+	// that only verified data is kept when resuming
+	chunks := newDownloadChunks(partialPath, downloadInfo)
+	resume, err := chunks.verifyPartial(w, downloadInfo.Size)
 	if err != nil {
+		w.Close()
 		return err
 	}
 	defer func() {
@@ -185,10 +193,12 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 			err = cerr
 		}
 		if err == nil {
+			chunks.remove()
 			return
 		}
 		if dlOpts == nil || !dlOpts.LeavePartialOnError || fi == nil || fi.Size() == 0 {
 			os.Remove(w.Name())
+			chunks.remove()
 		}
 	}()
 	if resume > 0 {
@@ -207,8 +217,14 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		url = downloadInfo.DownloadURL
 	}
 
+	chunkedOpts := &DownloadOptions{chunks: chunks}
+	if dlOpts != nil {
+		*chunkedOpts = *dlOpts
+		chunkedOpts.chunks = chunks
+	}
+
 	if downloadInfo.Size == 0 || resume < downloadInfo.Size {
-		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar, dlOpts)
+		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar, chunkedOpts)
 		if err != nil {
 			logger.Debugf("download of %q failed: %#v", url, err)
 		}
@@ -226,7 +242,13 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 			err = HashError{name, actualSha3, downloadInfo.Sha3_384}
 		}
 	}
-	// If hashsum is incorrect retry once
+	// If hashsum is incorrect try to re-fetch only the corrupted chunks
+	if _, ok := err.(HashError); ok && chunks.canRepair() {
+		logger.Debugf("Hashsum error on download: %v", err.Error())
+		logger.Debugf("Trying to repair the corrupted chunks.")
+		err = s.repairChunks(ctx, name, url, w, chunks, downloadInfo, user)
+	}
+	// If hashsum is still incorrect retry once
 	if _, ok := err.(HashError); ok {
 		logger.Debugf("Hashsum error on download: %v", err.Error())
 		logger.Debugf("Truncating and trying again from scratch.")
@@ -238,7 +260,8 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		if err != nil {
 			return err
 		}
-		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, 0, pbar, nil)
+		chunks.reset()
+		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, 0, pbar, &DownloadOptions{chunks: chunks})
 		if err != nil {
 			logger.Debugf("download of %q failed: %#v", url, err)
 		}
@@ -482,7 +505,12 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 			logger.Noticef("Download size for %s: %d", downloadURL, resp.ContentLength)
 		}
 		pbar.Start(name, dlSize)
-		mw := io.MultiWriter(w, h, pbar, tc)
+		writers := []io.Writer{w, h, pbar, tc}
+		if dlOpts.chunks != nil {
+			dlOpts.chunks.restart(resume)
+			writers = append(writers, dlOpts.chunks)
+		}
+		mw := io.MultiWriter(writers...)
 		var limiter io.Reader
 		limiter = resp.Body
 		if limit := dlOpts.RateLimit; limit > 0 {
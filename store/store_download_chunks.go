@@ -0,0 +1,424 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/snap"
+)
+
+// downloadChunkSize is the size of the chunks hashed locally while
+// downloading when the store does not provide chunk hashes itself.
+var downloadChunkSize = int64(4 * 1024 * 1024)
+
+// chunkRecord is the on-disk record of the chunk hashes of a partial
+// download, kept next to the partial file so that a download can be
+// resumed from a verified offset even across snapd restarts.
+type chunkRecord struct {
+	// Sha3_384 is the digest of the whole download the record is for.
+	Sha3_384  string `json:"sha3-384"`
+	ChunkSize int64  `json:"chunk-size"`
+	// Chunks holds the sha3-384 of each complete chunk written so
+	// far, an empty entry means the chunk could not be hashed.
+	Chunks []string `json:"chunks"`
+}
+
+// downloadChunks tracks the chunk hashes of a partial download. It is
+// an io.Writer that is fed with the data as it is appended to the
+// partial file, in the same way as the full-file hash.
+type downloadChunks struct {
+	recordPath string
+	record     chunkRecord
+	// expected are the chunk hashes as provided by the store, if any
+	expected []string
+	// hadRecord is true if a record was found for the partial file
+	hadRecord bool
+
+	cur    hash.Hash
+	curLen int64
+	// skip is the number of bytes to discard before the start of the
+	// next chunk boundary, when writing resumed from an unaligned
+	// offset whose chunk cannot be hashed anymore
+	skip int64
+	// disabled is set if saving the record failed
+	disabled bool
+}
+
+func chunkRecordPath(partialPath string) string {
+	return partialPath + ".chunks"
+}
+
+func newDownloadChunks(partialPath string, downloadInfo *snap.DownloadInfo) *downloadChunks {
+	chunkSize := downloadChunkSize
+	var expected []string
+	if downloadInfo.ChunkSize > 0 && len(downloadInfo.ChunkSha3_384) > 0 {
+		chunkSize = downloadInfo.ChunkSize
+		expected = downloadInfo.ChunkSha3_384
+	}
+	dc := &downloadChunks{
+		recordPath: chunkRecordPath(partialPath),
+		record: chunkRecord{
+			Sha3_384:  downloadInfo.Sha3_384,
+			ChunkSize: chunkSize,
+		},
+		expected: expected,
+		cur:      crypto.SHA3_384.New(),
+	}
+
+	data, err := ioutil.ReadFile(dc.recordPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Noticef("Cannot read download chunks record %q: %v", dc.recordPath, err)
+			dc.hadRecord = true
+		}
+		return dc
+	}
+	dc.hadRecord = true
+	var rec chunkRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		logger.Noticef("Cannot decode download chunks record %q: %v", dc.recordPath, err)
+		return dc
+	}
+	if rec.Sha3_384 != dc.record.Sha3_384 || rec.ChunkSize != dc.record.ChunkSize {
+		logger.Debugf("Ignoring download chunks record %q for a different download.", dc.recordPath)
+		return dc
+	}
+	dc.record.Chunks = rec.Chunks
+	return dc
+}
+
+// canRepair returns whether the store provided chunk hashes that can
+// be used to find and re-fetch corrupted ranges of a download.
+func (dc *downloadChunks) canRepair() bool {
+	return len(dc.expected) > 0
+}
+
+func (dc *downloadChunks) chunkSize() int64 {
+	return dc.record.ChunkSize
+}
+
+// expectedChunk returns the hash expected for the given chunk, the
+// store provided hashes have precedence over the locally recorded ones.
+func (dc *downloadChunks) expectedChunk(i int) string {
+	if i < len(dc.expected) {
+		return dc.expected[i]
+	}
+	if len(dc.expected) == 0 && i < len(dc.record.Chunks) {
+		return dc.record.Chunks[i]
+	}
+	return ""
+}
+
+// verifyPartial verifies the existing content of the partial file
+// against the known chunk hashes, truncating it at the first chunk that
+// is corrupted or cannot be verified. It returns the offset from which
+// the download can be resumed. A partial file without any chunk
+// information is trusted as is.
+func (dc *downloadChunks) verifyPartial(f *os.File, totalSize int64) (int64, error) {
+	trustUnknown := !dc.hadRecord && !dc.canRepair()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	var verified []string
+	var offset int64
+	buf := make([]byte, dc.chunkSize())
+	for {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+		complete := int64(n) == dc.chunkSize() || (totalSize > 0 && offset+int64(n) == totalSize)
+		h := crypto.SHA3_384.New()
+		h.Write(buf[:n])
+		sum := fmt.Sprintf("%x", h.Sum(nil))
+		expected := dc.expectedChunk(len(verified))
+		if !complete {
+			if trustUnknown {
+				// keep hashing the incomplete trailing chunk
+				dc.cur.Write(buf[:n])
+				dc.curLen = int64(n)
+				offset += int64(n)
+			}
+			break
+		}
+		if expected == "" && !trustUnknown {
+			break
+		}
+		if expected != "" && expected != sum {
+			logger.Noticef("Chunk %d of partial download %q is corrupted, resuming from %d.", len(verified), f.Name(), offset)
+			break
+		}
+		verified = append(verified, sum)
+		offset += int64(n)
+		if int64(n) < dc.chunkSize() {
+			break
+		}
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if fi.Size() != offset {
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	// only chunks of the full chunk size are hashed while writing, so
+	// a verified short last chunk is left unknown
+	if offset > 0 && offset%dc.chunkSize() != 0 && dc.curLen == 0 {
+		verified[len(verified)-1] = ""
+		dc.skip = dc.chunkSize() - offset%dc.chunkSize()
+	}
+	dc.record.Chunks = verified
+	dc.save()
+	return offset, nil
+}
+
+// covered returns the offset up to which the written data has been
+// seen by the chunk hashing.
+func (dc *downloadChunks) covered() int64 {
+	if dc.skip > 0 {
+		return int64(len(dc.record.Chunks))*dc.chunkSize() - dc.skip
+	}
+	return int64(len(dc.record.Chunks))*dc.chunkSize() + dc.curLen
+}
+
+// restart prepares the chunk hashing for data written from the given
+// offset onwards.
+func (dc *downloadChunks) restart(offset int64) {
+	if offset == dc.covered() {
+		return
+	}
+	n := offset / dc.chunkSize()
+	if n > int64(len(dc.record.Chunks)) {
+		n = int64(len(dc.record.Chunks))
+	}
+	dc.record.Chunks = dc.record.Chunks[:n]
+	dc.cur.Reset()
+	dc.curLen = 0
+	dc.skip = 0
+	// the hashes of the chunks before offset that were not seen, and
+	// of the chunk containing it, cannot be computed without re-reading
+	// them, so leave them unknown
+	for rem := offset - n*dc.chunkSize(); rem > 0; rem -= dc.chunkSize() {
+		dc.record.Chunks = append(dc.record.Chunks, "")
+		if rem < dc.chunkSize() {
+			dc.skip = dc.chunkSize() - rem
+		}
+	}
+	dc.save()
+}
+
+// Write implements io.Writer.
+func (dc *downloadChunks) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if dc.skip > 0 {
+			l := dc.skip
+			if int64(len(p)) < l {
+				l = int64(len(p))
+			}
+			dc.skip -= l
+			p = p[l:]
+			continue
+		}
+		l := dc.chunkSize() - dc.curLen
+		if int64(len(p)) < l {
+			l = int64(len(p))
+		}
+		dc.cur.Write(p[:l])
+		dc.curLen += l
+		p = p[l:]
+		if dc.curLen == dc.chunkSize() {
+			dc.record.Chunks = append(dc.record.Chunks, fmt.Sprintf("%x", dc.cur.Sum(nil)))
+			dc.cur.Reset()
+			dc.curLen = 0
+			dc.save()
+		}
+	}
+	return n, nil
+}
+
+func (dc *downloadChunks) save() {
+	if dc.disabled {
+		return
+	}
+	data, err := json.Marshal(&dc.record)
+	if err != nil {
+		// not expected
+		logger.Noticef("Cannot encode download chunks record: %v", err)
+		dc.disabled = true
+		return
+	}
+	if err := osutil.AtomicWriteFile(dc.recordPath, data, 0600, 0); err != nil {
+		logger.Noticef("Cannot save download chunks record %q: %v", dc.recordPath, err)
+		dc.disabled = true
+	}
+}
+
+// remove removes the on-disk record.
+func (dc *downloadChunks) remove() {
+	if err := os.Remove(dc.recordPath); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("Cannot remove download chunks record %q: %v", dc.recordPath, err)
+	}
+}
+
+// reset forgets all recorded chunks, for when the partial file is
+// truncated.
+func (dc *downloadChunks) reset() {
+	dc.record.Chunks = nil
+	dc.cur.Reset()
+	dc.curLen = 0
+	dc.skip = 0
+	dc.save()
+}
+
+// corruptedChunks returns the indexes of the chunks of the file that
+// do not match the store provided chunk hashes.
+func (dc *downloadChunks) corruptedChunks(f *os.File) ([]int, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var bad []int
+	buf := make([]byte, dc.chunkSize())
+	for i := range dc.expected {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return nil, err
+		}
+		h := crypto.SHA3_384.New()
+		h.Write(buf[:n])
+		if fmt.Sprintf("%x", h.Sum(nil)) != dc.expected[i] {
+			bad = append(bad, i)
+		}
+	}
+	return bad, nil
+}
+
+var maxRepairedChunksRatio = 0.5
+
+// repairChunks re-fetches the chunks of a complete download that do not
+// match the store provided chunk hashes and then verifies the whole
+// file again. It returns a HashError if the download could not be
+// repaired.
+func (s *Store) repairChunks(ctx context.Context, name, downloadURL string, f *os.File, dc *downloadChunks, downloadInfo *snap.DownloadInfo, user *auth.UserState) error {
+	if err := f.Truncate(downloadInfo.Size); err != nil {
+		return err
+	}
+	bad, err := dc.corruptedChunks(f)
+	if err != nil {
+		return err
+	}
+	if len(bad) == 0 || float64(len(bad)) > maxRepairedChunksRatio*float64(len(dc.expected)) {
+		actualSha3, err := fileSha3_384(f)
+		if err != nil {
+			return err
+		}
+		return HashError{name, actualSha3, downloadInfo.Sha3_384}
+	}
+
+	storeURL, err := url.Parse(downloadURL)
+	if err != nil {
+		return err
+	}
+	cdnHeader, err := s.cdnHeader()
+	if err != nil {
+		return err
+	}
+	for _, i := range bad {
+		start := int64(i) * dc.chunkSize()
+		end := start + dc.chunkSize() - 1
+		if end >= downloadInfo.Size {
+			end = downloadInfo.Size - 1
+		}
+		logger.Noticef("Re-fetching corrupted chunk %d (bytes %d-%d) of %s.", i, start, end, name)
+		if err := s.downloadRange(ctx, storeURL, cdnHeader, start, end, f, dc.expected[i], user); err != nil {
+			return err
+		}
+	}
+
+	actualSha3, err := fileSha3_384(f)
+	if err != nil {
+		return err
+	}
+	if actualSha3 != downloadInfo.Sha3_384 {
+		return HashError{name, actualSha3, downloadInfo.Sha3_384}
+	}
+	return nil
+}
+
+// downloadRange fetches the given byte range (inclusive) and writes
+// it at the same offset in f, checking it against the expected hash.
+func (s *Store) downloadRange(ctx context.Context, storeURL *url.URL, cdnHeader string, start, end int64, f *os.File, sha3_384 string, user *auth.UserState) error {
+	reqOptions := downloadReqOpts(storeURL, cdnHeader, nil)
+	reqOptions.ExtraHeaders["Range"] = fmt.Sprintf("bytes=%d-%d", start, end)
+	cli := s.newHTTPClient(nil)
+	resp, err := s.doRequest(ctx, cli, reqOptions, user)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 206 {
+		return &DownloadError{Code: resp.StatusCode, URL: resp.Request.URL}
+	}
+
+	buf := make([]byte, end-start+1)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return err
+	}
+	h := crypto.SHA3_384.New()
+	h.Write(buf)
+	if actual := fmt.Sprintf("%x", h.Sum(nil)); actual != sha3_384 {
+		return fmt.Errorf("sha3-384 mismatch for re-fetched range %d-%d: got %s but expected %s", start, end, actual, sha3_384)
+	}
+	_, err = f.WriteAt(buf, start)
+	return err
+}
+
+func fileSha3_384(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	h := crypto.SHA3_384.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
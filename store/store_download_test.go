@@ -559,6 +559,145 @@ func (s *storeDownloadSuite) TestDownloadFailsDoesNotLeavePartialIfEmpty(c *C) {
 	c.Assert(osutil.FileExists(path), Equals, false)
 }
 
+func (s *storeDownloadSuite) TestDownloadResumesFromVerifiedChunks(c *C) {
+	restore := store.MockDownloadChunkSize(8)
+	defer restore()
+
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	h := crypto.SHA3_384.New()
+	h.Write(content)
+
+	n := 0
+	restore = store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		n++
+		if n == 1 {
+			// the chunk hashes are recorded as the data is written
+			c.Check(resume, Equals, int64(0))
+			w.Write(content[:20])
+			store.FeedDownloadChunks(dlOpts, content[:20])
+			return fmt.Errorf("uh, it failed")
+		}
+		// resumed from the start of the corrupted chunk
+		c.Check(resume, Equals, int64(8))
+		w.Write(content[resume:])
+		return nil
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", h.Sum(nil))
+	snap.Size = int64(len(content))
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{LeavePartialOnError: true})
+	c.Assert(err, ErrorMatches, "uh, it failed")
+	c.Assert(targetFn+".partial", testutil.FileEquals, content[:20])
+	c.Assert(osutil.FileExists(targetFn+".partial.chunks"), Equals, true)
+
+	// corrupt the second chunk of the partial download
+	partial, err := ioutil.ReadFile(targetFn + ".partial")
+	c.Assert(err, IsNil)
+	partial[10] = 'X'
+	c.Assert(ioutil.WriteFile(targetFn+".partial", partial, 0600), IsNil)
+
+	err = s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	c.Check(targetFn, testutil.FileEquals, content)
+	c.Check(osutil.FileExists(targetFn+".partial.chunks"), Equals, false)
+}
+
+func (s *storeDownloadSuite) TestDownloadRepairsCorruptedChunks(c *C) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	h := crypto.SHA3_384.New()
+	h.Write(content)
+	var chunkHashes []string
+	for i := 0; i < len(content); i += 8 {
+		end := i + 8
+		if end > len(content) {
+			end = len(content)
+		}
+		chunkHashes = append(chunkHashes, fmt.Sprintf("%x", sha3.Sum384(content[i:end])))
+	}
+
+	var ranges []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		ranges = append(ranges, rng)
+		if rng == "" {
+			// send a corrupted byte in the third chunk
+			corrupted := append([]byte(nil), content...)
+			corrupted[17] = 'X'
+			w.Write(corrupted)
+			return
+		}
+		var start, end int
+		_, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+		c.Assert(err, IsNil)
+		w.WriteHeader(206)
+		w.Write(content[start : end+1])
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", h.Sum(nil))
+	snap.Size = int64(len(content))
+	snap.ChunkSize = 8
+	snap.ChunkSha3_384 = chunkHashes
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(targetFn, testutil.FileEquals, content)
+	// only the corrupted chunk was downloaded again
+	c.Check(ranges, DeepEquals, []string{"", "bytes=16-23"})
+	c.Check(osutil.FileExists(targetFn+".partial.chunks"), Equals, false)
+}
+
+func (s *storeDownloadSuite) TestDownloadResumeVerifiesStoreChunks(c *C) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	h := crypto.SHA3_384.New()
+	h.Write(content)
+	var chunkHashes []string
+	for i := 0; i < len(content); i += 8 {
+		end := i + 8
+		if end > len(content) {
+			end = len(content)
+		}
+		chunkHashes = append(chunkHashes, fmt.Sprintf("%x", sha3.Sum384(content[i:end])))
+	}
+
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		// the last incomplete chunk cannot be verified
+		c.Check(resume, Equals, int64(16))
+		w.Write(content[resume:])
+		return nil
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", h.Sum(nil))
+	snap.Size = int64(len(content))
+	snap.ChunkSize = 8
+	snap.ChunkSha3_384 = chunkHashes
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	c.Assert(ioutil.WriteFile(targetFn+".partial", content[:20], 0600), IsNil)
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(targetFn, testutil.FileEquals, content)
+}
+
 func (s *storeDownloadSuite) TestDownloadSyncFails(c *C) {
 	var tmpfile *os.File
 	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
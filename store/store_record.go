@@ -0,0 +1,450 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// With SNAPD_STORE_RECORD=<dir> every request to the store and its
+// response are recorded in <dir>; with SNAPD_STORE_REPLAY=<dir> the
+// recorded responses are served back instead of contacting the store.
+// Bodies larger than maxRecordedBodySize, like the ones of snap
+// downloads, are streamed through and not recorded.
+const (
+	storeRecordEnvKey = "SNAPD_STORE_RECORD"
+	storeReplayEnvKey = "SNAPD_STORE_REPLAY"
+)
+
+const redacted = "<redacted>"
+
+var maxRecordedBodySize = 1024 * 1024
+
+var (
+	// headers carrying credentials, their values are never recorded
+	redactedHeaders = []string{
+		"Authorization",
+		"X-Device-Authorization",
+		"Snap-Device-Authorization",
+		"Cookie",
+		"Set-Cookie",
+	}
+	// fields of json bodies carrying credentials, at any depth
+	redactedFields = []string{
+		"password",
+		"otp",
+		"macaroon",
+		"discharge_macaroon",
+		"session_macaroon",
+	}
+)
+
+type recordedBody struct {
+	Body       string `json:"body,omitempty"`
+	BodyBase64 []byte `json:"body-base64,omitempty"`
+	// BodyOmitted is set when the body was too large to be recorded
+	BodyOmitted bool `json:"body-omitted,omitempty"`
+}
+
+func newRecordedBody(body []byte) recordedBody {
+	if utf8.Valid(body) {
+		return recordedBody{Body: string(body)}
+	}
+	return recordedBody{BodyBase64: body}
+}
+
+func (b *recordedBody) bytes() []byte {
+	if b.BodyBase64 != nil {
+		return b.BodyBase64
+	}
+	return []byte(b.Body)
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	recordedBody
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status-code"`
+	Header     http.Header `json:"header,omitempty"`
+	recordedBody
+}
+
+type storeRecord struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+func copyHeader(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	h2 := make(http.Header, len(h))
+	for k, v := range h {
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
+}
+
+func redactHeader(h http.Header) http.Header {
+	h = copyHeader(h)
+	for _, k := range redactedHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(k)]; ok {
+			h.Set(k, redacted)
+		}
+	}
+	return h
+}
+
+func redactValue(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range redactedFields {
+			if _, ok := v[k]; ok {
+				v[k] = redacted
+				changed = true
+			}
+		}
+		for _, vv := range v {
+			if redactValue(vv) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, vv := range v {
+			if redactValue(vv) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func redactBody(body []byte) []byte {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	if !redactValue(v) {
+		return body
+	}
+	redactedBody, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return redactedBody
+}
+
+// recordingTransport records the store requests and responses going
+// through it as numbered json files in a directory.
+type recordingTransport struct {
+	transport http.RoundTripper
+	dir       string
+}
+
+var recordSeq struct {
+	mu    sync.Mutex
+	byDir map[string]int
+}
+
+// nextRecordSeq returns the number of the next record in dir, carrying on
+// after the records already there.
+func nextRecordSeq(dir string) int {
+	recordSeq.mu.Lock()
+	defer recordSeq.mu.Unlock()
+	if recordSeq.byDir == nil {
+		recordSeq.byDir = make(map[string]int)
+	}
+	n, ok := recordSeq.byDir[dir]
+	if !ok {
+		names, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		for _, name := range names {
+			var i int
+			if _, err := fmt.Sscanf(filepath.Base(name), "%d.json", &i); err == nil && i > n {
+				n = i
+			}
+		}
+	}
+	n++
+	recordSeq.byDir[dir] = n
+	return n
+}
+
+// readBodyPrefix reads the body up to the recording limit. If the body is
+// larger, the read prefix is put back in front of the rest and the body
+// is reported as omitted.
+func readBodyPrefix(body io.ReadCloser) (rc io.ReadCloser, prefix []byte, omitted bool, err error) {
+	prefix, err = ioutil.ReadAll(io.LimitReader(body, int64(maxRecordedBodySize)+1))
+	if err != nil {
+		body.Close()
+		return nil, nil, false, err
+	}
+	if len(prefix) > maxRecordedBodySize {
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(prefix), body), body}, nil, true, nil
+	}
+	body.Close()
+	return ioutil.NopCloser(bytes.NewReader(prefix)), prefix, false, nil
+}
+
+func newRecordedBodyOrOmitted(body []byte, omitted bool) recordedBody {
+	if omitted {
+		return recordedBody{BodyOmitted: true}
+	}
+	return newRecordedBody(redactBody(body))
+}
+
+func (tr *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	reqBodyOmitted := false
+	if req.Body != nil {
+		var err error
+		req.Body, reqBody, reqBodyOmitted, err = readBodyPrefix(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rsp, err := tr.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &storeRecord{
+		Request: recordedRequest{
+			Method:       req.Method,
+			URL:          req.URL.String(),
+			Header:       redactHeader(req.Header),
+			recordedBody: newRecordedBodyOrOmitted(reqBody, reqBodyOmitted),
+		},
+		Response: recordedResponse{
+			StatusCode: rsp.StatusCode,
+			Header:     redactHeader(rsp.Header),
+		},
+	}
+	// the response body is recorded as it is read, so that large
+	// bodies are still streamed to the caller
+	rsp.Body = &recordingBody{
+		ReadCloser: rsp.Body,
+		tr:         tr,
+		rec:        rec,
+	}
+	return rsp, nil
+}
+
+// recordingBody collects a response body, up to the recording limit, as
+// it is read and writes the record once the body is read or closed.
+type recordingBody struct {
+	io.ReadCloser
+	tr  *recordingTransport
+	rec *storeRecord
+
+	buf     bytes.Buffer
+	omitted bool
+	eof     bool
+	once    sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.omitted {
+		if b.buf.Len()+n > maxRecordedBodySize {
+			b.omitted = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		b.eof = true
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	if !b.eof && !b.omitted {
+		// decoders can stop before the end of the body, record the
+		// rest of it if it is small enough
+		rest, err := ioutil.ReadAll(io.LimitReader(b.ReadCloser, int64(maxRecordedBodySize-b.buf.Len())+1))
+		if err == nil && b.buf.Len()+len(rest) <= maxRecordedBodySize {
+			b.buf.Write(rest)
+			b.eof = true
+		}
+	}
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() {
+		// a body that was not read to the end is not complete
+		b.rec.Response.recordedBody = newRecordedBodyOrOmitted(b.buf.Bytes(), b.omitted || !b.eof)
+		if err := b.tr.write(b.rec); err != nil {
+			logger.Noticef("cannot record store request: %v", err)
+		}
+	})
+}
+
+func (tr *recordingTransport) write(rec *storeRecord) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tr.dir, 0700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%06d.json", nextRecordSeq(tr.dir))
+	return osutil.AtomicWriteFile(filepath.Join(tr.dir, name), data, 0600, 0)
+}
+
+// replayingTransport serves the responses recorded in a directory,
+// matching requests by method and URL in recording order.
+type replayingTransport struct {
+	dir string
+
+	mu      sync.Mutex
+	loaded  bool
+	records []*storeRecord
+	used    []bool
+}
+
+func (tr *replayingTransport) load() error {
+	if tr.loaded {
+		return nil
+	}
+	names, err := filepath.Glob(filepath.Join(tr.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		var rec storeRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("cannot decode recorded store request %q: %v", name, err)
+		}
+		tr.records = append(tr.records, &rec)
+	}
+	tr.used = make([]bool, len(tr.records))
+	tr.loaded = true
+	return nil
+}
+
+func (tr *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if err := tr.load(); err != nil {
+		return nil, err
+	}
+
+	u := req.URL.String()
+	for i, rec := range tr.records {
+		if tr.used[i] || rec.Request.Method != req.Method || rec.Request.URL != u {
+			continue
+		}
+		tr.used[i] = true
+		if rec.Response.BodyOmitted {
+			return nil, fmt.Errorf("recorded store response for %s %s has no body", req.Method, u)
+		}
+		body := rec.Response.bytes()
+		header := copyHeader(rec.Response.Header)
+		// the body might have been redacted
+		header.Del("Content-Length")
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", rec.Response.StatusCode, http.StatusText(rec.Response.StatusCode)),
+			StatusCode:    rec.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded store response for %s %s", req.Method, u)
+}
+
+var replayTransports struct {
+	mu    sync.Mutex
+	byDir map[string]*replayingTransport
+}
+
+func replayTransportFor(dir string) *replayingTransport {
+	replayTransports.mu.Lock()
+	defer replayTransports.mu.Unlock()
+	if replayTransports.byDir == nil {
+		replayTransports.byDir = make(map[string]*replayingTransport)
+	}
+	// all the clients share the replay state, so that every recorded
+	// response is served once
+	tr := replayTransports.byDir[dir]
+	if tr == nil {
+		tr = &replayingTransport{dir: dir}
+		replayTransports.byDir[dir] = tr
+	}
+	return tr
+}
+
+// recordOrReplay wraps the transport of the given store client to
+// record or replay the store requests as requested via the environment.
+func recordOrReplay(cli *http.Client) *http.Client {
+	if dir := strings.TrimSpace(os.Getenv(storeReplayEnvKey)); dir != "" {
+		cli.Transport = replayTransportFor(dir)
+		return cli
+	}
+	if dir := strings.TrimSpace(os.Getenv(storeRecordEnvKey)); dir != "" {
+		cli.Transport = &recordingTransport{
+			transport: cli.Transport,
+			dir:       dir,
+		}
+	}
+	return cli
+}
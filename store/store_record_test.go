@@ -0,0 +1,204 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package store_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/store"
+)
+
+type storeRecordSuite struct {
+	baseStoreSuite
+}
+
+var _ = Suite(&storeRecordSuite{})
+
+func (s *storeRecordSuite) setEnv(c *C, key, value string) {
+	old, had := os.LookupEnv(key)
+	c.Assert(os.Setenv(key, value), IsNil)
+	s.AddCleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func (s *storeRecordSuite) TestRecordAndReplay(c *C) {
+	recordDir := filepath.Join(c.MkDir(), "record")
+
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "GET", sectionsPath)
+		w.Header().Set("Content-Type", "application/hal+json")
+		w.WriteHeader(200)
+		io.WriteString(w, MockSectionsJSON)
+		n++
+	}))
+	c.Assert(mockServer, NotNil)
+	serverURL, _ := url.Parse(mockServer.URL)
+	cfg := store.Config{
+		StoreBaseURL: serverURL,
+	}
+	dauthCtx := &testDauthContext{c: c, device: s.device}
+
+	s.setEnv(c, "SNAPD_STORE_RECORD", recordDir)
+	sto := store.New(&cfg, dauthCtx)
+	sections, err := sto.Sections(s.ctx, s.user)
+	c.Assert(err, IsNil)
+	c.Check(sections, DeepEquals, []string{"featured", "database"})
+	c.Check(n, Equals, 1)
+
+	mockServer.Close()
+
+	names, err := filepath.Glob(filepath.Join(recordDir, "*.json"))
+	c.Assert(err, IsNil)
+	c.Assert(names, DeepEquals, []string{filepath.Join(recordDir, "000001.json")})
+	data, err := ioutil.ReadFile(names[0])
+	c.Assert(err, IsNil)
+	var rec struct {
+		Request struct {
+			Method string      `json:"method"`
+			URL    string      `json:"url"`
+			Header http.Header `json:"header"`
+		} `json:"request"`
+		Response struct {
+			StatusCode int    `json:"status-code"`
+			Body       string `json:"body"`
+		} `json:"response"`
+	}
+	c.Assert(json.Unmarshal(data, &rec), IsNil)
+	c.Check(rec.Request.Method, Equals, "GET")
+	c.Check(rec.Request.URL, Equals, mockServer.URL+sectionsPath)
+	// credentials are not recorded
+	c.Check(rec.Request.Header.Get("Authorization"), Equals, "<redacted>")
+	c.Check(rec.Response.StatusCode, Equals, 200)
+	c.Check(rec.Response.Body, Equals, MockSectionsJSON)
+
+	// the recorded response is served back without the server
+	s.setEnv(c, "SNAPD_STORE_RECORD", "")
+	s.setEnv(c, "SNAPD_STORE_REPLAY", recordDir)
+	sto = store.New(&cfg, dauthCtx)
+	sections, err = sto.Sections(s.ctx, s.user)
+	c.Assert(err, IsNil)
+	c.Check(sections, DeepEquals, []string{"featured", "database"})
+	c.Check(n, Equals, 1)
+
+	// each recorded response is served once
+	_, err = sto.Sections(s.ctx, s.user)
+	c.Check(err, ErrorMatches, `.*no recorded store response for GET `+mockServer.URL+sectionsPath)
+}
+
+func (s *storeRecordSuite) TestRecordRedactsCredentialsInBodies(c *C) {
+	recordDir := filepath.Join(c.MkDir(), "record")
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		io.WriteString(w, `{"discharge_macaroon": "the-discharge", "nested": [{"macaroon": "the-nested-macaroon", "n": 12345678901234567890}]}`)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+	oldDischargeAPI := store.UbuntuoneDischargeAPI
+	store.UbuntuoneDischargeAPI = mockServer.URL + "/tokens/discharge"
+	defer func() { store.UbuntuoneDischargeAPI = oldDischargeAPI }()
+
+	s.setEnv(c, "SNAPD_STORE_RECORD", recordDir)
+	sto := store.New(&store.Config{}, nil)
+	discharge, err := store.DischargeAuthCaveat(sto.Client(), "caveat", "foo@example.com", "secret", "")
+	c.Assert(err, IsNil)
+	c.Check(discharge, Equals, "the-discharge")
+
+	data, err := ioutil.ReadFile(filepath.Join(recordDir, "000001.json"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Not(Matches), `(?s).*secret.*`)
+	c.Check(string(data), Not(Matches), `(?s).*the-discharge.*`)
+	// nested fields are redacted too
+	c.Check(string(data), Not(Matches), `(?s).*the-nested-macaroon.*`)
+	c.Check(string(data), Matches, `(?s).*12345678901234567890.*`)
+	c.Check(string(data), Matches, `(?s).*foo@example.com.*`)
+}
+
+func (s *storeRecordSuite) TestRecordStreamsLargeBodies(c *C) {
+	recordDir := filepath.Join(c.MkDir(), "record")
+	restore := store.MockMaxRecordedBodySize(10)
+	defer restore()
+
+	blob := strings.Repeat("x", 100)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		c.Check(err, IsNil)
+		c.Check(string(body), Equals, blob)
+		w.WriteHeader(200)
+		io.WriteString(w, blob)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	s.setEnv(c, "SNAPD_STORE_RECORD", recordDir)
+	sto := store.New(&store.Config{}, nil)
+	rsp, err := sto.Client().Post(mockServer.URL+"/download", "application/octet-stream", strings.NewReader(blob))
+	c.Assert(err, IsNil)
+	body, err := ioutil.ReadAll(rsp.Body)
+	c.Assert(err, IsNil)
+	c.Assert(rsp.Body.Close(), IsNil)
+	c.Check(string(body), Equals, blob)
+
+	data, err := ioutil.ReadFile(filepath.Join(recordDir, "000001.json"))
+	c.Assert(err, IsNil)
+	var rec struct {
+		Request struct {
+			Body        string `json:"body"`
+			BodyOmitted bool   `json:"body-omitted"`
+		} `json:"request"`
+		Response struct {
+			StatusCode  int    `json:"status-code"`
+			Body        string `json:"body"`
+			BodyOmitted bool   `json:"body-omitted"`
+		} `json:"response"`
+	}
+	c.Assert(json.Unmarshal(data, &rec), IsNil)
+	c.Check(rec.Request.Body, Equals, "")
+	c.Check(rec.Request.BodyOmitted, Equals, true)
+	c.Check(rec.Response.StatusCode, Equals, 200)
+	c.Check(rec.Response.Body, Equals, "")
+	c.Check(rec.Response.BodyOmitted, Equals, true)
+
+	// the response cannot be replayed
+	s.setEnv(c, "SNAPD_STORE_RECORD", "")
+	s.setEnv(c, "SNAPD_STORE_REPLAY", recordDir)
+	sto = store.New(&store.Config{}, nil)
+	_, err = sto.Client().Get(mockServer.URL + "/download")
+	c.Check(err, ErrorMatches, `.*no recorded store response for GET .*/download`)
+	_, err = sto.Client().Post(mockServer.URL+"/download", "application/octet-stream", strings.NewReader(blob))
+	c.Check(err, ErrorMatches, `.*recorded store response for POST .*/download has no body`)
+}
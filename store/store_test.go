@@ -2310,6 +2310,11 @@ const mockNamesJSON = `
         "apps": ["foo"],
         "package_name": "foo",
         "version": "1.0"
+      },
+      {
+        "summary": "content only",
+        "package_name": "qux",
+        "version": "3.0"
       }
     ]
   }
@@ -2366,7 +2371,7 @@ func (s *storeTestSuite) testSnapCommands(c *C, onClassic bool) {
 	err = sto.WriteCatalogs(s.ctx, &bufNames, db)
 	c.Assert(err, IsNil)
 	db.Commit()
-	c.Check(bufNames.String(), Equals, "bar\nfoo\n")
+	c.Check(bufNames.String(), Equals, "bar\nfoo\nqux\n")
 
 	dump, err := advisor.DumpCommands()
 	c.Assert(err, IsNil)
@@ -2377,6 +2382,15 @@ func (s *storeTestSuite) testSnapCommands(c *C, onClassic bool) {
 		"meh":     `[{"snap":"bar","version":"2.0"},{"snap":"foo","version":"1.0"}]`,
 	})
 	c.Check(n, Equals, 1)
+
+	// snaps without commands can be searched
+	pkgs, err := advisor.SearchPackages("content", false)
+	c.Assert(err, IsNil)
+	c.Check(pkgs, DeepEquals, []advisor.Package{{Snap: "qux", Version: "3.0", Summary: "content only"}})
+	// but are not offered as packages providing commands
+	pkg, err := advisor.FindPackage("qux")
+	c.Assert(err, IsNil)
+	c.Check(pkg, IsNil)
 }
 
 func (s *storeTestSuite) TestSnapCommandsTooMany(c *C) {
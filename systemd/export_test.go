@@ -24,7 +24,8 @@ import (
 )
 
 var (
-	Jctl = jctl
+	Jctl      = jctl
+	JctlAudit = jctlAudit
 )
 
 func MockOsGetenv(f func(string) string) func() {
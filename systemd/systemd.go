@@ -204,6 +204,39 @@ func MockJournalctl(f func(svcs []string, n int, follow bool) (io.ReadCloser, er
 	}
 }
 
+// jctlAudit calls journalctl to get the last n JSON kernel and audit
+// messages, only the ones after the given cursor if there is one, and to
+// keep getting the new ones when following.
+var jctlAudit = func(cursor string, n int, follow bool) (io.ReadCloser, error) {
+	args := []string{"-o", "json", "--no-pager", "-n", strconv.Itoa(n)}
+	if cursor != "" {
+		args = append(args, "--after-cursor", cursor)
+	}
+	if follow {
+		args = append(args, "-f")
+	}
+	// matches of the same field are OR-ed
+	args = append(args, "_TRANSPORT=kernel", "_TRANSPORT=audit")
+
+	return osutilStreamCommand("journalctl", args...)
+}
+
+func MockJournalctlAudit(f func(cursor string, n int, follow bool) (io.ReadCloser, error)) func() {
+	oldJctlAudit := jctlAudit
+	jctlAudit = f
+	return func() {
+		jctlAudit = oldJctlAudit
+	}
+}
+
+// AuditLogReader returns a reader for the last n JSON kernel and audit
+// messages of the journal, only the ones after the given cursor if there is
+// one. When following, the new messages are read as they are logged until
+// the reader is closed.
+func AuditLogReader(cursor string, n int, follow bool) (io.ReadCloser, error) {
+	return jctlAudit(cursor, n, follow)
+}
+
 // Systemd exposes a minimal interface to manage systemd via the systemctl command.
 type Systemd interface {
 	// DaemonReload reloads systemd's configuration.
@@ -1003,6 +1036,18 @@ func (l Log) Message() string {
 	return msg
 }
 
+// Cursor is the journal cursor of the Log, to read the entries after it, if
+// any; otherwise, "".
+func (l Log) Cursor() string {
+	cursor, err := l.parseLogRawMessageString("__CURSOR", func([]string) (string, error) {
+		return "", errors.New("multiple cursors not supported")
+	})
+	if err != nil {
+		return ""
+	}
+	return cursor
+}
+
 // SID is the syslog identifier of the Log, if any; otherwise, "-".
 func (l Log) SID() string {
 	// if there are multiple SYSLOG_IDENTIFIER values, just act like there was
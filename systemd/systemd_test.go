@@ -710,6 +710,12 @@ func (s *SystemdTestSuite) TestLogPID(c *C) {
 	}.PID(), Equals, "42")
 }
 
+func (s *SystemdTestSuite) TestLogCursor(c *C) {
+	c.Check(Log{}.Cursor(), Equals, "")
+	c.Check(Log{"__CURSOR": mustJSONMarshal("s=1;i=2")}.Cursor(), Equals, "s=1;i=2")
+	c.Check(Log{"__CURSOR": mustJSONMarshal([]string{"a", "b"})}.Cursor(), Equals, "")
+}
+
 func (s *SystemdTestSuite) TestTime(c *C) {
 	t, err := Log{}.Time()
 	c.Check(t.IsZero(), Equals, true)
@@ -955,6 +961,26 @@ func (s *SystemdTestSuite) TestJctl(c *C) {
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "-u", "foo", "-u", "bar"})
 }
 
+func (s *SystemdTestSuite) TestJctlAudit(c *C) {
+	var args []string
+	restore := MockOsutilStreamCommand(func(name string, myargs ...string) (io.ReadCloser, error) {
+		c.Check(name, Equals, "journalctl")
+		args = myargs
+		return nil, nil
+	})
+	defer restore()
+
+	_, err := JctlAudit("", 100, false)
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "100", "_TRANSPORT=kernel", "_TRANSPORT=audit"})
+	_, err = JctlAudit("s=1;i=2", 100, false)
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "100", "--after-cursor", "s=1;i=2", "_TRANSPORT=kernel", "_TRANSPORT=audit"})
+	_, err = JctlAudit("s=1;i=2", 100, true)
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "100", "--after-cursor", "s=1;i=2", "-f", "_TRANSPORT=kernel", "_TRANSPORT=audit"})
+}
+
 func (s *SystemdTestSuite) TestIsActiveUnderRoot(c *C) {
 	sysErr := &Error{}
 	// manpage states that systemctl returns exit code 3 for inactive
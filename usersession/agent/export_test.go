@@ -22,12 +22,16 @@ package agent
 import (
 	"syscall"
 	"time"
+
+	"github.com/snapcore/snapd/client"
 )
 
 var (
 	SessionInfoCmd                = sessionInfoCmd
 	ServiceControlCmd             = serviceControlCmd
 	PendingRefreshNotificationCmd = pendingRefreshNotificationCmd
+	FinishRefreshNotificationCmd  = finishRefreshNotificationCmd
+	PromptNotificationCmd         = promptNotificationCmd
 )
 
 func MockStopTimeouts(stop, kill time.Duration) (restore func()) {
@@ -59,3 +63,24 @@ func MockNoBus(agent *SessionAgent) (restore func()) {
 		agent.bus = bus
 	}
 }
+
+func MockRefreshSnapNow(f func(instanceName string) error) (restore func()) {
+	old := refreshSnapNow
+	refreshSnapNow = f
+	return func() {
+		refreshSnapNow = old
+	}
+}
+
+func MockReplyToPrompt(f func(id string, reply *client.PromptReply) error) (restore func()) {
+	old := replyToPrompt
+	replyToPrompt = f
+	return func() {
+		replyToPrompt = old
+	}
+}
+
+// PendingNotifications returns the number of tracked notifications with actions
+func PendingNotifications(agent *SessionAgent) int {
+	return agent.notifications.count()
+}
@@ -0,0 +1,135 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/snapcore/snapd/desktop/notification"
+	"github.com/snapcore/snapd/logger"
+)
+
+// pendingNotification is a notification offering actions to the user.
+type pendingNotification struct {
+	// instanceName is the snap a pending refresh notification is about.
+	instanceName string
+	// handleAction is called with the action invoked by the user.
+	handleAction func(actionKey string)
+	// handleClosed is called when the notification is closed without
+	// any action being invoked, it may be nil.
+	handleClosed func()
+}
+
+// actionNotifications keeps track of the pending notifications that offer
+// actions to the user.
+type actionNotifications struct {
+	mu      sync.Mutex
+	pending map[notification.ID]*pendingNotification
+	// stopObserving stops observing the notifications and waits for
+	// it, it is nil while not observing
+	stopObserving func()
+}
+
+func (an *actionNotifications) count() int {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	return len(an.pending)
+}
+
+// forget stops tracking the given notification and returns it.
+func (an *actionNotifications) forget(id notification.ID) (pn *pendingNotification, ok bool) {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	pn, ok = an.pending[id]
+	delete(an.pending, id)
+	return pn, ok
+}
+
+// actionNotificationObserver reacts to the actions of the pending
+// notifications.
+type actionNotificationObserver struct {
+	s *SessionAgent
+}
+
+func (o actionNotificationObserver) NotificationClosed(id notification.ID, reason notification.CloseReason) error {
+	pn, ok := o.s.notifications.forget(id)
+	if ok && pn.handleClosed != nil {
+		pn.handleClosed()
+	}
+	return nil
+}
+
+func (o actionNotificationObserver) ActionInvoked(id notification.ID, actionKey string) error {
+	pn, ok := o.s.notifications.forget(id)
+	if ok {
+		pn.handleAction(actionKey)
+	}
+	return nil
+}
+
+// trackNotification remembers the given pending notification so that its
+// actions can be handled, observing the notifications as needed. The
+// session agent does not exit on idle while such notifications are
+// pending.
+func (s *SessionAgent) trackNotification(srv *notification.Server, id notification.ID, pn *pendingNotification) {
+	an := &s.notifications
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	if an.pending == nil {
+		an.pending = make(map[notification.ID]*pendingNotification)
+	}
+	an.pending[id] = pn
+	if an.stopObserving != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	an.stopObserving = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		err := srv.ObserveNotifications(ctx, actionNotificationObserver{s: s})
+		if err != nil && err != context.Canceled {
+			logger.Noticef("Cannot observe notifications: %v", err)
+		}
+		an.mu.Lock()
+		defer an.mu.Unlock()
+		// the actions cannot be handled anymore
+		an.pending = nil
+		an.stopObserving = nil
+	}()
+}
+
+// stopObservingNotifications stops observing the notifications, it must
+// be called before closing the session bus connection.
+func (s *SessionAgent) stopObservingNotifications() {
+	an := &s.notifications
+	an.mu.Lock()
+	stop := an.stopObserving
+	an.stopObserving = nil
+	an.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
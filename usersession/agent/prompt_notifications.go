@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package agent
+
+import (
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/desktop/notification"
+	"github.com/snapcore/snapd/logger"
+)
+
+const (
+	// allowOnceAction allows the access for a short time.
+	allowOnceAction = "allow-once"
+	// allowAlwaysAction allows the access until the rule is revoked.
+	allowAlwaysAction = "allow-always"
+	// denyAlwaysAction denies the access until the rule is revoked.
+	denyAlwaysAction = "deny-always"
+)
+
+// promptInfo holds information about a prompt provided by snapd.
+type promptInfo struct {
+	ID          string   `json:"id"`
+	Snap        string   `json:"snap"`
+	Path        string   `json:"path"`
+	Permissions []string `json:"permissions"`
+}
+
+// replyToPrompt sends the decision of the user about the given prompt to
+// snapd.
+var replyToPrompt = func(id string, reply *client.PromptReply) error {
+	cli := client.New(nil)
+	_, err := cli.ReplyToPrompt(id, reply)
+	return err
+}
+
+// trackPromptNotification remembers the given prompt notification so that
+// the decision of the user can be sent to snapd. Dismissing the
+// notification denies the access once.
+func (s *SessionAgent) trackPromptNotification(srv *notification.Server, id notification.ID, prompt *promptInfo) {
+	reply := func(outcome, lifespan string) {
+		// do not block processing of further signals while
+		// talking to snapd
+		go func() {
+			err := replyToPrompt(prompt.ID, &client.PromptReply{
+				Outcome:     outcome,
+				Lifespan:    lifespan,
+				PathPattern: prompt.Path,
+				Permissions: prompt.Permissions,
+			})
+			if err != nil {
+				logger.Noticef("Cannot reply to prompt %s: %v", prompt.ID, err)
+			}
+		}()
+	}
+	s.trackNotification(srv, id, &pendingNotification{
+		handleAction: func(actionKey string) {
+			switch actionKey {
+			case allowOnceAction:
+				reply("allow", "once")
+			case allowAlwaysAction:
+				reply("allow", "always")
+			case denyAlwaysAction:
+				reply("deny", "always")
+			default:
+				reply("deny", "once")
+			}
+		},
+		handleClosed: func() {
+			reply("deny", "once")
+		},
+	})
+}
@@ -0,0 +1,87 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package agent
+
+import (
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/desktop/notification"
+	"github.com/snapcore/snapd/logger"
+)
+
+const (
+	// updateNowAction refreshes the snap right away.
+	updateNowAction = "update-now"
+	// laterAction leaves the refresh to happen once the apps are closed.
+	laterAction = "later"
+)
+
+// refreshSnapNow asks snapd to refresh the given snap right away. The
+// running apps of the snap keep using the current revision until they
+// are restarted.
+var refreshSnapNow = func(instanceName string) error {
+	cli := client.New(&client.Config{Interactive: true})
+	_, err := cli.Refresh(instanceName, &client.SnapOptions{IgnoreRunning: true})
+	return err
+}
+
+// trackRefreshNotification remembers the given pending refresh
+// notification so that its actions can be handled.
+func (s *SessionAgent) trackRefreshNotification(srv *notification.Server, id notification.ID, instanceName string) {
+	s.trackNotification(srv, id, &pendingNotification{
+		instanceName: instanceName,
+		handleAction: func(actionKey string) {
+			switch actionKey {
+			case updateNowAction:
+				// talking to snapd may need the user to
+				// authenticate, do not block processing of
+				// further signals meanwhile
+				go func() {
+					if err := refreshSnapNow(instanceName); err != nil {
+						logger.Noticef("Cannot refresh snap %q: %v", instanceName, err)
+					}
+				}()
+			case laterAction:
+				// nothing to do, snapd refreshes the snap once
+				// its apps are closed
+			}
+		},
+	})
+}
+
+// closeRefreshNotifications closes the pending refresh notifications of
+// the given snap.
+func (s *SessionAgent) closeRefreshNotifications(srv *notification.Server, instanceName string) {
+	an := &s.notifications
+	an.mu.Lock()
+	var ids []notification.ID
+	for id, pn := range an.pending {
+		if pn.instanceName == instanceName {
+			ids = append(ids, id)
+			delete(an.pending, id)
+		}
+	}
+	an.mu.Unlock()
+
+	for _, id := range ids {
+		if err := srv.CloseNotification(id); err != nil {
+			logger.Noticef("Cannot close notification %v: %v", id, err)
+		}
+	}
+}
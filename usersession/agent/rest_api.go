@@ -43,6 +43,8 @@ var restApi = []*Command{
 	sessionInfoCmd,
 	serviceControlCmd,
 	pendingRefreshNotificationCmd,
+	finishRefreshNotificationCmd,
+	promptNotificationCmd,
 }
 
 var (
@@ -65,6 +67,16 @@ var (
 		Path: "/v1/notifications/pending-refresh",
 		POST: postPendingRefreshNotification,
 	}
+
+	finishRefreshNotificationCmd = &Command{
+		Path: "/v1/notifications/finish-refresh",
+		POST: postRefreshFinishedNotification,
+	}
+
+	promptNotificationCmd = &Command{
+		Path: "/v1/notifications/prompt",
+		POST: postPromptNotification,
+	}
 )
 
 func sessionInfo(c *Command, r *http.Request) Response {
@@ -211,7 +223,9 @@ func postServiceControl(c *Command, r *http.Request) Response {
 	return impl(&inst, sysd)
 }
 
-func postPendingRefreshNotification(c *Command, r *http.Request) Response {
+// validateJSONRequest returns an error response unless the request
+// carries a JSON body.
+func validateJSONRequest(r *http.Request) Response {
 	contentType := r.Header.Get("Content-Type")
 	mediaType, params, err := mime.ParseMediaType(contentType)
 	if err != nil {
@@ -226,6 +240,13 @@ func postPendingRefreshNotification(c *Command, r *http.Request) Response {
 	if charset != "" && charset != "UTF-8" {
 		return BadRequest("unknown charset in content type: %s", contentType)
 	}
+	return nil
+}
+
+func postPendingRefreshNotification(c *Command, r *http.Request) Response {
+	if rsp := validateJSONRequest(r); rsp != nil {
+		return rsp
+	}
 
 	decoder := json.NewDecoder(r.Body)
 
@@ -235,6 +256,7 @@ func postPendingRefreshNotification(c *Command, r *http.Request) Response {
 		TimeRemaining       time.Duration `json:"time-remaining,omitempty"`
 		BusyAppName         string        `json:"busy-app-name,omitempty"`
 		BusyAppDesktopEntry string        `json:"busy-app-desktop-entry,omitempty"`
+		Downloaded          bool          `json:"downloaded,omitempty"`
 	}
 	var refreshInfo pendingSnapRefreshInfo
 	if err := decoder.Decode(&refreshInfo); err != nil {
@@ -298,9 +320,74 @@ func postPendingRefreshNotification(c *Command, r *http.Request) Response {
 		Body:    body,
 		Hints:   hints,
 	}
+	// The update was downloaded already and is applied once the app is
+	// closed, let the user decide to update right away instead.
+	withActions := refreshInfo.Downloaded && refreshInfo.TimeRemaining > 0
+	if withActions {
+		msg.Actions = []notification.Action{
+			{ActionKey: updateNowAction, LocalizedText: i18n.G("Update now")},
+			{ActionKey: laterAction, LocalizedText: i18n.G("Later")},
+		}
+	}
 
 	// TODO: silently ignore error returned when the notification server does not exist.
-	// TODO: track returned notification ID and respond to actions, if supported.
+	id, err := notifySrv.SendNotification(msg)
+	if err != nil {
+		return SyncResponse(&resp{
+			Type:   ResponseTypeError,
+			Status: 500,
+			Result: &errorResult{
+				Message: fmt.Sprintf("cannot send notification message: %v", err),
+			},
+		})
+	}
+	if withActions {
+		c.s.trackRefreshNotification(notifySrv, id, refreshInfo.InstanceName)
+	}
+	return SyncResponse(nil)
+}
+
+func postRefreshFinishedNotification(c *Command, r *http.Request) Response {
+	if rsp := validateJSONRequest(r); rsp != nil {
+		return rsp
+	}
+
+	decoder := json.NewDecoder(r.Body)
+
+	// finishedSnapRefreshInfo holds information about a finished snap refresh provided by snapd.
+	type finishedSnapRefreshInfo struct {
+		InstanceName string `json:"instance-name"`
+	}
+	var finishRefresh finishedSnapRefreshInfo
+	if err := decoder.Decode(&finishRefresh); err != nil {
+		return BadRequest("cannot decode request body into finish refresh notification info: %v", err)
+	}
+
+	// Note that since the connection is shared, we are not closing it.
+	if c.s.bus == nil {
+		return SyncResponse(&resp{
+			Type:   ResponseTypeError,
+			Status: 500,
+			Result: &errorResult{
+				Message: fmt.Sprintf("cannot connect to the session bus"),
+			},
+		})
+	}
+
+	notifySrv := notification.New(c.s.bus)
+	// the pending refresh notifications of the snap are obsolete now
+	c.s.closeRefreshNotifications(notifySrv, finishRefresh.InstanceName)
+
+	msg := &notification.Message{
+		AppName: finishRefresh.InstanceName,
+		Summary: fmt.Sprintf(i18n.G("Snap %q has been refreshed"), finishRefresh.InstanceName),
+		Body:    i18n.G("Now available to launch"),
+		Hints: []notification.Hint{
+			notification.WithUrgency(notification.LowUrgency),
+			// The notification is provided by snapd session agent.
+			notification.WithDesktopEntry("io.snapcraft.SessionAgent"),
+		},
+	}
 	if _, err := notifySrv.SendNotification(msg); err != nil {
 		return SyncResponse(&resp{
 			Type:   ResponseTypeError,
@@ -312,3 +399,66 @@ func postPendingRefreshNotification(c *Command, r *http.Request) Response {
 	}
 	return SyncResponse(nil)
 }
+
+func postPromptNotification(c *Command, r *http.Request) Response {
+	if rsp := validateJSONRequest(r); rsp != nil {
+		return rsp
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var prompt promptInfo
+	if err := decoder.Decode(&prompt); err != nil {
+		return BadRequest("cannot decode request body into prompt info: %v", err)
+	}
+
+	// Note that since the connection is shared, we are not closing it.
+	if c.s.bus == nil {
+		return SyncResponse(&resp{
+			Type:   ResponseTypeError,
+			Status: 500,
+			Result: &errorResult{
+				Message: fmt.Sprintf("cannot connect to the session bus"),
+			},
+		})
+	}
+
+	// The prompt follows an access that was denied already, the decision
+	// of the user applies when the snap tries again.
+	notifySrv := notification.New(c.s.bus)
+	var summary string
+	switch strings.Join(prompt.Permissions, ",") {
+	case "read":
+		summary = fmt.Sprintf(i18n.G("Snap %q was denied reading %s"), prompt.Snap, prompt.Path)
+	case "write":
+		summary = fmt.Sprintf(i18n.G("Snap %q was denied writing %s"), prompt.Snap, prompt.Path)
+	default:
+		summary = fmt.Sprintf(i18n.G("Snap %q was denied reading and writing %s"), prompt.Snap, prompt.Path)
+	}
+	msg := &notification.Message{
+		AppName: prompt.Snap,
+		Summary: summary,
+		Body:    i18n.G("Allowing applies when the snap tries again"),
+		Hints: []notification.Hint{
+			notification.WithUrgency(notification.CriticalUrgency),
+			// The notification is provided by snapd session agent.
+			notification.WithDesktopEntry("io.snapcraft.SessionAgent"),
+		},
+		Actions: []notification.Action{
+			{ActionKey: allowOnceAction, LocalizedText: i18n.G("Allow once")},
+			{ActionKey: allowAlwaysAction, LocalizedText: i18n.G("Always allow")},
+			{ActionKey: denyAlwaysAction, LocalizedText: i18n.G("Always deny")},
+		},
+	}
+	id, err := notifySrv.SendNotification(msg)
+	if err != nil {
+		return SyncResponse(&resp{
+			Type:   ResponseTypeError,
+			Status: 500,
+			Result: &errorResult{
+				Message: fmt.Sprintf("cannot send notification message: %v", err),
+			},
+		})
+	}
+	c.s.trackPromptNotification(notifySrv, id, &prompt)
+	return SyncResponse(nil)
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/godbus/dbus"
 	. "gopkg.in/check.v1"
 
+	snapdclient "github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/desktop/notification"
 	"github.com/snapcore/snapd/desktop/notification/notificationtest"
 	"github.com/snapcore/snapd/dirs"
@@ -596,3 +597,295 @@ func (s *restSuite) TestPostPendingRefreshNotificationNotificationServerFailure(
 	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
 	c.Check(rsp.Result, DeepEquals, map[string]interface{}{"message": "cannot send notification message: org.freedesktop.DBus.Error.Failed"})
 }
+
+func (s *restSuite) TestPostPendingRefreshNotificationDownloadedActions(c *C) {
+	refreshed := make(chan string, 1)
+	restore := agent.MockRefreshSnapNow(func(instanceName string) error {
+		refreshed <- instanceName
+		return nil
+	})
+	defer restore()
+
+	refreshInfo := &client.PendingSnapRefreshInfo{
+		InstanceName:  "pkg",
+		TimeRemaining: time.Hour * 72,
+		Downloaded:    true,
+	}
+	s.testPostPendingRefreshNotificationBody(c, refreshInfo)
+	notifications := s.notify.GetAll()
+	c.Assert(notifications, HasLen, 1)
+	n := notifications[0]
+	c.Check(n.Summary, Equals, `Pending update of "pkg" snap`)
+	c.Check(n.Actions, DeepEquals, []string{"update-now", "Update now", "later", "Later"})
+	c.Check(agent.PendingNotifications(s.agent), Equals, 1)
+
+	// the signal is only seen once the agent observes the notifications
+	var name string
+	for i := 0; i < 100 && name == ""; i++ {
+		c.Assert(s.notify.InvokeAction(n.ID, "update-now"), IsNil)
+		select {
+		case name = <-refreshed:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	c.Check(name, Equals, "pkg")
+	c.Check(agent.PendingNotifications(s.agent), Equals, 0)
+}
+
+func (s *restSuite) TestPostPendingRefreshNotificationLater(c *C) {
+	restore := agent.MockRefreshSnapNow(func(instanceName string) error {
+		c.Errorf("unexpected refresh of %q", instanceName)
+		return nil
+	})
+	defer restore()
+
+	refreshInfo := &client.PendingSnapRefreshInfo{
+		InstanceName:  "pkg",
+		TimeRemaining: time.Hour,
+		Downloaded:    true,
+	}
+	s.testPostPendingRefreshNotificationBody(c, refreshInfo)
+	notifications := s.notify.GetAll()
+	c.Assert(notifications, HasLen, 1)
+
+	for i := 0; i < 100 && agent.PendingNotifications(s.agent) > 0; i++ {
+		c.Assert(s.notify.InvokeAction(notifications[0].ID, "later"), IsNil)
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.Check(agent.PendingNotifications(s.agent), Equals, 0)
+}
+
+func (s *restSuite) TestPostPendingRefreshNotificationNoActionsWhenNotDownloaded(c *C) {
+	refreshInfo := &client.PendingSnapRefreshInfo{
+		InstanceName:  "pkg",
+		TimeRemaining: time.Hour * 72,
+	}
+	s.testPostPendingRefreshNotificationBody(c, refreshInfo)
+	notifications := s.notify.GetAll()
+	c.Assert(notifications, HasLen, 1)
+	c.Check(notifications[0].Actions, DeepEquals, []string{})
+	c.Check(agent.PendingNotifications(s.agent), Equals, 0)
+}
+
+func (s *restSuite) testPostFinishRefreshNotificationBody(c *C, refreshInfo *client.FinishedSnapRefreshInfo) {
+	reqBody, err := json.Marshal(refreshInfo)
+	c.Assert(err, IsNil)
+	req := httptest.NewRequest("POST", "/v1/notifications/finish-refresh", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.FinishRefreshNotificationCmd.POST(agent.FinishRefreshNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+	c.Check(rec.HeaderMap.Get("Content-Type"), Equals, "application/json")
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, IsNil)
+}
+
+func (s *restSuite) TestPostFinishRefreshNotification(c *C) {
+	// a pending notification with actions for the snap is closed
+	s.testPostPendingRefreshNotificationBody(c, &client.PendingSnapRefreshInfo{
+		InstanceName:  "pkg",
+		TimeRemaining: time.Hour,
+		Downloaded:    true,
+	})
+	c.Assert(s.notify.GetAll(), HasLen, 1)
+	c.Check(agent.PendingNotifications(s.agent), Equals, 1)
+
+	s.testPostFinishRefreshNotificationBody(c, &client.FinishedSnapRefreshInfo{InstanceName: "pkg"})
+	c.Check(agent.PendingNotifications(s.agent), Equals, 0)
+
+	notifications := s.notify.GetAll()
+	c.Assert(notifications, HasLen, 1)
+	n := notifications[0]
+	c.Check(n.AppName, Equals, "pkg")
+	c.Check(n.Summary, Equals, `Snap "pkg" has been refreshed`)
+	c.Check(n.Body, Equals, "Now available to launch")
+	c.Check(n.Actions, DeepEquals, []string{})
+	c.Check(n.Hints, DeepEquals, map[string]dbus.Variant{
+		"urgency":       dbus.MakeVariant(byte(notification.LowUrgency)),
+		"desktop-entry": dbus.MakeVariant("io.snapcraft.SessionAgent"),
+	})
+}
+
+func (s *restSuite) TestPostFinishRefreshNotificationErrors(c *C) {
+	req := httptest.NewRequest("POST", "/v1/notifications/finish-refresh", bytes.NewBufferString(""))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	agent.FinishRefreshNotificationCmd.POST(agent.FinishRefreshNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{"message": "unknown content type: text/plain"})
+
+	req = httptest.NewRequest("POST", "/v1/notifications/finish-refresh", bytes.NewBufferString(`{"instance-name":syntaxerror}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	agent.FinishRefreshNotificationCmd.POST(agent.FinishRefreshNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{"message": "cannot decode request body into finish refresh notification info: invalid character 's' looking for beginning of value"})
+
+	restore := agent.MockNoBus(s.agent)
+	defer restore()
+	req = httptest.NewRequest("POST", "/v1/notifications/finish-refresh", bytes.NewBufferString(`{"instance-name":"pkg"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	agent.FinishRefreshNotificationCmd.POST(agent.FinishRefreshNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{"message": "cannot connect to the session bus"})
+}
+
+func (s *restSuite) testPostPromptNotification(c *C, prompt *client.PromptInfo) *notificationtest.FdoNotification {
+	reqBody, err := json.Marshal(prompt)
+	c.Assert(err, IsNil)
+	req := httptest.NewRequest("POST", "/v1/notifications/prompt", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.PromptNotificationCmd.POST(agent.PromptNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+
+	notifications := s.notify.GetAll()
+	c.Assert(notifications, HasLen, 1)
+	return notifications[0]
+}
+
+func (s *restSuite) TestPostPromptNotification(c *C) {
+	replies := make(chan *clientPromptReply, 1)
+	restore := agent.MockReplyToPrompt(func(id string, reply *snapdclient.PromptReply) error {
+		replies <- &clientPromptReply{id, reply}
+		return nil
+	})
+	defer restore()
+
+	n := s.testPostPromptNotification(c, &client.PromptInfo{
+		ID:          "1",
+		Snap:        "pkg",
+		Path:        "/home/alice/doc.txt",
+		Permissions: []string{"read"},
+	})
+	c.Check(n.AppName, Equals, "pkg")
+	c.Check(n.Summary, Equals, `Snap "pkg" was denied reading /home/alice/doc.txt`)
+	c.Check(n.Body, Equals, "Allowing applies when the snap tries again")
+	c.Check(n.Actions, DeepEquals, []string{"allow-once", "Allow once", "allow-always", "Always allow", "deny-always", "Always deny"})
+	c.Check(n.Hints, DeepEquals, map[string]dbus.Variant{
+		"urgency":       dbus.MakeVariant(byte(notification.CriticalUrgency)),
+		"desktop-entry": dbus.MakeVariant("io.snapcraft.SessionAgent"),
+	})
+	c.Check(agent.PendingNotifications(s.agent), Equals, 1)
+
+	// the signal is only seen once the agent observes the notifications
+	var reply *clientPromptReply
+	for i := 0; i < 100 && reply == nil; i++ {
+		c.Assert(s.notify.InvokeAction(n.ID, "allow-always"), IsNil)
+		select {
+		case reply = <-replies:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	c.Assert(reply, NotNil)
+	c.Check(reply.id, Equals, "1")
+	c.Check(reply.reply, DeepEquals, &snapdclient.PromptReply{
+		Outcome:     "allow",
+		Lifespan:    "always",
+		PathPattern: "/home/alice/doc.txt",
+		Permissions: []string{"read"},
+	})
+	c.Check(agent.PendingNotifications(s.agent), Equals, 0)
+}
+
+func (s *restSuite) TestPostPromptNotificationDismissed(c *C) {
+	replies := make(chan *clientPromptReply, 1)
+	restore := agent.MockReplyToPrompt(func(id string, reply *snapdclient.PromptReply) error {
+		replies <- &clientPromptReply{id, reply}
+		return nil
+	})
+	defer restore()
+
+	n := s.testPostPromptNotification(c, &client.PromptInfo{
+		ID:          "1",
+		Snap:        "pkg",
+		Path:        "/home/alice/doc.txt",
+		Permissions: []string{"read", "write"},
+	})
+	c.Check(n.Summary, Equals, `Snap "pkg" was denied reading and writing /home/alice/doc.txt`)
+
+	// wait for the agent to observe the notifications
+	var reply *clientPromptReply
+	for i := 0; i < 100 && reply == nil; i++ {
+		c.Assert(s.notify.InvokeAction(n.ID, "allow-once"), IsNil)
+		select {
+		case reply = <-replies:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	c.Assert(reply, NotNil)
+	c.Check(reply.reply.Outcome, Equals, "allow")
+	c.Check(reply.reply.Lifespan, Equals, "once")
+	c.Assert(s.notify.Close(n.ID, uint32(notification.CloseReasonClosed)), IsNil)
+
+	n = s.testPostPromptNotification(c, &client.PromptInfo{
+		ID:          "2",
+		Snap:        "pkg",
+		Path:        "/home/alice/other.txt",
+		Permissions: []string{"write"},
+	})
+	c.Check(n.Summary, Equals, `Snap "pkg" was denied writing /home/alice/other.txt`)
+	// the notification is dismissed by the user
+	c.Assert(s.notify.Close(n.ID, uint32(notification.CloseReasonDismissed)), IsNil)
+	select {
+	case reply = <-replies:
+	case <-time.After(5 * time.Second):
+		c.Fatal("no reply to the dismissed prompt")
+	}
+	c.Check(reply.id, Equals, "2")
+	c.Check(reply.reply, DeepEquals, &snapdclient.PromptReply{
+		Outcome:     "deny",
+		Lifespan:    "once",
+		PathPattern: "/home/alice/other.txt",
+		Permissions: []string{"write"},
+	})
+	c.Check(agent.PendingNotifications(s.agent), Equals, 0)
+}
+
+type clientPromptReply struct {
+	id    string
+	reply *snapdclient.PromptReply
+}
+
+func (s *restSuite) TestPostPromptNotificationErrors(c *C) {
+	req := httptest.NewRequest("POST", "/v1/notifications/prompt", bytes.NewBufferString(`{"id":syntaxerror}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.PromptNotificationCmd.POST(agent.PromptNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{"message": "cannot decode request body into prompt info: invalid character 's' looking for beginning of value"})
+
+	s.notify.SetError(&dbus.Error{Name: "org.freedesktop.DBus.Error.Failed"})
+	req = httptest.NewRequest("POST", "/v1/notifications/prompt", bytes.NewBufferString(`{"id":"1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	agent.PromptNotificationCmd.POST(agent.PromptNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{"message": "cannot send notification message: org.freedesktop.DBus.Error.Failed"})
+	c.Check(agent.PendingNotifications(s.agent), Equals, 0)
+
+	restore := agent.MockNoBus(s.agent)
+	defer restore()
+	req = httptest.NewRequest("POST", "/v1/notifications/prompt", bytes.NewBufferString(`{"id":"1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	agent.PromptNotificationCmd.POST(agent.PromptNotificationCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 500)
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{"message": "cannot connect to the session bus"})
+}
@@ -51,6 +51,8 @@ type SessionAgent struct {
 
 	idle        *idleTracker
 	IdleTimeout time.Duration
+
+	notifications actionNotifications
 }
 
 const sessionAgentBusName = "io.snapcraft.SessionAgent"
@@ -264,6 +266,7 @@ func (s *SessionAgent) shutdownServerOnKill() error {
 	// Historically We do something similar in the main daemon
 	// logic as well.
 	s.listener.Close()
+	s.stopObservingNotifications()
 	s.bus.Close()
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
@@ -280,7 +283,11 @@ Loop:
 		case <-timer.C:
 			// Have we been idle
 			idleDuration := s.idle.idleDuration()
-			if idleDuration >= s.IdleTimeout {
+			if s.notifications.count() > 0 {
+				// keep handling the actions of the
+				// notifications
+				timer.Reset(s.IdleTimeout)
+			} else if idleDuration >= s.IdleTimeout {
 				s.tomb.Kill(nil)
 				break Loop
 			} else {
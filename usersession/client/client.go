@@ -116,43 +116,46 @@ func (client *Client) doMany(ctx context.Context, method, urlpath string, query
 				// (i.e. /run/user/NNNN).
 				return
 			}
-			response := response{uid: uid}
-			defer func() {
-				mu.Lock()
-				defer mu.Unlock()
-				responses = append(responses, &response)
-			}()
-
-			u := url.URL{
-				Scheme:   "http",
-				Host:     uidStr,
-				Path:     urlpath,
-				RawQuery: query.Encode(),
-			}
-			req, err := http.NewRequest(method, u.String(), bytes.NewBuffer(body))
-			if err != nil {
-				response.err = fmt.Errorf("internal error: %v", err)
-				return
-			}
-			req = req.WithContext(ctx)
-			for key, value := range headers {
-				req.Header.Set(key, value)
-			}
-			httpResp, err := client.doer.Do(req)
-			if err != nil {
-				response.err = err
-				return
-			}
-			defer httpResp.Body.Close()
-			response.statusCode = httpResp.StatusCode
-			response.err = decodeInto(httpResp.Body, &response)
-			response.checkError()
+			response := client.doOne(ctx, uid, method, urlpath, query, headers, body)
+			mu.Lock()
+			defer mu.Unlock()
+			responses = append(responses, response)
 		}(socket)
 	}
 	wg.Wait()
 	return responses, nil
 }
 
+// doOne sends a request to the session agent of the given user.
+func (client *Client) doOne(ctx context.Context, uid int, method, urlpath string, query url.Values, headers map[string]string, body []byte) *response {
+	response := &response{uid: uid}
+	u := url.URL{
+		Scheme:   "http",
+		Host:     strconv.Itoa(uid),
+		Path:     urlpath,
+		RawQuery: query.Encode(),
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewBuffer(body))
+	if err != nil {
+		response.err = fmt.Errorf("internal error: %v", err)
+		return response
+	}
+	req = req.WithContext(ctx)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	httpResp, err := client.doer.Do(req)
+	if err != nil {
+		response.err = err
+		return response
+	}
+	defer httpResp.Body.Close()
+	response.statusCode = httpResp.StatusCode
+	response.err = decodeInto(httpResp.Body, response)
+	response.checkError()
+	return response
+}
+
 func decodeInto(reader io.Reader, v interface{}) error {
 	dec := json.NewDecoder(reader)
 	if err := dec.Decode(v); err != nil {
@@ -275,6 +278,9 @@ type PendingSnapRefreshInfo struct {
 	TimeRemaining       time.Duration `json:"time-remaining,omitempty"`
 	BusyAppName         string        `json:"busy-app-name,omitempty"`
 	BusyAppDesktopEntry string        `json:"busy-app-desktop-entry,omitempty"`
+	// Downloaded is set when the update was downloaded already and the
+	// snap is refreshed as soon as its apps are closed.
+	Downloaded bool `json:"downloaded,omitempty"`
 }
 
 // PendingRefreshNotification broadcasts information about a refresh.
@@ -287,3 +293,40 @@ func (client *Client) PendingRefreshNotification(ctx context.Context, refreshInf
 	_, err = client.doMany(ctx, "POST", "/v1/notifications/pending-refresh", nil, headers, reqBody)
 	return err
 }
+
+// FinishedSnapRefreshInfo holds information about a finished refresh provided to userd.
+type FinishedSnapRefreshInfo struct {
+	InstanceName string `json:"instance-name"`
+}
+
+// FinishRefreshNotification broadcasts information about a refresh that
+// finished after having been inhibited by running apps.
+func (client *Client) FinishRefreshNotification(ctx context.Context, refreshInfo *FinishedSnapRefreshInfo) error {
+	headers := map[string]string{"Content-Type": "application/json"}
+	reqBody, err := json.Marshal(refreshInfo)
+	if err != nil {
+		return err
+	}
+	_, err = client.doMany(ctx, "POST", "/v1/notifications/finish-refresh", nil, headers, reqBody)
+	return err
+}
+
+// PromptInfo holds information about a prompt provided to userd.
+type PromptInfo struct {
+	ID          string   `json:"id"`
+	Snap        string   `json:"snap"`
+	Path        string   `json:"path"`
+	Permissions []string `json:"permissions"`
+}
+
+// PromptNotification notifies the given user about a prompt about a file
+// access of a snap.
+func (client *Client) PromptNotification(ctx context.Context, uid int, prompt *PromptInfo) error {
+	headers := map[string]string{"Content-Type": "application/json"}
+	reqBody, err := json.Marshal(prompt)
+	if err != nil {
+		return err
+	}
+	response := client.doOne(ctx, uid, "POST", "/v1/notifications/prompt", nil, headers, reqBody)
+	return response.err
+}
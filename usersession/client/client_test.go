@@ -22,6 +22,7 @@ package client_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -457,3 +458,52 @@ func (s *clientSuite) TestPendingRefreshNotification(c *C) {
 	err := s.cli.PendingRefreshNotification(context.Background(), &client.PendingSnapRefreshInfo{})
 	c.Assert(err, IsNil)
 }
+
+func (s *clientSuite) TestPromptNotification(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/v1/notifications/prompt")
+		// only the session agent of the prompted user is notified
+		c.Check(r.Host, Equals, "1000")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Check(err, IsNil)
+		c.Check(string(body), Equals, `{"id":"1","snap":"some-snap","path":"/home/alice/doc.txt","permissions":["read"]}`)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{"type": "sync"}`))
+	})
+	err := s.cli.PromptNotification(context.Background(), 1000, &client.PromptInfo{
+		ID:          "1",
+		Snap:        "some-snap",
+		Path:        "/home/alice/doc.txt",
+		Permissions: []string{"read"},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *clientSuite) TestPromptNotificationError(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(500)
+		w.Write([]byte(`{"type": "error", "result": {"message": "cannot connect to the session bus"}}`))
+	})
+	err := s.cli.PromptNotification(context.Background(), 1000, &client.PromptInfo{ID: "1"})
+	c.Assert(err, ErrorMatches, "cannot connect to the session bus")
+
+	// users without a session agent cannot be notified
+	err = s.cli.PromptNotification(context.Background(), 1001, &client.PromptInfo{ID: "1"})
+	c.Assert(err, ErrorMatches, ".*no such file or directory")
+}
+
+func (s *clientSuite) TestFinishRefreshNotification(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/v1/notifications/finish-refresh")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Check(err, IsNil)
+		c.Check(string(body), Equals, `{"instance-name":"some-snap"}`)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{"type": "sync"}`))
+	})
+	err := s.cli.FinishRefreshNotification(context.Background(), &client.FinishedSnapRefreshInfo{InstanceName: "some-snap"})
+	c.Assert(err, IsNil)
+}
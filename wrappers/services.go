@@ -836,6 +836,45 @@ func ServicesEnableState(s *snap.Info, inter interacter) (map[string]bool, error
 	return snapSvcsState, nil
 }
 
+// InactiveServices returns the names of the enabled services of the
+// given snap that are expected to be running but are not active. Services
+// activated by sockets or timers and oneshot services are not expected to
+// be running and are ignored.
+func InactiveServices(s *snap.Info, inter interacter) ([]string, error) {
+	apps := s.Services()
+	sort.Sort(snap.AppInfoBySnapApp(apps))
+
+	var svcs []string
+	var unitNames []string
+	for _, app := range apps {
+		// FIXME: handle user daemons
+		if app.DaemonScope != snap.SystemDaemon {
+			continue
+		}
+		if app.Daemon == "oneshot" || app.Timer != nil || len(app.Sockets) != 0 {
+			continue
+		}
+		svcs = append(svcs, app.Name)
+		unitNames = append(unitNames, app.ServiceName())
+	}
+	if len(unitNames) == 0 {
+		return nil, nil
+	}
+
+	sysd := systemd.New(systemd.SystemMode, inter)
+	sts, err := sysd.Status(unitNames...)
+	if err != nil {
+		return nil, err
+	}
+	var inactive []string
+	for i, st := range sts {
+		if st.Enabled && !st.Active {
+			inactive = append(inactive, svcs[i])
+		}
+	}
+	return inactive, nil
+}
+
 // RemoveQuotaGroup ensures that the slice file for a quota group is removed. It
 // assumes that the slice corresponding to the group is not in use anymore by
 // any services or sub-groups of the group when it is invoked. To remove a group
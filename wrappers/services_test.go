@@ -1997,6 +1997,70 @@ func (s *servicesTestSuite) TestServicesEnableStateFail(c *C) {
 	})
 }
 
+func (s *servicesTestSuite) TestInactiveServices(c *C) {
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: forking
+ svc3:
+  command: bin/hello
+  daemon: simple
+ svc4:
+  command: bin/hello
+  daemon: oneshot
+ svc5:
+  command: bin/hello
+  daemon: simple
+  timer: 10:00-12:00
+ svc6:
+  command: bin/hello
+  daemon: simple
+  daemon-scope: user
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	s.systemctlRestorer()
+	r := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		return []byte(`Id=snap.hello-snap.svc1.service
+Type=simple
+ActiveState=failed
+UnitFileState=enabled
+
+Id=snap.hello-snap.svc2.service
+Type=forking
+ActiveState=active
+UnitFileState=enabled
+
+Id=snap.hello-snap.svc3.service
+Type=simple
+ActiveState=inactive
+UnitFileState=disabled
+`), nil
+	})
+	defer r()
+
+	inactive, err := wrappers.InactiveServices(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(inactive, DeepEquals, []string{"svc1"})
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState,Type", "snap.hello-snap.svc1.service", "snap.hello-snap.svc2.service", "snap.hello-snap.svc3.service"},
+	})
+}
+
+func (s *servicesTestSuite) TestInactiveServicesNoServices(c *C) {
+	info := snaptest.MockSnap(c, `name: hello-snap
+version: 1.10
+apps:
+ hello:
+   command: bin/hello
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	inactive, err := wrappers.InactiveServices(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(inactive, HasLen, 0)
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
 func (s *servicesTestSuite) TestAddSnapServicesWithDisabledServices(c *C) {
 	info := snaptest.MockSnap(c, packageHello+`
  svc2: